@@ -1,12 +1,27 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"payment-service/internal/breaker"
+	"payment-service/internal/entity"
+	"payment-service/internal/gateway"
 	"payment-service/internal/handler"
+	"payment-service/internal/idempotency"
+	"payment-service/internal/ledger"
 	"payment-service/internal/repository"
+	"payment-service/internal/server"
 	"payment-service/internal/usecase"
+	"payment-service/internal/webhook"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -24,14 +39,124 @@ import (
 // @BasePath /
 
 func main() {
+	store := flag.String("store", "memory", "payment store: \"memory\", \"file:/path/to/payments.db\" or a postgres:// connection URL")
+	idempotencyTTL := flag.Duration("idempotency-ttl", idempotency.DefaultTTL, "how long responses are replayed for a repeated idempotency key")
+	authorizationWindow := flag.Duration("authorization-window", usecase.DefaultAuthorizationWindow, "how long an authorized payment can be captured before the authorization lapses")
+	feeBasisPoints := flag.Int64("fee-bps", 0, "processing fee charged on captured amounts, in basis points (1/100 of a percent)")
+	gatewayName := flag.String("gateway", "simulator", "payment processors charging card payments: \"simulator\" or \"none\" to approve them without a processor")
+	routesFile := flag.String("routes", "", "JSON file of routes choosing among the simulated processors; by default every card payment tries \"primary\", then \"secondary\"")
+	transferLimit := flag.String("transfer-limit", "", "comma-separated currency=amount pairs of the largest single transfer in each currency, such as USD=1000.00,JPY=100000")
+	dailyTransferLimit := flag.String("daily-transfer-limit", "", "comma-separated currency=amount pairs of the most a user can send by transfer in 24 hours in each currency")
+	storageTimeout := flag.Duration("storage-timeout", 2*time.Second, "deadline of every payment store call (0 for none)")
+	gatewayTimeout := flag.Duration("gateway-timeout", 5*time.Second, "deadline of every payment processor call (0 for none)")
+	breakerThreshold := flag.Int("breaker-threshold", breaker.DefaultFailureThreshold, "failures in a row of the store or the processors that open their circuit breaker")
+	breakerCooldown := flag.Duration("breaker-cooldown", breaker.DefaultOpenTimeout, "how long an open circuit breaker fails calls fast before letting a trial call through")
+	webhookAttempts := flag.Int("webhook-attempts", webhook.DefaultMaxAttempts, "attempts to deliver a webhook event before it is dead-lettered")
+	webhookConcurrency := flag.Int("webhook-concurrency", webhook.DefaultConcurrency, "webhook deliveries sent at once")
+	webhookBackoff := flag.Duration("webhook-backoff", webhook.DefaultBaseDelay, "wait before the first webhook retry; it doubles with every failed attempt")
+	processorSecrets := flag.String("processor-secrets", "", "comma-separated processor=secret pairs verifying the callbacks of each simulated processor; callbacks from other processors are rejected")
+	shutdownTimeout := flag.Duration("shutdown-timeout", server.DefaultShutdownTimeout, "how long the service waits for requests in progress on SIGTERM or SIGINT before closing their connections")
+	flag.Parse()
+
+	if *idempotencyTTL <= 0 {
+		log.Fatalf("idempotency-ttl must be positive")
+	}
+	if *feeBasisPoints < 0 || *feeBasisPoints > 10000 {
+		log.Fatalf("fee-bps must be between 0 and 10000, got %d", *feeBasisPoints)
+	}
+	transferLimits, err := parseTransferLimits(*transferLimit, *dailyTransferLimit)
+	if err != nil {
+		log.Fatalf("Invalid transfer limits: %v", err)
+	}
+	if *breakerThreshold < 1 || *breakerCooldown <= 0 {
+		log.Fatalf("breaker-threshold and breaker-cooldown must be positive")
+	}
+	if *webhookAttempts < 1 || *webhookBackoff <= 0 || *webhookConcurrency < 1 {
+		log.Fatalf("webhook-attempts, webhook-backoff and webhook-concurrency must be positive")
+	}
+	if *shutdownTimeout <= 0 {
+		log.Fatalf("shutdown-timeout must be positive")
+	}
+
+	// Run until SIGTERM or SIGINT
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
 	// Initialize repository
-	paymentRepo := repository.NewInMemoryPaymentRepository()
+	paymentRepo, closeStore, err := newPaymentRepository(*store)
+	if err != nil {
+		log.Fatalf("Failed to initialize payment store: %v", err)
+	}
+	defer closeStore()
+
+	// Guard the store with a circuit breaker
+	guardedStore := breaker.NewStore(paymentRepo, breaker.Settings{
+		FailureThreshold: *breakerThreshold,
+		OpenTimeout:      *breakerCooldown,
+		CallTimeout:      *storageTimeout,
+	})
+	breakers := []*breaker.Breaker{guardedStore.Breaker()}
+
+	// Initialize payment processors
+	paymentRouter, err := newPaymentRouter(*gatewayName, *routesFile)
+	if err != nil {
+		log.Fatalf("Failed to initialize payment gateway: %v", err)
+	}
+
+	// Initialize ledger, read from the journal entries recorded in the store
+	paymentLedger := ledger.NewJournal(guardedStore)
+
+	// Initialize webhook dispatcher, delivering the events queued in the store in the background
+	webhooks := webhook.NewDispatcher(guardedStore, webhook.WithRetryPolicy(webhook.RetryPolicy{
+		MaxAttempts: *webhookAttempts,
+		BaseDelay:   *webhookBackoff,
+		MaxDelay:    webhook.DefaultMaxDelay,
+	}), webhook.WithConcurrency(*webhookConcurrency))
+	webhookDelivery := server.Go(ctx, func(ctx context.Context) { webhooks.Run(ctx, webhook.DefaultPollInterval) })
 
 	// Initialize use case
-	paymentUseCase := usecase.NewPaymentUseCase(paymentRepo)
+	opts := []usecase.Option{
+		usecase.WithAuthorizationWindow(*authorizationWindow),
+		usecase.WithWallets(guardedStore),
+		usecase.WithTransfers(guardedStore),
+		usecase.WithTransferLimits(transferLimits),
+		usecase.WithProcessingFee(*feeBasisPoints),
+		usecase.WithEvents(webhooks, guardedStore),
+	}
+	// Jobs reach the workers only through a PostgreSQL store they share; without one,
+	// asynchronous payments are rejected rather than queued with nobody to process them
+	if repository.IsPostgresURL(*store) {
+		opts = append(opts, usecase.WithJobQueue(guardedStore))
+	}
+	if paymentRouter != nil {
+		guardedGateway := breaker.NewGateway(paymentRouter, breaker.Settings{
+			FailureThreshold: *breakerThreshold,
+			OpenTimeout:      *breakerCooldown,
+			CallTimeout:      *gatewayTimeout,
+		})
+		breakers = append(breakers, guardedGateway.Breaker())
+		opts = append(opts, usecase.WithGateway(guardedGateway))
+	}
+	paymentUseCase := usecase.NewPaymentUseCase(guardedStore, opts...)
+	authorizationSweep := server.Go(ctx, func(ctx context.Context) {
+		expireAuthorizations(ctx, paymentUseCase, authorizationSweepInterval)
+	})
+	// Publish the events of changes stored without being published, including the worker's
+	eventSweep := server.Go(ctx, func(ctx context.Context) {
+		publishPendingEvents(ctx, paymentUseCase, eventSweepInterval)
+	})
 
-	// Initialize handler
-	paymentHandler := handler.NewPaymentHandler(paymentUseCase)
+	// Initialize idempotency key store
+	idempotencyStore := idempotency.NewMemoryStore(*idempotencyTTL)
+	defer idempotencyStore.Close()
+
+	// Initialize handler, accepting callbacks from the processors with a secret
+	secrets, err := parseProcessorSecrets(*processorSecrets)
+	if err != nil {
+		log.Fatalf("Invalid processor-secrets: %v", err)
+	}
+	paymentHandler := handler.NewPaymentHandler(paymentUseCase, idempotencyStore, handler.WithProviderCallbacks(gateway.NewCallbacks(secrets)))
+	ledgerHandler := handler.NewLedgerHandler(paymentLedger)
 
 	// Setup router
 	r := chi.NewRouter()
@@ -44,18 +169,22 @@ func main() {
 	// Mount payment routes
 	r.Mount("/", paymentHandler.SetupRoutes())
 
+	// Mount ledger routes
+	r.Mount("/ledger", ledgerHandler.SetupRoutes())
+
+	// Mount merchant webhook routes
+	r.Mount("/merchants", handler.NewWebhookHandler(webhooks).SetupRoutes())
+
+	// Mount the dead-letter queue of the payment workers
+	r.Mount("/jobs", handler.NewJobHandler(paymentUseCase).SetupRoutes())
+
+	// Mount routing debug routes
+	if paymentRouter != nil {
+		r.Mount("/routing", handler.NewRoutingHandler(paymentRouter).SetupRoutes())
+	}
+
 	// Health check endpoint
-	// @Summary Health Check
-	// @Description Returns the health status of the payment service
-	// @Tags Health
-	// @Produce json
-	// @Success 200 {object} map[string]string "Service is healthy"
-	// @Router /health [get]
-	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		fmt.Fprint(w, `{"status":"ok","service":"payment-service"}`)
-	})
+	r.Get("/health", handler.NewHealthHandler(breakers...).GetHealth)
 
 	// Swagger documentation endpoint
 	r.Get("/swagger/*", httpSwagger.Handler(
@@ -68,6 +197,192 @@ func main() {
 	})
 
 	port := ":8080"
+	listener, err := net.Listen("tcp", port)
+	if err != nil {
+		log.Fatalf("Failed to listen on port %s: %v", port, err)
+	}
+
+	// Serve until SIGTERM or SIGINT, then drain the requests in progress
 	fmt.Printf("Payment service starting on port %s\n", port)
-	log.Fatal(http.ListenAndServe(port, r))
+	serveErr := server.Serve(ctx, &http.Server{Handler: r}, listener, *shutdownTimeout)
+
+	// Stop the webhook dispatcher, which hands the deliveries it is attempting back to the queue
+	// in the store, and the authorization and event sweeps, and wait for them so what they are
+	// saving is stored before the store is closed
+	stop()
+	deadline := time.Now().Add(*shutdownTimeout)
+	if err := webhookDelivery.Wait(time.Until(deadline)); err != nil {
+		log.Printf("Webhook dispatcher stopped: %v", err)
+	}
+	if err := authorizationSweep.Wait(time.Until(deadline)); err != nil {
+		log.Printf("Authorization sweep stopped: %v", err)
+	}
+	if err := eventSweep.Wait(time.Until(deadline)); err != nil {
+		log.Printf("Event sweep stopped: %v", err)
+	}
+	if serveErr != nil {
+		log.Printf("Payment service stopped: %v", serveErr)
+		return
+	}
+	fmt.Println("Payment service stopped")
+}
+
+// authorizationSweepInterval is how often lapsed authorizations are voided
+const authorizationSweepInterval = time.Minute
+
+// expireAuthorizations voids lapsed authorizations every interval until ctx is done
+func expireAuthorizations(ctx context.Context, paymentUseCase *usecase.PaymentUseCase, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := paymentUseCase.ExpireAuthorizations(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Failed to expire authorizations: %v", err)
+		}
+	}
+}
+
+// eventSweepInterval is how often the events of changes stored without being published are published
+const eventSweepInterval = 10 * time.Second
+
+// publishPendingEvents publishes the events of changes stored without being published every interval until ctx is done
+func publishPendingEvents(ctx context.Context, paymentUseCase *usecase.PaymentUseCase, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := paymentUseCase.PublishPendingEvents(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Failed to publish pending events: %v", err)
+		}
+	}
+}
+
+// newPaymentRouter creates the router over the payment processors selected by the gateway flag,
+// reading its routes from routesFile when one is given. It returns nil for "none", which leaves
+// card payments to be approved by the service itself.
+func newPaymentRouter(name, routesFile string) (*gateway.Router, error) {
+	switch name {
+	case "simulator":
+		return gateway.NewSimulatedRouter(routesFile)
+	case "none":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown gateway %q", name)
+	}
+}
+
+// parseProcessorSecrets parses comma-separated processor=secret pairs
+func parseProcessorSecrets(value string) (map[string]string, error) {
+	secrets := make(map[string]string)
+	if value == "" {
+		return secrets, nil
+	}
+	for _, pair := range strings.Split(value, ",") {
+		processor, secret, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || processor == "" || secret == "" {
+			return nil, fmt.Errorf("%q is not a processor=secret pair", pair)
+		}
+		secrets[processor] = secret
+	}
+	return secrets, nil
+}
+
+// parseTransferLimits parses the per-transfer and daily limits, each comma-separated currency=amount pairs.
+// A currency in either is allowed, with no limit of the other kind unless it is in both.
+func parseTransferLimits(perTransfer, daily string) (map[string]usecase.TransferLimits, error) {
+	perTransferAmounts, err := parseCurrencyAmounts(perTransfer)
+	if err != nil {
+		return nil, err
+	}
+	dailyAmounts, err := parseCurrencyAmounts(daily)
+	if err != nil {
+		return nil, err
+	}
+	limits := make(map[string]usecase.TransferLimits)
+	for currency, amount := range perTransferAmounts {
+		limits[currency] = usecase.TransferLimits{PerTransfer: amount}
+	}
+	for currency, amount := range dailyAmounts {
+		currencyLimits := limits[currency]
+		currencyLimits.Daily = amount
+		limits[currency] = currencyLimits
+	}
+	return limits, nil
+}
+
+// parseCurrencyAmounts parses comma-separated currency=amount pairs into minor units by currency
+func parseCurrencyAmounts(value string) (map[string]int64, error) {
+	amounts := make(map[string]int64)
+	if value == "" {
+		return amounts, nil
+	}
+	for _, pair := range strings.Split(value, ",") {
+		currency, amount, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not a currency=amount pair", pair)
+		}
+		money, err := entity.ParseMoney(amount, currency)
+		if err != nil || !money.IsPositive() {
+			return nil, fmt.Errorf("%q is not a positive amount in a supported currency", pair)
+		}
+		amounts[money.Currency] = money.Amount
+	}
+	return amounts, nil
+}
+
+// paymentStore keeps payments, wallets, transfers, the jobs of asynchronous payments, the events
+// still to publish, the ledger's journal entries and merchants' webhooks; every repository
+// implementation provides all seven
+type paymentStore interface {
+	usecase.PaymentRepository
+	usecase.WalletRepository
+	usecase.TransferRepository
+	usecase.JobQueue
+	usecase.EventOutbox
+	ledger.EntryStore
+	webhook.Store
+}
+
+// newPaymentRepository creates the payment repository selected by the store flag
+// and returns a function that releases its resources
+func newPaymentRepository(store string) (paymentStore, func() error, error) {
+	switch {
+	case store == "memory":
+		return repository.NewInMemoryPaymentRepository(), func() error { return nil }, nil
+
+	case strings.HasPrefix(store, "file:"):
+		db, err := repository.OpenBolt(strings.TrimPrefix(store, "file:"))
+		if err != nil {
+			return nil, nil, err
+		}
+		repo, err := repository.NewBoltPaymentRepository(db)
+		if err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+		return repo, db.Close, nil
+
+	case repository.IsPostgresURL(store):
+		db, err := repository.OpenPostgres(store)
+		if err != nil {
+			return nil, nil, err
+		}
+		repo := repository.NewPostgresPaymentRepository(db)
+		if err := repo.Migrate(); err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+		return repo, db.Close, nil
+
+	default:
+		return nil, nil, fmt.Errorf("unsupported store %q", store)
+	}
 }
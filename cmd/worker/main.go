@@ -1,164 +1,162 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
-	"math/rand"
+	"io"
+	"log"
+	"net"
+	"net/http"
 	"os"
-	"os/exec"
-	"runtime"
+	"os/signal"
+	"payment-service/internal/breaker"
+	"payment-service/internal/gateway"
+	"payment-service/internal/handler"
+	"payment-service/internal/repository"
+	"payment-service/internal/server"
+	"payment-service/internal/usecase"
+	"payment-service/internal/webhook"
+	"payment-service/internal/worker"
 	"sync"
+	"syscall"
 	"time"
 )
 
-// WorkerStatus tracks what each worker is currently doing
-type WorkerStatus struct {
-	mu          sync.RWMutex
-	workers     map[int]int  // worker ID -> current task ID
-	lastUpdated map[int]bool // worker ID -> was just updated
-}
-
-func NewWorkerStatus() *WorkerStatus {
-	return &WorkerStatus{
-		workers:     make(map[int]int),
-		lastUpdated: make(map[int]bool),
+// quietObserver leaves the terminal to the status display
+type quietObserver struct{}
+
+func (quietObserver) Started(int, worker.Task) {}
+
+func (quietObserver) Finished(worker.Result) {}
+
+// displayStatus redraws the status of the pool on out every interval until ctx is done,
+// using ANSI escape codes to clear the terminal
+func displayStatus(ctx context.Context, out io.Writer, pool *worker.Pool, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		fmt.Fprint(out, "\x1b[H\x1b[2J")
+		pool.Status(ctx).WriteText(out)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
 	}
 }
 
-func (ws *WorkerStatus) updateWorker(workerID, taskID int) {
-	ws.mu.Lock()
-	defer ws.mu.Unlock()
-
-	// Reset all "just updated" flags
-	for id := range ws.lastUpdated {
-		ws.lastUpdated[id] = false
+func main() {
+	store := flag.String("store", "", "postgres:// connection URL of the payment store shared with the payment service")
+	feeBasisPoints := flag.Int64("fee-bps", 0, "processing fee charged on captured amounts, in basis points (1/100 of a percent); the same as the payment service's")
+	numWorkers := flag.Int("workers", 5, "number of payments processed concurrently")
+	pollInterval := flag.Duration("poll-interval", worker.DefaultPollInterval, "how long an idle worker waits before checking the job queue again")
+	visibilityTimeout := flag.Duration("visibility-timeout", worker.DefaultVisibilityTimeout, "how long a leased job is hidden from other workers before it is delivered again; longer than a payment takes")
+	maxAttempts := flag.Int("max-attempts", worker.DefaultMaxAttempts, "attempts to process a payment before its job is dead-lettered")
+	shutdownTimeout := flag.Duration("shutdown-timeout", worker.DefaultShutdownTimeout, "how long the workers finish their payments on SIGTERM or SIGINT before handing them back to the queue; shorter than visibility-timeout")
+	retryBackoff := flag.Duration("retry-backoff", worker.DefaultBaseDelay, "wait before the first retry of a failed job; it doubles with every failed attempt, with jitter")
+	gatewayName := flag.String("gateway", "simulator", "payment processors charging card payments: \"simulator\" or \"none\" to approve them without a processor")
+	routesFile := flag.String("routes", "", "JSON file of routes choosing among the simulated processors; by default every card payment tries \"primary\", then \"secondary\"")
+	storageTimeout := flag.Duration("storage-timeout", 2*time.Second, "deadline of every payment store call (0 for none)")
+	gatewayTimeout := flag.Duration("gateway-timeout", 5*time.Second, "deadline of every payment processor call (0 for none)")
+	statusAddr := flag.String("status-addr", ":8081", "address serving the status of the workers at GET /status (empty for none)")
+	tty := flag.Bool("tty", false, "redraw the status of the workers on the terminal every second instead of logging every payment")
+	flag.Parse()
+
+	if *numWorkers < 1 {
+		log.Fatalf("workers must be positive, got %d", *numWorkers)
 	}
-
-	// Mark this worker as just updated
-	ws.workers[workerID] = taskID
-	ws.lastUpdated[workerID] = true
-}
-
-func (ws *WorkerStatus) printStatus(timestamp string) {
-	ws.mu.RLock()
-	defer ws.mu.RUnlock()
-
-	// Clear screen before printing new status
-	clearScreen()
-
-	fmt.Printf("--------[%s]--------\n", timestamp)
-	for i := 1; i <= 5; i++ {
-		if taskID, exists := ws.workers[i]; exists {
-			if ws.lastUpdated[i] {
-				fmt.Printf("Worker %d started task %d (new)\n", i, taskID)
-			} else {
-				fmt.Printf("Worker %d started task %d\n", i, taskID)
-			}
-		}
+	if *feeBasisPoints < 0 || *feeBasisPoints > 10000 {
+		log.Fatalf("fee-bps must be between 0 and 10000, got %d", *feeBasisPoints)
 	}
-}
-
-// clearScreen clears the console output
-func clearScreen() {
-	var cmd *exec.Cmd
-	if runtime.GOOS == "windows" {
-		cmd = exec.Command("cmd", "/c", "cls")
-	} else {
-		cmd = exec.Command("clear")
+	if *pollInterval <= 0 || *visibilityTimeout <= 0 || *maxAttempts < 1 || *retryBackoff <= 0 {
+		log.Fatalf("poll-interval, visibility-timeout, max-attempts and retry-backoff must be positive")
+	}
+	if *shutdownTimeout <= 0 || *shutdownTimeout >= *visibilityTimeout {
+		log.Fatalf("shutdown-timeout must be positive and shorter than visibility-timeout")
+	}
+	// Jobs are handed over through the store, so it must be one the service shares
+	if !repository.IsPostgresURL(*store) {
+		log.Fatalf("store must be the postgres:// URL of the payment service's store")
 	}
-	cmd.Stdout = os.Stdout
-	cmd.Run()
-}
-
-// Task represents a work item
-type Task struct {
-	ID    int
-	Value int
-}
-
-// Result represents the output of a task
-type Result struct {
-	ID     int
-	Value  int
-	Result int
-}
-
-// Worker processes tasks from the task channel
-func worker(id int, tasks <-chan Task, results chan<- Result, wg *sync.WaitGroup, status *WorkerStatus) {
-	defer wg.Done()
-
-	for task := range tasks {
-		// Update worker status and print all workers
-		timestamp := time.Now().Format("2006-01-02 15:04:05")
-		status.updateWorker(id, task.ID)
-		status.printStatus(timestamp)
-
-		// Random delay between 1-10 seconds to observe dynamic concurrency
-		randomDelay := time.Duration(rand.Intn(10)+1) * time.Second
-		time.Sleep(randomDelay)
 
-		// Create a simple result (no square operation needed)
-		result := Result{
-			ID:     task.ID,
-			Value:  task.Value,
-			Result: 0, // No calculation needed
+	// Initialize repository
+	db, err := repository.OpenPostgres(*store)
+	if err != nil {
+		log.Fatalf("Failed to initialize payment store: %v", err)
+	}
+	defer db.Close()
+	paymentRepo := repository.NewPostgresPaymentRepository(db)
+	if err := paymentRepo.Migrate(); err != nil {
+		log.Fatalf("Failed to initialize payment store: %v", err)
+	}
+	guardedStore := breaker.NewStore(paymentRepo, breaker.Settings{CallTimeout: *storageTimeout})
+
+	// Initialize use case like the payment service's: captures record their journal entries in the
+	// shared store, and events are queued there for the service's dispatcher to deliver
+	opts := []usecase.Option{
+		usecase.WithWallets(guardedStore),
+		usecase.WithTransfers(guardedStore),
+		usecase.WithProcessingFee(*feeBasisPoints),
+		usecase.WithEvents(webhook.NewDispatcher(guardedStore), guardedStore),
+	}
+	switch *gatewayName {
+	case "simulator":
+		paymentRouter, err := gateway.NewSimulatedRouter(*routesFile)
+		if err != nil {
+			log.Fatalf("Failed to initialize payment gateway: %v", err)
 		}
-
-		results <- result
+		opts = append(opts, usecase.WithGateway(breaker.NewGateway(paymentRouter, breaker.Settings{CallTimeout: *gatewayTimeout})))
+	case "none":
+	default:
+		log.Fatalf("Failed to initialize payment gateway: unknown gateway %q", *gatewayName)
 	}
-}
-
-func main() {
-	fmt.Println("Starting Worker Pool Demo")
-	fmt.Println("=========================")
-
-	const numTasks = 100
-	const numWorkers = 5
-
-	// Create channels
-	tasks := make(chan Task, numTasks)
-	results := make(chan Result, numTasks)
-
-	// Create wait group for workers
-	var wg sync.WaitGroup
-
-	// Create worker status tracker
-	status := NewWorkerStatus()
+	paymentUseCase := usecase.NewPaymentUseCase(guardedStore, opts...)
 
 	// Start workers
-	fmt.Printf("Starting %d workers...\n\n", numWorkers)
-
-	// Start all workers simultaneously
-	for i := 1; i <= numWorkers; i++ {
-		wg.Add(1)
-		go worker(i, tasks, results, &wg, status)
+	fmt.Printf("Starting %d workers...\n\n", *numWorkers)
+	poolOpts := []worker.Option{
+		worker.WithPollInterval(*pollInterval),
+		worker.WithVisibilityTimeout(*visibilityTimeout),
+		worker.WithShutdownTimeout(*shutdownTimeout),
+		worker.WithRetryPolicy(worker.RetryPolicy{MaxAttempts: *maxAttempts, BaseDelay: *retryBackoff, MaxDelay: worker.DefaultMaxDelay}),
 	}
-
-	// Give workers a moment to start, then begin sending tasks
-	time.Sleep(100 * time.Millisecond)
-
-	// Send tasks
-	fmt.Printf("Sending %d tasks...\n\n", numTasks)
-	go func() {
-		for i := 1; i <= numTasks; i++ {
-			tasks <- Task{
-				ID:    i,
-				Value: i,
-			}
+	if *tty {
+		poolOpts = append(poolOpts, worker.WithObserver(quietObserver{}))
+	}
+	pool := worker.NewPool(paymentUseCase, guardedStore, *numWorkers, poolOpts...)
+
+	// Work until SIGTERM or SIGINT, then let the payments in progress finish
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	// Report the status of the workers until they have stopped, so a shutdown can be followed
+	monitorCtx, stopMonitors := context.WithCancel(context.Background())
+	var monitors sync.WaitGroup
+	if *statusAddr != "" {
+		listener, err := net.Listen("tcp", *statusAddr)
+		if err != nil {
+			log.Fatalf("Failed to listen on %s: %v", *statusAddr, err)
 		}
-		close(tasks)
-	}()
-
-	// Collect results
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
-
-	// Store results in a slice to maintain order
-	resultSlice := make([]Result, numTasks)
-	for result := range results {
-		resultSlice[result.ID-1] = result
+		statusServer := &http.Server{Handler: handler.NewWorkerStatusHandler(pool).SetupRoutes()}
+		monitors.Add(1)
+		go func() {
+			defer monitors.Done()
+			if err := server.Serve(monitorCtx, statusServer, listener, server.DefaultShutdownTimeout); err != nil {
+				log.Printf("Worker status stopped: %v", err)
+			}
+		}()
+	}
+	if *tty {
+		monitors.Add(1)
+		go func() {
+			defer monitors.Done()
+			displayStatus(monitorCtx, os.Stdout, pool, time.Second)
+		}()
 	}
 
-	fmt.Println("\nWorker pool demo completed!")
+	pool.Run(ctx)
+	stopMonitors()
+	monitors.Wait()
+	fmt.Println("Workers stopped")
 }
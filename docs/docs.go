@@ -20,9 +20,383 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/health": {
+            "get": {
+                "description": "Returns the health status of the payment service and the circuit breaker of each dependency. The service still answers while degraded; requests needing an open dependency fail fast with 503.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Health Check",
+                "responses": {
+                    "200": {
+                        "description": "Service health",
+                        "schema": {
+                            "$ref": "#/definitions/handler.HealthResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs/dead": {
+            "get": {
+                "description": "Lists the jobs of asynchronous payments that failed every attempt, oldest first, with the error of the last attempt.\nTheir payments stay pending and queued until the job is requeued.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Jobs"
+                ],
+                "summary": "List Dead Jobs",
+                "responses": {
+                    "200": {
+                        "description": "Dead-lettered jobs",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/entity.Job"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "The store is failing",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs/dead/{transaction_id}/requeue": {
+            "post": {
+                "description": "Makes a dead-lettered job available to the workers again, with a fresh set of attempts",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Jobs"
+                ],
+                "summary": "Requeue Dead Job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Transaction ID",
+                        "name": "transaction_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Requeued job",
+                        "schema": {
+                            "$ref": "#/definitions/entity.Job"
+                        }
+                    },
+                    "404": {
+                        "description": "No dead-lettered job for the transaction",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "The store is failing",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/ledger/accounts/{account}": {
+            "get": {
+                "description": "Returns the balance of a ledger account in one currency",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Ledger"
+                ],
+                "summary": "Get Account Balance",
+                "parameters": [
+                    {
+                        "enum": [
+                            "cash",
+                            "merchant_payable",
+                            "fee_revenue",
+                            "wallet_funds"
+                        ],
+                        "type": "string",
+                        "description": "Account code",
+                        "name": "account",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ISO 4217 currency",
+                        "name": "currency",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Account balance",
+                        "schema": {
+                            "$ref": "#/definitions/handler.AccountBalance"
+                        }
+                    },
+                    "400": {
+                        "description": "Missing currency",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown account",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/ledger/trial-balance": {
+            "get": {
+                "description": "Lists the debits, credits and balance of every ledger account per currency, with the debit and credit totals.\nbalanced is true when debits equal credits in every currency.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Ledger"
+                ],
+                "summary": "Get Trial Balance",
+                "responses": {
+                    "200": {
+                        "description": "Trial balance",
+                        "schema": {
+                            "$ref": "#/definitions/ledger.TrialBalance"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/merchants/{merchant_id}/webhooks": {
+            "get": {
+                "description": "Lists the merchant's webhook endpoints, without their secrets",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "List Webhook Endpoints",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Merchant ID",
+                        "name": "merchant_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Webhook endpoints",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/webhook.Endpoint"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Registers an endpoint that receives an event for every status change of the merchant's payments, or only the given event types.\nThe response carries the endpoint's signing secret, which is never shown again. Every request has a Webhook-Signature header\n\"t=\u003cunix seconds\u003e,v1=\u003chex HMAC-SHA256 of the timestamp, a dot and the body, keyed with the secret\u003e\" and a Webhook-Id header with the event ID.\nFailed deliveries are retried with exponential backoff until they are dead-lettered.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "Register Webhook Endpoint",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Merchant ID",
+                        "name": "merchant_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Endpoint",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.RegisterEndpointRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Registered endpoint with its secret",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.Endpoint"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid URL or event types",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/merchants/{merchant_id}/webhooks/deliveries": {
+            "get": {
+                "description": "Lists the merchant's webhook deliveries, newest first. Dead deliveries failed every attempt and are only sent again when replayed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "List Webhook Deliveries",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Merchant ID",
+                        "name": "merchant_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "pending",
+                            "delivered",
+                            "dead"
+                        ],
+                        "type": "string",
+                        "description": "Delivery status",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Webhook deliveries",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/webhook.Delivery"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid status",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/merchants/{merchant_id}/webhooks/deliveries/{delivery_id}/replay": {
+            "post": {
+                "description": "Sends a delivery again, whatever its status, with a fresh set of attempts. Receivers should deduplicate events by their Webhook-Id.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "Replay Webhook Delivery",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Merchant ID",
+                        "name": "merchant_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Delivery ID",
+                        "name": "delivery_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Delivery scheduled for sending",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.Delivery"
+                        }
+                    },
+                    "404": {
+                        "description": "Delivery not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Delivery is being attempted",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
         "/pay": {
             "post": {
-                "description": "Processes a payment request with idempotency support. Retrying the same transaction_id will not charge twice.",
+                "description": "Processes a payment request with idempotency support. Retrying the same transaction_id will not charge twice; reusing it with a different payload is rejected with 409.\nThe idempotency key is taken from the Idempotency-Key header, falling back to transaction_id. Repeated keys replay the original status and body.",
                 "consumes": [
                     "application/json"
                 ],
@@ -34,6 +408,12 @@ const docTemplate = `{
                 ],
                 "summary": "Process Payment",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Idempotency key; used as transaction_id when the body omits it",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
                     {
                         "description": "Payment request",
                         "name": "payment",
@@ -46,7 +426,13 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Payment processed successfully",
+                        "description": "Payment processed successfully, or created pending authorization for manual capture",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Asynchronous payment queued, pending until a worker processes it",
                         "schema": {
                             "$ref": "#/definitions/usecase.PaymentResponse"
                         }
@@ -57,29 +443,1923 @@ const docTemplate = `{
                             "$ref": "#/definitions/usecase.PaymentResponse"
                         }
                     },
+                    "402": {
+                        "description": "Declined by the payment processor, or insufficient funds in the wallet of a wallet payment",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict - idempotency key reused with a different payload or still in progress",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
                             "$ref": "#/definitions/usecase.PaymentResponse"
                         }
+                    },
+                    "501": {
+                        "description": "Asynchronous processing requires a store shared with workers",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Payment processor failed; nothing was charged",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "A dependency is failing; retry after the Retry-After header's seconds",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Payment processor timed out; retry with the same transaction_id",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/payments": {
+            "get": {
+                "description": "Lists payments newest first, optionally filtered. Pages are linked by next_cursor, which stays valid while payments are added.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Payments"
+                ],
+                "summary": "List Payments",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only payments of this user",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "pending",
+                            "authorized",
+                            "captured",
+                            "voided",
+                            "refunded",
+                            "partially_refunded",
+                            "failed",
+                            "settled",
+                            "disputed",
+                            "charged_back"
+                        ],
+                        "type": "string",
+                        "description": "Only payments in this status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only payments in this ISO 4217 currency",
+                        "name": "currency",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Smallest amount as a decimal string, inclusive; requires currency",
+                        "name": "min_amount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Largest amount as a decimal string, inclusive; requires currency",
+                        "name": "max_amount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Earliest creation time (RFC 3339), inclusive",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Latest creation time (RFC 3339), exclusive",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size, 1 to 200 (default 50)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Page of payments",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter, cursor or limit",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "A dependency is failing; retry after the Retry-After header's seconds",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/payments/{transaction_id}": {
+            "get": {
+                "description": "Returns a payment with its full status history and refunds",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Payments"
+                ],
+                "summary": "Get Payment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Transaction ID",
+                        "name": "transaction_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Payment",
+                        "schema": {
+                            "$ref": "#/definitions/entity.Payment"
+                        }
+                    },
+                    "404": {
+                        "description": "Payment not found",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "A dependency is failing; retry after the Retry-After header's seconds",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/payments/{transaction_id}/authorize": {
+            "post": {
+                "description": "Places a hold for the full amount of a payment created with capture_method \"manual\". Authorizing an already authorized payment returns it unchanged.\nThe authorization lapses if it is not captured within the configured window.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Payments"
+                ],
+                "summary": "Authorize Payment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Transaction ID",
+                        "name": "transaction_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Idempotency key; repeated keys replay the original response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Payment authorized",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentResponse"
+                        }
+                    },
+                    "402": {
+                        "description": "Declined by the payment processor",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Payment not found",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Payment is not pending",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Payment processor failed",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "A dependency is failing; retry after the Retry-After header's seconds",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Payment processor timed out",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/payments/{transaction_id}/capture": {
+            "post": {
+                "description": "Captures an authorized payment in full, or partially when an amount is given; the uncaptured remainder is released.\nRepeating a capture of the same amount returns the payment unchanged. Capturing a lapsed authorization voids it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Payments"
+                ],
+                "summary": "Capture Payment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Transaction ID",
+                        "name": "transaction_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Idempotency key; repeated keys replay the original response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Capture request; omit to capture the full amount",
+                        "name": "capture",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.CaptureRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Payment captured",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid capture amount",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Payment not found",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Payment is not authorized or the authorization has expired",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Payment processor failed",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "A dependency is failing; retry after the Retry-After header's seconds",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Payment processor timed out",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/payments/{transaction_id}/refunds": {
+            "post": {
+                "description": "Refunds part or all of a captured payment. Several partial refunds may be made, but together they never exceed the captured amount.\nEach refund needs its own idempotency key, from the body or the Idempotency-Key header; retrying a key returns the original refund.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Payments"
+                ],
+                "summary": "Refund Payment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Transaction ID",
+                        "name": "transaction_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Refund idempotency key; used when the body omits idempotency_key",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Refund request",
+                        "name": "refund",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/usecase.RefundRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Refund processed",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.RefundResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid amount, reason or missing idempotency key",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.RefundResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Payment not found",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.RefundResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Payment is not captured, or the idempotency key was used for a different refund",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.RefundResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.RefundResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Payment processor failed",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.RefundResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "A dependency is failing; retry after the Retry-After header's seconds",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.RefundResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Payment processor timed out",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.RefundResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/payments/{transaction_id}/void": {
+            "post": {
+                "description": "Releases the hold of an authorized payment without capturing it. Voiding an already voided payment returns it unchanged.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Payments"
+                ],
+                "summary": "Void Payment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Transaction ID",
+                        "name": "transaction_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Idempotency key; repeated keys replay the original response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Payment voided",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Payment not found",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Payment is not authorized",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "A dependency is failing; retry after the Retry-After header's seconds",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/routing/decisions": {
+            "get": {
+                "description": "Lists the most recent routing decisions, newest first: the candidate processors of each authorization and the outcome of every attempt.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Routing"
+                ],
+                "summary": "List Routing Decisions",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of decisions (default 50)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Routing decisions",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/gateway.Decision"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid limit",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/routing/decisions/{transaction_id}": {
+            "get": {
+                "description": "Returns the latest routing decision for a transaction",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Routing"
+                ],
+                "summary": "Get Routing Decision",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Transaction ID",
+                        "name": "transaction_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Routing decision",
+                        "schema": {
+                            "$ref": "#/definitions/gateway.Decision"
+                        }
+                    },
+                    "404": {
+                        "description": "No routing decision for the transaction",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/routing/processors": {
+            "get": {
+                "description": "Lists every payment processor with the health the router tracks for it. Unhealthy processors are tried last until their cooldown passes.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Routing"
+                ],
+                "summary": "Get Processor Health",
+                "responses": {
+                    "200": {
+                        "description": "Processor health",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/gateway.ProcessorHealth"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/transfers": {
+            "post": {
+                "description": "Moves funds from one user's wallet to another's with the same idempotency as POST /pay. Retrying the same transaction_id will not move funds twice; reusing it with a different payload is rejected with 409.\nThe idempotency key is taken from the Idempotency-Key header, falling back to transaction_id. Repeated keys replay the original status and body.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Transfers"
+                ],
+                "summary": "Transfer Funds",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Idempotency key; used as transaction_id when the body omits it",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Transfer request",
+                        "name": "transfer",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/usecase.TransferRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Transfer completed",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.TransferResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - validation error or transfer to the same user",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.TransferResponse"
+                        }
+                    },
+                    "402": {
+                        "description": "Insufficient funds in the sender's wallet",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.TransferResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict - idempotency key reused with a different payload or still in progress",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.TransferResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Per-transfer or daily transfer limit exceeded, or transfers are not allowed in the currency",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.TransferResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.TransferResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{user_id}/payments": {
+            "get": {
+                "description": "Lists one user's payments newest first. Accepts the same filters and cursor pagination as GET /payments.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "List User Payments",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "pending",
+                            "authorized",
+                            "captured",
+                            "voided",
+                            "refunded",
+                            "partially_refunded",
+                            "failed",
+                            "settled",
+                            "disputed",
+                            "charged_back"
+                        ],
+                        "type": "string",
+                        "description": "Only payments in this status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only payments in this ISO 4217 currency",
+                        "name": "currency",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Smallest amount as a decimal string, inclusive; requires currency",
+                        "name": "min_amount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Largest amount as a decimal string, inclusive; requires currency",
+                        "name": "max_amount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Earliest creation time (RFC 3339), inclusive",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Latest creation time (RFC 3339), exclusive",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size, 1 to 200 (default 50)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Page of payments",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter, cursor or limit",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{user_id}/statements/{month}": {
+            "get": {
+                "description": "Summarizes a user's payments and refunds for a calendar month (UTC): every entry plus captured, refunded and net totals per currency.\nPayments count in the month they were created; refunds in the month they were made.\nThe format is chosen with the format parameter, or else the Accept header (text/csv, text/plain); JSON is the default.",
+                "produces": [
+                    "application/json",
+                    "text/csv",
+                    "text/plain"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Get Monthly Statement",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Month as YYYY-MM",
+                        "name": "month",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "csv",
+                            "text"
+                        ],
+                        "type": "string",
+                        "description": "Response format",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Statement",
+                        "schema": {
+                            "$ref": "#/definitions/entity.Statement"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid month or format",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.PaymentResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{user_id}/transfers": {
+            "get": {
+                "description": "Lists the transfers a user sent and received, newest first. With the \"with\" parameter only the transfers between the two users are listed, which is the same history both parties see.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Transfers"
+                ],
+                "summary": "Get Transfer History",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only transfers with this user",
+                        "name": "with",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Transfer history",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.TransferHistoryResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.TransferResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{user_id}/wallet": {
+            "get": {
+                "description": "Returns a user's wallet balances per currency and every transaction, oldest first. Users who never used their wallet have an empty one.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Wallets"
+                ],
+                "summary": "Get Wallet",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Wallet",
+                        "schema": {
+                            "$ref": "#/definitions/entity.Wallet"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.WalletResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{user_id}/wallet/debits": {
+            "post": {
+                "description": "Withdraws funds from a user's wallet. Fails with 402 when the balance is too low. Each debit needs its own idempotency key; retrying a key returns the original debit.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Wallets"
+                ],
+                "summary": "Debit Wallet",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Debit idempotency key; used when the body omits idempotency_key",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Debit request",
+                        "name": "debit",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/usecase.WalletRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Wallet debited",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.WalletResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid amount, currency or missing idempotency key",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.WalletResponse"
+                        }
+                    },
+                    "402": {
+                        "description": "Insufficient funds",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.WalletResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Idempotency key used for a different amount",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.WalletResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.WalletResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{user_id}/wallet/top-ups": {
+            "post": {
+                "description": "Adds funds to a user's wallet. Each top-up needs its own idempotency key, from the body or the Idempotency-Key header; retrying a key returns the original top-up.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Wallets"
+                ],
+                "summary": "Top Up Wallet",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Top-up idempotency key; used when the body omits idempotency_key",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Top-up request",
+                        "name": "top_up",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/usecase.WalletRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Wallet topped up",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.WalletResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid amount, currency or missing idempotency key",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.WalletResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Idempotency key used for a different amount",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.WalletResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.WalletResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{provider}": {
+            "post": {
+                "description": "Receives an asynchronous notification from a payment processor: a settlement, a late decline, or a dispute being opened, won or lost.\nThe body must be signed with the processor's secret in the Webhook-Signature header. Each event is applied once however often it is delivered;\nevents the payment's status no longer allows are acknowledged with applied false. Any status other than 2xx asks the processor to send the event again.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Processors"
+                ],
+                "summary": "Receive Processor Callback",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Processor name",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "t=\u003cunix seconds\u003e,v1=\u003chex HMAC-SHA256 of the timestamp, a dot and the body\u003e",
+                        "name": "Webhook-Signature",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Processor event",
+                        "name": "callback",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/gateway.Callback"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Event applied, already applied, or not applicable",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.ProviderEventResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Not an event of a known type",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing, invalid or expired signature",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown processor, or no payment charged by the processor",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.ProviderEventResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.ProviderEventResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "A dependency is failing; retry after the Retry-After header's seconds",
+                        "schema": {
+                            "$ref": "#/definitions/usecase.ProviderEventResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "breaker.State": {
+            "type": "string",
+            "enum": [
+                "closed",
+                "open",
+                "half_open"
+            ],
+            "x-enum-varnames": [
+                "Closed",
+                "Open",
+                "HalfOpen"
+            ]
+        },
+        "breaker.Status": {
+            "type": "object",
+            "properties": {
+                "consecutive_failures": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "opened_at": {
+                    "type": "string"
+                },
+                "state": {
+                    "$ref": "#/definitions/breaker.State"
+                }
+            }
+        },
+        "entity.Job": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "description": "Deliveries so far; a lease is identified by the attempt it started",
+                    "type": "integer"
+                },
+                "available_at": {
+                    "description": "When the job is next delivered: its retry time or the end of its lease",
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "description": "Why the latest attempt failed",
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "transaction_id": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "entity.MoneyJSON": {
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "description": "ISO 4217 currency code",
+                    "type": "string",
+                    "example": "USD"
+                },
+                "value": {
+                    "description": "Amount in major units as a decimal string",
+                    "type": "string",
+                    "example": "99.99"
+                }
+            }
+        },
+        "entity.Payment": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "$ref": "#/definitions/entity.MoneyJSON"
+                },
+                "authorization_expires_at": {
+                    "description": "When an uncaptured authorization lapses",
+                    "type": "string"
+                },
+                "captured_amount": {
+                    "description": "Amount actually captured, at most Amount",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/entity.MoneyJSON"
+                        }
+                    ]
+                },
+                "card_last4": {
+                    "description": "Last four digits of the card, when its token ends in them",
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "merchant_id": {
+                    "description": "Merchant the payment is made to, used to route card payments",
+                    "type": "string"
+                },
+                "payment_method": {
+                    "description": "PaymentMethodCard or PaymentMethodWallet",
+                    "type": "string"
+                },
+                "pending_action": {
+                    "description": "Processor call claimed and not yet recorded",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/entity.PendingAction"
+                        }
+                    ]
+                },
+                "processor": {
+                    "description": "Name of the processor a card payment is authorized at, stored before it is called",
+                    "type": "string"
+                },
+                "processor_reference": {
+                    "description": "The processor's ID of a card payment, once authorized",
+                    "type": "string"
+                },
+                "queued": {
+                    "description": "Waiting for a worker to process an asynchronous request",
+                    "type": "boolean"
+                },
+                "refunds": {
+                    "description": "Refunds of the captured amount, oldest first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entity.Refund"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                },
+                "status_history": {
+                    "description": "Every status change, oldest first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entity.StatusChange"
+                    }
+                },
+                "transaction_id": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                },
+                "version": {
+                    "description": "Incremented on every update, for optimistic concurrency",
+                    "type": "integer"
+                }
+            }
+        },
+        "entity.PendingAction": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "description": "ActionCapture or ActionVoid",
+                    "type": "string"
+                },
+                "actor": {
+                    "type": "string"
+                },
+                "amount": {
+                    "description": "Amount to capture, or the authorized amount a void releases",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/entity.MoneyJSON"
+                        }
+                    ]
+                },
+                "claimed_at": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "entity.Refund": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "$ref": "#/definitions/entity.MoneyJSON"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "idempotency_key": {
+                    "description": "Client key that makes retrying the refund safe",
+                    "type": "string"
+                },
+                "reason": {
+                    "description": "One of the RefundReason codes",
+                    "type": "string"
+                },
+                "refund_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "entity.Statement": {
+            "type": "object",
+            "properties": {
+                "entries": {
+                    "description": "Payments and refunds of the month, oldest first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entity.StatementEntry"
+                    }
+                },
+                "month": {
+                    "description": "Calendar month as YYYY-MM",
+                    "type": "string"
+                },
+                "period_end": {
+                    "description": "Start of the next month, exclusive",
+                    "type": "string"
+                },
+                "period_start": {
+                    "description": "Start of the month, inclusive",
+                    "type": "string"
+                },
+                "totals": {
+                    "description": "One total per currency, ordered by currency code",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entity.StatementTotal"
+                    }
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "entity.StatementEntry": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "Captured amount for payments, refunded amount for refunds",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/entity.MoneyJSON"
+                        }
+                    ]
+                },
+                "at": {
+                    "type": "string"
+                },
+                "refund_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "Current payment status, for payment entries",
+                    "type": "string"
+                },
+                "transaction_id": {
+                    "type": "string"
+                },
+                "type": {
+                    "description": "EntryPayment or EntryRefund",
+                    "type": "string"
+                }
+            }
+        },
+        "entity.StatementTotal": {
+            "type": "object",
+            "properties": {
+                "captured": {
+                    "description": "Captured by payments created in the month",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/entity.MoneyJSON"
+                        }
+                    ]
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "net": {
+                    "description": "Captured minus refunded",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/entity.MoneyJSON"
+                        }
+                    ]
+                },
+                "payments": {
+                    "description": "Number of payments created in the month",
+                    "type": "integer"
+                },
+                "refunded": {
+                    "description": "Refunded during the month, whenever the payment was made",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/entity.MoneyJSON"
+                        }
+                    ]
+                }
+            }
+        },
+        "entity.StatusChange": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "type": "string"
+                },
+                "at": {
+                    "type": "string"
+                },
+                "event_id": {
+                    "description": "ID of the processor event that made the change, if any",
+                    "type": "string"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "entity.Wallet": {
+            "type": "object",
+            "properties": {
+                "balances": {
+                    "description": "Balance per currency code",
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/entity.MoneyJSON"
+                    }
+                },
+                "transactions": {
+                    "description": "Every balance change, oldest first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entity.WalletTransaction"
+                    }
+                },
+                "user_id": {
+                    "type": "string"
+                },
+                "version": {
+                    "description": "Incremented on every save, for optimistic concurrency",
+                    "type": "integer"
+                }
+            }
+        },
+        "entity.WalletTransaction": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "Amount moved, always positive",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/entity.MoneyJSON"
+                        }
+                    ]
+                },
+                "balance": {
+                    "description": "Balance in the same currency after the transaction",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/entity.MoneyJSON"
+                        }
+                    ]
+                },
+                "counterparty": {
+                    "description": "The other user of a transfer",
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "description": "Unique within the wallet; a transaction ID is never applied twice",
+                    "type": "string"
+                },
+                "reference": {
+                    "description": "Transaction ID of the payment or transfer",
+                    "type": "string"
+                },
+                "type": {
+                    "description": "WalletTopUp, WalletDebit, WalletPayment, WalletRefund, WalletTransferOut or WalletTransferIn",
+                    "type": "string"
+                }
+            }
+        },
+        "gateway.Attempt": {
+            "type": "object",
+            "properties": {
+                "detail": {
+                    "description": "Decline code or error message",
+                    "type": "string"
+                },
+                "outcome": {
+                    "type": "string"
+                },
+                "processor": {
+                    "type": "string"
+                },
+                "unhealthy": {
+                    "description": "The processor was unhealthy when tried, so it was tried last",
+                    "type": "boolean"
+                }
+            }
+        },
+        "gateway.Callback": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "string"
+                },
+                "id": {
+                    "description": "Unique per event; redeliveries repeat it",
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "reference": {
+                    "description": "The processor's reference of the payment, such as sim_txn123",
+                    "type": "string"
+                },
+                "transaction_id": {
+                    "description": "Transaction ID the payment was authorized with",
+                    "type": "string"
+                },
+                "type": {
+                    "description": "Such as charge.settled or charge.dispute.created",
+                    "type": "string"
+                }
+            }
+        },
+        "gateway.Decision": {
+            "type": "object",
+            "properties": {
+                "at": {
+                    "type": "string"
+                },
+                "attempts": {
+                    "description": "Calls made, in order",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/gateway.Attempt"
+                    }
+                },
+                "candidates": {
+                    "description": "Matching processors in the order they were tried",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "processor": {
+                    "description": "Processor whose answer was final: it approved or hard declined",
+                    "type": "string"
+                },
+                "transaction_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "gateway.ProcessorHealth": {
+            "type": "object",
+            "properties": {
+                "consecutive_failures": {
+                    "type": "integer"
+                },
+                "healthy": {
+                    "type": "boolean"
+                },
+                "last_failure": {
+                    "type": "string"
+                },
+                "processor": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.AccountBalance": {
+            "type": "object",
+            "properties": {
+                "account": {
+                    "type": "string"
+                },
+                "balance": {
+                    "$ref": "#/definitions/entity.MoneyJSON"
+                }
+            }
+        },
+        "handler.HealthResponse": {
+            "type": "object",
+            "properties": {
+                "dependencies": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/breaker.Status"
+                    }
+                },
+                "service": {
+                    "type": "string",
+                    "example": "payment-service"
+                },
+                "status": {
+                    "description": "\"degraded\" while any breaker is not closed",
+                    "type": "string",
+                    "enum": [
+                        "ok",
+                        "degraded"
+                    ],
+                    "example": "ok"
+                }
+            }
+        },
+        "handler.RegisterEndpointRequest": {
+            "type": "object",
+            "properties": {
+                "event_types": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "payment.captured",
+                        "payment.refunded"
+                    ]
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://merchant.example.com/webhooks"
+                }
+            }
+        },
+        "ledger.AccountBalance": {
+            "type": "object",
+            "properties": {
+                "account": {
+                    "type": "string"
+                },
+                "balance": {
+                    "description": "Debit minus credit for asset and expense accounts, credit minus debit otherwise",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/entity.MoneyJSON"
+                        }
+                    ]
+                },
+                "credit": {
+                    "description": "Total credited",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/entity.MoneyJSON"
+                        }
+                    ]
+                },
+                "debit": {
+                    "description": "Total debited",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/entity.MoneyJSON"
+                        }
+                    ]
+                },
+                "type": {
+                    "$ref": "#/definitions/ledger.AccountType"
+                }
+            }
+        },
+        "ledger.AccountType": {
+            "type": "string",
+            "enum": [
+                "asset",
+                "liability",
+                "equity",
+                "revenue",
+                "expense"
+            ],
+            "x-enum-varnames": [
+                "Asset",
+                "Liability",
+                "Equity",
+                "Revenue",
+                "Expense"
+            ]
+        },
+        "ledger.TrialBalance": {
+            "type": "object",
+            "properties": {
+                "accounts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/ledger.AccountBalance"
+                    }
+                },
+                "balanced": {
+                    "description": "Debits equal credits in every currency",
+                    "type": "boolean"
+                },
+                "totals": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/ledger.TrialTotal"
+                    }
+                }
+            }
+        },
+        "ledger.TrialTotal": {
+            "type": "object",
+            "properties": {
+                "credit": {
+                    "$ref": "#/definitions/entity.MoneyJSON"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "debit": {
+                    "$ref": "#/definitions/entity.MoneyJSON"
+                }
+            }
+        },
+        "usecase.CaptureRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "Amount to capture as a decimal string; omit to capture the full authorization",
+                    "type": "string",
+                    "example": "50.00"
+                }
+            }
+        },
+        "usecase.PaymentEvent": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "description": "When the status changed",
+                    "type": "string",
+                    "example": "2025-01-01T10:00:00Z"
+                },
+                "data": {
+                    "description": "The payment and its status change",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/usecase.PaymentEventData"
+                        }
+                    ]
+                },
+                "id": {
+                    "description": "Unique per status change, so receivers can drop repeated deliveries",
+                    "type": "string",
+                    "example": "evt_txn-456_2"
+                },
+                "type": {
+                    "description": "\"payment.\" followed by the new status",
+                    "type": "string",
+                    "example": "payment.captured"
+                }
+            }
+        },
+        "usecase.PaymentEventData": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "description": "Who made the change",
+                    "type": "string",
+                    "example": "system"
+                },
+                "amount": {
+                    "description": "Payment amount as a decimal string",
+                    "type": "string",
+                    "example": "99.99"
+                },
+                "currency": {
+                    "description": "ISO 4217 currency code",
+                    "type": "string",
+                    "example": "USD"
+                },
+                "merchant_id": {
+                    "description": "Merchant the payment is made to",
+                    "type": "string",
+                    "example": "merchant-1"
+                },
+                "previous_status": {
+                    "description": "The status before the change; empty for a new payment",
+                    "type": "string",
+                    "example": "authorized"
+                },
+                "reason": {
+                    "description": "Why the status changed",
+                    "type": "string",
+                    "example": "payment captured"
+                },
+                "status": {
+                    "description": "The new status",
+                    "type": "string",
+                    "example": "captured"
+                },
+                "transaction_id": {
+                    "description": "Transaction ID",
+                    "type": "string",
+                    "example": "txn-456"
+                },
+                "user_id": {
+                    "description": "User ID",
+                    "type": "string",
+                    "example": "user123"
+                }
+            }
+        },
+        "usecase.PaymentListResponse": {
+            "type": "object",
+            "properties": {
+                "next_cursor": {
+                    "description": "Cursor of the next page; absent on the last page",
+                    "type": "string",
+                    "example": "MjAyNS0wMS0wMVQxMDo"
+                },
+                "payments": {
+                    "description": "Payments, newest first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entity.Payment"
                     }
                 }
             }
-        }
-    },
-    "definitions": {
+        },
         "usecase.PaymentRequest": {
             "type": "object",
             "required": [
                 "amount",
+                "currency",
                 "transaction_id",
                 "user_id"
             ],
             "properties": {
                 "amount": {
-                    "description": "Payment amount (must be greater than 0)",
-                    "type": "number",
-                    "example": 99.99
+                    "description": "Payment amount as a decimal string (must be greater than 0)",
+                    "type": "string",
+                    "example": "99.99"
+                },
+                "capture_method": {
+                    "description": "\"automatic\" (default) captures immediately; \"manual\" leaves the payment pending for authorize/capture",
+                    "type": "string",
+                    "enum": [
+                        "automatic",
+                        "manual"
+                    ],
+                    "example": "automatic"
+                },
+                "card_token": {
+                    "description": "Processor token of the card to charge; the simulated processor also accepts its test card numbers",
+                    "type": "string",
+                    "example": "tok_visa"
+                },
+                "currency": {
+                    "description": "ISO 4217 currency code",
+                    "type": "string",
+                    "example": "USD"
+                },
+                "merchant_id": {
+                    "description": "Merchant the payment is made to; card payments may be routed by merchant",
+                    "type": "string",
+                    "example": "merchant-1"
+                },
+                "payment_method": {
+                    "description": "\"card\" (default) or \"wallet\" to pay from the user's wallet balance; wallet payments are captured automatically",
+                    "type": "string",
+                    "enum": [
+                        "card",
+                        "wallet"
+                    ],
+                    "example": "card"
+                },
+                "processing_mode": {
+                    "description": "\"sync\" (default) processes the payment before answering; \"async\" queues it for a worker and answers 202 with the pending payment",
+                    "type": "string",
+                    "enum": [
+                        "sync",
+                        "async"
+                    ],
+                    "example": "sync"
                 },
                 "transaction_id": {
                     "description": "Unique transaction ID for idempotency",
@@ -97,19 +2377,59 @@ const docTemplate = `{
             "type": "object",
             "properties": {
                 "amount": {
-                    "description": "Payment amount",
-                    "type": "number",
-                    "example": 99.99
+                    "description": "Payment amount as a decimal string",
+                    "type": "string",
+                    "example": "99.99"
+                },
+                "authorization_expires_at": {
+                    "description": "When an uncaptured authorization lapses",
+                    "type": "string",
+                    "example": "2025-01-08T10:00:00Z"
+                },
+                "captured_amount": {
+                    "description": "Amount captured so far as a decimal string",
+                    "type": "string",
+                    "example": "99.99"
+                },
+                "currency": {
+                    "description": "ISO 4217 currency code",
+                    "type": "string",
+                    "example": "USD"
                 },
                 "message": {
                     "description": "Status message",
                     "type": "string",
                     "example": "Payment processed successfully"
                 },
+                "payment_method": {
+                    "description": "How the payment is paid (card or wallet)",
+                    "type": "string",
+                    "example": "card"
+                },
+                "processor": {
+                    "description": "Processor a card payment is authorized at, stored before it is called",
+                    "type": "string",
+                    "example": "primary"
+                },
+                "processor_reference": {
+                    "description": "The processor's ID of a card payment",
+                    "type": "string",
+                    "example": "primary:sim_txn-456"
+                },
+                "queued": {
+                    "description": "Whether the payment waits for a worker to process it",
+                    "type": "boolean",
+                    "example": false
+                },
+                "refunded_amount": {
+                    "description": "Amount refunded so far as a decimal string",
+                    "type": "string",
+                    "example": "25.00"
+                },
                 "status": {
-                    "description": "Payment status (success, failed)",
+                    "description": "Payment status (pending, authorized, captured, voided, refunded, partially_refunded, failed, settled, disputed, charged_back)",
                     "type": "string",
-                    "example": "success"
+                    "example": "captured"
                 },
                 "transaction_id": {
                     "description": "Transaction ID",
@@ -122,6 +2442,419 @@ const docTemplate = `{
                     "example": "user123"
                 }
             }
+        },
+        "usecase.ProviderEventResponse": {
+            "type": "object",
+            "properties": {
+                "applied": {
+                    "description": "Whether the event changed the payment",
+                    "type": "boolean",
+                    "example": true
+                },
+                "event_id": {
+                    "description": "The processor's event ID",
+                    "type": "string",
+                    "example": "evt_123"
+                },
+                "message": {
+                    "description": "Status message",
+                    "type": "string",
+                    "example": "Payment status updated"
+                },
+                "status": {
+                    "description": "Payment status after the event",
+                    "type": "string",
+                    "example": "settled"
+                },
+                "transaction_id": {
+                    "description": "Transaction ID of the payment",
+                    "type": "string",
+                    "example": "txn-456"
+                }
+            }
+        },
+        "usecase.RefundRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "Amount to refund as a decimal string; omit to refund everything not yet refunded",
+                    "type": "string",
+                    "example": "25.00"
+                },
+                "idempotency_key": {
+                    "description": "Unique key per refund; retrying with the same key never refunds twice",
+                    "type": "string",
+                    "example": "refund-789"
+                },
+                "reason": {
+                    "description": "Refund reason code",
+                    "type": "string",
+                    "enum": [
+                        "requested_by_customer",
+                        "duplicate",
+                        "fraudulent",
+                        "other"
+                    ],
+                    "example": "requested_by_customer"
+                }
+            }
+        },
+        "usecase.RefundResponse": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "Refunded amount as a decimal string",
+                    "type": "string",
+                    "example": "25.00"
+                },
+                "created_at": {
+                    "description": "When the refund was made",
+                    "type": "string",
+                    "example": "2025-01-01T10:00:00Z"
+                },
+                "currency": {
+                    "description": "ISO 4217 currency code",
+                    "type": "string",
+                    "example": "USD"
+                },
+                "idempotency_key": {
+                    "description": "Idempotency key of the refund",
+                    "type": "string",
+                    "example": "refund-789"
+                },
+                "message": {
+                    "description": "Status message",
+                    "type": "string",
+                    "example": "Refund processed successfully"
+                },
+                "payment_status": {
+                    "description": "Status of the payment after the refund",
+                    "type": "string",
+                    "example": "partially_refunded"
+                },
+                "reason": {
+                    "description": "Refund reason code",
+                    "type": "string",
+                    "example": "requested_by_customer"
+                },
+                "refund_id": {
+                    "description": "Refund ID",
+                    "type": "string",
+                    "example": "txn-456-refund-1"
+                },
+                "refunded_amount": {
+                    "description": "Total refunded for the payment so far",
+                    "type": "string",
+                    "example": "25.00"
+                },
+                "transaction_id": {
+                    "description": "Transaction ID of the refunded payment",
+                    "type": "string",
+                    "example": "txn-456"
+                }
+            }
+        },
+        "usecase.TransferHistoryEntry": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "$ref": "#/definitions/entity.MoneyJSON"
+                },
+                "at": {
+                    "type": "string"
+                },
+                "balance": {
+                    "description": "The user's balance in the currency after the transfer",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/entity.MoneyJSON"
+                        }
+                    ]
+                },
+                "counterparty": {
+                    "description": "The other user",
+                    "type": "string",
+                    "example": "user456"
+                },
+                "direction": {
+                    "description": "Whether the user sent or received the funds",
+                    "type": "string",
+                    "enum": [
+                        "sent",
+                        "received"
+                    ],
+                    "example": "sent"
+                },
+                "transaction_id": {
+                    "type": "string",
+                    "example": "transfer-789"
+                }
+            }
+        },
+        "usecase.TransferHistoryResponse": {
+            "type": "object",
+            "properties": {
+                "counterparty": {
+                    "description": "Only transfers with this user, when set",
+                    "type": "string",
+                    "example": "user456"
+                },
+                "transfers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/usecase.TransferHistoryEntry"
+                    }
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "user123"
+                }
+            }
+        },
+        "usecase.TransferRequest": {
+            "type": "object",
+            "required": [
+                "amount",
+                "currency",
+                "from_user_id",
+                "to_user_id",
+                "transaction_id"
+            ],
+            "properties": {
+                "amount": {
+                    "description": "Amount as a decimal string (must be greater than 0)",
+                    "type": "string",
+                    "example": "25.00"
+                },
+                "currency": {
+                    "description": "ISO 4217 currency code",
+                    "type": "string",
+                    "example": "USD"
+                },
+                "from_user_id": {
+                    "description": "Sender, whose wallet is debited",
+                    "type": "string",
+                    "example": "user123"
+                },
+                "to_user_id": {
+                    "description": "Recipient, whose wallet is credited",
+                    "type": "string",
+                    "example": "user456"
+                },
+                "transaction_id": {
+                    "description": "Unique transaction ID for idempotency",
+                    "type": "string",
+                    "example": "transfer-789"
+                }
+            }
+        },
+        "usecase.TransferResponse": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "Amount as a decimal string",
+                    "type": "string",
+                    "example": "25.00"
+                },
+                "completed_at": {
+                    "description": "When the funds moved",
+                    "type": "string"
+                },
+                "created_at": {
+                    "description": "When the transfer was first requested",
+                    "type": "string"
+                },
+                "currency": {
+                    "description": "ISO 4217 currency code",
+                    "type": "string",
+                    "example": "USD"
+                },
+                "from_user_id": {
+                    "description": "Sender",
+                    "type": "string",
+                    "example": "user123"
+                },
+                "message": {
+                    "description": "Status message",
+                    "type": "string",
+                    "example": "Transfer completed"
+                },
+                "status": {
+                    "description": "Transfer status (pending, completed, declined, failed)",
+                    "type": "string",
+                    "example": "completed"
+                },
+                "to_user_id": {
+                    "description": "Recipient",
+                    "type": "string",
+                    "example": "user456"
+                },
+                "transaction_id": {
+                    "description": "Transaction ID",
+                    "type": "string",
+                    "example": "transfer-789"
+                }
+            }
+        },
+        "usecase.WalletRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "Amount as a decimal string (must be greater than 0)",
+                    "type": "string",
+                    "example": "50.00"
+                },
+                "currency": {
+                    "description": "ISO 4217 currency code",
+                    "type": "string",
+                    "example": "USD"
+                },
+                "idempotency_key": {
+                    "description": "Unique key per operation; retrying with the same key never applies it twice",
+                    "type": "string",
+                    "example": "top-up-123"
+                }
+            }
+        },
+        "usecase.WalletResponse": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "Amount as a decimal string",
+                    "type": "string",
+                    "example": "50.00"
+                },
+                "balance": {
+                    "description": "Wallet balance in the currency after the operation",
+                    "type": "string",
+                    "example": "150.00"
+                },
+                "created_at": {
+                    "description": "When the operation was applied",
+                    "type": "string"
+                },
+                "currency": {
+                    "description": "ISO 4217 currency code",
+                    "type": "string",
+                    "example": "USD"
+                },
+                "message": {
+                    "description": "Status message",
+                    "type": "string",
+                    "example": "Wallet topped up"
+                },
+                "type": {
+                    "description": "Transaction type (top_up or debit)",
+                    "type": "string",
+                    "example": "top_up"
+                },
+                "user_id": {
+                    "description": "Wallet owner",
+                    "type": "string",
+                    "example": "user123"
+                },
+                "wallet_transaction_id": {
+                    "description": "ID of the wallet transaction",
+                    "type": "string",
+                    "example": "top_up:top-up-123"
+                }
+            }
+        },
+        "webhook.Delivery": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "description": "Attempts since the delivery was created or last replayed",
+                    "type": "integer",
+                    "example": 1
+                },
+                "created_at": {
+                    "type": "string",
+                    "example": "2025-01-01T10:00:00Z"
+                },
+                "delivered_at": {
+                    "type": "string",
+                    "example": "2025-01-01T10:00:31Z"
+                },
+                "endpoint_id": {
+                    "type": "string",
+                    "example": "we_3f9a2c71d04b8e56"
+                },
+                "event": {
+                    "$ref": "#/definitions/usecase.PaymentEvent"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "wd_8c41d07e2f9b3a65"
+                },
+                "last_attempt_at": {
+                    "type": "string",
+                    "example": "2025-01-01T10:00:00Z"
+                },
+                "last_error": {
+                    "type": "string",
+                    "example": "endpoint answered 503"
+                },
+                "last_status_code": {
+                    "description": "HTTP status of the last answer",
+                    "type": "integer",
+                    "example": 503
+                },
+                "merchant_id": {
+                    "type": "string",
+                    "example": "merchant-1"
+                },
+                "next_attempt_at": {
+                    "description": "When a pending delivery is attempted next",
+                    "type": "string",
+                    "example": "2025-01-01T10:00:30Z"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "pending",
+                        "delivered",
+                        "dead"
+                    ],
+                    "example": "pending"
+                }
+            }
+        },
+        "webhook.Endpoint": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string",
+                    "example": "2025-01-01T10:00:00Z"
+                },
+                "event_types": {
+                    "description": "Event types sent to the endpoint; all when empty",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "payment.captured"
+                    ]
+                },
+                "id": {
+                    "type": "string",
+                    "example": "we_3f9a2c71d04b8e56"
+                },
+                "merchant_id": {
+                    "type": "string",
+                    "example": "merchant-1"
+                },
+                "secret": {
+                    "description": "Signing secret; only returned when the endpoint is registered",
+                    "type": "string",
+                    "example": "whsec_5f2b..."
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://merchant.example.com/webhooks"
+                }
+            }
         }
     }
 }`
@@ -0,0 +1,233 @@
+// Package breaker guards calls to slow or failing dependencies with circuit breakers.
+// A breaker gives every call a deadline and, after too many failures in a row, rejects
+// calls straight away until the dependency has had time to recover.
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	ErrOpen    = errors.New("circuit breaker is open")
+	ErrTimeout = errors.New("call exceeded its deadline")
+)
+
+// State is the state of a circuit breaker
+type State string
+
+// Breaker states
+const (
+	// Closed lets every call through
+	Closed State = "closed"
+	// Open rejects every call until the open timeout has passed
+	Open State = "open"
+	// HalfOpen lets a single trial call through; its outcome closes or reopens the breaker
+	HalfOpen State = "half_open"
+)
+
+// Default settings
+const (
+	DefaultFailureThreshold = 5
+	DefaultOpenTimeout      = 30 * time.Second
+)
+
+// OpenError is returned for calls rejected by an open breaker. It matches ErrOpen.
+type OpenError struct {
+	Name       string
+	RetryAfter time.Duration // How long until the breaker lets a trial call through
+}
+
+func (e *OpenError) Error() string {
+	return fmt.Sprintf("%s: %s (retry after %s)", ErrOpen, e.Name, e.RetryAfter)
+}
+
+// Is makes errors.Is(err, ErrOpen) match any OpenError
+func (e *OpenError) Is(target error) bool {
+	return target == ErrOpen
+}
+
+// Settings configures a breaker. Zero values select the defaults.
+type Settings struct {
+	FailureThreshold int           // Failures in a row that open the breaker
+	OpenTimeout      time.Duration // How long the breaker stays open before a trial call
+	CallTimeout      time.Duration // Deadline of every call; zero for none
+	// IsFailure reports whether an error returned by a call counts against the dependency.
+	// By default every error does; ErrTimeout always does.
+	IsFailure func(err error) bool
+}
+
+// Status is a snapshot of a breaker, as shown by the health check
+type Status struct {
+	Name                string     `json:"name"`
+	State               State      `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+}
+
+// Breaker is a circuit breaker around one dependency. It is safe for concurrent use.
+type Breaker struct {
+	name     string
+	settings Settings
+	now      func() time.Time
+
+	mutex    sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	trial    bool // A half-open trial call is in flight
+}
+
+// New creates a closed breaker
+func New(name string, settings Settings) *Breaker {
+	if settings.FailureThreshold <= 0 {
+		settings.FailureThreshold = DefaultFailureThreshold
+	}
+	if settings.OpenTimeout <= 0 {
+		settings.OpenTimeout = DefaultOpenTimeout
+	}
+	if settings.IsFailure == nil {
+		settings.IsFailure = func(err error) bool { return err != nil }
+	}
+	return &Breaker{name: name, settings: settings, now: time.Now, state: Closed}
+}
+
+// Name returns the name of the guarded dependency
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// Execute runs fn through the breaker
+func (b *Breaker) Execute(ctx context.Context, fn func(ctx context.Context) error) error {
+	_, err := Call(ctx, b, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	})
+	return err
+}
+
+// Call runs fn through the breaker and returns its result. It fails with an *OpenError
+// without running fn while the breaker is open, and with ErrTimeout if fn does not return
+// within the call timeout. fn gets a context that is done at the deadline; one that ignores
+// it keeps running in the background and its result is dropped. Calls given up because ctx
+// is done fail with ctx's error and say nothing about the dependency.
+func Call[T any](ctx context.Context, b *Breaker, fn func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+	if err := b.allow(); err != nil {
+		return zero, err
+	}
+	value, err := withDeadline(ctx, b.settings.CallTimeout, fn)
+	if ctx.Err() != nil {
+		b.release()
+		return zero, ctx.Err()
+	}
+	b.record(err)
+	return value, err
+}
+
+// Status returns a snapshot of the breaker
+func (b *Breaker) Status() Status {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	status := Status{Name: b.name, State: b.currentState(), ConsecutiveFailures: b.failures}
+	if status.State != Closed {
+		openedAt := b.openedAt
+		status.OpenedAt = &openedAt
+	}
+	return status
+}
+
+// allow reports whether a call may go ahead, claiming the trial call of a half-open breaker
+func (b *Breaker) allow() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	switch b.currentState() {
+	case Open:
+		return &OpenError{Name: b.name, RetryAfter: b.openedAt.Add(b.settings.OpenTimeout).Sub(b.now())}
+	case HalfOpen:
+		if b.trial {
+			return &OpenError{Name: b.name, RetryAfter: time.Second}
+		}
+		b.state = HalfOpen
+		b.trial = true
+	}
+	return nil
+}
+
+// release gives back the trial call of a half-open breaker without an outcome
+func (b *Breaker) release() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.trial = false
+}
+
+// record updates the breaker with the outcome of a call
+func (b *Breaker) record(err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	wasTrial := b.trial
+	b.trial = false
+	if !errors.Is(err, ErrTimeout) && !b.settings.IsFailure(err) {
+		b.state = Closed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if wasTrial || b.failures >= b.settings.FailureThreshold {
+		b.state = Open
+		b.openedAt = b.now()
+	}
+}
+
+// currentState returns the state, moving an open breaker whose timeout has passed to half-open.
+// The caller holds the mutex.
+func (b *Breaker) currentState() State {
+	if b.state == Open && !b.now().Before(b.openedAt.Add(b.settings.OpenTimeout)) {
+		return HalfOpen
+	}
+	return b.state
+}
+
+// withDeadline runs fn, giving up with ErrTimeout once timeout has passed
+func withDeadline[T any](ctx context.Context, timeout time.Duration, fn func(ctx context.Context) (T, error)) (T, error) {
+	if timeout <= 0 {
+		return fn(ctx)
+	}
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type outcome struct {
+		value T
+		err   error
+	}
+	// Buffered, so a call finishing after the deadline does not block forever
+	done := make(chan outcome, 1)
+	go func() {
+		value, err := fn(callCtx)
+		done <- outcome{value, err}
+	}()
+
+	var zero T
+	select {
+	case result := <-done:
+		// A call that gave up because of its own deadline timed out
+		if result.err != nil && errors.Is(callCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+			return zero, ErrTimeout
+		}
+		return result.value, result.err
+	case <-callCtx.Done():
+		if ctx.Err() != nil {
+			return zero, ctx.Err()
+		}
+		return zero, ErrTimeout
+	}
+}
@@ -0,0 +1,175 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errDown = errors.New("dependency down")
+
+// newTestBreaker returns a breaker opening after two failures, with a clock the test controls
+func newTestBreaker(settings Settings) (*Breaker, *time.Time) {
+	if settings.FailureThreshold == 0 {
+		settings.FailureThreshold = 2
+	}
+	settings.OpenTimeout = 10 * time.Second
+	b := New("test", settings)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	b.now = func() time.Time { return now }
+	return b, &now
+}
+
+func fail(context.Context) error    { return errDown }
+func succeed(context.Context) error { return nil }
+
+func TestBreaker_OpensAfterConsecutiveFailures(t *testing.T) {
+	// Arrange
+	b, _ := newTestBreaker(Settings{})
+	calls := 0
+
+	// Act
+	assert.ErrorIs(t, b.Execute(context.Background(), fail), errDown)
+	assert.ErrorIs(t, b.Execute(context.Background(), fail), errDown)
+	err := b.Execute(context.Background(), func(context.Context) error { calls++; return nil })
+
+	// Assert
+	assert.ErrorIs(t, err, ErrOpen)
+	assert.Zero(t, calls, "an open breaker does not call the dependency")
+	var openErr *OpenError
+	require.ErrorAs(t, err, &openErr)
+	assert.Equal(t, "test", openErr.Name)
+	assert.Equal(t, 10*time.Second, openErr.RetryAfter)
+
+	status := b.Status()
+	assert.Equal(t, Open, status.State)
+	assert.Equal(t, 2, status.ConsecutiveFailures)
+	assert.NotNil(t, status.OpenedAt)
+}
+
+func TestBreaker_SuccessResetsFailures(t *testing.T) {
+	// Arrange
+	b, _ := newTestBreaker(Settings{})
+
+	// Act
+	b.Execute(context.Background(), fail)
+	b.Execute(context.Background(), succeed)
+	b.Execute(context.Background(), fail)
+
+	// Assert
+	assert.Equal(t, Closed, b.Status().State)
+	assert.Equal(t, 1, b.Status().ConsecutiveFailures)
+}
+
+func TestBreaker_HalfOpenTrialClosesOrReopens(t *testing.T) {
+	// Arrange
+	b, now := newTestBreaker(Settings{})
+	b.Execute(context.Background(), fail)
+	b.Execute(context.Background(), fail)
+
+	// Act: the open timeout passes and the trial call fails
+	*now = now.Add(10 * time.Second)
+	assert.Equal(t, HalfOpen, b.Status().State)
+	trialErr := b.Execute(context.Background(), fail)
+
+	// Assert
+	assert.ErrorIs(t, trialErr, errDown)
+	assert.Equal(t, Open, b.Status().State, "a failed trial reopens the breaker")
+	assert.ErrorIs(t, b.Execute(context.Background(), succeed), ErrOpen)
+
+	// Act: the next trial succeeds
+	*now = now.Add(10 * time.Second)
+	require.NoError(t, b.Execute(context.Background(), succeed))
+
+	// Assert
+	assert.Equal(t, Closed, b.Status().State)
+	assert.Zero(t, b.Status().ConsecutiveFailures)
+	assert.Nil(t, b.Status().OpenedAt)
+}
+
+func TestBreaker_HalfOpenAllowsOneTrialAtATime(t *testing.T) {
+	// Arrange
+	b, now := newTestBreaker(Settings{})
+	b.Execute(context.Background(), fail)
+	b.Execute(context.Background(), fail)
+	*now = now.Add(10 * time.Second)
+
+	release := make(chan struct{})
+	trialDone := make(chan error)
+	go func() {
+		trialDone <- b.Execute(context.Background(), func(context.Context) error { <-release; return nil })
+	}()
+	require.Eventually(t, func() bool {
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+		return b.trial
+	}, time.Second, time.Millisecond)
+
+	// Act
+	concurrentErr := b.Execute(context.Background(), succeed)
+	close(release)
+
+	// Assert
+	assert.ErrorIs(t, concurrentErr, ErrOpen)
+	assert.NoError(t, <-trialDone)
+	assert.Equal(t, Closed, b.Status().State)
+}
+
+func TestBreaker_CallTimeout(t *testing.T) {
+	// Arrange
+	b, _ := newTestBreaker(Settings{FailureThreshold: 1, CallTimeout: 10 * time.Millisecond})
+	release := make(chan struct{})
+	defer close(release)
+
+	// Act
+	value, err := Call(context.Background(), b, func(context.Context) (string, error) {
+		<-release
+		return "late", nil
+	})
+
+	// Assert
+	assert.ErrorIs(t, err, ErrTimeout)
+	assert.Empty(t, value)
+	assert.Equal(t, Open, b.Status().State, "timeouts count as failures")
+}
+
+func TestBreaker_IsFailureIgnoresAnswers(t *testing.T) {
+	// Arrange
+	errAnswer := errors.New("not found")
+	b, _ := newTestBreaker(Settings{IsFailure: func(err error) bool {
+		return err != nil && !errors.Is(err, errAnswer)
+	}})
+
+	// Act
+	for i := 0; i < 3; i++ {
+		b.Execute(context.Background(), func(context.Context) error { return errAnswer })
+	}
+
+	// Assert
+	assert.Equal(t, Closed, b.Status().State)
+	assert.Zero(t, b.Status().ConsecutiveFailures)
+}
+
+func TestBreaker_CanceledCallsAreNotFailures(t *testing.T) {
+	// Arrange
+	b, now := newTestBreaker(Settings{FailureThreshold: 1})
+	b.Execute(context.Background(), fail)
+	*now = now.Add(10 * time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Act: the caller gives up during the half-open trial call
+	err := b.Execute(ctx, func(ctx context.Context) error {
+		cancel()
+		return ctx.Err()
+	})
+
+	// Assert
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, HalfOpen, b.Status().State, "the next call is still let through as a trial")
+	require.NoError(t, b.Execute(context.Background(), succeed))
+	assert.Equal(t, Closed, b.Status().State)
+}
@@ -0,0 +1,352 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"payment-service/internal/entity"
+	"payment-service/internal/gateway"
+	"payment-service/internal/ledger"
+	"payment-service/internal/usecase"
+	"payment-service/internal/webhook"
+	"time"
+)
+
+// Repository is the storage guarded by a Store
+type Repository interface {
+	usecase.PaymentRepository
+	usecase.WalletRepository
+	usecase.TransferRepository
+	usecase.JobQueue
+	usecase.EventOutbox
+	ledger.EntryStore
+	webhook.Store
+}
+
+// Store guards a repository with a breaker. Errors that are answers rather than
+// failures, such as a lost optimistic concurrency race, do not count against storage.
+type Store struct {
+	repo    Repository
+	breaker *Breaker
+}
+
+// NewStore wraps repo in a breaker named "storage"
+func NewStore(repo Repository, settings Settings) *Store {
+	settings.IsFailure = isStorageFailure
+	return &Store{repo: repo, breaker: New("storage", settings)}
+}
+
+// Breaker returns the breaker guarding the repository
+func (s *Store) Breaker() *Breaker {
+	return s.breaker
+}
+
+func isStorageFailure(err error) bool {
+	return err != nil &&
+		!errors.Is(err, usecase.ErrConcurrentUpdate) &&
+		!errors.Is(err, usecase.ErrDuplicateTransaction) &&
+		!errors.Is(err, usecase.ErrPaymentNotFound) &&
+		!errors.Is(err, usecase.ErrTransferNotFound) &&
+		!errors.Is(err, usecase.ErrJobNotFound) &&
+		!errors.Is(err, usecase.ErrJobLeaseLost) &&
+		!errors.Is(err, webhook.ErrDeliveryNotFound) &&
+		!errors.Is(err, webhook.ErrDeliveryInFlight) &&
+		!errors.Is(err, webhook.ErrDeliveryLeaseLost)
+}
+
+// createResult carries both results of a CreateIfAbsent call through a breaker
+type createResult[T any] struct {
+	value   T
+	created bool
+}
+
+// Store saves a payment
+func (s *Store) Store(ctx context.Context, payment *entity.Payment) error {
+	return s.breaker.Execute(ctx, func(ctx context.Context) error {
+		return s.repo.Store(ctx, payment)
+	})
+}
+
+// CreateIfAbsent stores a payment unless its transaction ID already exists
+func (s *Store) CreateIfAbsent(ctx context.Context, payment *entity.Payment) (*entity.Payment, bool, error) {
+	result, err := Call(ctx, s.breaker, func(ctx context.Context) (createResult[*entity.Payment], error) {
+		stored, created, err := s.repo.CreateIfAbsent(ctx, payment)
+		return createResult[*entity.Payment]{stored, created}, err
+	})
+	return result.value, result.created, err
+}
+
+// Update saves changes to an existing payment
+func (s *Store) Update(ctx context.Context, payment *entity.Payment) error {
+	return s.breaker.Execute(ctx, func(ctx context.Context) error {
+		return s.repo.Update(ctx, payment)
+	})
+}
+
+// GetByTransactionID returns a payment by its transaction ID
+func (s *Store) GetByTransactionID(ctx context.Context, transactionID string) (*entity.Payment, error) {
+	return Call(ctx, s.breaker, func(ctx context.Context) (*entity.Payment, error) {
+		return s.repo.GetByTransactionID(ctx, transactionID)
+	})
+}
+
+// Exists reports whether a payment exists. It has no way to report a failure, so it is not guarded.
+func (s *Store) Exists(ctx context.Context, transactionID string) bool {
+	return s.repo.Exists(ctx, transactionID)
+}
+
+// List returns the payments matching query
+func (s *Store) List(ctx context.Context, query usecase.PaymentQuery) ([]*entity.Payment, error) {
+	return Call(ctx, s.breaker, func(ctx context.Context) ([]*entity.Payment, error) {
+		return s.repo.List(ctx, query)
+	})
+}
+
+// GetWallet returns a user's wallet
+func (s *Store) GetWallet(ctx context.Context, userID string) (*entity.Wallet, error) {
+	return Call(ctx, s.breaker, func(ctx context.Context) (*entity.Wallet, error) {
+		return s.repo.GetWallet(ctx, userID)
+	})
+}
+
+// SaveWallets writes wallets in one atomic operation
+func (s *Store) SaveWallets(ctx context.Context, wallets ...*entity.Wallet) error {
+	return s.breaker.Execute(ctx, func(ctx context.Context) error {
+		return s.repo.SaveWallets(ctx, wallets...)
+	})
+}
+
+// CreateTransferIfAbsent stores a transfer unless its transaction ID already exists
+func (s *Store) CreateTransferIfAbsent(ctx context.Context, transfer *entity.Transfer) (*entity.Transfer, bool, error) {
+	result, err := Call(ctx, s.breaker, func(ctx context.Context) (createResult[*entity.Transfer], error) {
+		stored, created, err := s.repo.CreateTransferIfAbsent(ctx, transfer)
+		return createResult[*entity.Transfer]{stored, created}, err
+	})
+	return result.value, result.created, err
+}
+
+// UpdateTransfer saves changes to a stored transfer
+func (s *Store) UpdateTransfer(ctx context.Context, transfer *entity.Transfer) error {
+	return s.breaker.Execute(ctx, func(ctx context.Context) error {
+		return s.repo.UpdateTransfer(ctx, transfer)
+	})
+}
+
+// GetTransfer returns a transfer by its transaction ID
+func (s *Store) GetTransfer(ctx context.Context, transactionID string) (*entity.Transfer, error) {
+	return Call(ctx, s.breaker, func(ctx context.Context) (*entity.Transfer, error) {
+		return s.repo.GetTransfer(ctx, transactionID)
+	})
+}
+
+// EnqueueJob adds a job for a queued payment
+func (s *Store) EnqueueJob(ctx context.Context, transactionID string, now time.Time) error {
+	return s.breaker.Execute(ctx, func(ctx context.Context) error {
+		return s.repo.EnqueueJob(ctx, transactionID, now)
+	})
+}
+
+// LeaseJob delivers the next available job
+func (s *Store) LeaseJob(ctx context.Context, now, leaseUntil time.Time) (*entity.Job, error) {
+	return Call(ctx, s.breaker, func(ctx context.Context) (*entity.Job, error) {
+		return s.repo.LeaseJob(ctx, now, leaseUntil)
+	})
+}
+
+// CompleteJob removes a leased job
+func (s *Store) CompleteJob(ctx context.Context, transactionID string, attempt int) error {
+	return s.breaker.Execute(ctx, func(ctx context.Context) error {
+		return s.repo.CompleteJob(ctx, transactionID, attempt)
+	})
+}
+
+// RetryJob makes a leased job available again later
+func (s *Store) RetryJob(ctx context.Context, transactionID string, attempt int, lastError string, retryAt, now time.Time) error {
+	return s.breaker.Execute(ctx, func(ctx context.Context) error {
+		return s.repo.RetryJob(ctx, transactionID, attempt, lastError, retryAt, now)
+	})
+}
+
+// ReleaseJob hands a leased job back without counting its attempt
+func (s *Store) ReleaseJob(ctx context.Context, transactionID string, attempt int, lastError string, now time.Time) error {
+	return s.breaker.Execute(ctx, func(ctx context.Context) error {
+		return s.repo.ReleaseJob(ctx, transactionID, attempt, lastError, now)
+	})
+}
+
+// BuryJob moves a leased job to the dead-letter queue
+func (s *Store) BuryJob(ctx context.Context, transactionID string, attempt int, lastError string, now time.Time) error {
+	return s.breaker.Execute(ctx, func(ctx context.Context) error {
+		return s.repo.BuryJob(ctx, transactionID, attempt, lastError, now)
+	})
+}
+
+// DeadJobs lists the dead-lettered jobs
+func (s *Store) DeadJobs(ctx context.Context) ([]*entity.Job, error) {
+	return Call(ctx, s.breaker, func(ctx context.Context) ([]*entity.Job, error) {
+		return s.repo.DeadJobs(ctx)
+	})
+}
+
+// CountJobs counts the jobs available, delayed and dead
+func (s *Store) CountJobs(ctx context.Context, now time.Time) (*entity.JobCounts, error) {
+	return Call(ctx, s.breaker, func(ctx context.Context) (*entity.JobCounts, error) {
+		return s.repo.CountJobs(ctx, now)
+	})
+}
+
+// RequeueJob makes a dead-lettered job available again
+func (s *Store) RequeueJob(ctx context.Context, transactionID string, now time.Time) (*entity.Job, error) {
+	return Call(ctx, s.breaker, func(ctx context.Context) (*entity.Job, error) {
+		return s.repo.RequeueJob(ctx, transactionID, now)
+	})
+}
+
+// JournalEntries returns the recorded journal entries
+func (s *Store) JournalEntries(ctx context.Context) ([]entity.JournalEntry, error) {
+	return Call(ctx, s.breaker, func(ctx context.Context) ([]entity.JournalEntry, error) {
+		return s.repo.JournalEntries(ctx)
+	})
+}
+
+// UnpublishedPayments returns payments with changes not yet published
+func (s *Store) UnpublishedPayments(ctx context.Context, limit int) ([]*entity.Payment, error) {
+	return Call(ctx, s.breaker, func(ctx context.Context) ([]*entity.Payment, error) {
+		return s.repo.UnpublishedPayments(ctx, limit)
+	})
+}
+
+// MarkChangesPublished records that changes of a payment have been published
+func (s *Store) MarkChangesPublished(ctx context.Context, transactionID string, published int) error {
+	return s.breaker.Execute(ctx, func(ctx context.Context) error {
+		return s.repo.MarkChangesPublished(ctx, transactionID, published)
+	})
+}
+
+// SaveWebhookEndpoint stores a new webhook endpoint
+func (s *Store) SaveWebhookEndpoint(ctx context.Context, endpoint *webhook.Endpoint) error {
+	return s.breaker.Execute(ctx, func(ctx context.Context) error {
+		return s.repo.SaveWebhookEndpoint(ctx, endpoint)
+	})
+}
+
+// WebhookEndpoints lists a merchant's webhook endpoints
+func (s *Store) WebhookEndpoints(ctx context.Context, merchantID string) ([]*webhook.Endpoint, error) {
+	return Call(ctx, s.breaker, func(ctx context.Context) ([]*webhook.Endpoint, error) {
+		return s.repo.WebhookEndpoints(ctx, merchantID)
+	})
+}
+
+// AddWebhookDeliveries stores new webhook deliveries
+func (s *Store) AddWebhookDeliveries(ctx context.Context, deliveries []*webhook.Delivery) error {
+	return s.breaker.Execute(ctx, func(ctx context.Context) error {
+		return s.repo.AddWebhookDeliveries(ctx, deliveries)
+	})
+}
+
+// WebhookDeliveries lists a merchant's webhook deliveries
+func (s *Store) WebhookDeliveries(ctx context.Context, merchantID, status string) ([]*webhook.Delivery, error) {
+	return Call(ctx, s.breaker, func(ctx context.Context) ([]*webhook.Delivery, error) {
+		return s.repo.WebhookDeliveries(ctx, merchantID, status)
+	})
+}
+
+// LeaseWebhookDeliveries leases the webhook deliveries that are due
+func (s *Store) LeaseWebhookDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*webhook.Delivery, error) {
+	return Call(ctx, s.breaker, func(ctx context.Context) ([]*webhook.Delivery, error) {
+		return s.repo.LeaseWebhookDeliveries(ctx, now, leaseUntil, limit)
+	})
+}
+
+// SaveWebhookAttempt stores the outcome of a webhook delivery attempt
+func (s *Store) SaveWebhookAttempt(ctx context.Context, delivery *webhook.Delivery) error {
+	return s.breaker.Execute(ctx, func(ctx context.Context) error {
+		return s.repo.SaveWebhookAttempt(ctx, delivery)
+	})
+}
+
+// ReplayWebhookDelivery makes a webhook delivery pending again
+func (s *Store) ReplayWebhookDelivery(ctx context.Context, merchantID, deliveryID string, now time.Time) (*webhook.Delivery, error) {
+	return Call(ctx, s.breaker, func(ctx context.Context) (*webhook.Delivery, error) {
+		return s.repo.ReplayWebhookDelivery(ctx, merchantID, deliveryID, now)
+	})
+}
+
+// Gateway guards a payment gateway with a breaker. Only timeouts and outages count as
+// failures; declines and rejected operations are answers from a working processor.
+// Calls rejected by the open breaker fail with usecase.ErrGatewayUnavailable and calls
+// past their deadline with usecase.ErrGatewayTimeout, so the use case treats them like
+// any other processor outage.
+type Gateway struct {
+	gateway usecase.PaymentGateway
+	breaker *Breaker
+}
+
+// NewGateway wraps gw in a breaker named "gateway"
+func NewGateway(gw usecase.PaymentGateway, settings Settings) *Gateway {
+	settings.IsFailure = isGatewayFailure
+	return &Gateway{gateway: gw, breaker: New("gateway", settings)}
+}
+
+// Breaker returns the breaker guarding the gateway
+func (g *Gateway) Breaker() *Breaker {
+	return g.breaker
+}
+
+// isGatewayFailure reports whether err means the processors are down. Having no route
+// for a payment is a configuration problem, not an outage.
+func isGatewayFailure(err error) bool {
+	return (errors.Is(err, usecase.ErrGatewayTimeout) || errors.Is(err, usecase.ErrGatewayUnavailable)) &&
+		!errors.Is(err, gateway.ErrNoRoute)
+}
+
+// Authorize places a hold for the payment amount on the card
+func (g *Gateway) Authorize(ctx context.Context, req usecase.GatewayAuthorization) (*usecase.GatewayResult, error) {
+	return g.call(ctx, func(ctx context.Context) (*usecase.GatewayResult, error) {
+		return g.gateway.Authorize(ctx, req)
+	})
+}
+
+// Capture collects amount from an authorization
+func (g *Gateway) Capture(ctx context.Context, reference string, amount entity.Money) (*usecase.GatewayResult, error) {
+	return g.call(ctx, func(ctx context.Context) (*usecase.GatewayResult, error) {
+		return g.gateway.Capture(ctx, reference, amount)
+	})
+}
+
+// Void releases the hold of an authorization that was not captured
+func (g *Gateway) Void(ctx context.Context, reference string) (*usecase.GatewayResult, error) {
+	return g.call(ctx, func(ctx context.Context) (*usecase.GatewayResult, error) {
+		return g.gateway.Void(ctx, reference)
+	})
+}
+
+// Refund returns amount of a captured payment to the card
+func (g *Gateway) Refund(ctx context.Context, reference, refundKey string, amount entity.Money) (*usecase.GatewayResult, error) {
+	return g.call(ctx, func(ctx context.Context) (*usecase.GatewayResult, error) {
+		return g.gateway.Refund(ctx, reference, refundKey, amount)
+	})
+}
+
+// Status returns the processor's current view of a payment
+func (g *Gateway) Status(ctx context.Context, reference string) (*usecase.GatewayResult, error) {
+	return g.call(ctx, func(ctx context.Context) (*usecase.GatewayResult, error) {
+		return g.gateway.Status(ctx, reference)
+	})
+}
+
+// Candidates returns the processors an authorization may go to. It makes no call, so the breaker is not consulted.
+func (g *Gateway) Candidates(req usecase.GatewayAuthorization) []string {
+	return g.gateway.Candidates(req)
+}
+
+func (g *Gateway) call(ctx context.Context, fn func(ctx context.Context) (*usecase.GatewayResult, error)) (*usecase.GatewayResult, error) {
+	result, err := Call(ctx, g.breaker, fn)
+	switch {
+	case errors.Is(err, ErrOpen):
+		return nil, fmt.Errorf("%w: %w", usecase.ErrGatewayUnavailable, err)
+	case errors.Is(err, ErrTimeout):
+		return nil, fmt.Errorf("%w: %w", usecase.ErrGatewayTimeout, err)
+	}
+	return result, err
+}
@@ -0,0 +1,114 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"payment-service/internal/entity"
+	"payment-service/internal/gateway"
+	"payment-service/internal/repository"
+	"payment-service/internal/usecase"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// brokenRepository fails every payment lookup
+type brokenRepository struct {
+	*repository.InMemoryPaymentRepository
+	err error
+}
+
+func (r *brokenRepository) GetByTransactionID(ctx context.Context, transactionID string) (*entity.Payment, error) {
+	return nil, r.err
+}
+
+func TestStore_OpensOnStorageFailures(t *testing.T) {
+	// Arrange
+	repo := &brokenRepository{InMemoryPaymentRepository: repository.NewInMemoryPaymentRepository(), err: errDown}
+	store := NewStore(repo, Settings{FailureThreshold: 2})
+
+	// Act
+	store.GetByTransactionID(context.Background(), "txn123")
+	store.GetByTransactionID(context.Background(), "txn123")
+	_, err := store.GetWallet(context.Background(), "user123")
+
+	// Assert
+	assert.ErrorIs(t, err, ErrOpen, "one breaker guards the whole store")
+	assert.Equal(t, Open, store.Breaker().Status().State)
+	assert.Equal(t, "storage", store.Breaker().Name())
+}
+
+func TestStore_DomainErrorsAreNotFailures(t *testing.T) {
+	// Arrange
+	repo := repository.NewInMemoryPaymentRepository()
+	store := NewStore(repo, Settings{FailureThreshold: 1})
+	payment := entity.NewPayment("txn123", "user123", entity.Money{}, "user123", "payment requested", time.Now())
+	_, created, err := store.CreateIfAbsent(context.Background(), payment)
+	require.NoError(t, err)
+	require.True(t, created)
+
+	// Act
+	stale := payment.Clone()
+	require.NoError(t, store.Update(context.Background(), payment))
+	updateErr := store.Update(context.Background(), stale)
+	_, created, createErr := store.CreateIfAbsent(context.Background(), payment)
+
+	// Assert
+	assert.ErrorIs(t, updateErr, usecase.ErrConcurrentUpdate)
+	require.NoError(t, createErr)
+	assert.False(t, created)
+	assert.Equal(t, Closed, store.Breaker().Status().State)
+}
+
+func TestGateway_OpensOnOutage(t *testing.T) {
+	// Arrange
+	simulator := gateway.NewSimulator()
+	guarded := NewGateway(simulator, Settings{FailureThreshold: 2})
+	amount, err := entity.NewMoney(1000, "USD")
+	require.NoError(t, err)
+	authorization := usecase.GatewayAuthorization{TransactionID: "txn123", UserID: "user123", Amount: amount}
+	simulator.SetDown(true)
+
+	// Act
+	guarded.Authorize(context.Background(), authorization)
+	guarded.Authorize(context.Background(), authorization)
+	simulator.SetDown(false)
+	_, openErr := guarded.Authorize(context.Background(), authorization)
+
+	// Assert
+	assert.ErrorIs(t, openErr, ErrOpen)
+	assert.ErrorIs(t, openErr, usecase.ErrGatewayUnavailable)
+	assert.Equal(t, Open, guarded.Breaker().Status().State)
+}
+
+func TestGateway_DeclinesAndTimeouts(t *testing.T) {
+	// Arrange
+	slow := &slowGateway{release: make(chan struct{})}
+	defer close(slow.release)
+	guarded := NewGateway(slow, Settings{FailureThreshold: 1, CallTimeout: 10 * time.Millisecond})
+	declining := NewGateway(gateway.NewSimulator(), Settings{FailureThreshold: 1})
+
+	// Act
+	_, timeoutErr := guarded.Status(context.Background(), "sim_txn123")
+	_, unknownErr := declining.Status(context.Background(), "sim_txn123")
+
+	// Assert
+	assert.ErrorIs(t, timeoutErr, usecase.ErrGatewayTimeout)
+	assert.ErrorIs(t, timeoutErr, ErrTimeout)
+	assert.Equal(t, Open, guarded.Breaker().Status().State)
+	assert.ErrorIs(t, unknownErr, gateway.ErrUnknownReference)
+	assert.Equal(t, Closed, declining.Breaker().Status().State, "rejected operations are answers, not outages")
+}
+
+// slowGateway never answers until released
+type slowGateway struct {
+	usecase.PaymentGateway
+	release chan struct{}
+}
+
+func (g *slowGateway) Status(ctx context.Context, reference string) (*usecase.GatewayResult, error) {
+	<-g.release
+	return nil, errors.New("released")
+}
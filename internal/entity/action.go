@@ -0,0 +1,33 @@
+package entity
+
+import "time"
+
+// PendingAction is a processor call claimed for a payment before it is made. The claim is
+// stored first, so concurrent requests never both make the call, and a call cut short by a
+// crash is made again, with the same arguments, by the next request for the payment.
+type PendingAction struct {
+	Action    string    `json:"action"` // ActionCapture or ActionVoid
+	Amount    Money     `json:"amount"` // Amount to capture, or the authorized amount a void releases
+	Actor     string    `json:"actor"`
+	Reason    string    `json:"reason"`
+	ClaimedAt time.Time `json:"claimed_at"`
+}
+
+// Processor actions a payment can claim
+const (
+	ActionCapture = "capture"
+	ActionVoid    = "void"
+)
+
+// Status returns the status the payment moves to once the processor has made the action
+func (a PendingAction) Status() string {
+	if a.Action == ActionVoid {
+		return StatusVoided
+	}
+	return StatusCaptured
+}
+
+// Matches reports whether other claims the same processor call
+func (a PendingAction) Matches(other PendingAction) bool {
+	return a.Action == other.Action && a.Amount == other.Amount
+}
@@ -0,0 +1,39 @@
+package entity
+
+import "time"
+
+// Job statuses
+const (
+	JobQueued = "queued" // Waiting for a worker, or leased to one until AvailableAt
+	JobDead   = "dead"   // Out of attempts; kept in the dead-letter queue until requeued
+)
+
+// Job asks a worker to process a queued asynchronous payment. Workers lease jobs: a leased job
+// stays hidden until AvailableAt, and is delivered again if its worker has not finished it by then.
+type Job struct {
+	TransactionID string    `json:"transaction_id"`
+	Status        string    `json:"status"`
+	Attempts      int       `json:"attempts"`             // Deliveries so far; a lease is identified by the attempt it started
+	AvailableAt   time.Time `json:"available_at"`         // When the job is next delivered: its retry time or the end of its lease
+	LastError     string    `json:"last_error,omitempty"` // Why the latest attempt failed
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// JobCounts is the depth of a job queue at a point in time
+type JobCounts struct {
+	Available int `json:"available" example:"3"` // Queued jobs a worker can lease now
+	Delayed   int `json:"delayed" example:"2"`   // Queued jobs leased to a worker or waiting for their retry
+	Dead      int `json:"dead" example:"0"`      // Jobs in the dead-letter queue
+}
+
+// Clone returns a copy of the job, so the copy can be modified independently
+func (j *Job) Clone() *Job {
+	clone := *j
+	return &clone
+}
+
+// Available reports whether the job can be leased at now
+func (j *Job) Available(now time.Time) bool {
+	return j.Status == JobQueued && !j.AvailableAt.After(now)
+}
@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"time"
+)
+
+// Side is the side of a ledger account a posting is made to
+type Side string
+
+// Posting sides
+const (
+	Debit  Side = "debit"
+	Credit Side = "credit"
+)
+
+// Posting moves an amount to one side of one ledger account
+type Posting struct {
+	Account string `json:"account"`
+	Side    Side   `json:"side"`
+	Amount  Money  `json:"amount"`
+}
+
+// JournalEntry is a set of postings recorded together in the double-entry ledger.
+// Its ID makes recording idempotent: an entry whose ID was recorded before is skipped.
+type JournalEntry struct {
+	ID          string    `json:"id"`
+	Description string    `json:"description"`
+	PostedAt    time.Time `json:"posted_at"`
+	Postings    []Posting `json:"postings"`
+}
@@ -0,0 +1,123 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrInvalidTransition = errors.New("invalid payment status transition")
+	ErrUnknownStatus     = errors.New("unknown payment status")
+)
+
+// transitions lists the statuses a payment may move to from each status.
+// Statuses without an entry are terminal.
+var transitions = map[string][]string{
+	StatusPending:           {StatusAuthorized, StatusFailed},
+	StatusAuthorized:        {StatusCaptured, StatusVoided, StatusFailed},
+	StatusCaptured:          {StatusPartiallyRefunded, StatusRefunded, StatusSettled, StatusDisputed},
+	StatusPartiallyRefunded: {StatusPartiallyRefunded, StatusRefunded, StatusSettled, StatusDisputed},
+	StatusSettled:           {StatusPartiallyRefunded, StatusRefunded, StatusDisputed},
+	StatusDisputed:          {StatusSettled, StatusPartiallyRefunded, StatusChargedBack},
+	StatusVoided:            nil,
+	StatusRefunded:          nil,
+	StatusFailed:            nil,
+	StatusChargedBack:       nil,
+}
+
+// TransitionError reports an attempt to move a payment between statuses the lifecycle does not connect
+type TransitionError struct {
+	From string
+	To   string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("%s: %s -> %s", ErrInvalidTransition, e.From, e.To)
+}
+
+// Is makes errors.Is(err, ErrInvalidTransition) match any TransitionError
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
+
+// StatusChange records one status transition of a payment: who made it, when and why
+type StatusChange struct {
+	From    string    `json:"from,omitempty"`
+	To      string    `json:"to"`
+	Actor   string    `json:"actor"`
+	Reason  string    `json:"reason"`
+	At      time.Time `json:"at"`
+	EventID string    `json:"event_id,omitempty"` // ID of the processor event that made the change, if any
+}
+
+// CanTransition reports whether the lifecycle allows moving from one status to another
+func CanTransition(from, to string) bool {
+	for _, allowed := range transitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsStatus reports whether status is a known payment status
+func IsStatus(status string) bool {
+	_, known := transitions[status]
+	return known
+}
+
+// AppliedEvent reports whether the processor event with eventID already changed the payment's status
+func (p *Payment) AppliedEvent(eventID string) bool {
+	for _, change := range p.StatusHistory {
+		if change.EventID != "" && change.EventID == eventID {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTerminal reports whether no further transitions are possible from status
+func IsTerminal(status string) bool {
+	return len(transitions[status]) == 0
+}
+
+// NewPayment creates a pending payment and records its creation in the status history
+func NewPayment(transactionID, userID string, amount Money, actor, reason string, at time.Time) *Payment {
+	return &Payment{
+		TransactionID:  transactionID,
+		UserID:         userID,
+		Amount:         amount,
+		CapturedAmount: Money{Currency: amount.Currency},
+		PaymentMethod:  PaymentMethodCard,
+		Status:         StatusPending,
+		CreatedAt:      at,
+		StatusHistory: []StatusChange{{
+			To:     StatusPending,
+			Actor:  actor,
+			Reason: reason,
+			At:     at,
+		}},
+	}
+}
+
+// TransitionTo moves the payment to status and appends the change to its history.
+// It returns a *TransitionError if the lifecycle does not allow the transition.
+func (p *Payment) TransitionTo(status, actor, reason string, at time.Time) error {
+	if _, known := transitions[status]; !known {
+		return fmt.Errorf("%w: %q", ErrUnknownStatus, status)
+	}
+	if !CanTransition(p.Status, status) {
+		return &TransitionError{From: p.Status, To: status}
+	}
+
+	p.StatusHistory = append(p.StatusHistory, StatusChange{
+		From:   p.Status,
+		To:     status,
+		Actor:  actor,
+		Reason: reason,
+		At:     at,
+	})
+	p.Status = status
+	return nil
+}
@@ -0,0 +1,121 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPayment_TransitionTo_Allowed(t *testing.T) {
+	testCases := []struct {
+		name string
+		path []string
+	}{
+		{name: "Authorize Then Capture", path: []string{StatusAuthorized, StatusCaptured}},
+		{name: "Authorize Then Void", path: []string{StatusAuthorized, StatusVoided}},
+		{name: "Decline", path: []string{StatusFailed}},
+		{name: "Partial Then Full Refund", path: []string{StatusAuthorized, StatusCaptured, StatusPartiallyRefunded, StatusPartiallyRefunded, StatusRefunded}},
+		{name: "Settle Then Refund", path: []string{StatusAuthorized, StatusCaptured, StatusSettled, StatusRefunded}},
+		{name: "Dispute Won", path: []string{StatusAuthorized, StatusCaptured, StatusDisputed, StatusSettled}},
+		{name: "Settle Partially Refunded", path: []string{StatusAuthorized, StatusCaptured, StatusPartiallyRefunded, StatusSettled, StatusRefunded}},
+		{name: "Dispute Of Partially Refunded Won", path: []string{StatusAuthorized, StatusCaptured, StatusPartiallyRefunded, StatusDisputed, StatusPartiallyRefunded}},
+		{name: "Dispute Lost", path: []string{StatusAuthorized, StatusCaptured, StatusSettled, StatusDisputed, StatusChargedBack}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			now := time.Now()
+			payment := NewPayment("txn123", "user123", Money{Amount: 100, Currency: "USD"}, "user123", "payment requested", now)
+
+			// Act
+			for _, status := range tc.path {
+				require.NoError(t, payment.TransitionTo(status, "system", "test", now))
+			}
+
+			// Assert
+			assert.Equal(t, tc.path[len(tc.path)-1], payment.Status)
+			assert.Len(t, payment.StatusHistory, len(tc.path)+1)
+		})
+	}
+}
+
+func TestPayment_TransitionTo_Rejected(t *testing.T) {
+	testCases := []struct {
+		name string
+		from []string
+		to   string
+	}{
+		{name: "Capture Without Authorization", from: nil, to: StatusCaptured},
+		{name: "Refund Pending", from: nil, to: StatusRefunded},
+		{name: "Void Captured", from: []string{StatusAuthorized, StatusCaptured}, to: StatusVoided},
+		{name: "Capture Voided", from: []string{StatusAuthorized, StatusVoided}, to: StatusCaptured},
+		{name: "Leave Failed", from: []string{StatusFailed}, to: StatusAuthorized},
+		{name: "Leave Refunded", from: []string{StatusAuthorized, StatusCaptured, StatusRefunded}, to: StatusPartiallyRefunded},
+		{name: "Settle Authorized", from: []string{StatusAuthorized}, to: StatusSettled},
+		{name: "Refund Disputed", from: []string{StatusAuthorized, StatusCaptured, StatusDisputed}, to: StatusRefunded},
+		{name: "Leave Charged Back", from: []string{StatusAuthorized, StatusCaptured, StatusDisputed, StatusChargedBack}, to: StatusSettled},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			now := time.Now()
+			payment := NewPayment("txn123", "user123", Money{Amount: 100, Currency: "USD"}, "user123", "payment requested", now)
+			for _, status := range tc.from {
+				require.NoError(t, payment.TransitionTo(status, "system", "setup", now))
+			}
+			before := payment.Status
+			historyLen := len(payment.StatusHistory)
+
+			// Act
+			err := payment.TransitionTo(tc.to, "system", "test", now)
+
+			// Assert
+			assert.ErrorIs(t, err, ErrInvalidTransition)
+			var transitionErr *TransitionError
+			require.ErrorAs(t, err, &transitionErr)
+			assert.Equal(t, before, transitionErr.From)
+			assert.Equal(t, tc.to, transitionErr.To)
+			assert.Equal(t, before, payment.Status)
+			assert.Len(t, payment.StatusHistory, historyLen)
+		})
+	}
+}
+
+func TestPayment_TransitionTo_UnknownStatus(t *testing.T) {
+	// Arrange
+	payment := NewPayment("txn123", "user123", Money{Amount: 100, Currency: "USD"}, "user123", "payment requested", time.Now())
+
+	// Act
+	err := payment.TransitionTo("completed", "system", "test", time.Now())
+
+	// Assert
+	assert.ErrorIs(t, err, ErrUnknownStatus)
+}
+
+func TestPayment_StatusHistoryRecordsWhoWhenWhy(t *testing.T) {
+	// Arrange
+	created := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	authorized := created.Add(time.Minute)
+	payment := NewPayment("txn123", "user123", Money{Amount: 100, Currency: "USD"}, "user123", "payment requested", created)
+
+	// Act
+	require.NoError(t, payment.TransitionTo(StatusAuthorized, "system", "payment authorized", authorized))
+
+	// Assert
+	assert.Equal(t, []StatusChange{
+		{To: StatusPending, Actor: "user123", Reason: "payment requested", At: created},
+		{From: StatusPending, To: StatusAuthorized, Actor: "system", Reason: "payment authorized", At: authorized},
+	}, payment.StatusHistory)
+}
+
+func TestIsTerminal(t *testing.T) {
+	assert.False(t, IsTerminal(StatusPending))
+	assert.False(t, IsTerminal(StatusCaptured))
+	assert.True(t, IsTerminal(StatusVoided))
+	assert.True(t, IsTerminal(StatusRefunded))
+	assert.True(t, IsTerminal(StatusFailed))
+}
@@ -0,0 +1,198 @@
+package entity
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+	ErrInvalidMoneyAmount  = errors.New("invalid money amount")
+	ErrCurrencyMismatch    = errors.New("currency mismatch")
+)
+
+// currencyExponents maps supported ISO 4217 currency codes to the number of
+// decimal places of their minor unit
+var currencyExponents = map[string]int{
+	"AUD": 2,
+	"BHD": 3,
+	"BRL": 2,
+	"CAD": 2,
+	"CHF": 2,
+	"CNY": 2,
+	"CZK": 2,
+	"DKK": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"HKD": 2,
+	"IDR": 2,
+	"INR": 2,
+	"JOD": 3,
+	"JPY": 0,
+	"KRW": 0,
+	"KWD": 3,
+	"MXN": 2,
+	"MYR": 2,
+	"NOK": 2,
+	"NZD": 2,
+	"OMR": 3,
+	"PHP": 2,
+	"PLN": 2,
+	"SEK": 2,
+	"SGD": 2,
+	"THB": 2,
+	"TND": 3,
+	"TWD": 2,
+	"USD": 2,
+	"VND": 0,
+	"ZAR": 2,
+}
+
+// CurrencyExponent returns the number of minor unit decimal places for an ISO 4217 currency code
+func CurrencyExponent(currency string) (int, bool) {
+	exponent, ok := currencyExponents[currency]
+	return exponent, ok
+}
+
+// Money is an exact monetary amount expressed in the minor unit of its currency
+// (e.g. cents for USD), so arithmetic never suffers from floating point rounding
+type Money struct {
+	Amount   int64  // Amount in minor units
+	Currency string // ISO 4217 currency code
+}
+
+// NewMoney creates Money from an amount in minor units
+func NewMoney(amount int64, currency string) (Money, error) {
+	if _, ok := CurrencyExponent(currency); !ok {
+		return Money{}, fmt.Errorf("%w: %q", ErrUnsupportedCurrency, currency)
+	}
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+// ParseMoney parses a decimal string such as "99.99" in the given currency.
+// The amount may not have more decimal places than the currency's minor unit.
+func ParseMoney(amount, currency string) (Money, error) {
+	exponent, ok := CurrencyExponent(currency)
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %q", ErrUnsupportedCurrency, currency)
+	}
+
+	negative := strings.HasPrefix(amount, "-")
+	whole, fraction, hasFraction := strings.Cut(strings.TrimPrefix(amount, "-"), ".")
+	if whole == "" || (hasFraction && fraction == "") || !isDigits(whole) || !isDigits(fraction) {
+		return Money{}, fmt.Errorf("%w: %q is not a decimal number", ErrInvalidMoneyAmount, amount)
+	}
+	if len(fraction) > exponent {
+		return Money{}, fmt.Errorf("%w: %s allows at most %d decimal places", ErrInvalidMoneyAmount, currency, exponent)
+	}
+
+	minor, err := strconv.ParseInt(whole+fraction+strings.Repeat("0", exponent-len(fraction)), 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q is out of range", ErrInvalidMoneyAmount, amount)
+	}
+	if negative {
+		minor = -minor
+	}
+
+	return Money{Amount: minor, Currency: currency}, nil
+}
+
+// Decimal formats the amount as a decimal string in major units, e.g. "99.99"
+func (m Money) Decimal() string {
+	exponent, _ := CurrencyExponent(m.Currency)
+
+	sign := ""
+	abs := uint64(m.Amount)
+	if m.Amount < 0 {
+		sign = "-"
+		abs = uint64(-m.Amount)
+	}
+
+	digits := strconv.FormatUint(abs, 10)
+	if exponent == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+}
+
+// String formats the amount with its currency code, e.g. "99.99 USD"
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+// IsPositive reports whether the amount is greater than zero
+func (m Money) IsPositive() bool {
+	return m.Amount > 0
+}
+
+// IsZero reports whether the amount is zero
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// Add returns m + other. Both amounts must be in the same currency.
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	if (other.Amount > 0 && m.Amount > math.MaxInt64-other.Amount) ||
+		(other.Amount < 0 && m.Amount < math.MinInt64-other.Amount) {
+		return Money{}, fmt.Errorf("%w: sum overflows", ErrInvalidMoneyAmount)
+	}
+	return Money{Amount: m.Amount + other.Amount, Currency: m.Currency}, nil
+}
+
+// Sub returns m - other. Both amounts must be in the same currency.
+func (m Money) Sub(other Money) (Money, error) {
+	if other.Amount == math.MinInt64 {
+		return Money{}, fmt.Errorf("%w: difference overflows", ErrInvalidMoneyAmount)
+	}
+	return m.Add(Money{Amount: -other.Amount, Currency: other.Currency})
+}
+
+// MoneyJSON is the wire format of Money: a decimal string plus currency code.
+// It is exported so the API documentation can describe Money by it.
+type MoneyJSON struct {
+	Value    string `json:"value" example:"99.99"`  // Amount in major units as a decimal string
+	Currency string `json:"currency" example:"USD"` // ISO 4217 currency code
+}
+
+// MarshalJSON encodes Money as {"value":"99.99","currency":"USD"}
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(MoneyJSON{Value: m.Decimal(), Currency: m.Currency})
+}
+
+// UnmarshalJSON decodes Money from {"value":"99.99","currency":"USD"}
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var wire MoneyJSON
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+	if wire.Currency == "" && (wire.Value == "" || wire.Value == "0") {
+		*m = Money{}
+		return nil
+	}
+	parsed, err := ParseMoney(wire.Value, wire.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// isDigits reports whether s consists only of ASCII digits
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,117 @@
+package entity
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMoney(t *testing.T) {
+	testCases := []struct {
+		name     string
+		amount   string
+		currency string
+		expected Money
+	}{
+		{name: "Two Decimals", amount: "99.99", currency: "USD", expected: Money{Amount: 9999, Currency: "USD"}},
+		{name: "One Decimal", amount: "100.5", currency: "USD", expected: Money{Amount: 10050, Currency: "USD"}},
+		{name: "Whole Number", amount: "7", currency: "EUR", expected: Money{Amount: 700, Currency: "EUR"}},
+		{name: "Zero Exponent", amount: "1500", currency: "JPY", expected: Money{Amount: 1500, Currency: "JPY"}},
+		{name: "Three Decimals", amount: "1.234", currency: "KWD", expected: Money{Amount: 1234, Currency: "KWD"}},
+		{name: "Negative", amount: "-10.50", currency: "USD", expected: Money{Amount: -1050, Currency: "USD"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			money, err := ParseMoney(tc.amount, tc.currency)
+
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, money)
+		})
+	}
+}
+
+func TestParseMoney_Errors(t *testing.T) {
+	testCases := []struct {
+		name        string
+		amount      string
+		currency    string
+		expectedErr error
+	}{
+		{name: "Unsupported Currency", amount: "1.00", currency: "XXX", expectedErr: ErrUnsupportedCurrency},
+		{name: "Lowercase Currency", amount: "1.00", currency: "usd", expectedErr: ErrUnsupportedCurrency},
+		{name: "Too Many Decimals", amount: "1.001", currency: "USD", expectedErr: ErrInvalidMoneyAmount},
+		{name: "Decimals On Zero Exponent", amount: "1.5", currency: "JPY", expectedErr: ErrInvalidMoneyAmount},
+		{name: "Not A Number", amount: "abc", currency: "USD", expectedErr: ErrInvalidMoneyAmount},
+		{name: "Empty", amount: "", currency: "USD", expectedErr: ErrInvalidMoneyAmount},
+		{name: "Trailing Dot", amount: "1.", currency: "USD", expectedErr: ErrInvalidMoneyAmount},
+		{name: "Exponent Notation", amount: "1e3", currency: "USD", expectedErr: ErrInvalidMoneyAmount},
+		{name: "Overflow", amount: "99999999999999999999", currency: "USD", expectedErr: ErrInvalidMoneyAmount},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			_, err := ParseMoney(tc.amount, tc.currency)
+
+			// Assert
+			assert.ErrorIs(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestMoney_Decimal(t *testing.T) {
+	assert.Equal(t, "99.99", Money{Amount: 9999, Currency: "USD"}.Decimal())
+	assert.Equal(t, "0.05", Money{Amount: 5, Currency: "USD"}.Decimal())
+	assert.Equal(t, "-0.30", Money{Amount: -30, Currency: "EUR"}.Decimal())
+	assert.Equal(t, "1500", Money{Amount: 1500, Currency: "JPY"}.Decimal())
+	assert.Equal(t, "0.001", Money{Amount: 1, Currency: "BHD"}.Decimal())
+	assert.Equal(t, "12.34 USD", Money{Amount: 1234, Currency: "USD"}.String())
+}
+
+func TestMoney_AddIsExact(t *testing.T) {
+	// Arrange
+	a, _ := ParseMoney("0.1", "USD")
+	b, _ := ParseMoney("0.2", "USD")
+
+	// Act
+	sum, err := a.Add(b)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "0.30", sum.Decimal())
+}
+
+func TestMoney_CurrencyMismatch(t *testing.T) {
+	// Arrange
+	usd := Money{Amount: 100, Currency: "USD"}
+	eur := Money{Amount: 100, Currency: "EUR"}
+
+	// Act
+	_, addErr := usd.Add(eur)
+	_, subErr := usd.Sub(eur)
+
+	// Assert
+	assert.ErrorIs(t, addErr, ErrCurrencyMismatch)
+	assert.ErrorIs(t, subErr, ErrCurrencyMismatch)
+}
+
+func TestMoney_JSONRoundTrip(t *testing.T) {
+	// Arrange
+	money := Money{Amount: 10050, Currency: "USD"}
+
+	// Act
+	data, err := json.Marshal(money)
+	require.NoError(t, err)
+	var decoded Money
+	err = json.Unmarshal(data, &decoded)
+
+	// Assert
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"value":"100.50","currency":"USD"}`, string(data))
+	assert.Equal(t, money, decoded)
+}
@@ -6,15 +6,94 @@ import (
 
 // Payment represents a payment transaction
 type Payment struct {
-	TransactionID string    `json:"transaction_id"`
-	UserID        string    `json:"user_id"`
-	Amount        float64   `json:"amount"`
-	Status        string    `json:"status"`
-	CreatedAt     time.Time `json:"created_at"`
+	TransactionID          string         `json:"transaction_id"`
+	UserID                 string         `json:"user_id"`
+	Amount                 Money          `json:"amount"`
+	CapturedAmount         Money          `json:"captured_amount"`               // Amount actually captured, at most Amount
+	PaymentMethod          string         `json:"payment_method"`                // PaymentMethodCard or PaymentMethodWallet
+	CardToken              string         `json:"-"`                             // Processor token of the card a card payment is charged to; never returned
+	CardLast4              string         `json:"card_last4,omitempty"`          // Last four digits of the card, when its token ends in them
+	ProcessorReference     string         `json:"processor_reference,omitempty"` // The processor's ID of a card payment, once authorized
+	Processor              string         `json:"processor,omitempty"`           // Name of the processor a card payment is authorized at, stored before it is called
+	MerchantID             string         `json:"merchant_id,omitempty"`         // Merchant the payment is made to, used to route card payments
+	Queued                 bool           `json:"queued,omitempty"`              // Waiting for a worker to process an asynchronous request
+	Status                 string         `json:"status"`
+	CreatedAt              time.Time      `json:"created_at"`
+	AuthorizationExpiresAt *time.Time     `json:"authorization_expires_at,omitempty"` // When an uncaptured authorization lapses
+	RequestFingerprint     string         `json:"-"`                                  // Hash of the creating request, for idempotency conflict detection
+	StatusHistory          []StatusChange `json:"status_history"`                     // Every status change, oldest first
+	Refunds                []Refund       `json:"refunds"`                            // Refunds of the captured amount, oldest first
+	Version                int64          `json:"version"`                            // Incremented on every update, for optimistic concurrency
+	Journal                []JournalEntry `json:"-"`                                  // Ledger entries of this change, recorded when the payment is written
+	PublishedChanges       int            `json:"-"`                                  // How many StatusHistory changes have been published as events
+	PendingAction          *PendingAction `json:"pending_action,omitempty"`           // Processor call claimed and not yet recorded
 }
 
+// UnpublishedChanges reports whether some status changes of the payment have not been published as events
+func (p *Payment) UnpublishedChanges() bool {
+	return p.PublishedChanges < len(p.StatusHistory)
+}
+
+// Clone returns a deep copy of the payment, so the copy can be modified independently
+func (p *Payment) Clone() *Payment {
+	clone := *p
+	clone.StatusHistory = append([]StatusChange(nil), p.StatusHistory...)
+	clone.Refunds = append([]Refund(nil), p.Refunds...)
+	clone.Journal = append([]JournalEntry(nil), p.Journal...)
+	if p.AuthorizationExpiresAt != nil {
+		expiresAt := *p.AuthorizationExpiresAt
+		clone.AuthorizationExpiresAt = &expiresAt
+	}
+	if p.PendingAction != nil {
+		action := *p.PendingAction
+		clone.PendingAction = &action
+	}
+	return &clone
+}
+
+// SetCardToken sets the token of the card a card payment is charged to, and the last four digits shown for it
+func (p *Payment) SetCardToken(token string) {
+	p.CardToken = token
+	p.CardLast4 = cardLast4(token)
+}
+
+// cardLast4 returns the last four characters of a card token if they are digits, as in a card number
+func cardLast4(token string) string {
+	if len(token) < 4 {
+		return ""
+	}
+	last4 := token[len(token)-4:]
+	for _, c := range last4 {
+		if c < '0' || c > '9' {
+			return ""
+		}
+	}
+	return last4
+}
+
+// AuthorizationExpired reports whether the payment holds an authorization whose window has lapsed at now
+func (p *Payment) AuthorizationExpired(now time.Time) bool {
+	return p.Status == StatusAuthorized && p.AuthorizationExpiresAt != nil && !now.Before(*p.AuthorizationExpiresAt)
+}
+
+// Payment methods
+const (
+	// PaymentMethodCard charges an external card; payments stored before payment methods existed are card payments
+	PaymentMethodCard = "card"
+	// PaymentMethodWallet pays from the user's stored-value wallet
+	PaymentMethodWallet = "wallet"
+)
+
 // PaymentStatus constants
 const (
-	StatusCompleted = "completed"
-	StatusFailed    = "failed"
+	StatusPending           = "pending"
+	StatusAuthorized        = "authorized"
+	StatusCaptured          = "captured"
+	StatusVoided            = "voided"
+	StatusRefunded          = "refunded"
+	StatusPartiallyRefunded = "partially_refunded"
+	StatusFailed            = "failed"
+	StatusSettled           = "settled"      // The processor paid out the captured funds
+	StatusDisputed          = "disputed"     // The cardholder disputed the charge with their bank
+	StatusChargedBack       = "charged_back" // The dispute was lost and the funds returned to the cardholder
 )
@@ -0,0 +1,56 @@
+package entity
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPayment_SetCardToken(t *testing.T) {
+	tests := []struct {
+		token string
+		last4 string
+	}{
+		{token: "4242424242424242", last4: "4242"},
+		{token: "tok_visa", last4: ""},
+		{token: "tok_4000000000000002", last4: "0002"},
+		{token: "123", last4: ""},
+		{token: "", last4: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.token, func(t *testing.T) {
+			// Arrange
+			payment := &Payment{}
+
+			// Act
+			payment.SetCardToken(tt.token)
+
+			// Assert
+			assert.Equal(t, tt.token, payment.CardToken)
+			assert.Equal(t, tt.last4, payment.CardLast4)
+		})
+	}
+}
+
+func TestPayment_JSONHidesCardTokenAndFingerprint(t *testing.T) {
+	// Arrange
+	payment := NewPayment("txn123", "user123", Money{Amount: 10000, Currency: "USD"}, "user123", "payment requested", time.Now())
+	payment.SetCardToken("4242424242424242")
+	payment.RequestFingerprint = "fingerprint"
+
+	// Act
+	data, err := json.Marshal(payment)
+
+	// Assert
+	require.NoError(t, err)
+	var fields map[string]any
+	require.NoError(t, json.Unmarshal(data, &fields))
+	assert.Equal(t, "4242", fields["card_last4"])
+	assert.NotContains(t, fields, "card_token")
+	assert.NotContains(t, fields, "request_fingerprint")
+	assert.NotContains(t, string(data), "4242424242424242")
+}
@@ -0,0 +1,55 @@
+package entity
+
+import (
+	"time"
+)
+
+// Refund returns part or all of a captured payment to the payer
+type Refund struct {
+	RefundID       string    `json:"refund_id"`
+	IdempotencyKey string    `json:"idempotency_key"` // Client key that makes retrying the refund safe
+	Amount         Money     `json:"amount"`
+	Reason         string    `json:"reason"` // One of the RefundReason codes
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Refund reason codes
+const (
+	RefundReasonRequestedByCustomer = "requested_by_customer"
+	RefundReasonDuplicate           = "duplicate"
+	RefundReasonFraudulent          = "fraudulent"
+	RefundReasonOther               = "other"
+)
+
+// IsRefundReason reports whether reason is a known refund reason code
+func IsRefundReason(reason string) bool {
+	switch reason {
+	case RefundReasonRequestedByCustomer, RefundReasonDuplicate, RefundReasonFraudulent, RefundReasonOther:
+		return true
+	}
+	return false
+}
+
+// RefundedAmount returns the sum of all refunds of the payment
+func (p *Payment) RefundedAmount() Money {
+	total := Money{Currency: p.Amount.Currency}
+	for _, refund := range p.Refunds {
+		total.Amount += refund.Amount.Amount
+	}
+	return total
+}
+
+// RefundableAmount returns how much of the captured amount has not been refunded yet
+func (p *Payment) RefundableAmount() Money {
+	return Money{Amount: p.CapturedAmount.Amount - p.RefundedAmount().Amount, Currency: p.Amount.Currency}
+}
+
+// FindRefund returns the refund recorded under the idempotency key, or nil
+func (p *Payment) FindRefund(idempotencyKey string) *Refund {
+	for i := range p.Refunds {
+		if p.Refunds[i].IdempotencyKey == idempotencyKey {
+			return &p.Refunds[i]
+		}
+	}
+	return nil
+}
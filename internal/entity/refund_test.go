@@ -0,0 +1,31 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPayment_RefundAmounts(t *testing.T) {
+	// Arrange
+	payment := NewPayment("txn123", "user123", Money{Amount: 10000, Currency: "USD"}, "user123", "payment requested", time.Now())
+	payment.CapturedAmount = Money{Amount: 8000, Currency: "USD"}
+	payment.Refunds = []Refund{
+		{IdempotencyKey: "refund-1", Amount: Money{Amount: 2500, Currency: "USD"}, Reason: RefundReasonRequestedByCustomer},
+		{IdempotencyKey: "refund-2", Amount: Money{Amount: 500, Currency: "USD"}, Reason: RefundReasonOther},
+	}
+
+	// Act & Assert
+	assert.Equal(t, Money{Amount: 3000, Currency: "USD"}, payment.RefundedAmount())
+	assert.Equal(t, Money{Amount: 5000, Currency: "USD"}, payment.RefundableAmount())
+	assert.Equal(t, &payment.Refunds[1], payment.FindRefund("refund-2"))
+	assert.Nil(t, payment.FindRefund("refund-3"))
+}
+
+func TestIsRefundReason(t *testing.T) {
+	assert.True(t, IsRefundReason(RefundReasonRequestedByCustomer))
+	assert.True(t, IsRefundReason(RefundReasonFraudulent))
+	assert.False(t, IsRefundReason(""))
+	assert.False(t, IsRefundReason("changed_mind"))
+}
@@ -0,0 +1,114 @@
+package entity
+
+import (
+	"sort"
+	"time"
+)
+
+// Statement entry types
+const (
+	EntryPayment = "payment"
+	EntryRefund  = "refund"
+)
+
+// Statement summarizes a user's payments and refunds over one calendar month (UTC)
+type Statement struct {
+	UserID      string           `json:"user_id"`
+	Month       string           `json:"month"`        // Calendar month as YYYY-MM
+	PeriodStart time.Time        `json:"period_start"` // Start of the month, inclusive
+	PeriodEnd   time.Time        `json:"period_end"`   // Start of the next month, exclusive
+	Entries     []StatementEntry `json:"entries"`      // Payments and refunds of the month, oldest first
+	Totals      []StatementTotal `json:"totals"`       // One total per currency, ordered by currency code
+}
+
+// StatementEntry is one payment or refund on a statement
+type StatementEntry struct {
+	Type          string    `json:"type"` // EntryPayment or EntryRefund
+	At            time.Time `json:"at"`
+	TransactionID string    `json:"transaction_id"`
+	RefundID      string    `json:"refund_id,omitempty"`
+	Status        string    `json:"status,omitempty"` // Current payment status, for payment entries
+	Amount        Money     `json:"amount"`           // Captured amount for payments, refunded amount for refunds
+}
+
+// StatementTotal sums the entries of one currency
+type StatementTotal struct {
+	Currency string `json:"currency"`
+	Payments int    `json:"payments"` // Number of payments created in the month
+	Captured Money  `json:"captured"` // Captured by payments created in the month
+	Refunded Money  `json:"refunded"` // Refunded during the month, whenever the payment was made
+	Net      Money  `json:"net"`      // Captured minus refunded
+}
+
+// NewStatement builds the statement of the month starting at periodStart from a user's payments.
+// Payments count in the month they were created; refunds count in the month they were made,
+// so payments created earlier are needed for their refunds.
+func NewStatement(userID string, periodStart time.Time, payments []*Payment) *Statement {
+	periodEnd := periodStart.AddDate(0, 1, 0)
+	inPeriod := func(at time.Time) bool {
+		return !at.Before(periodStart) && at.Before(periodEnd)
+	}
+
+	statement := &Statement{
+		UserID:      userID,
+		Month:       periodStart.Format("2006-01"),
+		PeriodStart: periodStart,
+		PeriodEnd:   periodEnd,
+		Entries:     []StatementEntry{},
+		Totals:      []StatementTotal{},
+	}
+	totals := make(map[string]*StatementTotal)
+	total := func(currency string) *StatementTotal {
+		if totals[currency] == nil {
+			totals[currency] = &StatementTotal{
+				Currency: currency,
+				Captured: Money{Currency: currency},
+				Refunded: Money{Currency: currency},
+			}
+		}
+		return totals[currency]
+	}
+
+	for _, payment := range payments {
+		if payment.UserID != userID {
+			continue
+		}
+		if inPeriod(payment.CreatedAt) {
+			statement.Entries = append(statement.Entries, StatementEntry{
+				Type:          EntryPayment,
+				At:            payment.CreatedAt,
+				TransactionID: payment.TransactionID,
+				Status:        payment.Status,
+				Amount:        payment.CapturedAmount,
+			})
+			t := total(payment.Amount.Currency)
+			t.Payments++
+			t.Captured.Amount += payment.CapturedAmount.Amount
+		}
+		for _, refund := range payment.Refunds {
+			if !inPeriod(refund.CreatedAt) {
+				continue
+			}
+			statement.Entries = append(statement.Entries, StatementEntry{
+				Type:          EntryRefund,
+				At:            refund.CreatedAt,
+				TransactionID: payment.TransactionID,
+				RefundID:      refund.RefundID,
+				Amount:        refund.Amount,
+			})
+			total(refund.Amount.Currency).Refunded.Amount += refund.Amount.Amount
+		}
+	}
+
+	sort.SliceStable(statement.Entries, func(i, j int) bool {
+		return statement.Entries[i].At.Before(statement.Entries[j].At)
+	})
+	for _, t := range totals {
+		t.Net = Money{Amount: t.Captured.Amount - t.Refunded.Amount, Currency: t.Currency}
+		statement.Totals = append(statement.Totals, *t)
+	}
+	sort.Slice(statement.Totals, func(i, j int) bool {
+		return statement.Totals[i].Currency < statement.Totals[j].Currency
+	})
+	return statement
+}
@@ -0,0 +1,62 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewStatement(t *testing.T) {
+	// Arrange
+	may := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	april := NewPayment("txn-april", "user123", Money{Amount: 5000, Currency: "USD"}, "user123", "payment requested", may.AddDate(0, 0, -10))
+	april.CapturedAmount = april.Amount
+	april.Refunds = []Refund{{RefundID: "txn-april-refund-1", Amount: Money{Amount: 1000, Currency: "USD"}, CreatedAt: may.AddDate(0, 0, 3)}}
+
+	mayUSD := NewPayment("txn-may-usd", "user123", Money{Amount: 10000, Currency: "USD"}, "user123", "payment requested", may.AddDate(0, 0, 1))
+	mayUSD.CapturedAmount = Money{Amount: 8000, Currency: "USD"}
+	mayUSD.Refunds = []Refund{
+		{RefundID: "txn-may-usd-refund-1", Amount: Money{Amount: 500, Currency: "USD"}, CreatedAt: may.AddDate(0, 0, 2)},
+		{RefundID: "txn-may-usd-refund-2", Amount: Money{Amount: 700, Currency: "USD"}, CreatedAt: may.AddDate(0, 1, 0)},
+	}
+
+	mayEUR := NewPayment("txn-may-eur", "user123", Money{Amount: 2500, Currency: "EUR"}, "user123", "payment requested", may.AddDate(0, 0, 5))
+	pendingJune := NewPayment("txn-june", "user123", Money{Amount: 100, Currency: "USD"}, "user123", "payment requested", may.AddDate(0, 1, 0))
+	otherUser := NewPayment("txn-other", "other", Money{Amount: 100, Currency: "USD"}, "other", "payment requested", may.AddDate(0, 0, 1))
+
+	// Act
+	statement := NewStatement("user123", may, []*Payment{mayEUR, april, mayUSD, pendingJune, otherUser})
+
+	// Assert
+	assert.Equal(t, "2024-05", statement.Month)
+	assert.Equal(t, may.AddDate(0, 1, 0), statement.PeriodEnd)
+
+	var entries []string
+	for _, entry := range statement.Entries {
+		entries = append(entries, entry.Type+" "+entry.TransactionID+entry.RefundID)
+	}
+	assert.Equal(t, []string{
+		"payment txn-may-usd",
+		"refund txn-may-usdtxn-may-usd-refund-1",
+		"refund txn-apriltxn-april-refund-1",
+		"payment txn-may-eur",
+	}, entries)
+
+	require.Len(t, statement.Totals, 2)
+	assert.Equal(t, StatementTotal{
+		Currency: "EUR",
+		Payments: 1,
+		Captured: Money{Currency: "EUR"},
+		Refunded: Money{Currency: "EUR"},
+		Net:      Money{Currency: "EUR"},
+	}, statement.Totals[0])
+	assert.Equal(t, StatementTotal{
+		Currency: "USD",
+		Payments: 1,
+		Captured: Money{Amount: 8000, Currency: "USD"},
+		Refunded: Money{Amount: 1500, Currency: "USD"},
+		Net:      Money{Amount: 6500, Currency: "USD"},
+	}, statement.Totals[1])
+}
@@ -0,0 +1,39 @@
+package entity
+
+import "time"
+
+// Transfer statuses
+const (
+	TransferPending   = "pending"   // Recorded, funds not moved yet
+	TransferCompleted = "completed" // Funds moved from the sender's wallet to the recipient's
+	TransferDeclined  = "declined"  // Funds not moved because of the sender's balance or limits; may be retried
+)
+
+// Wallet transaction types of transfers
+const (
+	WalletTransferOut = "transfer_out" // Funds sent to another user
+	WalletTransferIn  = "transfer_in"  // Funds received from another user
+)
+
+// Transfer moves funds from one user's wallet to another's
+type Transfer struct {
+	TransactionID      string     `json:"transaction_id"`
+	FromUserID         string     `json:"from_user_id"`
+	ToUserID           string     `json:"to_user_id"`
+	Amount             Money      `json:"amount"`
+	Status             string     `json:"status"`
+	CreatedAt          time.Time  `json:"created_at"`
+	CompletedAt        *time.Time `json:"completed_at,omitempty"`
+	RequestFingerprint string     `json:"request_fingerprint,omitempty"` // Hash of the creating request, for idempotency conflict detection
+	Version            int64      `json:"version"`                       // Incremented on every update, for optimistic concurrency
+}
+
+// Clone returns a copy of the transfer, so the copy can be modified independently
+func (t *Transfer) Clone() *Transfer {
+	clone := *t
+	if t.CompletedAt != nil {
+		completedAt := *t.CompletedAt
+		clone.CompletedAt = &completedAt
+	}
+	return &clone
+}
@@ -0,0 +1,126 @@
+package entity
+
+import (
+	"time"
+)
+
+// Wallet transaction types
+const (
+	WalletTopUp   = "top_up"  // Funds added by the user
+	WalletDebit   = "debit"   // Funds withdrawn from the wallet
+	WalletPayment = "payment" // A payment paid from the wallet
+	WalletRefund  = "refund"  // A refund of a wallet payment returned to the wallet
+)
+
+// Wallet holds a user's stored-value balances, one per currency
+type Wallet struct {
+	UserID       string              `json:"user_id"`
+	Balances     map[string]Money    `json:"balances"`     // Balance per currency code
+	Transactions []WalletTransaction `json:"transactions"` // Every balance change, oldest first
+	Version      int64               `json:"version"`      // Incremented on every save, for optimistic concurrency
+	Journal      []JournalEntry      `json:"-"`            // Ledger entries of this change, recorded when the wallet is saved
+}
+
+// WalletTransaction is one change of a wallet balance
+type WalletTransaction struct {
+	ID           string    `json:"id"`                     // Unique within the wallet; a transaction ID is never applied twice
+	Type         string    `json:"type"`                   // WalletTopUp, WalletDebit, WalletPayment, WalletRefund, WalletTransferOut or WalletTransferIn
+	Amount       Money     `json:"amount"`                 // Amount moved, always positive
+	Balance      Money     `json:"balance"`                // Balance in the same currency after the transaction
+	Reference    string    `json:"reference,omitempty"`    // Transaction ID of the payment or transfer
+	Counterparty string    `json:"counterparty,omitempty"` // The other user of a transfer
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// NewWallet creates an empty wallet for a user
+func NewWallet(userID string) *Wallet {
+	return &Wallet{
+		UserID:       userID,
+		Balances:     make(map[string]Money),
+		Transactions: []WalletTransaction{},
+	}
+}
+
+// Clone returns a deep copy of the wallet, so the copy can be modified independently
+func (w *Wallet) Clone() *Wallet {
+	clone := *w
+	clone.Balances = make(map[string]Money, len(w.Balances))
+	for currency, balance := range w.Balances {
+		clone.Balances[currency] = balance
+	}
+	clone.Transactions = append([]WalletTransaction(nil), w.Transactions...)
+	clone.Journal = append([]JournalEntry(nil), w.Journal...)
+	return &clone
+}
+
+// Balance returns the wallet balance in a currency, zero if the wallet never held it
+func (w *Wallet) Balance(currency string) Money {
+	if balance, ok := w.Balances[currency]; ok {
+		return balance
+	}
+	return Money{Currency: currency}
+}
+
+// FindTransaction returns the transaction with the given ID, or nil if there is none
+func (w *Wallet) FindTransaction(id string) *WalletTransaction {
+	for i := range w.Transactions {
+		if w.Transactions[i].ID == id {
+			return &w.Transactions[i]
+		}
+	}
+	return nil
+}
+
+// Credit adds amount to the wallet and records the transaction
+func (w *Wallet) Credit(id, transactionType, reference string, amount Money, at time.Time) WalletTransaction {
+	return w.apply(WalletTransaction{ID: id, Type: transactionType, Amount: amount, Reference: reference, CreatedAt: at}, amount.Amount)
+}
+
+// Debit takes amount from the wallet and records the transaction.
+// It reports false and changes nothing if the balance is lower than amount.
+func (w *Wallet) Debit(id, transactionType, reference string, amount Money, at time.Time) (WalletTransaction, bool) {
+	return w.debit(WalletTransaction{ID: id, Type: transactionType, Amount: amount, Reference: reference, CreatedAt: at})
+}
+
+// TransferOut takes the amount of a transfer to counterparty from the wallet, like Debit
+func (w *Wallet) TransferOut(id, transactionID, counterparty string, amount Money, at time.Time) (WalletTransaction, bool) {
+	return w.debit(WalletTransaction{ID: id, Type: WalletTransferOut, Amount: amount, Reference: transactionID, Counterparty: counterparty, CreatedAt: at})
+}
+
+// TransferIn adds the amount of a transfer from counterparty to the wallet, like Credit
+func (w *Wallet) TransferIn(id, transactionID, counterparty string, amount Money, at time.Time) WalletTransaction {
+	return w.apply(WalletTransaction{ID: id, Type: WalletTransferIn, Amount: amount, Reference: transactionID, Counterparty: counterparty, CreatedAt: at}, amount.Amount)
+}
+
+// SentSince sums the transfers sent in currency at or after since
+func (w *Wallet) SentSince(currency string, since time.Time) Money {
+	sent := Money{Currency: currency}
+	for _, transaction := range w.Transactions {
+		if transaction.Type == WalletTransferOut && transaction.Amount.Currency == currency && !transaction.CreatedAt.Before(since) {
+			sent.Amount += transaction.Amount.Amount
+		}
+	}
+	return sent
+}
+
+// debit applies transaction as a debit unless the balance is lower than its amount
+func (w *Wallet) debit(transaction WalletTransaction) (WalletTransaction, bool) {
+	if w.Balance(transaction.Amount.Currency).Amount < transaction.Amount.Amount {
+		return WalletTransaction{}, false
+	}
+	return w.apply(transaction, -transaction.Amount.Amount), true
+}
+
+// apply changes the balance by delta minor units and appends the transaction with the new balance
+func (w *Wallet) apply(transaction WalletTransaction, delta int64) WalletTransaction {
+	if w.Balances == nil {
+		w.Balances = make(map[string]Money)
+	}
+	balance := w.Balance(transaction.Amount.Currency)
+	balance.Amount += delta
+	w.Balances[transaction.Amount.Currency] = balance
+
+	transaction.Balance = balance
+	w.Transactions = append(w.Transactions, transaction)
+	return transaction
+}
@@ -0,0 +1,86 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWallet_CreditAndDebit(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	wallet := NewWallet("user123")
+
+	// Act
+	wallet.Credit("top_up:1", WalletTopUp, "", Money{Amount: 5000, Currency: "USD"}, now)
+	debit, ok := wallet.Debit("payment:txn123", WalletPayment, "txn123", Money{Amount: 1250, Currency: "USD"}, now)
+
+	// Assert
+	require.True(t, ok)
+	assert.Equal(t, Money{Amount: 3750, Currency: "USD"}, debit.Balance)
+	assert.Equal(t, Money{Amount: 3750, Currency: "USD"}, wallet.Balance("USD"))
+	assert.Equal(t, Money{Currency: "EUR"}, wallet.Balance("EUR"))
+	assert.Len(t, wallet.Transactions, 2)
+	assert.Equal(t, &wallet.Transactions[1], wallet.FindTransaction("payment:txn123"))
+	assert.Nil(t, wallet.FindTransaction("payment:txn456"))
+}
+
+func TestWallet_DebitInsufficientFunds(t *testing.T) {
+	// Arrange
+	wallet := NewWallet("user123")
+	wallet.Credit("top_up:1", WalletTopUp, "", Money{Amount: 1000, Currency: "USD"}, time.Now())
+
+	// Act
+	_, sameCurrency := wallet.Debit("debit:1", WalletDebit, "", Money{Amount: 1001, Currency: "USD"}, time.Now())
+	_, otherCurrency := wallet.Debit("debit:2", WalletDebit, "", Money{Amount: 1, Currency: "EUR"}, time.Now())
+
+	// Assert
+	assert.False(t, sameCurrency)
+	assert.False(t, otherCurrency, "balances in other currencies cannot pay")
+	assert.Equal(t, Money{Amount: 1000, Currency: "USD"}, wallet.Balance("USD"))
+	assert.Len(t, wallet.Transactions, 1)
+}
+
+func TestWallet_Clone(t *testing.T) {
+	// Arrange
+	wallet := NewWallet("user123")
+	wallet.Credit("top_up:1", WalletTopUp, "", Money{Amount: 1000, Currency: "USD"}, time.Now())
+
+	// Act
+	clone := wallet.Clone()
+	clone.Credit("top_up:2", WalletTopUp, "", Money{Amount: 500, Currency: "USD"}, time.Now())
+
+	// Assert
+	assert.Equal(t, Money{Amount: 1000, Currency: "USD"}, wallet.Balance("USD"))
+	assert.Len(t, wallet.Transactions, 1)
+	assert.Equal(t, Money{Amount: 1500, Currency: "USD"}, clone.Balance("USD"))
+}
+
+func TestWallet_TransfersAndSentSince(t *testing.T) {
+	// Arrange
+	now := time.Date(2026, 5, 2, 12, 0, 0, 0, time.UTC)
+	sender := NewWallet("user123")
+	recipient := NewWallet("user456")
+	sender.Credit("top_up:1", WalletTopUp, "", Money{Amount: 10000, Currency: "USD"}, now.Add(-48*time.Hour))
+
+	// Act
+	_, old := sender.TransferOut("transfer:t1", "t1", "user456", Money{Amount: 3000, Currency: "USD"}, now.Add(-25*time.Hour))
+	out, recent := sender.TransferOut("transfer:t2", "t2", "user456", Money{Amount: 2000, Currency: "USD"}, now.Add(-time.Hour))
+	_, overdraft := sender.TransferOut("transfer:t3", "t3", "user456", Money{Amount: 5001, Currency: "USD"}, now)
+	in := recipient.TransferIn("transfer:t2", "t2", "user123", Money{Amount: 2000, Currency: "USD"}, now.Add(-time.Hour))
+
+	// Assert
+	assert.True(t, old)
+	assert.True(t, recent)
+	assert.False(t, overdraft)
+	assert.Equal(t, WalletTransferOut, out.Type)
+	assert.Equal(t, "user456", out.Counterparty)
+	assert.Equal(t, Money{Amount: 5000, Currency: "USD"}, out.Balance)
+	assert.Equal(t, WalletTransferIn, in.Type)
+	assert.Equal(t, Money{Amount: 2000, Currency: "USD"}, recipient.Balance("USD"))
+	assert.Equal(t, Money{Amount: 2000, Currency: "USD"}, sender.SentSince("USD", now.Add(-24*time.Hour)))
+	assert.Equal(t, Money{Amount: 5000, Currency: "USD"}, sender.SentSince("USD", now.Add(-48*time.Hour)))
+	assert.Equal(t, Money{Currency: "EUR"}, sender.SentSince("EUR", now.Add(-48*time.Hour)))
+}
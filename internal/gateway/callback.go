@@ -0,0 +1,76 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"payment-service/internal/usecase"
+	"payment-service/internal/webhook"
+	"time"
+)
+
+// ErrInvalidCallback is returned for a callback body that is not a processor event of a known type
+var ErrInvalidCallback = errors.New("callback is not a processor event of a known type")
+
+// Callback types the simulated processors send, and the processor events they report
+var callbackTypes = map[string]string{
+	"charge.settled":         usecase.ProviderEventSettled,
+	"charge.failed":          usecase.ProviderEventDeclined,
+	"charge.dispute.created": usecase.ProviderEventDisputeOpened,
+	"charge.dispute.won":     usecase.ProviderEventDisputeWon,
+	"charge.dispute.lost":    usecase.ProviderEventDisputeLost,
+}
+
+// Callback is the body of an asynchronous notification from a simulated processor
+type Callback struct {
+	ID            string    `json:"id"`             // Unique per event; redeliveries repeat it
+	Type          string    `json:"type"`           // Such as charge.settled or charge.dispute.created
+	Reference     string    `json:"reference"`      // The processor's reference of the payment, such as sim_txn123
+	TransactionID string    `json:"transaction_id"` // Transaction ID the payment was authorized with
+	Reason        string    `json:"reason,omitempty"`
+	Created       time.Time `json:"created"`
+}
+
+// Callbacks verifies and decodes the callbacks of the simulated processors. Each processor signs
+// its callbacks with its own secret, in the Webhook-Signature format of webhook.Sign.
+type Callbacks struct {
+	secrets   map[string]string
+	tolerance time.Duration
+	now       func() time.Time
+}
+
+// NewCallbacks accepts callbacks from the processors with a secret in secrets, keyed by processor name
+func NewCallbacks(secrets map[string]string) *Callbacks {
+	return &Callbacks{secrets: secrets, tolerance: webhook.DefaultTolerance, now: time.Now}
+}
+
+// Parse verifies the signature of a processor's callback and decodes it into a processor event.
+// It fails with ErrUnknownProcessor for a processor without a secret, with webhook.ErrInvalidSignature
+// or webhook.ErrSignatureExpired for a callback that was not signed with the secret just now, and
+// with ErrInvalidCallback for a body that cannot be decoded.
+func (c *Callbacks) Parse(processor, signature string, body []byte) (*usecase.ProviderEvent, error) {
+	secret, ok := c.secrets[processor]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownProcessor, processor)
+	}
+	if err := webhook.Verify(secret, signature, body, c.now(), c.tolerance); err != nil {
+		return nil, err
+	}
+
+	var callback Callback
+	if err := json.Unmarshal(body, &callback); err != nil {
+		return nil, ErrInvalidCallback
+	}
+	eventType, known := callbackTypes[callback.Type]
+	if !known {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidCallback, callback.Type)
+	}
+	return &usecase.ProviderEvent{
+		ID:            callback.ID,
+		Provider:      processor,
+		Type:          eventType,
+		TransactionID: callback.TransactionID,
+		Reference:     callback.Reference,
+		Reason:        callback.Reason,
+	}, nil
+}
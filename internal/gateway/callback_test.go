@@ -0,0 +1,58 @@
+package gateway
+
+import (
+	"payment-service/internal/usecase"
+	"payment-service/internal/webhook"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallbacks_Parse(t *testing.T) {
+	// Arrange
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	callbacks := NewCallbacks(map[string]string{"primary": "whsec_primary"})
+	callbacks.now = func() time.Time { return now }
+	body := []byte(`{"id":"evt_1","type":"charge.dispute.created","reference":"sim_txn123","transaction_id":"txn123","reason":"fraudulent","created":"2026-05-01T11:59:00Z"}`)
+	unknownType := []byte(`{"id":"evt_2","type":"charge.refund.updated","transaction_id":"txn123"}`)
+
+	tests := []struct {
+		name      string
+		processor string
+		signature string
+		body      []byte
+		err       error
+	}{
+		{"valid", "primary", webhook.Sign("whsec_primary", now, body), body, nil},
+		{"unknown processor", "secondary", webhook.Sign("whsec_primary", now, body), body, ErrUnknownProcessor},
+		{"wrong secret", "primary", webhook.Sign("whsec_other", now, body), body, webhook.ErrInvalidSignature},
+		{"unsigned", "primary", "", body, webhook.ErrInvalidSignature},
+		{"replayed", "primary", webhook.Sign("whsec_primary", now.Add(-time.Hour), body), body, webhook.ErrSignatureExpired},
+		{"unknown type", "primary", webhook.Sign("whsec_primary", now, unknownType), unknownType, ErrInvalidCallback},
+		{"not JSON", "primary", webhook.Sign("whsec_primary", now, []byte("{")), []byte("{"), ErrInvalidCallback},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			event, err := callbacks.Parse(tt.processor, tt.signature, tt.body)
+
+			// Assert
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, &usecase.ProviderEvent{
+				ID:            "evt_1",
+				Provider:      "primary",
+				Type:          usecase.ProviderEventDisputeOpened,
+				TransactionID: "txn123",
+				Reference:     "sim_txn123",
+				Reason:        "fraudulent",
+			}, event)
+		})
+	}
+}
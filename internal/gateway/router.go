@@ -0,0 +1,463 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"payment-service/internal/entity"
+	"payment-service/internal/usecase"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNoRoute          = errors.New("no payment processor accepts the payment")
+	ErrUnknownProcessor = errors.New("unknown payment processor")
+)
+
+// Default health policy of a Router
+const (
+	DefaultFailureThreshold = 3
+	DefaultCooldown         = 30 * time.Second
+	// MaxDecisions bounds the routing decisions a Router keeps for debugging
+	MaxDecisions = 1000
+)
+
+// Attempt outcomes recorded in routing decisions
+const (
+	OutcomeApproved    = "approved"
+	OutcomeDeclined    = "declined"
+	OutcomeSoftDecline = "soft_declined"
+	OutcomeTimeout     = "timeout"
+	OutcomeUnavailable = "unavailable"
+	OutcomeError       = "error"
+)
+
+// Route makes a processor a candidate for the payments it matches. Empty lists and zero
+// amounts match everything; amounts are in minor units of the payment currency.
+type Route struct {
+	Processor  string   `json:"processor"`
+	Currencies []string `json:"currencies,omitempty"`
+	Merchants  []string `json:"merchants,omitempty"`
+	MinAmount  int64    `json:"min_amount,omitempty"`
+	MaxAmount  int64    `json:"max_amount,omitempty"`
+}
+
+// matches reports whether the route accepts the authorization
+func (r Route) matches(req usecase.GatewayAuthorization) bool {
+	if len(r.Currencies) > 0 && !contains(r.Currencies, req.Amount.Currency) {
+		return false
+	}
+	if len(r.Merchants) > 0 && !contains(r.Merchants, req.MerchantID) {
+		return false
+	}
+	if r.MinAmount > 0 && req.Amount.Amount < r.MinAmount {
+		return false
+	}
+	return r.MaxAmount == 0 || req.Amount.Amount <= r.MaxAmount
+}
+
+// Decision records how the router handled the authorization of one transaction
+type Decision struct {
+	TransactionID string    `json:"transaction_id"`
+	Candidates    []string  `json:"candidates"`          // Matching processors in the order they were tried
+	Attempts      []Attempt `json:"attempts"`            // Calls made, in order
+	Processor     string    `json:"processor,omitempty"` // Processor whose answer was final: it approved or hard declined
+	At            time.Time `json:"at"`
+}
+
+// Attempt is one authorization call made for a routing decision
+type Attempt struct {
+	Processor string `json:"processor"`
+	Outcome   string `json:"outcome"`
+	Detail    string `json:"detail,omitempty"` // Decline code or error message
+	Unhealthy bool   `json:"unhealthy"`        // The processor was unhealthy when tried, so it was tried last
+}
+
+// ProcessorHealth is the health the router tracks for a processor.
+// A processor is unhealthy once FailureThreshold calls in a row time out or fail, until Cooldown passes.
+type ProcessorHealth struct {
+	Processor           string     `json:"processor"`
+	Healthy             bool       `json:"healthy"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastFailure         *time.Time `json:"last_failure,omitempty"`
+}
+
+// Router is a PaymentGateway that routes every authorization among several processors.
+// Candidates come from the routes matching the payment, with unhealthy processors tried last.
+// Processor failures and soft declines fail over to the next candidate; hard declines do not.
+// A processor that times out may still have authorized the payment, so it is asked for the
+// authorization's status first, and only one it has no record of fails over. Failover only
+// happens at authorization: captures, voids and refunds always go to the processor that
+// authorized the payment, so the payment is charged once.
+//
+// References returned by the router are prefixed with the processor name, so later calls
+// reach the same processor.
+type Router struct {
+	processors       map[string]Processor
+	routes           []Route
+	failureThreshold int
+	cooldown         time.Duration
+	now              func() time.Time
+
+	mutex     sync.Mutex
+	health    map[string]*ProcessorHealth
+	decisions []*Decision
+	byTxn     map[string]*Decision
+}
+
+// Processor is a payment processor a Router routes among. Reference returns the processor's
+// reference for the authorization of a transaction, and Status fails with ErrUnknownReference
+// for one it has no record of, so the router can learn the outcome of an authorization that
+// timed out.
+type Processor interface {
+	usecase.PaymentGateway
+	Reference(transactionID string) string
+}
+
+// RouterOption configures optional Router behaviour
+type RouterOption func(*Router)
+
+// WithHealthPolicy sets how many failures in a row make a processor unhealthy, and for how long
+func WithHealthPolicy(failureThreshold int, cooldown time.Duration) RouterOption {
+	return func(r *Router) {
+		r.failureThreshold = failureThreshold
+		r.cooldown = cooldown
+	}
+}
+
+// NewRouter creates a router over the named processors. Every route must name one of them.
+func NewRouter(processors map[string]Processor, routes []Route, opts ...RouterOption) (*Router, error) {
+	r := &Router{
+		processors:       processors,
+		routes:           routes,
+		failureThreshold: DefaultFailureThreshold,
+		cooldown:         DefaultCooldown,
+		now:              time.Now,
+		health:           make(map[string]*ProcessorHealth),
+		byTxn:            make(map[string]*Decision),
+	}
+	for name := range processors {
+		if strings.Contains(name, ":") {
+			return nil, fmt.Errorf("processor name %q must not contain ':'", name)
+		}
+		r.health[name] = &ProcessorHealth{Processor: name, Healthy: true}
+	}
+	for _, route := range routes {
+		if _, ok := processors[route.Processor]; !ok {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownProcessor, route.Processor)
+		}
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r, nil
+}
+
+// Authorize tries the candidate processors in order until one approves or hard declines the payment.
+// An authorization naming its Processor goes to that processor only, which lets a caller store the
+// processor before the authorization is made; the caller fails over by naming the next candidate.
+// Otherwise a transaction that a processor already answered is sent to that processor again, so
+// retries return the original outcome instead of authorizing elsewhere.
+func (r *Router) Authorize(ctx context.Context, req usecase.GatewayAuthorization) (*usecase.GatewayResult, error) {
+	decision, candidates, previous, err := r.begin(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.record(decision, previous)
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("%w: %w", usecase.ErrGatewayUnavailable, ErrNoRoute)
+	}
+
+	// The processor itself is not told which processor the authorization goes to
+	forwarded := req
+	forwarded.Processor = ""
+	var (
+		lastResult *usecase.GatewayResult
+		lastErr    error
+	)
+	for _, name := range candidates {
+		// A caller that has given up gets no failover
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		attempt := Attempt{Processor: name, Unhealthy: !r.healthy(name)}
+		result, err := r.processors[name].Authorize(ctx, forwarded)
+		r.observe(name, err)
+		if errors.Is(err, usecase.ErrGatewayTimeout) {
+			attempt.Outcome, attempt.Detail = OutcomeTimeout, err.Error()
+			if result, err = r.lookup(ctx, name, req.TransactionID, err); err == nil {
+				attempt.Detail = "answered through its status after timing out"
+			}
+		}
+
+		switch {
+		case err == nil && result.SoftDecline:
+			attempt.Outcome, attempt.Detail = OutcomeSoftDecline, result.DeclineCode
+		case err == nil:
+			attempt.Outcome = OutcomeApproved
+			if result.DeclineCode != "" {
+				attempt.Outcome, attempt.Detail = OutcomeDeclined, result.DeclineCode
+			}
+			decision.Attempts = append(decision.Attempts, attempt)
+			decision.Processor = name
+			return routed(name, result), nil
+		case errors.Is(err, usecase.ErrGatewayTimeout) && !errors.Is(err, usecase.ErrGatewayUnavailable):
+			// The processor may have authorized the payment, so no other processor may
+			decision.Attempts = append(decision.Attempts, attempt)
+			return nil, err
+		case errors.Is(err, usecase.ErrGatewayTimeout):
+			// A timed-out authorization the processor has no record of fails over
+		case errors.Is(err, usecase.ErrGatewayUnavailable):
+			attempt.Outcome, attempt.Detail = OutcomeUnavailable, err.Error()
+		default:
+			attempt.Outcome, attempt.Detail = OutcomeError, err.Error()
+			decision.Attempts = append(decision.Attempts, attempt)
+			return nil, err
+		}
+		decision.Attempts = append(decision.Attempts, attempt)
+		lastResult, lastErr = result, err
+	}
+
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	return routed(candidates[len(candidates)-1], lastResult), nil
+}
+
+// Capture captures the payment at the processor that authorized it
+func (r *Router) Capture(ctx context.Context, reference string, amount entity.Money) (*usecase.GatewayResult, error) {
+	return r.forward(reference, func(processor usecase.PaymentGateway, inner string) (*usecase.GatewayResult, error) {
+		return processor.Capture(ctx, inner, amount)
+	})
+}
+
+// Void releases the authorization at the processor that made it
+func (r *Router) Void(ctx context.Context, reference string) (*usecase.GatewayResult, error) {
+	return r.forward(reference, func(processor usecase.PaymentGateway, inner string) (*usecase.GatewayResult, error) {
+		return processor.Void(ctx, inner)
+	})
+}
+
+// Refund refunds the payment at the processor that authorized it
+func (r *Router) Refund(ctx context.Context, reference, refundKey string, amount entity.Money) (*usecase.GatewayResult, error) {
+	return r.forward(reference, func(processor usecase.PaymentGateway, inner string) (*usecase.GatewayResult, error) {
+		return processor.Refund(ctx, inner, refundKey, amount)
+	})
+}
+
+// Status asks the processor that authorized the payment for its status
+func (r *Router) Status(ctx context.Context, reference string) (*usecase.GatewayResult, error) {
+	return r.forward(reference, func(processor usecase.PaymentGateway, inner string) (*usecase.GatewayResult, error) {
+		return processor.Status(ctx, inner)
+	})
+}
+
+// Candidates returns the processors to try for an authorization, in order. A transaction that
+// a processor already answered has that processor as its only candidate.
+func (r *Router) Candidates(req usecase.GatewayAuthorization) []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.candidatesLocked(req)
+}
+
+// Decision returns the latest routing decision for a transaction, or nil if the router has none
+func (r *Router) Decision(transactionID string) *Decision {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if decision, ok := r.byTxn[transactionID]; ok {
+		copied := *decision
+		return &copied
+	}
+	return nil
+}
+
+// Decisions returns the most recent routing decisions, newest first
+func (r *Router) Decisions(limit int) []Decision {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	decisions := []Decision{}
+	for i := len(r.decisions) - 1; i >= 0 && len(decisions) < limit; i-- {
+		decisions = append(decisions, *r.decisions[i])
+	}
+	return decisions
+}
+
+// Health returns the health of every processor, sorted by name
+func (r *Router) Health() []ProcessorHealth {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	health := make([]ProcessorHealth, 0, len(r.health))
+	for _, h := range r.health {
+		current := *h
+		current.Healthy = r.healthyLocked(h)
+		health = append(health, current)
+	}
+	sort.Slice(health, func(i, j int) bool { return health[i].Processor < health[j].Processor })
+	return health
+}
+
+// begin starts the routing decision for an authorization and returns the processors to try.
+// An authorization naming its processor continues the decision for the transaction that no
+// processor has answered yet, which it replaces once recorded.
+func (r *Router) begin(req usecase.GatewayAuthorization) (decision *Decision, candidates []string, previous *Decision, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	decision = &Decision{TransactionID: req.TransactionID, At: r.now()}
+	if req.Processor == "" {
+		decision.Candidates = r.candidatesLocked(req)
+		return decision, decision.Candidates, nil, nil
+	}
+	if _, ok := r.processors[req.Processor]; !ok {
+		return nil, nil, nil, fmt.Errorf("%w: %q", ErrUnknownProcessor, req.Processor)
+	}
+	if earlier, ok := r.byTxn[req.TransactionID]; ok && earlier.Processor == "" {
+		previous = earlier
+		decision.Candidates = append([]string(nil), earlier.Candidates...)
+		decision.Attempts = append([]Attempt(nil), earlier.Attempts...)
+	}
+	if !contains(decision.Candidates, req.Processor) {
+		decision.Candidates = append(decision.Candidates, req.Processor)
+	}
+	return decision, []string{req.Processor}, previous, nil
+}
+
+// candidatesLocked returns the processors to try for the authorization, in order; the caller holds the mutex
+func (r *Router) candidatesLocked(req usecase.GatewayAuthorization) []string {
+	if previous, ok := r.byTxn[req.TransactionID]; ok && previous.Processor != "" {
+		return []string{previous.Processor}
+	}
+
+	var names []string
+	for _, route := range r.routes {
+		if route.matches(req) && !contains(names, route.Processor) {
+			names = append(names, route.Processor)
+		}
+	}
+	// Healthy processors first, keeping the route order within each group
+	sort.SliceStable(names, func(i, j int) bool {
+		return r.healthyLocked(r.health[names[i]]) && !r.healthyLocked(r.health[names[j]])
+	})
+	return names
+}
+
+// forward sends a call about an authorized payment to the processor named in its reference
+func (r *Router) forward(reference string, call func(processor usecase.PaymentGateway, inner string) (*usecase.GatewayResult, error)) (*usecase.GatewayResult, error) {
+	name, inner, ok := strings.Cut(reference, ":")
+	processor, known := r.processors[name]
+	if !ok || !known {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownReference, reference)
+	}
+	result, err := call(processor, inner)
+	r.observe(name, err)
+	if err != nil {
+		return nil, err
+	}
+	return routed(name, result), nil
+}
+
+// lookup asks a processor whose authorization timed out for the authorization's status. An
+// authorization the processor made is returned, and one it has no record of fails with the
+// timeout wrapped in usecase.ErrGatewayUnavailable, as the processor never made it. When the
+// status cannot be learned, the timeout is returned as it is.
+func (r *Router) lookup(ctx context.Context, name, transactionID string, timeout error) (*usecase.GatewayResult, error) {
+	processor := r.processors[name]
+	status, err := processor.Status(ctx, processor.Reference(transactionID))
+	switch {
+	case err == nil:
+		return status, nil
+	case errors.Is(err, ErrUnknownReference):
+		return nil, fmt.Errorf("%w: %w, and the processor has no record of the authorization", usecase.ErrGatewayUnavailable, timeout)
+	}
+	return nil, timeout
+}
+
+// observe updates the health of a processor after a call. Only timeouts and processor
+// failures count against it; declines and rejected requests show it is answering.
+// Calls the caller gave up on say nothing about the processor.
+func (r *Router) observe(name string, err error) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	health := r.health[name]
+	if errors.Is(err, usecase.ErrGatewayTimeout) || errors.Is(err, usecase.ErrGatewayUnavailable) {
+		now := r.now()
+		health.ConsecutiveFailures++
+		health.LastFailure = &now
+		return
+	}
+	health.ConsecutiveFailures = 0
+}
+
+// healthy reports whether a processor is healthy
+func (r *Router) healthy(name string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.healthyLocked(r.health[name])
+}
+
+// healthyLocked reports whether a processor is healthy; the caller holds the mutex.
+// An unhealthy processor is tried again once the cooldown after its last failure has passed.
+func (r *Router) healthyLocked(health *ProcessorHealth) bool {
+	if health.ConsecutiveFailures < r.failureThreshold || health.LastFailure == nil {
+		return true
+	}
+	return !r.now().Before(health.LastFailure.Add(r.cooldown))
+}
+
+// record keeps a decision, dropping the oldest once MaxDecisions are kept.
+// A decision continuing previous takes its place.
+func (r *Router) record(decision, previous *Decision) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if previous != nil {
+		for i := len(r.decisions) - 1; i >= 0; i-- {
+			if r.decisions[i] == previous {
+				r.decisions = append(r.decisions[:i], r.decisions[i+1:]...)
+				break
+			}
+		}
+	}
+	if len(r.decisions) == MaxDecisions {
+		oldest := r.decisions[0]
+		r.decisions = r.decisions[1:]
+		if r.byTxn[oldest.TransactionID] == oldest {
+			delete(r.byTxn, oldest.TransactionID)
+		}
+	}
+	r.decisions = append(r.decisions, decision)
+	// A retry that found no processor must not hide the processor that answered earlier
+	if latest, ok := r.byTxn[decision.TransactionID]; !ok || latest.Processor == "" || decision.Processor != "" {
+		r.byTxn[decision.TransactionID] = decision
+	}
+}
+
+// routed returns a copy of result naming the processor and carrying a routable reference
+func routed(name string, result *usecase.GatewayResult) *usecase.GatewayResult {
+	copied := *result
+	copied.Processor = name
+	if copied.Reference != "" {
+		copied.Reference = name + ":" + copied.Reference
+	}
+	return &copied
+}
+
+// contains reports whether values holds value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,318 @@
+package gateway
+
+import (
+	"context"
+	"payment-service/internal/entity"
+	"payment-service/internal/usecase"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRouter returns a router trying primary before secondary for every payment
+func newTestRouter(t *testing.T, routes ...Route) (*Router, *Simulator, *Simulator) {
+	primary, secondary := NewSimulator(), NewSimulator()
+	if len(routes) == 0 {
+		routes = []Route{{Processor: "primary"}, {Processor: "secondary"}}
+	}
+	router, err := NewRouter(map[string]Processor{"primary": primary, "secondary": secondary}, routes)
+	require.NoError(t, err)
+	return router, primary, secondary
+}
+
+func authorization(transactionID string, amount int64) usecase.GatewayAuthorization {
+	return usecase.GatewayAuthorization{TransactionID: transactionID, UserID: "user123", Amount: usd(amount)}
+}
+
+func TestRouter_RoutesToFirstCandidate(t *testing.T) {
+	// Arrange
+	router, _, secondary := newTestRouter(t)
+
+	// Act
+	authorized, err := router.Authorize(context.Background(), authorization("txn123", 10000))
+	require.NoError(t, err)
+	captured, captureErr := router.Capture(context.Background(), authorized.Reference, usd(10000))
+
+	// Assert
+	assert.Equal(t, "primary", authorized.Processor)
+	assert.Equal(t, "primary:sim_txn123", authorized.Reference)
+	require.NoError(t, captureErr)
+	assert.Equal(t, entity.StatusCaptured, captured.Status)
+	assert.Equal(t, authorized.Reference, captured.Reference)
+	_, err = secondary.Status(context.Background(), Reference("txn123"))
+	assert.ErrorIs(t, err, ErrUnknownReference)
+
+	decision := router.Decision("txn123")
+	require.NotNil(t, decision)
+	assert.Equal(t, []string{"primary", "secondary"}, decision.Candidates)
+	assert.Equal(t, []Attempt{{Processor: "primary", Outcome: OutcomeApproved}}, decision.Attempts)
+	assert.Equal(t, "primary", decision.Processor)
+}
+
+func TestRouter_FailsOverOnOutage(t *testing.T) {
+	// Arrange
+	router, primary, _ := newTestRouter(t)
+	primary.SetDown(true)
+
+	// Act
+	authorized, err := router.Authorize(context.Background(), authorization("txn123", 10000))
+	require.NoError(t, err)
+	primary.SetDown(false)
+	retry, retryErr := router.Authorize(context.Background(), authorization("txn123", 10000))
+
+	// Assert
+	assert.Equal(t, "secondary", authorized.Processor)
+	require.NoError(t, retryErr)
+	assert.Equal(t, "secondary", retry.Processor, "a retry stays with the processor that approved")
+	_, err = primary.Status(context.Background(), Reference("txn123"))
+	assert.ErrorIs(t, err, ErrUnknownReference, "the primary never authorized the payment")
+
+	decisions := router.Decisions(10)
+	require.Len(t, decisions, 2)
+	assert.Equal(t, []string{"secondary"}, decisions[0].Candidates)
+	assert.Equal(t, OutcomeUnavailable, decisions[1].Attempts[0].Outcome)
+	assert.Equal(t, OutcomeApproved, decisions[1].Attempts[1].Outcome)
+}
+
+func TestRouter_HardDeclineDoesNotFailOver(t *testing.T) {
+	// Arrange
+	router, _, secondary := newTestRouter(t)
+
+	// Act
+	result, err := router.Authorize(context.Background(), usecase.GatewayAuthorization{TransactionID: "txn123", Amount: usd(10000), CardToken: CardDeclined})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, entity.StatusFailed, result.Status)
+	assert.Equal(t, "primary", result.Processor)
+	_, err = secondary.Status(context.Background(), Reference("txn123"))
+	assert.ErrorIs(t, err, ErrUnknownReference)
+	assert.Equal(t, OutcomeDeclined, router.Decision("txn123").Attempts[0].Outcome)
+}
+
+func TestRouter_SoftDeclineAndTimeoutFailOver(t *testing.T) {
+	tests := []struct {
+		name    string
+		card    string
+		outcome string
+	}{
+		{"soft decline", CardSoftDecline, OutcomeSoftDecline},
+		{"timeout", CardTimeout, OutcomeTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			router, _, _ := newTestRouter(t)
+
+			// Act: both simulators answer test cards the same way, so every candidate is tried
+			result, err := router.Authorize(context.Background(), usecase.GatewayAuthorization{TransactionID: "txn123", Amount: usd(10000), CardToken: tt.card})
+
+			// Assert
+			decision := router.Decision("txn123")
+			require.Len(t, decision.Attempts, 2)
+			assert.Equal(t, tt.outcome, decision.Attempts[0].Outcome)
+			assert.Equal(t, tt.outcome, decision.Attempts[1].Outcome)
+			assert.Empty(t, decision.Processor)
+			if tt.outcome == OutcomeTimeout {
+				assert.ErrorIs(t, err, usecase.ErrGatewayTimeout)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, result.SoftDecline)
+			assert.Equal(t, "secondary", result.Processor)
+		})
+	}
+}
+
+func TestRouter_RoutesByCurrencyAmountAndMerchant(t *testing.T) {
+	// Arrange
+	router, _, _ := newTestRouter(t,
+		Route{Processor: "secondary", Merchants: []string{"merchant-vip"}},
+		Route{Processor: "primary", Currencies: []string{"USD"}, MaxAmount: 100000},
+		Route{Processor: "secondary", Currencies: []string{"USD", "EUR"}},
+	)
+
+	tests := []struct {
+		name      string
+		req       usecase.GatewayAuthorization
+		processor string
+	}{
+		{"small USD", usecase.GatewayAuthorization{TransactionID: "t1", Amount: usd(5000)}, "primary"},
+		{"large USD", usecase.GatewayAuthorization{TransactionID: "t2", Amount: usd(500000)}, "secondary"},
+		{"EUR", usecase.GatewayAuthorization{TransactionID: "t3", Amount: entity.Money{Amount: 5000, Currency: "EUR"}}, "secondary"},
+		{"merchant", usecase.GatewayAuthorization{TransactionID: "t4", MerchantID: "merchant-vip", Amount: usd(5000)}, "secondary"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			result, err := router.Authorize(context.Background(), tt.req)
+
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, tt.processor, result.Processor)
+		})
+	}
+
+	// No route accepts GBP
+	_, err := router.Authorize(context.Background(), usecase.GatewayAuthorization{TransactionID: "t5", Amount: entity.Money{Amount: 5000, Currency: "GBP"}})
+	assert.ErrorIs(t, err, ErrNoRoute)
+	assert.ErrorIs(t, err, usecase.ErrGatewayUnavailable)
+}
+
+func TestRouter_UnhealthyProcessorsAreTriedLast(t *testing.T) {
+	// Arrange
+	router, primary, _ := newTestRouter(t)
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	router.now = func() time.Time { return now }
+	primary.SetDown(true)
+	for i := 0; i < DefaultFailureThreshold; i++ {
+		_, err := router.Authorize(context.Background(), authorization(string(rune('a'+i)), 1000))
+		require.NoError(t, err)
+	}
+	primary.SetDown(false)
+
+	// Act
+	whileUnhealthy, err := router.Authorize(context.Background(), authorization("txn-unhealthy", 1000))
+	require.NoError(t, err)
+	now = now.Add(DefaultCooldown)
+	afterCooldown, err := router.Authorize(context.Background(), authorization("txn-recovered", 1000))
+	require.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, "secondary", whileUnhealthy.Processor)
+	assert.Equal(t, []string{"secondary", "primary"}, router.Decision("txn-unhealthy").Candidates)
+	assert.Equal(t, "primary", afterCooldown.Processor)
+	health := router.Health()
+	require.Len(t, health, 2)
+	assert.Equal(t, "primary", health[0].Processor)
+	assert.True(t, health[0].Healthy)
+	assert.Equal(t, 0, health[0].ConsecutiveFailures)
+}
+
+func TestRouter_CanceledContextStopsFailover(t *testing.T) {
+	// Arrange
+	router, primary, secondary := newTestRouter(t)
+	primary.SetDown(true)
+	_, err := router.Authorize(context.Background(), authorization("txn-outage", 1000))
+	require.NoError(t, err)
+	primary.SetDown(false)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Act
+	_, err = router.Authorize(ctx, authorization("txn123", 1000))
+
+	// Assert
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = secondary.Status(context.Background(), Reference("txn123"))
+	assert.ErrorIs(t, err, ErrUnknownReference, "no other processor is tried for a caller that gave up")
+	assert.Equal(t, 1, router.Health()[0].ConsecutiveFailures, "a canceled call says nothing about the processor")
+}
+
+// lateProcessor authorizes payments but times out before answering, like a processor whose
+// answer is lost on the way back. While statusDown it cannot be asked for the status either.
+type lateProcessor struct {
+	*Simulator
+	statusDown bool
+}
+
+func (p *lateProcessor) Authorize(ctx context.Context, req usecase.GatewayAuthorization) (*usecase.GatewayResult, error) {
+	if _, err := p.Simulator.Authorize(ctx, req); err != nil {
+		return nil, err
+	}
+	return nil, usecase.ErrGatewayTimeout
+}
+
+func (p *lateProcessor) Status(ctx context.Context, reference string) (*usecase.GatewayResult, error) {
+	if p.statusDown {
+		return nil, usecase.ErrGatewayUnavailable
+	}
+	return p.Simulator.Status(ctx, reference)
+}
+
+func TestRouter_TimeoutCheckedThroughStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		card       string
+		statusDown bool
+		outcome    string
+		err        error
+	}{
+		{"authorized before timing out", "tok_visa", false, OutcomeApproved, nil},
+		{"declined before timing out", CardDeclined, false, OutcomeDeclined, nil},
+		{"status unknown", "tok_visa", true, OutcomeTimeout, usecase.ErrGatewayTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			primary, secondary := &lateProcessor{Simulator: NewSimulator(), statusDown: tt.statusDown}, NewSimulator()
+			router, err := NewRouter(map[string]Processor{"primary": primary, "secondary": secondary}, []Route{{Processor: "primary"}, {Processor: "secondary"}})
+			require.NoError(t, err)
+
+			// Act
+			result, err := router.Authorize(context.Background(), usecase.GatewayAuthorization{TransactionID: "txn123", Amount: usd(10000), CardToken: tt.card})
+
+			// Assert
+			_, secondaryErr := secondary.Status(context.Background(), Reference("txn123"))
+			assert.ErrorIs(t, secondaryErr, ErrUnknownReference, "a processor that may have authorized the payment is not failed over")
+			decision := router.Decision("txn123")
+			require.Len(t, decision.Attempts, 1)
+			assert.Equal(t, tt.outcome, decision.Attempts[0].Outcome)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				assert.Empty(t, decision.Processor)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "primary", result.Processor)
+			assert.Equal(t, "primary:sim_txn123", result.Reference)
+			assert.Equal(t, "primary", decision.Processor)
+		})
+	}
+}
+
+func TestRouter_AuthorizesAtNamedProcessor(t *testing.T) {
+	// Arrange
+	router, primary, secondary := newTestRouter(t)
+	primary.SetDown(true)
+	req := authorization("txn123", 10000)
+
+	// Act: the caller fails over itself, naming one processor at a time
+	req.Processor = "primary"
+	_, outageErr := router.Authorize(context.Background(), req)
+	req.Processor = "secondary"
+	authorized, err := router.Authorize(context.Background(), req)
+	req.Processor = "backup"
+	_, unknownErr := router.Authorize(context.Background(), req)
+
+	// Assert
+	assert.ErrorIs(t, outageErr, usecase.ErrGatewayUnavailable)
+	require.NoError(t, err)
+	assert.Equal(t, "secondary", authorized.Processor)
+	status, err := secondary.Status(context.Background(), Reference("txn123"))
+	require.NoError(t, err)
+	assert.Equal(t, entity.StatusAuthorized, status.Status)
+	assert.ErrorIs(t, unknownErr, ErrUnknownProcessor)
+
+	decisions := router.Decisions(10)
+	require.Len(t, decisions, 1, "the attempts make up one decision")
+	assert.Equal(t, []string{"primary", "secondary"}, decisions[0].Candidates)
+	require.Len(t, decisions[0].Attempts, 2)
+	assert.Equal(t, OutcomeUnavailable, decisions[0].Attempts[0].Outcome)
+	assert.Equal(t, OutcomeApproved, decisions[0].Attempts[1].Outcome)
+	assert.Equal(t, "secondary", decisions[0].Processor)
+	assert.Equal(t, []string{"secondary"}, router.Candidates(authorization("txn123", 10000)))
+}
+
+func TestNewRouter_RejectsUnknownProcessor(t *testing.T) {
+	// Act
+	_, err := NewRouter(map[string]Processor{"primary": NewSimulator()}, []Route{{Processor: "backup"}})
+
+	// Assert
+	assert.ErrorIs(t, err, ErrUnknownProcessor)
+}
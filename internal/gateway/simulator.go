@@ -0,0 +1,300 @@
+// Package gateway provides payment processors implementing usecase.PaymentGateway.
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"payment-service/internal/entity"
+	"payment-service/internal/usecase"
+	"sync"
+)
+
+var (
+	ErrUnknownReference = errors.New("unknown processor reference")
+	ErrInvalidOperation = errors.New("operation not allowed in the payment's processor status")
+	ErrAmountTooLarge   = errors.New("amount exceeds what the payment allows")
+)
+
+// Test card tokens the simulator recognises. Every other token is approved.
+const (
+	CardDeclined          = "4000000000000002" // Declined with DeclineCardDeclined
+	CardInsufficientFunds = "4000000000009995" // Declined with DeclineInsufficientFunds
+	CardProcessingError   = "4000000000000119" // Fails with usecase.ErrGatewayUnavailable
+	CardTimeout           = "4000000000000408" // Fails with usecase.ErrGatewayTimeout
+	CardSoftDecline       = "4000000000000101" // Soft declined with DeclineTryAgainLater
+)
+
+// Magic amounts: the last two digits of the amount in minor units select the outcome,
+// so 10.51 USD is declined whatever the card
+const (
+	CentsDeclined          = 51
+	CentsInsufficientFunds = 52
+	CentsProcessingError   = 53
+	CentsTimeout           = 54
+	CentsSoftDecline       = 55
+)
+
+// Decline codes reported by the simulator
+const (
+	DeclineCardDeclined      = "card_declined"
+	DeclineInsufficientFunds = "insufficient_funds"
+	DeclineTryAgainLater     = "try_again_later"
+)
+
+// Simulator is a deterministic in-process payment processor for local development and tests.
+// Test card tokens and magic amounts make authorizations decline, fail or time out;
+// everything else is approved. Like a real processor it keeps its own record of every
+// payment, and repeated calls return the original outcome.
+type Simulator struct {
+	payments map[string]*simulatedPayment
+	down     bool
+	mutex    sync.Mutex
+}
+
+// simulatedPayment is the simulator's record of one payment
+type simulatedPayment struct {
+	authorization usecase.GatewayAuthorization // The request that created the payment
+	status        string
+	declineCode   string
+	captured      entity.Money
+	refunds       map[string]entity.Money
+	refunded      entity.Money
+}
+
+// NewSimulator creates a simulator without any payments
+func NewSimulator() *Simulator {
+	return &Simulator{payments: make(map[string]*simulatedPayment)}
+}
+
+// NewSimulatedRouter creates a router over simulated processors, one for every processor named by
+// the routes in routesFile. Without a routes file every card payment tries "primary", then "secondary".
+func NewSimulatedRouter(routesFile string, opts ...RouterOption) (*Router, error) {
+	routes := []Route{{Processor: "primary"}, {Processor: "secondary"}}
+	if routesFile != "" {
+		data, err := os.ReadFile(routesFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &routes); err != nil {
+			return nil, fmt.Errorf("invalid routes file: %w", err)
+		}
+	}
+
+	processors := make(map[string]Processor)
+	for _, route := range routes {
+		processors[route.Processor] = NewSimulator()
+	}
+	return NewRouter(processors, routes, opts...)
+}
+
+// Reference returns the processor reference the simulator gives a transaction ID
+func Reference(transactionID string) string {
+	return "sim_" + transactionID
+}
+
+// Reference returns the simulator's reference for the authorization of a transaction
+func (s *Simulator) Reference(transactionID string) string {
+	return Reference(transactionID)
+}
+
+// SetDown simulates an outage: while down, every call fails with usecase.ErrGatewayUnavailable
+func (s *Simulator) SetDown(down bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.down = down
+}
+
+// Authorize approves or declines the authorization according to the card token and amount.
+// Authorizing a transaction ID again returns the original outcome; doing so with a different
+// amount or card fails with usecase.ErrIdempotencyConflict.
+func (s *Simulator) Authorize(ctx context.Context, req usecase.GatewayAuthorization) (*usecase.GatewayResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.down {
+		return nil, usecase.ErrGatewayUnavailable
+	}
+	reference := Reference(req.TransactionID)
+	if payment, exists := s.payments[reference]; exists {
+		if payment.authorization != req {
+			return nil, usecase.ErrIdempotencyConflict
+		}
+		return payment.result(reference), nil
+	}
+
+	payment := &simulatedPayment{
+		authorization: req,
+		status:        entity.StatusAuthorized,
+		captured:      entity.Money{Currency: req.Amount.Currency},
+		refunds:       make(map[string]entity.Money),
+		refunded:      entity.Money{Currency: req.Amount.Currency},
+	}
+	switch outcome(req) {
+	case CentsProcessingError:
+		return nil, usecase.ErrGatewayUnavailable
+	case CentsTimeout:
+		return nil, usecase.ErrGatewayTimeout
+	case CentsDeclined:
+		payment.status, payment.declineCode = entity.StatusFailed, DeclineCardDeclined
+	case CentsInsufficientFunds:
+		payment.status, payment.declineCode = entity.StatusFailed, DeclineInsufficientFunds
+	case CentsSoftDecline:
+		// Soft declines are not remembered, so a later retry is decided afresh
+		return &usecase.GatewayResult{Reference: reference, Status: entity.StatusFailed, DeclineCode: DeclineTryAgainLater, SoftDecline: true}, nil
+	}
+	s.payments[reference] = payment
+	return payment.result(reference), nil
+}
+
+// Capture collects amount from an authorization. Capturing the same amount again returns the captured payment.
+func (s *Simulator) Capture(ctx context.Context, reference string, amount entity.Money) (*usecase.GatewayResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.down {
+		return nil, usecase.ErrGatewayUnavailable
+	}
+	payment, exists := s.payments[reference]
+	if !exists {
+		return nil, ErrUnknownReference
+	}
+	if payment.status == entity.StatusCaptured && payment.captured == amount {
+		return payment.result(reference), nil
+	}
+	if payment.status != entity.StatusAuthorized {
+		return nil, ErrInvalidOperation
+	}
+	if amount.Currency != payment.authorization.Amount.Currency || amount.Amount > payment.authorization.Amount.Amount {
+		return nil, ErrAmountTooLarge
+	}
+
+	payment.status = entity.StatusCaptured
+	payment.captured = amount
+	return payment.result(reference), nil
+}
+
+// Void releases an authorization that was not captured. Voiding it again returns the voided payment.
+func (s *Simulator) Void(ctx context.Context, reference string) (*usecase.GatewayResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.down {
+		return nil, usecase.ErrGatewayUnavailable
+	}
+	payment, exists := s.payments[reference]
+	if !exists {
+		return nil, ErrUnknownReference
+	}
+	if payment.status == entity.StatusVoided {
+		return payment.result(reference), nil
+	}
+	if payment.status != entity.StatusAuthorized {
+		return nil, ErrInvalidOperation
+	}
+
+	payment.status = entity.StatusVoided
+	return payment.result(reference), nil
+}
+
+// Refund returns amount of a captured payment, once per refundKey
+func (s *Simulator) Refund(ctx context.Context, reference, refundKey string, amount entity.Money) (*usecase.GatewayResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.down {
+		return nil, usecase.ErrGatewayUnavailable
+	}
+	payment, exists := s.payments[reference]
+	if !exists {
+		return nil, ErrUnknownReference
+	}
+	if refunded, exists := payment.refunds[refundKey]; exists {
+		if refunded != amount {
+			return nil, usecase.ErrRefundConflict
+		}
+		return payment.result(reference), nil
+	}
+	if payment.status != entity.StatusCaptured && payment.status != entity.StatusPartiallyRefunded {
+		return nil, ErrInvalidOperation
+	}
+	if amount.Currency != payment.captured.Currency || payment.refunded.Amount+amount.Amount > payment.captured.Amount {
+		return nil, ErrAmountTooLarge
+	}
+
+	payment.refunds[refundKey] = amount
+	payment.refunded.Amount += amount.Amount
+	payment.status = entity.StatusPartiallyRefunded
+	if payment.refunded == payment.captured {
+		payment.status = entity.StatusRefunded
+	}
+	return payment.result(reference), nil
+}
+
+// Status returns the simulator's record of a payment
+func (s *Simulator) Status(ctx context.Context, reference string) (*usecase.GatewayResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.down {
+		return nil, usecase.ErrGatewayUnavailable
+	}
+	payment, exists := s.payments[reference]
+	if !exists {
+		return nil, ErrUnknownReference
+	}
+	return payment.result(reference), nil
+}
+
+// Candidates returns none: the simulator is a single processor
+func (s *Simulator) Candidates(usecase.GatewayAuthorization) []string {
+	return nil
+}
+
+// result describes the payment as a gateway result
+func (p *simulatedPayment) result(reference string) *usecase.GatewayResult {
+	return &usecase.GatewayResult{Reference: reference, Status: p.status, DeclineCode: p.declineCode}
+}
+
+// outcome returns the magic cents value an authorization triggers, or 0 if it is approved
+func outcome(req usecase.GatewayAuthorization) int64 {
+	switch req.CardToken {
+	case CardDeclined:
+		return CentsDeclined
+	case CardInsufficientFunds:
+		return CentsInsufficientFunds
+	case CardProcessingError:
+		return CentsProcessingError
+	case CardTimeout:
+		return CentsTimeout
+	case CardSoftDecline:
+		return CentsSoftDecline
+	}
+	switch cents := req.Amount.Amount % 100; cents {
+	case CentsDeclined, CentsInsufficientFunds, CentsProcessingError, CentsTimeout, CentsSoftDecline:
+		return cents
+	}
+	return 0
+}
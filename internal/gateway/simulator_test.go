@@ -0,0 +1,179 @@
+package gateway
+
+import (
+	"context"
+	"payment-service/internal/entity"
+	"payment-service/internal/usecase"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func usd(amount int64) entity.Money {
+	return entity.Money{Amount: amount, Currency: "USD"}
+}
+
+func TestSimulator_AuthorizeCaptureRefund(t *testing.T) {
+	// Arrange
+	simulator := NewSimulator()
+	req := usecase.GatewayAuthorization{TransactionID: "txn123", UserID: "user123", Amount: usd(10000), CardToken: "tok_visa"}
+
+	// Act
+	authorized, err := simulator.Authorize(context.Background(), req)
+	require.NoError(t, err)
+	captured, err := simulator.Capture(context.Background(), authorized.Reference, usd(8000))
+	require.NoError(t, err)
+	partial, err := simulator.Refund(context.Background(), authorized.Reference, "refund-1", usd(3000))
+	require.NoError(t, err)
+	repeated, err := simulator.Refund(context.Background(), authorized.Reference, "refund-1", usd(3000))
+	require.NoError(t, err)
+	_, tooLarge := simulator.Refund(context.Background(), authorized.Reference, "refund-2", usd(5001))
+	full, err := simulator.Refund(context.Background(), authorized.Reference, "refund-2", usd(5000))
+	require.NoError(t, err)
+	status, err := simulator.Status(context.Background(), authorized.Reference)
+	require.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, "sim_txn123", authorized.Reference)
+	assert.Equal(t, entity.StatusAuthorized, authorized.Status)
+	assert.Equal(t, entity.StatusCaptured, captured.Status)
+	assert.Equal(t, entity.StatusPartiallyRefunded, partial.Status)
+	assert.Equal(t, entity.StatusPartiallyRefunded, repeated.Status, "repeated refunds are not applied twice")
+	assert.ErrorIs(t, tooLarge, ErrAmountTooLarge)
+	assert.Equal(t, entity.StatusRefunded, full.Status)
+	assert.Equal(t, entity.StatusRefunded, status.Status)
+}
+
+func TestSimulator_AuthorizeIsIdempotent(t *testing.T) {
+	// Arrange
+	simulator := NewSimulator()
+	req := usecase.GatewayAuthorization{TransactionID: "txn123", UserID: "user123", Amount: usd(10000)}
+	first, err := simulator.Authorize(context.Background(), req)
+	require.NoError(t, err)
+	_, err = simulator.Capture(context.Background(), first.Reference, usd(10000))
+	require.NoError(t, err)
+
+	// Act
+	retry, retryErr := simulator.Authorize(context.Background(), req)
+	req.Amount = usd(20000)
+	_, conflictErr := simulator.Authorize(context.Background(), req)
+
+	// Assert
+	require.NoError(t, retryErr)
+	assert.Equal(t, first.Reference, retry.Reference)
+	assert.Equal(t, entity.StatusCaptured, retry.Status, "the retry sees the original payment")
+	assert.ErrorIs(t, conflictErr, usecase.ErrIdempotencyConflict)
+}
+
+func TestSimulator_MagicCardsAndAmounts(t *testing.T) {
+	tests := []struct {
+		name        string
+		card        string
+		amount      int64
+		status      string
+		declineCode string
+		err         error
+	}{
+		{"approved", "tok_visa", 10000, entity.StatusAuthorized, "", nil},
+		{"declined card", CardDeclined, 10000, entity.StatusFailed, DeclineCardDeclined, nil},
+		{"insufficient funds card", CardInsufficientFunds, 10000, entity.StatusFailed, DeclineInsufficientFunds, nil},
+		{"processing error card", CardProcessingError, 10000, "", "", usecase.ErrGatewayUnavailable},
+		{"timeout card", CardTimeout, 10000, "", "", usecase.ErrGatewayTimeout},
+		{"declined amount", "", 1051, entity.StatusFailed, DeclineCardDeclined, nil},
+		{"insufficient funds amount", "", 1052, entity.StatusFailed, DeclineInsufficientFunds, nil},
+		{"processing error amount", "", 1053, "", "", usecase.ErrGatewayUnavailable},
+		{"timeout amount", "", 1054, "", "", usecase.ErrGatewayTimeout},
+		{"soft decline card", CardSoftDecline, 10000, entity.StatusFailed, DeclineTryAgainLater, nil},
+		{"soft decline amount", "", 1055, entity.StatusFailed, DeclineTryAgainLater, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			simulator := NewSimulator()
+
+			// Act
+			result, err := simulator.Authorize(context.Background(), usecase.GatewayAuthorization{TransactionID: "txn123", Amount: usd(tt.amount), CardToken: tt.card})
+
+			// Assert
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				_, statusErr := simulator.Status(context.Background(), Reference("txn123"))
+				assert.ErrorIs(t, statusErr, ErrUnknownReference, "failed calls leave no payment behind")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, result.Status)
+			assert.Equal(t, tt.declineCode, result.DeclineCode)
+		})
+	}
+}
+
+func TestSimulator_Outage(t *testing.T) {
+	// Arrange
+	simulator := NewSimulator()
+	authorized, err := simulator.Authorize(context.Background(), usecase.GatewayAuthorization{TransactionID: "txn1", Amount: usd(10000)})
+	require.NoError(t, err)
+	simulator.SetDown(true)
+
+	// Act
+	_, authorizeErr := simulator.Authorize(context.Background(), usecase.GatewayAuthorization{TransactionID: "txn2", Amount: usd(10000)})
+	_, captureErr := simulator.Capture(context.Background(), authorized.Reference, usd(10000))
+	simulator.SetDown(false)
+	captured, err := simulator.Capture(context.Background(), authorized.Reference, usd(10000))
+
+	// Assert
+	assert.ErrorIs(t, authorizeErr, usecase.ErrGatewayUnavailable)
+	assert.ErrorIs(t, captureErr, usecase.ErrGatewayUnavailable)
+	require.NoError(t, err)
+	assert.Equal(t, entity.StatusCaptured, captured.Status)
+}
+
+func TestSimulator_CaptureRejections(t *testing.T) {
+	// Arrange
+	simulator := NewSimulator()
+	declined, err := simulator.Authorize(context.Background(), usecase.GatewayAuthorization{TransactionID: "txn1", Amount: usd(10000), CardToken: CardDeclined})
+	require.NoError(t, err)
+	authorized, err := simulator.Authorize(context.Background(), usecase.GatewayAuthorization{TransactionID: "txn2", Amount: usd(10000)})
+	require.NoError(t, err)
+
+	// Act
+	_, unknownErr := simulator.Capture(context.Background(), "sim_missing", usd(100))
+	_, declinedErr := simulator.Capture(context.Background(), declined.Reference, usd(100))
+	_, tooLargeErr := simulator.Capture(context.Background(), authorized.Reference, usd(10001))
+	_, refundErr := simulator.Refund(context.Background(), authorized.Reference, "refund-1", usd(100))
+
+	// Assert
+	assert.ErrorIs(t, unknownErr, ErrUnknownReference)
+	assert.ErrorIs(t, declinedErr, ErrInvalidOperation)
+	assert.ErrorIs(t, tooLargeErr, ErrAmountTooLarge)
+	assert.ErrorIs(t, refundErr, ErrInvalidOperation, "only captured payments can be refunded")
+}
+
+func TestSimulator_Void(t *testing.T) {
+	// Arrange
+	simulator := NewSimulator()
+	authorized, err := simulator.Authorize(context.Background(), usecase.GatewayAuthorization{TransactionID: "txn1", Amount: usd(10000)})
+	require.NoError(t, err)
+	captured, err := simulator.Authorize(context.Background(), usecase.GatewayAuthorization{TransactionID: "txn2", Amount: usd(10000)})
+	require.NoError(t, err)
+	_, err = simulator.Capture(context.Background(), captured.Reference, usd(10000))
+	require.NoError(t, err)
+
+	// Act
+	voided, err := simulator.Void(context.Background(), authorized.Reference)
+	again, againErr := simulator.Void(context.Background(), authorized.Reference)
+	_, captureErr := simulator.Capture(context.Background(), authorized.Reference, usd(100))
+	_, capturedErr := simulator.Void(context.Background(), captured.Reference)
+	_, unknownErr := simulator.Void(context.Background(), "sim_missing")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, entity.StatusVoided, voided.Status)
+	require.NoError(t, againErr)
+	assert.Equal(t, voided, again, "voiding again returns the voided payment")
+	assert.ErrorIs(t, captureErr, ErrInvalidOperation, "a voided authorization cannot be captured")
+	assert.ErrorIs(t, capturedErr, ErrInvalidOperation, "a captured payment is refunded, not voided")
+	assert.ErrorIs(t, unknownErr, ErrUnknownReference)
+}
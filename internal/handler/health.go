@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"net/http"
+	"payment-service/internal/breaker"
+)
+
+// Health statuses
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+)
+
+// HealthResponse reports the health of the service and the circuit breakers guarding its dependencies
+type HealthResponse struct {
+	Status       string           `json:"status" example:"ok" enums:"ok,degraded"` // "degraded" while any breaker is not closed
+	Service      string           `json:"service" example:"payment-service"`
+	Dependencies []breaker.Status `json:"dependencies"`
+}
+
+// HealthHandler serves the health check
+type HealthHandler struct {
+	breakers []*breaker.Breaker
+}
+
+// NewHealthHandler creates a new health handler reporting the state of breakers
+func NewHealthHandler(breakers ...*breaker.Breaker) *HealthHandler {
+	return &HealthHandler{breakers: breakers}
+}
+
+// GetHealth handles GET /health requests
+// @Summary Health Check
+// @Description Returns the health status of the payment service and the circuit breaker of each dependency. The service still answers while degraded; requests needing an open dependency fail fast with 503.
+// @Tags Health
+// @Produce json
+// @Success 200 {object} HealthResponse "Service health"
+// @Router /health [get]
+func (h *HealthHandler) GetHealth(w http.ResponseWriter, r *http.Request) {
+	response := HealthResponse{Status: HealthOK, Service: "payment-service", Dependencies: []breaker.Status{}}
+	for _, b := range h.breakers {
+		status := b.Status()
+		if status.State != breaker.Closed {
+			response.Status = HealthDegraded
+		}
+		response.Dependencies = append(response.Dependencies, status)
+	}
+	writeResponse(w, response, nil)
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"payment-service/internal/breaker"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthHandler_GetHealth(t *testing.T) {
+	// Arrange
+	storage := breaker.New("storage", breaker.Settings{})
+	gatewayBreaker := breaker.New("gateway", breaker.Settings{FailureThreshold: 1})
+	handler := NewHealthHandler(storage, gatewayBreaker)
+
+	// Act
+	healthy := httptest.NewRecorder()
+	handler.GetHealth(healthy, httptest.NewRequest("GET", "/health", nil))
+	gatewayBreaker.Execute(context.Background(), func(context.Context) error { return errors.New("processor down") })
+	degraded := httptest.NewRecorder()
+	handler.GetHealth(degraded, httptest.NewRequest("GET", "/health", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusOK, healthy.Code)
+	var healthyResponse HealthResponse
+	require.NoError(t, json.Unmarshal(healthy.Body.Bytes(), &healthyResponse))
+	assert.Equal(t, HealthOK, healthyResponse.Status)
+	require.Len(t, healthyResponse.Dependencies, 2)
+	assert.Equal(t, breaker.Closed, healthyResponse.Dependencies[1].State)
+
+	assert.Equal(t, http.StatusOK, degraded.Code)
+	var degradedResponse HealthResponse
+	require.NoError(t, json.Unmarshal(degraded.Body.Bytes(), &degradedResponse))
+	assert.Equal(t, HealthDegraded, degradedResponse.Status)
+	assert.Equal(t, "gateway", degradedResponse.Dependencies[1].Name)
+	assert.Equal(t, breaker.Open, degradedResponse.Dependencies[1].State)
+	assert.NotNil(t, degradedResponse.Dependencies[1].OpenedAt)
+}
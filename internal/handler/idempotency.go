@@ -0,0 +1,214 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"payment-service/internal/entity"
+	"payment-service/internal/idempotency"
+	"payment-service/internal/usecase"
+)
+
+// IdempotencyKeyHeader is the request header carrying the client's idempotency key
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// idempotentReplayedHeader marks responses replayed from the idempotency store
+const idempotentReplayedHeader = "Idempotent-Replayed"
+
+// idempotencyRoute tells the idempotency middleware how to handle the requests of a route
+type idempotencyRoute struct {
+	// fallbackKey derives the key from the body when the Idempotency-Key header is absent
+	fallbackKey func(body []byte) string
+	// fingerprint identifies the request, so reusing a key for another request is refused;
+	// the canonicalized body when nil
+	fingerprint func(body []byte) string
+	// conflict builds the JSON body answering a key in progress or reused; its message alone when nil
+	conflict func(body []byte, err error) any
+}
+
+// paymentRoute keys payments on their transaction_id and fingerprints them as the use case does,
+// so a retry spelling the amount differently is the same payment
+var paymentRoute = idempotencyRoute{
+	fallbackKey: transactionIDKey,
+	fingerprint: func(body []byte) string {
+		var req usecase.PaymentRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			return requestFingerprint(body)
+		}
+		return usecase.PaymentFingerprint(req)
+	},
+	conflict: func(body []byte, err error) any {
+		var req usecase.PaymentRequest
+		json.Unmarshal(body, &req)
+		return &usecase.PaymentResponse{
+			TransactionID: req.TransactionID,
+			UserID:        req.UserID,
+			Amount:        req.Amount,
+			Currency:      req.Currency,
+			Status:        entity.StatusFailed,
+			Message:       err.Error(),
+		}
+	},
+}
+
+// transferRoute keys transfers on their transaction_id and fingerprints them as the use case does
+var transferRoute = idempotencyRoute{
+	fallbackKey: transactionIDKey,
+	fingerprint: func(body []byte) string {
+		var req usecase.TransferRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			return requestFingerprint(body)
+		}
+		return usecase.TransferFingerprint(req)
+	},
+	conflict: func(body []byte, err error) any {
+		var req usecase.TransferRequest
+		json.Unmarshal(body, &req)
+		return &usecase.TransferResponse{
+			TransactionID: req.TransactionID,
+			FromUserID:    req.FromUserID,
+			ToUserID:      req.ToUserID,
+			Amount:        req.Amount,
+			Currency:      req.Currency,
+			Status:        entity.StatusFailed,
+			Message:       err.Error(),
+		}
+	},
+}
+
+// conflictMessage is the body answering a key in progress or reused on routes without their own;
+// every response of the API carries a message
+type conflictMessage struct {
+	Message string `json:"message"`
+}
+
+// idempotent records the response of the first request for each idempotency key and
+// replays the exact status and body for repeats. The key is read from the Idempotency-Key
+// header, or derived from the request body by the route's fallbackKey when the header is absent.
+// Server errors are not recorded, so requests that failed that way can be retried.
+func idempotent(store idempotency.Store, route idempotencyRoute) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, "Failed to read request body", http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			// Header keys and body-derived keys live in separate namespaces, so they never replay each other
+			key, source := r.Header.Get(IdempotencyKeyHeader), "header"
+			if key == "" && route.fallbackKey != nil {
+				key, source = route.fallbackKey(body), "body"
+			}
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			key = r.Method + " " + r.URL.Path + " " + source + ":" + key
+
+			fingerprint := requestFingerprint
+			if route.fingerprint != nil {
+				fingerprint = route.fingerprint
+			}
+			recorded, err := store.Begin(key, fingerprint(body))
+			switch {
+			case errors.Is(err, idempotency.ErrInProgress), errors.Is(err, idempotency.ErrKeyReused):
+				var response any = conflictMessage{Message: err.Error()}
+				if route.conflict != nil {
+					response = route.conflict(body, err)
+				}
+				writeResponse(w, response, err)
+				return
+			case err != nil:
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			case recorded != nil:
+				replay(w, recorded)
+				return
+			}
+
+			rec := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+			defer func() {
+				if p := recover(); p != nil {
+					store.Release(key)
+					panic(p)
+				}
+				if rec.statusCode >= http.StatusInternalServerError {
+					store.Release(key)
+					return
+				}
+				store.Complete(key, idempotency.Response{
+					StatusCode: rec.statusCode,
+					Header:     rec.header,
+					Body:       rec.body.Bytes(),
+				})
+			}()
+
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
+
+// replay writes a recorded response
+func replay(w http.ResponseWriter, recorded *idempotency.Response) {
+	for name, values := range recorded.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set(idempotentReplayedHeader, "true")
+	w.WriteHeader(recorded.StatusCode)
+	w.Write(recorded.Body)
+}
+
+// requestFingerprint hashes the request body. JSON bodies are canonicalized first,
+// so whitespace or key order differences do not count as a different request.
+func requestFingerprint(body []byte) string {
+	var decoded any
+	if err := json.Unmarshal(body, &decoded); err == nil {
+		body, _ = json.Marshal(decoded)
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// transactionIDKey extracts the transaction_id from a payment request body
+func transactionIDKey(body []byte) string {
+	var req struct {
+		TransactionID string `json:"transaction_id"`
+	}
+	json.Unmarshal(body, &req)
+	return req.TransactionID
+}
+
+// responseRecorder captures the status, headers and body written by a handler
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode  int
+	header      http.Header
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+// WriteHeader captures the status code and a snapshot of the headers
+func (r *responseRecorder) WriteHeader(statusCode int) {
+	if r.wroteHeader {
+		return
+	}
+	r.wroteHeader = true
+	r.statusCode = statusCode
+	r.header = r.ResponseWriter.Header().Clone()
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write captures the body while passing it through
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,270 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"payment-service/internal/entity"
+	"payment-service/internal/idempotency"
+	"payment-service/internal/repository"
+	"payment-service/internal/usecase"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// newIdempotentTestRouter returns the payment routes backed by a mock use case and an in-memory key store
+func newIdempotentTestRouter(t *testing.T) (http.Handler, *MockPaymentUseCase) {
+	mockUseCase := new(MockPaymentUseCase)
+	store := idempotency.NewMemoryStore(time.Hour)
+	t.Cleanup(store.Close)
+	return NewPaymentHandler(mockUseCase, store).SetupRoutes(), mockUseCase
+}
+
+// postPay sends a POST /pay request with an optional Idempotency-Key header
+func postPay(router http.Handler, body, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/pay", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestPaymentHandler_IdempotencyKeyHeader_ReplaysResponse(t *testing.T) {
+	// Arrange
+	router, mockUseCase := newIdempotentTestRouter(t)
+	requestBody := usecase.PaymentRequest{UserID: "user123", Amount: "100.50", Currency: "USD", TransactionID: "key-1"}
+	mockUseCase.On("ProcessPayment", mock.Anything, requestBody).Return(&usecase.PaymentResponse{
+		TransactionID: "key-1",
+		UserID:        "user123",
+		Amount:        "100.50",
+		Currency:      "USD",
+		Status:        entity.StatusCaptured,
+		Message:       "Payment processed successfully",
+	}, nil).Once()
+
+	// Act
+	first := postPay(router, `{"user_id":"user123","amount":"100.50","currency":"USD"}`, "key-1")
+	second := postPay(router, `{"currency": "USD", "amount": "100.50", "user_id": "user123"}`, "key-1")
+
+	// Assert
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, first.Code, second.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, "application/json", second.Header().Get("Content-Type"))
+	assert.Empty(t, first.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, "true", second.Header().Get("Idempotent-Replayed"))
+	mockUseCase.AssertExpectations(t)
+}
+
+func TestPaymentHandler_IdempotencyFallsBackToTransactionID(t *testing.T) {
+	// Arrange
+	router, mockUseCase := newIdempotentTestRouter(t)
+	requestBody := usecase.PaymentRequest{UserID: "user123", Amount: "10.00", Currency: "USD", TransactionID: "txn123"}
+	mockUseCase.On("ProcessPayment", mock.Anything, requestBody).Return(&usecase.PaymentResponse{
+		TransactionID: "txn123",
+		UserID:        "user123",
+		Amount:        "10.00",
+		Currency:      "USD",
+		Status:        entity.StatusCaptured,
+		Message:       "Payment processed successfully",
+	}, nil).Once()
+	body := `{"user_id":"user123","amount":"10.00","currency":"USD","transaction_id":"txn123"}`
+
+	// Act
+	first := postPay(router, body, "")
+	second := postPay(router, body, "")
+
+	// Assert
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, "true", second.Header().Get("Idempotent-Replayed"))
+	mockUseCase.AssertExpectations(t)
+}
+
+func TestPaymentHandler_IdempotencyKeyHeaderDoesNotReplayTransactionIDKey(t *testing.T) {
+	// Arrange: a request keyed by its transaction_id, then one whose header holds the same value
+	router, mockUseCase := newIdempotentTestRouter(t)
+	mockUseCase.On("ProcessPayment", mock.Anything, usecase.PaymentRequest{UserID: "user123", Amount: "10.00", Currency: "USD", TransactionID: "X"}).Return(&usecase.PaymentResponse{
+		TransactionID: "X",
+		Status:        entity.StatusCaptured,
+	}, nil).Once()
+	mockUseCase.On("ProcessPayment", mock.Anything, usecase.PaymentRequest{UserID: "user123", Amount: "20.00", Currency: "USD", TransactionID: "other"}).Return(&usecase.PaymentResponse{
+		TransactionID: "other",
+		Status:        entity.StatusCaptured,
+	}, nil).Once()
+	postPay(router, `{"user_id":"user123","amount":"10.00","currency":"USD","transaction_id":"X"}`, "")
+
+	// Act
+	rr := postPay(router, `{"user_id":"user123","amount":"20.00","currency":"USD","transaction_id":"other"}`, "X")
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Header().Get("Idempotent-Replayed"))
+	assert.Contains(t, rr.Body.String(), `"transaction_id":"other"`)
+	mockUseCase.AssertExpectations(t)
+}
+
+func TestPaymentHandler_IdempotencyKeyReusedWithDifferentBody(t *testing.T) {
+	// Arrange
+	router, mockUseCase := newIdempotentTestRouter(t)
+	mockUseCase.On("ProcessPayment", mock.Anything, usecase.PaymentRequest{UserID: "user123", Amount: "10.00", Currency: "USD", TransactionID: "key-1"}).Return(&usecase.PaymentResponse{
+		TransactionID: "key-1",
+		Status:        entity.StatusCaptured,
+	}, nil).Once()
+	postPay(router, `{"user_id":"user123","amount":"10.00","currency":"USD"}`, "key-1")
+
+	// Act
+	rr := postPay(router, `{"user_id":"user123","amount":"20.00","currency":"USD"}`, "key-1")
+
+	// Assert: the conflict is a payment response, as the API documents
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	var response usecase.PaymentResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, entity.StatusFailed, response.Status)
+	assert.Equal(t, idempotency.ErrKeyReused.Error(), response.Message)
+	mockUseCase.AssertExpectations(t)
+}
+
+func TestPaymentHandler_IdempotencyKeyInProgressAnswersJSON(t *testing.T) {
+	// Arrange: a wallet debit holds the key while a retry arrives
+	router, mockUseCase := newIdempotentTestRouter(t)
+	started, release := make(chan struct{}), make(chan struct{})
+	mockUseCase.On("DebitWallet", mock.Anything, mock.AnythingOfType("usecase.WalletRequest")).Run(func(mock.Arguments) {
+		close(started)
+		<-release
+	}).Return(&usecase.WalletResponse{UserID: "user123", Message: "Wallet debited"}, nil).Once()
+	debit := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/users/user123/wallet/debits", strings.NewReader(`{"amount":"5.00","currency":"USD","idempotency_key":"debit-1"}`))
+		req.Header.Set(IdempotencyKeyHeader, "debit-1")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		debit()
+	}()
+	<-started
+
+	// Act
+	rr := debit()
+	close(release)
+	<-done
+
+	// Assert
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	var response usecase.WalletResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, idempotency.ErrInProgress.Error(), response.Message)
+	mockUseCase.AssertExpectations(t)
+}
+
+func TestPaymentHandler_IdempotencyDoesNotRecordServerErrors(t *testing.T) {
+	// Arrange
+	router, mockUseCase := newIdempotentTestRouter(t)
+	requestBody := usecase.PaymentRequest{UserID: "user123", Amount: "10.00", Currency: "USD", TransactionID: "key-1"}
+	mockUseCase.On("ProcessPayment", mock.Anything, requestBody).Return(&usecase.PaymentResponse{
+		TransactionID: "key-1",
+		Status:        entity.StatusFailed,
+		Message:       "Failed to process payment",
+	}, errors.New("storage unavailable")).Once()
+	mockUseCase.On("ProcessPayment", mock.Anything, requestBody).Return(&usecase.PaymentResponse{
+		TransactionID: "key-1",
+		Status:        entity.StatusCaptured,
+		Message:       "Payment processed successfully",
+	}, nil).Once()
+
+	// Act
+	first := postPay(router, `{"user_id":"user123","amount":"10.00","currency":"USD"}`, "key-1")
+	second := postPay(router, `{"user_id":"user123","amount":"10.00","currency":"USD"}`, "key-1")
+
+	// Assert
+	require.Equal(t, http.StatusInternalServerError, first.Code)
+	assert.Equal(t, http.StatusOK, second.Code)
+	assert.Empty(t, second.Header().Get("Idempotent-Replayed"))
+	mockUseCase.AssertExpectations(t)
+}
+
+func TestPaymentHandler_PaymentActionReplaysIdempotencyKey(t *testing.T) {
+	// Arrange
+	router, mockUseCase := newIdempotentTestRouter(t)
+	mockUseCase.On("AuthorizePayment", mock.Anything, "txn123").Return(&usecase.PaymentResponse{
+		TransactionID: "txn123",
+		Status:        entity.StatusAuthorized,
+		Message:       "Payment authorized",
+	}, nil).Once()
+
+	authorize := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/payments/txn123/authorize", nil)
+		req.Header.Set(IdempotencyKeyHeader, "auth-1")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Act
+	first := authorize()
+	second := authorize()
+
+	// Assert
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, http.StatusOK, second.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, "true", second.Header().Get(idempotentReplayedHeader))
+	mockUseCase.AssertExpectations(t)
+}
+
+func TestPaymentHandler_IdempotencyRetryWithAmountSpelledDifferently(t *testing.T) {
+	// Arrange: the payment is processed for real, keyed by its transaction_id
+	store := idempotency.NewMemoryStore(time.Hour)
+	t.Cleanup(store.Close)
+	router := NewPaymentHandler(usecase.NewPaymentUseCase(repository.NewInMemoryPaymentRepository()), store).SetupRoutes()
+	first := postPay(router, `{"user_id":"user123","amount":"10","currency":"USD","transaction_id":"txn123"}`, "")
+	require.Equal(t, http.StatusOK, first.Code)
+
+	// Act
+	retry := postPay(router, `{"user_id":"user123","amount":"10.00","currency":"USD","transaction_id":"txn123"}`, "")
+
+	// Assert: "10.00" is the same payment as "10", so the retry replays it
+	assert.Equal(t, http.StatusOK, retry.Code)
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, "true", retry.Header().Get(idempotentReplayedHeader))
+}
+
+func TestPaymentHandler_IdempotencyTransferRetryWithAmountSpelledDifferently(t *testing.T) {
+	// Arrange
+	router, mockUseCase := newIdempotentTestRouter(t)
+	mockUseCase.On("TransferFunds", mock.Anything, mock.AnythingOfType("usecase.TransferRequest")).Return(&usecase.TransferResponse{
+		TransactionID: "transfer-1",
+		Amount:        "25.00",
+		Currency:      "USD",
+		Status:        "completed",
+	}, nil).Once()
+	transfer := func(amount string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/transfers", strings.NewReader(`{"from_user_id":"user123","to_user_id":"user456","amount":"`+amount+`","currency":"USD","transaction_id":"transfer-1"}`))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	first := transfer("25")
+
+	// Act
+	retry := transfer("25.00")
+
+	// Assert
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, http.StatusOK, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get(idempotentReplayedHeader))
+	mockUseCase.AssertExpectations(t)
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"payment-service/internal/entity"
+	"payment-service/internal/usecase"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// DeadLetterQueue inspects and requeues the jobs of asynchronous payments that ran out of attempts
+type DeadLetterQueue interface {
+	DeadJobs(ctx context.Context) ([]*entity.Job, error)
+	RequeueJob(ctx context.Context, transactionID string) (*entity.Job, error)
+}
+
+// JobHandler serves the dead-letter queue of the payment workers
+type JobHandler struct {
+	queue DeadLetterQueue
+}
+
+// NewJobHandler creates a new job handler
+func NewJobHandler(queue DeadLetterQueue) *JobHandler {
+	return &JobHandler{queue: queue}
+}
+
+// ListDeadJobs handles GET /jobs/dead requests
+// @Summary List Dead Jobs
+// @Description Lists the jobs of asynchronous payments that failed every attempt, oldest first, with the error of the last attempt.
+// @Description Their payments stay pending and queued until the job is requeued.
+// @Tags Jobs
+// @Produce json
+// @Success 200 {array} entity.Job "Dead-lettered jobs"
+// @Failure 503 {string} string "The store is failing"
+// @Router /jobs/dead [get]
+func (h *JobHandler) ListDeadJobs(w http.ResponseWriter, r *http.Request) {
+	jobs, err := h.queue.DeadJobs(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), jobStatus(err))
+		return
+	}
+	writeResponse(w, jobs, nil)
+}
+
+// RequeueJob handles POST /jobs/dead/{transaction_id}/requeue requests
+// @Summary Requeue Dead Job
+// @Description Makes a dead-lettered job available to the workers again, with a fresh set of attempts
+// @Tags Jobs
+// @Produce json
+// @Param transaction_id path string true "Transaction ID"
+// @Success 200 {object} entity.Job "Requeued job"
+// @Failure 404 {string} string "No dead-lettered job for the transaction"
+// @Failure 503 {string} string "The store is failing"
+// @Router /jobs/dead/{transaction_id}/requeue [post]
+func (h *JobHandler) RequeueJob(w http.ResponseWriter, r *http.Request) {
+	job, err := h.queue.RequeueJob(r.Context(), chi.URLParam(r, "transaction_id"))
+	if err != nil {
+		http.Error(w, err.Error(), jobStatus(err))
+		return
+	}
+	writeResponse(w, job, nil)
+}
+
+// jobStatus maps job queue errors to HTTP status codes
+func jobStatus(err error) int {
+	if errors.Is(err, usecase.ErrJobNotFound) {
+		return http.StatusNotFound
+	}
+	return statusForError(err)
+}
+
+// SetupRoutes configures the HTTP routes
+func (h *JobHandler) SetupRoutes() chi.Router {
+	r := chi.NewRouter()
+	r.Get("/dead", h.ListDeadJobs)
+	r.Post("/dead/{transaction_id}/requeue", h.RequeueJob)
+	return r
+}
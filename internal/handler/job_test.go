@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"payment-service/internal/entity"
+	"payment-service/internal/repository"
+	"payment-service/internal/usecase"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobHandler_ListAndRequeueDeadJobs(t *testing.T) {
+	// Arrange: a job that failed its only attempt
+	repo := repository.NewInMemoryPaymentRepository()
+	useCase := usecase.NewPaymentUseCase(repo, usecase.WithJobQueue(repo))
+	now := time.Now()
+	require.NoError(t, repo.EnqueueJob(context.Background(), "txn123", now))
+	_, err := repo.LeaseJob(context.Background(), now, now.Add(time.Minute))
+	require.NoError(t, err)
+	require.NoError(t, repo.BuryJob(context.Background(), "txn123", 1, "payment processor unavailable", now))
+	router := NewJobHandler(useCase).SetupRoutes()
+
+	// Act
+	listed := httptest.NewRecorder()
+	router.ServeHTTP(listed, httptest.NewRequest("GET", "/dead", nil))
+	requeued := httptest.NewRecorder()
+	router.ServeHTTP(requeued, httptest.NewRequest("POST", "/dead/txn123/requeue", nil))
+	again := httptest.NewRecorder()
+	router.ServeHTTP(again, httptest.NewRequest("POST", "/dead/txn123/requeue", nil))
+
+	// Assert
+	require.Equal(t, http.StatusOK, listed.Code)
+	var dead []entity.Job
+	require.NoError(t, json.Unmarshal(listed.Body.Bytes(), &dead))
+	require.Len(t, dead, 1)
+	assert.Equal(t, "txn123", dead[0].TransactionID)
+	assert.Equal(t, "payment processor unavailable", dead[0].LastError)
+
+	require.Equal(t, http.StatusOK, requeued.Code)
+	var job entity.Job
+	require.NoError(t, json.Unmarshal(requeued.Body.Bytes(), &job))
+	assert.Equal(t, entity.JobQueued, job.Status)
+	assert.Equal(t, 0, job.Attempts)
+
+	assert.Equal(t, http.StatusNotFound, again.Code, "the job is no longer dead")
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"payment-service/internal/entity"
+	"payment-service/internal/ledger"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// LedgerReader is the read side of the double-entry ledger
+type LedgerReader interface {
+	Balance(ctx context.Context, account, currency string) (entity.Money, error)
+	TrialBalance(ctx context.Context) (ledger.TrialBalance, error)
+}
+
+// LedgerHandler serves account balances and the trial balance of the ledger
+type LedgerHandler struct {
+	ledger LedgerReader
+}
+
+// NewLedgerHandler creates a new ledger handler
+func NewLedgerHandler(l LedgerReader) *LedgerHandler {
+	return &LedgerHandler{ledger: l}
+}
+
+// AccountBalance is the balance of one ledger account in one currency
+type AccountBalance struct {
+	Account string       `json:"account"`
+	Balance entity.Money `json:"balance"`
+}
+
+// GetTrialBalance handles GET /ledger/trial-balance requests
+// @Summary Get Trial Balance
+// @Description Lists the debits, credits and balance of every ledger account per currency, with the debit and credit totals.
+// @Description balanced is true when debits equal credits in every currency.
+// @Tags Ledger
+// @Produce json
+// @Success 200 {object} ledger.TrialBalance "Trial balance"
+// @Failure 500 {string} string "Internal server error"
+// @Router /ledger/trial-balance [get]
+func (h *LedgerHandler) GetTrialBalance(w http.ResponseWriter, r *http.Request) {
+	trial, err := h.ledger.TrialBalance(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeResponse(w, trial, nil)
+}
+
+// GetAccountBalance handles GET /ledger/accounts/{account} requests
+// @Summary Get Account Balance
+// @Description Returns the balance of a ledger account in one currency
+// @Tags Ledger
+// @Produce json
+// @Param account path string true "Account code" Enums(cash, merchant_payable, fee_revenue, wallet_funds)
+// @Param currency query string true "ISO 4217 currency"
+// @Success 200 {object} AccountBalance "Account balance"
+// @Failure 400 {string} string "Missing currency"
+// @Failure 404 {string} string "Unknown account"
+// @Failure 500 {string} string "Internal server error"
+// @Router /ledger/accounts/{account} [get]
+func (h *LedgerHandler) GetAccountBalance(w http.ResponseWriter, r *http.Request) {
+	account := chi.URLParam(r, "account")
+	currency := r.URL.Query().Get("currency")
+	if currency == "" {
+		http.Error(w, "currency is required", http.StatusBadRequest)
+		return
+	}
+
+	balance, err := h.ledger.Balance(r.Context(), account, currency)
+	switch {
+	case errors.Is(err, ledger.ErrUnknownAccount):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		writeResponse(w, AccountBalance{Account: account, Balance: balance}, nil)
+	}
+}
+
+// SetupRoutes configures the HTTP routes
+func (h *LedgerHandler) SetupRoutes() chi.Router {
+	r := chi.NewRouter()
+	r.Get("/trial-balance", h.GetTrialBalance)
+	r.Get("/accounts/{account}", h.GetAccountBalance)
+	return r
+}
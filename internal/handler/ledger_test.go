@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"payment-service/internal/entity"
+	"payment-service/internal/ledger"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// entryStore serves a fixed list of journal entries, or fails with err
+type entryStore struct {
+	entries []ledger.JournalEntry
+	err     error
+}
+
+func (s entryStore) JournalEntries(context.Context) ([]ledger.JournalEntry, error) {
+	return s.entries, s.err
+}
+
+// testLedger returns a ledger holding one captured payment of 100.00 USD
+func testLedger() *ledger.Journal {
+	return ledger.NewJournal(entryStore{entries: []ledger.JournalEntry{{
+		ID: "capture:txn123",
+		Postings: []ledger.Posting{
+			{Account: ledger.AccountCash, Side: ledger.Debit, Amount: entity.Money{Amount: 10000, Currency: "USD"}},
+			{Account: ledger.AccountMerchantPayable, Side: ledger.Credit, Amount: entity.Money{Amount: 10000, Currency: "USD"}},
+		},
+	}}})
+}
+
+func TestLedgerHandler_GetTrialBalance(t *testing.T) {
+	// Arrange
+	router := NewLedgerHandler(testLedger()).SetupRoutes()
+	req := httptest.NewRequest("GET", "/trial-balance", nil)
+	rr := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var trial ledger.TrialBalance
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&trial))
+	assert.True(t, trial.Balanced)
+	assert.Len(t, trial.Accounts, 2)
+	assert.Equal(t, "100.00", trial.Totals[0].Debit.Decimal())
+}
+
+func TestLedgerHandler_StoreUnavailable(t *testing.T) {
+	for _, url := range []string{"/trial-balance", "/accounts/cash?currency=USD"} {
+		t.Run(url, func(t *testing.T) {
+			// Arrange
+			router := NewLedgerHandler(ledger.NewJournal(entryStore{err: errors.New("storage unavailable")})).SetupRoutes()
+			req := httptest.NewRequest("GET", url, nil)
+			rr := httptest.NewRecorder()
+
+			// Act
+			router.ServeHTTP(rr, req)
+
+			// Assert
+			assert.Equal(t, http.StatusInternalServerError, rr.Code)
+			assert.Contains(t, rr.Body.String(), "storage unavailable")
+		})
+	}
+}
+
+func TestLedgerHandler_GetAccountBalance(t *testing.T) {
+	tests := []struct {
+		name           string
+		url            string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "balance",
+			url:            "/accounts/cash?currency=USD",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"account":"cash","balance":{"value":"100.00","currency":"USD"}}`,
+		},
+		{
+			name:           "no postings in currency",
+			url:            "/accounts/cash?currency=EUR",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"account":"cash","balance":{"value":"0.00","currency":"EUR"}}`,
+		},
+		{
+			name:           "missing currency",
+			url:            "/accounts/cash",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown account",
+			url:            "/accounts/suspense?currency=USD",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			router := NewLedgerHandler(testLedger()).SetupRoutes()
+			req := httptest.NewRequest("GET", tt.url, nil)
+			rr := httptest.NewRecorder()
+
+			// Act
+			router.ServeHTTP(rr, req)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
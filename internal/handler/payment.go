@@ -2,34 +2,69 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"payment-service/internal/breaker"
+	"payment-service/internal/entity"
+	"payment-service/internal/idempotency"
 	"payment-service/internal/usecase"
+	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 )
 
 // PaymentHandler handles HTTP requests for payments
 type PaymentHandler struct {
-	paymentUseCase usecase.PaymentUseCaseInterface
+	paymentUseCase   usecase.PaymentUseCaseInterface
+	idempotencyStore idempotency.Store
+	callbacks        ProviderCallbacks
 }
 
-// NewPaymentHandler creates a new payment handler
-func NewPaymentHandler(paymentUseCase usecase.PaymentUseCaseInterface) *PaymentHandler {
-	return &PaymentHandler{
-		paymentUseCase: paymentUseCase,
+// PaymentHandlerOption configures a PaymentHandler
+type PaymentHandlerOption func(*PaymentHandler)
+
+// WithProviderCallbacks accepts the callbacks of payment processors. Without it, every callback is
+// rejected as coming from an unknown processor.
+func WithProviderCallbacks(callbacks ProviderCallbacks) PaymentHandlerOption {
+	return func(h *PaymentHandler) {
+		h.callbacks = callbacks
+	}
+}
+
+// NewPaymentHandler creates a new payment handler.
+// A nil idempotencyStore disables response replay for repeated idempotency keys.
+func NewPaymentHandler(paymentUseCase usecase.PaymentUseCaseInterface, idempotencyStore idempotency.Store, opts ...PaymentHandlerOption) *PaymentHandler {
+	h := &PaymentHandler{
+		paymentUseCase:   paymentUseCase,
+		idempotencyStore: idempotencyStore,
+	}
+	for _, opt := range opts {
+		opt(h)
 	}
+	return h
 }
 
 // ProcessPayment handles POST /pay requests
 // @Summary Process Payment
-// @Description Processes a payment request with idempotency support. Retrying the same transaction_id will not charge twice.
+// @Description Processes a payment request with idempotency support. Retrying the same transaction_id will not charge twice; reusing it with a different payload is rejected with 409.
+// @Description The idempotency key is taken from the Idempotency-Key header, falling back to transaction_id. Repeated keys replay the original status and body.
 // @Tags Payments
 // @Accept json
 // @Produce json
+// @Param Idempotency-Key header string false "Idempotency key; used as transaction_id when the body omits it"
 // @Param payment body usecase.PaymentRequest true "Payment request"
-// @Success 200 {object} usecase.PaymentResponse "Payment processed successfully"
+// @Success 200 {object} usecase.PaymentResponse "Payment processed successfully, or created pending authorization for manual capture"
+// @Success 202 {object} usecase.PaymentResponse "Asynchronous payment queued, pending until a worker processes it"
 // @Failure 400 {object} usecase.PaymentResponse "Bad request - validation error"
+// @Failure 402 {object} usecase.PaymentResponse "Declined by the payment processor, or insufficient funds in the wallet of a wallet payment"
+// @Failure 409 {object} usecase.PaymentResponse "Conflict - idempotency key reused with a different payload or still in progress"
 // @Failure 500 {object} usecase.PaymentResponse "Internal server error"
+// @Failure 501 {object} usecase.PaymentResponse "Asynchronous processing requires a store shared with workers"
+// @Failure 503 {object} usecase.PaymentResponse "A dependency is failing; retry after the Retry-After header's seconds"
+// @Failure 502 {object} usecase.PaymentResponse "Payment processor failed; nothing was charged"
+// @Failure 504 {object} usecase.PaymentResponse "Payment processor timed out; retry with the same transaction_id"
 // @Router /pay [post]
 func (h *PaymentHandler) ProcessPayment(w http.ResponseWriter, r *http.Request) {
 	var req usecase.PaymentRequest
@@ -40,25 +75,289 @@ func (h *PaymentHandler) ProcessPayment(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Clients following the Idempotency-Key convention may omit transaction_id from the body
+	if req.TransactionID == "" {
+		req.TransactionID = r.Header.Get(IdempotencyKeyHeader)
+	}
+
 	// Process payment through use case
-	response, err := h.paymentUseCase.ProcessPayment(req)
+	response, err := h.paymentUseCase.ProcessPayment(r.Context(), req)
+	if err == nil && response.Queued {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+	writeResponse(w, response, err)
+}
+
+// ListPayments handles GET /payments requests
+// @Summary List Payments
+// @Description Lists payments newest first, optionally filtered. Pages are linked by next_cursor, which stays valid while payments are added.
+// @Tags Payments
+// @Produce json
+// @Param user_id query string false "Only payments of this user"
+// @Param status query string false "Only payments in this status" Enums(pending, authorized, captured, voided, refunded, partially_refunded, failed, settled, disputed, charged_back)
+// @Param currency query string false "Only payments in this ISO 4217 currency"
+// @Param min_amount query string false "Smallest amount as a decimal string, inclusive; requires currency"
+// @Param max_amount query string false "Largest amount as a decimal string, inclusive; requires currency"
+// @Param created_from query string false "Earliest creation time (RFC 3339), inclusive"
+// @Param created_to query string false "Latest creation time (RFC 3339), exclusive"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param limit query int false "Page size, 1 to 200 (default 50)"
+// @Success 200 {object} usecase.PaymentListResponse "Page of payments"
+// @Failure 400 {object} usecase.PaymentResponse "Invalid filter, cursor or limit"
+// @Failure 500 {object} usecase.PaymentResponse "Internal server error"
+// @Failure 503 {object} usecase.PaymentResponse "A dependency is failing; retry after the Retry-After header's seconds"
+// @Router /payments [get]
+func (h *PaymentHandler) ListPayments(w http.ResponseWriter, r *http.Request) {
+	req, err := listPaymentsRequest(r)
+	if err != nil {
+		writeResponse(w, &usecase.PaymentResponse{Message: err.Error()}, err)
+		return
+	}
+
+	response, err := h.paymentUseCase.ListPayments(r.Context(), req)
 	if err != nil {
-		// Check if it's a validation error
-		switch err {
-		case usecase.ErrInvalidAmount, usecase.ErrInvalidUserID, usecase.ErrInvalidTransaction:
-			w.WriteHeader(http.StatusBadRequest)
-		default:
-			w.WriteHeader(http.StatusInternalServerError)
+		writeResponse(w, &usecase.PaymentResponse{Message: err.Error()}, err)
+		return
+	}
+	writeResponse(w, response, nil)
+}
+
+// listPaymentsRequest reads listing filters and the page from the query string
+func listPaymentsRequest(r *http.Request) (usecase.ListPaymentsRequest, error) {
+	params := r.URL.Query()
+	req := usecase.ListPaymentsRequest{
+		UserID:      params.Get("user_id"),
+		Status:      params.Get("status"),
+		Currency:    params.Get("currency"),
+		MinAmount:   params.Get("min_amount"),
+		MaxAmount:   params.Get("max_amount"),
+		CreatedFrom: params.Get("created_from"),
+		CreatedTo:   params.Get("created_to"),
+		Cursor:      params.Get("cursor"),
+	}
+	if limit := params.Get("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil {
+			return usecase.ListPaymentsRequest{}, usecase.ErrInvalidLimit
 		}
-	} else {
-		w.WriteHeader(http.StatusOK)
+		req.Limit = parsed
+	}
+	return req, nil
+}
+
+// GetPayment handles GET /payments/{transaction_id} requests
+// @Summary Get Payment
+// @Description Returns a payment with its full status history and refunds
+// @Tags Payments
+// @Produce json
+// @Param transaction_id path string true "Transaction ID"
+// @Success 200 {object} entity.Payment "Payment"
+// @Failure 404 {object} usecase.PaymentResponse "Payment not found"
+// @Failure 500 {object} usecase.PaymentResponse "Internal server error"
+// @Failure 503 {object} usecase.PaymentResponse "A dependency is failing; retry after the Retry-After header's seconds"
+// @Router /payments/{transaction_id} [get]
+func (h *PaymentHandler) GetPayment(w http.ResponseWriter, r *http.Request) {
+	transactionID := chi.URLParam(r, "transaction_id")
+	payment, err := h.paymentUseCase.GetPayment(r.Context(), transactionID)
+	if err != nil {
+		writeResponse(w, &usecase.PaymentResponse{TransactionID: transactionID, Message: err.Error()}, err)
+		return
+	}
+	writeResponse(w, payment, nil)
+}
+
+// AuthorizePayment handles POST /payments/{transaction_id}/authorize requests
+// @Summary Authorize Payment
+// @Description Places a hold for the full amount of a payment created with capture_method "manual". Authorizing an already authorized payment returns it unchanged.
+// @Description The authorization lapses if it is not captured within the configured window.
+// @Tags Payments
+// @Produce json
+// @Param transaction_id path string true "Transaction ID"
+// @Param Idempotency-Key header string false "Idempotency key; repeated keys replay the original response"
+// @Success 200 {object} usecase.PaymentResponse "Payment authorized"
+// @Failure 402 {object} usecase.PaymentResponse "Declined by the payment processor"
+// @Failure 404 {object} usecase.PaymentResponse "Payment not found"
+// @Failure 409 {object} usecase.PaymentResponse "Payment is not pending"
+// @Failure 500 {object} usecase.PaymentResponse "Internal server error"
+// @Failure 503 {object} usecase.PaymentResponse "A dependency is failing; retry after the Retry-After header's seconds"
+// @Failure 502 {object} usecase.PaymentResponse "Payment processor failed"
+// @Failure 504 {object} usecase.PaymentResponse "Payment processor timed out"
+// @Router /payments/{transaction_id}/authorize [post]
+func (h *PaymentHandler) AuthorizePayment(w http.ResponseWriter, r *http.Request) {
+	response, err := h.paymentUseCase.AuthorizePayment(r.Context(), chi.URLParam(r, "transaction_id"))
+	writeResponse(w, response, err)
+}
+
+// CapturePayment handles POST /payments/{transaction_id}/capture requests
+// @Summary Capture Payment
+// @Description Captures an authorized payment in full, or partially when an amount is given; the uncaptured remainder is released.
+// @Description Repeating a capture of the same amount returns the payment unchanged. Capturing a lapsed authorization voids it.
+// @Tags Payments
+// @Accept json
+// @Produce json
+// @Param transaction_id path string true "Transaction ID"
+// @Param Idempotency-Key header string false "Idempotency key; repeated keys replay the original response"
+// @Param capture body usecase.CaptureRequest false "Capture request; omit to capture the full amount"
+// @Success 200 {object} usecase.PaymentResponse "Payment captured"
+// @Failure 400 {object} usecase.PaymentResponse "Invalid capture amount"
+// @Failure 404 {object} usecase.PaymentResponse "Payment not found"
+// @Failure 409 {object} usecase.PaymentResponse "Payment is not authorized or the authorization has expired"
+// @Failure 500 {object} usecase.PaymentResponse "Internal server error"
+// @Failure 503 {object} usecase.PaymentResponse "A dependency is failing; retry after the Retry-After header's seconds"
+// @Failure 502 {object} usecase.PaymentResponse "Payment processor failed"
+// @Failure 504 {object} usecase.PaymentResponse "Payment processor timed out"
+// @Router /payments/{transaction_id}/capture [post]
+func (h *PaymentHandler) CapturePayment(w http.ResponseWriter, r *http.Request) {
+	var req usecase.CaptureRequest
+
+	// The body is optional: an empty body captures the full amount
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
 	}
+	req.TransactionID = chi.URLParam(r, "transaction_id")
+
+	response, err := h.paymentUseCase.CapturePayment(r.Context(), req)
+	writeResponse(w, response, err)
+}
+
+// VoidPayment handles POST /payments/{transaction_id}/void requests
+// @Summary Void Payment
+// @Description Releases the hold of an authorized payment without capturing it. Voiding an already voided payment returns it unchanged.
+// @Tags Payments
+// @Produce json
+// @Param transaction_id path string true "Transaction ID"
+// @Param Idempotency-Key header string false "Idempotency key; repeated keys replay the original response"
+// @Success 200 {object} usecase.PaymentResponse "Payment voided"
+// @Failure 404 {object} usecase.PaymentResponse "Payment not found"
+// @Failure 409 {object} usecase.PaymentResponse "Payment is not authorized"
+// @Failure 500 {object} usecase.PaymentResponse "Internal server error"
+// @Failure 503 {object} usecase.PaymentResponse "A dependency is failing; retry after the Retry-After header's seconds"
+// @Router /payments/{transaction_id}/void [post]
+func (h *PaymentHandler) VoidPayment(w http.ResponseWriter, r *http.Request) {
+	response, err := h.paymentUseCase.VoidPayment(r.Context(), chi.URLParam(r, "transaction_id"))
+	writeResponse(w, response, err)
+}
 
-	// Set content type and encode response
+// RefundPayment handles POST /payments/{transaction_id}/refunds requests
+// @Summary Refund Payment
+// @Description Refunds part or all of a captured payment. Several partial refunds may be made, but together they never exceed the captured amount.
+// @Description Each refund needs its own idempotency key, from the body or the Idempotency-Key header; retrying a key returns the original refund.
+// @Tags Payments
+// @Accept json
+// @Produce json
+// @Param transaction_id path string true "Transaction ID"
+// @Param Idempotency-Key header string false "Refund idempotency key; used when the body omits idempotency_key"
+// @Param refund body usecase.RefundRequest true "Refund request"
+// @Success 200 {object} usecase.RefundResponse "Refund processed"
+// @Failure 400 {object} usecase.RefundResponse "Invalid amount, reason or missing idempotency key"
+// @Failure 404 {object} usecase.RefundResponse "Payment not found"
+// @Failure 409 {object} usecase.RefundResponse "Payment is not captured, or the idempotency key was used for a different refund"
+// @Failure 500 {object} usecase.RefundResponse "Internal server error"
+// @Failure 503 {object} usecase.RefundResponse "A dependency is failing; retry after the Retry-After header's seconds"
+// @Failure 502 {object} usecase.RefundResponse "Payment processor failed"
+// @Failure 504 {object} usecase.RefundResponse "Payment processor timed out"
+// @Router /payments/{transaction_id}/refunds [post]
+func (h *PaymentHandler) RefundPayment(w http.ResponseWriter, r *http.Request) {
+	var req usecase.RefundRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	req.TransactionID = chi.URLParam(r, "transaction_id")
+	if req.IdempotencyKey == "" {
+		req.IdempotencyKey = r.Header.Get(IdempotencyKeyHeader)
+	}
+
+	response, err := h.paymentUseCase.RefundPayment(r.Context(), req)
+	writeResponse(w, response, err)
+}
+
+// writeResponse writes a use case response with the HTTP status matching err.
+// Calls rejected by an open circuit breaker tell the client when to retry.
+func writeResponse(w http.ResponseWriter, response any, err error) {
 	w.Header().Set("Content-Type", "application/json")
+	var openErr *breaker.OpenError
+	if errors.As(err, &openErr) {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(openErr.RetryAfter)))
+	}
+	if err != nil {
+		w.WriteHeader(statusForError(err))
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
 	json.NewEncoder(w).Encode(response)
 }
 
+// statusForError maps use case errors to HTTP status codes
+func statusForError(err error) int {
+	switch {
+	case errors.Is(err, breaker.ErrOpen),
+		errors.Is(err, breaker.ErrTimeout) && !errors.Is(err, usecase.ErrGatewayTimeout):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, usecase.ErrInvalidAmount),
+		errors.Is(err, usecase.ErrInvalidAmountFormat),
+		errors.Is(err, usecase.ErrInvalidCurrency),
+		errors.Is(err, usecase.ErrInvalidCaptureMethod),
+		errors.Is(err, usecase.ErrInvalidProcessingMode),
+		errors.Is(err, usecase.ErrInvalidCaptureAmount),
+		errors.Is(err, usecase.ErrInvalidRefundAmount),
+		errors.Is(err, usecase.ErrInvalidRefundReason),
+		errors.Is(err, usecase.ErrMissingRefundKey),
+		errors.Is(err, usecase.ErrInvalidStatus),
+		errors.Is(err, usecase.ErrInvalidAmountRange),
+		errors.Is(err, usecase.ErrInvalidDateRange),
+		errors.Is(err, usecase.ErrInvalidCursor),
+		errors.Is(err, usecase.ErrInvalidLimit),
+		errors.Is(err, usecase.ErrInvalidMonth),
+		errors.Is(err, usecase.ErrInvalidUserID),
+		errors.Is(err, usecase.ErrInvalidPaymentMethod),
+		errors.Is(err, usecase.ErrMissingWalletKey),
+		errors.Is(err, usecase.ErrSelfTransfer),
+		errors.Is(err, usecase.ErrInvalidProviderEvent),
+		errors.Is(err, usecase.ErrInvalidTransaction):
+		return http.StatusBadRequest
+	case errors.Is(err, usecase.ErrInsufficientFunds),
+		errors.Is(err, usecase.ErrPaymentDeclined):
+		return http.StatusPaymentRequired
+	case errors.Is(err, usecase.ErrPaymentNotFound),
+		errors.Is(err, usecase.ErrTransferNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, usecase.ErrDuplicateTransaction),
+		errors.Is(err, usecase.ErrRefundConflict),
+		errors.Is(err, usecase.ErrWalletConflict),
+		errors.Is(err, usecase.ErrAuthorizationExpired),
+		errors.Is(err, usecase.ErrConcurrentUpdate),
+		errors.Is(err, entity.ErrInvalidTransition),
+		errors.Is(err, idempotency.ErrInProgress),
+		errors.Is(err, idempotency.ErrKeyReused):
+		return http.StatusConflict
+	case errors.Is(err, usecase.ErrTransferLimitExceeded),
+		errors.Is(err, usecase.ErrTransferCurrencyNotAllowed):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, usecase.ErrWalletsDisabled),
+		errors.Is(err, usecase.ErrTransfersDisabled),
+		errors.Is(err, usecase.ErrAsyncDisabled):
+		return http.StatusNotImplemented
+	case errors.Is(err, usecase.ErrGatewayUnavailable):
+		return http.StatusBadGateway
+	case errors.Is(err, usecase.ErrGatewayTimeout):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// retryAfterSeconds rounds a wait up to whole seconds, as the Retry-After header requires
+func retryAfterSeconds(wait time.Duration) int {
+	seconds := int((wait + time.Second - 1) / time.Second)
+	return max(seconds, 1)
+}
+
 // SetupRoutes configures the HTTP routes
 func (h *PaymentHandler) SetupRoutes() chi.Router {
 	r := chi.NewRouter()
@@ -71,7 +370,49 @@ func (h *PaymentHandler) SetupRoutes() chi.Router {
 		})
 	})
 
-	r.Post("/pay", h.ProcessPayment)
+	if h.idempotencyStore != nil {
+		r.With(idempotent(h.idempotencyStore, paymentRoute)).Post("/pay", h.ProcessPayment)
+	} else {
+		r.Post("/pay", h.ProcessPayment)
+	}
+	if h.idempotencyStore != nil {
+		r.With(idempotent(h.idempotencyStore, transferRoute)).Post("/transfers", h.TransferFunds)
+	} else {
+		r.Post("/transfers", h.TransferFunds)
+	}
+
+	r.Post("/webhooks/{provider}", h.ReceiveProviderEvent)
+
+	r.Get("/payments", h.ListPayments)
+	r.Route("/payments/{transaction_id}", func(r chi.Router) {
+		r.Get("/", h.GetPayment)
+
+		r.Group(func(r chi.Router) {
+			// Actions are naturally idempotent; an Idempotency-Key additionally replays the exact response
+			if h.idempotencyStore != nil {
+				r.Use(idempotent(h.idempotencyStore, idempotencyRoute{}))
+			}
+			r.Post("/authorize", h.AuthorizePayment)
+			r.Post("/capture", h.CapturePayment)
+			r.Post("/void", h.VoidPayment)
+			r.Post("/refunds", h.RefundPayment)
+		})
+	})
+
+	r.Route("/users/{user_id}", func(r chi.Router) {
+		r.Get("/payments", h.ListUserPayments)
+		r.Get("/statements/{month}", h.GetStatement)
+		r.Get("/wallet", h.GetWallet)
+		r.Get("/transfers", h.GetTransferHistory)
+
+		r.Group(func(r chi.Router) {
+			if h.idempotencyStore != nil {
+				r.Use(idempotent(h.idempotencyStore, idempotencyRoute{}))
+			}
+			r.Post("/wallet/top-ups", h.TopUpWallet)
+			r.Post("/wallet/debits", h.DebitWallet)
+		})
+	})
 
 	return r
 }
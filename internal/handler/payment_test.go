@@ -2,15 +2,21 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"payment-service/internal/breaker"
 	"payment-service/internal/entity"
 	"payment-service/internal/usecase"
 	"testing"
+	"time"
 
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // MockPaymentUseCase is a mock implementation of PaymentUseCaseInterface
@@ -18,31 +24,107 @@ type MockPaymentUseCase struct {
 	mock.Mock
 }
 
-func (m *MockPaymentUseCase) ProcessPayment(req usecase.PaymentRequest) (*usecase.PaymentResponse, error) {
-	args := m.Called(req)
+func (m *MockPaymentUseCase) ProcessPayment(ctx context.Context, req usecase.PaymentRequest) (*usecase.PaymentResponse, error) {
+	args := m.Called(ctx, req)
 	return args.Get(0).(*usecase.PaymentResponse), args.Error(1)
 }
 
+func (m *MockPaymentUseCase) AuthorizePayment(ctx context.Context, transactionID string) (*usecase.PaymentResponse, error) {
+	args := m.Called(ctx, transactionID)
+	return args.Get(0).(*usecase.PaymentResponse), args.Error(1)
+}
+
+func (m *MockPaymentUseCase) CapturePayment(ctx context.Context, req usecase.CaptureRequest) (*usecase.PaymentResponse, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(*usecase.PaymentResponse), args.Error(1)
+}
+
+func (m *MockPaymentUseCase) VoidPayment(ctx context.Context, transactionID string) (*usecase.PaymentResponse, error) {
+	args := m.Called(ctx, transactionID)
+	return args.Get(0).(*usecase.PaymentResponse), args.Error(1)
+}
+
+func (m *MockPaymentUseCase) GetPayment(ctx context.Context, transactionID string) (*entity.Payment, error) {
+	args := m.Called(ctx, transactionID)
+	return args.Get(0).(*entity.Payment), args.Error(1)
+}
+
+func (m *MockPaymentUseCase) ListPayments(ctx context.Context, req usecase.ListPaymentsRequest) (*usecase.PaymentListResponse, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(*usecase.PaymentListResponse), args.Error(1)
+}
+
+func (m *MockPaymentUseCase) GetStatement(ctx context.Context, userID, month string) (*entity.Statement, error) {
+	args := m.Called(ctx, userID, month)
+	return args.Get(0).(*entity.Statement), args.Error(1)
+}
+
+func (m *MockPaymentUseCase) GetWallet(ctx context.Context, userID string) (*entity.Wallet, error) {
+	args := m.Called(ctx, userID)
+	if wallet, ok := args.Get(0).(*entity.Wallet); ok {
+		return wallet, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockPaymentUseCase) TopUpWallet(ctx context.Context, req usecase.WalletRequest) (*usecase.WalletResponse, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(*usecase.WalletResponse), args.Error(1)
+}
+
+func (m *MockPaymentUseCase) DebitWallet(ctx context.Context, req usecase.WalletRequest) (*usecase.WalletResponse, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(*usecase.WalletResponse), args.Error(1)
+}
+
+func (m *MockPaymentUseCase) TransferFunds(ctx context.Context, req usecase.TransferRequest) (*usecase.TransferResponse, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(*usecase.TransferResponse), args.Error(1)
+}
+
+func (m *MockPaymentUseCase) GetTransferHistory(ctx context.Context, userID, counterparty string) (*usecase.TransferHistoryResponse, error) {
+	args := m.Called(ctx, userID, counterparty)
+	if history, ok := args.Get(0).(*usecase.TransferHistoryResponse); ok {
+		return history, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockPaymentUseCase) ApplyProviderEvent(ctx context.Context, event usecase.ProviderEvent) (*usecase.ProviderEventResponse, error) {
+	args := m.Called(ctx, event)
+	if response, ok := args.Get(0).(*usecase.ProviderEventResponse); ok {
+		return response, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockPaymentUseCase) RefundPayment(ctx context.Context, req usecase.RefundRequest) (*usecase.RefundResponse, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(*usecase.RefundResponse), args.Error(1)
+}
+
 func TestPaymentHandler_ProcessPayment_Success(t *testing.T) {
 	// Arrange
 	mockUseCase := new(MockPaymentUseCase)
-	handler := NewPaymentHandler(mockUseCase)
+	handler := NewPaymentHandler(mockUseCase, nil)
 
 	requestBody := usecase.PaymentRequest{
 		UserID:        "user123",
-		Amount:        100.50,
+		Amount:        "100.50",
+		Currency:      "USD",
 		TransactionID: "txn123",
 	}
 
 	expectedResponse := &usecase.PaymentResponse{
 		TransactionID: "txn123",
 		UserID:        "user123",
-		Amount:        100.50,
-		Status:        entity.StatusCompleted,
+		Amount:        "100.50",
+		Currency:      "USD",
+		Status:        entity.StatusCaptured,
 		Message:       "Payment processed successfully",
 	}
 
-	mockUseCase.On("ProcessPayment", requestBody).Return(expectedResponse, nil)
+	mockUseCase.On("ProcessPayment", mock.Anything, requestBody).Return(expectedResponse, nil)
 
 	// Create request
 	jsonBody, _ := json.Marshal(requestBody)
@@ -70,26 +152,69 @@ func TestPaymentHandler_ProcessPayment_Success(t *testing.T) {
 	mockUseCase.AssertExpectations(t)
 }
 
+func TestPaymentHandler_ProcessPayment_AsyncAccepted(t *testing.T) {
+	// Arrange
+	mockUseCase := new(MockPaymentUseCase)
+	handler := NewPaymentHandler(mockUseCase, nil)
+
+	requestBody := usecase.PaymentRequest{
+		UserID:         "user123",
+		Amount:         "100.50",
+		Currency:       "USD",
+		TransactionID:  "txn123",
+		ProcessingMode: usecase.ProcessingAsync,
+	}
+
+	expectedResponse := &usecase.PaymentResponse{
+		TransactionID: "txn123",
+		Status:        entity.StatusPending,
+		Queued:        true,
+		Message:       "Payment queued for processing",
+	}
+
+	mockUseCase.On("ProcessPayment", mock.Anything, requestBody).Return(expectedResponse, nil)
+
+	jsonBody, _ := json.Marshal(requestBody)
+	req := httptest.NewRequest("POST", "/pay", bytes.NewBuffer(jsonBody))
+	rr := httptest.NewRecorder()
+
+	// Act
+	handler.ProcessPayment(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+	var response usecase.PaymentResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, entity.StatusPending, response.Status)
+	assert.True(t, response.Queued)
+
+	mockUseCase.AssertExpectations(t)
+}
+
 func TestPaymentHandler_ProcessPayment_ValidationError(t *testing.T) {
 	// Arrange
 	mockUseCase := new(MockPaymentUseCase)
-	handler := NewPaymentHandler(mockUseCase)
+	handler := NewPaymentHandler(mockUseCase, nil)
 
 	requestBody := usecase.PaymentRequest{
 		UserID:        "",
-		Amount:        100.50,
+		Amount:        "100.50",
+		Currency:      "USD",
 		TransactionID: "txn123",
 	}
 
 	expectedResponse := &usecase.PaymentResponse{
 		TransactionID: "txn123",
 		UserID:        "",
-		Amount:        100.50,
+		Amount:        "100.50",
+		Currency:      "USD",
 		Status:        entity.StatusFailed,
 		Message:       "user ID cannot be empty",
 	}
 
-	mockUseCase.On("ProcessPayment", requestBody).Return(expectedResponse, usecase.ErrInvalidUserID)
+	mockUseCase.On("ProcessPayment", mock.Anything, requestBody).Return(expectedResponse, usecase.ErrInvalidUserID)
 
 	// Create request
 	jsonBody, _ := json.Marshal(requestBody)
@@ -114,3 +239,404 @@ func TestPaymentHandler_ProcessPayment_ValidationError(t *testing.T) {
 
 	mockUseCase.AssertExpectations(t)
 }
+
+func TestPaymentHandler_ProcessPayment_IdempotencyConflict(t *testing.T) {
+	// Arrange
+	mockUseCase := new(MockPaymentUseCase)
+	handler := NewPaymentHandler(mockUseCase, nil)
+
+	requestBody := usecase.PaymentRequest{
+		UserID:        "user123",
+		Amount:        "200.00",
+		Currency:      "USD",
+		TransactionID: "txn123",
+	}
+
+	expectedResponse := &usecase.PaymentResponse{
+		TransactionID: "txn123",
+		UserID:        "user123",
+		Amount:        "200.00",
+		Currency:      "USD",
+		Status:        entity.StatusFailed,
+		Message:       usecase.ErrIdempotencyConflict.Error(),
+	}
+
+	mockUseCase.On("ProcessPayment", mock.Anything, requestBody).Return(expectedResponse, usecase.ErrIdempotencyConflict)
+
+	// Create request
+	jsonBody, _ := json.Marshal(requestBody)
+	req := httptest.NewRequest("POST", "/pay", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	// Create response recorder
+	rr := httptest.NewRecorder()
+
+	// Act
+	handler.ProcessPayment(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	var response usecase.PaymentResponse
+	err := json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.StatusFailed, response.Status)
+	assert.Equal(t, "transaction already processed with a different request payload", response.Message)
+
+	mockUseCase.AssertExpectations(t)
+}
+
+func TestPaymentHandler_ProcessPayment_AsyncDisabled(t *testing.T) {
+	// Arrange
+	mockUseCase := new(MockPaymentUseCase)
+	handler := NewPaymentHandler(mockUseCase, nil)
+
+	requestBody := usecase.PaymentRequest{
+		UserID:         "user123",
+		Amount:         "200.00",
+		Currency:       "USD",
+		TransactionID:  "txn123",
+		ProcessingMode: usecase.ProcessingAsync,
+	}
+
+	mockUseCase.On("ProcessPayment", mock.Anything, requestBody).Return(&usecase.PaymentResponse{
+		TransactionID: "txn123",
+		Status:        entity.StatusFailed,
+		Message:       usecase.ErrAsyncDisabled.Error(),
+	}, usecase.ErrAsyncDisabled)
+
+	jsonBody, _ := json.Marshal(requestBody)
+	req := httptest.NewRequest("POST", "/pay", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	// Act
+	handler.ProcessPayment(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNotImplemented, rr.Code)
+	mockUseCase.AssertExpectations(t)
+}
+
+func TestPaymentHandler_CapturePayment_PartialAmount(t *testing.T) {
+	// Arrange
+	mockUseCase := new(MockPaymentUseCase)
+	router := NewPaymentHandler(mockUseCase, nil).SetupRoutes()
+
+	mockUseCase.On("CapturePayment", mock.Anything, usecase.CaptureRequest{TransactionID: "txn123", Amount: "40.00"}).Return(&usecase.PaymentResponse{
+		TransactionID:  "txn123",
+		Amount:         "100.00",
+		Currency:       "USD",
+		Status:         entity.StatusCaptured,
+		CapturedAmount: "40.00",
+		Message:        "Payment captured",
+	}, nil)
+
+	req := httptest.NewRequest("POST", "/payments/txn123/capture", bytes.NewBufferString(`{"amount":"40.00"}`))
+	rr := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response usecase.PaymentResponse
+	err := json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.StatusCaptured, response.Status)
+	assert.Equal(t, "40.00", response.CapturedAmount)
+
+	mockUseCase.AssertExpectations(t)
+}
+
+func TestPaymentHandler_CapturePayment_EmptyBodyCapturesFullAmount(t *testing.T) {
+	// Arrange
+	mockUseCase := new(MockPaymentUseCase)
+	router := NewPaymentHandler(mockUseCase, nil).SetupRoutes()
+
+	mockUseCase.On("CapturePayment", mock.Anything, usecase.CaptureRequest{TransactionID: "txn123"}).Return(&usecase.PaymentResponse{
+		TransactionID: "txn123",
+		Status:        entity.StatusCaptured,
+	}, nil)
+
+	req := httptest.NewRequest("POST", "/payments/txn123/capture", nil)
+	rr := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockUseCase.AssertExpectations(t)
+}
+
+func TestPaymentHandler_PaymentActions_ErrorStatusCodes(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"not found", usecase.ErrPaymentNotFound, http.StatusNotFound},
+		{"invalid transition", &entity.TransitionError{From: entity.StatusCaptured, To: entity.StatusVoided}, http.StatusConflict},
+		{"authorization expired", usecase.ErrAuthorizationExpired, http.StatusConflict},
+		{"invalid capture amount", usecase.ErrInvalidCaptureAmount, http.StatusBadRequest},
+		{"declined by the processor", fmt.Errorf("%w: card_declined", usecase.ErrPaymentDeclined), http.StatusPaymentRequired},
+		{"processor unavailable", usecase.ErrGatewayUnavailable, http.StatusBadGateway},
+		{"processor timeout", usecase.ErrGatewayTimeout, http.StatusGatewayTimeout},
+		{"processor call deadline", fmt.Errorf("%w: %w", usecase.ErrGatewayTimeout, breaker.ErrTimeout), http.StatusGatewayTimeout},
+		{"processor breaker open", fmt.Errorf("%w: %w", usecase.ErrGatewayUnavailable, &breaker.OpenError{Name: "gateway"}), http.StatusServiceUnavailable},
+		{"storage breaker open", &breaker.OpenError{Name: "storage"}, http.StatusServiceUnavailable},
+		{"storage call deadline", breaker.ErrTimeout, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockUseCase := new(MockPaymentUseCase)
+			router := NewPaymentHandler(mockUseCase, nil).SetupRoutes()
+
+			mockUseCase.On("VoidPayment", mock.Anything, "txn123").Return(&usecase.PaymentResponse{
+				TransactionID: "txn123",
+				Message:       tt.err.Error(),
+			}, tt.err)
+
+			req := httptest.NewRequest("POST", "/payments/txn123/void", nil)
+			rr := httptest.NewRecorder()
+
+			// Act
+			router.ServeHTTP(rr, req)
+
+			// Assert
+			assert.Equal(t, tt.status, rr.Code)
+			mockUseCase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPaymentHandler_ProcessPayment_BreakerOpenSetsRetryAfter(t *testing.T) {
+	// Arrange
+	mockUseCase := new(MockPaymentUseCase)
+	router := NewPaymentHandler(mockUseCase, nil).SetupRoutes()
+	openErr := &breaker.OpenError{Name: "storage", RetryAfter: 2500 * time.Millisecond}
+
+	mockUseCase.On("ProcessPayment", mock.Anything, mock.Anything).Return(&usecase.PaymentResponse{
+		TransactionID: "txn123",
+		Status:        entity.StatusFailed,
+		Message:       "Failed to process payment",
+	}, openErr)
+
+	req := httptest.NewRequest("POST", "/pay", bytes.NewBufferString(`{"user_id":"user123","amount":"100.00","currency":"USD","transaction_id":"txn123"}`))
+	rr := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Equal(t, "3", rr.Header().Get("Retry-After"))
+	mockUseCase.AssertExpectations(t)
+}
+
+func TestPaymentHandler_RefundPayment_IdempotencyKeyFromHeader(t *testing.T) {
+	// Arrange
+	mockUseCase := new(MockPaymentUseCase)
+	router := NewPaymentHandler(mockUseCase, nil).SetupRoutes()
+
+	mockUseCase.On("RefundPayment", mock.Anything, usecase.RefundRequest{
+		TransactionID:  "txn123",
+		Amount:         "25.00",
+		Reason:         entity.RefundReasonRequestedByCustomer,
+		IdempotencyKey: "refund-1",
+	}).Return(&usecase.RefundResponse{
+		RefundID:       "txn123-refund-1",
+		TransactionID:  "txn123",
+		Amount:         "25.00",
+		Currency:       "USD",
+		PaymentStatus:  entity.StatusPartiallyRefunded,
+		RefundedAmount: "25.00",
+	}, nil)
+
+	req := httptest.NewRequest("POST", "/payments/txn123/refunds", bytes.NewBufferString(`{"amount":"25.00","reason":"requested_by_customer"}`))
+	req.Header.Set(IdempotencyKeyHeader, "refund-1")
+	rr := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response usecase.RefundResponse
+	err := json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "txn123-refund-1", response.RefundID)
+	assert.Equal(t, entity.StatusPartiallyRefunded, response.PaymentStatus)
+
+	mockUseCase.AssertExpectations(t)
+}
+
+func TestPaymentHandler_RefundPayment_ErrorStatusCodes(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"exceeds captured amount", usecase.ErrInvalidRefundAmount, http.StatusBadRequest},
+		{"unknown reason", usecase.ErrInvalidRefundReason, http.StatusBadRequest},
+		{"key reused", usecase.ErrRefundConflict, http.StatusConflict},
+		{"not captured", &entity.TransitionError{From: entity.StatusAuthorized, To: entity.StatusRefunded}, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockUseCase := new(MockPaymentUseCase)
+			router := NewPaymentHandler(mockUseCase, nil).SetupRoutes()
+
+			mockUseCase.On("RefundPayment", mock.Anything, mock.AnythingOfType("usecase.RefundRequest")).Return(&usecase.RefundResponse{
+				TransactionID: "txn123",
+				Message:       tt.err.Error(),
+			}, tt.err)
+
+			req := httptest.NewRequest("POST", "/payments/txn123/refunds", bytes.NewBufferString(`{"reason":"other","idempotency_key":"refund-1"}`))
+			rr := httptest.NewRecorder()
+
+			// Act
+			router.ServeHTTP(rr, req)
+
+			// Assert
+			assert.Equal(t, tt.status, rr.Code)
+			mockUseCase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPaymentHandler_GetPayment_Success(t *testing.T) {
+	// Arrange
+	mockUseCase := new(MockPaymentUseCase)
+	router := NewPaymentHandler(mockUseCase, nil).SetupRoutes()
+
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	payment := entity.NewPayment("txn123", "user123", entity.Money{Amount: 10050, Currency: "USD"}, "user123", "payment requested", createdAt)
+	mockUseCase.On("GetPayment", mock.Anything, "txn123").Return(payment, nil)
+
+	req := httptest.NewRequest("GET", "/payments/txn123", nil)
+	rr := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+	var response entity.Payment
+	err := json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "txn123", response.TransactionID)
+	assert.Equal(t, entity.Money{Amount: 10050, Currency: "USD"}, response.Amount)
+	assert.True(t, createdAt.Equal(response.CreatedAt))
+	assert.Len(t, response.StatusHistory, 1)
+
+	mockUseCase.AssertExpectations(t)
+}
+
+func TestPaymentHandler_PassesRequestContext(t *testing.T) {
+	// Arrange
+	mockUseCase := new(MockPaymentUseCase)
+	router := middleware.RequestID(NewPaymentHandler(mockUseCase, nil).SetupRoutes())
+
+	withRequestID := mock.MatchedBy(func(ctx context.Context) bool {
+		return middleware.GetReqID(ctx) != ""
+	})
+	payment := entity.NewPayment("txn123", "user123", entity.Money{Amount: 10050, Currency: "USD"}, "user123", "payment requested", time.Now())
+	mockUseCase.On("GetPayment", withRequestID, "txn123").Return(payment, nil)
+
+	req := httptest.NewRequest("GET", "/payments/txn123", nil)
+	rr := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockUseCase.AssertExpectations(t)
+}
+
+func TestPaymentHandler_GetPayment_NotFound(t *testing.T) {
+	// Arrange
+	mockUseCase := new(MockPaymentUseCase)
+	router := NewPaymentHandler(mockUseCase, nil).SetupRoutes()
+
+	mockUseCase.On("GetPayment", mock.Anything, "missing").Return((*entity.Payment)(nil), usecase.ErrPaymentNotFound)
+
+	req := httptest.NewRequest("GET", "/payments/missing", nil)
+	rr := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	var response usecase.PaymentResponse
+	err := json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "payment not found", response.Message)
+
+	mockUseCase.AssertExpectations(t)
+}
+
+func TestPaymentHandler_ListPayments_PassesFilters(t *testing.T) {
+	// Arrange
+	mockUseCase := new(MockPaymentUseCase)
+	router := NewPaymentHandler(mockUseCase, nil).SetupRoutes()
+
+	payment := entity.NewPayment("txn123", "user123", entity.Money{Amount: 10050, Currency: "USD"}, "user123", "payment requested", time.Now())
+	mockUseCase.On("ListPayments", mock.Anything, usecase.ListPaymentsRequest{
+		UserID:      "user123",
+		Status:      entity.StatusCaptured,
+		Currency:    "USD",
+		MinAmount:   "10.00",
+		MaxAmount:   "200.00",
+		CreatedFrom: "2024-05-01T00:00:00Z",
+		CreatedTo:   "2024-06-01T00:00:00Z",
+		Cursor:      "abc",
+		Limit:       10,
+	}).Return(&usecase.PaymentListResponse{Payments: []*entity.Payment{payment}, NextCursor: "def"}, nil)
+
+	req := httptest.NewRequest("GET", "/payments?user_id=user123&status=captured&currency=USD&min_amount=10.00&max_amount=200.00"+
+		"&created_from=2024-05-01T00:00:00Z&created_to=2024-06-01T00:00:00Z&cursor=abc&limit=10", nil)
+	rr := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response usecase.PaymentListResponse
+	err := json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Len(t, response.Payments, 1)
+	assert.Equal(t, "def", response.NextCursor)
+
+	mockUseCase.AssertExpectations(t)
+}
+
+func TestPaymentHandler_ListPayments_InvalidLimit(t *testing.T) {
+	// Arrange
+	mockUseCase := new(MockPaymentUseCase)
+	router := NewPaymentHandler(mockUseCase, nil).SetupRoutes()
+
+	req := httptest.NewRequest("GET", "/payments?limit=ten", nil)
+	rr := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	mockUseCase.AssertNotCalled(t, "ListPayments", mock.Anything, mock.Anything)
+}
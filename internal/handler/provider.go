@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"payment-service/internal/gateway"
+	"payment-service/internal/usecase"
+	"payment-service/internal/webhook"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// maxCallbackSize bounds the body of a processor callback
+const maxCallbackSize = 1 << 20
+
+// ProviderCallbacks verifies the signature of a payment processor's callback and decodes it
+type ProviderCallbacks interface {
+	Parse(provider, signature string, body []byte) (*usecase.ProviderEvent, error)
+}
+
+// ReceiveProviderEvent handles POST /webhooks/{provider} requests
+// @Summary Receive Processor Callback
+// @Description Receives an asynchronous notification from a payment processor: a settlement, a late decline, or a dispute being opened, won or lost.
+// @Description The body must be signed with the processor's secret in the Webhook-Signature header. Each event is applied once however often it is delivered;
+// @Description events the payment's status no longer allows are acknowledged with applied false. Any status other than 2xx asks the processor to send the event again.
+// @Tags Processors
+// @Accept json
+// @Produce json
+// @Param provider path string true "Processor name"
+// @Param Webhook-Signature header string true "t=<unix seconds>,v1=<hex HMAC-SHA256 of the timestamp, a dot and the body>"
+// @Param callback body gateway.Callback true "Processor event"
+// @Success 200 {object} usecase.ProviderEventResponse "Event applied, already applied, or not applicable"
+// @Failure 400 {string} string "Not an event of a known type"
+// @Failure 401 {string} string "Missing, invalid or expired signature"
+// @Failure 404 {object} usecase.ProviderEventResponse "Unknown processor, or no payment charged by the processor"
+// @Failure 500 {object} usecase.ProviderEventResponse "Internal server error"
+// @Failure 503 {object} usecase.ProviderEventResponse "A dependency is failing; retry after the Retry-After header's seconds"
+// @Router /webhooks/{provider} [post]
+func (h *PaymentHandler) ReceiveProviderEvent(w http.ResponseWriter, r *http.Request) {
+	provider := chi.URLParam(r, "provider")
+	if h.callbacks == nil {
+		http.Error(w, "unknown payment processor", http.StatusNotFound)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxCallbackSize))
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	event, err := h.callbacks.Parse(provider, r.Header.Get(webhook.SignatureHeader), body)
+	if err != nil {
+		http.Error(w, err.Error(), callbackStatus(err))
+		return
+	}
+	response, err := h.paymentUseCase.ApplyProviderEvent(r.Context(), *event)
+	writeResponse(w, response, err)
+}
+
+// callbackStatus maps callback verification errors to HTTP status codes
+func callbackStatus(err error) int {
+	switch {
+	case errors.Is(err, gateway.ErrUnknownProcessor):
+		return http.StatusNotFound
+	case errors.Is(err, webhook.ErrInvalidSignature),
+		errors.Is(err, webhook.ErrSignatureExpired):
+		return http.StatusUnauthorized
+	default:
+		return http.StatusBadRequest
+	}
+}
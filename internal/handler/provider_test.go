@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"payment-service/internal/entity"
+	"payment-service/internal/gateway"
+	"payment-service/internal/ledger"
+	"payment-service/internal/repository"
+	"payment-service/internal/usecase"
+	"payment-service/internal/webhook"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// simulatorSecret signs the callbacks of the "primary" simulated processor in tests
+const simulatorSecret = "whsec_simulator"
+
+// newCallbackTestRouter returns routes over a use case charging cards through a simulator named
+// "primary", whose callbacks are signed with simulatorSecret
+func newCallbackTestRouter(t *testing.T) (http.Handler, *usecase.PaymentUseCase, *ledger.Journal) {
+	repo := repository.NewInMemoryPaymentRepository()
+	useCase := usecase.NewPaymentUseCase(repo, usecase.WithGateway(gateway.NewSimulator()))
+	callbacks := gateway.NewCallbacks(map[string]string{"primary": simulatorSecret})
+	return NewPaymentHandler(useCase, nil, WithProviderCallbacks(callbacks)).SetupRoutes(), useCase, ledger.NewJournal(repo)
+}
+
+// fixture reads a callback body of the simulated processor from testdata
+func fixture(t *testing.T, name string) []byte {
+	body, err := os.ReadFile(filepath.Join("testdata", "simulator", name))
+	require.NoError(t, err)
+	return body
+}
+
+// sendCallback posts a callback to the "primary" processor's endpoint, signed with secret
+func sendCallback(router http.Handler, secret string, body []byte) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/webhooks/primary", bytes.NewReader(body))
+	req.Header.Set(webhook.SignatureHeader, webhook.Sign(secret, time.Now(), body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func decodeProviderEventResponse(t *testing.T, w *httptest.ResponseRecorder) usecase.ProviderEventResponse {
+	var response usecase.ProviderEventResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return response
+}
+
+func TestPaymentHandler_ReceiveProviderEvent_SettlementAndChargeback(t *testing.T) {
+	// Arrange
+	router, useCase, l := newCallbackTestRouter(t)
+	_, err := useCase.ProcessPayment(context.Background(), usecase.PaymentRequest{UserID: "user123", Amount: "100.00", Currency: "USD", TransactionID: "txn123"})
+	require.NoError(t, err)
+
+	// Act
+	settled := sendCallback(router, simulatorSecret, fixture(t, "settled.json"))
+	redelivered := sendCallback(router, simulatorSecret, fixture(t, "settled.json"))
+	disputed := sendCallback(router, simulatorSecret, fixture(t, "dispute_created.json"))
+	chargedBack := sendCallback(router, simulatorSecret, fixture(t, "dispute_lost.json"))
+
+	// Assert
+	assert.Equal(t, http.StatusOK, settled.Code)
+	response := decodeProviderEventResponse(t, settled)
+	assert.True(t, response.Applied)
+	assert.Equal(t, entity.StatusSettled, response.Status)
+
+	assert.Equal(t, http.StatusOK, redelivered.Code)
+	response = decodeProviderEventResponse(t, redelivered)
+	assert.False(t, response.Applied, "a redelivered event is applied once")
+	assert.Equal(t, "Event already processed", response.Message)
+
+	assert.Equal(t, entity.StatusDisputed, decodeProviderEventResponse(t, disputed).Status)
+	assert.Equal(t, entity.StatusChargedBack, decodeProviderEventResponse(t, chargedBack).Status)
+
+	payment, err := useCase.GetPayment(context.Background(), "txn123")
+	require.NoError(t, err)
+	last := payment.StatusHistory[len(payment.StatusHistory)-1]
+	assert.Equal(t, usecase.ProcessorActor, last.Actor)
+	assert.Equal(t, "evt_sim_dispute_2", last.EventID)
+	assert.Equal(t, "dispute lost by primary: fraudulent", last.Reason)
+	cash, err := l.Balance(context.Background(), ledger.AccountCash, "USD")
+	require.NoError(t, err)
+	assert.True(t, cash.IsZero(), "the chargeback returned the captured cash")
+}
+
+func TestPaymentHandler_ReceiveProviderEvent_PartiallyRefundedPayment(t *testing.T) {
+	// Arrange
+	router, useCase, l := newCallbackTestRouter(t)
+	_, err := useCase.ProcessPayment(context.Background(), usecase.PaymentRequest{UserID: "user123", Amount: "100.00", Currency: "USD", TransactionID: "txn123"})
+	require.NoError(t, err)
+	_, err = useCase.RefundPayment(context.Background(), usecase.RefundRequest{TransactionID: "txn123", Amount: "25.00", Reason: "requested_by_customer", IdempotencyKey: "refund-1"})
+	require.NoError(t, err)
+
+	// Act
+	settled := sendCallback(router, simulatorSecret, fixture(t, "settled.json"))
+	disputed := sendCallback(router, simulatorSecret, fixture(t, "dispute_created.json"))
+	won := sendCallback(router, simulatorSecret, fixture(t, "dispute_won.json"))
+
+	// Assert
+	response := decodeProviderEventResponse(t, settled)
+	assert.True(t, response.Applied, "a partially refunded payment settles")
+	assert.Equal(t, entity.StatusSettled, response.Status)
+	assert.Equal(t, entity.StatusDisputed, decodeProviderEventResponse(t, disputed).Status)
+	response = decodeProviderEventResponse(t, won)
+	assert.True(t, response.Applied)
+	assert.Equal(t, entity.StatusPartiallyRefunded, response.Status, "a won dispute keeps the earlier refund visible")
+
+	payment, err := useCase.GetPayment(context.Background(), "txn123")
+	require.NoError(t, err)
+	assert.Equal(t, int64(7500), payment.RefundableAmount().Amount)
+	payable, err := l.Balance(context.Background(), ledger.AccountMerchantPayable, "USD")
+	require.NoError(t, err)
+	assert.Equal(t, int64(7500), payable.Amount, "a won dispute moves no money")
+}
+
+func TestPaymentHandler_ReceiveProviderEvent_LateDecline(t *testing.T) {
+	// Arrange
+	router, useCase, _ := newCallbackTestRouter(t)
+	_, err := useCase.ProcessPayment(context.Background(), usecase.PaymentRequest{UserID: "user123", Amount: "100.00", Currency: "USD", TransactionID: "txn123", CaptureMethod: usecase.CaptureManual})
+	require.NoError(t, err)
+	_, err = useCase.AuthorizePayment(context.Background(), "txn123")
+	require.NoError(t, err)
+
+	// Act
+	declined := sendCallback(router, simulatorSecret, fixture(t, "failed.json"))
+	settled := sendCallback(router, simulatorSecret, fixture(t, "settled.json"))
+
+	// Assert
+	assert.Equal(t, http.StatusOK, declined.Code)
+	assert.Equal(t, entity.StatusFailed, decodeProviderEventResponse(t, declined).Status)
+	assert.Equal(t, http.StatusOK, settled.Code, "events that no longer apply are acknowledged")
+	response := decodeProviderEventResponse(t, settled)
+	assert.False(t, response.Applied)
+	assert.Equal(t, entity.StatusFailed, response.Status)
+}
+
+func TestPaymentHandler_ReceiveProviderEvent_Rejected(t *testing.T) {
+	// Arrange
+	router, useCase, _ := newCallbackTestRouter(t)
+	_, err := useCase.ProcessPayment(context.Background(), usecase.PaymentRequest{UserID: "user123", Amount: "100.00", Currency: "USD", TransactionID: "txn456"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		path   string
+		secret string
+		body   []byte
+		status int
+	}{
+		{"wrong secret", "/webhooks/primary", "whsec_other", fixture(t, "settled.json"), http.StatusUnauthorized},
+		{"unknown processor", "/webhooks/secondary", simulatorSecret, fixture(t, "settled.json"), http.StatusNotFound},
+		{"unknown payment", "/webhooks/primary", simulatorSecret, fixture(t, "settled.json"), http.StatusNotFound},
+		{"unknown event type", "/webhooks/primary", simulatorSecret, fixture(t, "unknown_type.json"), http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tt.path, bytes.NewReader(tt.body))
+			req.Header.Set(webhook.SignatureHeader, webhook.Sign(tt.secret, time.Now(), tt.body))
+			w := httptest.NewRecorder()
+
+			// Act
+			router.ServeHTTP(w, req)
+
+			// Assert
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+
+	// Without callbacks configured, no processor is known
+	w := httptest.NewRecorder()
+	NewPaymentHandler(useCase, nil).SetupRoutes().ServeHTTP(w, httptest.NewRequest("POST", "/webhooks/primary", bytes.NewReader(fixture(t, "settled.json"))))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
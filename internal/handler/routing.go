@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"net/http"
+	"payment-service/internal/gateway"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// DefaultDecisionLimit is how many routing decisions are listed when no limit is given
+const DefaultDecisionLimit = 50
+
+// RoutingReader exposes the routing decisions and processor health of a payment router
+type RoutingReader interface {
+	Decision(transactionID string) *gateway.Decision
+	Decisions(limit int) []gateway.Decision
+	Health() []gateway.ProcessorHealth
+}
+
+// RoutingHandler serves routing decisions and processor health for debugging
+type RoutingHandler struct {
+	router RoutingReader
+}
+
+// NewRoutingHandler creates a new routing handler
+func NewRoutingHandler(router RoutingReader) *RoutingHandler {
+	return &RoutingHandler{router: router}
+}
+
+// GetProcessors handles GET /routing/processors requests
+// @Summary Get Processor Health
+// @Description Lists every payment processor with the health the router tracks for it. Unhealthy processors are tried last until their cooldown passes.
+// @Tags Routing
+// @Produce json
+// @Success 200 {array} gateway.ProcessorHealth "Processor health"
+// @Router /routing/processors [get]
+func (h *RoutingHandler) GetProcessors(w http.ResponseWriter, r *http.Request) {
+	writeResponse(w, h.router.Health(), nil)
+}
+
+// ListDecisions handles GET /routing/decisions requests
+// @Summary List Routing Decisions
+// @Description Lists the most recent routing decisions, newest first: the candidate processors of each authorization and the outcome of every attempt.
+// @Tags Routing
+// @Produce json
+// @Param limit query int false "Maximum number of decisions (default 50)"
+// @Success 200 {array} gateway.Decision "Routing decisions"
+// @Failure 400 {string} string "Invalid limit"
+// @Router /routing/decisions [get]
+func (h *RoutingHandler) ListDecisions(w http.ResponseWriter, r *http.Request) {
+	limit := DefaultDecisionLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > gateway.MaxDecisions {
+			http.Error(w, "limit must be between 1 and "+strconv.Itoa(gateway.MaxDecisions), http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+	writeResponse(w, h.router.Decisions(limit), nil)
+}
+
+// GetDecision handles GET /routing/decisions/{transaction_id} requests
+// @Summary Get Routing Decision
+// @Description Returns the latest routing decision for a transaction
+// @Tags Routing
+// @Produce json
+// @Param transaction_id path string true "Transaction ID"
+// @Success 200 {object} gateway.Decision "Routing decision"
+// @Failure 404 {string} string "No routing decision for the transaction"
+// @Router /routing/decisions/{transaction_id} [get]
+func (h *RoutingHandler) GetDecision(w http.ResponseWriter, r *http.Request) {
+	decision := h.router.Decision(chi.URLParam(r, "transaction_id"))
+	if decision == nil {
+		http.Error(w, "no routing decision for the transaction", http.StatusNotFound)
+		return
+	}
+	writeResponse(w, decision, nil)
+}
+
+// SetupRoutes configures the HTTP routes
+func (h *RoutingHandler) SetupRoutes() chi.Router {
+	r := chi.NewRouter()
+	r.Get("/processors", h.GetProcessors)
+	r.Get("/decisions", h.ListDecisions)
+	r.Get("/decisions/{transaction_id}", h.GetDecision)
+	return r
+}
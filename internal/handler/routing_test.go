@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"payment-service/internal/entity"
+	"payment-service/internal/gateway"
+	"payment-service/internal/usecase"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testRouter returns a router whose primary processor is down, so txn123 failed over to the secondary
+func testRouter(t *testing.T) *gateway.Router {
+	primary := gateway.NewSimulator()
+	primary.SetDown(true)
+	router, err := gateway.NewRouter(
+		map[string]gateway.Processor{"primary": primary, "secondary": gateway.NewSimulator()},
+		[]gateway.Route{{Processor: "primary"}, {Processor: "secondary"}},
+	)
+	require.NoError(t, err)
+	_, err = router.Authorize(context.Background(), usecase.GatewayAuthorization{TransactionID: "txn123", Amount: entity.Money{Amount: 10000, Currency: "USD"}})
+	require.NoError(t, err)
+	return router
+}
+
+func TestRoutingHandler_GetDecision(t *testing.T) {
+	// Arrange
+	router := NewRoutingHandler(testRouter(t)).SetupRoutes()
+
+	// Act
+	found := httptest.NewRecorder()
+	router.ServeHTTP(found, httptest.NewRequest("GET", "/decisions/txn123", nil))
+	missing := httptest.NewRecorder()
+	router.ServeHTTP(missing, httptest.NewRequest("GET", "/decisions/txn999", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusOK, found.Code)
+	var decision gateway.Decision
+	require.NoError(t, json.Unmarshal(found.Body.Bytes(), &decision))
+	assert.Equal(t, "secondary", decision.Processor)
+	require.Len(t, decision.Attempts, 2)
+	assert.Equal(t, gateway.OutcomeUnavailable, decision.Attempts[0].Outcome)
+	assert.Equal(t, http.StatusNotFound, missing.Code)
+}
+
+func TestRoutingHandler_ListDecisionsAndProcessors(t *testing.T) {
+	// Arrange
+	router := NewRoutingHandler(testRouter(t)).SetupRoutes()
+
+	// Act
+	decisions := httptest.NewRecorder()
+	router.ServeHTTP(decisions, httptest.NewRequest("GET", "/decisions?limit=10", nil))
+	invalid := httptest.NewRecorder()
+	router.ServeHTTP(invalid, httptest.NewRequest("GET", "/decisions?limit=0", nil))
+	processors := httptest.NewRecorder()
+	router.ServeHTTP(processors, httptest.NewRequest("GET", "/processors", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusOK, decisions.Code)
+	var listed []gateway.Decision
+	require.NoError(t, json.Unmarshal(decisions.Body.Bytes(), &listed))
+	assert.Len(t, listed, 1)
+	assert.Equal(t, http.StatusBadRequest, invalid.Code)
+
+	assert.Equal(t, http.StatusOK, processors.Code)
+	var health []gateway.ProcessorHealth
+	require.NoError(t, json.Unmarshal(processors.Body.Bytes(), &health))
+	require.Len(t, health, 2)
+	assert.Equal(t, 1, health[0].ConsecutiveFailures)
+}
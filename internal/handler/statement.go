@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"payment-service/internal/entity"
+	"payment-service/internal/usecase"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Statement formats
+const (
+	formatJSON = "json"
+	formatCSV  = "csv"
+	formatText = "text"
+)
+
+// ListUserPayments handles GET /users/{user_id}/payments requests
+// @Summary List User Payments
+// @Description Lists one user's payments newest first. Accepts the same filters and cursor pagination as GET /payments.
+// @Tags Users
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Param status query string false "Only payments in this status" Enums(pending, authorized, captured, voided, refunded, partially_refunded, failed, settled, disputed, charged_back)
+// @Param currency query string false "Only payments in this ISO 4217 currency"
+// @Param min_amount query string false "Smallest amount as a decimal string, inclusive; requires currency"
+// @Param max_amount query string false "Largest amount as a decimal string, inclusive; requires currency"
+// @Param created_from query string false "Earliest creation time (RFC 3339), inclusive"
+// @Param created_to query string false "Latest creation time (RFC 3339), exclusive"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param limit query int false "Page size, 1 to 200 (default 50)"
+// @Success 200 {object} usecase.PaymentListResponse "Page of payments"
+// @Failure 400 {object} usecase.PaymentResponse "Invalid filter, cursor or limit"
+// @Failure 500 {object} usecase.PaymentResponse "Internal server error"
+// @Router /users/{user_id}/payments [get]
+func (h *PaymentHandler) ListUserPayments(w http.ResponseWriter, r *http.Request) {
+	req, err := listPaymentsRequest(r)
+	if err != nil {
+		writeResponse(w, &usecase.PaymentResponse{Message: err.Error()}, err)
+		return
+	}
+	req.UserID = chi.URLParam(r, "user_id")
+
+	response, err := h.paymentUseCase.ListPayments(r.Context(), req)
+	if err != nil {
+		writeResponse(w, &usecase.PaymentResponse{UserID: req.UserID, Message: err.Error()}, err)
+		return
+	}
+	writeResponse(w, response, nil)
+}
+
+// GetStatement handles GET /users/{user_id}/statements/{month} requests
+// @Summary Get Monthly Statement
+// @Description Summarizes a user's payments and refunds for a calendar month (UTC): every entry plus captured, refunded and net totals per currency.
+// @Description Payments count in the month they were created; refunds in the month they were made.
+// @Description The format is chosen with the format parameter, or else the Accept header (text/csv, text/plain); JSON is the default.
+// @Tags Users
+// @Produce json
+// @Produce text/csv
+// @Produce plain
+// @Param user_id path string true "User ID"
+// @Param month path string true "Month as YYYY-MM"
+// @Param format query string false "Response format" Enums(json, csv, text)
+// @Success 200 {object} entity.Statement "Statement"
+// @Failure 400 {object} usecase.PaymentResponse "Invalid month or format"
+// @Failure 500 {object} usecase.PaymentResponse "Internal server error"
+// @Router /users/{user_id}/statements/{month} [get]
+func (h *PaymentHandler) GetStatement(w http.ResponseWriter, r *http.Request) {
+	format, ok := statementFormat(r)
+	if !ok {
+		http.Error(w, "format must be json, csv or text", http.StatusBadRequest)
+		return
+	}
+
+	userID := chi.URLParam(r, "user_id")
+	statement, err := h.paymentUseCase.GetStatement(r.Context(), userID, chi.URLParam(r, "month"))
+	if err != nil {
+		writeResponse(w, &usecase.PaymentResponse{UserID: userID, Message: err.Error()}, err)
+		return
+	}
+
+	switch format {
+	case formatCSV:
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "statement-"+statement.Month+".csv"))
+		writeStatementCSV(w, statement)
+	case formatText:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		writeStatementText(w, statement)
+	default:
+		writeResponse(w, statement, nil)
+	}
+}
+
+// statementFormat picks the statement format from the format parameter or the Accept header
+func statementFormat(r *http.Request) (string, bool) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format, format == formatJSON || format == formatCSV || format == formatText
+	}
+	accept := r.Header.Get("Accept")
+	switch {
+	case strings.Contains(accept, "text/csv"):
+		return formatCSV, true
+	case strings.Contains(accept, "text/plain"):
+		return formatText, true
+	}
+	return formatJSON, true
+}
+
+// writeStatementCSV writes one row per statement entry, followed by one row per currency total
+func writeStatementCSV(w http.ResponseWriter, statement *entity.Statement) {
+	out := csv.NewWriter(w)
+	out.Write([]string{"type", "date", "transaction_id", "refund_id", "status", "currency", "amount"})
+	for _, entry := range statement.Entries {
+		amount := entry.Amount.Decimal()
+		if entry.Type == entity.EntryRefund {
+			amount = "-" + amount
+		}
+		out.Write([]string{entry.Type, entry.At.UTC().Format(time.RFC3339), entry.TransactionID, entry.RefundID, entry.Status, entry.Amount.Currency, amount})
+	}
+	for _, total := range statement.Totals {
+		out.Write([]string{"net_total", statement.Month, "", "", "", total.Currency, total.Net.Decimal()})
+	}
+	out.Flush()
+}
+
+// writeStatementText writes the statement as an aligned plain-text report
+func writeStatementText(w http.ResponseWriter, statement *entity.Statement) {
+	fmt.Fprintf(w, "Statement for %s, %s\n", statement.UserID, statement.PeriodStart.Format("January 2006"))
+	fmt.Fprintf(w, "Period: %s to %s (UTC)\n\n", statement.PeriodStart.Format("2006-01-02"), statement.PeriodEnd.AddDate(0, 0, -1).Format("2006-01-02"))
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	if len(statement.Entries) == 0 {
+		fmt.Fprintln(w, "No payments or refunds this month.")
+	} else {
+		fmt.Fprintln(tw, "Date\tType\tTransaction\tAmount\t")
+		for _, entry := range statement.Entries {
+			amount := entry.Amount.String()
+			if entry.Type == entity.EntryRefund {
+				amount = "-" + amount
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t\n", entry.At.UTC().Format("2006-01-02"), entry.Type, entry.TransactionID, amount)
+		}
+		tw.Flush()
+	}
+
+	for _, total := range statement.Totals {
+		fmt.Fprintf(w, "\nTotals (%s)\n", total.Currency)
+		fmt.Fprintf(tw, "Payments\t%d\t\n", total.Payments)
+		fmt.Fprintf(tw, "Captured\t%s\t\n", total.Captured.Decimal())
+		fmt.Fprintf(tw, "Refunded\t%s\t\n", total.Refunded.Decimal())
+		fmt.Fprintf(tw, "Net\t%s\t\n", total.Net.Decimal())
+		tw.Flush()
+	}
+}
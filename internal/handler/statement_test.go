@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"payment-service/internal/entity"
+	"payment-service/internal/usecase"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// testStatement returns a May 2024 statement with one payment and one refund
+func testStatement() *entity.Statement {
+	may := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	payment := entity.NewPayment("txn123", "user123", entity.Money{Amount: 10000, Currency: "USD"}, "user123", "payment requested", may.AddDate(0, 0, 1))
+	payment.Status = entity.StatusPartiallyRefunded
+	payment.CapturedAmount = payment.Amount
+	payment.Refunds = []entity.Refund{{RefundID: "txn123-refund-1", Amount: entity.Money{Amount: 2500, Currency: "USD"}, CreatedAt: may.AddDate(0, 0, 2)}}
+	return entity.NewStatement("user123", may, []*entity.Payment{payment})
+}
+
+func TestPaymentHandler_ListUserPayments_UsesPathUser(t *testing.T) {
+	// Arrange
+	mockUseCase := new(MockPaymentUseCase)
+	router := NewPaymentHandler(mockUseCase, nil).SetupRoutes()
+
+	mockUseCase.On("ListPayments", mock.Anything, usecase.ListPaymentsRequest{UserID: "user123", Status: entity.StatusCaptured, Limit: 5}).
+		Return(&usecase.PaymentListResponse{Payments: []*entity.Payment{}}, nil)
+
+	// user_id in the query string must not override the path
+	req := httptest.NewRequest("GET", "/users/user123/payments?status=captured&limit=5&user_id=other", nil)
+	rr := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockUseCase.AssertExpectations(t)
+}
+
+func TestPaymentHandler_GetStatement_JSON(t *testing.T) {
+	// Arrange
+	mockUseCase := new(MockPaymentUseCase)
+	router := NewPaymentHandler(mockUseCase, nil).SetupRoutes()
+	mockUseCase.On("GetStatement", mock.Anything, "user123", "2024-05").Return(testStatement(), nil)
+
+	req := httptest.NewRequest("GET", "/users/user123/statements/2024-05", nil)
+	rr := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+	var statement entity.Statement
+	err := json.Unmarshal(rr.Body.Bytes(), &statement)
+	assert.NoError(t, err)
+	assert.Len(t, statement.Entries, 2)
+	assert.Equal(t, entity.Money{Amount: 7500, Currency: "USD"}, statement.Totals[0].Net)
+	mockUseCase.AssertExpectations(t)
+}
+
+func TestPaymentHandler_GetStatement_CSV(t *testing.T) {
+	// Arrange
+	mockUseCase := new(MockPaymentUseCase)
+	router := NewPaymentHandler(mockUseCase, nil).SetupRoutes()
+	mockUseCase.On("GetStatement", mock.Anything, "user123", "2024-05").Return(testStatement(), nil)
+
+	req := httptest.NewRequest("GET", "/users/user123/statements/2024-05?format=csv", nil)
+	rr := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "type,date,transaction_id,refund_id,status,currency,amount\n"+
+		"payment,2024-05-02T00:00:00Z,txn123,,partially_refunded,USD,100.00\n"+
+		"refund,2024-05-03T00:00:00Z,txn123,txn123-refund-1,,USD,-25.00\n"+
+		"net_total,2024-05,,,,USD,75.00\n", rr.Body.String())
+	mockUseCase.AssertExpectations(t)
+}
+
+func TestPaymentHandler_GetStatement_TextFromAcceptHeader(t *testing.T) {
+	// Arrange
+	mockUseCase := new(MockPaymentUseCase)
+	router := NewPaymentHandler(mockUseCase, nil).SetupRoutes()
+	mockUseCase.On("GetStatement", mock.Anything, "user123", "2024-05").Return(testStatement(), nil)
+
+	req := httptest.NewRequest("GET", "/users/user123/statements/2024-05", nil)
+	req.Header.Set("Accept", "text/plain")
+	rr := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/plain; charset=utf-8", rr.Header().Get("Content-Type"))
+	body := rr.Body.String()
+	assert.Contains(t, body, "Statement for user123, May 2024")
+	assert.Contains(t, body, "-25.00 USD")
+	assert.Regexp(t, `Net\s+75\.00`, body)
+	mockUseCase.AssertExpectations(t)
+}
+
+func TestPaymentHandler_GetStatement_Errors(t *testing.T) {
+	// Arrange
+	mockUseCase := new(MockPaymentUseCase)
+	router := NewPaymentHandler(mockUseCase, nil).SetupRoutes()
+	mockUseCase.On("GetStatement", mock.Anything, "user123", "May").Return((*entity.Statement)(nil), usecase.ErrInvalidMonth)
+
+	badMonth := httptest.NewRecorder()
+	badFormat := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(badMonth, httptest.NewRequest("GET", "/users/user123/statements/May", nil))
+	router.ServeHTTP(badFormat, httptest.NewRequest("GET", "/users/user123/statements/2024-05?format=pdf", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, badMonth.Code)
+	assert.Equal(t, http.StatusBadRequest, badFormat.Code)
+	mockUseCase.AssertNotCalled(t, "GetStatement", mock.Anything, "user123", "2024-05")
+	mockUseCase.AssertExpectations(t)
+}
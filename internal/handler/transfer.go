@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"payment-service/internal/usecase"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// TransferFunds handles POST /transfers requests
+// @Summary Transfer Funds
+// @Description Moves funds from one user's wallet to another's with the same idempotency as POST /pay. Retrying the same transaction_id will not move funds twice; reusing it with a different payload is rejected with 409.
+// @Description The idempotency key is taken from the Idempotency-Key header, falling back to transaction_id. Repeated keys replay the original status and body.
+// @Tags Transfers
+// @Accept json
+// @Produce json
+// @Param Idempotency-Key header string false "Idempotency key; used as transaction_id when the body omits it"
+// @Param transfer body usecase.TransferRequest true "Transfer request"
+// @Success 200 {object} usecase.TransferResponse "Transfer completed"
+// @Failure 400 {object} usecase.TransferResponse "Bad request - validation error or transfer to the same user"
+// @Failure 402 {object} usecase.TransferResponse "Insufficient funds in the sender's wallet"
+// @Failure 409 {object} usecase.TransferResponse "Conflict - idempotency key reused with a different payload or still in progress"
+// @Failure 422 {object} usecase.TransferResponse "Per-transfer or daily transfer limit exceeded, or transfers are not allowed in the currency"
+// @Failure 500 {object} usecase.TransferResponse "Internal server error"
+// @Router /transfers [post]
+func (h *PaymentHandler) TransferFunds(w http.ResponseWriter, r *http.Request) {
+	var req usecase.TransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	// Clients following the Idempotency-Key convention may omit transaction_id from the body
+	if req.TransactionID == "" {
+		req.TransactionID = r.Header.Get(IdempotencyKeyHeader)
+	}
+
+	response, err := h.paymentUseCase.TransferFunds(r.Context(), req)
+	writeResponse(w, response, err)
+}
+
+// GetTransferHistory handles GET /users/{user_id}/transfers requests
+// @Summary Get Transfer History
+// @Description Lists the transfers a user sent and received, newest first. With the "with" parameter only the transfers between the two users are listed, which is the same history both parties see.
+// @Tags Transfers
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Param with query string false "Only transfers with this user"
+// @Success 200 {object} usecase.TransferHistoryResponse "Transfer history"
+// @Failure 500 {object} usecase.TransferResponse "Internal server error"
+// @Router /users/{user_id}/transfers [get]
+func (h *PaymentHandler) GetTransferHistory(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "user_id")
+	history, err := h.paymentUseCase.GetTransferHistory(r.Context(), userID, r.URL.Query().Get("with"))
+	if err != nil {
+		writeResponse(w, &usecase.TransferResponse{FromUserID: userID, Message: err.Error()}, err)
+		return
+	}
+	writeResponse(w, history, nil)
+}
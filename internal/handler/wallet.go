@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"payment-service/internal/usecase"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// GetWallet handles GET /users/{user_id}/wallet requests
+// @Summary Get Wallet
+// @Description Returns a user's wallet balances per currency and every transaction, oldest first. Users who never used their wallet have an empty one.
+// @Tags Wallets
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Success 200 {object} entity.Wallet "Wallet"
+// @Failure 500 {object} usecase.WalletResponse "Internal server error"
+// @Router /users/{user_id}/wallet [get]
+func (h *PaymentHandler) GetWallet(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "user_id")
+	wallet, err := h.paymentUseCase.GetWallet(r.Context(), userID)
+	if err != nil {
+		writeResponse(w, &usecase.WalletResponse{UserID: userID, Message: err.Error()}, err)
+		return
+	}
+	writeResponse(w, wallet, nil)
+}
+
+// TopUpWallet handles POST /users/{user_id}/wallet/top-ups requests
+// @Summary Top Up Wallet
+// @Description Adds funds to a user's wallet. Each top-up needs its own idempotency key, from the body or the Idempotency-Key header; retrying a key returns the original top-up.
+// @Tags Wallets
+// @Accept json
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Param Idempotency-Key header string false "Top-up idempotency key; used when the body omits idempotency_key"
+// @Param top_up body usecase.WalletRequest true "Top-up request"
+// @Success 200 {object} usecase.WalletResponse "Wallet topped up"
+// @Failure 400 {object} usecase.WalletResponse "Invalid amount, currency or missing idempotency key"
+// @Failure 409 {object} usecase.WalletResponse "Idempotency key used for a different amount"
+// @Failure 500 {object} usecase.WalletResponse "Internal server error"
+// @Router /users/{user_id}/wallet/top-ups [post]
+func (h *PaymentHandler) TopUpWallet(w http.ResponseWriter, r *http.Request) {
+	req, ok := walletRequest(w, r)
+	if !ok {
+		return
+	}
+	response, err := h.paymentUseCase.TopUpWallet(r.Context(), req)
+	writeResponse(w, response, err)
+}
+
+// DebitWallet handles POST /users/{user_id}/wallet/debits requests
+// @Summary Debit Wallet
+// @Description Withdraws funds from a user's wallet. Fails with 402 when the balance is too low. Each debit needs its own idempotency key; retrying a key returns the original debit.
+// @Tags Wallets
+// @Accept json
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Param Idempotency-Key header string false "Debit idempotency key; used when the body omits idempotency_key"
+// @Param debit body usecase.WalletRequest true "Debit request"
+// @Success 200 {object} usecase.WalletResponse "Wallet debited"
+// @Failure 400 {object} usecase.WalletResponse "Invalid amount, currency or missing idempotency key"
+// @Failure 402 {object} usecase.WalletResponse "Insufficient funds"
+// @Failure 409 {object} usecase.WalletResponse "Idempotency key used for a different amount"
+// @Failure 500 {object} usecase.WalletResponse "Internal server error"
+// @Router /users/{user_id}/wallet/debits [post]
+func (h *PaymentHandler) DebitWallet(w http.ResponseWriter, r *http.Request) {
+	req, ok := walletRequest(w, r)
+	if !ok {
+		return
+	}
+	response, err := h.paymentUseCase.DebitWallet(r.Context(), req)
+	writeResponse(w, response, err)
+}
+
+// walletRequest decodes a top-up or debit request, writing a 400 response if the body is not valid JSON
+func walletRequest(w http.ResponseWriter, r *http.Request) (usecase.WalletRequest, bool) {
+	var req usecase.WalletRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return req, false
+	}
+	req.UserID = chi.URLParam(r, "user_id")
+	if req.IdempotencyKey == "" {
+		req.IdempotencyKey = r.Header.Get(IdempotencyKeyHeader)
+	}
+	return req, true
+}
@@ -0,0 +1,242 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"payment-service/internal/entity"
+	"payment-service/internal/usecase"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaymentHandler_GetWallet(t *testing.T) {
+	// Arrange
+	mockUseCase := new(MockPaymentUseCase)
+	router := NewPaymentHandler(mockUseCase, nil).SetupRoutes()
+
+	wallet := entity.NewWallet("user123")
+	wallet.Credit("top_up:1", entity.WalletTopUp, "", entity.Money{Amount: 5000, Currency: "USD"}, time.Now())
+	mockUseCase.On("GetWallet", mock.Anything, "user123").Return(wallet, nil)
+
+	req := httptest.NewRequest("GET", "/users/user123/wallet", nil)
+	rr := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response entity.Wallet
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, "50.00", response.Balance("USD").Decimal())
+	assert.Len(t, response.Transactions, 1)
+	mockUseCase.AssertExpectations(t)
+}
+
+func TestPaymentHandler_TopUpWallet_IdempotencyKeyFromHeader(t *testing.T) {
+	// Arrange
+	mockUseCase := new(MockPaymentUseCase)
+	router := NewPaymentHandler(mockUseCase, nil).SetupRoutes()
+
+	mockUseCase.On("TopUpWallet", mock.Anything, usecase.WalletRequest{
+		UserID:         "user123",
+		Amount:         "25.00",
+		Currency:       "USD",
+		IdempotencyKey: "top-up-1",
+	}).Return(&usecase.WalletResponse{
+		UserID:              "user123",
+		WalletTransactionID: "top_up:top-up-1",
+		Type:                entity.WalletTopUp,
+		Amount:              "25.00",
+		Currency:            "USD",
+		Balance:             "25.00",
+		Message:             "Wallet topped up",
+	}, nil)
+
+	req := httptest.NewRequest("POST", "/users/user123/wallet/top-ups", bytes.NewBufferString(`{"amount":"25.00","currency":"USD"}`))
+	req.Header.Set(IdempotencyKeyHeader, "top-up-1")
+	rr := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response usecase.WalletResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, "25.00", response.Balance)
+	mockUseCase.AssertExpectations(t)
+}
+
+func TestPaymentHandler_DebitWallet_ErrorStatusCodes(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"insufficient funds", usecase.ErrInsufficientFunds, http.StatusPaymentRequired},
+		{"missing key", usecase.ErrMissingWalletKey, http.StatusBadRequest},
+		{"key reused", usecase.ErrWalletConflict, http.StatusConflict},
+		{"wallets disabled", usecase.ErrWalletsDisabled, http.StatusNotImplemented},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockUseCase := new(MockPaymentUseCase)
+			router := NewPaymentHandler(mockUseCase, nil).SetupRoutes()
+
+			mockUseCase.On("DebitWallet", mock.Anything, mock.AnythingOfType("usecase.WalletRequest")).Return(&usecase.WalletResponse{
+				UserID:  "user123",
+				Message: tt.err.Error(),
+			}, tt.err)
+
+			req := httptest.NewRequest("POST", "/users/user123/wallet/debits", bytes.NewBufferString(`{"amount":"5.00","currency":"USD","idempotency_key":"debit-1"}`))
+			rr := httptest.NewRecorder()
+
+			// Act
+			router.ServeHTTP(rr, req)
+
+			// Assert
+			assert.Equal(t, tt.status, rr.Code)
+			mockUseCase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPaymentHandler_ProcessPayment_InsufficientFunds(t *testing.T) {
+	// Arrange
+	mockUseCase := new(MockPaymentUseCase)
+	router := NewPaymentHandler(mockUseCase, nil).SetupRoutes()
+
+	paymentReq := usecase.PaymentRequest{UserID: "user123", Amount: "100.00", Currency: "USD", TransactionID: "txn123", PaymentMethod: "wallet"}
+	mockUseCase.On("ProcessPayment", mock.Anything, paymentReq).Return(&usecase.PaymentResponse{
+		TransactionID: "txn123",
+		Status:        entity.StatusFailed,
+		Message:       usecase.ErrInsufficientFunds.Error(),
+	}, usecase.ErrInsufficientFunds)
+
+	body, _ := json.Marshal(paymentReq)
+	req := httptest.NewRequest("POST", "/pay", bytes.NewBuffer(body))
+	rr := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusPaymentRequired, rr.Code)
+	mockUseCase.AssertExpectations(t)
+}
+
+func TestPaymentHandler_TransferFunds_TransactionIDFromHeader(t *testing.T) {
+	// Arrange
+	mockUseCase := new(MockPaymentUseCase)
+	router := NewPaymentHandler(mockUseCase, nil).SetupRoutes()
+
+	mockUseCase.On("TransferFunds", mock.Anything, usecase.TransferRequest{
+		FromUserID:    "user123",
+		ToUserID:      "user456",
+		Amount:        "25.00",
+		Currency:      "USD",
+		TransactionID: "transfer-1",
+	}).Return(&usecase.TransferResponse{
+		TransactionID: "transfer-1",
+		FromUserID:    "user123",
+		ToUserID:      "user456",
+		Amount:        "25.00",
+		Currency:      "USD",
+		Status:        entity.TransferCompleted,
+		Message:       "Transfer completed",
+	}, nil)
+
+	req := httptest.NewRequest("POST", "/transfers", bytes.NewBufferString(`{"from_user_id":"user123","to_user_id":"user456","amount":"25.00","currency":"USD"}`))
+	req.Header.Set(IdempotencyKeyHeader, "transfer-1")
+	rr := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response usecase.TransferResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, entity.TransferCompleted, response.Status)
+	mockUseCase.AssertExpectations(t)
+}
+
+func TestPaymentHandler_TransferFunds_ErrorStatusCodes(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"self transfer", usecase.ErrSelfTransfer, http.StatusBadRequest},
+		{"insufficient funds", usecase.ErrInsufficientFunds, http.StatusPaymentRequired},
+		{"transaction ID reused", usecase.ErrIdempotencyConflict, http.StatusConflict},
+		{"limit exceeded", usecase.ErrTransferLimitExceeded, http.StatusUnprocessableEntity},
+		{"currency not allowed", usecase.ErrTransferCurrencyNotAllowed, http.StatusUnprocessableEntity},
+		{"transfers disabled", usecase.ErrTransfersDisabled, http.StatusNotImplemented},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockUseCase := new(MockPaymentUseCase)
+			router := NewPaymentHandler(mockUseCase, nil).SetupRoutes()
+
+			mockUseCase.On("TransferFunds", mock.Anything, mock.AnythingOfType("usecase.TransferRequest")).Return(&usecase.TransferResponse{
+				TransactionID: "transfer-1",
+				Message:       tt.err.Error(),
+			}, tt.err)
+
+			req := httptest.NewRequest("POST", "/transfers", bytes.NewBufferString(`{"from_user_id":"user123","to_user_id":"user456","amount":"5.00","currency":"USD","transaction_id":"transfer-1"}`))
+			rr := httptest.NewRecorder()
+
+			// Act
+			router.ServeHTTP(rr, req)
+
+			// Assert
+			assert.Equal(t, tt.status, rr.Code)
+			mockUseCase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPaymentHandler_GetTransferHistory(t *testing.T) {
+	// Arrange
+	mockUseCase := new(MockPaymentUseCase)
+	router := NewPaymentHandler(mockUseCase, nil).SetupRoutes()
+
+	mockUseCase.On("GetTransferHistory", mock.Anything, "user123", "user456").Return(&usecase.TransferHistoryResponse{
+		UserID:       "user123",
+		Counterparty: "user456",
+		Transfers: []usecase.TransferHistoryEntry{{
+			TransactionID: "transfer-1",
+			Direction:     usecase.TransferSent,
+			Counterparty:  "user456",
+			Amount:        entity.Money{Amount: 2500, Currency: "USD"},
+			Balance:       entity.Money{Amount: 7500, Currency: "USD"},
+			At:            time.Now(),
+		}},
+	}, nil)
+
+	req := httptest.NewRequest("GET", "/users/user123/transfers?with=user456", nil)
+	rr := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response usecase.TransferHistoryResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	require.Len(t, response.Transfers, 1)
+	assert.Equal(t, "25.00", response.Transfers[0].Amount.Decimal())
+	mockUseCase.AssertExpectations(t)
+}
@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"payment-service/internal/webhook"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// WebhookRegistry manages the webhook endpoints of merchants and the deliveries made to them
+type WebhookRegistry interface {
+	RegisterEndpoint(ctx context.Context, merchantID, url string, eventTypes []string) (*webhook.Endpoint, error)
+	Endpoints(ctx context.Context, merchantID string) ([]webhook.Endpoint, error)
+	Deliveries(ctx context.Context, merchantID, status string) ([]webhook.Delivery, error)
+	Replay(ctx context.Context, merchantID, deliveryID string) (*webhook.Delivery, error)
+}
+
+// WebhookHandler lets merchants register webhook endpoints and inspect and replay deliveries
+type WebhookHandler struct {
+	registry WebhookRegistry
+}
+
+// NewWebhookHandler creates a new webhook handler
+func NewWebhookHandler(registry WebhookRegistry) *WebhookHandler {
+	return &WebhookHandler{registry: registry}
+}
+
+// RegisterEndpointRequest registers a webhook endpoint
+type RegisterEndpointRequest struct {
+	URL        string   `json:"url" example:"https://merchant.example.com/webhooks"`
+	EventTypes []string `json:"event_types,omitempty" example:"payment.captured,payment.refunded"`
+}
+
+// RegisterEndpoint handles POST /merchants/{merchant_id}/webhooks requests
+// @Summary Register Webhook Endpoint
+// @Description Registers an endpoint that receives an event for every status change of the merchant's payments, or only the given event types.
+// @Description The response carries the endpoint's signing secret, which is never shown again. Every request has a Webhook-Signature header
+// @Description "t=<unix seconds>,v1=<hex HMAC-SHA256 of the timestamp, a dot and the body, keyed with the secret>" and a Webhook-Id header with the event ID.
+// @Description Failed deliveries are retried with exponential backoff until they are dead-lettered.
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param merchant_id path string true "Merchant ID"
+// @Param request body RegisterEndpointRequest true "Endpoint"
+// @Success 201 {object} webhook.Endpoint "Registered endpoint with its secret"
+// @Failure 400 {string} string "Invalid URL or event types"
+// @Failure 500 {string} string "Internal server error"
+// @Router /merchants/{merchant_id}/webhooks [post]
+func (h *WebhookHandler) RegisterEndpoint(w http.ResponseWriter, r *http.Request) {
+	var req RegisterEndpointRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	endpoint, err := h.registry.RegisterEndpoint(r.Context(), chi.URLParam(r, "merchant_id"), req.URL, req.EventTypes)
+	if err != nil {
+		http.Error(w, err.Error(), webhookStatus(err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(endpoint)
+}
+
+// ListEndpoints handles GET /merchants/{merchant_id}/webhooks requests
+// @Summary List Webhook Endpoints
+// @Description Lists the merchant's webhook endpoints, without their secrets
+// @Tags Webhooks
+// @Produce json
+// @Param merchant_id path string true "Merchant ID"
+// @Success 200 {array} webhook.Endpoint "Webhook endpoints"
+// @Failure 500 {string} string "Internal server error"
+// @Router /merchants/{merchant_id}/webhooks [get]
+func (h *WebhookHandler) ListEndpoints(w http.ResponseWriter, r *http.Request) {
+	endpoints, err := h.registry.Endpoints(r.Context(), chi.URLParam(r, "merchant_id"))
+	if err != nil {
+		http.Error(w, err.Error(), webhookStatus(err))
+		return
+	}
+	writeResponse(w, endpoints, nil)
+}
+
+// ListDeliveries handles GET /merchants/{merchant_id}/webhooks/deliveries requests
+// @Summary List Webhook Deliveries
+// @Description Lists the merchant's webhook deliveries, newest first. Dead deliveries failed every attempt and are only sent again when replayed.
+// @Tags Webhooks
+// @Produce json
+// @Param merchant_id path string true "Merchant ID"
+// @Param status query string false "Delivery status" Enums(pending, delivered, dead)
+// @Success 200 {array} webhook.Delivery "Webhook deliveries"
+// @Failure 400 {string} string "Invalid status"
+// @Failure 500 {string} string "Internal server error"
+// @Router /merchants/{merchant_id}/webhooks/deliveries [get]
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	deliveries, err := h.registry.Deliveries(r.Context(), chi.URLParam(r, "merchant_id"), r.URL.Query().Get("status"))
+	if err != nil {
+		http.Error(w, err.Error(), webhookStatus(err))
+		return
+	}
+	writeResponse(w, deliveries, nil)
+}
+
+// ReplayDelivery handles POST /merchants/{merchant_id}/webhooks/deliveries/{delivery_id}/replay requests
+// @Summary Replay Webhook Delivery
+// @Description Sends a delivery again, whatever its status, with a fresh set of attempts. Receivers should deduplicate events by their Webhook-Id.
+// @Tags Webhooks
+// @Produce json
+// @Param merchant_id path string true "Merchant ID"
+// @Param delivery_id path string true "Delivery ID"
+// @Success 200 {object} webhook.Delivery "Delivery scheduled for sending"
+// @Failure 404 {string} string "Delivery not found"
+// @Failure 409 {string} string "Delivery is being attempted"
+// @Failure 500 {string} string "Internal server error"
+// @Router /merchants/{merchant_id}/webhooks/deliveries/{delivery_id}/replay [post]
+func (h *WebhookHandler) ReplayDelivery(w http.ResponseWriter, r *http.Request) {
+	delivery, err := h.registry.Replay(r.Context(), chi.URLParam(r, "merchant_id"), chi.URLParam(r, "delivery_id"))
+	if err != nil {
+		http.Error(w, err.Error(), webhookStatus(err))
+		return
+	}
+	writeResponse(w, delivery, nil)
+}
+
+// webhookStatus maps webhook registry errors to HTTP status codes
+func webhookStatus(err error) int {
+	switch {
+	case errors.Is(err, webhook.ErrInvalidURL),
+		errors.Is(err, webhook.ErrInvalidEventType),
+		errors.Is(err, webhook.ErrInvalidMerchant),
+		errors.Is(err, webhook.ErrInvalidStatusFilter):
+		return http.StatusBadRequest
+	case errors.Is(err, webhook.ErrDeliveryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, webhook.ErrDeliveryInFlight):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// SetupRoutes configures the HTTP routes
+func (h *WebhookHandler) SetupRoutes() chi.Router {
+	r := chi.NewRouter()
+	r.Post("/{merchant_id}/webhooks", h.RegisterEndpoint)
+	r.Get("/{merchant_id}/webhooks", h.ListEndpoints)
+	r.Get("/{merchant_id}/webhooks/deliveries", h.ListDeliveries)
+	r.Post("/{merchant_id}/webhooks/deliveries/{delivery_id}/replay", h.ReplayDelivery)
+	return r
+}
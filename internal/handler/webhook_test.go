@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"payment-service/internal/repository"
+	"payment-service/internal/usecase"
+	"payment-service/internal/webhook"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookHandler_RegisterEndpoint(t *testing.T) {
+	// Arrange
+	router := NewWebhookHandler(webhook.NewDispatcher(repository.NewInMemoryPaymentRepository())).SetupRoutes()
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"valid", `{"url":"https://merchant.example.com/hooks","event_types":["payment.captured"]}`, http.StatusCreated},
+		{"invalid URL", `{"url":"merchant.example.com"}`, http.StatusBadRequest},
+		{"invalid event type", `{"url":"https://merchant.example.com/hooks","event_types":["payment.shipped"]}`, http.StatusBadRequest},
+		{"invalid body", `{`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("POST", "/merchant-1/webhooks", strings.NewReader(tt.body)))
+
+			// Assert
+			assert.Equal(t, tt.status, w.Code)
+			if tt.status == http.StatusCreated {
+				var endpoint webhook.Endpoint
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &endpoint))
+				assert.Equal(t, "merchant-1", endpoint.MerchantID)
+				assert.NotEmpty(t, endpoint.Secret)
+			}
+		})
+	}
+
+	listed := httptest.NewRecorder()
+	router.ServeHTTP(listed, httptest.NewRequest("GET", "/merchant-1/webhooks", nil))
+	var endpoints []webhook.Endpoint
+	require.NoError(t, json.Unmarshal(listed.Body.Bytes(), &endpoints))
+	require.Len(t, endpoints, 1)
+	assert.Empty(t, endpoints[0].Secret)
+}
+
+func TestWebhookHandler_ListAndReplayDeliveries(t *testing.T) {
+	// Arrange: a receiver that is down, so the only attempt dead-letters the delivery
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+	repo := repository.NewInMemoryPaymentRepository()
+	dispatcher := webhook.NewDispatcher(repo, webhook.WithRetryPolicy(webhook.RetryPolicy{MaxAttempts: 1}))
+	_, err := dispatcher.RegisterEndpoint(context.Background(), "merchant-1", receiver.URL, nil)
+	require.NoError(t, err)
+	useCase := usecase.NewPaymentUseCase(repo, usecase.WithEvents(dispatcher, repo))
+	_, err = useCase.ProcessPayment(context.Background(), usecase.PaymentRequest{UserID: "user123", Amount: "100.00", Currency: "USD", TransactionID: "txn123", MerchantID: "merchant-1"})
+	require.NoError(t, err)
+	dispatcher.DeliverDue(context.Background())
+	router := NewWebhookHandler(dispatcher).SetupRoutes()
+
+	// Act
+	dead := httptest.NewRecorder()
+	router.ServeHTTP(dead, httptest.NewRequest("GET", "/merchant-1/webhooks/deliveries?status=dead", nil))
+	invalid := httptest.NewRecorder()
+	router.ServeHTTP(invalid, httptest.NewRequest("GET", "/merchant-1/webhooks/deliveries?status=lost", nil))
+	var deliveries []webhook.Delivery
+	require.NoError(t, json.Unmarshal(dead.Body.Bytes(), &deliveries))
+	require.NotEmpty(t, deliveries)
+	replayed := httptest.NewRecorder()
+	router.ServeHTTP(replayed, httptest.NewRequest("POST", "/merchant-1/webhooks/deliveries/"+deliveries[0].ID+"/replay", nil))
+	missing := httptest.NewRecorder()
+	router.ServeHTTP(missing, httptest.NewRequest("POST", "/merchant-2/webhooks/deliveries/"+deliveries[0].ID+"/replay", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusOK, dead.Code)
+	assert.Equal(t, "txn123", deliveries[0].Event.Data.TransactionID)
+	assert.Equal(t, http.StatusBadRequest, invalid.Code)
+	assert.Equal(t, http.StatusOK, replayed.Code)
+	var delivery webhook.Delivery
+	require.NoError(t, json.Unmarshal(replayed.Body.Bytes(), &delivery))
+	assert.Equal(t, webhook.DeliveryPending, delivery.Status)
+	assert.Equal(t, http.StatusNotFound, missing.Code)
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"payment-service/internal/worker"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// PoolStatusReader reports what the workers of a pool are doing
+type PoolStatusReader interface {
+	Status(ctx context.Context) *worker.PoolStatus
+}
+
+// WorkerStatusHandler serves the status of the payment worker pool. It is served by the worker
+// rather than the payment service, so its routes are not part of the service's API documentation.
+type WorkerStatusHandler struct {
+	pool PoolStatusReader
+}
+
+// NewWorkerStatusHandler creates a new worker status handler
+func NewWorkerStatusHandler(pool PoolStatusReader) *WorkerStatusHandler {
+	return &WorkerStatusHandler{pool: pool}
+}
+
+// GetStatus handles GET /status requests: the task in progress, start time, throughput and errors
+// of every worker and the depth of the job queue, as JSON or, with format=text, as a plain-text table
+func (h *WorkerStatusHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	status := h.pool.Status(r.Context())
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		writeResponse(w, status, nil)
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		status.WriteText(w)
+	default:
+		http.Error(w, "format must be json or text", http.StatusBadRequest)
+	}
+}
+
+// SetupRoutes configures the HTTP routes
+func (h *WorkerStatusHandler) SetupRoutes() chi.Router {
+	r := chi.NewRouter()
+	r.Get("/status", h.GetStatus)
+	return r
+}
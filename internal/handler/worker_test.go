@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"payment-service/internal/repository"
+	"payment-service/internal/usecase"
+	"payment-service/internal/worker"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkerStatusHandler_GetStatus(t *testing.T) {
+	// Arrange: a pool of two workers over a queue holding one job
+	repo := repository.NewInMemoryPaymentRepository()
+	require.NoError(t, repo.EnqueueJob(context.Background(), "txn123", time.Now()))
+	pool := worker.NewPool(usecase.NewPaymentUseCase(repo), repo, 2)
+	router := NewWorkerStatusHandler(pool).SetupRoutes()
+
+	// Act
+	asJSON := httptest.NewRecorder()
+	router.ServeHTTP(asJSON, httptest.NewRequest("GET", "/status", nil))
+	asText := httptest.NewRecorder()
+	router.ServeHTTP(asText, httptest.NewRequest("GET", "/status?format=text", nil))
+	unknown := httptest.NewRecorder()
+	router.ServeHTTP(unknown, httptest.NewRequest("GET", "/status?format=xml", nil))
+
+	// Assert
+	require.Equal(t, http.StatusOK, asJSON.Code)
+	assert.Equal(t, "application/json", asJSON.Header().Get("Content-Type"))
+	var status worker.PoolStatus
+	require.NoError(t, json.Unmarshal(asJSON.Body.Bytes(), &status))
+	assert.Len(t, status.Workers, 2)
+	require.NotNil(t, status.Queue)
+	assert.Equal(t, 1, status.Queue.Available)
+
+	require.Equal(t, http.StatusOK, asText.Code)
+	assert.Contains(t, asText.Header().Get("Content-Type"), "text/plain")
+	assert.Contains(t, asText.Body.String(), "Queue: 1 available, 0 delayed, 0 dead")
+
+	assert.Equal(t, http.StatusBadRequest, unknown.Code)
+}
@@ -0,0 +1,145 @@
+package idempotency
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultTTL is how long keys are kept when no positive TTL is given
+const DefaultTTL = 24 * time.Hour
+
+var (
+	ErrInProgress = errors.New("a request with this idempotency key is already in progress")
+	ErrKeyReused  = errors.New("idempotency key already used with a different request")
+)
+
+// Response is a recorded HTTP response that is replayed for repeated requests
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Store keeps idempotency keys and the responses recorded for them
+type Store interface {
+	// Begin claims key for a request with the given fingerprint. It returns the recorded
+	// response if the key has already completed, ErrInProgress if another request holding
+	// the key is still running, or ErrKeyReused if the key was used with a different fingerprint.
+	Begin(key, fingerprint string) (*Response, error)
+	// Complete records the response for a claimed key so it is replayed until the key expires
+	Complete(key string, response Response)
+	// Release drops the claim on key so the request can be retried
+	Release(key string)
+}
+
+// entry is the state kept for one idempotency key
+type entry struct {
+	fingerprint string
+	response    *Response // nil while the request is in progress
+	expiresAt   time.Time
+}
+
+// MemoryStore implements Store in memory. Keys expire after a fixed TTL and are
+// removed by a background goroutine until Close is called.
+type MemoryStore struct {
+	entries map[string]*entry
+	ttl     time.Duration
+	mutex   sync.Mutex
+	done    chan struct{}
+	once    sync.Once
+	now     func() time.Time
+}
+
+// NewMemoryStore creates a new in-memory idempotency store whose keys expire after ttl,
+// or after DefaultTTL if ttl is not positive
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	s := &MemoryStore{
+		entries: make(map[string]*entry),
+		ttl:     ttl,
+		done:    make(chan struct{}),
+		now:     time.Now,
+	}
+
+	go s.expireLoop(min(ttl, time.Minute))
+	return s
+}
+
+// Begin claims key for a request with the given fingerprint
+func (s *MemoryStore) Begin(key, fingerprint string) (*Response, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.now()
+	if e, exists := s.entries[key]; exists && now.Before(e.expiresAt) {
+		if e.fingerprint != fingerprint {
+			return nil, ErrKeyReused
+		}
+		if e.response == nil {
+			return nil, ErrInProgress
+		}
+		return e.response, nil
+	}
+
+	s.entries[key] = &entry{
+		fingerprint: fingerprint,
+		expiresAt:   now.Add(s.ttl),
+	}
+	return nil, nil
+}
+
+// Complete records the response for a claimed key
+func (s *MemoryStore) Complete(key string, response Response) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if e, exists := s.entries[key]; exists {
+		e.response = &response
+		e.expiresAt = s.now().Add(s.ttl)
+	}
+}
+
+// Release drops the claim on key
+func (s *MemoryStore) Release(key string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.entries, key)
+}
+
+// Close stops the background expiry goroutine
+func (s *MemoryStore) Close() {
+	s.once.Do(func() { close(s.done) })
+}
+
+// expireLoop removes expired keys every interval until the store is closed
+func (s *MemoryStore) expireLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.removeExpired()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// removeExpired deletes every key whose TTL has elapsed
+func (s *MemoryStore) removeExpired() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.now()
+	for key, e := range s.entries {
+		if !now.Before(e.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+}
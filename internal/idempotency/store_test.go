@@ -0,0 +1,112 @@
+package idempotency
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore_ReplaysCompletedResponse(t *testing.T) {
+	// Arrange
+	store := NewMemoryStore(time.Hour)
+	defer store.Close()
+
+	recorded := Response{StatusCode: http.StatusOK, Body: []byte(`{"status":"completed"}`)}
+
+	// Act
+	first, err := store.Begin("key", "fp")
+	require.NoError(t, err)
+	store.Complete("key", recorded)
+	replayed, err := store.Begin("key", "fp")
+
+	// Assert
+	assert.Nil(t, first)
+	require.NoError(t, err)
+	require.NotNil(t, replayed)
+	assert.Equal(t, recorded, *replayed)
+}
+
+func TestMemoryStore_InProgress(t *testing.T) {
+	// Arrange
+	store := NewMemoryStore(time.Hour)
+	defer store.Close()
+	_, err := store.Begin("key", "fp")
+	require.NoError(t, err)
+
+	// Act
+	_, err = store.Begin("key", "fp")
+
+	// Assert
+	assert.ErrorIs(t, err, ErrInProgress)
+}
+
+func TestMemoryStore_KeyReused(t *testing.T) {
+	// Arrange
+	store := NewMemoryStore(time.Hour)
+	defer store.Close()
+	_, err := store.Begin("key", "fp")
+	require.NoError(t, err)
+	store.Complete("key", Response{StatusCode: http.StatusOK})
+
+	// Act
+	_, err = store.Begin("key", "other")
+
+	// Assert
+	assert.ErrorIs(t, err, ErrKeyReused)
+}
+
+func TestMemoryStore_Release(t *testing.T) {
+	// Arrange
+	store := NewMemoryStore(time.Hour)
+	defer store.Close()
+	_, err := store.Begin("key", "fp")
+	require.NoError(t, err)
+
+	// Act
+	store.Release("key")
+	response, err := store.Begin("key", "fp")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Nil(t, response)
+}
+
+func TestMemoryStore_Expiry(t *testing.T) {
+	// Arrange
+	store := NewMemoryStore(time.Hour)
+	defer store.Close()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	_, err := store.Begin("key", "fp")
+	require.NoError(t, err)
+	store.Complete("key", Response{StatusCode: http.StatusOK})
+
+	// Act
+	now = now.Add(2 * time.Hour)
+	store.removeExpired()
+
+	// Assert
+	assert.Empty(t, store.entries)
+	response, err := store.Begin("key", "other")
+	assert.NoError(t, err)
+	assert.Nil(t, response)
+}
+
+func TestMemoryStore_NonPositiveTTL(t *testing.T) {
+	// Arrange
+	store := NewMemoryStore(0)
+	defer store.Close()
+
+	// Act
+	_, err := store.Begin("key", "fp")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, DefaultTTL, store.ttl)
+	_, err = store.Begin("key", "fp")
+	assert.ErrorIs(t, err, ErrInProgress, "the key did not expire at once")
+}
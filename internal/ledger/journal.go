@@ -0,0 +1,57 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+	"payment-service/internal/entity"
+)
+
+// EntryStore reads the journal entries recorded with the payments and wallets they account for
+type EntryStore interface {
+	// JournalEntries returns every recorded entry, oldest first
+	JournalEntries(ctx context.Context) ([]JournalEntry, error)
+}
+
+// Journal is the ledger of the entries kept in a store. Every read replays the stored entries,
+// so the balances survive restarts and every instance sharing the store reports the same ones.
+type Journal struct {
+	store EntryStore
+}
+
+// NewJournal creates a ledger that reads its entries from store
+func NewJournal(store EntryStore) *Journal {
+	return &Journal{store: store}
+}
+
+// Ledger replays the stored entries into a new ledger
+func (j *Journal) Ledger(ctx context.Context) (*Ledger, error) {
+	entries, err := j.store.JournalEntries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	l := NewLedger()
+	for _, entry := range entries {
+		if err := l.Post(entry); err != nil {
+			return nil, fmt.Errorf("journal entry %s: %w", entry.ID, err)
+		}
+	}
+	return l, nil
+}
+
+// Balance returns the balance of an account in a currency
+func (j *Journal) Balance(ctx context.Context, account, currency string) (entity.Money, error) {
+	l, err := j.Ledger(ctx)
+	if err != nil {
+		return entity.Money{}, err
+	}
+	return l.Balance(account, currency)
+}
+
+// TrialBalance returns the trial balance of all stored entries
+func (j *Journal) TrialBalance(ctx context.Context) (TrialBalance, error) {
+	l, err := j.Ledger(ctx)
+	if err != nil {
+		return TrialBalance{}, err
+	}
+	return l.TrialBalance(), nil
+}
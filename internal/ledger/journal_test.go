@@ -0,0 +1,63 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// entryStore serves a fixed list of journal entries, or fails with err
+type entryStore struct {
+	entries []JournalEntry
+	err     error
+}
+
+func (s entryStore) JournalEntries(context.Context) ([]JournalEntry, error) {
+	return s.entries, s.err
+}
+
+func TestJournal_ReplaysStoredEntries(t *testing.T) {
+	// Arrange
+	journal := NewJournal(entryStore{entries: []JournalEntry{
+		captureEntry("capture:txn1", 10000, 290),
+		captureEntry("capture:txn2", 5000, 0),
+	}})
+
+	// Act
+	payable, err := journal.Balance(context.Background(), AccountMerchantPayable, "USD")
+	trial, trialErr := journal.TrialBalance(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	require.NoError(t, trialErr)
+	assert.Equal(t, usd(14710), payable)
+	assert.True(t, trial.Balanced)
+	assert.Equal(t, usd(15000), trial.Totals[0].Debit)
+}
+
+func TestJournal_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		store entryStore
+		err   error
+	}{
+		{name: "store unavailable", store: entryStore{err: errors.New("storage unavailable")}},
+		{name: "invalid entry", store: entryStore{entries: []JournalEntry{{ID: "capture:txn1"}}}, err: ErrInvalidPosting},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			_, err := NewJournal(tt.store).TrialBalance(context.Background())
+
+			// Assert
+			require.Error(t, err)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+			}
+		})
+	}
+}
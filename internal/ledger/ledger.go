@@ -0,0 +1,270 @@
+// Package ledger implements a double-entry ledger. Every journal entry moves money between
+// accounts through postings whose debits and credits balance in each currency, so the
+// ledger as a whole always balances.
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"payment-service/internal/entity"
+	"sort"
+	"sync"
+)
+
+var (
+	ErrUnbalancedEntry = errors.New("journal entry debits and credits do not balance")
+	ErrInvalidPosting  = errors.New("journal entry needs at least two postings with positive amounts")
+	ErrUnknownAccount  = errors.New("unknown ledger account")
+	ErrDuplicateEntry  = errors.New("journal entry already posted")
+)
+
+// AccountType classifies an account and decides the side that increases its balance
+type AccountType string
+
+// Account types
+const (
+	Asset     AccountType = "asset"
+	Liability AccountType = "liability"
+	Equity    AccountType = "equity"
+	Revenue   AccountType = "revenue"
+	Expense   AccountType = "expense"
+)
+
+// debitNormal reports whether debits increase the balance of accounts of this type
+func (t AccountType) debitNormal() bool {
+	return t == Asset || t == Expense
+}
+
+// Standard accounts opened in every ledger
+const (
+	// AccountCash holds funds collected from payers until they are settled
+	AccountCash = "cash"
+	// AccountMerchantPayable is what captured payments owe the merchant, net of fees and refunds
+	AccountMerchantPayable = "merchant_payable"
+	// AccountFeeRevenue collects processing fees
+	AccountFeeRevenue = "fee_revenue"
+	// AccountWalletFunds is what the service owes wallet holders: the sum of all wallet balances
+	AccountWalletFunds = "wallet_funds"
+)
+
+// Side is the side of an account a posting is made to
+type Side = entity.Side
+
+// Posting sides
+const (
+	Debit  = entity.Debit
+	Credit = entity.Credit
+)
+
+// Posting moves an amount to one side of one account
+type Posting = entity.Posting
+
+// JournalEntry is a set of postings recorded together. Its ID makes posting idempotent.
+type JournalEntry = entity.JournalEntry
+
+// AccountBalance is the balance of one account in one currency
+type AccountBalance struct {
+	Account string       `json:"account"`
+	Type    AccountType  `json:"type"`
+	Debit   entity.Money `json:"debit"`   // Total debited
+	Credit  entity.Money `json:"credit"`  // Total credited
+	Balance entity.Money `json:"balance"` // Debit minus credit for asset and expense accounts, credit minus debit otherwise
+}
+
+// TrialBalance lists every account balance with the debit and credit totals per currency
+type TrialBalance struct {
+	Accounts []AccountBalance `json:"accounts"`
+	Totals   []TrialTotal     `json:"totals"`
+	Balanced bool             `json:"balanced"` // Debits equal credits in every currency
+}
+
+// TrialTotal sums all debits and credits in one currency
+type TrialTotal struct {
+	Currency string       `json:"currency"`
+	Debit    entity.Money `json:"debit"`
+	Credit   entity.Money `json:"credit"`
+}
+
+// sideTotals accumulates the debits and credits of an account in one currency
+type sideTotals struct {
+	debit  int64
+	credit int64
+}
+
+// Ledger is an in-memory double-entry ledger. It is safe for concurrent use,
+// and each entry is posted entirely or not at all.
+type Ledger struct {
+	accounts map[string]AccountType
+	entries  []JournalEntry
+	posted   map[string]bool
+	totals   map[string]map[string]*sideTotals // account -> currency -> totals
+	mutex    sync.RWMutex
+}
+
+// NewLedger creates a ledger with the standard accounts open
+func NewLedger() *Ledger {
+	l := &Ledger{
+		accounts: make(map[string]AccountType),
+		posted:   make(map[string]bool),
+		totals:   make(map[string]map[string]*sideTotals),
+	}
+	l.OpenAccount(AccountCash, Asset)
+	l.OpenAccount(AccountMerchantPayable, Liability)
+	l.OpenAccount(AccountFeeRevenue, Revenue)
+	l.OpenAccount(AccountWalletFunds, Liability)
+	return l
+}
+
+// OpenAccount adds an account to the ledger. Opening an existing account does nothing.
+func (l *Ledger) OpenAccount(code string, accountType AccountType) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if _, exists := l.accounts[code]; !exists {
+		l.accounts[code] = accountType
+		l.totals[code] = make(map[string]*sideTotals)
+	}
+}
+
+// Post records a journal entry. The entry must have at least two postings to open accounts,
+// all with positive amounts, whose debits equal its credits in every currency.
+// Posting an ID that was posted before fails with ErrDuplicateEntry and changes nothing.
+func (l *Ledger) Post(entry JournalEntry) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.posted[entry.ID] {
+		return fmt.Errorf("%w: %s", ErrDuplicateEntry, entry.ID)
+	}
+	if err := l.validate(entry); err != nil {
+		return err
+	}
+
+	for _, posting := range entry.Postings {
+		byCurrency := l.totals[posting.Account]
+		totals := byCurrency[posting.Amount.Currency]
+		if totals == nil {
+			totals = &sideTotals{}
+			byCurrency[posting.Amount.Currency] = totals
+		}
+		if posting.Side == Debit {
+			totals.debit += posting.Amount.Amount
+		} else {
+			totals.credit += posting.Amount.Amount
+		}
+	}
+	entry.Postings = append([]Posting(nil), entry.Postings...)
+	l.entries = append(l.entries, entry)
+	l.posted[entry.ID] = true
+	return nil
+}
+
+// validate checks an entry before it is posted. The caller must hold the lock.
+func (l *Ledger) validate(entry JournalEntry) error {
+	if len(entry.Postings) < 2 {
+		return ErrInvalidPosting
+	}
+	net := make(map[string]int64)
+	for _, posting := range entry.Postings {
+		if _, exists := l.accounts[posting.Account]; !exists {
+			return fmt.Errorf("%w: %s", ErrUnknownAccount, posting.Account)
+		}
+		if !posting.Amount.IsPositive() || (posting.Side != Debit && posting.Side != Credit) {
+			return ErrInvalidPosting
+		}
+		if posting.Side == Debit {
+			net[posting.Amount.Currency] += posting.Amount.Amount
+		} else {
+			net[posting.Amount.Currency] -= posting.Amount.Amount
+		}
+	}
+	for currency, difference := range net {
+		if difference != 0 {
+			return fmt.Errorf("%w in %s", ErrUnbalancedEntry, currency)
+		}
+	}
+	return nil
+}
+
+// Balance returns the balance of an account in a currency
+func (l *Ledger) Balance(account, currency string) (entity.Money, error) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	accountType, exists := l.accounts[account]
+	if !exists {
+		return entity.Money{}, fmt.Errorf("%w: %s", ErrUnknownAccount, account)
+	}
+	return l.balance(account, accountType, currency).Balance, nil
+}
+
+// Entries returns every posted journal entry, oldest first
+func (l *Ledger) Entries() []JournalEntry {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	return append([]JournalEntry(nil), l.entries...)
+}
+
+// TrialBalance returns the balances of all accounts with postings, ordered by account and currency,
+// and whether debits equal credits in every currency
+func (l *Ledger) TrialBalance() TrialBalance {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	trial := TrialBalance{Accounts: []AccountBalance{}, Totals: []TrialTotal{}, Balanced: true}
+	totals := make(map[string]*TrialTotal)
+	for account, byCurrency := range l.totals {
+		for currency := range byCurrency {
+			balance := l.balance(account, l.accounts[account], currency)
+			trial.Accounts = append(trial.Accounts, balance)
+
+			if totals[currency] == nil {
+				totals[currency] = &TrialTotal{
+					Currency: currency,
+					Debit:    entity.Money{Currency: currency},
+					Credit:   entity.Money{Currency: currency},
+				}
+			}
+			totals[currency].Debit.Amount += balance.Debit.Amount
+			totals[currency].Credit.Amount += balance.Credit.Amount
+		}
+	}
+	for _, total := range totals {
+		trial.Totals = append(trial.Totals, *total)
+		if total.Debit != total.Credit {
+			trial.Balanced = false
+		}
+	}
+
+	sort.Slice(trial.Accounts, func(i, j int) bool {
+		a, b := trial.Accounts[i], trial.Accounts[j]
+		if a.Account != b.Account {
+			return a.Account < b.Account
+		}
+		return a.Balance.Currency < b.Balance.Currency
+	})
+	sort.Slice(trial.Totals, func(i, j int) bool {
+		return trial.Totals[i].Currency < trial.Totals[j].Currency
+	})
+	return trial
+}
+
+// balance computes an account balance in a currency. The caller must hold the lock.
+func (l *Ledger) balance(account string, accountType AccountType, currency string) AccountBalance {
+	totals := l.totals[account][currency]
+	if totals == nil {
+		totals = &sideTotals{}
+	}
+	net := totals.credit - totals.debit
+	if accountType.debitNormal() {
+		net = -net
+	}
+	return AccountBalance{
+		Account: account,
+		Type:    accountType,
+		Debit:   entity.Money{Amount: totals.debit, Currency: currency},
+		Credit:  entity.Money{Amount: totals.credit, Currency: currency},
+		Balance: entity.Money{Amount: net, Currency: currency},
+	}
+}
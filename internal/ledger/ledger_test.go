@@ -0,0 +1,188 @@
+package ledger
+
+import (
+	"payment-service/internal/entity"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func usd(amount int64) entity.Money {
+	return entity.Money{Amount: amount, Currency: "USD"}
+}
+
+func captureEntry(id string, amount, fee int64) JournalEntry {
+	entry := JournalEntry{
+		ID:       id,
+		PostedAt: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		Postings: []Posting{
+			{Account: AccountCash, Side: Debit, Amount: usd(amount)},
+			{Account: AccountMerchantPayable, Side: Credit, Amount: usd(amount - fee)},
+		},
+	}
+	if fee > 0 {
+		entry.Postings = append(entry.Postings, Posting{Account: AccountFeeRevenue, Side: Credit, Amount: usd(fee)})
+	}
+	return entry
+}
+
+func TestLedger_Post_Balanced(t *testing.T) {
+	// Arrange
+	l := NewLedger()
+
+	// Act
+	err := l.Post(captureEntry("capture:txn1", 10000, 290))
+
+	// Assert
+	require.NoError(t, err)
+	cash, err := l.Balance(AccountCash, "USD")
+	require.NoError(t, err)
+	assert.Equal(t, usd(10000), cash)
+	payable, err := l.Balance(AccountMerchantPayable, "USD")
+	require.NoError(t, err)
+	assert.Equal(t, usd(9710), payable)
+	fees, err := l.Balance(AccountFeeRevenue, "USD")
+	require.NoError(t, err)
+	assert.Equal(t, usd(290), fees)
+	assert.Len(t, l.Entries(), 1)
+}
+
+func TestLedger_Post_Rejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		postings []Posting
+		expected error
+	}{
+		{
+			name: "unbalanced",
+			postings: []Posting{
+				{Account: AccountCash, Side: Debit, Amount: usd(100)},
+				{Account: AccountMerchantPayable, Side: Credit, Amount: usd(99)},
+			},
+			expected: ErrUnbalancedEntry,
+		},
+		{
+			name: "balanced across currencies only",
+			postings: []Posting{
+				{Account: AccountCash, Side: Debit, Amount: usd(100)},
+				{Account: AccountMerchantPayable, Side: Credit, Amount: entity.Money{Amount: 100, Currency: "EUR"}},
+			},
+			expected: ErrUnbalancedEntry,
+		},
+		{
+			name: "unknown account",
+			postings: []Posting{
+				{Account: AccountCash, Side: Debit, Amount: usd(100)},
+				{Account: "suspense", Side: Credit, Amount: usd(100)},
+			},
+			expected: ErrUnknownAccount,
+		},
+		{
+			name: "single posting",
+			postings: []Posting{
+				{Account: AccountCash, Side: Debit, Amount: usd(100)},
+			},
+			expected: ErrInvalidPosting,
+		},
+		{
+			name: "zero amount",
+			postings: []Posting{
+				{Account: AccountCash, Side: Debit, Amount: usd(0)},
+				{Account: AccountMerchantPayable, Side: Credit, Amount: usd(0)},
+			},
+			expected: ErrInvalidPosting,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			l := NewLedger()
+
+			// Act
+			err := l.Post(JournalEntry{ID: "entry", Postings: tt.postings})
+
+			// Assert
+			assert.ErrorIs(t, err, tt.expected)
+			assert.Empty(t, l.Entries())
+			assert.Empty(t, l.TrialBalance().Accounts, "a rejected entry must not change any balance")
+		})
+	}
+}
+
+func TestLedger_Post_DuplicateEntry(t *testing.T) {
+	// Arrange
+	l := NewLedger()
+	require.NoError(t, l.Post(captureEntry("capture:txn1", 10000, 0)))
+
+	// Act
+	err := l.Post(captureEntry("capture:txn1", 10000, 0))
+
+	// Assert
+	assert.ErrorIs(t, err, ErrDuplicateEntry)
+	cash, _ := l.Balance(AccountCash, "USD")
+	assert.Equal(t, usd(10000), cash)
+}
+
+func TestLedger_Balance_UnknownAccount(t *testing.T) {
+	// Arrange
+	l := NewLedger()
+
+	// Act
+	_, err := l.Balance("suspense", "USD")
+
+	// Assert
+	assert.ErrorIs(t, err, ErrUnknownAccount)
+}
+
+func TestLedger_TrialBalance(t *testing.T) {
+	// Arrange
+	l := NewLedger()
+	require.NoError(t, l.Post(captureEntry("capture:txn1", 10000, 300)))
+	require.NoError(t, l.Post(JournalEntry{
+		ID: "refund:txn1-refund-1",
+		Postings: []Posting{
+			{Account: AccountMerchantPayable, Side: Debit, Amount: usd(2500)},
+			{Account: AccountCash, Side: Credit, Amount: usd(2500)},
+		},
+	}))
+
+	// Act
+	trial := l.TrialBalance()
+
+	// Assert
+	assert.True(t, trial.Balanced)
+	assert.Equal(t, []TrialTotal{{Currency: "USD", Debit: usd(12500), Credit: usd(12500)}}, trial.Totals)
+	require.Len(t, trial.Accounts, 3)
+	assert.Equal(t, AccountBalance{Account: AccountCash, Type: Asset, Debit: usd(10000), Credit: usd(2500), Balance: usd(7500)}, trial.Accounts[0])
+	assert.Equal(t, AccountBalance{Account: AccountFeeRevenue, Type: Revenue, Debit: usd(0), Credit: usd(300), Balance: usd(300)}, trial.Accounts[1])
+	assert.Equal(t, AccountBalance{Account: AccountMerchantPayable, Type: Liability, Debit: usd(2500), Credit: usd(9700), Balance: usd(7200)}, trial.Accounts[2])
+}
+
+func TestLedger_Post_Concurrent(t *testing.T) {
+	// Arrange
+	const entries = 100
+	l := NewLedger()
+	var wg sync.WaitGroup
+
+	// Act
+	for i := 0; i < entries; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Every entry is posted twice; only the first post of each ID counts
+			l.Post(captureEntry(time.Duration(i).String(), 100, 1))
+			l.Post(captureEntry(time.Duration(i).String(), 100, 1))
+		}(i)
+	}
+	wg.Wait()
+
+	// Assert
+	assert.Len(t, l.Entries(), entries)
+	assert.True(t, l.TrialBalance().Balanced)
+	cash, _ := l.Balance(AccountCash, "USD")
+	assert.Equal(t, usd(entries*100), cash)
+}
@@ -0,0 +1,1084 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"payment-service/internal/entity"
+	"payment-service/internal/usecase"
+	"payment-service/internal/webhook"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// paymentsBucket holds payments keyed by transaction ID
+	paymentsBucket = []byte("payments")
+	// createdIndexBucket indexes payments in listing order: newest first by creation time
+	createdIndexBucket = []byte("payments_by_created")
+	// userIndexBucket indexes payments by user, in listing order within each user
+	userIndexBucket = []byte("payments_by_user")
+	// outboxBucket indexes the payments with status changes not yet published, oldest first by creation time
+	outboxBucket = []byte("payments_unpublished")
+	// walletsBucket holds wallets keyed by user ID
+	walletsBucket = []byte("wallets")
+	// transfersBucket holds transfers keyed by transaction ID
+	transfersBucket = []byte("transfers")
+	// jobsBucket holds the jobs of queued asynchronous payments keyed by transaction ID
+	jobsBucket = []byte("jobs")
+	// journalBucket holds the journal entries recorded with payments and wallets keyed by entry ID
+	journalBucket = []byte("journal")
+	// webhookEndpointsBucket holds merchants' webhook endpoints keyed by endpoint ID
+	webhookEndpointsBucket = []byte("webhook_endpoints")
+	// webhookDeliveriesBucket holds webhook deliveries keyed by delivery ID
+	webhookDeliveriesBucket = []byte("webhook_deliveries")
+	// metaBucket holds database metadata such as the schema version
+	metaBucket = []byte("meta")
+	// schemaVersionKey is the metaBucket key of the applied schema version
+	schemaVersionKey = []byte("schema_version")
+)
+
+// boltMigrations upgrade stored data between schema versions; migration i moves version i to i+1
+var boltMigrations = []func(tx *bolt.Tx) error{
+	migrateBoltAmountsToMoney,
+	migrateBoltCompletedToCaptured,
+	migrateBoltCapturedAmounts,
+	migrateBoltListingIndexes,
+	migrateBoltPaymentMethods,
+}
+
+// BoltPaymentRepository implements PaymentRepository, WalletRepository, TransferRepository, JobQueue and webhook.Store
+// using an embedded bbolt database file.
+// Every write is a fsync'd transaction, so committed payments survive crashes and restarts.
+type BoltPaymentRepository struct {
+	db *bolt.DB
+}
+
+// OpenBolt opens (or creates) the bbolt database file at path.
+// The file is locked exclusively, so only one process can use it at a time.
+func OpenBolt(path string) (*bolt.DB, error) {
+	return bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+}
+
+// NewBoltPaymentRepository creates a new bbolt payment repository, creating its buckets
+// and upgrading data written by older versions if needed
+func NewBoltPaymentRepository(db *bolt.DB) (*BoltPaymentRepository, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		payments := tx.Bucket(paymentsBucket)
+		fresh := payments == nil
+		if fresh {
+			if _, err := tx.CreateBucket(paymentsBucket); err != nil {
+				return err
+			}
+		}
+		meta, err := tx.CreateBucketIfNotExists(metaBucket)
+		if err != nil {
+			return err
+		}
+		for _, name := range [][]byte{createdIndexBucket, userIndexBucket, outboxBucket, walletsBucket, transfersBucket, jobsBucket, journalBucket, webhookEndpointsBucket, webhookDeliveriesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+
+		// A new file starts at the latest schema; an existing one is migrated step by step
+		version := len(boltMigrations)
+		if !fresh {
+			version = 0
+			if v := meta.Get(schemaVersionKey); v != nil {
+				version = int(binary.BigEndian.Uint64(v))
+			}
+		}
+		for ; version < len(boltMigrations); version++ {
+			if err := boltMigrations[version](tx); err != nil {
+				return fmt.Errorf("bolt migration %d: %w", version+1, err)
+			}
+		}
+
+		return meta.Put(schemaVersionKey, binary.BigEndian.AppendUint64(nil, uint64(version)))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &BoltPaymentRepository{
+		db: db,
+	}, nil
+}
+
+// Store saves a payment to the database file. Storing a transaction ID that already
+// exists fails with usecase.ErrDuplicateTransaction and leaves the original untouched.
+func (r *BoltPaymentRepository) Store(ctx context.Context, payment *entity.Payment) error {
+	err := r.update(ctx, func(tx *bolt.Tx) error {
+		if tx.Bucket(paymentsBucket).Get([]byte(payment.TransactionID)) != nil {
+			return usecase.ErrDuplicateTransaction
+		}
+		if err := putBoltJournal(tx, payment.Journal); err != nil {
+			return err
+		}
+		return putBoltPayment(tx, payment, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	payment.Journal = nil
+	return nil
+}
+
+// CreateIfAbsent stores the payment unless its transaction ID already exists,
+// in which case the existing payment is returned. The check and the write share one transaction.
+func (r *BoltPaymentRepository) CreateIfAbsent(ctx context.Context, payment *entity.Payment) (*entity.Payment, bool, error) {
+	var existing *entity.Payment
+	err := r.update(ctx, func(tx *bolt.Tx) error {
+		if current := tx.Bucket(paymentsBucket).Get([]byte(payment.TransactionID)); current != nil {
+			existing = &entity.Payment{}
+			return unmarshalBoltPayment(current, existing)
+		}
+		if err := putBoltJournal(tx, payment.Journal); err != nil {
+			return err
+		}
+		return putBoltPayment(tx, payment, nil)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if existing != nil {
+		return existing, false, nil
+	}
+
+	payment.Journal = nil
+	return payment, true, nil
+}
+
+// Update saves changes to an existing payment if its version matches the stored one
+func (r *BoltPaymentRepository) Update(ctx context.Context, payment *entity.Payment) error {
+	return r.update(ctx, func(tx *bolt.Tx) error {
+		b := tx.Bucket(paymentsBucket)
+		key := []byte(payment.TransactionID)
+
+		current := b.Get(key)
+		if current == nil {
+			return usecase.ErrPaymentNotFound
+		}
+		var existing entity.Payment
+		if err := unmarshalBoltPayment(current, &existing); err != nil {
+			return err
+		}
+		if existing.Version != payment.Version {
+			return usecase.ErrConcurrentUpdate
+		}
+
+		updated := payment.Clone()
+		updated.Version++
+		updated.PublishedChanges = max(updated.PublishedChanges, existing.PublishedChanges)
+		if err := putBoltJournal(tx, payment.Journal); err != nil {
+			return err
+		}
+		if err := putBoltPayment(tx, updated, &existing); err != nil {
+			return err
+		}
+
+		payment.Version = updated.Version
+		payment.Journal = nil
+		return nil
+	})
+}
+
+// GetByTransactionID retrieves a payment by transaction ID
+func (r *BoltPaymentRepository) GetByTransactionID(ctx context.Context, transactionID string) (*entity.Payment, error) {
+	var payment *entity.Payment
+	err := r.view(ctx, func(tx *bolt.Tx) error {
+		data := tx.Bucket(paymentsBucket).Get([]byte(transactionID))
+		if data == nil {
+			return nil
+		}
+		payment = &entity.Payment{}
+		return unmarshalBoltPayment(data, payment)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+// Exists checks if a payment with the given transaction ID exists
+func (r *BoltPaymentRepository) Exists(ctx context.Context, transactionID string) bool {
+	var exists bool
+	r.view(ctx, func(tx *bolt.Tx) error {
+		exists = tx.Bucket(paymentsBucket).Get([]byte(transactionID)) != nil
+		return nil
+	})
+	return exists
+}
+
+// List returns at most query.Limit payments matching query, newest first.
+// Queries for one user walk that user's index; all others walk the creation time index.
+func (r *BoltPaymentRepository) List(ctx context.Context, query usecase.PaymentQuery) ([]*entity.Payment, error) {
+	var payments []*entity.Payment
+	err := r.view(ctx, func(tx *bolt.Tx) error {
+		index := tx.Bucket(createdIndexBucket)
+		var prefix []byte
+		if query.UserID != "" {
+			index = tx.Bucket(userIndexBucket)
+			prefix = userIndexPrefix(query.UserID)
+		}
+		var start []byte
+		if query.After != nil {
+			start = append(append([]byte(nil), prefix...), listingKey(query.After.CreatedAt, query.After.TransactionID)...)
+		}
+
+		payments = nil
+		data := tx.Bucket(paymentsBucket)
+		c := index.Cursor()
+		for k, transactionID := seekBefore(c, prefix, start); k != nil && bytes.HasPrefix(k, prefix); k, transactionID = c.Prev() {
+			if len(payments) == query.Limit {
+				break
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			payment := &entity.Payment{}
+			if err := unmarshalBoltPayment(data.Get(transactionID), payment); err != nil {
+				return err
+			}
+			if query.Matches(payment) {
+				payments = append(payments, payment)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return payments, nil
+}
+
+// GetWallet retrieves a user's wallet
+func (r *BoltPaymentRepository) GetWallet(ctx context.Context, userID string) (*entity.Wallet, error) {
+	var wallet *entity.Wallet
+	err := r.view(ctx, func(tx *bolt.Tx) error {
+		data := tx.Bucket(walletsBucket).Get([]byte(userID))
+		if data == nil {
+			return nil
+		}
+		wallet = &entity.Wallet{}
+		return json.Unmarshal(data, wallet)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return wallet, nil
+}
+
+// SaveWallets stores wallets whose versions all match the stored ones, or none of them.
+// The checks and the writes share one transaction.
+func (r *BoltPaymentRepository) SaveWallets(ctx context.Context, wallets ...*entity.Wallet) error {
+	err := r.update(ctx, func(tx *bolt.Tx) error {
+		b := tx.Bucket(walletsBucket)
+		for _, wallet := range wallets {
+			var existing entity.Wallet
+			if current := b.Get([]byte(wallet.UserID)); current != nil {
+				if err := json.Unmarshal(current, &existing); err != nil {
+					return err
+				}
+			}
+			if existing.Version != wallet.Version {
+				return usecase.ErrConcurrentUpdate
+			}
+
+			updated := wallet.Clone()
+			updated.Version++
+			data, err := json.Marshal(updated)
+			if err != nil {
+				return err
+			}
+			if err := b.Put([]byte(wallet.UserID), data); err != nil {
+				return err
+			}
+			if err := putBoltJournal(tx, wallet.Journal); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, wallet := range wallets {
+		wallet.Version++
+		wallet.Journal = nil
+	}
+	return nil
+}
+
+// CreateTransferIfAbsent stores the transfer unless its transaction ID already exists,
+// in which case the existing transfer is returned. The check and the write share one transaction.
+func (r *BoltPaymentRepository) CreateTransferIfAbsent(ctx context.Context, transfer *entity.Transfer) (*entity.Transfer, bool, error) {
+	var existing *entity.Transfer
+	err := r.update(ctx, func(tx *bolt.Tx) error {
+		b := tx.Bucket(transfersBucket)
+		if current := b.Get([]byte(transfer.TransactionID)); current != nil {
+			existing = &entity.Transfer{}
+			return json.Unmarshal(current, existing)
+		}
+		data, err := json.Marshal(transfer)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(transfer.TransactionID), data)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if existing != nil {
+		return existing, false, nil
+	}
+
+	return transfer, true, nil
+}
+
+// UpdateTransfer saves changes to an existing transfer if its version matches the stored one
+func (r *BoltPaymentRepository) UpdateTransfer(ctx context.Context, transfer *entity.Transfer) error {
+	return r.update(ctx, func(tx *bolt.Tx) error {
+		b := tx.Bucket(transfersBucket)
+		current := b.Get([]byte(transfer.TransactionID))
+		if current == nil {
+			return usecase.ErrTransferNotFound
+		}
+		var existing entity.Transfer
+		if err := json.Unmarshal(current, &existing); err != nil {
+			return err
+		}
+		if existing.Version != transfer.Version {
+			return usecase.ErrConcurrentUpdate
+		}
+
+		updated := transfer.Clone()
+		updated.Version++
+		data, err := json.Marshal(updated)
+		if err != nil {
+			return err
+		}
+		if err := b.Put([]byte(transfer.TransactionID), data); err != nil {
+			return err
+		}
+
+		transfer.Version = updated.Version
+		return nil
+	})
+}
+
+// GetTransfer retrieves a transfer by transaction ID
+func (r *BoltPaymentRepository) GetTransfer(ctx context.Context, transactionID string) (*entity.Transfer, error) {
+	var transfer *entity.Transfer
+	err := r.view(ctx, func(tx *bolt.Tx) error {
+		data := tx.Bucket(transfersBucket).Get([]byte(transactionID))
+		if data == nil {
+			return nil
+		}
+		transfer = &entity.Transfer{}
+		return json.Unmarshal(data, transfer)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return transfer, nil
+}
+
+// EnqueueJob adds a job for the transaction unless there already is one
+func (r *BoltPaymentRepository) EnqueueJob(ctx context.Context, transactionID string, now time.Time) error {
+	return r.update(ctx, func(tx *bolt.Tx) error {
+		if tx.Bucket(jobsBucket).Get([]byte(transactionID)) != nil {
+			return nil
+		}
+		return putBoltJob(tx, newJob(transactionID, now))
+	})
+}
+
+// LeaseJob delivers the job that has been available longest, hiding it until leaseUntil.
+// The bucket only holds unfinished and dead-lettered jobs, so it is scanned in full.
+func (r *BoltPaymentRepository) LeaseJob(ctx context.Context, now, leaseUntil time.Time) (*entity.Job, error) {
+	var next *entity.Job
+	err := r.update(ctx, func(tx *bolt.Tx) error {
+		err := tx.Bucket(jobsBucket).ForEach(func(_, data []byte) error {
+			job := &entity.Job{}
+			if err := json.Unmarshal(data, job); err != nil {
+				return err
+			}
+			if job.Available(now) && (next == nil || leasedBefore(job, next)) {
+				next = job
+			}
+			return nil
+		})
+		if err != nil || next == nil {
+			return err
+		}
+		next.Attempts++
+		next.AvailableAt = leaseUntil
+		next.UpdatedAt = now
+		return putBoltJob(tx, next)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return next, nil
+}
+
+// CompleteJob removes the job leased by attempt
+func (r *BoltPaymentRepository) CompleteJob(ctx context.Context, transactionID string, attempt int) error {
+	return r.changeLeasedJob(ctx, transactionID, attempt, func(tx *bolt.Tx, job *entity.Job) error {
+		return tx.Bucket(jobsBucket).Delete([]byte(transactionID))
+	})
+}
+
+// RetryJob makes the job leased by attempt available again at retryAt
+func (r *BoltPaymentRepository) RetryJob(ctx context.Context, transactionID string, attempt int, lastError string, retryAt, now time.Time) error {
+	return r.changeLeasedJob(ctx, transactionID, attempt, func(tx *bolt.Tx, job *entity.Job) error {
+		job.AvailableAt = retryAt
+		job.LastError = lastError
+		job.UpdatedAt = now
+		return putBoltJob(tx, job)
+	})
+}
+
+// ReleaseJob hands the job leased by attempt back at now, without counting the attempt
+func (r *BoltPaymentRepository) ReleaseJob(ctx context.Context, transactionID string, attempt int, lastError string, now time.Time) error {
+	return r.changeLeasedJob(ctx, transactionID, attempt, func(tx *bolt.Tx, job *entity.Job) error {
+		job.Attempts--
+		job.AvailableAt = now
+		job.LastError = lastError
+		job.UpdatedAt = now
+		return putBoltJob(tx, job)
+	})
+}
+
+// BuryJob moves the job leased by attempt to the dead-letter queue
+func (r *BoltPaymentRepository) BuryJob(ctx context.Context, transactionID string, attempt int, lastError string, now time.Time) error {
+	return r.changeLeasedJob(ctx, transactionID, attempt, func(tx *bolt.Tx, job *entity.Job) error {
+		job.Status = entity.JobDead
+		job.LastError = lastError
+		job.UpdatedAt = now
+		return putBoltJob(tx, job)
+	})
+}
+
+// DeadJobs lists the dead-lettered jobs, oldest first
+func (r *BoltPaymentRepository) DeadJobs(ctx context.Context) ([]*entity.Job, error) {
+	dead := []*entity.Job{}
+	err := r.view(ctx, func(tx *bolt.Tx) error {
+		return tx.Bucket(jobsBucket).ForEach(func(_, data []byte) error {
+			job := &entity.Job{}
+			if err := json.Unmarshal(data, job); err != nil {
+				return err
+			}
+			if job.Status == entity.JobDead {
+				dead = append(dead, job)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(dead, func(i, j int) bool { return buriedBefore(dead[i], dead[j]) })
+	return dead, nil
+}
+
+// CountJobs counts the jobs available, delayed and dead at now
+func (r *BoltPaymentRepository) CountJobs(ctx context.Context, now time.Time) (*entity.JobCounts, error) {
+	counts := &entity.JobCounts{}
+	err := r.view(ctx, func(tx *bolt.Tx) error {
+		return tx.Bucket(jobsBucket).ForEach(func(_, data []byte) error {
+			job := &entity.Job{}
+			if err := json.Unmarshal(data, job); err != nil {
+				return err
+			}
+			count(counts, job, now)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// RequeueJob makes a dead-lettered job available at now with no attempts
+func (r *BoltPaymentRepository) RequeueJob(ctx context.Context, transactionID string, now time.Time) (*entity.Job, error) {
+	var job *entity.Job
+	err := r.update(ctx, func(tx *bolt.Tx) error {
+		var err error
+		job, err = getBoltJob(tx, transactionID)
+		if err != nil {
+			return err
+		}
+		if job == nil || job.Status != entity.JobDead {
+			return usecase.ErrJobNotFound
+		}
+		requeue(job, now)
+		return putBoltJob(tx, job)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// changeLeasedJob applies change to the job in one transaction if it is still leased by attempt
+func (r *BoltPaymentRepository) changeLeasedJob(ctx context.Context, transactionID string, attempt int, change func(tx *bolt.Tx, job *entity.Job) error) error {
+	return r.update(ctx, func(tx *bolt.Tx) error {
+		job, err := getBoltJob(tx, transactionID)
+		if err != nil {
+			return err
+		}
+		if job == nil || job.Status != entity.JobQueued || job.Attempts != attempt {
+			return usecase.ErrJobLeaseLost
+		}
+		return change(tx, job)
+	})
+}
+
+// getBoltJob reads a job, or returns nil if there is none for the transaction
+func getBoltJob(tx *bolt.Tx, transactionID string) (*entity.Job, error) {
+	data := tx.Bucket(jobsBucket).Get([]byte(transactionID))
+	if data == nil {
+		return nil, nil
+	}
+	job := &entity.Job{}
+	if err := json.Unmarshal(data, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// putBoltJob writes a job
+func putBoltJob(tx *bolt.Tx, job *entity.Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(jobsBucket).Put([]byte(job.TransactionID), data)
+}
+
+// JournalEntries returns every journal entry recorded with a payment or wallet, oldest first
+func (r *BoltPaymentRepository) JournalEntries(ctx context.Context) ([]entity.JournalEntry, error) {
+	entries := []entity.JournalEntry{}
+	err := r.view(ctx, func(tx *bolt.Tx) error {
+		return tx.Bucket(journalBucket).ForEach(func(_, data []byte) error {
+			var entry entity.JournalEntry
+			if err := json.Unmarshal(data, &entry); err != nil {
+				return err
+			}
+			entries = append(entries, entry)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return postedBefore(entries[i], entries[j]) })
+	return entries, nil
+}
+
+// UnpublishedPayments returns at most limit payments with changes not yet published, oldest first
+func (r *BoltPaymentRepository) UnpublishedPayments(ctx context.Context, limit int) ([]*entity.Payment, error) {
+	payments := []*entity.Payment{}
+	err := r.view(ctx, func(tx *bolt.Tx) error {
+		b := tx.Bucket(paymentsBucket)
+		c := tx.Bucket(outboxBucket).Cursor()
+		for key, transactionID := c.First(); key != nil && len(payments) < limit; key, transactionID = c.Next() {
+			payment := &entity.Payment{}
+			if err := unmarshalBoltPayment(b.Get(transactionID), payment); err != nil {
+				return err
+			}
+			payments = append(payments, payment)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return payments, nil
+}
+
+// MarkChangesPublished records that the first published changes of a payment have been published
+func (r *BoltPaymentRepository) MarkChangesPublished(ctx context.Context, transactionID string, published int) error {
+	return r.update(ctx, func(tx *bolt.Tx) error {
+		current := tx.Bucket(paymentsBucket).Get([]byte(transactionID))
+		if current == nil {
+			return usecase.ErrPaymentNotFound
+		}
+		var existing entity.Payment
+		if err := unmarshalBoltPayment(current, &existing); err != nil {
+			return err
+		}
+		if published <= existing.PublishedChanges {
+			return nil
+		}
+
+		updated := existing.Clone()
+		updated.PublishedChanges = published
+		return putBoltPayment(tx, updated, &existing)
+	})
+}
+
+// SaveWebhookEndpoint stores a new webhook endpoint
+func (r *BoltPaymentRepository) SaveWebhookEndpoint(ctx context.Context, endpoint *webhook.Endpoint) error {
+	return r.update(ctx, func(tx *bolt.Tx) error {
+		data, err := json.Marshal(endpoint)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(webhookEndpointsBucket).Put([]byte(endpoint.ID), data)
+	})
+}
+
+// WebhookEndpoints lists a merchant's webhook endpoints, oldest first
+func (r *BoltPaymentRepository) WebhookEndpoints(ctx context.Context, merchantID string) ([]*webhook.Endpoint, error) {
+	endpoints := []*webhook.Endpoint{}
+	err := r.view(ctx, func(tx *bolt.Tx) error {
+		return tx.Bucket(webhookEndpointsBucket).ForEach(func(_, data []byte) error {
+			endpoint := &webhook.Endpoint{}
+			if err := json.Unmarshal(data, endpoint); err != nil {
+				return err
+			}
+			if endpoint.MerchantID == merchantID {
+				endpoints = append(endpoints, endpoint)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(endpoints, func(i, j int) bool { return registeredBefore(endpoints[i], endpoints[j]) })
+	return endpoints, nil
+}
+
+// AddWebhookDeliveries stores the deliveries whose IDs are not stored yet
+func (r *BoltPaymentRepository) AddWebhookDeliveries(ctx context.Context, deliveries []*webhook.Delivery) error {
+	return r.update(ctx, func(tx *bolt.Tx) error {
+		for _, delivery := range deliveries {
+			if tx.Bucket(webhookDeliveriesBucket).Get([]byte(delivery.ID)) != nil {
+				continue
+			}
+			if err := putBoltDelivery(tx, delivery); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// WebhookDeliveries lists a merchant's webhook deliveries, newest first, optionally only those with a status
+func (r *BoltPaymentRepository) WebhookDeliveries(ctx context.Context, merchantID, status string) ([]*webhook.Delivery, error) {
+	deliveries := []*webhook.Delivery{}
+	err := r.view(ctx, func(tx *bolt.Tx) error {
+		return tx.Bucket(webhookDeliveriesBucket).ForEach(func(_, data []byte) error {
+			delivery, err := unmarshalBoltDelivery(data)
+			if err != nil {
+				return err
+			}
+			if delivery.MerchantID == merchantID && (status == "" || delivery.Status == status) {
+				deliveries = append(deliveries, delivery)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(deliveries, func(i, j int) bool { return publishedAfter(deliveries[i], deliveries[j]) })
+	return deliveries, nil
+}
+
+// LeaseWebhookDeliveries leases up to limit pending webhook deliveries due at now until leaseUntil,
+// those due longest first. Delivered and dead deliveries stay in the bucket for listing, so it is
+// scanned in full.
+func (r *BoltPaymentRepository) LeaseWebhookDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*webhook.Delivery, error) {
+	var leased []*webhook.Delivery
+	err := r.update(ctx, func(tx *bolt.Tx) error {
+		due := []*webhook.Delivery{}
+		err := tx.Bucket(webhookDeliveriesBucket).ForEach(func(_, data []byte) error {
+			delivery, err := unmarshalBoltDelivery(data)
+			if err != nil {
+				return err
+			}
+			if delivery.Due(now) {
+				due = append(due, delivery)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		sort.Slice(due, func(i, j int) bool { return dueBefore(due[i], due[j]) })
+
+		leased = due[:min(limit, len(due))]
+		for _, delivery := range leased {
+			delivery.Lease(leaseUntil)
+			if err := putBoltDelivery(tx, delivery); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return leased, nil
+}
+
+// SaveWebhookAttempt stores the outcome of an attempt if it still holds the delivery's lease
+func (r *BoltPaymentRepository) SaveWebhookAttempt(ctx context.Context, delivery *webhook.Delivery) error {
+	return r.update(ctx, func(tx *bolt.Tx) error {
+		stored, err := getBoltDelivery(tx, delivery.ID)
+		if err != nil {
+			return err
+		}
+		if !holdsLease(stored, delivery) {
+			return webhook.ErrDeliveryLeaseLost
+		}
+		saved := delivery.Clone()
+		saved.LeasedUntil = nil
+		return putBoltDelivery(tx, saved)
+	})
+}
+
+// ReplayWebhookDelivery makes a merchant's webhook delivery pending at now with no attempts
+func (r *BoltPaymentRepository) ReplayWebhookDelivery(ctx context.Context, merchantID, deliveryID string, now time.Time) (*webhook.Delivery, error) {
+	var delivery *webhook.Delivery
+	err := r.update(ctx, func(tx *bolt.Tx) error {
+		var err error
+		delivery, err = getBoltDelivery(tx, deliveryID)
+		if err != nil {
+			return err
+		}
+		if err := replayable(delivery, merchantID, now); err != nil {
+			return err
+		}
+		delivery.Replay(now)
+		return putBoltDelivery(tx, delivery)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// update runs fn in a write transaction unless ctx is done by the time the transaction starts.
+// Write transactions take turns, so a caller may give up while waiting for its turn.
+func (r *BoltPaymentRepository) update(ctx context.Context, fn func(tx *bolt.Tx) error) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+// view runs fn in a read transaction unless ctx is done
+func (r *BoltPaymentRepository) view(ctx context.Context, fn func(tx *bolt.Tx) error) error {
+	return r.db.View(func(tx *bolt.Tx) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+// putBoltJournal writes the journal entries whose IDs are not recorded yet
+func putBoltJournal(tx *bolt.Tx, entries []entity.JournalEntry) error {
+	b := tx.Bucket(journalBucket)
+	for _, entry := range entries {
+		if b.Get([]byte(entry.ID)) != nil {
+			continue
+		}
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		if err := b.Put([]byte(entry.ID), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// boltPayment is the stored form of a payment. It keeps the fields that payments leave out of their JSON.
+// Payments stored before events were tracked have no published count; their changes were all published.
+type boltPayment struct {
+	*entity.Payment
+	CardToken          string `json:"card_token,omitempty"`
+	RequestFingerprint string `json:"request_fingerprint,omitempty"`
+	PublishedChanges   *int   `json:"published_changes,omitempty"`
+}
+
+// unmarshalBoltPayment decodes a stored payment into payment
+func unmarshalBoltPayment(data []byte, payment *entity.Payment) error {
+	stored := boltPayment{Payment: payment}
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return err
+	}
+	payment.SetCardToken(stored.CardToken)
+	payment.RequestFingerprint = stored.RequestFingerprint
+	payment.PublishedChanges = len(payment.StatusHistory)
+	if stored.PublishedChanges != nil {
+		payment.PublishedChanges = *stored.PublishedChanges
+	}
+	return nil
+}
+
+// putBoltPayment writes a payment and its index entries, replacing the entries of previous if set
+func putBoltPayment(tx *bolt.Tx, payment, previous *entity.Payment) error {
+	data, err := json.Marshal(boltPayment{
+		Payment:            payment,
+		CardToken:          payment.CardToken,
+		RequestFingerprint: payment.RequestFingerprint,
+		PublishedChanges:   &payment.PublishedChanges,
+	})
+	if err != nil {
+		return err
+	}
+	if err := tx.Bucket(paymentsBucket).Put([]byte(payment.TransactionID), data); err != nil {
+		return err
+	}
+
+	created, users, outbox := tx.Bucket(createdIndexBucket), tx.Bucket(userIndexBucket), tx.Bucket(outboxBucket)
+	if previous != nil {
+		key := listingKey(previous.CreatedAt, previous.TransactionID)
+		if err := created.Delete(key); err != nil {
+			return err
+		}
+		if err := users.Delete(append(userIndexPrefix(previous.UserID), key...)); err != nil {
+			return err
+		}
+		if err := outbox.Delete(key); err != nil {
+			return err
+		}
+	}
+	key := listingKey(payment.CreatedAt, payment.TransactionID)
+	if err := created.Put(key, []byte(payment.TransactionID)); err != nil {
+		return err
+	}
+	if payment.UnpublishedChanges() {
+		if err := outbox.Put(key, []byte(payment.TransactionID)); err != nil {
+			return err
+		}
+	}
+	return users.Put(append(userIndexPrefix(payment.UserID), key...), []byte(payment.TransactionID))
+}
+
+// listingKey encodes a listing position so that byte order matches listing order reversed:
+// the creation time in big-endian nanoseconds followed by the transaction ID.
+// Times before 1970 sort as 1970.
+func listingKey(createdAt time.Time, transactionID string) []byte {
+	nanos := createdAt.UnixNano()
+	if createdAt.Before(time.Unix(0, 0)) {
+		nanos = 0
+	}
+	return append(binary.BigEndian.AppendUint64(nil, uint64(nanos)), transactionID...)
+}
+
+// userIndexPrefix returns the userIndexBucket key prefix of a user's payments
+func userIndexPrefix(userID string) []byte {
+	return append([]byte(userID), 0)
+}
+
+// seekBefore positions c on the last key with prefix that sorts before start, or on the
+// last key with prefix when start is nil, and returns it. The result may lack the prefix
+// when no such key exists.
+func seekBefore(c *bolt.Cursor, prefix, start []byte) ([]byte, []byte) {
+	if start == nil && len(prefix) > 0 {
+		// The first key after every key with prefix: prefixes end in a 0 separator, so bump it
+		start = append(append([]byte(nil), prefix[:len(prefix)-1]...), prefix[len(prefix)-1]+1)
+	}
+	if start == nil {
+		return c.Last()
+	}
+	if k, _ := c.Seek(start); k == nil {
+		return c.Last()
+	}
+	return c.Prev()
+}
+
+// migrateBoltAmountsToMoney converts float amounts written before currencies were
+// introduced into Money values. All such payments were in USD.
+func migrateBoltAmountsToMoney(tx *bolt.Tx) error {
+	return upgradeBoltPayments(tx, func(fields map[string]json.RawMessage) (bool, error) {
+		var legacy float64
+		if err := json.Unmarshal(fields["amount"], &legacy); err != nil {
+			return false, nil // already a Money value
+		}
+		amount, err := json.Marshal(entity.Money{Amount: int64(math.Round(legacy * 100)), Currency: "USD"})
+		if err != nil {
+			return false, err
+		}
+		fields["amount"] = amount
+		return true, nil
+	})
+}
+
+// migrateBoltCompletedToCaptured renames the former "completed" status to the lifecycle's
+// "captured" and seeds the status history of payments written before it existed
+func migrateBoltCompletedToCaptured(tx *bolt.Tx) error {
+	return upgradeBoltPayments(tx, func(fields map[string]json.RawMessage) (bool, error) {
+		var status string
+		if err := json.Unmarshal(fields["status"], &status); err != nil || status != "completed" {
+			return false, err
+		}
+		var createdAt time.Time
+		if err := json.Unmarshal(fields["created_at"], &createdAt); err != nil {
+			return false, err
+		}
+
+		history, err := json.Marshal([]entity.StatusChange{{
+			To:     entity.StatusCaptured,
+			Actor:  "system",
+			Reason: "migrated from completed status",
+			At:     createdAt,
+		}})
+		if err != nil {
+			return false, err
+		}
+		fields["status"] = json.RawMessage(`"` + entity.StatusCaptured + `"`)
+		fields["status_history"] = history
+		return true, nil
+	})
+}
+
+// migrateBoltCapturedAmounts records the full amount as captured for payments captured
+// before partial capture existed
+func migrateBoltCapturedAmounts(tx *bolt.Tx) error {
+	return upgradeBoltPayments(tx, func(fields map[string]json.RawMessage) (bool, error) {
+		var status string
+		if err := json.Unmarshal(fields["status"], &status); err != nil || status != entity.StatusCaptured {
+			return false, err
+		}
+		if _, ok := fields["captured_amount"]; ok {
+			return false, nil
+		}
+		fields["captured_amount"] = fields["amount"]
+		return true, nil
+	})
+}
+
+// migrateBoltListingIndexes builds the listing indexes for payments stored before they existed
+func migrateBoltListingIndexes(tx *bolt.Tx) error {
+	return tx.Bucket(paymentsBucket).ForEach(func(_, data []byte) error {
+		var payment entity.Payment
+		if err := json.Unmarshal(data, &payment); err != nil {
+			return err
+		}
+		key := listingKey(payment.CreatedAt, payment.TransactionID)
+		if err := tx.Bucket(createdIndexBucket).Put(key, []byte(payment.TransactionID)); err != nil {
+			return err
+		}
+		return tx.Bucket(userIndexBucket).Put(append(userIndexPrefix(payment.UserID), key...), []byte(payment.TransactionID))
+	})
+}
+
+// migrateBoltPaymentMethods marks payments stored before payment methods existed as card payments
+func migrateBoltPaymentMethods(tx *bolt.Tx) error {
+	return upgradeBoltPayments(tx, func(fields map[string]json.RawMessage) (bool, error) {
+		if _, ok := fields["payment_method"]; ok {
+			return false, nil
+		}
+		fields["payment_method"] = json.RawMessage(`"` + entity.PaymentMethodCard + `"`)
+		return true, nil
+	})
+}
+
+// upgradeBoltPayments rewrites every stored payment for which upgrade reports a change.
+// Payments are handled as raw JSON fields so migrations do not depend on the current entity shape.
+func upgradeBoltPayments(tx *bolt.Tx, upgrade func(fields map[string]json.RawMessage) (bool, error)) error {
+	b := tx.Bucket(paymentsBucket)
+
+	// Collect upgrades first: a bucket must not be modified while iterating it
+	upgrades := make(map[string][]byte)
+	err := b.ForEach(func(key, data []byte) error {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return err
+		}
+
+		changed, err := upgrade(fields)
+		if err != nil || !changed {
+			return err
+		}
+
+		upgraded, err := json.Marshal(fields)
+		if err != nil {
+			return err
+		}
+		upgrades[string(key)] = upgraded
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for key, data := range upgrades {
+		if err := b.Put([]byte(key), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// boltDelivery is the stored form of a webhook delivery. It keeps the lease, which deliveries leave out of their JSON.
+type boltDelivery struct {
+	*webhook.Delivery
+	LeasedUntil *time.Time `json:"leased_until,omitempty"`
+	Leases      int        `json:"leases"`
+}
+
+// unmarshalBoltDelivery decodes a stored webhook delivery
+func unmarshalBoltDelivery(data []byte) (*webhook.Delivery, error) {
+	delivery := &webhook.Delivery{}
+	stored := boltDelivery{Delivery: delivery}
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, err
+	}
+	delivery.LeasedUntil = stored.LeasedUntil
+	delivery.Leases = stored.Leases
+	return delivery, nil
+}
+
+// getBoltDelivery reads a webhook delivery, or returns nil if there is none with the ID
+func getBoltDelivery(tx *bolt.Tx, deliveryID string) (*webhook.Delivery, error) {
+	data := tx.Bucket(webhookDeliveriesBucket).Get([]byte(deliveryID))
+	if data == nil {
+		return nil, nil
+	}
+	return unmarshalBoltDelivery(data)
+}
+
+// putBoltDelivery writes a webhook delivery
+func putBoltDelivery(tx *bolt.Tx, delivery *webhook.Delivery) error {
+	data, err := json.Marshal(boltDelivery{Delivery: delivery, LeasedUntil: delivery.LeasedUntil, Leases: delivery.Leases})
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(webhookDeliveriesBucket).Put([]byte(delivery.ID), data)
+}
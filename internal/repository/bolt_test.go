@@ -0,0 +1,213 @@
+package repository
+
+import (
+	"context"
+	"path/filepath"
+	"payment-service/internal/entity"
+	"payment-service/internal/usecase"
+	"payment-service/internal/webhook"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+// newBoltTestRepository opens a repository backed by a fresh database file in a temp directory
+func newBoltTestRepository(t *testing.T) *BoltPaymentRepository {
+	db, err := OpenBolt(filepath.Join(t.TempDir(), "payments.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	repo, err := NewBoltPaymentRepository(db)
+	require.NoError(t, err)
+	return repo
+}
+
+func TestBoltPaymentRepository(t *testing.T) {
+	testPaymentRepository(t, func(t *testing.T) usecase.PaymentRepository {
+		return newBoltTestRepository(t)
+	})
+}
+
+func TestBoltWalletRepository(t *testing.T) {
+	testWalletRepository(t, func(t *testing.T) usecase.WalletRepository {
+		return newBoltTestRepository(t)
+	})
+}
+
+func TestBoltTransferRepository(t *testing.T) {
+	testTransferRepository(t, func(t *testing.T) usecase.TransferRepository {
+		return newBoltTestRepository(t)
+	})
+}
+
+func TestBoltJournal(t *testing.T) {
+	testJournal(t, func(t *testing.T) journalStore {
+		return newBoltTestRepository(t)
+	})
+}
+
+func TestBoltEventOutbox(t *testing.T) {
+	testEventOutbox(t, func(t *testing.T) outboxStore {
+		return newBoltTestRepository(t)
+	})
+}
+
+func TestBoltJobQueue(t *testing.T) {
+	testJobQueue(t, func(t *testing.T) usecase.JobQueue {
+		return newBoltTestRepository(t)
+	})
+}
+
+func TestBoltWebhookStore(t *testing.T) {
+	testWebhookStore(t, func(t *testing.T) webhook.Store {
+		return newBoltTestRepository(t)
+	})
+}
+
+func TestBoltJobQueue_LeaseSurvivesReopen(t *testing.T) {
+	// Arrange: a worker leases a job, then crashes before finishing it
+	path := filepath.Join(t.TempDir(), "payments.db")
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	db, err := OpenBolt(path)
+	require.NoError(t, err)
+	repo, err := NewBoltPaymentRepository(db)
+	require.NoError(t, err)
+	require.NoError(t, repo.EnqueueJob(context.Background(), "txn123", start))
+	_, err = repo.LeaseJob(context.Background(), start, start.Add(time.Minute))
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	// Act
+	db, err = OpenBolt(path)
+	require.NoError(t, err)
+	defer db.Close()
+	repo, err = NewBoltPaymentRepository(db)
+	require.NoError(t, err)
+	job, err := repo.LeaseJob(context.Background(), start.Add(time.Minute), start.Add(2*time.Minute))
+
+	// Assert
+	require.NoError(t, err)
+	require.NotNil(t, job, "the job is delivered again once the lease runs out")
+	assert.Equal(t, 2, job.Attempts)
+}
+
+func TestBoltWebhookStore_SurvivesReopen(t *testing.T) {
+	// Arrange: a dispatcher leases a delivery to a registered endpoint, then crashes before recording the attempt
+	path := filepath.Join(t.TempDir(), "payments.db")
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	db, err := OpenBolt(path)
+	require.NoError(t, err)
+	repo, err := NewBoltPaymentRepository(db)
+	require.NoError(t, err)
+	endpoint := &webhook.Endpoint{ID: "we_1", MerchantID: "merchant-1", URL: "https://merchant.example.com/hooks", Secret: "whsec_1", CreatedAt: start}
+	require.NoError(t, repo.SaveWebhookEndpoint(context.Background(), endpoint))
+	delivery := &webhook.Delivery{ID: "wd_1", EndpointID: "we_1", MerchantID: "merchant-1", Status: webhook.DeliveryPending, NextAttemptAt: &start, CreatedAt: start}
+	require.NoError(t, repo.AddWebhookDeliveries(context.Background(), []*webhook.Delivery{delivery}))
+	_, err = repo.LeaseWebhookDeliveries(context.Background(), start, start.Add(time.Minute), 10)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	// Act
+	db, err = OpenBolt(path)
+	require.NoError(t, err)
+	defer db.Close()
+	repo, err = NewBoltPaymentRepository(db)
+	require.NoError(t, err)
+	endpoints, endpointsErr := repo.WebhookEndpoints(context.Background(), "merchant-1")
+	_, inFlightErr := repo.ReplayWebhookDelivery(context.Background(), "merchant-1", "wd_1", start.Add(time.Second))
+	leased, err := repo.LeaseWebhookDeliveries(context.Background(), start.Add(time.Minute), start.Add(2*time.Minute), 10)
+
+	// Assert
+	require.NoError(t, endpointsErr)
+	require.Len(t, endpoints, 1)
+	assert.Equal(t, "whsec_1", endpoints[0].Secret)
+	assert.ErrorIs(t, inFlightErr, webhook.ErrDeliveryInFlight, "the lease is stored with the delivery")
+	require.NoError(t, err)
+	require.Len(t, leased, 1, "the delivery is attempted again once the lease runs out")
+	assert.Equal(t, 2, leased[0].Leases)
+}
+
+func TestBoltPaymentRepository_SurvivesReopen(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "payments.db")
+	db, err := OpenBolt(path)
+	require.NoError(t, err)
+	repo, err := NewBoltPaymentRepository(db)
+	require.NoError(t, err)
+	require.NoError(t, repo.Store(context.Background(), &entity.Payment{
+		TransactionID: "txn123",
+		UserID:        "user123",
+		Amount:        entity.Money{Amount: 10050, Currency: "USD"},
+		Status:        entity.StatusCaptured,
+		CreatedAt:     time.Now(),
+		Journal: []entity.JournalEntry{{
+			ID: "capture:txn123",
+			Postings: []entity.Posting{
+				{Account: "cash", Side: entity.Debit, Amount: entity.Money{Amount: 10050, Currency: "USD"}},
+				{Account: "merchant_payable", Side: entity.Credit, Amount: entity.Money{Amount: 10050, Currency: "USD"}},
+			},
+		}},
+	}))
+	require.NoError(t, db.Close())
+
+	// Act
+	db, err = OpenBolt(path)
+	require.NoError(t, err)
+	defer db.Close()
+	repo, err = NewBoltPaymentRepository(db)
+	require.NoError(t, err)
+	stored, err := repo.GetByTransactionID(context.Background(), "txn123")
+	entries, entriesErr := repo.JournalEntries(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.Equal(t, "user123", stored.UserID)
+	assert.Equal(t, entity.Money{Amount: 10050, Currency: "USD"}, stored.Amount)
+	require.NoError(t, entriesErr)
+	require.Len(t, entries, 1, "the journal survives restarts")
+	assert.Equal(t, "capture:txn123", entries[0].ID)
+}
+
+func TestBoltPaymentRepository_MigratesLegacyFloatAmounts(t *testing.T) {
+	// Arrange: a file written before amounts were stored as Money
+	path := filepath.Join(t.TempDir(), "payments.db")
+	db, err := OpenBolt(path)
+	require.NoError(t, err)
+	require.NoError(t, db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucket(paymentsBucket)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte("txn123"), []byte(`{"transaction_id":"txn123","user_id":"user123","amount":100.5,"status":"completed","created_at":"2025-01-02T03:04:05Z"}`))
+	}))
+
+	// Act
+	repo, err := NewBoltPaymentRepository(db)
+	require.NoError(t, err)
+	stored, err := repo.GetByTransactionID(context.Background(), "txn123")
+
+	// Assert
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.Equal(t, entity.Money{Amount: 10050, Currency: "USD"}, stored.Amount)
+	assert.Equal(t, entity.StatusCaptured, stored.Status)
+	assert.Equal(t, stored.Amount, stored.CapturedAmount)
+	assert.Equal(t, entity.PaymentMethodCard, stored.PaymentMethod)
+	require.Len(t, stored.StatusHistory, 1)
+	assert.Equal(t, entity.StatusCaptured, stored.StatusHistory[0].To)
+	listed, err := repo.List(context.Background(), usecase.PaymentQuery{UserID: "user123", Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"txn123"}, transactionIDs(listed))
+	require.NoError(t, db.Close())
+
+	// Reopening must not re-run the migration
+	db, err = OpenBolt(path)
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = NewBoltPaymentRepository(db)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"payment-service/internal/entity"
+	"time"
+)
+
+// newJob creates the job of a transaction, available at now
+func newJob(transactionID string, now time.Time) *entity.Job {
+	return &entity.Job{
+		TransactionID: transactionID,
+		Status:        entity.JobQueued,
+		AvailableAt:   now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+}
+
+// requeue makes a dead-lettered job available at now with a fresh set of attempts
+func requeue(job *entity.Job, now time.Time) {
+	job.Status = entity.JobQueued
+	job.Attempts = 0
+	job.AvailableAt = now
+	job.UpdatedAt = now
+}
+
+// count adds a job to the counts of a queue at now
+func count(counts *entity.JobCounts, job *entity.Job, now time.Time) {
+	switch {
+	case job.Status == entity.JobDead:
+		counts.Dead++
+	case job.Available(now):
+		counts.Available++
+	default:
+		counts.Delayed++
+	}
+}
+
+// leasedBefore reports whether job a is delivered before job b: the one available longest goes first
+func leasedBefore(a, b *entity.Job) bool {
+	if !a.AvailableAt.Equal(b.AvailableAt) {
+		return a.AvailableAt.Before(b.AvailableAt)
+	}
+	return a.TransactionID < b.TransactionID
+}
+
+// buriedBefore orders dead-lettered jobs oldest first, by when they were buried
+func buriedBefore(a, b *entity.Job) bool {
+	if !a.UpdatedAt.Equal(b.UpdatedAt) {
+		return a.UpdatedAt.Before(b.UpdatedAt)
+	}
+	return a.TransactionID < b.TransactionID
+}
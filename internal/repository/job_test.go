@@ -0,0 +1,230 @@
+package repository
+
+import (
+	"context"
+	"payment-service/internal/entity"
+	"payment-service/internal/usecase"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testJobQueue runs the behaviour every JobQueue implementation must satisfy.
+// newQueue must return an empty queue.
+func testJobQueue(t *testing.T, newQueue func(t *testing.T) usecase.JobQueue) {
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	t.Run("LeaseOldestFirst", func(t *testing.T) {
+		// Arrange
+		queue := newQueue(t)
+		require.NoError(t, queue.EnqueueJob(ctx, "txn2", start.Add(time.Second)))
+		require.NoError(t, queue.EnqueueJob(ctx, "txn1", start))
+		require.NoError(t, queue.EnqueueJob(ctx, "txn3", start.Add(time.Hour)))
+		now := start.Add(time.Minute)
+
+		// Act
+		first, firstErr := queue.LeaseJob(ctx, now, now.Add(30*time.Second))
+		second, secondErr := queue.LeaseJob(ctx, now, now.Add(30*time.Second))
+		none, noneErr := queue.LeaseJob(ctx, now, now.Add(30*time.Second))
+
+		// Assert
+		require.NoError(t, firstErr)
+		require.NotNil(t, first)
+		assert.Equal(t, "txn1", first.TransactionID)
+		assert.Equal(t, entity.JobQueued, first.Status)
+		assert.Equal(t, 1, first.Attempts)
+		assert.True(t, now.Add(30*time.Second).Equal(first.AvailableAt), "a leased job is hidden until its lease runs out")
+		assert.True(t, start.Equal(first.CreatedAt))
+		require.NoError(t, secondErr)
+		require.NotNil(t, second)
+		assert.Equal(t, "txn2", second.TransactionID)
+		require.NoError(t, noneErr)
+		assert.Nil(t, none, "txn3 is not available yet")
+	})
+
+	t.Run("EnqueueExisting", func(t *testing.T) {
+		// Arrange
+		queue := newQueue(t)
+		require.NoError(t, queue.EnqueueJob(ctx, "txn123", start))
+		leased, err := queue.LeaseJob(ctx, start, start.Add(time.Minute))
+		require.NoError(t, err)
+		require.NotNil(t, leased)
+
+		// Act
+		err = queue.EnqueueJob(ctx, "txn123", start)
+
+		// Assert
+		require.NoError(t, err)
+		again, err := queue.LeaseJob(ctx, start, start.Add(time.Minute))
+		require.NoError(t, err)
+		assert.Nil(t, again, "enqueueing a leased job does not deliver it twice")
+	})
+
+	t.Run("LeaseRunsOut", func(t *testing.T) {
+		// Arrange
+		queue := newQueue(t)
+		require.NoError(t, queue.EnqueueJob(ctx, "txn123", start))
+		_, err := queue.LeaseJob(ctx, start, start.Add(time.Minute))
+		require.NoError(t, err)
+
+		// Act
+		during, duringErr := queue.LeaseJob(ctx, start.Add(30*time.Second), start.Add(90*time.Second))
+		after, afterErr := queue.LeaseJob(ctx, start.Add(time.Minute), start.Add(2*time.Minute))
+		staleErr := queue.CompleteJob(ctx, "txn123", 1)
+		currentErr := queue.CompleteJob(ctx, "txn123", 2)
+
+		// Assert
+		require.NoError(t, duringErr)
+		assert.Nil(t, during)
+		require.NoError(t, afterErr)
+		require.NotNil(t, after, "a job whose worker did not finish it is delivered again")
+		assert.Equal(t, 2, after.Attempts)
+		assert.ErrorIs(t, staleErr, usecase.ErrJobLeaseLost)
+		assert.NoError(t, currentErr)
+		gone, err := queue.LeaseJob(ctx, start.Add(time.Hour), start.Add(2*time.Hour))
+		require.NoError(t, err)
+		assert.Nil(t, gone, "a completed job is removed")
+	})
+
+	t.Run("Retry", func(t *testing.T) {
+		// Arrange
+		queue := newQueue(t)
+		require.NoError(t, queue.EnqueueJob(ctx, "txn123", start))
+		_, err := queue.LeaseJob(ctx, start, start.Add(time.Minute))
+		require.NoError(t, err)
+
+		// Act
+		err = queue.RetryJob(ctx, "txn123", 1, "processor unavailable", start.Add(5*time.Minute), start.Add(time.Second))
+
+		// Assert
+		require.NoError(t, err)
+		early, err := queue.LeaseJob(ctx, start.Add(2*time.Minute), start.Add(3*time.Minute))
+		require.NoError(t, err)
+		assert.Nil(t, early, "a retried job waits for its retry time")
+		retried, err := queue.LeaseJob(ctx, start.Add(5*time.Minute), start.Add(6*time.Minute))
+		require.NoError(t, err)
+		require.NotNil(t, retried)
+		assert.Equal(t, 2, retried.Attempts)
+		assert.Equal(t, "processor unavailable", retried.LastError)
+		assert.ErrorIs(t, queue.RetryJob(ctx, "txn123", 1, "late", start, start), usecase.ErrJobLeaseLost)
+	})
+
+	t.Run("ReleaseDoesNotCountTheAttempt", func(t *testing.T) {
+		// Arrange
+		queue := newQueue(t)
+		require.NoError(t, queue.EnqueueJob(ctx, "txn123", start))
+		_, err := queue.LeaseJob(ctx, start, start.Add(time.Minute))
+		require.NoError(t, err)
+
+		// Act
+		err = queue.ReleaseJob(ctx, "txn123", 1, "worker stopped", start.Add(time.Second))
+
+		// Assert: the job is available straight away, and its next lease is its first attempt again
+		require.NoError(t, err)
+		released, err := queue.LeaseJob(ctx, start.Add(time.Second), start.Add(time.Minute))
+		require.NoError(t, err)
+		require.NotNil(t, released)
+		assert.Equal(t, 1, released.Attempts)
+		assert.Equal(t, "worker stopped", released.LastError)
+		assert.ErrorIs(t, queue.ReleaseJob(ctx, "txn123", 2, "late", start), usecase.ErrJobLeaseLost)
+	})
+
+	t.Run("BuryAndRequeue", func(t *testing.T) {
+		// Arrange
+		queue := newQueue(t)
+		require.NoError(t, queue.EnqueueJob(ctx, "txn123", start))
+		require.NoError(t, queue.EnqueueJob(ctx, "txn456", start))
+		for range 2 {
+			leased, err := queue.LeaseJob(ctx, start, start.Add(time.Minute))
+			require.NoError(t, err)
+			require.NotNil(t, leased)
+		}
+		require.NoError(t, queue.BuryJob(ctx, "txn456", 1, "second failure", start.Add(2*time.Second)))
+
+		// Act
+		err := queue.BuryJob(ctx, "txn123", 1, "first failure", start.Add(time.Second))
+
+		// Assert
+		require.NoError(t, err)
+		dead, err := queue.DeadJobs(ctx)
+		require.NoError(t, err)
+		require.Len(t, dead, 2)
+		assert.Equal(t, "txn123", dead[0].TransactionID, "oldest first")
+		assert.Equal(t, entity.JobDead, dead[0].Status)
+		assert.Equal(t, "first failure", dead[0].LastError)
+		assert.Equal(t, 1, dead[0].Attempts)
+
+		require.NoError(t, queue.EnqueueJob(ctx, "txn123", start.Add(time.Hour)))
+		hidden, err := queue.LeaseJob(ctx, start.Add(time.Hour), start.Add(2*time.Hour))
+		require.NoError(t, err)
+		assert.Nil(t, hidden, "dead jobs are only delivered again once requeued")
+
+		requeued, err := queue.RequeueJob(ctx, "txn123", start.Add(time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, entity.JobQueued, requeued.Status)
+		assert.Equal(t, 0, requeued.Attempts)
+		leased, err := queue.LeaseJob(ctx, start.Add(time.Hour), start.Add(2*time.Hour))
+		require.NoError(t, err)
+		require.NotNil(t, leased)
+		assert.Equal(t, "txn123", leased.TransactionID)
+		assert.Equal(t, 1, leased.Attempts)
+
+		_, err = queue.RequeueJob(ctx, "txn123", start.Add(time.Hour))
+		assert.ErrorIs(t, err, usecase.ErrJobNotFound, "only dead jobs can be requeued")
+		_, err = queue.RequeueJob(ctx, "missing", start.Add(time.Hour))
+		assert.ErrorIs(t, err, usecase.ErrJobNotFound)
+		remaining, err := queue.DeadJobs(ctx)
+		require.NoError(t, err)
+		assert.Len(t, remaining, 1)
+	})
+
+	t.Run("CountJobs", func(t *testing.T) {
+		// Arrange: one job available, one leased, one waiting for its retry and one dead
+		queue := newQueue(t)
+		require.NoError(t, queue.EnqueueJob(ctx, "txn1", start))
+		require.NoError(t, queue.EnqueueJob(ctx, "txn2", start))
+		require.NoError(t, queue.EnqueueJob(ctx, "txn3", start))
+		require.NoError(t, queue.EnqueueJob(ctx, "txn4", start.Add(time.Second)))
+		for _, retry := range []bool{false, true, false} {
+			job, err := queue.LeaseJob(ctx, start, start.Add(time.Minute))
+			require.NoError(t, err)
+			require.NotNil(t, job)
+			if retry {
+				require.NoError(t, queue.RetryJob(ctx, job.TransactionID, job.Attempts, "timeout", start.Add(time.Hour), start))
+			}
+		}
+		leased, err := queue.LeaseJob(ctx, start.Add(time.Second), start.Add(time.Minute))
+		require.NoError(t, err)
+		require.NoError(t, queue.BuryJob(ctx, leased.TransactionID, leased.Attempts, "timeout", start))
+		require.NoError(t, queue.EnqueueJob(ctx, "txn5", start))
+
+		// Act
+		counts, err := queue.CountJobs(ctx, start.Add(time.Second))
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, entity.JobCounts{Available: 1, Delayed: 3, Dead: 1}, *counts)
+	})
+
+	t.Run("NoDeadJobs", func(t *testing.T) {
+		// Arrange
+		queue := newQueue(t)
+
+		// Act
+		dead, err := queue.DeadJobs(ctx)
+
+		// Assert
+		require.NoError(t, err)
+		assert.NotNil(t, dead)
+		assert.Empty(t, dead)
+	})
+}
+
+func TestInMemoryJobQueue(t *testing.T) {
+	testJobQueue(t, func(t *testing.T) usecase.JobQueue {
+		return NewInMemoryPaymentRepository()
+	})
+}
@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"payment-service/internal/entity"
+)
+
+// postedBefore orders journal entries oldest first, by when they were posted
+func postedBefore(a, b entity.JournalEntry) bool {
+	if !a.PostedAt.Equal(b.PostedAt) {
+		return a.PostedAt.Before(b.PostedAt)
+	}
+	return a.ID < b.ID
+}
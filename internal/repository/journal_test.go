@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"payment-service/internal/entity"
+	"payment-service/internal/ledger"
+	"payment-service/internal/usecase"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// journalStore writes payments and wallets and reads back the journal entries recorded with them
+type journalStore interface {
+	usecase.PaymentRepository
+	usecase.WalletRepository
+	ledger.EntryStore
+}
+
+// testJournal runs the behaviour every repository recording journal entries must satisfy.
+// newStore must return an empty repository.
+func testJournal(t *testing.T, newStore func(t *testing.T) journalStore) {
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	entry := func(id string, postedAt time.Time) entity.JournalEntry {
+		amount := entity.Money{Amount: 1000, Currency: "USD"}
+		return entity.JournalEntry{
+			ID:          id,
+			Description: "entry " + id,
+			PostedAt:    postedAt,
+			Postings: []entity.Posting{
+				{Account: ledger.AccountCash, Side: entity.Debit, Amount: amount},
+				{Account: ledger.AccountMerchantPayable, Side: entity.Credit, Amount: amount},
+			},
+		}
+	}
+	entryIDs := func(t *testing.T, store journalStore) []string {
+		entries, err := store.JournalEntries(ctx)
+		require.NoError(t, err)
+		ids := []string{}
+		for _, entry := range entries {
+			ids = append(ids, entry.ID)
+		}
+		return ids
+	}
+
+	t.Run("RecordedWithPayments", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		payment := entity.NewPayment("txn123", "user123", entity.Money{Amount: 1000, Currency: "USD"}, "user123", "payment requested", start)
+		payment.Journal = []entity.JournalEntry{entry("capture:txn123", start)}
+
+		// Act
+		_, created, createErr := store.CreateIfAbsent(ctx, payment)
+		createdJournal := payment.Journal
+		payment.Journal = []entity.JournalEntry{entry("refund:txn123-refund-1", start.Add(time.Minute)), entry("capture:txn123", start.Add(time.Hour))}
+		updateErr := store.Update(ctx, payment)
+
+		// Assert
+		require.NoError(t, createErr)
+		assert.True(t, created)
+		assert.Empty(t, createdJournal, "recorded entries are cleared")
+		require.NoError(t, updateErr)
+		assert.Empty(t, payment.Journal)
+		entries, err := store.JournalEntries(ctx)
+		require.NoError(t, err)
+		require.Len(t, entries, 2, "an entry is recorded once")
+		assert.Equal(t, "capture:txn123", entries[0].ID)
+		assert.True(t, start.Equal(entries[0].PostedAt), "the first recording is kept")
+		assert.Equal(t, "entry capture:txn123", entries[0].Description)
+		assert.Equal(t, entry("capture:txn123", start).Postings, entries[0].Postings)
+		assert.Equal(t, "refund:txn123-refund-1", entries[1].ID)
+		stored, err := store.GetByTransactionID(ctx, "txn123")
+		require.NoError(t, err)
+		assert.Empty(t, stored.Journal, "entries are not part of the payment")
+	})
+
+	t.Run("RejectedWritesRecordNothing", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		payment := entity.NewPayment("txn123", "user123", entity.Money{Amount: 1000, Currency: "USD"}, "user123", "payment requested", start)
+		require.NoError(t, store.Store(ctx, payment))
+		duplicate := payment.Clone()
+		duplicate.Journal = []entity.JournalEntry{entry("capture:duplicate", start)}
+		stale := payment.Clone()
+		stale.Version = 7
+		stale.Journal = []entity.JournalEntry{entry("capture:stale", start)}
+
+		// Act
+		_, created, createErr := store.CreateIfAbsent(ctx, duplicate)
+		updateErr := store.Update(ctx, stale)
+
+		// Assert
+		require.NoError(t, createErr)
+		assert.False(t, created)
+		assert.ErrorIs(t, updateErr, usecase.ErrConcurrentUpdate)
+		assert.Empty(t, entryIDs(t, store))
+	})
+
+	t.Run("RecordedWithWallets", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		wallet := entity.NewWallet("user123")
+		wallet.Journal = []entity.JournalEntry{entry("wallet:user123:top_up:1", start)}
+		stale := entity.NewWallet("user123")
+		stale.Journal = []entity.JournalEntry{entry("wallet:user123:top_up:stale", start)}
+
+		// Act
+		err := store.SaveWallets(ctx, wallet)
+		staleErr := store.SaveWallets(ctx, stale)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Empty(t, wallet.Journal)
+		assert.ErrorIs(t, staleErr, usecase.ErrConcurrentUpdate)
+		assert.Equal(t, []string{"wallet:user123:top_up:1"}, entryIDs(t, store))
+	})
+
+	t.Run("OldestFirst", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		wallet := entity.NewWallet("user123")
+		wallet.Journal = []entity.JournalEntry{entry("b", start.Add(time.Hour)), entry("c", start), entry("a", start.Add(time.Hour))}
+
+		// Act
+		err := store.SaveWallets(ctx, wallet)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, []string{"c", "a", "b"}, entryIDs(t, store))
+	})
+}
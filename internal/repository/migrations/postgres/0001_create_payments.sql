@@ -0,0 +1,11 @@
+CREATE TABLE payments (
+    id             BIGSERIAL PRIMARY KEY,
+    transaction_id TEXT NOT NULL,
+    user_id        TEXT NOT NULL,
+    amount         DOUBLE PRECISION NOT NULL,
+    status         TEXT NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL,
+    CONSTRAINT payments_transaction_id_key UNIQUE (transaction_id)
+);
+
+CREATE INDEX idx_payments_user_id ON payments (user_id);
@@ -0,0 +1 @@
+ALTER TABLE payments ADD COLUMN request_fingerprint TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,12 @@
+-- Store amounts exactly as integer minor units plus an ISO 4217 currency code.
+-- Payments recorded before currencies were introduced were all USD.
+ALTER TABLE payments
+    ADD COLUMN amount_minor BIGINT,
+    ADD COLUMN currency     TEXT;
+
+UPDATE payments SET amount_minor = ROUND(amount * 100)::BIGINT, currency = 'USD';
+
+ALTER TABLE payments
+    ALTER COLUMN amount_minor SET NOT NULL,
+    ALTER COLUMN currency SET NOT NULL,
+    DROP COLUMN amount;
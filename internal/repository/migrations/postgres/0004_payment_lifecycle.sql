@@ -0,0 +1,15 @@
+-- Payments follow the pending/authorized/captured/... lifecycle and keep their status history.
+-- The former "completed" status is what the lifecycle calls "captured".
+ALTER TABLE payments ADD COLUMN status_history JSONB NOT NULL DEFAULT '[]';
+
+UPDATE payments
+SET status = 'captured',
+    status_history = jsonb_build_array(jsonb_build_object(
+        'to', 'captured',
+        'actor', 'system',
+        'reason', 'migrated from completed status',
+        'at', created_at
+    ))
+WHERE status = 'completed';
+
+CREATE INDEX idx_payments_status ON payments (status);
@@ -0,0 +1,8 @@
+-- Two-step payments: captured amount, authorization expiry and an optimistic concurrency version.
+-- Every payment captured so far was captured in full.
+ALTER TABLE payments
+    ADD COLUMN captured_amount_minor    BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN authorization_expires_at TIMESTAMPTZ,
+    ADD COLUMN version                  BIGINT NOT NULL DEFAULT 0;
+
+UPDATE payments SET captured_amount_minor = amount_minor WHERE status = 'captured';
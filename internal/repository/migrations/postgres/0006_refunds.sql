@@ -0,0 +1,3 @@
+-- Refunds are stored with their payment, so a refund and the status change it causes
+-- are written by the same optimistic concurrency update.
+ALTER TABLE payments ADD COLUMN refunds JSONB NOT NULL DEFAULT '[]';
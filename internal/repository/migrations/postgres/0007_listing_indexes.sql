@@ -0,0 +1,5 @@
+-- Indexes backing payment listings, which are ordered newest first with the transaction ID
+-- (compared bytewise) as a tie-breaker, overall and for a single user or status.
+CREATE INDEX payments_listing_idx ON payments (created_at DESC, transaction_id COLLATE "C" DESC);
+CREATE INDEX payments_user_listing_idx ON payments (user_id, created_at DESC, transaction_id COLLATE "C" DESC);
+CREATE INDEX payments_status_listing_idx ON payments (status, created_at DESC, transaction_id COLLATE "C" DESC);
@@ -0,0 +1,11 @@
+-- Payments record how they were paid; everything stored before wallets was a card payment.
+ALTER TABLE payments ADD COLUMN payment_method TEXT NOT NULL DEFAULT 'card';
+
+-- Wallets keep their balances and transactions together, so a balance change and its
+-- transaction are written by the same optimistic concurrency update.
+CREATE TABLE wallets (
+    user_id      TEXT PRIMARY KEY,
+    balances     JSONB NOT NULL DEFAULT '{}',
+    transactions JSONB NOT NULL DEFAULT '[]',
+    version      BIGINT NOT NULL DEFAULT 0
+);
@@ -0,0 +1,14 @@
+-- Transfers between wallets. The primary key makes transaction IDs unique across transfers,
+-- so a retried transfer request can never move funds twice.
+CREATE TABLE transfers (
+    transaction_id      TEXT PRIMARY KEY,
+    from_user_id        TEXT NOT NULL,
+    to_user_id          TEXT NOT NULL,
+    amount_minor        BIGINT NOT NULL,
+    currency            TEXT NOT NULL,
+    status              TEXT NOT NULL,
+    created_at          TIMESTAMPTZ NOT NULL,
+    completed_at        TIMESTAMPTZ,
+    request_fingerprint TEXT NOT NULL DEFAULT '',
+    version             BIGINT NOT NULL DEFAULT 0
+);
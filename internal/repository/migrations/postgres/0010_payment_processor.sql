@@ -0,0 +1,3 @@
+-- Card payments remember the card token they are charged to and the processor's ID for them.
+ALTER TABLE payments ADD COLUMN card_token TEXT NOT NULL DEFAULT '';
+ALTER TABLE payments ADD COLUMN processor_reference TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+-- Card payments are routed among several processors by merchant, and remember the one that authorized them.
+ALTER TABLE payments ADD COLUMN processor TEXT NOT NULL DEFAULT '';
+ALTER TABLE payments ADD COLUMN merchant_id TEXT NOT NULL DEFAULT '';
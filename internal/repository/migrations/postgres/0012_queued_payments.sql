@@ -0,0 +1,3 @@
+-- Asynchronous payments are stored pending and queued until a worker processes them.
+ALTER TABLE payments ADD COLUMN queued BOOLEAN NOT NULL DEFAULT FALSE;
+CREATE INDEX payments_queued_idx ON payments (created_at) WHERE queued;
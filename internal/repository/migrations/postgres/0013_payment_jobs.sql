@@ -0,0 +1,15 @@
+-- Jobs of queued asynchronous payments, leased by workers. A leased job's available_at is the end
+-- of its lease, so a job whose worker crashed becomes available again without any cleanup.
+-- Payments already queued are picked up by the workers' sweep of queued payments.
+CREATE TABLE payment_jobs (
+    transaction_id TEXT PRIMARY KEY,
+    status         TEXT NOT NULL,
+    attempts       INTEGER NOT NULL DEFAULT 0,
+    available_at   TIMESTAMPTZ NOT NULL,
+    last_error     TEXT NOT NULL DEFAULT '',
+    created_at     TIMESTAMPTZ NOT NULL,
+    updated_at     TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX payment_jobs_available_idx ON payment_jobs (available_at, transaction_id) WHERE status = 'queued';
+CREATE INDEX payment_jobs_dead_idx ON payment_jobs (updated_at, transaction_id) WHERE status = 'dead';
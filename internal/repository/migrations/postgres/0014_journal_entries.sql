@@ -0,0 +1,10 @@
+-- Double-entry journal entries, written in the same transaction as the payment or wallet change
+-- they account for. The ledger replays them in posted order.
+CREATE TABLE journal_entries (
+    id          TEXT PRIMARY KEY,
+    description TEXT NOT NULL,
+    posted_at   TIMESTAMPTZ NOT NULL,
+    postings    JSONB NOT NULL
+);
+
+CREATE INDEX journal_entries_posted_idx ON journal_entries (posted_at, id);
@@ -0,0 +1,33 @@
+-- Merchants' webhook endpoints and the deliveries of payment events to them. Dispatchers lease the
+-- deliveries they attempt: a leased delivery's next_attempt_at is the end of its lease, so a delivery
+-- whose dispatcher stopped is attempted again without any cleanup.
+CREATE TABLE webhook_endpoints (
+    id          TEXT PRIMARY KEY,
+    merchant_id TEXT NOT NULL,
+    url         TEXT NOT NULL,
+    secret      TEXT NOT NULL,
+    event_types JSONB NOT NULL DEFAULT '[]',
+    created_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX webhook_endpoints_merchant_idx ON webhook_endpoints (merchant_id, created_at, id);
+
+CREATE TABLE webhook_deliveries (
+    id               TEXT PRIMARY KEY,
+    endpoint_id      TEXT NOT NULL REFERENCES webhook_endpoints (id),
+    merchant_id      TEXT NOT NULL,
+    event            JSONB NOT NULL,
+    status           TEXT NOT NULL,
+    attempts         INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at  TIMESTAMPTZ,
+    last_attempt_at  TIMESTAMPTZ,
+    last_status_code INTEGER NOT NULL DEFAULT 0,
+    last_error       TEXT NOT NULL DEFAULT '',
+    delivered_at     TIMESTAMPTZ,
+    created_at       TIMESTAMPTZ NOT NULL,
+    leased_until     TIMESTAMPTZ,
+    leases           INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX webhook_deliveries_merchant_idx ON webhook_deliveries (merchant_id, created_at DESC, id DESC);
+CREATE INDEX webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
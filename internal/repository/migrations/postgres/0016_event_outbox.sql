@@ -0,0 +1,6 @@
+-- Payments count the status changes published as events; the rest are published by the outbox sweep.
+-- Changes stored before the count existed were published when they were made.
+ALTER TABLE payments ADD COLUMN published_changes INTEGER NOT NULL DEFAULT 0;
+UPDATE payments SET published_changes = jsonb_array_length(status_history);
+CREATE INDEX payments_unpublished_idx ON payments (created_at, transaction_id)
+    WHERE published_changes < jsonb_array_length(status_history);
@@ -0,0 +1,2 @@
+-- Captures and voids are claimed on the payment before the processor is called.
+ALTER TABLE payments ADD COLUMN pending_action JSONB;
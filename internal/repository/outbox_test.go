@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"payment-service/internal/entity"
+	"payment-service/internal/usecase"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// outboxStore writes payments and tracks which of their status changes have been published
+type outboxStore interface {
+	usecase.PaymentRepository
+	usecase.EventOutbox
+}
+
+// testEventOutbox runs the behaviour every usecase.EventOutbox implementation must satisfy.
+// newStore must return an empty repository.
+func testEventOutbox(t *testing.T, newStore func(t *testing.T) outboxStore) {
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	create := func(t *testing.T, store outboxStore, transactionID string, createdAt time.Time) *entity.Payment {
+		payment := entity.NewPayment(transactionID, "user123", entity.Money{Amount: 1000, Currency: "USD"}, "user123", "payment requested", createdAt)
+		require.NoError(t, store.Store(ctx, payment))
+		return payment
+	}
+	ids := func(payments []*entity.Payment) []string {
+		ids := []string{}
+		for _, payment := range payments {
+			ids = append(ids, payment.TransactionID)
+		}
+		return ids
+	}
+
+	t.Run("UnpublishedOldestFirst", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		create(t, store, "txn1", start.Add(time.Second))
+		create(t, store, "txn2", start)
+		create(t, store, "txn3", start.Add(2*time.Second))
+		require.NoError(t, store.MarkChangesPublished(ctx, "txn3", 1))
+
+		// Act
+		unpublished, err := store.UnpublishedPayments(ctx, 10)
+		first, firstErr := store.UnpublishedPayments(ctx, 1)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, []string{"txn2", "txn1"}, ids(unpublished), "txn3 has every change published")
+		assert.Zero(t, unpublished[0].PublishedChanges)
+		require.NoError(t, firstErr)
+		assert.Equal(t, []string{"txn2"}, ids(first))
+	})
+
+	t.Run("MarkPublished", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		create(t, store, "txn1", start)
+		loaded, err := store.GetByTransactionID(ctx, "txn1")
+		require.NoError(t, err)
+
+		// Act
+		markErr := store.MarkChangesPublished(ctx, "txn1", 1)
+		lowerErr := store.MarkChangesPublished(ctx, "txn1", 0)
+		missingErr := store.MarkChangesPublished(ctx, "txn9", 1)
+
+		// Assert
+		require.NoError(t, markErr)
+		require.NoError(t, lowerErr)
+		assert.ErrorIs(t, missingErr, usecase.ErrPaymentNotFound)
+		stored, err := store.GetByTransactionID(ctx, "txn1")
+		require.NoError(t, err)
+		assert.Equal(t, 1, stored.PublishedChanges, "the count is never lowered")
+		assert.Equal(t, loaded.Version, stored.Version, "marking changes published is not an update")
+		unpublished, err := store.UnpublishedPayments(ctx, 10)
+		require.NoError(t, err)
+		assert.Empty(t, unpublished)
+	})
+
+	t.Run("UpdateKeepsPublishedCount", func(t *testing.T) {
+		// Arrange: the change is marked published after the payment was read for an update
+		store := newStore(t)
+		create(t, store, "txn1", start)
+		loaded, err := store.GetByTransactionID(ctx, "txn1")
+		require.NoError(t, err)
+		require.NoError(t, store.MarkChangesPublished(ctx, "txn1", 1))
+		require.NoError(t, loaded.TransitionTo(entity.StatusAuthorized, "system", "authorized", start.Add(time.Second)))
+
+		// Act
+		err = store.Update(ctx, loaded)
+
+		// Assert
+		require.NoError(t, err)
+		stored, err := store.GetByTransactionID(ctx, "txn1")
+		require.NoError(t, err)
+		assert.Equal(t, 1, stored.PublishedChanges, "an update never lowers the count")
+		unpublished, err := store.UnpublishedPayments(ctx, 10)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"txn1"}, ids(unpublished), "the new change is not published yet")
+	})
+}
+
+func TestInMemoryEventOutbox(t *testing.T) {
+	testEventOutbox(t, func(t *testing.T) outboxStore {
+		return NewInMemoryPaymentRepository()
+	})
+}
@@ -1,35 +1,118 @@
 package repository
 
 import (
+	"context"
 	"payment-service/internal/entity"
+	"payment-service/internal/usecase"
+	"payment-service/internal/webhook"
+	"sort"
 	"sync"
+	"time"
 )
 
-// InMemoryPaymentRepository implements PaymentRepository using in-memory storage
+// InMemoryPaymentRepository implements PaymentRepository, WalletRepository, TransferRepository, JobQueue
+// and webhook.Store using in-memory storage.
+// Payments are copied on the way in and out, so callers never share state with the store.
+// Secondary indexes keep payments in listing order overall, per user and per status.
+// It also keeps the journal entries recorded with payments and wallets.
 type InMemoryPaymentRepository struct {
-	payments map[string]*entity.Payment
-	mutex    sync.RWMutex
+	payments   map[string]*entity.Payment
+	ordered    paymentIndex
+	byUser     map[string]paymentIndex
+	byStatus   map[string]paymentIndex
+	wallets    map[string]*entity.Wallet
+	transfers  map[string]*entity.Transfer
+	jobs       map[string]*entity.Job
+	journal    []entity.JournalEntry
+	recorded   map[string]bool
+	endpoints  []*webhook.Endpoint
+	deliveries map[string]*webhook.Delivery
+	mutex      sync.RWMutex
 }
 
 // NewInMemoryPaymentRepository creates a new in-memory payment repository
 func NewInMemoryPaymentRepository() *InMemoryPaymentRepository {
 	return &InMemoryPaymentRepository{
-		payments: make(map[string]*entity.Payment),
-		mutex:    sync.RWMutex{},
+		payments:   make(map[string]*entity.Payment),
+		byUser:     make(map[string]paymentIndex),
+		byStatus:   make(map[string]paymentIndex),
+		wallets:    make(map[string]*entity.Wallet),
+		transfers:  make(map[string]*entity.Transfer),
+		jobs:       make(map[string]*entity.Job),
+		recorded:   make(map[string]bool),
+		deliveries: make(map[string]*webhook.Delivery),
+		mutex:      sync.RWMutex{},
 	}
 }
 
-// Store saves a payment to the in-memory storage
-func (r *InMemoryPaymentRepository) Store(payment *entity.Payment) error {
+// Store saves a payment to the in-memory storage. Storing a transaction ID that
+// already exists fails with usecase.ErrDuplicateTransaction and leaves the original untouched.
+func (r *InMemoryPaymentRepository) Store(ctx context.Context, payment *entity.Payment) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	r.payments[payment.TransactionID] = payment
+	if _, exists := r.payments[payment.TransactionID]; exists {
+		return usecase.ErrDuplicateTransaction
+	}
+	r.record(&payment.Journal)
+	r.put(payment.Clone())
+	return nil
+}
+
+// CreateIfAbsent stores the payment unless its transaction ID already exists,
+// in which case the existing payment is returned
+func (r *InMemoryPaymentRepository) CreateIfAbsent(ctx context.Context, payment *entity.Payment) (*entity.Payment, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if existing, exists := r.payments[payment.TransactionID]; exists {
+		return existing.Clone(), false, nil
+	}
+	r.record(&payment.Journal)
+	r.put(payment.Clone())
+	return payment, true, nil
+}
+
+// Update saves changes to an existing payment if its version matches the stored one
+func (r *InMemoryPaymentRepository) Update(ctx context.Context, payment *entity.Payment) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	existing, exists := r.payments[payment.TransactionID]
+	if !exists {
+		return usecase.ErrPaymentNotFound
+	}
+	if existing.Version != payment.Version {
+		return usecase.ErrConcurrentUpdate
+	}
+
+	payment.Version++
+	r.record(&payment.Journal)
+	r.unindex(existing)
+	updated := payment.Clone()
+	updated.PublishedChanges = max(updated.PublishedChanges, existing.PublishedChanges)
+	r.put(updated)
 	return nil
 }
 
 // GetByTransactionID retrieves a payment by transaction ID
-func (r *InMemoryPaymentRepository) GetByTransactionID(transactionID string) (*entity.Payment, error) {
+func (r *InMemoryPaymentRepository) GetByTransactionID(ctx context.Context, transactionID string) (*entity.Payment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
@@ -38,14 +121,528 @@ func (r *InMemoryPaymentRepository) GetByTransactionID(transactionID string) (*e
 		return nil, nil
 	}
 
-	return payment, nil
+	return payment.Clone(), nil
 }
 
 // Exists checks if a payment with the given transaction ID exists
-func (r *InMemoryPaymentRepository) Exists(transactionID string) bool {
+func (r *InMemoryPaymentRepository) Exists(ctx context.Context, transactionID string) bool {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
 	_, exists := r.payments[transactionID]
 	return exists
 }
+
+// List returns at most query.Limit payments matching query, newest first.
+// It walks the smallest index that covers the query's user or status filter.
+func (r *InMemoryPaymentRepository) List(ctx context.Context, query usecase.PaymentQuery) ([]*entity.Payment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	index := r.ordered
+	if query.UserID != "" {
+		index = r.byUser[query.UserID]
+	}
+	if query.Status != "" && len(r.byStatus[query.Status]) < len(index) {
+		index = r.byStatus[query.Status]
+	}
+
+	var payments []*entity.Payment
+	for _, position := range index[index.after(query.After):] {
+		if len(payments) == query.Limit {
+			break
+		}
+		payment := r.payments[position.TransactionID]
+		if query.Matches(payment) {
+			payments = append(payments, payment.Clone())
+		}
+	}
+	return payments, nil
+}
+
+// GetWallet retrieves a user's wallet
+func (r *InMemoryPaymentRepository) GetWallet(ctx context.Context, userID string) (*entity.Wallet, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	wallet, exists := r.wallets[userID]
+	if !exists {
+		return nil, nil
+	}
+	return wallet.Clone(), nil
+}
+
+// SaveWallets stores wallets whose versions all match the stored ones, or none of them
+func (r *InMemoryPaymentRepository) SaveWallets(ctx context.Context, wallets ...*entity.Wallet) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, wallet := range wallets {
+		var version int64
+		if existing, exists := r.wallets[wallet.UserID]; exists {
+			version = existing.Version
+		}
+		if wallet.Version != version {
+			return usecase.ErrConcurrentUpdate
+		}
+	}
+	for _, wallet := range wallets {
+		wallet.Version++
+		r.record(&wallet.Journal)
+		r.wallets[wallet.UserID] = wallet.Clone()
+	}
+	return nil
+}
+
+// CreateTransferIfAbsent stores the transfer unless its transaction ID already exists,
+// in which case the existing transfer is returned
+func (r *InMemoryPaymentRepository) CreateTransferIfAbsent(ctx context.Context, transfer *entity.Transfer) (*entity.Transfer, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if existing, exists := r.transfers[transfer.TransactionID]; exists {
+		return existing.Clone(), false, nil
+	}
+	r.transfers[transfer.TransactionID] = transfer.Clone()
+	return transfer, true, nil
+}
+
+// UpdateTransfer saves changes to an existing transfer if its version matches the stored one
+func (r *InMemoryPaymentRepository) UpdateTransfer(ctx context.Context, transfer *entity.Transfer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	existing, exists := r.transfers[transfer.TransactionID]
+	if !exists {
+		return usecase.ErrTransferNotFound
+	}
+	if existing.Version != transfer.Version {
+		return usecase.ErrConcurrentUpdate
+	}
+
+	transfer.Version++
+	r.transfers[transfer.TransactionID] = transfer.Clone()
+	return nil
+}
+
+// GetTransfer retrieves a transfer by transaction ID
+func (r *InMemoryPaymentRepository) GetTransfer(ctx context.Context, transactionID string) (*entity.Transfer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	transfer, exists := r.transfers[transactionID]
+	if !exists {
+		return nil, nil
+	}
+	return transfer.Clone(), nil
+}
+
+// EnqueueJob adds a job for the transaction unless there already is one
+func (r *InMemoryPaymentRepository) EnqueueJob(ctx context.Context, transactionID string, now time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.jobs[transactionID]; !exists {
+		r.jobs[transactionID] = newJob(transactionID, now)
+	}
+	return nil
+}
+
+// LeaseJob delivers the job that has been available longest, hiding it until leaseUntil
+func (r *InMemoryPaymentRepository) LeaseJob(ctx context.Context, now, leaseUntil time.Time) (*entity.Job, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var next *entity.Job
+	for _, job := range r.jobs {
+		if job.Available(now) && (next == nil || leasedBefore(job, next)) {
+			next = job
+		}
+	}
+	if next == nil {
+		return nil, nil
+	}
+	next.Attempts++
+	next.AvailableAt = leaseUntil
+	next.UpdatedAt = now
+	return next.Clone(), nil
+}
+
+// CompleteJob removes the job leased by attempt
+func (r *InMemoryPaymentRepository) CompleteJob(ctx context.Context, transactionID string, attempt int) error {
+	return r.changeLeasedJob(ctx, transactionID, attempt, func(job *entity.Job) {
+		delete(r.jobs, transactionID)
+	})
+}
+
+// RetryJob makes the job leased by attempt available again at retryAt
+func (r *InMemoryPaymentRepository) RetryJob(ctx context.Context, transactionID string, attempt int, lastError string, retryAt, now time.Time) error {
+	return r.changeLeasedJob(ctx, transactionID, attempt, func(job *entity.Job) {
+		job.AvailableAt = retryAt
+		job.LastError = lastError
+		job.UpdatedAt = now
+	})
+}
+
+// ReleaseJob hands the job leased by attempt back at now, without counting the attempt
+func (r *InMemoryPaymentRepository) ReleaseJob(ctx context.Context, transactionID string, attempt int, lastError string, now time.Time) error {
+	return r.changeLeasedJob(ctx, transactionID, attempt, func(job *entity.Job) {
+		job.Attempts--
+		job.AvailableAt = now
+		job.LastError = lastError
+		job.UpdatedAt = now
+	})
+}
+
+// BuryJob moves the job leased by attempt to the dead-letter queue
+func (r *InMemoryPaymentRepository) BuryJob(ctx context.Context, transactionID string, attempt int, lastError string, now time.Time) error {
+	return r.changeLeasedJob(ctx, transactionID, attempt, func(job *entity.Job) {
+		job.Status = entity.JobDead
+		job.LastError = lastError
+		job.UpdatedAt = now
+	})
+}
+
+// DeadJobs lists the dead-lettered jobs, oldest first
+func (r *InMemoryPaymentRepository) DeadJobs(ctx context.Context) ([]*entity.Job, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	dead := []*entity.Job{}
+	for _, job := range r.jobs {
+		if job.Status == entity.JobDead {
+			dead = append(dead, job.Clone())
+		}
+	}
+	sort.Slice(dead, func(i, j int) bool { return buriedBefore(dead[i], dead[j]) })
+	return dead, nil
+}
+
+// CountJobs counts the jobs available, delayed and dead at now
+func (r *InMemoryPaymentRepository) CountJobs(ctx context.Context, now time.Time) (*entity.JobCounts, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	counts := &entity.JobCounts{}
+	for _, job := range r.jobs {
+		count(counts, job, now)
+	}
+	return counts, nil
+}
+
+// RequeueJob makes a dead-lettered job available at now with no attempts
+func (r *InMemoryPaymentRepository) RequeueJob(ctx context.Context, transactionID string, now time.Time) (*entity.Job, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	job, exists := r.jobs[transactionID]
+	if !exists || job.Status != entity.JobDead {
+		return nil, usecase.ErrJobNotFound
+	}
+	requeue(job, now)
+	return job.Clone(), nil
+}
+
+// changeLeasedJob applies change to the job if it is still leased by attempt
+func (r *InMemoryPaymentRepository) changeLeasedJob(ctx context.Context, transactionID string, attempt int, change func(job *entity.Job)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	job, exists := r.jobs[transactionID]
+	if !exists || job.Status != entity.JobQueued || job.Attempts != attempt {
+		return usecase.ErrJobLeaseLost
+	}
+	change(job)
+	return nil
+}
+
+// SaveWebhookEndpoint stores a new webhook endpoint
+func (r *InMemoryPaymentRepository) SaveWebhookEndpoint(ctx context.Context, endpoint *webhook.Endpoint) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.endpoints = append(r.endpoints, endpoint.Clone())
+	return nil
+}
+
+// WebhookEndpoints lists a merchant's webhook endpoints, oldest first
+func (r *InMemoryPaymentRepository) WebhookEndpoints(ctx context.Context, merchantID string) ([]*webhook.Endpoint, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	endpoints := []*webhook.Endpoint{}
+	for _, endpoint := range r.endpoints {
+		if endpoint.MerchantID == merchantID {
+			endpoints = append(endpoints, endpoint.Clone())
+		}
+	}
+	sort.Slice(endpoints, func(i, j int) bool { return registeredBefore(endpoints[i], endpoints[j]) })
+	return endpoints, nil
+}
+
+// AddWebhookDeliveries stores the deliveries whose IDs are not stored yet
+func (r *InMemoryPaymentRepository) AddWebhookDeliveries(ctx context.Context, deliveries []*webhook.Delivery) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, delivery := range deliveries {
+		if _, exists := r.deliveries[delivery.ID]; !exists {
+			r.deliveries[delivery.ID] = delivery.Clone()
+		}
+	}
+	return nil
+}
+
+// WebhookDeliveries lists a merchant's webhook deliveries, newest first, optionally only those with a status
+func (r *InMemoryPaymentRepository) WebhookDeliveries(ctx context.Context, merchantID, status string) ([]*webhook.Delivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	deliveries := []*webhook.Delivery{}
+	for _, delivery := range r.deliveries {
+		if delivery.MerchantID == merchantID && (status == "" || delivery.Status == status) {
+			deliveries = append(deliveries, delivery.Clone())
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool { return publishedAfter(deliveries[i], deliveries[j]) })
+	return deliveries, nil
+}
+
+// LeaseWebhookDeliveries leases up to limit pending webhook deliveries due at now until leaseUntil,
+// those due longest first
+func (r *InMemoryPaymentRepository) LeaseWebhookDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*webhook.Delivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	due := []*webhook.Delivery{}
+	for _, delivery := range r.deliveries {
+		if delivery.Due(now) {
+			due = append(due, delivery)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return dueBefore(due[i], due[j]) })
+
+	leased := []*webhook.Delivery{}
+	for _, delivery := range due[:min(limit, len(due))] {
+		delivery.Lease(leaseUntil)
+		leased = append(leased, delivery.Clone())
+	}
+	return leased, nil
+}
+
+// SaveWebhookAttempt stores the outcome of an attempt if it still holds the delivery's lease
+func (r *InMemoryPaymentRepository) SaveWebhookAttempt(ctx context.Context, delivery *webhook.Delivery) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if !holdsLease(r.deliveries[delivery.ID], delivery) {
+		return webhook.ErrDeliveryLeaseLost
+	}
+	saved := delivery.Clone()
+	saved.LeasedUntil = nil
+	r.deliveries[delivery.ID] = saved
+	return nil
+}
+
+// ReplayWebhookDelivery makes a merchant's webhook delivery pending at now with no attempts
+func (r *InMemoryPaymentRepository) ReplayWebhookDelivery(ctx context.Context, merchantID, deliveryID string, now time.Time) (*webhook.Delivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delivery := r.deliveries[deliveryID]
+	if err := replayable(delivery, merchantID, now); err != nil {
+		return nil, err
+	}
+	delivery.Replay(now)
+	return delivery.Clone(), nil
+}
+
+// put stores a payment and adds it to every index. The caller must hold the write lock.
+func (r *InMemoryPaymentRepository) put(payment *entity.Payment) {
+	r.payments[payment.TransactionID] = payment
+	position := usecase.CursorOf(payment)
+	r.ordered = r.ordered.insert(position)
+	r.byUser[payment.UserID] = r.byUser[payment.UserID].insert(position)
+	r.byStatus[payment.Status] = r.byStatus[payment.Status].insert(position)
+}
+
+// unindex removes a stored payment from every index. The caller must hold the write lock.
+func (r *InMemoryPaymentRepository) unindex(payment *entity.Payment) {
+	position := usecase.CursorOf(payment)
+	r.ordered = r.ordered.remove(position)
+	r.byUser[payment.UserID] = r.byUser[payment.UserID].remove(position)
+	r.byStatus[payment.Status] = r.byStatus[payment.Status].remove(position)
+}
+
+// JournalEntries returns every journal entry recorded with a payment or wallet, oldest first
+func (r *InMemoryPaymentRepository) JournalEntries(ctx context.Context) ([]entity.JournalEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	entries := append([]entity.JournalEntry{}, r.journal...)
+	sort.SliceStable(entries, func(i, j int) bool { return postedBefore(entries[i], entries[j]) })
+	return entries, nil
+}
+
+// UnpublishedPayments returns at most limit payments with changes not yet published, oldest first
+func (r *InMemoryPaymentRepository) UnpublishedPayments(ctx context.Context, limit int) ([]*entity.Payment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	payments := []*entity.Payment{}
+	for i := len(r.ordered) - 1; i >= 0 && len(payments) < limit; i-- {
+		payment := r.payments[r.ordered[i].TransactionID]
+		if payment.UnpublishedChanges() {
+			payments = append(payments, payment.Clone())
+		}
+	}
+	return payments, nil
+}
+
+// MarkChangesPublished records that the first published changes of a payment have been published
+func (r *InMemoryPaymentRepository) MarkChangesPublished(ctx context.Context, transactionID string, published int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	payment, exists := r.payments[transactionID]
+	if !exists {
+		return usecase.ErrPaymentNotFound
+	}
+	payment.PublishedChanges = max(payment.PublishedChanges, published)
+	return nil
+}
+
+// record appends the entries of journal not recorded before and clears it.
+// The caller must hold the write lock.
+func (r *InMemoryPaymentRepository) record(journal *[]entity.JournalEntry) {
+	for _, entry := range *journal {
+		if !r.recorded[entry.ID] {
+			entry.Postings = append([]entity.Posting(nil), entry.Postings...)
+			r.journal = append(r.journal, entry)
+			r.recorded[entry.ID] = true
+		}
+	}
+	*journal = nil
+}
+
+// paymentIndex holds payment positions sorted in listing order
+type paymentIndex []usecase.PaymentCursor
+
+// search returns the index of the first position not listed before position
+func (idx paymentIndex) search(position usecase.PaymentCursor) int {
+	return sort.Search(len(idx), func(i int) bool { return !idx[i].Before(position) })
+}
+
+// after returns the index of the first position listed after cursor, or 0 without a cursor
+func (idx paymentIndex) after(cursor *usecase.PaymentCursor) int {
+	if cursor == nil {
+		return 0
+	}
+	return sort.Search(len(idx), func(i int) bool { return cursor.Before(idx[i]) })
+}
+
+// insert adds position, keeping the index sorted
+func (idx paymentIndex) insert(position usecase.PaymentCursor) paymentIndex {
+	i := idx.search(position)
+	idx = append(idx, usecase.PaymentCursor{})
+	copy(idx[i+1:], idx[i:])
+	idx[i] = position
+	return idx
+}
+
+// remove deletes position if the index holds it
+func (idx paymentIndex) remove(position usecase.PaymentCursor) paymentIndex {
+	i := idx.search(position)
+	if i < len(idx) && !position.Before(idx[i]) {
+		return append(idx[:i], idx[i+1:]...)
+	}
+	return idx
+}
@@ -0,0 +1,433 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"payment-service/internal/entity"
+	"payment-service/internal/usecase"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPaymentRepository runs the behaviour every PaymentRepository implementation must satisfy.
+// newRepo must return an empty repository.
+func testPaymentRepository(t *testing.T, newRepo func(t *testing.T) usecase.PaymentRepository) {
+	t.Run("StoreAndGet", func(t *testing.T) {
+		// Arrange
+		repo := newRepo(t)
+		now := time.Now().UTC().Truncate(time.Microsecond)
+		payment := entity.NewPayment("txn123", "user123", entity.Money{Amount: 10050, Currency: "USD"}, "user123", "payment requested", now)
+		payment.RequestFingerprint = "fingerprint"
+		payment.PaymentMethod = entity.PaymentMethodWallet
+		payment.SetCardToken("4242424242424242")
+		payment.ProcessorReference = "sim_txn123"
+		payment.Processor = "primary"
+		payment.MerchantID = "merchant-1"
+		require.NoError(t, payment.TransitionTo(entity.StatusAuthorized, "system", "payment authorized", now))
+
+		// Act
+		err := repo.Store(context.Background(), payment)
+
+		// Assert
+		require.NoError(t, err)
+		stored, err := repo.GetByTransactionID(context.Background(), "txn123")
+		require.NoError(t, err)
+		require.NotNil(t, stored)
+		assert.Equal(t, payment.TransactionID, stored.TransactionID)
+		assert.Equal(t, payment.UserID, stored.UserID)
+		assert.Equal(t, payment.Amount, stored.Amount)
+		assert.Equal(t, payment.Status, stored.Status)
+		assert.True(t, payment.CreatedAt.Equal(stored.CreatedAt))
+		assert.Equal(t, payment.RequestFingerprint, stored.RequestFingerprint)
+		assert.Equal(t, entity.PaymentMethodWallet, stored.PaymentMethod)
+		assert.Equal(t, "4242424242424242", stored.CardToken)
+		assert.Equal(t, "4242", stored.CardLast4)
+		assert.Equal(t, "sim_txn123", stored.ProcessorReference)
+		assert.Equal(t, "primary", stored.Processor)
+		assert.Equal(t, "merchant-1", stored.MerchantID)
+		require.Len(t, stored.StatusHistory, 2)
+		assert.Equal(t, entity.StatusChange{To: entity.StatusPending, Actor: "user123", Reason: "payment requested", At: now}, normalizeChange(stored.StatusHistory[0]))
+		assert.Equal(t, entity.StatusChange{From: entity.StatusPending, To: entity.StatusAuthorized, Actor: "system", Reason: "payment authorized", At: now}, normalizeChange(stored.StatusHistory[1]))
+	})
+
+	t.Run("GetMissing", func(t *testing.T) {
+		// Arrange
+		repo := newRepo(t)
+
+		// Act
+		payment, err := repo.GetByTransactionID(context.Background(), "missing")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Nil(t, payment)
+	})
+
+	t.Run("CanceledContext", func(t *testing.T) {
+		// Arrange
+		repo := newRepo(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		payment := entity.NewPayment("txn123", "user123", entity.Money{Amount: 1000, Currency: "USD"}, "user123", "payment requested", time.Now())
+
+		// Act
+		_, _, createErr := repo.CreateIfAbsent(ctx, payment)
+		_, getErr := repo.GetByTransactionID(ctx, "txn123")
+		_, listErr := repo.List(ctx, usecase.PaymentQuery{Limit: 10})
+
+		// Assert
+		assert.ErrorIs(t, createErr, context.Canceled)
+		assert.ErrorIs(t, getErr, context.Canceled)
+		assert.ErrorIs(t, listErr, context.Canceled)
+		assert.False(t, repo.Exists(context.Background(), "txn123"), "nothing is written once the context is done")
+	})
+
+	t.Run("Exists", func(t *testing.T) {
+		// Arrange
+		repo := newRepo(t)
+		require.NoError(t, repo.Store(context.Background(), &entity.Payment{
+			TransactionID: "txn123",
+			UserID:        "user123",
+			Amount:        entity.Money{Amount: 1000, Currency: "USD"},
+			Status:        entity.StatusCaptured,
+			CreatedAt:     time.Now(),
+		}))
+
+		// Act & Assert
+		assert.True(t, repo.Exists(context.Background(), "txn123"))
+		assert.False(t, repo.Exists(context.Background(), "txn456"))
+	})
+
+	t.Run("StoreDuplicate", func(t *testing.T) {
+		// Arrange
+		repo := newRepo(t)
+		require.NoError(t, repo.Store(context.Background(), &entity.Payment{TransactionID: "txn123", UserID: "user123", Amount: entity.Money{Amount: 100, Currency: "USD"}, CreatedAt: time.Now()}))
+
+		// Act
+		err := repo.Store(context.Background(), &entity.Payment{TransactionID: "txn123", UserID: "user456", Amount: entity.Money{Amount: 200, Currency: "USD"}, CreatedAt: time.Now()})
+
+		// Assert
+		assert.ErrorIs(t, err, usecase.ErrDuplicateTransaction)
+		stored, err := repo.GetByTransactionID(context.Background(), "txn123")
+		require.NoError(t, err)
+		assert.Equal(t, "user123", stored.UserID)
+	})
+
+	t.Run("CreateIfAbsent", func(t *testing.T) {
+		// Arrange
+		repo := newRepo(t)
+		first := &entity.Payment{TransactionID: "txn123", UserID: "user123", Amount: entity.Money{Amount: 100, Currency: "USD"}, CreatedAt: time.Now()}
+		second := &entity.Payment{TransactionID: "txn123", UserID: "user456", Amount: entity.Money{Amount: 200, Currency: "USD"}, CreatedAt: time.Now()}
+
+		// Act
+		stored, created, err := repo.CreateIfAbsent(context.Background(), first)
+		require.NoError(t, err)
+		assert.True(t, created)
+		assert.Equal(t, "user123", stored.UserID)
+
+		existing, created, err := repo.CreateIfAbsent(context.Background(), second)
+
+		// Assert
+		require.NoError(t, err)
+		assert.False(t, created)
+		assert.Equal(t, "user123", existing.UserID)
+		assert.Equal(t, entity.Money{Amount: 100, Currency: "USD"}, existing.Amount)
+	})
+
+	t.Run("CreateIfAbsentConcurrent", func(t *testing.T) {
+		// Arrange
+		const attempts = 200
+		repo := newRepo(t)
+		var (
+			wg      sync.WaitGroup
+			mu      sync.Mutex
+			created int
+			winner  string
+			start   = make(chan struct{})
+		)
+
+		// Act
+		for i := 0; i < attempts; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				<-start
+				userID := fmt.Sprintf("user%d", i)
+				_, ok, err := repo.CreateIfAbsent(context.Background(), &entity.Payment{
+					TransactionID: "txn-race",
+					UserID:        userID,
+					Amount:        entity.Money{Amount: 100, Currency: "USD"},
+					CreatedAt:     time.Now(),
+				})
+				assert.NoError(t, err)
+				if ok {
+					mu.Lock()
+					created++
+					winner = userID
+					mu.Unlock()
+				}
+			}(i)
+		}
+		close(start)
+		wg.Wait()
+
+		// Assert
+		assert.Equal(t, 1, created)
+		stored, err := repo.GetByTransactionID(context.Background(), "txn-race")
+		require.NoError(t, err)
+		assert.Equal(t, winner, stored.UserID)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		// Arrange
+		repo := newRepo(t)
+		now := time.Now().UTC().Truncate(time.Microsecond)
+		payment := entity.NewPayment("txn123", "user123", entity.Money{Amount: 10000, Currency: "USD"}, "user123", "payment requested", now)
+		require.NoError(t, repo.Store(context.Background(), payment))
+
+		loaded, err := repo.GetByTransactionID(context.Background(), "txn123")
+		require.NoError(t, err)
+		require.NoError(t, loaded.TransitionTo(entity.StatusAuthorized, "merchant", "payment authorized", now))
+		require.NoError(t, loaded.TransitionTo(entity.StatusCaptured, "merchant", "payment captured", now))
+		loaded.CapturedAmount = entity.Money{Amount: 4000, Currency: "USD"}
+		loaded.Refunds = []entity.Refund{{
+			RefundID:       "txn123-refund-1",
+			IdempotencyKey: "refund-1",
+			Amount:         entity.Money{Amount: 1500, Currency: "USD"},
+			Reason:         entity.RefundReasonDuplicate,
+			CreatedAt:      now,
+		}}
+		expiresAt := now.Add(time.Hour)
+		loaded.AuthorizationExpiresAt = &expiresAt
+		loaded.ProcessorReference = "sim_txn123"
+
+		// Act
+		err = repo.Update(context.Background(), loaded)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), loaded.Version)
+		stored, err := repo.GetByTransactionID(context.Background(), "txn123")
+		require.NoError(t, err)
+		assert.Equal(t, entity.StatusCaptured, stored.Status)
+		assert.Equal(t, entity.Money{Amount: 4000, Currency: "USD"}, stored.CapturedAmount)
+		assert.Equal(t, "sim_txn123", stored.ProcessorReference)
+		require.NotNil(t, stored.AuthorizationExpiresAt)
+		assert.True(t, expiresAt.Equal(*stored.AuthorizationExpiresAt))
+		assert.Len(t, stored.StatusHistory, 3)
+		require.Len(t, stored.Refunds, 1)
+		stored.Refunds[0].CreatedAt = stored.Refunds[0].CreatedAt.UTC()
+		assert.Equal(t, loaded.Refunds[0], stored.Refunds[0])
+		assert.Equal(t, int64(1), stored.Version)
+	})
+
+	t.Run("PendingAction", func(t *testing.T) {
+		// Arrange: a capture is claimed before the processor is called
+		repo := newRepo(t)
+		now := time.Now().UTC().Truncate(time.Microsecond)
+		payment := entity.NewPayment("txn123", "user123", entity.Money{Amount: 10000, Currency: "USD"}, "user123", "payment requested", now)
+		require.NoError(t, payment.TransitionTo(entity.StatusAuthorized, "merchant", "payment authorized", now))
+		action := entity.PendingAction{Action: entity.ActionCapture, Amount: entity.Money{Amount: 4000, Currency: "USD"}, Actor: "merchant", Reason: "payment captured", ClaimedAt: now}
+		payment.PendingAction = &action
+		require.NoError(t, repo.Store(context.Background(), payment))
+
+		// Act: the capture is recorded once the processor has made it
+		claimed, claimedErr := repo.GetByTransactionID(context.Background(), "txn123")
+		require.NoError(t, claimedErr)
+		recorded := claimed.Clone()
+		recorded.PendingAction = nil
+		updateErr := repo.Update(context.Background(), recorded)
+
+		// Assert
+		require.NotNil(t, claimed.PendingAction)
+		assert.True(t, action.Matches(*claimed.PendingAction))
+		assert.Equal(t, "merchant", claimed.PendingAction.Actor)
+		assert.Equal(t, "payment captured", claimed.PendingAction.Reason)
+		assert.True(t, now.Equal(claimed.PendingAction.ClaimedAt))
+		require.NoError(t, updateErr)
+		stored, err := repo.GetByTransactionID(context.Background(), "txn123")
+		require.NoError(t, err)
+		assert.Nil(t, stored.PendingAction)
+	})
+
+	t.Run("UpdateStaleVersion", func(t *testing.T) {
+		// Arrange
+		repo := newRepo(t)
+		require.NoError(t, repo.Store(context.Background(), entity.NewPayment("txn123", "user123", entity.Money{Amount: 10000, Currency: "USD"}, "user123", "payment requested", time.Now())))
+		first, err := repo.GetByTransactionID(context.Background(), "txn123")
+		require.NoError(t, err)
+		second, err := repo.GetByTransactionID(context.Background(), "txn123")
+		require.NoError(t, err)
+		require.NoError(t, first.TransitionTo(entity.StatusAuthorized, "merchant", "payment authorized", time.Now()))
+		require.NoError(t, repo.Update(context.Background(), first))
+
+		// Act
+		require.NoError(t, second.TransitionTo(entity.StatusFailed, "merchant", "payment failed", time.Now()))
+		err = repo.Update(context.Background(), second)
+
+		// Assert
+		assert.ErrorIs(t, err, usecase.ErrConcurrentUpdate)
+		stored, err := repo.GetByTransactionID(context.Background(), "txn123")
+		require.NoError(t, err)
+		assert.Equal(t, entity.StatusAuthorized, stored.Status)
+	})
+
+	t.Run("ListFilters", func(t *testing.T) {
+		// Arrange
+		repo := newRepo(t)
+		base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		for i, p := range []struct {
+			userID string
+			amount entity.Money
+			status string
+		}{
+			{"alice", entity.Money{Amount: 1000, Currency: "USD"}, entity.StatusCaptured},
+			{"bob", entity.Money{Amount: 2000, Currency: "USD"}, entity.StatusPending},
+			{"alice", entity.Money{Amount: 3000, Currency: "EUR"}, entity.StatusCaptured},
+			{"alice", entity.Money{Amount: 4000, Currency: "USD"}, entity.StatusPending},
+			{"bob", entity.Money{Amount: 5000, Currency: "USD"}, entity.StatusCaptured},
+		} {
+			payment := entity.NewPayment(fmt.Sprintf("txn%d", i), p.userID, p.amount, p.userID, "payment requested", base.Add(time.Duration(i)*time.Hour))
+			payment.Status = p.status
+			payment.Queued = i == 3
+			require.NoError(t, repo.Store(context.Background(), payment))
+		}
+		minAmount, maxAmount := int64(1500), int64(4000)
+		from, to := base.Add(time.Hour), base.Add(4*time.Hour)
+
+		tests := []struct {
+			name  string
+			query usecase.PaymentQuery
+			want  []string
+		}{
+			{"all", usecase.PaymentQuery{}, []string{"txn4", "txn3", "txn2", "txn1", "txn0"}},
+			{"user", usecase.PaymentQuery{UserID: "alice"}, []string{"txn3", "txn2", "txn0"}},
+			{"status", usecase.PaymentQuery{Status: entity.StatusCaptured}, []string{"txn4", "txn2", "txn0"}},
+			{"user and status", usecase.PaymentQuery{UserID: "bob", Status: entity.StatusPending}, []string{"txn1"}},
+			{"currency", usecase.PaymentQuery{Currency: "EUR"}, []string{"txn2"}},
+			{"amount range", usecase.PaymentQuery{Currency: "USD", MinAmount: &minAmount, MaxAmount: &maxAmount}, []string{"txn3", "txn1"}},
+			{"created range", usecase.PaymentQuery{CreatedFrom: &from, CreatedTo: &to}, []string{"txn3", "txn2", "txn1"}},
+			{"limit", usecase.PaymentQuery{Limit: 2}, []string{"txn4", "txn3"}},
+			{"queued", usecase.PaymentQuery{Status: entity.StatusPending, Queued: true}, []string{"txn3"}},
+			{"unknown user", usecase.PaymentQuery{UserID: "carol"}, nil},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if tt.query.Limit == 0 {
+					tt.query.Limit = 10
+				}
+
+				// Act
+				payments, err := repo.List(context.Background(), tt.query)
+
+				// Assert
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, transactionIDs(payments))
+			})
+		}
+	})
+
+	t.Run("ListPagination", func(t *testing.T) {
+		// Arrange: payments sharing a creation time are ordered by transaction ID
+		repo := newRepo(t)
+		createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		for _, id := range []string{"b", "a", "d", "c", "e"} {
+			require.NoError(t, repo.Store(context.Background(), entity.NewPayment(id, "user123", entity.Money{Amount: 100, Currency: "USD"}, "user123", "payment requested", createdAt)))
+		}
+		require.NoError(t, repo.Store(context.Background(), entity.NewPayment("z", "user123", entity.Money{Amount: 100, Currency: "USD"}, "user123", "payment requested", createdAt.Add(-time.Minute))))
+
+		// Act
+		var pages [][]string
+		query := usecase.PaymentQuery{UserID: "user123", Limit: 2}
+		for {
+			payments, err := repo.List(context.Background(), query)
+			require.NoError(t, err)
+			if len(payments) == 0 {
+				break
+			}
+			pages = append(pages, transactionIDs(payments))
+			cursor := usecase.CursorOf(payments[len(payments)-1])
+			query.After = &cursor
+		}
+
+		// Assert
+		assert.Equal(t, [][]string{{"e", "d"}, {"c", "b"}, {"a", "z"}}, pages)
+	})
+
+	t.Run("ListAfterUpdate", func(t *testing.T) {
+		// Arrange
+		repo := newRepo(t)
+		require.NoError(t, repo.Store(context.Background(), entity.NewPayment("txn123", "user123", entity.Money{Amount: 100, Currency: "USD"}, "user123", "payment requested", time.Now())))
+		payment, err := repo.GetByTransactionID(context.Background(), "txn123")
+		require.NoError(t, err)
+		require.NoError(t, payment.TransitionTo(entity.StatusAuthorized, "merchant", "payment authorized", time.Now()))
+		require.NoError(t, repo.Update(context.Background(), payment))
+
+		// Act
+		pending, err := repo.List(context.Background(), usecase.PaymentQuery{Status: entity.StatusPending, Limit: 10})
+		require.NoError(t, err)
+		authorized, err := repo.List(context.Background(), usecase.PaymentQuery{Status: entity.StatusAuthorized, Limit: 10})
+		require.NoError(t, err)
+		all, err := repo.List(context.Background(), usecase.PaymentQuery{UserID: "user123", Limit: 10})
+		require.NoError(t, err)
+
+		// Assert
+		assert.Empty(t, pending)
+		assert.Equal(t, []string{"txn123"}, transactionIDs(authorized))
+		assert.Equal(t, []string{"txn123"}, transactionIDs(all))
+	})
+
+	t.Run("UpdateMissing", func(t *testing.T) {
+		// Arrange
+		repo := newRepo(t)
+
+		// Act
+		err := repo.Update(context.Background(), entity.NewPayment("missing", "user123", entity.Money{Amount: 100, Currency: "USD"}, "user123", "payment requested", time.Now()))
+
+		// Assert
+		assert.ErrorIs(t, err, usecase.ErrPaymentNotFound)
+	})
+}
+
+// transactionIDs returns the transaction IDs of payments in order
+func transactionIDs(payments []*entity.Payment) []string {
+	var ids []string
+	for _, payment := range payments {
+		ids = append(ids, payment.TransactionID)
+	}
+	return ids
+}
+
+// normalizeChange converts the change time to UTC so changes read back from storage compare equal
+func normalizeChange(change entity.StatusChange) entity.StatusChange {
+	change.At = change.At.UTC()
+	return change
+}
+
+func TestInMemoryPaymentRepository(t *testing.T) {
+	testPaymentRepository(t, func(t *testing.T) usecase.PaymentRepository {
+		return NewInMemoryPaymentRepository()
+	})
+}
+
+func TestInMemoryJournal(t *testing.T) {
+	testJournal(t, func(t *testing.T) journalStore {
+		return NewInMemoryPaymentRepository()
+	})
+}
+
+func TestInMemoryWalletRepository(t *testing.T) {
+	testWalletRepository(t, func(t *testing.T) usecase.WalletRepository {
+		return NewInMemoryPaymentRepository()
+	})
+}
+
+func TestInMemoryTransferRepository(t *testing.T) {
+	testTransferRepository(t, func(t *testing.T) usecase.TransferRepository {
+		return NewInMemoryPaymentRepository()
+	})
+}
@@ -0,0 +1,1225 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"payment-service/internal/entity"
+	"payment-service/internal/usecase"
+	"payment-service/internal/webhook"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+//go:embed migrations/postgres/*.sql
+var postgresMigrations embed.FS
+
+// migrationLockID is the advisory lock key that serializes concurrent migrations
+const migrationLockID = 72_914_003
+
+// uniqueViolation is the PostgreSQL error code for unique constraint violations
+const uniqueViolation = "23505"
+
+// paymentColumns lists the payments table columns in the order scanPayment reads them
+const paymentColumns = `transaction_id, user_id, amount_minor, currency, captured_amount_minor, status, created_at,
+	authorization_expires_at, request_fingerprint, status_history, refunds, payment_method, card_token,
+	processor_reference, processor, merchant_id, queued, version, published_changes, pending_action`
+
+// transferColumns lists the transfers table columns in the order scanTransfer reads them
+const transferColumns = `transaction_id, from_user_id, to_user_id, amount_minor, currency, status, created_at,
+	completed_at, request_fingerprint, version`
+
+// jobColumns lists the payment_jobs table columns in the order scanJob reads them
+const jobColumns = `transaction_id, status, attempts, available_at, last_error, created_at, updated_at`
+
+// endpointColumns lists the webhook_endpoints table columns in the order scanEndpoint reads them
+const endpointColumns = `id, merchant_id, url, secret, event_types, created_at`
+
+// deliveryColumns lists the webhook_deliveries table columns in the order scanDelivery reads them
+const deliveryColumns = `id, endpoint_id, merchant_id, event, status, attempts, next_attempt_at, last_attempt_at,
+	last_status_code, last_error, delivered_at, created_at, leased_until, leases`
+
+// PostgresPaymentRepository implements PaymentRepository, WalletRepository, TransferRepository, JobQueue and
+// webhook.Store using PostgreSQL storage
+type PostgresPaymentRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresPaymentRepository creates a new PostgreSQL payment repository.
+// Call Migrate before first use to make sure the schema is up to date.
+func NewPostgresPaymentRepository(db *sql.DB) *PostgresPaymentRepository {
+	return &PostgresPaymentRepository{
+		db: db,
+	}
+}
+
+// IsPostgresURL reports whether store is a PostgreSQL connection URL
+func IsPostgresURL(store string) bool {
+	return strings.HasPrefix(store, "postgres://") || strings.HasPrefix(store, "postgresql://")
+}
+
+// OpenPostgres opens a PostgreSQL connection pool for the given URL and verifies it is reachable
+func OpenPostgres(url string) (*sql.DB, error) {
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Migrate applies all pending schema migrations in version order
+func (r *PostgresPaymentRepository) Migrate() error {
+	ctx := context.Background()
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Serialize migrations across service instances starting at the same time
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, migrationLockID)
+
+	if _, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    INTEGER PRIMARY KEY,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`); err != nil {
+		return err
+	}
+
+	migrations, err := loadMigrations(postgresMigrations, "migrations/postgres")
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		var applied bool
+		err := conn.QueryRowContext(ctx,
+			`SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, m.version,
+		).Scan(&applied)
+		if err != nil {
+			return err
+		}
+		if applied {
+			continue
+		}
+
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(m.sql); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %s: %w", m.name, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version) VALUES ($1)`, m.version); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %s: %w", m.name, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("migration %s: %w", m.name, err)
+		}
+	}
+
+	return nil
+}
+
+// Store saves a payment to PostgreSQL. The unique constraint on transaction_id
+// guarantees a transaction is never recorded twice, even across service instances.
+func (r *PostgresPaymentRepository) Store(ctx context.Context, payment *entity.Payment) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO payments (`+paymentColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)`,
+		paymentValues(payment)...,
+	)
+	if isUniqueViolation(err) {
+		return usecase.ErrDuplicateTransaction
+	}
+	if err != nil {
+		return err
+	}
+	if err := insertJournalEntries(ctx, tx, payment.Journal); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	payment.Journal = nil
+	return nil
+}
+
+// CreateIfAbsent stores the payment unless its transaction ID already exists,
+// in which case the existing payment is returned. The unique constraint makes the
+// check-and-insert atomic across concurrent requests and service instances, and the
+// payment's journal entries are inserted in the same transaction.
+func (r *PostgresPaymentRepository) CreateIfAbsent(ctx context.Context, payment *entity.Payment) (*entity.Payment, bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO payments (`+paymentColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)
+		ON CONFLICT (transaction_id) DO NOTHING`,
+		paymentValues(payment)...,
+	)
+	if err != nil {
+		return nil, false, err
+	}
+
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return nil, false, err
+	}
+	if inserted == 1 {
+		if err := insertJournalEntries(ctx, tx, payment.Journal); err != nil {
+			return nil, false, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, false, err
+		}
+		payment.Journal = nil
+		return payment, true, nil
+	}
+	tx.Rollback()
+
+	existing, err := r.GetByTransactionID(ctx, payment.TransactionID)
+	if err != nil {
+		return nil, false, err
+	}
+	return existing, false, nil
+}
+
+// Update saves changes to an existing payment if its version matches the stored one.
+// The version check and the write are a single conditional UPDATE, sharing a transaction
+// with the payment's journal entries.
+func (r *PostgresPaymentRepository) Update(ctx context.Context, payment *entity.Payment) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	values := paymentValues(payment)
+	result, err := tx.ExecContext(ctx, `
+		UPDATE payments
+		SET user_id = $2, amount_minor = $3, currency = $4, captured_amount_minor = $5, status = $6,
+			created_at = $7, authorization_expires_at = $8, request_fingerprint = $9, status_history = $10,
+			refunds = $11, payment_method = $12, card_token = $13, processor_reference = $14,
+			processor = $15, merchant_id = $16, queued = $17, version = version + 1,
+			published_changes = GREATEST(published_changes, $19), pending_action = $20
+		WHERE transaction_id = $1 AND version = $18`,
+		values...,
+	)
+	if err != nil {
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		tx.Rollback()
+		if !r.Exists(ctx, payment.TransactionID) {
+			return usecase.ErrPaymentNotFound
+		}
+		return usecase.ErrConcurrentUpdate
+	}
+	if err := insertJournalEntries(ctx, tx, payment.Journal); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	payment.Version++
+	payment.Journal = nil
+	return nil
+}
+
+// GetByTransactionID retrieves a payment by transaction ID
+func (r *PostgresPaymentRepository) GetByTransactionID(ctx context.Context, transactionID string) (*entity.Payment, error) {
+	payment, err := scanPayment(r.db.QueryRowContext(ctx, `
+		SELECT `+paymentColumns+`
+		FROM payments
+		WHERE transaction_id = $1`,
+		transactionID,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+// Exists checks if a payment with the given transaction ID exists
+func (r *PostgresPaymentRepository) Exists(ctx context.Context, transactionID string) bool {
+	var exists bool
+	err := r.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM payments WHERE transaction_id = $1)`,
+		transactionID,
+	).Scan(&exists)
+	if err != nil {
+		return false
+	}
+	return exists
+}
+
+// List returns at most query.Limit payments matching query, newest first.
+// Filters become WHERE conditions, and the cursor a row comparison the listing indexes serve.
+// Transaction IDs compare bytewise, like in the other repositories.
+func (r *PostgresPaymentRepository) List(ctx context.Context, query usecase.PaymentQuery) ([]*entity.Payment, error) {
+	var (
+		conditions []string
+		args       []any
+	)
+	where := func(condition string, values ...any) {
+		for _, value := range values {
+			args = append(args, value)
+			condition = strings.Replace(condition, "?", "$"+strconv.Itoa(len(args)), 1)
+		}
+		conditions = append(conditions, condition)
+	}
+
+	if query.UserID != "" {
+		where("user_id = ?", query.UserID)
+	}
+	if query.Status != "" {
+		where("status = ?", query.Status)
+	}
+	if query.Currency != "" {
+		where("currency = ?", query.Currency)
+	}
+	if query.MinAmount != nil {
+		where("amount_minor >= ?", *query.MinAmount)
+	}
+	if query.MaxAmount != nil {
+		where("amount_minor <= ?", *query.MaxAmount)
+	}
+	if query.CreatedFrom != nil {
+		where("created_at >= ?", *query.CreatedFrom)
+	}
+	if query.CreatedTo != nil {
+		where("created_at < ?", *query.CreatedTo)
+	}
+	if query.Queued {
+		where("queued")
+	}
+	if query.After != nil {
+		where(`(created_at, transaction_id COLLATE "C") < (?, ?)`, query.After.CreatedAt, query.After.TransactionID)
+	}
+
+	statement := `SELECT ` + paymentColumns + ` FROM payments`
+	if len(conditions) > 0 {
+		statement += ` WHERE ` + strings.Join(conditions, ` AND `)
+	}
+	statement += ` ORDER BY created_at DESC, transaction_id COLLATE "C" DESC LIMIT ` + strconv.Itoa(query.Limit)
+
+	rows, err := r.db.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payments []*entity.Payment
+	for rows.Next() {
+		payment, err := scanPayment(rows)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, payment)
+	}
+	return payments, rows.Err()
+}
+
+// GetWallet retrieves a user's wallet
+func (r *PostgresPaymentRepository) GetWallet(ctx context.Context, userID string) (*entity.Wallet, error) {
+	wallet := &entity.Wallet{UserID: userID}
+	var balances, transactions []byte
+	err := r.db.QueryRowContext(ctx, `
+		SELECT balances, transactions, version
+		FROM wallets
+		WHERE user_id = $1`,
+		userID,
+	).Scan(&balances, &transactions, &wallet.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(balances, &wallet.Balances); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(transactions, &wallet.Transactions); err != nil {
+		return nil, err
+	}
+	return wallet, nil
+}
+
+// SaveWallets stores wallets whose versions all match the stored ones, or none of them.
+// Each wallet is a conditional INSERT or UPDATE, and all of them share one transaction.
+func (r *PostgresPaymentRepository) SaveWallets(ctx context.Context, wallets ...*entity.Wallet) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, wallet := range wallets {
+		balances, _ := json.Marshal(wallet.Balances) // plain structs always marshal
+		if wallet.Balances == nil {
+			balances = []byte("{}")
+		}
+		transactions, _ := json.Marshal(wallet.Transactions)
+		if wallet.Transactions == nil {
+			transactions = []byte("[]")
+		}
+
+		var result sql.Result
+		if wallet.Version == 0 {
+			result, err = tx.ExecContext(ctx, `
+				INSERT INTO wallets (user_id, balances, transactions, version)
+				VALUES ($1, $2, $3, 1)
+				ON CONFLICT (user_id) DO NOTHING`,
+				wallet.UserID, string(balances), string(transactions),
+			)
+		} else {
+			result, err = tx.ExecContext(ctx, `
+				UPDATE wallets
+				SET balances = $2, transactions = $3, version = version + 1
+				WHERE user_id = $1 AND version = $4`,
+				wallet.UserID, string(balances), string(transactions), wallet.Version,
+			)
+		}
+		if err != nil {
+			return err
+		}
+		written, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if written == 0 {
+			return usecase.ErrConcurrentUpdate
+		}
+		if err := insertJournalEntries(ctx, tx, wallet.Journal); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	for _, wallet := range wallets {
+		wallet.Version++
+		wallet.Journal = nil
+	}
+	return nil
+}
+
+// CreateTransferIfAbsent stores the transfer unless its transaction ID already exists,
+// in which case the existing transfer is returned. The primary key makes the
+// check-and-insert atomic across concurrent requests and service instances.
+func (r *PostgresPaymentRepository) CreateTransferIfAbsent(ctx context.Context, transfer *entity.Transfer) (*entity.Transfer, bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO transfers (`+transferColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (transaction_id) DO NOTHING`,
+		transferValues(transfer)...,
+	)
+	if err != nil {
+		return nil, false, err
+	}
+
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return nil, false, err
+	}
+	if inserted == 1 {
+		return transfer, true, nil
+	}
+
+	existing, err := r.GetTransfer(ctx, transfer.TransactionID)
+	if err != nil {
+		return nil, false, err
+	}
+	return existing, false, nil
+}
+
+// UpdateTransfer saves changes to an existing transfer if its version matches the stored one.
+// The version check and the write are a single conditional UPDATE.
+func (r *PostgresPaymentRepository) UpdateTransfer(ctx context.Context, transfer *entity.Transfer) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE transfers
+		SET from_user_id = $2, to_user_id = $3, amount_minor = $4, currency = $5, status = $6,
+			created_at = $7, completed_at = $8, request_fingerprint = $9, version = version + 1
+		WHERE transaction_id = $1 AND version = $10`,
+		transferValues(transfer)...,
+	)
+	if err != nil {
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		existing, err := r.GetTransfer(ctx, transfer.TransactionID)
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			return usecase.ErrTransferNotFound
+		}
+		return usecase.ErrConcurrentUpdate
+	}
+
+	transfer.Version++
+	return nil
+}
+
+// GetTransfer retrieves a transfer by transaction ID
+func (r *PostgresPaymentRepository) GetTransfer(ctx context.Context, transactionID string) (*entity.Transfer, error) {
+	transfer := &entity.Transfer{}
+	var completedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, `
+		SELECT `+transferColumns+`
+		FROM transfers
+		WHERE transaction_id = $1`,
+		transactionID,
+	).Scan(
+		&transfer.TransactionID,
+		&transfer.FromUserID,
+		&transfer.ToUserID,
+		&transfer.Amount.Amount,
+		&transfer.Amount.Currency,
+		&transfer.Status,
+		&transfer.CreatedAt,
+		&completedAt,
+		&transfer.RequestFingerprint,
+		&transfer.Version,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if completedAt.Valid {
+		transfer.CompletedAt = &completedAt.Time
+	}
+	return transfer, nil
+}
+
+// EnqueueJob adds a job for the transaction unless there already is one
+func (r *PostgresPaymentRepository) EnqueueJob(ctx context.Context, transactionID string, now time.Time) error {
+	job := newJob(transactionID, now)
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO payment_jobs (`+jobColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (transaction_id) DO NOTHING`,
+		job.TransactionID, job.Status, job.Attempts, job.AvailableAt, job.LastError, job.CreatedAt, job.UpdatedAt,
+	)
+	return err
+}
+
+// LeaseJob delivers the job that has been available longest, hiding it until leaseUntil.
+// SKIP LOCKED lets workers in other processes lease the next jobs concurrently.
+func (r *PostgresPaymentRepository) LeaseJob(ctx context.Context, now, leaseUntil time.Time) (*entity.Job, error) {
+	job, err := scanJob(r.db.QueryRowContext(ctx, `
+		UPDATE payment_jobs
+		SET attempts = attempts + 1, available_at = $2, updated_at = $1
+		WHERE transaction_id = (
+			SELECT transaction_id FROM payment_jobs
+			WHERE status = 'queued' AND available_at <= $1
+			ORDER BY available_at, transaction_id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+jobColumns,
+		now, leaseUntil,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return job, err
+}
+
+// CompleteJob removes the job leased by attempt
+func (r *PostgresPaymentRepository) CompleteJob(ctx context.Context, transactionID string, attempt int) error {
+	return r.changeLeasedJob(ctx, `
+		DELETE FROM payment_jobs
+		WHERE transaction_id = $1 AND attempts = $2 AND status = 'queued'`,
+		transactionID, attempt,
+	)
+}
+
+// RetryJob makes the job leased by attempt available again at retryAt
+func (r *PostgresPaymentRepository) RetryJob(ctx context.Context, transactionID string, attempt int, lastError string, retryAt, now time.Time) error {
+	return r.changeLeasedJob(ctx, `
+		UPDATE payment_jobs
+		SET available_at = $3, last_error = $4, updated_at = $5
+		WHERE transaction_id = $1 AND attempts = $2 AND status = 'queued'`,
+		transactionID, attempt, retryAt, lastError, now,
+	)
+}
+
+// ReleaseJob hands the job leased by attempt back at now, without counting the attempt
+func (r *PostgresPaymentRepository) ReleaseJob(ctx context.Context, transactionID string, attempt int, lastError string, now time.Time) error {
+	return r.changeLeasedJob(ctx, `
+		UPDATE payment_jobs
+		SET attempts = attempts - 1, available_at = $3, last_error = $4, updated_at = $3
+		WHERE transaction_id = $1 AND attempts = $2 AND status = 'queued'`,
+		transactionID, attempt, now, lastError,
+	)
+}
+
+// BuryJob moves the job leased by attempt to the dead-letter queue
+func (r *PostgresPaymentRepository) BuryJob(ctx context.Context, transactionID string, attempt int, lastError string, now time.Time) error {
+	return r.changeLeasedJob(ctx, `
+		UPDATE payment_jobs
+		SET status = 'dead', last_error = $3, updated_at = $4
+		WHERE transaction_id = $1 AND attempts = $2 AND status = 'queued'`,
+		transactionID, attempt, lastError, now,
+	)
+}
+
+// DeadJobs lists the dead-lettered jobs, oldest first
+func (r *PostgresPaymentRepository) DeadJobs(ctx context.Context) ([]*entity.Job, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+jobColumns+`
+		FROM payment_jobs
+		WHERE status = 'dead'
+		ORDER BY updated_at, transaction_id`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dead := []*entity.Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		dead = append(dead, job)
+	}
+	return dead, rows.Err()
+}
+
+// CountJobs counts the jobs available, delayed and dead at now
+func (r *PostgresPaymentRepository) CountJobs(ctx context.Context, now time.Time) (*entity.JobCounts, error) {
+	counts := &entity.JobCounts{}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT
+			count(*) FILTER (WHERE status = 'queued' AND available_at <= $1),
+			count(*) FILTER (WHERE status = 'queued' AND available_at > $1),
+			count(*) FILTER (WHERE status = 'dead')
+		FROM payment_jobs`,
+		now,
+	).Scan(&counts.Available, &counts.Delayed, &counts.Dead)
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// RequeueJob makes a dead-lettered job available at now with no attempts
+func (r *PostgresPaymentRepository) RequeueJob(ctx context.Context, transactionID string, now time.Time) (*entity.Job, error) {
+	job, err := scanJob(r.db.QueryRowContext(ctx, `
+		UPDATE payment_jobs
+		SET status = 'queued', attempts = 0, available_at = $2, updated_at = $2
+		WHERE transaction_id = $1 AND status = 'dead'
+		RETURNING `+jobColumns,
+		transactionID, now,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, usecase.ErrJobNotFound
+	}
+	return job, err
+}
+
+// JournalEntries returns every journal entry recorded with a payment or wallet, oldest first
+func (r *PostgresPaymentRepository) JournalEntries(ctx context.Context) ([]entity.JournalEntry, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, description, posted_at, postings
+		FROM journal_entries
+		ORDER BY posted_at, id`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []entity.JournalEntry{}
+	for rows.Next() {
+		var entry entity.JournalEntry
+		var postings []byte
+		if err := rows.Scan(&entry.ID, &entry.Description, &entry.PostedAt, &postings); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(postings, &entry.Postings); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// UnpublishedPayments returns at most limit payments with changes not yet published, oldest first
+func (r *PostgresPaymentRepository) UnpublishedPayments(ctx context.Context, limit int) ([]*entity.Payment, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+paymentColumns+`
+		FROM payments
+		WHERE published_changes < jsonb_array_length(status_history)
+		ORDER BY created_at, transaction_id
+		LIMIT $1`,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	payments := []*entity.Payment{}
+	for rows.Next() {
+		payment, err := scanPayment(rows)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, payment)
+	}
+	return payments, rows.Err()
+}
+
+// MarkChangesPublished records that the first published changes of a payment have been published.
+// It leaves the version alone, so it never makes a concurrent update fail.
+func (r *PostgresPaymentRepository) MarkChangesPublished(ctx context.Context, transactionID string, published int) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE payments
+		SET published_changes = GREATEST(published_changes, $2)
+		WHERE transaction_id = $1`,
+		transactionID, published,
+	)
+	if err != nil {
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return usecase.ErrPaymentNotFound
+	}
+	return nil
+}
+
+// SaveWebhookEndpoint stores a new webhook endpoint
+func (r *PostgresPaymentRepository) SaveWebhookEndpoint(ctx context.Context, endpoint *webhook.Endpoint) error {
+	eventTypes, _ := json.Marshal(endpoint.EventTypes) // plain strings always marshal
+	if endpoint.EventTypes == nil {
+		eventTypes = []byte("[]")
+	}
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO webhook_endpoints (`+endpointColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		endpoint.ID, endpoint.MerchantID, endpoint.URL, endpoint.Secret, string(eventTypes), endpoint.CreatedAt,
+	)
+	return err
+}
+
+// WebhookEndpoints lists a merchant's webhook endpoints, oldest first
+func (r *PostgresPaymentRepository) WebhookEndpoints(ctx context.Context, merchantID string) ([]*webhook.Endpoint, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+endpointColumns+`
+		FROM webhook_endpoints
+		WHERE merchant_id = $1
+		ORDER BY created_at, id`,
+		merchantID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	endpoints := []*webhook.Endpoint{}
+	for rows.Next() {
+		endpoint, err := scanEndpoint(rows)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints, rows.Err()
+}
+
+// AddWebhookDeliveries stores the deliveries whose IDs are not stored yet, all or none of them
+func (r *PostgresPaymentRepository) AddWebhookDeliveries(ctx context.Context, deliveries []*webhook.Delivery) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, delivery := range deliveries {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO webhook_deliveries (`+deliveryColumns+`)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+			ON CONFLICT (id) DO NOTHING`,
+			deliveryValues(delivery)...,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// WebhookDeliveries lists a merchant's webhook deliveries, newest first, optionally only those with a status
+func (r *PostgresPaymentRepository) WebhookDeliveries(ctx context.Context, merchantID, status string) ([]*webhook.Delivery, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+deliveryColumns+`
+		FROM webhook_deliveries
+		WHERE merchant_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC, id DESC`,
+		merchantID, status,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanDeliveries(rows)
+}
+
+// LeaseWebhookDeliveries leases up to limit pending webhook deliveries due at now until leaseUntil,
+// those due longest first. SKIP LOCKED lets dispatchers in other processes lease other deliveries concurrently.
+func (r *PostgresPaymentRepository) LeaseWebhookDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*webhook.Delivery, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		UPDATE webhook_deliveries
+		SET next_attempt_at = $2, leased_until = $2, leases = leases + 1
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at, id
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+deliveryColumns,
+		now, leaseUntil, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanDeliveries(rows)
+}
+
+// SaveWebhookAttempt stores the outcome of an attempt if it still holds the delivery's lease
+func (r *PostgresPaymentRepository) SaveWebhookAttempt(ctx context.Context, delivery *webhook.Delivery) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $3, attempts = $4, next_attempt_at = $5, last_attempt_at = $6, last_status_code = $7,
+			last_error = $8, delivered_at = $9, leased_until = NULL
+		WHERE id = $1 AND leases = $2 AND leased_until IS NOT NULL`,
+		delivery.ID, delivery.Leases, delivery.Status, delivery.Attempts, delivery.NextAttemptAt, delivery.LastAttemptAt,
+		delivery.LastStatusCode, delivery.LastError, delivery.DeliveredAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	changed, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if changed == 0 {
+		return webhook.ErrDeliveryLeaseLost
+	}
+	return nil
+}
+
+// ReplayWebhookDelivery makes a merchant's webhook delivery pending at now with no attempts
+func (r *PostgresPaymentRepository) ReplayWebhookDelivery(ctx context.Context, merchantID, deliveryID string, now time.Time) (*webhook.Delivery, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	delivery, err := scanDelivery(tx.QueryRowContext(ctx, `
+		SELECT `+deliveryColumns+`
+		FROM webhook_deliveries
+		WHERE id = $1
+		FOR UPDATE`,
+		deliveryID,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, webhook.ErrDeliveryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := replayable(delivery, merchantID, now); err != nil {
+		return nil, err
+	}
+
+	delivery.Replay(now)
+	_, err = tx.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, next_attempt_at = $4, delivered_at = NULL, leased_until = NULL, leases = $5
+		WHERE id = $1`,
+		delivery.ID, delivery.Status, delivery.Attempts, delivery.NextAttemptAt, delivery.Leases,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// insertJournalEntries records journal entries in tx, skipping those whose IDs are recorded already
+func insertJournalEntries(ctx context.Context, tx *sql.Tx, entries []entity.JournalEntry) error {
+	for _, entry := range entries {
+		postings, _ := json.Marshal(entry.Postings) // plain structs always marshal
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO journal_entries (id, description, posted_at, postings)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (id) DO NOTHING`,
+			entry.ID, entry.Description, entry.PostedAt, string(postings),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// changeLeasedJob runs a statement that changes a job only while it is leased by the given attempt
+func (r *PostgresPaymentRepository) changeLeasedJob(ctx context.Context, query string, args ...any) error {
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	changed, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if changed == 0 {
+		return usecase.ErrJobLeaseLost
+	}
+	return nil
+}
+
+// scanJob reads a job selected with jobColumns
+func scanJob(row rowScanner) (*entity.Job, error) {
+	job := &entity.Job{}
+	err := row.Scan(
+		&job.TransactionID,
+		&job.Status,
+		&job.Attempts,
+		&job.AvailableAt,
+		&job.LastError,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// scanEndpoint reads a webhook endpoint selected with endpointColumns
+func scanEndpoint(row rowScanner) (*webhook.Endpoint, error) {
+	endpoint := &webhook.Endpoint{}
+	var eventTypes []byte
+	err := row.Scan(
+		&endpoint.ID,
+		&endpoint.MerchantID,
+		&endpoint.URL,
+		&endpoint.Secret,
+		&eventTypes,
+		&endpoint.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(eventTypes, &endpoint.EventTypes); err != nil {
+		return nil, err
+	}
+	if len(endpoint.EventTypes) == 0 {
+		endpoint.EventTypes = nil
+	}
+	return endpoint, nil
+}
+
+// scanDelivery reads a webhook delivery selected with deliveryColumns
+func scanDelivery(row rowScanner) (*webhook.Delivery, error) {
+	delivery := &webhook.Delivery{}
+	var (
+		event                                                  []byte
+		nextAttemptAt, lastAttemptAt, deliveredAt, leasedUntil sql.NullTime
+	)
+	err := row.Scan(
+		&delivery.ID,
+		&delivery.EndpointID,
+		&delivery.MerchantID,
+		&event,
+		&delivery.Status,
+		&delivery.Attempts,
+		&nextAttemptAt,
+		&lastAttemptAt,
+		&delivery.LastStatusCode,
+		&delivery.LastError,
+		&deliveredAt,
+		&delivery.CreatedAt,
+		&leasedUntil,
+		&delivery.Leases,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(event, &delivery.Event); err != nil {
+		return nil, err
+	}
+	delivery.NextAttemptAt = nullTime(nextAttemptAt)
+	delivery.LastAttemptAt = nullTime(lastAttemptAt)
+	delivery.DeliveredAt = nullTime(deliveredAt)
+	delivery.LeasedUntil = nullTime(leasedUntil)
+	return delivery, nil
+}
+
+// scanDeliveries reads and closes rows of webhook deliveries selected with deliveryColumns
+func scanDeliveries(rows *sql.Rows) ([]*webhook.Delivery, error) {
+	defer rows.Close()
+
+	deliveries := []*webhook.Delivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// deliveryValues returns the webhook delivery fields in deliveryColumns order
+func deliveryValues(delivery *webhook.Delivery) []any {
+	event, _ := json.Marshal(delivery.Event) // plain structs always marshal
+	return []any{
+		delivery.ID,
+		delivery.EndpointID,
+		delivery.MerchantID,
+		string(event),
+		delivery.Status,
+		delivery.Attempts,
+		delivery.NextAttemptAt,
+		delivery.LastAttemptAt,
+		delivery.LastStatusCode,
+		delivery.LastError,
+		delivery.DeliveredAt,
+		delivery.CreatedAt,
+		delivery.LeasedUntil,
+		delivery.Leases,
+	}
+}
+
+// nullTime returns the time of a nullable column, or nil if it is NULL
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// transferValues returns the transfer fields in transferColumns order
+func transferValues(transfer *entity.Transfer) []any {
+	return []any{
+		transfer.TransactionID,
+		transfer.FromUserID,
+		transfer.ToUserID,
+		transfer.Amount.Amount,
+		transfer.Amount.Currency,
+		transfer.Status,
+		transfer.CreatedAt,
+		transfer.CompletedAt,
+		transfer.RequestFingerprint,
+		transfer.Version,
+	}
+}
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanPayment reads a payment selected with paymentColumns
+func scanPayment(row rowScanner) (*entity.Payment, error) {
+	payment := &entity.Payment{}
+	var (
+		history   []byte
+		refunds   []byte
+		expiresAt sql.NullTime
+		action    []byte
+	)
+	err := row.Scan(
+		&payment.TransactionID,
+		&payment.UserID,
+		&payment.Amount.Amount,
+		&payment.Amount.Currency,
+		&payment.CapturedAmount.Amount,
+		&payment.Status,
+		&payment.CreatedAt,
+		&expiresAt,
+		&payment.RequestFingerprint,
+		&history,
+		&refunds,
+		&payment.PaymentMethod,
+		&payment.CardToken,
+		&payment.ProcessorReference,
+		&payment.Processor,
+		&payment.MerchantID,
+		&payment.Queued,
+		&payment.Version,
+		&payment.PublishedChanges,
+		&action,
+	)
+	if err != nil {
+		return nil, err
+	}
+	payment.CapturedAmount.Currency = payment.Amount.Currency
+	payment.SetCardToken(payment.CardToken)
+	if expiresAt.Valid {
+		payment.AuthorizationExpiresAt = &expiresAt.Time
+	}
+	if err := json.Unmarshal(history, &payment.StatusHistory); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(refunds, &payment.Refunds); err != nil {
+		return nil, err
+	}
+	if action != nil {
+		payment.PendingAction = &entity.PendingAction{}
+		if err := json.Unmarshal(action, payment.PendingAction); err != nil {
+			return nil, err
+		}
+	}
+	return payment, nil
+}
+
+// paymentValues returns the payment fields in paymentColumns order
+func paymentValues(payment *entity.Payment) []any {
+	history, _ := json.Marshal(payment.StatusHistory) // plain structs always marshal
+	if payment.StatusHistory == nil {
+		history = []byte("[]")
+	}
+	refunds, _ := json.Marshal(payment.Refunds)
+	if payment.Refunds == nil {
+		refunds = []byte("[]")
+	}
+	var action *string
+	if payment.PendingAction != nil {
+		data, _ := json.Marshal(payment.PendingAction)
+		claimed := string(data)
+		action = &claimed
+	}
+	return []any{
+		payment.TransactionID,
+		payment.UserID,
+		payment.Amount.Amount,
+		payment.Amount.Currency,
+		payment.CapturedAmount.Amount,
+		payment.Status,
+		payment.CreatedAt,
+		payment.AuthorizationExpiresAt,
+		payment.RequestFingerprint,
+		string(history), // lib/pq would send []byte as bytea
+		string(refunds),
+		payment.PaymentMethod,
+		payment.CardToken,
+		payment.ProcessorReference,
+		payment.Processor,
+		payment.MerchantID,
+		payment.Queued,
+		payment.Version,
+		payment.PublishedChanges,
+		action,
+	}
+}
+
+// isUniqueViolation reports whether err is a PostgreSQL unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation
+}
+
+// migration is a single versioned schema change
+type migration struct {
+	version int
+	name    string
+	sql     string
+}
+
+// loadMigrations reads NNNN_description.sql files from dir, sorted by version
+func loadMigrations(fsys fs.FS, dir string) ([]migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []migration
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".sql") {
+			continue
+		}
+		prefix, _, _ := strings.Cut(e.Name(), "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: invalid version prefix", e.Name())
+		}
+		content, err := fs.ReadFile(fsys, dir+"/"+e.Name())
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, migration{version: version, name: e.Name(), sql: string(content)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+	return migrations, nil
+}
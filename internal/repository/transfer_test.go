@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"payment-service/internal/entity"
+	"payment-service/internal/usecase"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testTransferRepository runs the behaviour every TransferRepository implementation must satisfy.
+// newRepo must return an empty repository.
+func testTransferRepository(t *testing.T, newRepo func(t *testing.T) usecase.TransferRepository) {
+	newTransfer := func() *entity.Transfer {
+		return &entity.Transfer{
+			TransactionID:      "txn123",
+			FromUserID:         "user123",
+			ToUserID:           "user456",
+			Amount:             entity.Money{Amount: 2500, Currency: "USD"},
+			Status:             entity.TransferPending,
+			CreatedAt:          time.Now().UTC().Truncate(time.Microsecond),
+			RequestFingerprint: "fingerprint",
+		}
+	}
+
+	t.Run("CreateAndGet", func(t *testing.T) {
+		// Arrange
+		repo := newRepo(t)
+		transfer := newTransfer()
+
+		// Act
+		stored, created, err := repo.CreateTransferIfAbsent(context.Background(), transfer)
+
+		// Assert
+		require.NoError(t, err)
+		assert.True(t, created)
+		assert.Same(t, transfer, stored)
+		got, err := repo.GetTransfer(context.Background(), "txn123")
+		require.NoError(t, err)
+		require.NotNil(t, got)
+		assert.Equal(t, transfer.FromUserID, got.FromUserID)
+		assert.Equal(t, transfer.ToUserID, got.ToUserID)
+		assert.Equal(t, transfer.Amount, got.Amount)
+		assert.Equal(t, entity.TransferPending, got.Status)
+		assert.True(t, transfer.CreatedAt.Equal(got.CreatedAt))
+		assert.Nil(t, got.CompletedAt)
+		assert.Equal(t, "fingerprint", got.RequestFingerprint)
+	})
+
+	t.Run("CreateExisting", func(t *testing.T) {
+		// Arrange
+		repo := newRepo(t)
+		_, _, err := repo.CreateTransferIfAbsent(context.Background(), newTransfer())
+		require.NoError(t, err)
+		duplicate := newTransfer()
+		duplicate.ToUserID = "user789"
+
+		// Act
+		stored, created, err := repo.CreateTransferIfAbsent(context.Background(), duplicate)
+
+		// Assert
+		require.NoError(t, err)
+		assert.False(t, created)
+		assert.Equal(t, "user456", stored.ToUserID)
+	})
+
+	t.Run("GetMissing", func(t *testing.T) {
+		// Arrange
+		repo := newRepo(t)
+
+		// Act
+		transfer, err := repo.GetTransfer(context.Background(), "missing")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Nil(t, transfer)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		// Arrange
+		repo := newRepo(t)
+		transfer := newTransfer()
+		_, _, err := repo.CreateTransferIfAbsent(context.Background(), transfer)
+		require.NoError(t, err)
+		completedAt := transfer.CreatedAt.Add(time.Second)
+		transfer.Status = entity.TransferCompleted
+		transfer.CompletedAt = &completedAt
+
+		// Act
+		err = repo.UpdateTransfer(context.Background(), transfer)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), transfer.Version)
+		stored, err := repo.GetTransfer(context.Background(), "txn123")
+		require.NoError(t, err)
+		assert.Equal(t, entity.TransferCompleted, stored.Status)
+		require.NotNil(t, stored.CompletedAt)
+		assert.True(t, completedAt.Equal(*stored.CompletedAt))
+		assert.Equal(t, int64(1), stored.Version)
+	})
+
+	t.Run("UpdateStaleVersion", func(t *testing.T) {
+		// Arrange
+		repo := newRepo(t)
+		_, _, err := repo.CreateTransferIfAbsent(context.Background(), newTransfer())
+		require.NoError(t, err)
+		first, err := repo.GetTransfer(context.Background(), "txn123")
+		require.NoError(t, err)
+		second, err := repo.GetTransfer(context.Background(), "txn123")
+		require.NoError(t, err)
+		first.Status = entity.TransferCompleted
+		require.NoError(t, repo.UpdateTransfer(context.Background(), first))
+
+		// Act
+		second.Status = entity.TransferDeclined
+		err = repo.UpdateTransfer(context.Background(), second)
+
+		// Assert
+		assert.ErrorIs(t, err, usecase.ErrConcurrentUpdate)
+		stored, err := repo.GetTransfer(context.Background(), "txn123")
+		require.NoError(t, err)
+		assert.Equal(t, entity.TransferCompleted, stored.Status)
+	})
+
+	t.Run("UpdateMissing", func(t *testing.T) {
+		// Arrange
+		repo := newRepo(t)
+
+		// Act
+		err := repo.UpdateTransfer(context.Background(), newTransfer())
+
+		// Assert
+		assert.ErrorIs(t, err, usecase.ErrTransferNotFound)
+	})
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"payment-service/internal/entity"
+	"payment-service/internal/usecase"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testWalletRepository runs the behaviour every WalletRepository implementation must satisfy.
+// newRepo must return an empty repository.
+func testWalletRepository(t *testing.T, newRepo func(t *testing.T) usecase.WalletRepository) {
+	usd := func(amount int64) entity.Money { return entity.Money{Amount: amount, Currency: "USD"} }
+
+	t.Run("SaveAndGet", func(t *testing.T) {
+		// Arrange
+		repo := newRepo(t)
+		now := time.Now().UTC().Truncate(time.Microsecond)
+		wallet := entity.NewWallet("user123")
+		wallet.Credit("top-up:1", entity.WalletTopUp, "", usd(5000), now)
+
+		// Act
+		err := repo.SaveWallets(context.Background(), wallet)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), wallet.Version)
+		stored, err := repo.GetWallet(context.Background(), "user123")
+		require.NoError(t, err)
+		require.NotNil(t, stored)
+		assert.Equal(t, usd(5000), stored.Balance("USD"))
+		assert.Equal(t, int64(1), stored.Version)
+		require.Len(t, stored.Transactions, 1)
+		assert.Equal(t, "top-up:1", stored.Transactions[0].ID)
+		assert.True(t, now.Equal(stored.Transactions[0].CreatedAt))
+	})
+
+	t.Run("GetMissing", func(t *testing.T) {
+		// Arrange
+		repo := newRepo(t)
+
+		// Act
+		wallet, err := repo.GetWallet(context.Background(), "missing")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Nil(t, wallet)
+	})
+
+	t.Run("StaleVersion", func(t *testing.T) {
+		// Arrange
+		repo := newRepo(t)
+		require.NoError(t, repo.SaveWallets(context.Background(), entity.NewWallet("user123")))
+		first, err := repo.GetWallet(context.Background(), "user123")
+		require.NoError(t, err)
+		second, err := repo.GetWallet(context.Background(), "user123")
+		require.NoError(t, err)
+		first.Credit("top-up:1", entity.WalletTopUp, "", usd(100), time.Now())
+		require.NoError(t, repo.SaveWallets(context.Background(), first))
+
+		// Act
+		second.Credit("top-up:2", entity.WalletTopUp, "", usd(200), time.Now())
+		err = repo.SaveWallets(context.Background(), second)
+
+		// Assert
+		assert.ErrorIs(t, err, usecase.ErrConcurrentUpdate)
+		stored, err := repo.GetWallet(context.Background(), "user123")
+		require.NoError(t, err)
+		assert.Equal(t, usd(100), stored.Balance("USD"))
+	})
+
+	t.Run("CreateExisting", func(t *testing.T) {
+		// Arrange
+		repo := newRepo(t)
+		require.NoError(t, repo.SaveWallets(context.Background(), entity.NewWallet("user123")))
+
+		// Act
+		err := repo.SaveWallets(context.Background(), entity.NewWallet("user123"))
+
+		// Assert
+		assert.ErrorIs(t, err, usecase.ErrConcurrentUpdate)
+	})
+
+	t.Run("SaveIsAllOrNothing", func(t *testing.T) {
+		// Arrange
+		repo := newRepo(t)
+		require.NoError(t, repo.SaveWallets(context.Background(), entity.NewWallet("user456")))
+		payer := entity.NewWallet("user123")
+		payer.Credit("top-up:1", entity.WalletTopUp, "", usd(100), time.Now())
+		stale := entity.NewWallet("user456") // Version 0, but user456 already has a wallet
+
+		// Act
+		err := repo.SaveWallets(context.Background(), payer, stale)
+
+		// Assert
+		assert.ErrorIs(t, err, usecase.ErrConcurrentUpdate)
+		stored, err := repo.GetWallet(context.Background(), "user123")
+		require.NoError(t, err)
+		assert.Nil(t, stored, "no wallet may be written when any version check fails")
+	})
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"payment-service/internal/webhook"
+	"time"
+)
+
+// registeredBefore orders endpoints oldest first, by when they were registered
+func registeredBefore(a, b *webhook.Endpoint) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.ID < b.ID
+}
+
+// publishedAfter orders deliveries newest first, by when they were created
+func publishedAfter(a, b *webhook.Delivery) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.After(b.CreatedAt)
+	}
+	return a.ID > b.ID
+}
+
+// dueBefore orders deliveries by how long they have been due, those due longest first
+func dueBefore(a, b *webhook.Delivery) bool {
+	if !a.NextAttemptAt.Equal(*b.NextAttemptAt) {
+		return a.NextAttemptAt.Before(*b.NextAttemptAt)
+	}
+	return a.ID < b.ID
+}
+
+// replayable finds a merchant's delivery to replay at now
+func replayable(delivery *webhook.Delivery, merchantID string, now time.Time) error {
+	if delivery == nil || delivery.MerchantID != merchantID {
+		return webhook.ErrDeliveryNotFound
+	}
+	if delivery.Leased(now) {
+		return webhook.ErrDeliveryInFlight
+	}
+	return nil
+}
+
+// holdsLease reports whether an attempt of a delivery still holds the lease of the stored delivery
+func holdsLease(stored, attempt *webhook.Delivery) bool {
+	return stored != nil && stored.LeasedUntil != nil && stored.Leases == attempt.Leases
+}
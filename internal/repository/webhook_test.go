@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"context"
+	"payment-service/internal/usecase"
+	"payment-service/internal/webhook"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testWebhookStore runs the behaviour every webhook.Store implementation must satisfy.
+// newStore must return an empty store.
+func testWebhookStore(t *testing.T, newStore func(t *testing.T) webhook.Store) {
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	endpoint := func(t *testing.T, store webhook.Store, id, merchantID string, createdAt time.Time) *webhook.Endpoint {
+		endpoint := &webhook.Endpoint{ID: id, MerchantID: merchantID, URL: "https://merchant.example.com/hooks", Secret: "whsec_" + id, CreatedAt: createdAt}
+		require.NoError(t, store.SaveWebhookEndpoint(ctx, endpoint))
+		return endpoint
+	}
+	delivery := func(id string, endpoint *webhook.Endpoint, createdAt time.Time) *webhook.Delivery {
+		return &webhook.Delivery{
+			ID:         id,
+			EndpointID: endpoint.ID,
+			MerchantID: endpoint.MerchantID,
+			Event: usecase.PaymentEvent{
+				ID:        "evt_" + id,
+				Type:      "payment.captured",
+				CreatedAt: createdAt,
+				Data:      usecase.PaymentEventData{TransactionID: "txn123", MerchantID: endpoint.MerchantID, Amount: "100.00", Currency: "USD", Status: "captured"},
+			},
+			Status:        webhook.DeliveryPending,
+			NextAttemptAt: &createdAt,
+			CreatedAt:     createdAt,
+		}
+	}
+	ids := func(deliveries []*webhook.Delivery) []string {
+		ids := []string{}
+		for _, delivery := range deliveries {
+			ids = append(ids, delivery.ID)
+		}
+		return ids
+	}
+
+	t.Run("EndpointsByMerchant", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		endpoint(t, store, "we_2", "merchant-1", start.Add(time.Second))
+		endpoint(t, store, "we_3", "merchant-2", start)
+		first := &webhook.Endpoint{ID: "we_1", MerchantID: "merchant-1", URL: "https://merchant.example.com/refunds", Secret: "whsec_1", EventTypes: []string{"payment.refunded"}, CreatedAt: start}
+		require.NoError(t, store.SaveWebhookEndpoint(ctx, first))
+
+		// Act
+		endpoints, err := store.WebhookEndpoints(ctx, "merchant-1")
+		none, noneErr := store.WebhookEndpoints(ctx, "merchant-3")
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, endpoints, 2)
+		assert.Equal(t, "we_1", endpoints[0].ID, "oldest first")
+		assert.Equal(t, first.URL, endpoints[0].URL)
+		assert.Equal(t, "whsec_1", endpoints[0].Secret, "the dispatcher signs with the stored secret")
+		assert.Equal(t, []string{"payment.refunded"}, endpoints[0].EventTypes)
+		assert.True(t, start.Equal(endpoints[0].CreatedAt))
+		assert.Equal(t, "we_2", endpoints[1].ID)
+		assert.Nil(t, endpoints[1].EventTypes)
+		require.NoError(t, noneErr)
+		assert.NotNil(t, none)
+		assert.Empty(t, none)
+	})
+
+	t.Run("AddDeliveriesOnce", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		target := endpoint(t, store, "we_1", "merchant-1", start)
+		require.NoError(t, store.AddWebhookDeliveries(ctx, []*webhook.Delivery{delivery("wd_1", target, start)}))
+		again := delivery("wd_1", target, start)
+		again.Status = webhook.DeliveryDead
+
+		// Act
+		err := store.AddWebhookDeliveries(ctx, []*webhook.Delivery{again, delivery("wd_2", target, start.Add(time.Second))})
+
+		// Assert
+		require.NoError(t, err)
+		all, err := store.WebhookDeliveries(ctx, "merchant-1", "")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"wd_2", "wd_1"}, ids(all), "newest first")
+		assert.Equal(t, webhook.DeliveryPending, all[1].Status, "adding a stored delivery again changes nothing")
+		assert.Equal(t, "evt_wd_1", all[1].Event.ID)
+		assert.Equal(t, "txn123", all[1].Event.Data.TransactionID)
+		dead, err := store.WebhookDeliveries(ctx, "merchant-1", webhook.DeliveryDead)
+		require.NoError(t, err)
+		assert.Empty(t, dead)
+		other, err := store.WebhookDeliveries(ctx, "merchant-2", "")
+		require.NoError(t, err)
+		assert.Empty(t, other)
+	})
+
+	t.Run("LeaseDueDeliveries", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		target := endpoint(t, store, "we_1", "merchant-1", start)
+		require.NoError(t, store.AddWebhookDeliveries(ctx, []*webhook.Delivery{
+			delivery("wd_1", target, start),
+			delivery("wd_2", target, start.Add(time.Hour)),
+		}))
+		now := start.Add(time.Minute)
+
+		// Act
+		leased, err := store.LeaseWebhookDeliveries(ctx, now, now.Add(30*time.Second), 10)
+		during, duringErr := store.LeaseWebhookDeliveries(ctx, now.Add(10*time.Second), now.Add(40*time.Second), 10)
+		after, afterErr := store.LeaseWebhookDeliveries(ctx, now.Add(30*time.Second), now.Add(time.Minute), 10)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, []string{"wd_1"}, ids(leased), "wd_2 is not due yet")
+		assert.Equal(t, 1, leased[0].Leases)
+		assert.True(t, leased[0].Leased(now))
+		require.NoError(t, duringErr)
+		assert.Empty(t, during, "a leased delivery is hidden until its lease runs out")
+		require.NoError(t, afterErr)
+		require.Len(t, after, 1)
+		assert.Equal(t, 2, after[0].Leases, "a lease that ran out is handed to the next dispatcher")
+	})
+
+	t.Run("LeaseBoundedBatch", func(t *testing.T) {
+		// Arrange: a backlog of three due deliveries
+		store := newStore(t)
+		target := endpoint(t, store, "we_1", "merchant-1", start)
+		require.NoError(t, store.AddWebhookDeliveries(ctx, []*webhook.Delivery{
+			delivery("wd_3", target, start),
+			delivery("wd_1", target, start.Add(2*time.Second)),
+			delivery("wd_2", target, start.Add(time.Second)),
+		}))
+		now := start.Add(time.Minute)
+
+		// Act
+		first, firstErr := store.LeaseWebhookDeliveries(ctx, now, now.Add(time.Minute), 2)
+		rest, restErr := store.LeaseWebhookDeliveries(ctx, now, now.Add(time.Minute), 2)
+
+		// Assert: those due longest are leased first, no more than the limit at a time
+		require.NoError(t, firstErr)
+		assert.Equal(t, []string{"wd_3", "wd_2"}, ids(first))
+		require.NoError(t, restErr)
+		assert.Equal(t, []string{"wd_1"}, ids(rest))
+	})
+
+	t.Run("AttemptsNeedTheLease", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		target := endpoint(t, store, "we_1", "merchant-1", start)
+		require.NoError(t, store.AddWebhookDeliveries(ctx, []*webhook.Delivery{delivery("wd_1", target, start)}))
+		leased, err := store.LeaseWebhookDeliveries(ctx, start, start.Add(time.Minute), 10)
+		require.NoError(t, err)
+		require.Len(t, leased, 1)
+		attempt := leased[0]
+		attemptedAt := start.Add(time.Second)
+		attempt.Status = webhook.DeliveryDead
+		attempt.Attempts = 1
+		attempt.NextAttemptAt = nil
+		attempt.LastAttemptAt = &attemptedAt
+		attempt.LastStatusCode = 410
+		attempt.LastError = "endpoint answered 410"
+
+		// Act
+		_, inFlightErr := store.ReplayWebhookDelivery(ctx, "merchant-1", "wd_1", start.Add(time.Second))
+		saveErr := store.SaveWebhookAttempt(ctx, attempt)
+		againErr := store.SaveWebhookAttempt(ctx, attempt)
+
+		// Assert
+		assert.ErrorIs(t, inFlightErr, webhook.ErrDeliveryInFlight)
+		require.NoError(t, saveErr)
+		assert.ErrorIs(t, againErr, webhook.ErrDeliveryLeaseLost, "saving ends the lease")
+		dead, err := store.WebhookDeliveries(ctx, "merchant-1", webhook.DeliveryDead)
+		require.NoError(t, err)
+		require.Len(t, dead, 1)
+		assert.Equal(t, 1, dead[0].Attempts)
+		assert.Nil(t, dead[0].NextAttemptAt)
+		assert.True(t, attemptedAt.Equal(*dead[0].LastAttemptAt))
+		assert.Equal(t, 410, dead[0].LastStatusCode)
+		assert.Equal(t, "endpoint answered 410", dead[0].LastError)
+		assert.Nil(t, dead[0].LeasedUntil)
+	})
+
+	t.Run("ReplayRestartsAttempts", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		target := endpoint(t, store, "we_1", "merchant-1", start)
+		require.NoError(t, store.AddWebhookDeliveries(ctx, []*webhook.Delivery{delivery("wd_1", target, start)}))
+		leased, err := store.LeaseWebhookDeliveries(ctx, start, start.Add(time.Minute), 10)
+		require.NoError(t, err)
+		require.Len(t, leased, 1)
+		now := start.Add(2 * time.Minute)
+
+		// Act: the lease ran out without an outcome, then the merchant replays the delivery
+		_, otherMerchantErr := store.ReplayWebhookDelivery(ctx, "merchant-2", "wd_1", now)
+		_, missingErr := store.ReplayWebhookDelivery(ctx, "merchant-1", "wd_9", now)
+		replayed, err := store.ReplayWebhookDelivery(ctx, "merchant-1", "wd_1", now)
+		staleErr := store.SaveWebhookAttempt(ctx, leased[0])
+
+		// Assert
+		assert.ErrorIs(t, otherMerchantErr, webhook.ErrDeliveryNotFound)
+		assert.ErrorIs(t, missingErr, webhook.ErrDeliveryNotFound)
+		require.NoError(t, err)
+		assert.Equal(t, webhook.DeliveryPending, replayed.Status)
+		assert.Zero(t, replayed.Attempts)
+		assert.True(t, now.Equal(*replayed.NextAttemptAt))
+		assert.False(t, replayed.Leased(now))
+		assert.ErrorIs(t, staleErr, webhook.ErrDeliveryLeaseLost, "the outcome of an attempt from before the replay is dropped")
+		due, err := store.LeaseWebhookDeliveries(ctx, now, now.Add(time.Minute), 10)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"wd_1"}, ids(due))
+	})
+}
+
+func TestInMemoryWebhookStore(t *testing.T) {
+	testWebhookStore(t, func(t *testing.T) webhook.Store {
+		return NewInMemoryPaymentRepository()
+	})
+}
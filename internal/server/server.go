@@ -0,0 +1,70 @@
+// Package server runs HTTP servers that shut down gracefully: once asked to stop they
+// refuse new connections and let the requests in progress, and their background tasks, finish within a deadline.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// DefaultShutdownTimeout is how long a stopping server waits for the requests in progress
+const DefaultShutdownTimeout = 15 * time.Second
+
+// Serve serves HTTP requests on listener until ctx is done. It then closes the listener and waits up to
+// shutdownTimeout for the requests in progress to finish; connections still busy after that are closed,
+// which cancels the contexts of their requests, and Serve returns an error wrapping context.DeadlineExceeded.
+func Serve(ctx context.Context, srv *http.Server, listener net.Listener, shutdownTimeout time.Duration) error {
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.Serve(listener)
+	}()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		srv.Close()
+		return fmt.Errorf("requests still in progress after %s: %w", shutdownTimeout, err)
+	}
+	if err := <-served; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Task is a background task stopped along with a server, such as a queue's dispatcher
+type Task struct {
+	done chan struct{}
+}
+
+// Go runs task in the background until ctx is done; task must return once ctx is done
+func Go(ctx context.Context, task func(ctx context.Context)) *Task {
+	t := &Task{done: make(chan struct{})}
+	go func() {
+		defer close(t.done)
+		task(ctx)
+	}()
+	return t
+}
+
+// Wait waits up to timeout for the task to return, and returns an error wrapping
+// context.DeadlineExceeded if it is still running after that
+func (t *Task) Wait(timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-t.done:
+		return nil
+	case <-timer.C:
+		return fmt.Errorf("still running after %s: %w", timeout, context.DeadlineExceeded)
+	}
+}
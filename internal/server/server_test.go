@@ -0,0 +1,142 @@
+package server_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"payment-service/internal/server"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingHandler answers once release is closed, telling started when a request arrives
+type blockingHandler struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func newBlockingHandler() *blockingHandler {
+	return &blockingHandler{started: make(chan struct{}, 1), release: make(chan struct{})}
+}
+
+func (h *blockingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.started <- struct{}{}
+	select {
+	case <-h.release:
+		w.Write([]byte("captured"))
+	case <-r.Context().Done():
+	}
+}
+
+// startServer serves handler on a free port until the returned cancel is called
+func startServer(t *testing.T, handler http.Handler, shutdownTimeout time.Duration) (string, context.CancelFunc, <-chan error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- server.Serve(ctx, &http.Server{Handler: handler}, listener, shutdownTimeout)
+	}()
+	return "http://" + listener.Addr().String(), cancel, stopped
+}
+
+// post sends a request in the background and returns its response body or error
+func post(url string) <-chan string {
+	answered := make(chan string, 1)
+	go func() {
+		response, err := http.Post(url+"/pay", "application/json", nil)
+		if err != nil {
+			answered <- "error: " + err.Error()
+			return
+		}
+		defer response.Body.Close()
+		body, _ := io.ReadAll(response.Body)
+		answered <- string(body)
+	}()
+	return answered
+}
+
+func TestServe_DrainsRequestsInProgress(t *testing.T) {
+	// Arrange: a payment request is in progress
+	handler := newBlockingHandler()
+	url, shutdown, stopped := startServer(t, handler, time.Second)
+	answered := post(url)
+	<-handler.started
+
+	// Act
+	shutdown()
+
+	// Assert: new connections are refused while the request finishes
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+		if err == nil {
+			conn.Close()
+		}
+		return err != nil
+	}, time.Second, 5*time.Millisecond)
+	select {
+	case <-stopped:
+		t.Fatal("the server stopped before its request finished")
+	default:
+	}
+
+	close(handler.release)
+	assert.Equal(t, "captured", <-answered)
+	assert.NoError(t, <-stopped)
+}
+
+func TestServe_ClosesRequestsOverTheDeadline(t *testing.T) {
+	// Arrange: a payment request that does not finish by itself
+	handler := newBlockingHandler()
+	url, shutdown, stopped := startServer(t, handler, 20*time.Millisecond)
+	answered := post(url)
+	<-handler.started
+
+	// Act
+	shutdown()
+
+	// Assert
+	err := <-stopped
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, <-answered, "error:", "the connection is closed under the request")
+}
+
+func TestGo_WaitsForTheTaskToStop(t *testing.T) {
+	// Arrange: a task recording its outcome once ctx is done, as a dispatcher handing back its deliveries
+	ctx, cancel := context.WithCancel(context.Background())
+	var handedBack bool
+	task := server.Go(ctx, func(ctx context.Context) {
+		<-ctx.Done()
+		time.Sleep(20 * time.Millisecond)
+		handedBack = true
+	})
+
+	// Act
+	cancel()
+	err := task.Wait(time.Second)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, handedBack, "Wait returns once the task has returned")
+}
+
+func TestGo_WaitGivesUpAfterTheDeadline(t *testing.T) {
+	// Arrange: a task that does not stop by itself
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	ctx, cancel := context.WithCancel(context.Background())
+	task := server.Go(ctx, func(context.Context) { <-release })
+
+	// Act
+	cancel()
+	err := task.Wait(20 * time.Millisecond)
+
+	// Assert
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,95 @@
+package usecase
+
+import (
+	"payment-service/internal/entity"
+	"payment-service/internal/ledger"
+	"time"
+)
+
+// recordCapture adds the journal entry of a payment's captured amount to the payment, so it is recorded
+// in the ledger with the capture: cash comes in, or wallet funds are spent, the merchant is owed the
+// amount net of the processing fee, and the fee is earned.
+func (p *PaymentUseCase) recordCapture(payment *entity.Payment, now time.Time) {
+	captured := payment.CapturedAmount
+	if !captured.IsPositive() {
+		return
+	}
+
+	fee := p.processingFee(captured)
+	postings := []ledger.Posting{{Account: fundingAccount(payment), Side: ledger.Debit, Amount: captured}}
+	if net := (entity.Money{Amount: captured.Amount - fee.Amount, Currency: captured.Currency}); net.IsPositive() {
+		postings = append(postings, ledger.Posting{Account: ledger.AccountMerchantPayable, Side: ledger.Credit, Amount: net})
+	}
+	if fee.IsPositive() {
+		postings = append(postings, ledger.Posting{Account: ledger.AccountFeeRevenue, Side: ledger.Credit, Amount: fee})
+	}
+	payment.Journal = append(payment.Journal, ledger.JournalEntry{
+		ID:          "capture:" + payment.TransactionID,
+		Description: "capture of payment " + payment.TransactionID,
+		PostedAt:    now,
+		Postings:    postings,
+	})
+}
+
+// recordRefund adds the journal entry of a refund to the payment: the merchant's payable shrinks
+// as cash, or wallet funds, go back to the payer. Processing fees are not returned.
+func (p *PaymentUseCase) recordRefund(payment *entity.Payment, refund entity.Refund) {
+	payment.Journal = append(payment.Journal, ledger.JournalEntry{
+		ID:          "refund:" + refund.RefundID,
+		Description: "refund " + refund.RefundID + " of payment " + payment.TransactionID,
+		PostedAt:    refund.CreatedAt,
+		Postings: []ledger.Posting{
+			{Account: ledger.AccountMerchantPayable, Side: ledger.Debit, Amount: refund.Amount},
+			{Account: fundingAccount(payment), Side: ledger.Credit, Amount: refund.Amount},
+		},
+	})
+}
+
+// recordWalletTransaction adds the journal entry of a wallet top-up or debit to the wallet: cash held
+// for the wallet holder goes up or down with the funds owed to them.
+func (p *PaymentUseCase) recordWalletTransaction(wallet *entity.Wallet, transaction entity.WalletTransaction) {
+	debit, credit := ledger.AccountCash, ledger.AccountWalletFunds
+	if transaction.Type == entity.WalletDebit {
+		debit, credit = credit, debit
+	}
+	wallet.Journal = append(wallet.Journal, ledger.JournalEntry{
+		ID:          "wallet:" + wallet.UserID + ":" + transaction.ID,
+		Description: transaction.Type + " of wallet " + wallet.UserID,
+		PostedAt:    transaction.CreatedAt,
+		Postings: []ledger.Posting{
+			{Account: debit, Side: ledger.Debit, Amount: transaction.Amount},
+			{Account: credit, Side: ledger.Credit, Amount: transaction.Amount},
+		},
+	})
+}
+
+// fundingAccount returns the account a payment's funds come from
+func fundingAccount(payment *entity.Payment) string {
+	if payment.PaymentMethod == entity.PaymentMethodWallet {
+		return ledger.AccountWalletFunds
+	}
+	return ledger.AccountCash
+}
+
+// processingFee returns the fee charged on a captured amount, rounded half up to the minor unit
+func (p *PaymentUseCase) processingFee(captured entity.Money) entity.Money {
+	return entity.Money{Amount: (captured.Amount*p.feeBasisPoints + 5000) / 10000, Currency: captured.Currency}
+}
+
+// recordChargeback adds the journal entry of a lost dispute to the payment: the processor returned
+// everything not yet refunded to the cardholder, out of the merchant's payable.
+func (p *PaymentUseCase) recordChargeback(payment *entity.Payment, at time.Time) {
+	amount := payment.RefundableAmount()
+	if !amount.IsPositive() {
+		return
+	}
+	payment.Journal = append(payment.Journal, ledger.JournalEntry{
+		ID:          "chargeback:" + payment.TransactionID,
+		Description: "chargeback of payment " + payment.TransactionID,
+		PostedAt:    at,
+		Postings: []ledger.Posting{
+			{Account: ledger.AccountMerchantPayable, Side: ledger.Debit, Amount: amount},
+			{Account: fundingAccount(payment), Side: ledger.Credit, Amount: amount},
+		},
+	})
+}
@@ -0,0 +1,140 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"payment-service/internal/entity"
+	"payment-service/internal/ledger"
+	"payment-service/internal/repository"
+	"payment-service/internal/usecase"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unreliableRepository fails the first update of every payment, as if storage were briefly unavailable
+type unreliableRepository struct {
+	*repository.InMemoryPaymentRepository
+	failed map[string]bool
+}
+
+func (r *unreliableRepository) Update(ctx context.Context, payment *entity.Payment) error {
+	if !r.failed[payment.TransactionID] {
+		r.failed[payment.TransactionID] = true
+		return errors.New("storage unavailable")
+	}
+	return r.InMemoryPaymentRepository.Update(ctx, payment)
+}
+
+func usd(amount int64) entity.Money {
+	return entity.Money{Amount: amount, Currency: "USD"}
+}
+
+func assertBalance(t *testing.T, l *ledger.Journal, account string, expected entity.Money) {
+	t.Helper()
+	balance, err := l.Balance(context.Background(), account, expected.Currency)
+	require.NoError(t, err)
+	assert.Equal(t, expected, balance, account)
+}
+
+func assertBalanced(t *testing.T, l *ledger.Journal) {
+	t.Helper()
+	trial, err := l.TrialBalance(context.Background())
+	require.NoError(t, err)
+	assert.True(t, trial.Balanced)
+}
+
+func journalEntries(t *testing.T, repo *repository.InMemoryPaymentRepository) []entity.JournalEntry {
+	t.Helper()
+	entries, err := repo.JournalEntries(context.Background())
+	require.NoError(t, err)
+	return entries
+}
+
+func TestPaymentUseCase_Ledger_PaymentFeeAndRefunds(t *testing.T) {
+	// Arrange
+	repo := repository.NewInMemoryPaymentRepository()
+	l := ledger.NewJournal(repo)
+	useCase := usecase.NewPaymentUseCase(repo, usecase.WithProcessingFee(290))
+	request := usecase.PaymentRequest{UserID: "user123", Amount: "100.00", Currency: "USD", TransactionID: "txn-ledger"}
+
+	// Act
+	_, err := useCase.ProcessPayment(context.Background(), request)
+	require.NoError(t, err)
+	_, err = useCase.ProcessPayment(context.Background(), request)
+	require.NoError(t, err)
+
+	// Assert: the fee is taken from the merchant's share, and the retry posts nothing
+	assertBalance(t, l, ledger.AccountCash, usd(10000))
+	assertBalance(t, l, ledger.AccountMerchantPayable, usd(9710))
+	assertBalance(t, l, ledger.AccountFeeRevenue, usd(290))
+	assert.Len(t, journalEntries(t, repo), 1)
+	assertBalanced(t, l)
+
+	// Act
+	for _, refund := range []usecase.RefundRequest{
+		{TransactionID: "txn-ledger", Amount: "25.00", Reason: "requested_by_customer", IdempotencyKey: "refund-1"},
+		{TransactionID: "txn-ledger", Amount: "25.00", Reason: "requested_by_customer", IdempotencyKey: "refund-1"},
+		{TransactionID: "txn-ledger", Reason: "duplicate", IdempotencyKey: "refund-2"},
+	} {
+		_, err := useCase.RefundPayment(context.Background(), refund)
+		require.NoError(t, err)
+	}
+
+	// Assert: refunds return all the cash and the fee is kept
+	assertBalance(t, l, ledger.AccountCash, usd(0))
+	assertBalance(t, l, ledger.AccountMerchantPayable, usd(-290))
+	assertBalance(t, l, ledger.AccountFeeRevenue, usd(290))
+	assert.Len(t, journalEntries(t, repo), 3)
+	assertBalanced(t, l)
+}
+
+func TestPaymentUseCase_Ledger_ManualCapture(t *testing.T) {
+	// Arrange
+	repo := repository.NewInMemoryPaymentRepository()
+	l := ledger.NewJournal(repo)
+	useCase := usecase.NewPaymentUseCase(repo, usecase.WithProcessingFee(100))
+	_, err := useCase.ProcessPayment(context.Background(), usecase.PaymentRequest{UserID: "user123", Amount: "80.00", Currency: "USD", TransactionID: "txn-manual", CaptureMethod: "manual"})
+	require.NoError(t, err)
+	_, err = useCase.AuthorizePayment(context.Background(), "txn-manual")
+	require.NoError(t, err)
+	require.Empty(t, journalEntries(t, repo), "nothing is posted before capture")
+
+	// Act
+	_, err = useCase.CapturePayment(context.Background(), usecase.CaptureRequest{TransactionID: "txn-manual", Amount: "60.00"})
+	require.NoError(t, err)
+	_, err = useCase.CapturePayment(context.Background(), usecase.CaptureRequest{TransactionID: "txn-manual", Amount: "60.00"})
+	require.NoError(t, err)
+
+	// Assert: only the captured amount is posted, once
+	assertBalance(t, l, ledger.AccountCash, usd(6000))
+	assertBalance(t, l, ledger.AccountMerchantPayable, usd(5940))
+	assertBalance(t, l, ledger.AccountFeeRevenue, usd(60))
+	assert.Len(t, journalEntries(t, repo), 1)
+	assertBalanced(t, l)
+}
+
+func TestPaymentUseCase_Ledger_EntriesAreRecordedWithTheirChanges(t *testing.T) {
+	// Arrange
+	repo := &unreliableRepository{InMemoryPaymentRepository: repository.NewInMemoryPaymentRepository(), failed: make(map[string]bool)}
+	l := ledger.NewJournal(repo)
+	useCase := usecase.NewPaymentUseCase(repo)
+	_, err := useCase.ProcessPayment(context.Background(), usecase.PaymentRequest{UserID: "user123", Amount: "10.00", Currency: "USD", TransactionID: "txn-unreliable"})
+	require.NoError(t, err)
+	refund := usecase.RefundRequest{TransactionID: "txn-unreliable", Reason: "other", IdempotencyKey: "refund-1"}
+
+	// Act
+	_, refundErr := useCase.RefundPayment(context.Background(), refund)
+	afterFailure := journalEntries(t, repo.InMemoryPaymentRepository)
+	_, retryErr := useCase.RefundPayment(context.Background(), refund)
+
+	// Assert: the failed refund recorded nothing, and its retry records the refund once
+	assert.Error(t, refundErr)
+	require.Len(t, afterFailure, 1)
+	assert.Equal(t, "capture:txn-unreliable", afterFailure[0].ID)
+	require.NoError(t, retryErr)
+	assert.Len(t, journalEntries(t, repo.InMemoryPaymentRepository), 2)
+	assertBalance(t, l, ledger.AccountCash, usd(0))
+	assertBalanced(t, l)
+}
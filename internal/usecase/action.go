@@ -0,0 +1,137 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"payment-service/internal/entity"
+	"time"
+)
+
+// act applies change to a payment like updatePayment, but never calls the processor from inside
+// the optimistic update, where a lost race would repeat the call. Instead change claims the call
+// as the payment's pending action; once the claim is stored the processor is called and its
+// outcome recorded. An action left pending by an earlier request, which may have stopped after
+// claiming it, is finished before change runs, so only one processor call is ever in flight.
+func (p *PaymentUseCase) act(ctx context.Context, transactionID string, change func(payment *entity.Payment, now time.Time) (bool, error)) (*entity.Payment, error) {
+	for attempt := 0; attempt < maxUpdateAttempts; attempt++ {
+		var (
+			earlier   bool
+			claimed   bool
+			changeErr error
+		)
+		payment, err := p.updatePayment(ctx, transactionID, func(payment *entity.Payment, now time.Time) (bool, error) {
+			earlier, claimed, changeErr = payment.PendingAction != nil, false, nil
+			if earlier {
+				return false, nil
+			}
+			changed, err := change(payment, now)
+			claimed, changeErr = changed && payment.PendingAction != nil, err
+			return changed, err
+		})
+		switch {
+		case earlier && err == nil:
+			if payment, err = p.finishAction(ctx, payment); err != nil {
+				return payment, err
+			}
+			continue
+		case claimed && err == changeErr:
+			if payment, err = p.finishAction(ctx, payment); err != nil {
+				return payment, err
+			}
+			return payment, changeErr
+		}
+		return payment, err
+	}
+
+	return nil, ErrConcurrentUpdate
+}
+
+// finishAction makes the processor call claimed as the payment's pending action and records its
+// outcome. A call that fails is checked against the processor's status, since it may have been made
+// before failing. A call the processor refused releases the claim; one whose outcome is unknown
+// keeps it, so the next request for the payment makes the call again.
+func (p *PaymentUseCase) finishAction(ctx context.Context, payment *entity.Payment) (*entity.Payment, error) {
+	if p.gateway == nil {
+		return payment, ErrGatewayUnavailable
+	}
+	action := *payment.PendingAction
+	if err := p.callProcessor(ctx, payment.ProcessorReference, action); err != nil {
+		// The processor is asked even if the client has gone away, to learn whether the call was made
+		status, statusErr := p.gateway.Status(context.WithoutCancel(ctx), payment.ProcessorReference)
+		switch {
+		case statusErr != nil:
+			return payment, err
+		case status.Status == action.Status():
+			// The call was made before it failed
+		case mayHaveReachedProcessor(err):
+			return payment, err
+		default:
+			return p.releaseAction(context.WithoutCancel(ctx), payment.TransactionID, action, err)
+		}
+	}
+
+	// The processor has made the call, so its outcome is recorded even if the client has gone away
+	return p.updatePayment(context.WithoutCancel(ctx), payment.TransactionID, func(payment *entity.Payment, now time.Time) (bool, error) {
+		if payment.PendingAction == nil || !payment.PendingAction.Matches(action) {
+			// A concurrent request finishing the same action recorded it first
+			return false, nil
+		}
+		payment.PendingAction = nil
+		return true, p.recordAction(payment, action, now)
+	})
+}
+
+// callProcessor makes the processor call of a claimed action
+func (p *PaymentUseCase) callProcessor(ctx context.Context, reference string, action entity.PendingAction) error {
+	var err error
+	if action.Action == entity.ActionVoid {
+		_, err = p.gateway.Void(ctx, reference)
+	} else {
+		_, err = p.gateway.Capture(ctx, reference, action.Amount)
+	}
+	return err
+}
+
+// recordAction moves the payment to the status the processor reached with a claimed action
+func (p *PaymentUseCase) recordAction(payment *entity.Payment, action entity.PendingAction, now time.Time) error {
+	if err := payment.TransitionTo(action.Status(), action.Actor, action.Reason, now); err != nil {
+		return err
+	}
+	payment.AuthorizationExpiresAt = nil
+	if action.Action == entity.ActionCapture {
+		payment.CapturedAmount = action.Amount
+		p.recordCapture(payment, now)
+	}
+	return nil
+}
+
+// releaseAction drops the claim of an action the processor refused and reports refused
+func (p *PaymentUseCase) releaseAction(ctx context.Context, transactionID string, action entity.PendingAction, refused error) (*entity.Payment, error) {
+	payment, err := p.updatePayment(ctx, transactionID, func(payment *entity.Payment, now time.Time) (bool, error) {
+		if payment.PendingAction == nil || !payment.PendingAction.Matches(action) {
+			return false, nil
+		}
+		payment.PendingAction = nil
+		return true, nil
+	})
+	if err != nil {
+		return payment, err
+	}
+	return payment, refused
+}
+
+// claimAction claims a processor call for the payment, to be made once the claim is stored.
+// The claim already fails if the payment's status does not allow the action's outcome.
+func claimAction(payment *entity.Payment, action entity.PendingAction) error {
+	if !entity.CanTransition(payment.Status, action.Status()) {
+		return &entity.TransitionError{From: payment.Status, To: action.Status()}
+	}
+	payment.PendingAction = &action
+	return nil
+}
+
+// mayHaveReachedProcessor reports whether a failed processor call may still take effect:
+// it timed out, or the caller gave up on it while it was in flight
+func mayHaveReachedProcessor(err error) bool {
+	return errors.Is(err, ErrGatewayTimeout) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,152 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"payment-service/internal/entity"
+	"time"
+)
+
+// WithJobQueue enables asynchronous payments: every one gets a job in queue, which workers lease to
+// process it. Without a job queue, asynchronous payments are rejected with ErrAsyncDisabled, as no
+// worker would ever process them.
+func WithJobQueue(queue JobQueue) Option {
+	return func(p *PaymentUseCase) {
+		p.jobs = queue
+	}
+}
+
+// queuePayment stores an asynchronous payment pending and queued for a worker, which processes it
+// with ExecutePayment. Retries answer with the payment as it is now, queued or processed.
+func (p *PaymentUseCase) queuePayment(ctx context.Context, req PaymentRequest, payment *entity.Payment) (*PaymentResponse, error) {
+	payment.Queued = true
+	stored, created, err := p.repo.CreateIfAbsent(ctx, payment)
+	if err != nil {
+		return failedResponse(req, "Failed to process payment"), err
+	}
+	if created {
+		p.publishChanges(ctx, payment)
+		if err := p.enqueueJob(ctx, payment); err != nil {
+			return paymentResponse(payment, errNotQueuedMessage), err
+		}
+		return paymentResponse(payment, "Payment queued for processing"), nil
+	}
+
+	// Payments stored before fingerprinting have no fingerprint and are replayed as-is
+	if stored.RequestFingerprint != "" && stored.RequestFingerprint != payment.RequestFingerprint {
+		return failedResponse(req, ErrIdempotencyConflict.Error()), ErrIdempotencyConflict
+	}
+	switch {
+	case stored.Queued:
+		// The job may have been lost with the request that stored the payment
+		if err := p.enqueueJob(ctx, stored); err != nil {
+			return paymentResponse(stored, errNotQueuedMessage), err
+		}
+		return paymentResponse(stored, "Payment queued for processing"), nil
+	case stored.Status == entity.StatusFailed:
+		return paymentResponse(stored, declineReason(stored)), ErrPaymentDeclined
+	}
+	return paymentResponse(stored, "Transaction already processed"), nil
+}
+
+// errNotQueuedMessage answers a request whose payment was stored but not handed to the workers
+const errNotQueuedMessage = "Payment stored but not queued; retry the request"
+
+// enqueueJob adds the job of a queued payment to the job queue
+func (p *PaymentUseCase) enqueueJob(ctx context.Context, payment *entity.Payment) error {
+	return p.jobs.EnqueueJob(ctx, payment.TransactionID, p.now())
+}
+
+// ExecutePayment processes a queued asynchronous payment the way ProcessPayment processes a synchronous
+// one: it takes the funds and captures the payment, or fails it when the card is declined or the wallet
+// balance is too low. Other failures, such as a processor outage, leave the payment queued so the job can
+// be retried; the funds are taken once per transaction ID however often it runs. Payments no longer
+// queued are returned unchanged.
+func (p *PaymentUseCase) ExecutePayment(ctx context.Context, transactionID string) (*PaymentResponse, error) {
+	message := "Payment processed successfully"
+	var declined error
+	card := false
+	payment, err := p.act(ctx, transactionID, func(payment *entity.Payment, now time.Time) (bool, error) {
+		if !payment.Queued {
+			message = "Transaction already processed"
+			return false, nil
+		}
+
+		if payment.PaymentMethod == entity.PaymentMethodWallet {
+			if _, err := p.payFromWallet(ctx, payment); errors.Is(err, ErrInsufficientFunds) {
+				declined = err
+				payment.Queued = false
+				return true, payment.TransitionTo(entity.StatusFailed, SystemActor, "insufficient funds in the wallet", now)
+			} else if err != nil {
+				return false, err
+			}
+		}
+		if p.chargesCard(payment) {
+			// The processor charges the card outside the update, and the payment stays queued until it answers
+			card = true
+			return false, nil
+		}
+		if err := p.authorize(payment, SystemActor, now); err != nil {
+			return false, err
+		}
+		if err := p.capture(payment, payment.Amount, SystemActor, now); err != nil {
+			return false, err
+		}
+		payment.Queued = false
+		return true, nil
+	})
+	if err == nil && card {
+		if payment, err = p.authorizeCard(ctx, transactionID, SystemActor, true); errors.Is(err, ErrPaymentDeclined) {
+			declined, err = err, nil
+		}
+	}
+	if err != nil {
+		return actionFailedResponse(transactionID, payment, err), err
+	}
+	if declined != nil {
+		return paymentResponse(payment, declined.Error()), declined
+	}
+	return paymentResponse(payment, message), nil
+}
+
+// QueuedPayments returns the transaction IDs of the payments waiting for a worker, oldest first
+func (p *PaymentUseCase) QueuedPayments(ctx context.Context) ([]string, error) {
+	query := PaymentQuery{Status: entity.StatusPending, Queued: true, Limit: MaxPageSize}
+	var newestFirst []string
+	for {
+		payments, err := p.repo.List(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		for _, payment := range payments {
+			newestFirst = append(newestFirst, payment.TransactionID)
+		}
+		if len(payments) < query.Limit {
+			break
+		}
+		cursor := CursorOf(payments[len(payments)-1])
+		query.After = &cursor
+	}
+
+	oldestFirst := make([]string, len(newestFirst))
+	for i, transactionID := range newestFirst {
+		oldestFirst[len(newestFirst)-1-i] = transactionID
+	}
+	return oldestFirst, nil
+}
+
+// DeadJobs lists the jobs of asynchronous payments that ran out of attempts, oldest first
+func (p *PaymentUseCase) DeadJobs(ctx context.Context) ([]*entity.Job, error) {
+	if p.jobs == nil {
+		return []*entity.Job{}, nil
+	}
+	return p.jobs.DeadJobs(ctx)
+}
+
+// RequeueJob gives a dead-lettered job a fresh set of attempts, making it available to the workers now
+func (p *PaymentUseCase) RequeueJob(ctx context.Context, transactionID string) (*entity.Job, error) {
+	if p.jobs == nil {
+		return nil, ErrJobNotFound
+	}
+	return p.jobs.RequeueJob(ctx, transactionID, p.now())
+}
@@ -0,0 +1,211 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"payment-service/internal/entity"
+	"payment-service/internal/gateway"
+	"payment-service/internal/repository"
+	"payment-service/internal/usecase"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func asyncRequest(transactionID, amount, cardToken string) usecase.PaymentRequest {
+	return usecase.PaymentRequest{UserID: "user123", Amount: amount, Currency: "USD", TransactionID: transactionID, CardToken: cardToken, ProcessingMode: usecase.ProcessingAsync}
+}
+
+func TestPaymentUseCase_AsyncPaymentIsQueuedThenExecuted(t *testing.T) {
+	// Arrange
+	useCase, repo, simulator, _ := newGatewayUseCase()
+	req := asyncRequest("txn123", "100.00", "tok_visa")
+
+	// Act
+	queued, err := useCase.ProcessPayment(context.Background(), req)
+	require.NoError(t, err)
+	pending, pendingErr := useCase.QueuedPayments(context.Background())
+	_, statusErr := simulator.Status(context.Background(), gateway.Reference("txn123"))
+	executed, executeErr := useCase.ExecutePayment(context.Background(), "txn123")
+	again, againErr := useCase.ExecutePayment(context.Background(), "txn123")
+	retry, retryErr := useCase.ProcessPayment(context.Background(), req)
+
+	// Assert
+	assert.Equal(t, entity.StatusPending, queued.Status)
+	assert.True(t, queued.Queued)
+	assert.ErrorIs(t, statusErr, gateway.ErrUnknownReference, "nothing is charged before a worker runs the payment")
+	require.NoError(t, pendingErr)
+	assert.Equal(t, []string{"txn123"}, pending)
+
+	require.NoError(t, executeErr)
+	assert.Equal(t, entity.StatusCaptured, executed.Status)
+	assert.False(t, executed.Queued)
+	require.NoError(t, againErr)
+	assert.Equal(t, "Transaction already processed", again.Message)
+	require.NoError(t, retryErr)
+	assert.Equal(t, entity.StatusCaptured, retry.Status)
+
+	stored, err := repo.GetByTransactionID(context.Background(), "txn123")
+	require.NoError(t, err)
+	assert.False(t, stored.Queued)
+	remaining, err := useCase.QueuedPayments(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, remaining)
+}
+
+func TestPaymentUseCase_ExecutePayment_Outcomes(t *testing.T) {
+	tests := []struct {
+		name   string
+		req    usecase.PaymentRequest
+		status string
+		queued bool
+		err    error
+	}{
+		{"declined", asyncRequest("txn123", "100.00", gateway.CardDeclined), entity.StatusFailed, false, usecase.ErrPaymentDeclined},
+		{"processor timeout", asyncRequest("txn123", "10.54", "tok_visa"), entity.StatusPending, true, usecase.ErrGatewayTimeout},
+		{"processor outage", asyncRequest("txn123", "10.53", "tok_visa"), entity.StatusPending, true, usecase.ErrGatewayUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			useCase, repo, _, _ := newGatewayUseCase()
+			_, err := useCase.ProcessPayment(context.Background(), tt.req)
+			require.NoError(t, err)
+
+			// Act
+			_, err = useCase.ExecutePayment(context.Background(), "txn123")
+
+			// Assert
+			assert.ErrorIs(t, err, tt.err)
+			stored, getErr := repo.GetByTransactionID(context.Background(), "txn123")
+			require.NoError(t, getErr)
+			assert.Equal(t, tt.status, stored.Status)
+			assert.Equal(t, tt.queued, stored.Queued, "only failures worth retrying leave the payment queued")
+		})
+	}
+}
+
+func TestPaymentUseCase_ExecutePayment_WalletInsufficientFunds(t *testing.T) {
+	// Arrange
+	repo := repository.NewInMemoryPaymentRepository()
+	useCase := usecase.NewPaymentUseCase(repo, usecase.WithWallets(repo), usecase.WithJobQueue(repo))
+	req := asyncRequest("txn123", "100.00", "")
+	req.PaymentMethod = entity.PaymentMethodWallet
+	_, err := useCase.ProcessPayment(context.Background(), req)
+	require.NoError(t, err)
+
+	// Act
+	response, err := useCase.ExecutePayment(context.Background(), "txn123")
+	retry, retryErr := useCase.ProcessPayment(context.Background(), req)
+
+	// Assert
+	assert.ErrorIs(t, err, usecase.ErrInsufficientFunds)
+	assert.Equal(t, entity.StatusFailed, response.Status)
+	assert.False(t, response.Queued)
+	assert.ErrorIs(t, retryErr, usecase.ErrPaymentDeclined, "retries replay the failure")
+	assert.Equal(t, "insufficient funds in the wallet", retry.Message)
+}
+
+func TestPaymentUseCase_ProcessPayment_InvalidProcessingMode(t *testing.T) {
+	// Arrange
+	useCase := usecase.NewPaymentUseCase(repository.NewInMemoryPaymentRepository())
+	manual := asyncRequest("txn123", "100.00", "")
+	manual.CaptureMethod = usecase.CaptureManual
+	unknown := asyncRequest("txn456", "100.00", "")
+	unknown.ProcessingMode = "batch"
+
+	// Act
+	_, manualErr := useCase.ProcessPayment(context.Background(), manual)
+	_, unknownErr := useCase.ProcessPayment(context.Background(), unknown)
+
+	// Assert
+	assert.ErrorIs(t, manualErr, usecase.ErrInvalidProcessingMode)
+	assert.ErrorIs(t, unknownErr, usecase.ErrInvalidProcessingMode)
+}
+
+func TestPaymentUseCase_ProcessPayment_AsyncWithoutJobQueue(t *testing.T) {
+	// Arrange: no job queue, so no worker would ever process the payment
+	repo := repository.NewInMemoryPaymentRepository()
+	useCase := usecase.NewPaymentUseCase(repo)
+
+	// Act
+	_, err := useCase.ProcessPayment(context.Background(), asyncRequest("txn123", "100.00", ""))
+
+	// Assert
+	assert.ErrorIs(t, err, usecase.ErrAsyncDisabled)
+	assert.False(t, repo.Exists(context.Background(), "txn123"), "the payment is not stored")
+}
+
+// flakyQueue fails to enqueue jobs while down
+type flakyQueue struct {
+	*repository.InMemoryPaymentRepository
+	down bool
+}
+
+func (q *flakyQueue) EnqueueJob(ctx context.Context, transactionID string, now time.Time) error {
+	if q.down {
+		return errors.New("queue unavailable")
+	}
+	return q.InMemoryPaymentRepository.EnqueueJob(ctx, transactionID, now)
+}
+
+func TestPaymentUseCase_AsyncPaymentQueuesJob(t *testing.T) {
+	// Arrange
+	repo := repository.NewInMemoryPaymentRepository()
+	queue := &flakyQueue{InMemoryPaymentRepository: repo, down: true}
+	useCase := usecase.NewPaymentUseCase(repo, usecase.WithJobQueue(queue))
+	req := asyncRequest("txn123", "100.00", "")
+	now := time.Now()
+
+	// Act
+	_, failedErr := useCase.ProcessPayment(context.Background(), req)
+	unqueued, unqueuedErr := repo.LeaseJob(context.Background(), now.Add(time.Minute), now.Add(2*time.Minute))
+	queue.down = false
+	retry, retryErr := useCase.ProcessPayment(context.Background(), req)
+
+	// Assert
+	assert.Error(t, failedErr)
+	require.NoError(t, unqueuedErr)
+	assert.Nil(t, unqueued)
+	require.NoError(t, retryErr, "the retry queues the job the first request could not")
+	assert.True(t, retry.Queued)
+	job, err := repo.LeaseJob(context.Background(), now.Add(time.Minute), now.Add(2*time.Minute))
+	require.NoError(t, err)
+	require.NotNil(t, job)
+	assert.Equal(t, "txn123", job.TransactionID)
+}
+
+func TestPaymentUseCase_DeadJobsWithoutJobQueue(t *testing.T) {
+	// Arrange
+	useCase := usecase.NewPaymentUseCase(repository.NewInMemoryPaymentRepository())
+
+	// Act
+	dead, deadErr := useCase.DeadJobs(context.Background())
+	_, requeueErr := useCase.RequeueJob(context.Background(), "txn123")
+
+	// Assert
+	require.NoError(t, deadErr)
+	assert.Empty(t, dead)
+	assert.ErrorIs(t, requeueErr, usecase.ErrJobNotFound)
+}
+
+func TestPaymentUseCase_AsyncRetryOfFailedPaymentWithoutHistory(t *testing.T) {
+	// Arrange: a failed payment stored before status histories were kept
+	useCase, repo, _, _ := newGatewayUseCase()
+	require.NoError(t, repo.Store(context.Background(), &entity.Payment{
+		TransactionID: "txn123",
+		UserID:        "user123",
+		Amount:        entity.Money{Amount: 10000, Currency: "USD"},
+		Status:        entity.StatusFailed,
+	}))
+
+	// Act
+	response, err := useCase.ProcessPayment(context.Background(), asyncRequest("txn123", "100.00", "tok_visa"))
+
+	// Assert
+	assert.ErrorIs(t, err, usecase.ErrPaymentDeclined)
+	assert.Equal(t, usecase.ErrPaymentDeclined.Error(), response.Message)
+}
@@ -0,0 +1,96 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"payment-service/internal/entity"
+)
+
+// EventTypePrefix starts the type of every payment event; the new status follows it
+const EventTypePrefix = "payment."
+
+// WithEvents publishes an event for every status change of a payment. outbox is the store the
+// payments are kept in; it records which changes have been published, so changes left unpublished
+// when publishing fails or the service stops are published later by PublishPendingEvents.
+func WithEvents(publisher EventPublisher, outbox EventOutbox) Option {
+	return func(p *PaymentUseCase) {
+		p.events = publisher
+		p.outbox = outbox
+	}
+}
+
+// PublishPendingEvents publishes the stored status changes that have not been published yet and
+// returns how many payments it published changes of. Running it periodically delivers the events
+// of changes whose publishing failed, or was cut short, after the change was stored.
+func (p *PaymentUseCase) PublishPendingEvents(ctx context.Context) (int, error) {
+	if p.events == nil {
+		return 0, nil
+	}
+	published := 0
+	for {
+		page, err := p.outbox.UnpublishedPayments(ctx, MaxPageSize)
+		if err != nil {
+			return published, err
+		}
+		for _, payment := range page {
+			if err := p.publishPending(ctx, payment); err != nil {
+				return published, err
+			}
+			published++
+		}
+		if len(page) < MaxPageSize {
+			return published, nil
+		}
+	}
+}
+
+// publishChanges publishes the status changes of a stored payment that are not published yet.
+// The changes are stored, so they are published even if the client has gone away; changes that
+// fail to publish stay in the outbox for PublishPendingEvents.
+func (p *PaymentUseCase) publishChanges(ctx context.Context, payment *entity.Payment) {
+	if p.events == nil {
+		return
+	}
+	_ = p.publishPending(context.WithoutCancel(ctx), payment)
+}
+
+// publishPending publishes the unpublished status changes of a stored payment in order, then marks
+// those published in the outbox. Event IDs are derived from the position of a change, so a change
+// published again, because marking it failed, yields the same event.
+func (p *PaymentUseCase) publishPending(ctx context.Context, payment *entity.Payment) error {
+	var publishErr error
+	published := payment.PublishedChanges
+	for ; published < len(payment.StatusHistory); published++ {
+		if publishErr = p.events.Publish(ctx, paymentEvent(payment, published)); publishErr != nil {
+			break
+		}
+	}
+	if published > payment.PublishedChanges {
+		if err := p.outbox.MarkChangesPublished(ctx, payment.TransactionID, published); err != nil {
+			return err
+		}
+		payment.PublishedChanges = published
+	}
+	return publishErr
+}
+
+// paymentEvent describes the status change at position i of a payment's history
+func paymentEvent(payment *entity.Payment, i int) PaymentEvent {
+	change := payment.StatusHistory[i]
+	return PaymentEvent{
+		ID:        fmt.Sprintf("evt_%s_%d", payment.TransactionID, i),
+		Type:      EventTypePrefix + change.To,
+		CreatedAt: change.At,
+		Data: PaymentEventData{
+			TransactionID:  payment.TransactionID,
+			UserID:         payment.UserID,
+			MerchantID:     payment.MerchantID,
+			Amount:         payment.Amount.Decimal(),
+			Currency:       payment.Amount.Currency,
+			Status:         change.To,
+			PreviousStatus: change.From,
+			Actor:          change.Actor,
+			Reason:         change.Reason,
+		},
+	}
+}
@@ -0,0 +1,122 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"payment-service/internal/entity"
+	"payment-service/internal/repository"
+	"payment-service/internal/usecase"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingPublisher keeps every published event, or fails to publish while err is set
+type recordingPublisher struct {
+	mutex  sync.Mutex
+	events []usecase.PaymentEvent
+	err    error
+}
+
+func (r *recordingPublisher) Publish(_ context.Context, event usecase.PaymentEvent) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *recordingPublisher) failWith(err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.err = err
+}
+
+func (r *recordingPublisher) types() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	types := make([]string, len(r.events))
+	for i, event := range r.events {
+		types[i] = event.Type
+	}
+	return types
+}
+
+func TestPaymentUseCase_PublishesStatusChanges(t *testing.T) {
+	// Arrange
+	publisher := &recordingPublisher{}
+	repo := repository.NewInMemoryPaymentRepository()
+	useCase := usecase.NewPaymentUseCase(repo, usecase.WithEvents(publisher, repo))
+	req := usecase.PaymentRequest{UserID: "user123", Amount: "100.00", Currency: "USD", TransactionID: "txn123", MerchantID: "merchant-1", CaptureMethod: usecase.CaptureManual}
+
+	// Act
+	_, err := useCase.ProcessPayment(context.Background(), req)
+	require.NoError(t, err)
+	_, err = useCase.ProcessPayment(context.Background(), req)
+	require.NoError(t, err)
+	created := publisher.types()
+	_, err = useCase.AuthorizePayment(context.Background(), "txn123")
+	require.NoError(t, err)
+	_, err = useCase.CapturePayment(context.Background(), usecase.CaptureRequest{TransactionID: "txn123"})
+	require.NoError(t, err)
+	_, err = useCase.CapturePayment(context.Background(), usecase.CaptureRequest{TransactionID: "txn123"})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []string{"payment." + entity.StatusPending}, created, "a retried request publishes nothing")
+	assert.Equal(t, []string{
+		"payment." + entity.StatusPending,
+		"payment." + entity.StatusAuthorized,
+		"payment." + entity.StatusCaptured,
+	}, publisher.types(), "a repeated capture changes nothing and publishes nothing")
+
+	captured := publisher.events[2]
+	assert.Equal(t, "evt_txn123_2", captured.ID)
+	assert.Equal(t, usecase.PaymentEventData{
+		TransactionID:  "txn123",
+		UserID:         "user123",
+		MerchantID:     "merchant-1",
+		Amount:         "100.00",
+		Currency:       "USD",
+		Status:         entity.StatusCaptured,
+		PreviousStatus: entity.StatusAuthorized,
+		Actor:          captured.Data.Actor,
+		Reason:         captured.Data.Reason,
+	}, captured.Data)
+	assert.NotEmpty(t, captured.Data.Actor)
+}
+
+func TestPaymentUseCase_PublishPendingEvents(t *testing.T) {
+	// Arrange: the changes are stored while publishing fails
+	publisher := &recordingPublisher{}
+	repo := repository.NewInMemoryPaymentRepository()
+	useCase := usecase.NewPaymentUseCase(repo, usecase.WithEvents(publisher, repo))
+	publisher.failWith(errors.New("webhook store unavailable"))
+	_, err := useCase.ProcessPayment(context.Background(), usecase.PaymentRequest{UserID: "user123", Amount: "100.00", Currency: "USD", TransactionID: "txn123", MerchantID: "merchant-1", CaptureMethod: usecase.CaptureManual})
+	require.NoError(t, err, "a change is stored even if its event cannot be published yet")
+	_, err = useCase.AuthorizePayment(context.Background(), "txn123")
+	require.NoError(t, err)
+	failing, failingErr := useCase.PublishPendingEvents(context.Background())
+	publisher.failWith(nil)
+
+	// Act
+	published, err := useCase.PublishPendingEvents(context.Background())
+	again, againErr := useCase.PublishPendingEvents(context.Background())
+
+	// Assert
+	assert.Error(t, failingErr)
+	assert.Zero(t, failing)
+	require.NoError(t, err)
+	assert.Equal(t, 1, published)
+	assert.Equal(t, []string{"payment." + entity.StatusPending, "payment." + entity.StatusAuthorized}, publisher.types())
+	assert.Equal(t, "evt_txn123_1", publisher.events[1].ID)
+	require.NoError(t, againErr)
+	assert.Zero(t, again, "published changes are not published again")
+	unpublished, err := repo.UnpublishedPayments(context.Background(), 10)
+	require.NoError(t, err)
+	assert.Empty(t, unpublished)
+}
@@ -0,0 +1,548 @@
+package usecase_test
+
+import (
+	"context"
+	"payment-service/internal/entity"
+	"payment-service/internal/gateway"
+	"payment-service/internal/ledger"
+	"payment-service/internal/repository"
+	"payment-service/internal/usecase"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newGatewayUseCase returns a use case charging card payments through a simulated processor
+func newGatewayUseCase() (*usecase.PaymentUseCase, *repository.InMemoryPaymentRepository, *gateway.Simulator, *ledger.Journal) {
+	repo := repository.NewInMemoryPaymentRepository()
+	simulator := gateway.NewSimulator()
+	l := ledger.NewJournal(repo)
+	useCase := usecase.NewPaymentUseCase(repo, usecase.WithGateway(simulator), usecase.WithJobQueue(repo))
+	return useCase, repo, simulator, l
+}
+
+func TestPaymentUseCase_ProcessPayment_ChargedThroughGateway(t *testing.T) {
+	// Arrange
+	useCase, _, simulator, l := newGatewayUseCase()
+	req := usecase.PaymentRequest{UserID: "user123", Amount: "100.00", Currency: "USD", TransactionID: "txn123", CardToken: "tok_visa"}
+
+	// Act
+	response, err := useCase.ProcessPayment(context.Background(), req)
+	retry, retryErr := useCase.ProcessPayment(context.Background(), req)
+
+	// Assert
+	require.NoError(t, err)
+	require.NoError(t, retryErr)
+	assert.Equal(t, entity.StatusCaptured, response.Status)
+	assert.Equal(t, gateway.Reference("txn123"), response.ProcessorReference)
+	assert.Equal(t, "Transaction already processed", retry.Message)
+	status, err := simulator.Status(context.Background(), response.ProcessorReference)
+	require.NoError(t, err)
+	assert.Equal(t, entity.StatusCaptured, status.Status)
+	assertBalance(t, l, ledger.AccountCash, usd(10000))
+}
+
+func TestPaymentUseCase_ProcessPayment_DeclinedByGateway(t *testing.T) {
+	// Arrange
+	useCase, repo, _, l := newGatewayUseCase()
+	req := usecase.PaymentRequest{UserID: "user123", Amount: "100.00", Currency: "USD", TransactionID: "txn123", CardToken: gateway.CardInsufficientFunds}
+
+	// Act
+	response, err := useCase.ProcessPayment(context.Background(), req)
+	retry, retryErr := useCase.ProcessPayment(context.Background(), req)
+
+	// Assert
+	assert.ErrorIs(t, err, usecase.ErrPaymentDeclined)
+	assert.Equal(t, entity.StatusFailed, response.Status)
+	assert.Contains(t, response.Message, gateway.DeclineInsufficientFunds)
+	assert.ErrorIs(t, retryErr, usecase.ErrPaymentDeclined, "retries replay the decline")
+	assert.Equal(t, entity.StatusFailed, retry.Status)
+
+	stored, err := repo.GetByTransactionID(context.Background(), "txn123")
+	require.NoError(t, err)
+	require.NotNil(t, stored, "declined payments are stored")
+	assert.Equal(t, entity.StatusFailed, stored.Status)
+	assert.Equal(t, "declined by the processor: insufficient_funds", stored.StatusHistory[len(stored.StatusHistory)-1].Reason)
+	assertBalance(t, l, ledger.AccountCash, usd(0))
+}
+
+func TestPaymentUseCase_ProcessPayment_GatewayFailuresLeaveThePaymentPending(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount string
+		err    error
+	}{
+		{"timeout", "10.54", usecase.ErrGatewayTimeout},
+		{"processing error", "10.53", usecase.ErrGatewayUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			useCase, repo, _, _ := newGatewayUseCase()
+			req := usecase.PaymentRequest{UserID: "user123", Amount: tt.amount, Currency: "USD", TransactionID: "txn123"}
+
+			// Act
+			response, err := useCase.ProcessPayment(context.Background(), req)
+			retry, retryErr := useCase.ProcessPayment(context.Background(), req)
+			req.Amount = "20.00"
+			_, conflictErr := useCase.ProcessPayment(context.Background(), req)
+
+			// Assert
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, entity.StatusPending, response.Status)
+			assert.ErrorIs(t, retryErr, tt.err, "a retry charges the stored payment again")
+			assert.Equal(t, entity.StatusPending, retry.Status)
+			assert.ErrorIs(t, conflictErr, usecase.ErrIdempotencyConflict)
+			stored, err := repo.GetByTransactionID(context.Background(), "txn123")
+			require.NoError(t, err)
+			require.NotNil(t, stored, "the payment is stored before the card is charged")
+			assert.Equal(t, entity.StatusPending, stored.Status)
+			assert.Empty(t, stored.ProcessorReference)
+		})
+	}
+}
+
+func TestPaymentUseCase_ProcessPayment_RetryAfterOutageChargesStoredPayment(t *testing.T) {
+	// Arrange
+	useCase, _, simulator, l := newGatewayUseCase()
+	req := usecase.PaymentRequest{UserID: "user123", Amount: "100.00", Currency: "USD", TransactionID: "txn123", CardToken: "tok_visa"}
+	simulator.SetDown(true)
+	_, err := useCase.ProcessPayment(context.Background(), req)
+	require.ErrorIs(t, err, usecase.ErrGatewayUnavailable)
+	simulator.SetDown(false)
+
+	// Act
+	retry, err := useCase.ProcessPayment(context.Background(), req)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, entity.StatusCaptured, retry.Status)
+	status, err := simulator.Status(context.Background(), gateway.Reference("txn123"))
+	require.NoError(t, err)
+	assert.Equal(t, entity.StatusCaptured, status.Status)
+	assertBalance(t, l, ledger.AccountCash, usd(10000))
+}
+
+func TestPaymentUseCase_ManualCaptureAndRefundThroughGateway(t *testing.T) {
+	// Arrange
+	useCase, _, simulator, _ := newGatewayUseCase()
+	_, err := useCase.ProcessPayment(context.Background(), usecase.PaymentRequest{UserID: "user123", Amount: "100.00", Currency: "USD", TransactionID: "txn123", CaptureMethod: usecase.CaptureManual})
+	require.NoError(t, err)
+
+	// Act
+	authorized, authorizeErr := useCase.AuthorizePayment(context.Background(), "txn123")
+	captured, captureErr := useCase.CapturePayment(context.Background(), usecase.CaptureRequest{TransactionID: "txn123", Amount: "60.00"})
+	refund, refundErr := useCase.RefundPayment(context.Background(), usecase.RefundRequest{TransactionID: "txn123", Amount: "60.00", Reason: entity.RefundReasonRequestedByCustomer, IdempotencyKey: "refund-1"})
+
+	// Assert
+	require.NoError(t, authorizeErr)
+	require.NoError(t, captureErr)
+	require.NoError(t, refundErr)
+	assert.Equal(t, gateway.Reference("txn123"), authorized.ProcessorReference)
+	assert.Equal(t, "60.00", captured.CapturedAmount)
+	assert.Equal(t, entity.StatusRefunded, refund.PaymentStatus)
+	status, err := simulator.Status(context.Background(), gateway.Reference("txn123"))
+	require.NoError(t, err)
+	assert.Equal(t, entity.StatusRefunded, status.Status)
+}
+
+func TestPaymentUseCase_AuthorizePayment_DeclinedByGateway(t *testing.T) {
+	// Arrange
+	useCase, _, _, _ := newGatewayUseCase()
+	_, err := useCase.ProcessPayment(context.Background(), usecase.PaymentRequest{UserID: "user123", Amount: "100.00", Currency: "USD", TransactionID: "txn123", CaptureMethod: usecase.CaptureManual, CardToken: gateway.CardDeclined})
+	require.NoError(t, err)
+
+	// Act
+	response, err := useCase.AuthorizePayment(context.Background(), "txn123")
+
+	// Assert
+	assert.ErrorIs(t, err, usecase.ErrPaymentDeclined)
+	assert.Equal(t, entity.StatusFailed, response.Status)
+	payment, err := useCase.GetPayment(context.Background(), "txn123")
+	require.NoError(t, err)
+	assert.Equal(t, entity.StatusFailed, payment.Status)
+}
+
+func TestPaymentUseCase_ProcessPayment_WalletPaymentsBypassGateway(t *testing.T) {
+	// Arrange
+	repo := repository.NewInMemoryPaymentRepository()
+	simulator := gateway.NewSimulator()
+	useCase := usecase.NewPaymentUseCase(repo, usecase.WithGateway(simulator), usecase.WithWallets(repo))
+	_, err := useCase.TopUpWallet(context.Background(), usecase.WalletRequest{UserID: "user123", Amount: "20.00", Currency: "USD", IdempotencyKey: "initial"})
+	require.NoError(t, err)
+
+	// Act: 10.51 would be declined by the processor
+	response, err := useCase.ProcessPayment(context.Background(), usecase.PaymentRequest{UserID: "user123", Amount: "10.51", Currency: "USD", TransactionID: "txn123", PaymentMethod: entity.PaymentMethodWallet})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, entity.StatusCaptured, response.Status)
+	assert.Empty(t, response.ProcessorReference)
+	_, statusErr := simulator.Status(context.Background(), gateway.Reference("txn123"))
+	assert.ErrorIs(t, statusErr, gateway.ErrUnknownReference)
+}
+
+func TestPaymentUseCase_ProcessPayment_RecordsRoutedProcessor(t *testing.T) {
+	// Arrange
+	primary, secondary := gateway.NewSimulator(), gateway.NewSimulator()
+	router, err := gateway.NewRouter(
+		map[string]gateway.Processor{"primary": primary, "secondary": secondary},
+		[]gateway.Route{{Processor: "primary"}, {Processor: "secondary"}},
+	)
+	require.NoError(t, err)
+	repo := repository.NewInMemoryPaymentRepository()
+	useCase := usecase.NewPaymentUseCase(repo, usecase.WithGateway(router))
+	primary.SetDown(true)
+
+	// Act
+	response, err := useCase.ProcessPayment(context.Background(), usecase.PaymentRequest{UserID: "user123", Amount: "100.00", Currency: "USD", TransactionID: "txn123", MerchantID: "merchant-1"})
+	require.NoError(t, err)
+	primary.SetDown(false)
+	refund, refundErr := useCase.RefundPayment(context.Background(), usecase.RefundRequest{TransactionID: "txn123", Reason: entity.RefundReasonRequestedByCustomer, IdempotencyKey: "refund-1"})
+
+	// Assert
+	assert.Equal(t, "secondary", response.Processor)
+	assert.Equal(t, "secondary:"+gateway.Reference("txn123"), response.ProcessorReference)
+	stored, err := repo.GetByTransactionID(context.Background(), "txn123")
+	require.NoError(t, err)
+	assert.Equal(t, "secondary", stored.Processor)
+	assert.Equal(t, "merchant-1", stored.MerchantID)
+
+	require.NoError(t, refundErr)
+	assert.Equal(t, entity.StatusRefunded, refund.PaymentStatus)
+	status, err := secondary.Status(context.Background(), gateway.Reference("txn123"))
+	require.NoError(t, err)
+	assert.Equal(t, entity.StatusRefunded, status.Status, "the refund went to the processor that charged the payment")
+	_, err = primary.Status(context.Background(), gateway.Reference("txn123"))
+	assert.ErrorIs(t, err, gateway.ErrUnknownReference)
+}
+
+// unreachableProcessor authorizes payments, but while unreachable its answers never arrive and it
+// cannot be asked for the status either, so the outcome of its authorizations stays unknown
+type unreachableProcessor struct {
+	*gateway.Simulator
+	unreachable bool
+}
+
+func (p *unreachableProcessor) Authorize(ctx context.Context, req usecase.GatewayAuthorization) (*usecase.GatewayResult, error) {
+	result, err := p.Simulator.Authorize(ctx, req)
+	if p.unreachable {
+		return nil, usecase.ErrGatewayTimeout
+	}
+	return result, err
+}
+
+func (p *unreachableProcessor) Status(ctx context.Context, reference string) (*usecase.GatewayResult, error) {
+	if p.unreachable {
+		return nil, usecase.ErrGatewayUnavailable
+	}
+	return p.Simulator.Status(ctx, reference)
+}
+
+func TestPaymentUseCase_ProcessPayment_StoresProcessorBeforeCharging(t *testing.T) {
+	// Arrange
+	primary, secondary := &unreachableProcessor{Simulator: gateway.NewSimulator(), unreachable: true}, gateway.NewSimulator()
+	processors := map[string]gateway.Processor{"primary": primary, "secondary": secondary}
+	routes := []gateway.Route{{Processor: "primary"}, {Processor: "secondary"}}
+	router, err := gateway.NewRouter(processors, routes)
+	require.NoError(t, err)
+	repo := repository.NewInMemoryPaymentRepository()
+	req := usecase.PaymentRequest{UserID: "user123", Amount: "100.00", Currency: "USD", TransactionID: "txn123"}
+	_, err = usecase.NewPaymentUseCase(repo, usecase.WithGateway(router)).ProcessPayment(context.Background(), req)
+	require.ErrorIs(t, err, usecase.ErrGatewayTimeout)
+	pending, err := repo.GetByTransactionID(context.Background(), "txn123")
+	require.NoError(t, err)
+
+	// Act: the service restarts, so a new router knows nothing of the first attempt
+	primary.unreachable = false
+	restarted, err := gateway.NewRouter(processors, routes)
+	require.NoError(t, err)
+	retry, retryErr := usecase.NewPaymentUseCase(repo, usecase.WithGateway(restarted)).ProcessPayment(context.Background(), req)
+
+	// Assert
+	assert.Equal(t, entity.StatusPending, pending.Status)
+	assert.Equal(t, "primary", pending.Processor, "the processor is stored before it is called")
+	require.NoError(t, retryErr)
+	assert.Equal(t, entity.StatusCaptured, retry.Status)
+	assert.Equal(t, "primary", retry.Processor)
+	status, err := primary.Status(context.Background(), gateway.Reference("txn123"))
+	require.NoError(t, err)
+	assert.Equal(t, entity.StatusCaptured, status.Status)
+	_, err = secondary.Status(context.Background(), gateway.Reference("txn123"))
+	assert.ErrorIs(t, err, gateway.ErrUnknownReference, "the payment is never authorized at a second processor")
+}
+
+// cancelingGateway cancels the request context once the processor has answered, like a client
+// disconnecting while its card is being charged
+type cancelingGateway struct {
+	usecase.PaymentGateway
+	cancel context.CancelFunc
+}
+
+func (g *cancelingGateway) Capture(ctx context.Context, reference string, amount entity.Money) (*usecase.GatewayResult, error) {
+	defer g.cancel()
+	return g.PaymentGateway.Capture(ctx, reference, amount)
+}
+
+func TestPaymentUseCase_ProcessPayment_CanceledContext(t *testing.T) {
+	// Arrange
+	useCase, repo, _, _ := newGatewayUseCase()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := usecase.PaymentRequest{UserID: "user123", Amount: "100.00", Currency: "USD", TransactionID: "txn123", CardToken: "tok_visa"}
+
+	// Act
+	_, err := useCase.ProcessPayment(ctx, req)
+
+	// Assert
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, repo.Exists(context.Background(), "txn123"))
+}
+
+func TestPaymentUseCase_ProcessPayment_StoredAfterChargeWhenCanceled(t *testing.T) {
+	// Arrange
+	repo := repository.NewInMemoryPaymentRepository()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	useCase := usecase.NewPaymentUseCase(repo, usecase.WithGateway(&cancelingGateway{PaymentGateway: gateway.NewSimulator(), cancel: cancel}))
+	req := usecase.PaymentRequest{UserID: "user123", Amount: "100.00", Currency: "USD", TransactionID: "txn123", CardToken: "tok_visa"}
+
+	// Act
+	response, err := useCase.ProcessPayment(ctx, req)
+
+	// Assert
+	require.NoError(t, err, "a charged card is recorded even though the client went away")
+	assert.Equal(t, entity.StatusCaptured, response.Status)
+	stored, err := repo.GetByTransactionID(context.Background(), "txn123")
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.Equal(t, entity.StatusCaptured, stored.Status)
+}
+
+func TestPaymentUseCase_ApplyProviderEvent_OnlyFromChargingProcessor(t *testing.T) {
+	// Arrange
+	router, err := gateway.NewRouter(
+		map[string]gateway.Processor{"primary": gateway.NewSimulator(), "secondary": gateway.NewSimulator()},
+		[]gateway.Route{{Processor: "primary"}, {Processor: "secondary"}},
+	)
+	require.NoError(t, err)
+	useCase := usecase.NewPaymentUseCase(repository.NewInMemoryPaymentRepository(), usecase.WithGateway(router))
+	_, err = useCase.ProcessPayment(context.Background(), usecase.PaymentRequest{UserID: "user123", Amount: "100.00", Currency: "USD", TransactionID: "txn123"})
+	require.NoError(t, err)
+	settled := usecase.ProviderEvent{ID: "evt_1", Type: usecase.ProviderEventSettled, TransactionID: "txn123", Reference: gateway.Reference("txn123")}
+
+	// Act
+	otherProcessor := settled
+	otherProcessor.Provider = "secondary"
+	_, otherErr := useCase.ApplyProviderEvent(context.Background(), otherProcessor)
+	charging := settled
+	charging.Provider = "primary"
+	response, err := useCase.ApplyProviderEvent(context.Background(), charging)
+	_, invalidErr := useCase.ApplyProviderEvent(context.Background(), usecase.ProviderEvent{ID: "evt_2", Provider: "primary", Type: "refunded", TransactionID: "txn123"})
+
+	// Assert
+	assert.ErrorIs(t, otherErr, usecase.ErrPaymentNotFound)
+	require.NoError(t, err)
+	assert.True(t, response.Applied)
+	assert.Equal(t, entity.StatusSettled, response.Status)
+	assert.ErrorIs(t, invalidErr, usecase.ErrInvalidProviderEvent)
+}
+
+// manuallyAuthorized stores a manual-capture card payment of 100.00 USD and authorizes it
+func manuallyAuthorized(t *testing.T, useCase *usecase.PaymentUseCase) {
+	_, err := useCase.ProcessPayment(context.Background(), usecase.PaymentRequest{UserID: "user123", Amount: "100.00", Currency: "USD", TransactionID: "txn123", CaptureMethod: usecase.CaptureManual})
+	require.NoError(t, err)
+	_, err = useCase.AuthorizePayment(context.Background(), "txn123")
+	require.NoError(t, err)
+}
+
+func TestPaymentUseCase_VoidPayment_ReleasesAuthorizationAtGateway(t *testing.T) {
+	// Arrange
+	useCase, repo, simulator, _ := newGatewayUseCase()
+	manuallyAuthorized(t, useCase)
+
+	// Act
+	response, err := useCase.VoidPayment(context.Background(), "txn123")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, entity.StatusVoided, response.Status)
+	status, err := simulator.Status(context.Background(), gateway.Reference("txn123"))
+	require.NoError(t, err)
+	assert.Equal(t, entity.StatusVoided, status.Status)
+	stored, err := repo.GetByTransactionID(context.Background(), "txn123")
+	require.NoError(t, err)
+	assert.Nil(t, stored.PendingAction)
+}
+
+func TestPaymentUseCase_ExpireAuthorizations_ReleasesAuthorizationAtGateway(t *testing.T) {
+	// Arrange: the authorization lapses straight away
+	repo := repository.NewInMemoryPaymentRepository()
+	simulator := gateway.NewSimulator()
+	useCase := usecase.NewPaymentUseCase(repo, usecase.WithGateway(simulator), usecase.WithAuthorizationWindow(time.Nanosecond))
+	manuallyAuthorized(t, useCase)
+
+	// Act
+	expired, err := useCase.ExpireAuthorizations(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 1, expired)
+	status, err := simulator.Status(context.Background(), gateway.Reference("txn123"))
+	require.NoError(t, err)
+	assert.Equal(t, entity.StatusVoided, status.Status)
+	stored, err := repo.GetByTransactionID(context.Background(), "txn123")
+	require.NoError(t, err)
+	assert.Equal(t, entity.StatusVoided, stored.Status)
+	assert.Equal(t, usecase.SystemActor, stored.StatusHistory[len(stored.StatusHistory)-1].Actor)
+}
+
+// observedGateway calls onCapture before every capture reaches the processor
+type observedGateway struct {
+	usecase.PaymentGateway
+	onCapture func(amount entity.Money)
+}
+
+func (g *observedGateway) Capture(ctx context.Context, reference string, amount entity.Money) (*usecase.GatewayResult, error) {
+	g.onCapture(amount)
+	return g.PaymentGateway.Capture(ctx, reference, amount)
+}
+
+func TestPaymentUseCase_CapturePayment_ClaimedBeforeGatewayCall(t *testing.T) {
+	// Arrange
+	repo := repository.NewInMemoryPaymentRepository()
+	var claimed *entity.PendingAction
+	observed := &observedGateway{PaymentGateway: gateway.NewSimulator(), onCapture: func(entity.Money) {
+		stored, err := repo.GetByTransactionID(context.Background(), "txn123")
+		require.NoError(t, err)
+		claimed = stored.PendingAction
+	}}
+	useCase := usecase.NewPaymentUseCase(repo, usecase.WithGateway(observed))
+	manuallyAuthorized(t, useCase)
+
+	// Act
+	response, err := useCase.CapturePayment(context.Background(), usecase.CaptureRequest{TransactionID: "txn123", Amount: "60.00"})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, entity.StatusCaptured, response.Status)
+	require.NotNil(t, claimed, "the capture is stored as claimed before the processor is called")
+	assert.Equal(t, entity.ActionCapture, claimed.Action)
+	assert.Equal(t, usd(6000), claimed.Amount)
+	stored, err := repo.GetByTransactionID(context.Background(), "txn123")
+	require.NoError(t, err)
+	assert.Nil(t, stored.PendingAction, "the claim is dropped once the capture is recorded")
+	assert.Equal(t, usd(6000), stored.CapturedAmount)
+}
+
+func TestPaymentUseCase_CapturePayment_ConcurrentCaptureOfAnotherAmount(t *testing.T) {
+	// Arrange: the first capture is held up at the processor
+	repo := repository.NewInMemoryPaymentRepository()
+	reached, release := make(chan struct{}), make(chan struct{})
+	var (
+		mutex    sync.Mutex
+		captures []entity.Money
+	)
+	observed := &observedGateway{PaymentGateway: gateway.NewSimulator(), onCapture: func(amount entity.Money) {
+		mutex.Lock()
+		captures = append(captures, amount)
+		first := len(captures) == 1
+		mutex.Unlock()
+		if first {
+			close(reached)
+			<-release
+		}
+	}}
+	useCase := usecase.NewPaymentUseCase(repo, usecase.WithGateway(observed))
+	manuallyAuthorized(t, useCase)
+	firstDone := make(chan error)
+	go func() {
+		_, err := useCase.CapturePayment(context.Background(), usecase.CaptureRequest{TransactionID: "txn123", Amount: "60.00"})
+		firstDone <- err
+	}()
+	<-reached
+
+	// Act
+	_, secondErr := useCase.CapturePayment(context.Background(), usecase.CaptureRequest{TransactionID: "txn123", Amount: "40.00"})
+	close(release)
+	firstErr := <-firstDone
+
+	// Assert: the second request finishes the claimed capture instead of capturing its own amount
+	assert.ErrorIs(t, secondErr, entity.ErrInvalidTransition)
+	require.NoError(t, firstErr)
+	assert.Equal(t, []entity.Money{usd(6000), usd(6000)}, captures, "only the claimed amount reaches the processor")
+	stored, err := repo.GetByTransactionID(context.Background(), "txn123")
+	require.NoError(t, err)
+	assert.Equal(t, entity.StatusCaptured, stored.Status)
+	assert.Equal(t, usd(6000), stored.CapturedAmount)
+	assert.Nil(t, stored.PendingAction)
+}
+
+// timingOutGateway answers the next timeouts captures with usecase.ErrGatewayTimeout,
+// passing them on to the processor first if captureFirst is set
+type timingOutGateway struct {
+	usecase.PaymentGateway
+	timeouts     int
+	captureFirst bool
+}
+
+func (g *timingOutGateway) Capture(ctx context.Context, reference string, amount entity.Money) (*usecase.GatewayResult, error) {
+	if g.timeouts == 0 {
+		return g.PaymentGateway.Capture(ctx, reference, amount)
+	}
+	g.timeouts--
+	if g.captureFirst {
+		if _, err := g.PaymentGateway.Capture(ctx, reference, amount); err != nil {
+			return nil, err
+		}
+	}
+	return nil, usecase.ErrGatewayTimeout
+}
+
+func TestPaymentUseCase_CapturePayment_TimeoutReconciledThroughStatus(t *testing.T) {
+	// Arrange: the processor captures the payment, but its answer is lost
+	repo := repository.NewInMemoryPaymentRepository()
+	useCase := usecase.NewPaymentUseCase(repo, usecase.WithGateway(&timingOutGateway{PaymentGateway: gateway.NewSimulator(), timeouts: 1, captureFirst: true}))
+	manuallyAuthorized(t, useCase)
+
+	// Act
+	response, err := useCase.CapturePayment(context.Background(), usecase.CaptureRequest{TransactionID: "txn123"})
+
+	// Assert
+	require.NoError(t, err, "the processor's status shows the capture was made")
+	assert.Equal(t, entity.StatusCaptured, response.Status)
+	stored, err := repo.GetByTransactionID(context.Background(), "txn123")
+	require.NoError(t, err)
+	assert.Nil(t, stored.PendingAction)
+}
+
+func TestPaymentUseCase_CapturePayment_TimeoutKeepsTheClaim(t *testing.T) {
+	// Arrange: the capture times out before reaching the processor
+	repo := repository.NewInMemoryPaymentRepository()
+	simulator := gateway.NewSimulator()
+	useCase := usecase.NewPaymentUseCase(repo, usecase.WithGateway(&timingOutGateway{PaymentGateway: simulator, timeouts: 1}))
+	manuallyAuthorized(t, useCase)
+	_, timeoutErr := useCase.CapturePayment(context.Background(), usecase.CaptureRequest{TransactionID: "txn123", Amount: "60.00"})
+	claimed, err := repo.GetByTransactionID(context.Background(), "txn123")
+	require.NoError(t, err)
+
+	// Act: a void arrives next
+	_, voidErr := useCase.VoidPayment(context.Background(), "txn123")
+
+	// Assert: the claimed capture is made before the void is considered
+	assert.ErrorIs(t, timeoutErr, usecase.ErrGatewayTimeout)
+	require.NotNil(t, claimed.PendingAction, "the capture may still reach the processor, so it stays claimed")
+	assert.Equal(t, entity.StatusAuthorized, claimed.Status)
+	assert.ErrorIs(t, voidErr, entity.ErrInvalidTransition)
+	stored, err := repo.GetByTransactionID(context.Background(), "txn123")
+	require.NoError(t, err)
+	assert.Equal(t, entity.StatusCaptured, stored.Status)
+	assert.Equal(t, usd(6000), stored.CapturedAmount)
+	status, err := simulator.Status(context.Background(), gateway.Reference("txn123"))
+	require.NoError(t, err)
+	assert.Equal(t, entity.StatusCaptured, status.Status)
+}
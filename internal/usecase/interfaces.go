@@ -1,41 +1,416 @@
 package usecase
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"payment-service/internal/entity"
+	"time"
 )
 
-// PaymentRepository defines the interface for payment storage
+// PaymentRepository defines the interface for payment storage.
+// Every method gives up with the context's error once ctx is done.
+// Methods that write a payment record the entries of its Journal in the same atomic operation,
+// skipping entries whose ID was recorded before, and clear Journal once they are written.
 type PaymentRepository interface {
-	Store(payment *entity.Payment) error
-	GetByTransactionID(transactionID string) (*entity.Payment, error)
-	Exists(transactionID string) bool
+	Store(ctx context.Context, payment *entity.Payment) error
+	// CreateIfAbsent atomically stores payment unless its transaction ID already exists.
+	// It returns the stored payment and true when created, or the existing payment and false on conflict.
+	CreateIfAbsent(ctx context.Context, payment *entity.Payment) (*entity.Payment, bool, error)
+	// Update saves changes to an existing payment if its Version still matches the stored one,
+	// then increments Version. It fails with ErrConcurrentUpdate if another update won the race.
+	Update(ctx context.Context, payment *entity.Payment) error
+	GetByTransactionID(ctx context.Context, transactionID string) (*entity.Payment, error)
+	Exists(ctx context.Context, transactionID string) bool
+	// List returns at most query.Limit payments matching query, newest first,
+	// starting after query.After when it is set
+	List(ctx context.Context, query PaymentQuery) ([]*entity.Payment, error)
+}
+
+// WalletRepository defines the interface for wallet storage
+type WalletRepository interface {
+	// GetWallet returns a user's wallet, or nil if the user has none yet
+	GetWallet(ctx context.Context, userID string) (*entity.Wallet, error)
+	// SaveWallets writes all wallets in one atomic operation. A wallet with Version 0 must not
+	// exist yet, and any other must match the stored version; otherwise nothing is written and
+	// ErrConcurrentUpdate is returned. On success the Version of every wallet is incremented.
+	// The entries of each wallet's Journal are recorded with it like those of a payment.
+	SaveWallets(ctx context.Context, wallets ...*entity.Wallet) error
+}
+
+// TransferRepository defines the interface for transfer storage
+type TransferRepository interface {
+	// CreateTransferIfAbsent atomically stores the transfer unless its transaction ID already exists.
+	// It returns the stored transfer and whether it was created by this call.
+	CreateTransferIfAbsent(ctx context.Context, transfer *entity.Transfer) (*entity.Transfer, bool, error)
+	// UpdateTransfer saves changes to a stored transfer if its Version matches the stored one,
+	// then increments Version. It fails with ErrConcurrentUpdate if another update won the race.
+	UpdateTransfer(ctx context.Context, transfer *entity.Transfer) error
+	// GetTransfer returns a transfer, or nil if there is none with the transaction ID
+	GetTransfer(ctx context.Context, transactionID string) (*entity.Transfer, error)
+}
+
+// JobQueue holds the jobs of asynchronous payments until a worker processes them. It delivers every job
+// at least once: a leased job that is not completed, retried or buried before its lease runs out is
+// delivered again. Calls on a lease that ran out fail with ErrJobLeaseLost.
+type JobQueue interface {
+	// EnqueueJob adds a job for the transaction, available at now, unless the queue already holds one
+	EnqueueJob(ctx context.Context, transactionID string, now time.Time) error
+	// LeaseJob delivers the queued job that has been available longest at now, incrementing its
+	// Attempts and hiding it until leaseUntil. It returns nil when no job is available.
+	LeaseJob(ctx context.Context, now, leaseUntil time.Time) (*entity.Job, error)
+	// CompleteJob removes the job leased by attempt
+	CompleteJob(ctx context.Context, transactionID string, attempt int) error
+	// RetryJob makes the job leased by attempt available again at retryAt, recording why it failed
+	RetryJob(ctx context.Context, transactionID string, attempt int, lastError string, retryAt, now time.Time) error
+	// ReleaseJob hands the job leased by attempt back, available at now, recording why it was released.
+	// The attempt is not counted, so a job released when its worker stops never uses up a retry for it.
+	ReleaseJob(ctx context.Context, transactionID string, attempt int, lastError string, now time.Time) error
+	// BuryJob moves the job leased by attempt to the dead-letter queue
+	BuryJob(ctx context.Context, transactionID string, attempt int, lastError string, now time.Time) error
+	// DeadJobs lists the dead-lettered jobs, oldest first
+	DeadJobs(ctx context.Context) ([]*entity.Job, error)
+	// CountJobs counts the jobs available, delayed and dead at now
+	CountJobs(ctx context.Context, now time.Time) (*entity.JobCounts, error)
+	// RequeueJob makes a dead-lettered job available at now with no attempts.
+	// It fails with ErrJobNotFound if there is no dead-lettered job for the transaction.
+	RequeueJob(ctx context.Context, transactionID string, now time.Time) (*entity.Job, error)
+}
+
+// EventPublisher receives an event for every status change of a payment, once the change is stored.
+// Publishing must not block on delivery; events are delivered to their receivers asynchronously.
+// Publishing an event again must have no further effect, since a change is published again
+// until it is marked published.
+type EventPublisher interface {
+	Publish(ctx context.Context, event PaymentEvent) error
+}
+
+// EventOutbox tracks which status changes of stored payments have been published as events.
+// Stores write a payment's published count with the payment, so a change stored is never lost
+// before it is published, even if the service stops in between.
+type EventOutbox interface {
+	// UnpublishedPayments returns at most limit payments with changes not yet published, oldest first
+	UnpublishedPayments(ctx context.Context, limit int) ([]*entity.Payment, error)
+	// MarkChangesPublished records that the first published changes of a payment have been published.
+	// It never lowers the count and leaves the payment's version alone, so it never conflicts with an update.
+	MarkChangesPublished(ctx context.Context, transactionID string, published int) error
+}
+
+// PaymentGateway executes card payments at an external payment processor (PSP).
+// Every call is idempotent: repeating it returns the original outcome without charging again.
+// A call abandoned because ctx is done may still have reached the processor; repeat it to learn the outcome.
+// Authorizations that are not captured, including those whose window lapsed, are released with Void.
+type PaymentGateway interface {
+	// Authorize places a hold for the payment amount on the card. A declined authorization
+	// is not an error; it is reported by a result with StatusFailed and a DeclineCode.
+	Authorize(ctx context.Context, req GatewayAuthorization) (*GatewayResult, error)
+	// Capture collects amount, at most the authorized amount, from an authorization
+	Capture(ctx context.Context, reference string, amount entity.Money) (*GatewayResult, error)
+	// Void releases the hold of an authorization that was not captured
+	Void(ctx context.Context, reference string) (*GatewayResult, error)
+	// Refund returns amount of a captured payment to the card, once per refundKey
+	Refund(ctx context.Context, reference, refundKey string, amount entity.Money) (*GatewayResult, error)
+	// Status returns the processor's current view of a payment
+	Status(ctx context.Context, reference string) (*GatewayResult, error)
+	// Candidates returns the processors an authorization may go to, in the order to try them.
+	// A gateway over a single processor returns none, as it has no choice to make.
+	Candidates(req GatewayAuthorization) []string
+}
+
+// GatewayAuthorization asks a processor to authorize a card payment
+type GatewayAuthorization struct {
+	TransactionID string // Idempotency key; authorizing it again returns the original authorization
+	UserID        string
+	MerchantID    string
+	Amount        entity.Money
+	CardToken     string
+	Processor     string // One of the Candidates the authorization must go to; empty lets the gateway choose
+}
+
+// GatewayResult is a processor's answer to a gateway call
+type GatewayResult struct {
+	Reference   string // The processor's ID of the payment
+	Processor   string // Name of the processor that handled the call, when the gateway routes among several
+	Status      string // The payment status at the processor: authorized, captured, partially_refunded, refunded or failed
+	DeclineCode string // Why the processor declined the payment, when Status is failed
+	SoftDecline bool   // The decline is temporary, so another processor or a later retry may approve the payment
 }
 
 // PaymentUseCaseInterface defines the interface for payment use case
 type PaymentUseCaseInterface interface {
-	ProcessPayment(req PaymentRequest) (*PaymentResponse, error)
+	ProcessPayment(ctx context.Context, req PaymentRequest) (*PaymentResponse, error)
+	AuthorizePayment(ctx context.Context, transactionID string) (*PaymentResponse, error)
+	CapturePayment(ctx context.Context, req CaptureRequest) (*PaymentResponse, error)
+	VoidPayment(ctx context.Context, transactionID string) (*PaymentResponse, error)
+	RefundPayment(ctx context.Context, req RefundRequest) (*RefundResponse, error)
+	GetPayment(ctx context.Context, transactionID string) (*entity.Payment, error)
+	ListPayments(ctx context.Context, req ListPaymentsRequest) (*PaymentListResponse, error)
+	GetStatement(ctx context.Context, userID, month string) (*entity.Statement, error)
+	GetWallet(ctx context.Context, userID string) (*entity.Wallet, error)
+	TopUpWallet(ctx context.Context, req WalletRequest) (*WalletResponse, error)
+	DebitWallet(ctx context.Context, req WalletRequest) (*WalletResponse, error)
+	TransferFunds(ctx context.Context, req TransferRequest) (*TransferResponse, error)
+	GetTransferHistory(ctx context.Context, userID, counterparty string) (*TransferHistoryResponse, error)
+	ApplyProviderEvent(ctx context.Context, event ProviderEvent) (*ProviderEventResponse, error)
 }
 
+// Capture methods for PaymentRequest.CaptureMethod
+const (
+	CaptureAutomatic = "automatic"
+	CaptureManual    = "manual"
+)
+
+// Processing modes for PaymentRequest.ProcessingMode
+const (
+	ProcessingSync  = "sync"
+	ProcessingAsync = "async"
+)
+
 // PaymentRequest represents the request payload for payment
 type PaymentRequest struct {
-	UserID        string  `json:"user_id" example:"user123" validate:"required"`        // User ID for the payment
-	Amount        float64 `json:"amount" example:"99.99" validate:"required,gt=0"`      // Payment amount (must be greater than 0)
-	TransactionID string  `json:"transaction_id" example:"txn-456" validate:"required"` // Unique transaction ID for idempotency
+	UserID         string `json:"user_id" example:"user123" validate:"required"`                         // User ID for the payment
+	Amount         string `json:"amount" example:"99.99" validate:"required"`                            // Payment amount as a decimal string (must be greater than 0)
+	Currency       string `json:"currency" example:"USD" validate:"required"`                            // ISO 4217 currency code
+	TransactionID  string `json:"transaction_id" example:"txn-456" validate:"required"`                  // Unique transaction ID for idempotency
+	CaptureMethod  string `json:"capture_method,omitempty" example:"automatic" enums:"automatic,manual"` // "automatic" (default) captures immediately; "manual" leaves the payment pending for authorize/capture
+	PaymentMethod  string `json:"payment_method,omitempty" example:"card" enums:"card,wallet"`           // "card" (default) or "wallet" to pay from the user's wallet balance; wallet payments are captured automatically
+	CardToken      string `json:"card_token,omitempty" example:"tok_visa"`                               // Processor token of the card to charge; the simulated processor also accepts its test card numbers
+	MerchantID     string `json:"merchant_id,omitempty" example:"merchant-1"`                            // Merchant the payment is made to; card payments may be routed by merchant
+	ProcessingMode string `json:"processing_mode,omitempty" example:"sync" enums:"sync,async"`           // "sync" (default) processes the payment before answering; "async" queues it for a worker and answers 202 with the pending payment
+}
+
+// CaptureRequest represents the request payload for capturing an authorized payment
+type CaptureRequest struct {
+	TransactionID string `json:"-"`                                // Transaction ID of the payment, taken from the URL
+	Amount        string `json:"amount,omitempty" example:"50.00"` // Amount to capture as a decimal string; omit to capture the full authorization
+}
+
+// RefundRequest represents the request payload for refunding a captured payment
+type RefundRequest struct {
+	TransactionID  string `json:"-"`                                                                                               // Transaction ID of the payment, taken from the URL
+	Amount         string `json:"amount,omitempty" example:"25.00"`                                                                // Amount to refund as a decimal string; omit to refund everything not yet refunded
+	Reason         string `json:"reason" example:"requested_by_customer" enums:"requested_by_customer,duplicate,fraudulent,other"` // Refund reason code
+	IdempotencyKey string `json:"idempotency_key" example:"refund-789"`                                                            // Unique key per refund; retrying with the same key never refunds twice
+}
+
+// RefundResponse represents the response for a refund
+type RefundResponse struct {
+	RefundID       string    `json:"refund_id" example:"txn-456-refund-1"`            // Refund ID
+	TransactionID  string    `json:"transaction_id" example:"txn-456"`                // Transaction ID of the refunded payment
+	Amount         string    `json:"amount" example:"25.00"`                          // Refunded amount as a decimal string
+	Currency       string    `json:"currency" example:"USD"`                          // ISO 4217 currency code
+	Reason         string    `json:"reason" example:"requested_by_customer"`          // Refund reason code
+	IdempotencyKey string    `json:"idempotency_key" example:"refund-789"`            // Idempotency key of the refund
+	CreatedAt      time.Time `json:"created_at" example:"2025-01-01T10:00:00Z"`       // When the refund was made
+	PaymentStatus  string    `json:"payment_status" example:"partially_refunded"`     // Status of the payment after the refund
+	RefundedAmount string    `json:"refunded_amount" example:"25.00"`                 // Total refunded for the payment so far
+	Message        string    `json:"message" example:"Refund processed successfully"` // Status message
+}
+
+// WalletRequest represents the request payload for a wallet top-up or debit
+type WalletRequest struct {
+	UserID         string `json:"-"`                                    // Wallet owner, taken from the URL
+	Amount         string `json:"amount" example:"50.00"`               // Amount as a decimal string (must be greater than 0)
+	Currency       string `json:"currency" example:"USD"`               // ISO 4217 currency code
+	IdempotencyKey string `json:"idempotency_key" example:"top-up-123"` // Unique key per operation; retrying with the same key never applies it twice
+}
+
+// WalletResponse represents the response for a wallet top-up or debit
+type WalletResponse struct {
+	UserID              string     `json:"user_id" example:"user123"`                                   // Wallet owner
+	WalletTransactionID string     `json:"wallet_transaction_id,omitempty" example:"top_up:top-up-123"` // ID of the wallet transaction
+	Type                string     `json:"type,omitempty" example:"top_up"`                             // Transaction type (top_up or debit)
+	Amount              string     `json:"amount" example:"50.00"`                                      // Amount as a decimal string
+	Currency            string     `json:"currency" example:"USD"`                                      // ISO 4217 currency code
+	Balance             string     `json:"balance,omitempty" example:"150.00"`                          // Wallet balance in the currency after the operation
+	CreatedAt           *time.Time `json:"created_at,omitempty"`                                        // When the operation was applied
+	Message             string     `json:"message" example:"Wallet topped up"`                          // Status message
+}
+
+// TransferRequest represents the request payload for a transfer between users
+type TransferRequest struct {
+	FromUserID    string `json:"from_user_id" example:"user123" validate:"required"`        // Sender, whose wallet is debited
+	ToUserID      string `json:"to_user_id" example:"user456" validate:"required"`          // Recipient, whose wallet is credited
+	Amount        string `json:"amount" example:"25.00" validate:"required"`                // Amount as a decimal string (must be greater than 0)
+	Currency      string `json:"currency" example:"USD" validate:"required"`                // ISO 4217 currency code
+	TransactionID string `json:"transaction_id" example:"transfer-789" validate:"required"` // Unique transaction ID for idempotency
+}
+
+// TransferResponse represents the response for a transfer
+type TransferResponse struct {
+	TransactionID string     `json:"transaction_id" example:"transfer-789"` // Transaction ID
+	FromUserID    string     `json:"from_user_id" example:"user123"`        // Sender
+	ToUserID      string     `json:"to_user_id" example:"user456"`          // Recipient
+	Amount        string     `json:"amount" example:"25.00"`                // Amount as a decimal string
+	Currency      string     `json:"currency" example:"USD"`                // ISO 4217 currency code
+	Status        string     `json:"status" example:"completed"`            // Transfer status (pending, completed, declined, failed)
+	CreatedAt     *time.Time `json:"created_at,omitempty"`                  // When the transfer was first requested
+	CompletedAt   *time.Time `json:"completed_at,omitempty"`                // When the funds moved
+	Message       string     `json:"message" example:"Transfer completed"`  // Status message
+}
+
+// TransferHistoryResponse lists the transfers a user sent and received, newest first
+type TransferHistoryResponse struct {
+	UserID       string                 `json:"user_id" example:"user123"`
+	Counterparty string                 `json:"counterparty,omitempty" example:"user456"` // Only transfers with this user, when set
+	Transfers    []TransferHistoryEntry `json:"transfers"`
+}
+
+// TransferHistoryEntry is one transfer as seen by one of its parties
+type TransferHistoryEntry struct {
+	TransactionID string       `json:"transaction_id" example:"transfer-789"`
+	Direction     string       `json:"direction" example:"sent" enums:"sent,received"` // Whether the user sent or received the funds
+	Counterparty  string       `json:"counterparty" example:"user456"`                 // The other user
+	Amount        entity.Money `json:"amount"`
+	Balance       entity.Money `json:"balance"` // The user's balance in the currency after the transfer
+	At            time.Time    `json:"at"`
+}
+
+// Transfer directions of TransferHistoryEntry
+const (
+	TransferSent     = "sent"
+	TransferReceived = "received"
+)
+
+// ListPaymentsRequest represents the filters and page of a payment listing. Empty fields do not filter.
+type ListPaymentsRequest struct {
+	UserID      string // Only payments of this user
+	Status      string // Only payments in this status
+	Currency    string // Only payments in this ISO 4217 currency
+	MinAmount   string // Smallest amount as a decimal string, inclusive; requires Currency
+	MaxAmount   string // Largest amount as a decimal string, inclusive; requires Currency
+	CreatedFrom string // Earliest creation time in RFC 3339 format, inclusive
+	CreatedTo   string // Latest creation time in RFC 3339 format, exclusive
+	Cursor      string // next_cursor of the previous page
+	Limit       int    // Page size; 0 uses the default
+}
+
+// PaymentListResponse represents one page of a payment listing
+type PaymentListResponse struct {
+	Payments   []*entity.Payment `json:"payments"`                                            // Payments, newest first
+	NextCursor string            `json:"next_cursor,omitempty" example:"MjAyNS0wMS0wMVQxMDo"` // Cursor of the next page; absent on the last page
 }
 
 // PaymentResponse represents the response for payment
 type PaymentResponse struct {
-	TransactionID string  `json:"transaction_id" example:"txn-456"`                 // Transaction ID
-	UserID        string  `json:"user_id" example:"user123"`                        // User ID
-	Amount        float64 `json:"amount" example:"99.99"`                           // Payment amount
-	Status        string  `json:"status" example:"success"`                         // Payment status (success, failed)
-	Message       string  `json:"message" example:"Payment processed successfully"` // Status message
+	TransactionID          string     `json:"transaction_id" example:"txn-456"`                                  // Transaction ID
+	UserID                 string     `json:"user_id" example:"user123"`                                         // User ID
+	Amount                 string     `json:"amount" example:"99.99"`                                            // Payment amount as a decimal string
+	Currency               string     `json:"currency" example:"USD"`                                            // ISO 4217 currency code
+	Status                 string     `json:"status" example:"captured"`                                         // Payment status (pending, authorized, captured, voided, refunded, partially_refunded, failed, settled, disputed, charged_back)
+	Message                string     `json:"message" example:"Payment processed successfully"`                  // Status message
+	CapturedAmount         string     `json:"captured_amount,omitempty" example:"99.99"`                         // Amount captured so far as a decimal string
+	PaymentMethod          string     `json:"payment_method,omitempty" example:"card"`                           // How the payment is paid (card or wallet)
+	AuthorizationExpiresAt *time.Time `json:"authorization_expires_at,omitempty" example:"2025-01-08T10:00:00Z"` // When an uncaptured authorization lapses
+	RefundedAmount         string     `json:"refunded_amount,omitempty" example:"25.00"`                         // Amount refunded so far as a decimal string
+	ProcessorReference     string     `json:"processor_reference,omitempty" example:"primary:sim_txn-456"`       // The processor's ID of a card payment
+	Processor              string     `json:"processor,omitempty" example:"primary"`                             // Processor a card payment is authorized at, stored before it is called
+	Queued                 bool       `json:"queued,omitempty" example:"false"`                                  // Whether the payment waits for a worker to process it
+}
+
+// PaymentEvent reports a status change of a payment
+type PaymentEvent struct {
+	ID        string           `json:"id" example:"evt_txn-456_2"`                // Unique per status change, so receivers can drop repeated deliveries
+	Type      string           `json:"type" example:"payment.captured"`           // "payment." followed by the new status
+	CreatedAt time.Time        `json:"created_at" example:"2025-01-01T10:00:00Z"` // When the status changed
+	Data      PaymentEventData `json:"data"`                                      // The payment and its status change
+}
+
+// PaymentEventData describes a payment and one change of its status
+type PaymentEventData struct {
+	TransactionID  string `json:"transaction_id" example:"txn-456"`               // Transaction ID
+	UserID         string `json:"user_id" example:"user123"`                      // User ID
+	MerchantID     string `json:"merchant_id,omitempty" example:"merchant-1"`     // Merchant the payment is made to
+	Amount         string `json:"amount" example:"99.99"`                         // Payment amount as a decimal string
+	Currency       string `json:"currency" example:"USD"`                         // ISO 4217 currency code
+	Status         string `json:"status" example:"captured"`                      // The new status
+	PreviousStatus string `json:"previous_status,omitempty" example:"authorized"` // The status before the change; empty for a new payment
+	Actor          string `json:"actor" example:"system"`                         // Who made the change
+	Reason         string `json:"reason" example:"payment captured"`              // Why the status changed
+}
+
+// Types of ProviderEvent
+const (
+	ProviderEventSettled       = "settled"        // The processor paid out the captured funds
+	ProviderEventDeclined      = "declined"       // The processor declined an authorization after approving it provisionally
+	ProviderEventDisputeOpened = "dispute_opened" // The cardholder disputed the charge
+	ProviderEventDisputeWon    = "dispute_won"    // The dispute was decided for the merchant
+	ProviderEventDisputeLost   = "dispute_lost"   // The dispute was decided for the cardholder and the funds returned
+)
+
+// ProviderEvent is an asynchronous notification from a payment processor about a payment it handled,
+// decoded from the processor's callback once its signature has been verified
+type ProviderEvent struct {
+	ID            string // The processor's event ID; an event is applied once however often it is delivered
+	Provider      string // Name of the processor that sent the event
+	Type          string // One of the ProviderEvent constants
+	TransactionID string // Transaction ID the payment was authorized with
+	Reference     string // The processor's own ID of the payment
+	Reason        string // Why the processor sent the event, such as a decline or dispute reason
+}
+
+// ProviderEventResponse represents the outcome of a processor event
+type ProviderEventResponse struct {
+	EventID       string `json:"event_id" example:"evt_123"`               // The processor's event ID
+	TransactionID string `json:"transaction_id" example:"txn-456"`         // Transaction ID of the payment
+	Status        string `json:"status,omitempty" example:"settled"`       // Payment status after the event
+	Applied       bool   `json:"applied" example:"true"`                   // Whether the event changed the payment
+	Message       string `json:"message" example:"Payment status updated"` // Status message
 }
 
 var (
 	ErrInvalidAmount        = errors.New("amount must be greater than 0")
+	ErrInvalidAmountFormat  = errors.New("amount must be a decimal string with no more decimal places than the currency allows")
+	ErrInvalidCurrency      = errors.New("currency must be a supported ISO 4217 code")
+	ErrInvalidCaptureMethod = errors.New("capture_method must be \"automatic\" or \"manual\"")
+	ErrInvalidCaptureAmount = errors.New("capture amount must be greater than 0 and no more than the authorized amount")
+	ErrInvalidRefundAmount  = errors.New("refund amount must be greater than 0 and no more than the captured amount not yet refunded")
+	ErrInvalidRefundReason  = errors.New("reason must be one of requested_by_customer, duplicate, fraudulent or other")
+	ErrMissingRefundKey     = errors.New("refund idempotency key cannot be empty")
+	ErrInvalidStatus        = errors.New("status must be a known payment status")
+	ErrInvalidAmountRange   = errors.New("min_amount and max_amount must be decimal amounts in the given currency, with min_amount no more than max_amount")
+	ErrInvalidDateRange     = errors.New("created_from and created_to must be RFC 3339 times, with created_from before created_to")
+	ErrInvalidCursor        = errors.New("cursor is not valid")
+	ErrInvalidLimit         = errors.New("limit must be between 1 and 200")
+	ErrInvalidMonth         = errors.New("month must be formatted as YYYY-MM")
+	ErrPaymentNotFound      = errors.New("payment not found")
+	ErrAuthorizationExpired = errors.New("authorization has expired")
+	ErrConcurrentUpdate     = errors.New("payment was modified concurrently")
 	ErrInvalidUserID        = errors.New("user ID cannot be empty")
 	ErrInvalidTransaction   = errors.New("transaction ID cannot be empty")
 	ErrDuplicateTransaction = errors.New("transaction already processed")
+	// ErrIdempotencyConflict is returned when a transaction ID is retried with a different payload
+	ErrIdempotencyConflict = fmt.Errorf("%w with a different request payload", ErrDuplicateTransaction)
+	// ErrRefundConflict is returned when a refund idempotency key is retried with a different amount or reason
+	ErrRefundConflict = errors.New("refund idempotency key already used with a different amount or reason")
+	// ErrInsufficientFunds is returned when a wallet balance is lower than the amount to pay or debit
+	ErrInsufficientFunds    = errors.New("insufficient funds")
+	ErrInvalidPaymentMethod = errors.New("payment_method must be \"card\" or \"wallet\", and wallet payments must be captured automatically")
+	ErrMissingWalletKey     = errors.New("wallet idempotency key cannot be empty")
+	// ErrWalletConflict is returned when a wallet idempotency key is retried with a different amount or currency
+	ErrWalletConflict    = errors.New("wallet idempotency key already used with a different amount or currency")
+	ErrTransferNotFound  = errors.New("transfer not found")
+	ErrTransfersDisabled = errors.New("transfers are not enabled")
+	ErrSelfTransfer      = errors.New("cannot transfer to the same user")
+	// ErrTransferLimitExceeded is returned when a transfer is larger than the per-transfer limit or would exceed the sender's daily limit
+	ErrTransferLimitExceeded = errors.New("transfer exceeds the transfer limits")
+	// ErrTransferCurrencyNotAllowed is returned when transfer limits are set, but none for the transfer's currency
+	ErrTransferCurrencyNotAllowed = errors.New("transfers are not allowed in the currency")
+	// ErrPaymentDeclined is returned when the payment processor declines a card payment
+	ErrPaymentDeclined = errors.New("payment declined by the processor")
+	// ErrGatewayTimeout is returned when the payment processor does not answer in time; the outcome is unknown until retried
+	ErrGatewayTimeout = errors.New("payment processor timed out")
+	// ErrGatewayUnavailable is returned when the payment processor fails to handle a request
+	ErrGatewayUnavailable = errors.New("payment processor unavailable")
+	// ErrWalletsDisabled is returned by wallet operations when the use case has no wallet repository
+	ErrWalletsDisabled = errors.New("wallets are not enabled")
+	// ErrInvalidProcessingMode is returned for an unknown processing mode, or an asynchronous payment with manual capture
+	ErrInvalidProcessingMode = errors.New("processing_mode must be \"sync\" or \"async\", and async payments must be captured automatically")
+	// ErrAsyncDisabled is returned for an asynchronous payment when the use case has no job queue shared with workers
+	ErrAsyncDisabled = errors.New("asynchronous processing is not enabled")
+	ErrJobNotFound   = errors.New("job not found")
+	// ErrJobLeaseLost is returned for a job whose lease ran out, so it may be delivered to another worker
+	ErrJobLeaseLost = errors.New("job lease ran out")
+	// ErrInvalidProviderEvent is returned for a processor event without an ID or transaction ID, or of an unknown type
+	ErrInvalidProviderEvent = errors.New("processor event must have an id, a transaction ID and a known type")
 )
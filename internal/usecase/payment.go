@@ -1,90 +1,937 @@
 package usecase
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"payment-service/internal/entity"
+	"slices"
 	"time"
 )
 
+// Actors recorded in the status history
+const (
+	// SystemActor makes changes the service performs by itself
+	SystemActor = "system"
+	// MerchantActor makes changes requested through the payment action endpoints
+	MerchantActor = "merchant"
+	// ProcessorActor makes changes reported by the payment processor's callbacks
+	ProcessorActor = "processor"
+)
+
+// DefaultAuthorizationWindow is how long an authorization can be captured before it lapses
+const DefaultAuthorizationWindow = 7 * 24 * time.Hour
+
+// Page sizes of payment listings
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 200
+)
+
+// maxUpdateAttempts bounds the retries of an update that loses an optimistic concurrency race
+const maxUpdateAttempts = 5
+
 // PaymentUseCase handles payment business logic
 type PaymentUseCase struct {
-	repo PaymentRepository
+	repo                PaymentRepository
+	wallets             WalletRepository
+	transfers           TransferRepository
+	transferLimits      map[string]TransferLimits
+	gateway             PaymentGateway
+	events              EventPublisher
+	outbox              EventOutbox
+	jobs                JobQueue
+	feeBasisPoints      int64
+	authorizationWindow time.Duration
+	now                 func() time.Time
+}
+
+// Option configures optional PaymentUseCase behaviour
+type Option func(*PaymentUseCase)
+
+// WithAuthorizationWindow sets how long an authorization can be captured before it lapses
+func WithAuthorizationWindow(window time.Duration) Option {
+	return func(p *PaymentUseCase) {
+		p.authorizationWindow = window
+	}
+}
+
+// WithGateway charges card payments through a payment processor.
+// Without one, the service approves card payments by itself.
+func WithGateway(gateway PaymentGateway) Option {
+	return func(p *PaymentUseCase) {
+		p.gateway = gateway
+	}
+}
+
+// WithProcessingFee charges a fee of basisPoints hundredths of a percent on every captured amount.
+// Fees only show in the ledger.
+func WithProcessingFee(basisPoints int64) Option {
+	return func(p *PaymentUseCase) {
+		p.feeBasisPoints = basisPoints
+	}
 }
 
 // NewPaymentUseCase creates a new payment use case
-func NewPaymentUseCase(repo PaymentRepository) *PaymentUseCase {
-	return &PaymentUseCase{
-		repo: repo,
+func NewPaymentUseCase(repo PaymentRepository, opts ...Option) *PaymentUseCase {
+	p := &PaymentUseCase{
+		repo:                repo,
+		authorizationWindow: DefaultAuthorizationWindow,
+		now:                 time.Now,
 	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
 // ProcessPayment processes a payment request with idempotency
-func (p *PaymentUseCase) ProcessPayment(req PaymentRequest) (*PaymentResponse, error) {
+func (p *PaymentUseCase) ProcessPayment(ctx context.Context, req PaymentRequest) (*PaymentResponse, error) {
 	// Validate request
-	if err := p.validateRequest(req); err != nil {
-		return &PaymentResponse{
-			TransactionID: req.TransactionID,
-			UserID:        req.UserID,
-			Amount:        req.Amount,
-			Status:        entity.StatusFailed,
-			Message:       err.Error(),
-		}, err
-	}
-
-	// Check if transaction already exists (idempotency)
-	if p.repo.Exists(req.TransactionID) {
-		existingPayment, err := p.repo.GetByTransactionID(req.TransactionID)
+	amount, err := p.validateRequest(req)
+	if err != nil {
+		return failedResponse(req, err.Error()), err
+	}
+
+	// Create new payment. Automatic capture runs it through authorization and capture
+	// straight away; manual capture leaves it pending for the authorize and capture actions.
+	now := p.now()
+	payment := entity.NewPayment(req.TransactionID, req.UserID, amount, req.UserID, "payment requested", now)
+	payment.RequestFingerprint = fingerprint(req)
+	payment.SetCardToken(req.CardToken)
+	payment.MerchantID = req.MerchantID
+	if req.PaymentMethod == entity.PaymentMethodWallet {
+		payment.PaymentMethod = entity.PaymentMethodWallet
+	}
+	if req.ProcessingMode == ProcessingAsync {
+		return p.queuePayment(ctx, req, payment)
+	}
+	automatic := req.CaptureMethod != CaptureManual
+	charged := p.chargesCard(payment)
+	if automatic && !charged {
+		if err := p.authorize(payment, SystemActor, now); err != nil {
+			return failedResponse(req, "Failed to process payment"), err
+		}
+		if err := p.capture(payment, payment.Amount, SystemActor, now); err != nil {
+			return failedResponse(req, "Failed to process payment"), err
+		}
+	}
+
+	// Wallet payments take the funds before the payment is stored. The wallet transaction is keyed
+	// by the transaction ID, so retries and concurrent duplicates never charge twice.
+	debited := false
+	if payment.PaymentMethod == entity.PaymentMethodWallet {
+		existing, err := p.repo.GetByTransactionID(ctx, payment.TransactionID)
+		if err != nil {
+			return failedResponse(req, "Failed to process payment"), err
+		}
+		if existing == nil {
+			if debited, err = p.payFromWallet(ctx, payment); err != nil {
+				return failedResponse(req, err.Error()), err
+			}
+		}
+	}
+
+	// Store payment unless the transaction already exists (idempotency).
+	// The check and the insert are a single atomic repository operation,
+	// so concurrent retries can never charge twice. Funds already taken are
+	// recorded even if the client has gone away in the meantime. Card payments
+	// are stored pending and charged afterwards, see authorizeCard.
+	if debited {
+		ctx = context.WithoutCancel(ctx)
+	}
+	stored, created, err := p.repo.CreateIfAbsent(ctx, payment)
+	if err != nil {
+		return failedResponse(req, "Failed to process payment"), err
+	}
+
+	if !created {
+		// A different request won the transaction ID; the funds this one took go back
+		if debited && stored.RequestFingerprint != payment.RequestFingerprint {
+			if err := p.reverseWalletPayment(ctx, payment); err != nil {
+				return failedResponse(req, "Failed to process payment"), err
+			}
+		}
+
+		// Payments stored before fingerprinting have no fingerprint and are replayed as-is
+		if stored.RequestFingerprint != "" && stored.RequestFingerprint != payment.RequestFingerprint {
+			return failedResponse(req, ErrIdempotencyConflict.Error()), ErrIdempotencyConflict
+		}
+		// The request that stored the payment may have stopped before the processor charged the
+		// card or captured it
+		if charged && automatic && stored.RequestFingerprint != "" && stored.Status == entity.StatusPending {
+			if stored, err = p.authorizeCard(ctx, stored.TransactionID, SystemActor, true); err != nil && !errors.Is(err, ErrPaymentDeclined) {
+				return actionFailedResponse(req.TransactionID, stored, err), err
+			}
+		} else if stored.PendingAction != nil {
+			if stored, err = p.finishAction(ctx, stored); err != nil {
+				return paymentResponse(stored, err.Error()), err
+			}
+		}
+		if stored.Status == entity.StatusFailed {
+			return paymentResponse(stored, declineReason(stored)), ErrPaymentDeclined
+		}
+		return paymentResponse(stored, "Transaction already processed"), nil
+	}
+
+	p.publishChanges(ctx, payment)
+	if charged && automatic {
+		if payment, err = p.authorizeCard(ctx, payment.TransactionID, SystemActor, true); err != nil {
+			return actionFailedResponse(req.TransactionID, payment, err), err
+		}
+	}
+	if payment.Status == entity.StatusPending {
+		return paymentResponse(payment, "Payment created, awaiting authorization"), nil
+	}
+	return paymentResponse(payment, "Payment processed successfully"), nil
+}
+
+// AuthorizePayment places a hold for the full amount of a pending payment.
+// Authorizing an already authorized payment returns it unchanged.
+func (p *PaymentUseCase) AuthorizePayment(ctx context.Context, transactionID string) (*PaymentResponse, error) {
+	message := "Payment authorized"
+	card := false
+	payment, err := p.act(ctx, transactionID, func(payment *entity.Payment, now time.Time) (bool, error) {
+		if payment.AuthorizationExpired(now) {
+			return true, p.expireAuthorization(payment, now)
+		}
+		if payment.Status == entity.StatusAuthorized {
+			message = "Payment already authorized"
+			return false, nil
+		}
+		if p.chargesCard(payment) {
+			if !entity.CanTransition(payment.Status, entity.StatusAuthorized) {
+				return false, &entity.TransitionError{From: payment.Status, To: entity.StatusAuthorized}
+			}
+			// The processor authorizes card payments outside the update
+			card = true
+			return false, nil
+		}
+		return true, p.authorize(payment, MerchantActor, now)
+	})
+	if err == nil && card {
+		payment, err = p.authorizeCard(ctx, transactionID, MerchantActor, false)
+	}
+	if err != nil {
+		return actionFailedResponse(transactionID, payment, err), err
+	}
+	return paymentResponse(payment, message), nil
+}
+
+// CapturePayment captures an authorized payment, either in full or for a smaller amount.
+// Any uncaptured remainder of a partial capture is released. Repeating a capture of the
+// same amount returns the captured payment unchanged. Capturing a lapsed authorization
+// voids it and fails with ErrAuthorizationExpired.
+func (p *PaymentUseCase) CapturePayment(ctx context.Context, req CaptureRequest) (*PaymentResponse, error) {
+	message := "Payment captured"
+	payment, err := p.act(ctx, req.TransactionID, func(payment *entity.Payment, now time.Time) (bool, error) {
+		amount := payment.Amount
+		if req.Amount != "" {
+			parsed, err := entity.ParseMoney(req.Amount, payment.Amount.Currency)
+			if err != nil || !parsed.IsPositive() || parsed.Amount > payment.Amount.Amount {
+				return false, ErrInvalidCaptureAmount
+			}
+			amount = parsed
+		}
+
+		if payment.Status == entity.StatusCaptured && payment.CapturedAmount == amount {
+			message = "Payment already captured"
+			return false, nil
+		}
+		if payment.AuthorizationExpired(now) {
+			return true, p.expireAuthorization(payment, now)
+		}
+		return true, p.capture(payment, amount, MerchantActor, now)
+	})
+	if err != nil {
+		return actionFailedResponse(req.TransactionID, payment, err), err
+	}
+	return paymentResponse(payment, message), nil
+}
+
+// VoidPayment releases the hold of an authorized payment without capturing it.
+// Voiding an already voided payment returns it unchanged.
+func (p *PaymentUseCase) VoidPayment(ctx context.Context, transactionID string) (*PaymentResponse, error) {
+	message := "Payment voided"
+	payment, err := p.act(ctx, transactionID, func(payment *entity.Payment, now time.Time) (bool, error) {
+		if payment.Status == entity.StatusVoided {
+			message = "Payment already voided"
+			return false, nil
+		}
+		if err := p.void(payment, MerchantActor, "authorization voided", now); err != nil {
+			return false, err
+		}
+		return true, nil
+	})
+	if err != nil {
+		return actionFailedResponse(transactionID, payment, err), err
+	}
+	return paymentResponse(payment, message), nil
+}
+
+// RefundPayment refunds part or all of a captured payment. Refunds may be repeated until
+// the captured amount is used up; the payment becomes partially_refunded, then refunded.
+// Retrying with the same idempotency key returns the original refund without refunding twice.
+func (p *PaymentUseCase) RefundPayment(ctx context.Context, req RefundRequest) (*RefundResponse, error) {
+	if req.IdempotencyKey == "" {
+		return refundFailedResponse(req, nil, ErrMissingRefundKey), ErrMissingRefundKey
+	}
+	if !entity.IsRefundReason(req.Reason) {
+		return refundFailedResponse(req, nil, ErrInvalidRefundReason), ErrInvalidRefundReason
+	}
+
+	message := "Refund processed successfully"
+	var refund entity.Refund
+	payment, err := p.updatePayment(ctx, req.TransactionID, func(payment *entity.Payment, now time.Time) (bool, error) {
+		if existing := payment.FindRefund(req.IdempotencyKey); existing != nil {
+			if existing.Reason != req.Reason {
+				return false, ErrRefundConflict
+			}
+			if req.Amount != "" {
+				if parsed, err := entity.ParseMoney(req.Amount, payment.Amount.Currency); err != nil || parsed != existing.Amount {
+					return false, ErrRefundConflict
+				}
+			}
+			refund = *existing
+			message = "Refund already processed"
+			return false, nil
+		}
+
+		if !entity.CanTransition(payment.Status, entity.StatusRefunded) {
+			return false, &entity.TransitionError{From: payment.Status, To: entity.StatusRefunded}
+		}
+		amount := payment.RefundableAmount()
+		if req.Amount != "" {
+			parsed, err := entity.ParseMoney(req.Amount, payment.Amount.Currency)
+			if err != nil || !parsed.IsPositive() || parsed.Amount > amount.Amount {
+				return false, ErrInvalidRefundAmount
+			}
+			amount = parsed
+		}
+
+		refund = entity.Refund{
+			RefundID:       fmt.Sprintf("%s-refund-%d", payment.TransactionID, len(payment.Refunds)+1),
+			IdempotencyKey: req.IdempotencyKey,
+			Amount:         amount,
+			Reason:         req.Reason,
+			CreatedAt:      now,
+		}
+		return true, p.refund(ctx, payment, refund, MerchantActor, now)
+	})
+	if err != nil {
+		return refundFailedResponse(req, payment, err), err
+	}
+	if payment.PaymentMethod == entity.PaymentMethodWallet {
+		if err := p.refundToWallet(ctx, payment, refund); err != nil {
+			return refundResponse(payment, refund, "Refund recorded but not returned to the wallet; retry the request"), err
+		}
+	}
+	return refundResponse(payment, refund, message), nil
+}
+
+// GetPayment returns a stored payment, failing with ErrPaymentNotFound if it does not exist.
+// An authorization whose window has lapsed is voided first, so it never reads as authorized.
+func (p *PaymentUseCase) GetPayment(ctx context.Context, transactionID string) (*entity.Payment, error) {
+	if transactionID == "" {
+		return nil, ErrInvalidTransaction
+	}
+	payment, err := p.repo.GetByTransactionID(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if payment == nil {
+		return nil, ErrPaymentNotFound
+	}
+	if payment.AuthorizationExpired(p.now()) {
+		return p.voidLapsedAuthorization(ctx, transactionID)
+	}
+	return payment, nil
+}
+
+// ExpireAuthorizations voids every authorization whose window has lapsed and returns how many it voided.
+// Reads of a single payment void its lapsed authorization themselves; running this periodically keeps
+// listings and statements from reporting lapsed authorizations as authorized.
+func (p *PaymentUseCase) ExpireAuthorizations(ctx context.Context) (int, error) {
+	now := p.now()
+	query := PaymentQuery{Status: entity.StatusAuthorized, Limit: MaxPageSize}
+	expired := 0
+	for {
+		page, err := p.repo.List(ctx, query)
+		if err != nil {
+			return expired, err
+		}
+		for _, payment := range page {
+			if !payment.AuthorizationExpired(now) {
+				continue
+			}
+			if _, err := p.voidLapsedAuthorization(ctx, payment.TransactionID); err != nil {
+				return expired, err
+			}
+			expired++
+		}
+		if len(page) < query.Limit {
+			return expired, nil
+		}
+		cursor := CursorOf(page[len(page)-1])
+		query.After = &cursor
+	}
+}
+
+// voidLapsedAuthorization voids the payment's authorization if it has lapsed and returns the payment
+func (p *PaymentUseCase) voidLapsedAuthorization(ctx context.Context, transactionID string) (*entity.Payment, error) {
+	payment, err := p.act(ctx, transactionID, func(payment *entity.Payment, now time.Time) (bool, error) {
+		if !payment.AuthorizationExpired(now) {
+			return false, nil
+		}
+		return true, p.expireAuthorization(payment, now)
+	})
+	if errors.Is(err, ErrAuthorizationExpired) {
+		return payment, nil
+	}
+	return payment, err
+}
+
+// ListPayments returns one page of the payments matching the request's filters, newest first.
+// Pass the returned NextCursor back to fetch the following page.
+func (p *PaymentUseCase) ListPayments(ctx context.Context, req ListPaymentsRequest) (*PaymentListResponse, error) {
+	query, err := listQuery(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch one payment more than the page holds to learn whether another page follows
+	limit := query.Limit
+	query.Limit++
+	payments, err := p.repo.List(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &PaymentListResponse{Payments: payments}
+	if len(payments) > limit {
+		response.Payments = payments[:limit]
+		response.NextCursor = CursorOf(payments[limit-1]).Encode()
+	}
+	if response.Payments == nil {
+		response.Payments = []*entity.Payment{}
+	}
+	return response, nil
+}
+
+// GetStatement builds a user's statement for a calendar month (UTC) given as YYYY-MM.
+// It reads the user's payments through the repository's user index.
+func (p *PaymentUseCase) GetStatement(ctx context.Context, userID, month string) (*entity.Statement, error) {
+	if userID == "" {
+		return nil, ErrInvalidUserID
+	}
+	periodStart, err := time.Parse("2006-01", month)
+	if err != nil {
+		return nil, ErrInvalidMonth
+	}
+
+	// Payments made before the month still matter for refunds made during it
+	periodEnd := periodStart.AddDate(0, 1, 0)
+	query := PaymentQuery{UserID: userID, CreatedTo: &periodEnd, Limit: MaxPageSize}
+	var payments []*entity.Payment
+	for {
+		page, err := p.repo.List(ctx, query)
 		if err != nil {
 			return nil, err
 		}
+		payments = append(payments, page...)
+		if len(page) < query.Limit {
+			break
+		}
+		cursor := CursorOf(page[len(page)-1])
+		query.After = &cursor
+	}
+
+	return entity.NewStatement(userID, periodStart, payments), nil
+}
 
-		return &PaymentResponse{
-			TransactionID: existingPayment.TransactionID,
-			UserID:        existingPayment.UserID,
-			Amount:        existingPayment.Amount,
-			Status:        existingPayment.Status,
-			Message:       "Transaction already processed",
-		}, nil
+// authorize moves a pending payment to authorized and starts the authorization window.
+// Card payments are authorized by the processor first, see authorizeCard.
+func (p *PaymentUseCase) authorize(payment *entity.Payment, actor string, now time.Time) error {
+	if err := payment.TransitionTo(entity.StatusAuthorized, actor, "payment authorized", now); err != nil {
+		return err
 	}
+	expiresAt := now.Add(p.authorizationWindow)
+	payment.AuthorizationExpiresAt = &expiresAt
+	return nil
+}
 
-	// Create new payment
-	payment := &entity.Payment{
-		TransactionID: req.TransactionID,
-		UserID:        req.UserID,
-		Amount:        req.Amount,
-		Status:        entity.StatusCompleted,
-		CreatedAt:     time.Now(),
+// authorizeCard has the processor authorize a pending card payment and records its answer: the
+// payment becomes authorized, or failed if the processor declines it. The processor call is made
+// outside any update. When the gateway chooses among several processors, the one an authorization
+// goes to is stored with the payment before the call, so a retry after a timeout or a crash reaches
+// the same processor instead of authorizing the payment at another one. The next candidate is only
+// tried once a processor turned the authorization away without making it. An automatic payment is
+// claimed for capture along with its authorization, and captured once the claim is stored.
+func (p *PaymentUseCase) authorizeCard(ctx context.Context, transactionID, actor string, automatic bool) (*entity.Payment, error) {
+	var (
+		tried       []string
+		refused     error
+		softDecline *GatewayResult
+	)
+	for {
+		var req GatewayAuthorization
+		payment, err := p.updatePayment(ctx, transactionID, func(payment *entity.Payment, now time.Time) (bool, error) {
+			if payment.Status != entity.StatusPending {
+				// A concurrent request recorded the processor's answer
+				return false, nil
+			}
+			req = gatewayAuthorization(payment)
+			if payment.Processor != "" && !slices.Contains(tried, payment.Processor) {
+				// An earlier request chose the processor
+				req.Processor = payment.Processor
+				return false, nil
+			}
+			candidates := p.gateway.Candidates(req)
+			if len(candidates) == 0 {
+				return false, nil
+			}
+			for _, candidate := range candidates {
+				if !slices.Contains(tried, candidate) {
+					payment.Processor, req.Processor = candidate, candidate
+					return true, nil
+				}
+			}
+			// Every candidate turned the authorization away
+			if softDecline != nil {
+				return true, p.recordAuthorization(payment, softDecline, actor, automatic, now)
+			}
+			payment.Processor = ""
+			return true, refused
+		})
+		if err != nil || payment.Status != entity.StatusPending {
+			return p.authorized(ctx, payment, err)
+		}
+
+		result, err := p.gateway.Authorize(ctx, req)
+		turnedAway := errors.Is(err, ErrGatewayUnavailable) || (err == nil && result.SoftDecline)
+		if turnedAway && req.Processor != "" {
+			tried = append(tried, req.Processor)
+			refused, softDecline = err, nil
+			if err == nil {
+				softDecline = result
+			}
+			continue
+		}
+		if err != nil {
+			return payment, err
+		}
+
+		// The processor has answered, so its answer is recorded even if the client has gone away
+		payment, err = p.updatePayment(context.WithoutCancel(ctx), transactionID, func(payment *entity.Payment, now time.Time) (bool, error) {
+			if payment.Status != entity.StatusPending {
+				return false, nil
+			}
+			return true, p.recordAuthorization(payment, result, actor, automatic, now)
+		})
+		return p.authorized(ctx, payment, err)
 	}
+}
 
-	// Store payment
-	if err := p.repo.Store(payment); err != nil {
-		return &PaymentResponse{
-			TransactionID: req.TransactionID,
-			UserID:        req.UserID,
-			Amount:        req.Amount,
-			Status:        entity.StatusFailed,
-			Message:       "Failed to process payment",
-		}, err
+// authorized finishes the authorization of a card payment whose processor answer is stored:
+// a decline is reported as ErrPaymentDeclined, and a claimed capture is made
+func (p *PaymentUseCase) authorized(ctx context.Context, payment *entity.Payment, err error) (*entity.Payment, error) {
+	switch {
+	case err != nil:
+		return payment, err
+	case payment.Status == entity.StatusFailed:
+		return payment, ErrPaymentDeclined
+	case payment.PendingAction != nil:
+		return p.finishAction(ctx, payment)
 	}
+	return payment, nil
+}
 
-	return &PaymentResponse{
+// recordAuthorization records the processor's answer to the authorization of a pending card payment.
+// The payment leaves the job queue, and an automatic payment is claimed for capture.
+func (p *PaymentUseCase) recordAuthorization(payment *entity.Payment, result *GatewayResult, actor string, automatic bool, now time.Time) error {
+	payment.ProcessorReference = result.Reference
+	payment.Processor = result.Processor
+	payment.Queued = false
+	if result.Status == entity.StatusFailed {
+		if err := payment.TransitionTo(entity.StatusFailed, actor, "declined by the processor: "+result.DeclineCode, now); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: %s", ErrPaymentDeclined, result.DeclineCode)
+	}
+	if err := p.authorize(payment, actor, now); err != nil {
+		return err
+	}
+	if automatic {
+		return p.capture(payment, payment.Amount, SystemActor, now)
+	}
+	return nil
+}
+
+// gatewayAuthorization describes the authorization of a card payment to the processor
+func gatewayAuthorization(payment *entity.Payment) GatewayAuthorization {
+	return GatewayAuthorization{
 		TransactionID: payment.TransactionID,
 		UserID:        payment.UserID,
+		MerchantID:    payment.MerchantID,
 		Amount:        payment.Amount,
-		Status:        payment.Status,
-		Message:       "Payment processed successfully",
-	}, nil
+		CardToken:     payment.CardToken,
+	}
+}
+
+// capture moves an authorized payment to captured for the given amount. A payment authorized
+// by the processor is claimed for capture instead, to be captured by the processor once the
+// claim is stored (see act).
+func (p *PaymentUseCase) capture(payment *entity.Payment, amount entity.Money, actor string, now time.Time) error {
+	reason := "payment captured"
+	if amount != payment.Amount {
+		reason = "payment partially captured for " + amount.String()
+	}
+	action := entity.PendingAction{Action: entity.ActionCapture, Amount: amount, Actor: actor, Reason: reason, ClaimedAt: now}
+	if p.callsProcessor(payment) {
+		return claimAction(payment, action)
+	}
+	return p.recordAction(payment, action, now)
 }
 
-// validateRequest validates the payment request
-func (p *PaymentUseCase) validateRequest(req PaymentRequest) error {
+// void moves an authorized payment to voided, releasing its hold. A payment authorized by the
+// processor is claimed for voiding instead, to be voided by the processor once the claim is stored.
+func (p *PaymentUseCase) void(payment *entity.Payment, actor, reason string, now time.Time) error {
+	action := entity.PendingAction{Action: entity.ActionVoid, Amount: payment.Amount, Actor: actor, Reason: reason, ClaimedAt: now}
+	if p.callsProcessor(payment) {
+		return claimAction(payment, action)
+	}
+	return p.recordAction(payment, action, now)
+}
+
+// refund records a refund and moves the payment to refunded once nothing is left to refund
+func (p *PaymentUseCase) refund(ctx context.Context, payment *entity.Payment, refund entity.Refund, actor string, now time.Time) error {
+	// The refund ID depends on the refunds already stored, so the processor is keyed by the
+	// idempotency key, which stays the same when a concurrent refund forces a retry
+	if p.callsProcessor(payment) {
+		if _, err := p.gateway.Refund(ctx, payment.ProcessorReference, refund.IdempotencyKey, refund.Amount); err != nil {
+			return err
+		}
+	}
+	payment.Refunds = append(payment.Refunds, refund)
+	p.recordRefund(payment, refund)
+	status := entity.StatusPartiallyRefunded
+	if payment.RefundableAmount().IsZero() {
+		status = entity.StatusRefunded
+	}
+	return payment.TransitionTo(status, actor, "refunded "+refund.Amount.String()+": "+refund.Reason, now)
+}
+
+// callsProcessor reports whether the processor authorized the payment, so it must capture, void and refund it too
+func (p *PaymentUseCase) callsProcessor(payment *entity.Payment) bool {
+	return p.gateway != nil && payment.ProcessorReference != ""
+}
+
+// chargesCard reports whether the payment is authorized by the payment processor
+func (p *PaymentUseCase) chargesCard(payment *entity.Payment) bool {
+	return p.gateway != nil && payment.PaymentMethod == entity.PaymentMethodCard
+}
+
+// expireAuthorization voids a lapsed authorization, releasing it at the processor, and reports ErrAuthorizationExpired
+func (p *PaymentUseCase) expireAuthorization(payment *entity.Payment, now time.Time) error {
+	if err := p.void(payment, SystemActor, "authorization expired", now); err != nil {
+		return err
+	}
+	return ErrAuthorizationExpired
+}
+
+// updatePayment loads a payment, applies change and saves it when change reports a modification.
+// An error returned by change is passed on after saving, so a change may record a transition and
+// still fail the action. Updates that lose an optimistic concurrency race are retried on fresh data.
+func (p *PaymentUseCase) updatePayment(ctx context.Context, transactionID string, change func(payment *entity.Payment, now time.Time) (bool, error)) (*entity.Payment, error) {
+	if transactionID == "" {
+		return nil, ErrInvalidTransaction
+	}
+
+	for attempt := 0; attempt < maxUpdateAttempts; attempt++ {
+		payment, err := p.repo.GetByTransactionID(ctx, transactionID)
+		if err != nil {
+			return nil, err
+		}
+		if payment == nil {
+			return nil, ErrPaymentNotFound
+		}
+
+		changed, changeErr := change(payment, p.now())
+		if !changed {
+			return payment, changeErr
+		}
+
+		// The change may have reached the processor, so it is saved even if the client has gone away
+		if err := p.repo.Update(context.WithoutCancel(ctx), payment); err != nil {
+			if errors.Is(err, ErrConcurrentUpdate) {
+				continue
+			}
+			return payment, err
+		}
+		p.publishChanges(ctx, payment)
+		return payment, changeErr
+	}
+
+	return nil, ErrConcurrentUpdate
+}
+
+// validateRequest validates the payment request and returns the parsed amount
+func (p *PaymentUseCase) validateRequest(req PaymentRequest) (entity.Money, error) {
 	if req.UserID == "" {
-		return ErrInvalidUserID
+		return entity.Money{}, ErrInvalidUserID
 	}
 	if req.TransactionID == "" {
-		return ErrInvalidTransaction
+		return entity.Money{}, ErrInvalidTransaction
 	}
-	if req.Amount <= 0 {
-		return ErrInvalidAmount
+	if _, ok := entity.CurrencyExponent(req.Currency); !ok {
+		return entity.Money{}, ErrInvalidCurrency
 	}
-	return nil
+	amount, err := entity.ParseMoney(req.Amount, req.Currency)
+	if err != nil {
+		return entity.Money{}, ErrInvalidAmountFormat
+	}
+	if !amount.IsPositive() {
+		return entity.Money{}, ErrInvalidAmount
+	}
+	if req.CaptureMethod != "" && req.CaptureMethod != CaptureAutomatic && req.CaptureMethod != CaptureManual {
+		return entity.Money{}, ErrInvalidCaptureMethod
+	}
+	switch req.ProcessingMode {
+	case "", ProcessingSync:
+	case ProcessingAsync:
+		if req.CaptureMethod == CaptureManual {
+			return entity.Money{}, ErrInvalidProcessingMode
+		}
+		if p.jobs == nil {
+			return entity.Money{}, ErrAsyncDisabled
+		}
+	default:
+		return entity.Money{}, ErrInvalidProcessingMode
+	}
+	switch req.PaymentMethod {
+	case "", entity.PaymentMethodCard:
+	case entity.PaymentMethodWallet:
+		if req.CaptureMethod == CaptureManual {
+			return entity.Money{}, ErrInvalidPaymentMethod
+		}
+		if p.wallets == nil {
+			return entity.Money{}, ErrWalletsDisabled
+		}
+	default:
+		return entity.Money{}, ErrInvalidPaymentMethod
+	}
+	return amount, nil
+}
+
+// listQuery validates a listing request and converts it to a repository query
+func listQuery(req ListPaymentsRequest) (PaymentQuery, error) {
+	query := PaymentQuery{
+		UserID:   req.UserID,
+		Status:   req.Status,
+		Currency: req.Currency,
+		Limit:    req.Limit,
+	}
+
+	if query.Limit == 0 {
+		query.Limit = DefaultPageSize
+	}
+	if query.Limit < 1 || query.Limit > MaxPageSize {
+		return PaymentQuery{}, ErrInvalidLimit
+	}
+	if req.Status != "" && !entity.IsStatus(req.Status) {
+		return PaymentQuery{}, ErrInvalidStatus
+	}
+	if _, ok := entity.CurrencyExponent(req.Currency); req.Currency != "" && !ok {
+		return PaymentQuery{}, ErrInvalidCurrency
+	}
+
+	// Amounts only compare within a currency
+	for _, bound := range []struct {
+		value  string
+		target **int64
+	}{{req.MinAmount, &query.MinAmount}, {req.MaxAmount, &query.MaxAmount}} {
+		if bound.value == "" {
+			continue
+		}
+		amount, err := entity.ParseMoney(bound.value, req.Currency)
+		if err != nil {
+			return PaymentQuery{}, ErrInvalidAmountRange
+		}
+		*bound.target = &amount.Amount
+	}
+	if query.MinAmount != nil && query.MaxAmount != nil && *query.MinAmount > *query.MaxAmount {
+		return PaymentQuery{}, ErrInvalidAmountRange
+	}
+
+	for _, bound := range []struct {
+		value  string
+		target **time.Time
+	}{{req.CreatedFrom, &query.CreatedFrom}, {req.CreatedTo, &query.CreatedTo}} {
+		if bound.value == "" {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339Nano, bound.value)
+		if err != nil {
+			return PaymentQuery{}, ErrInvalidDateRange
+		}
+		*bound.target = &at
+	}
+	if query.CreatedFrom != nil && query.CreatedTo != nil && !query.CreatedFrom.Before(*query.CreatedTo) {
+		return PaymentQuery{}, ErrInvalidDateRange
+	}
+
+	if req.Cursor != "" {
+		cursor, err := DecodeCursor(req.Cursor)
+		if err != nil {
+			return PaymentQuery{}, err
+		}
+		query.After = &cursor
+	}
+	return query, nil
+}
+
+// declineReason returns why a failed payment was declined. Payments stored before status
+// histories were kept have none, and get a generic message.
+func declineReason(payment *entity.Payment) string {
+	if len(payment.StatusHistory) == 0 {
+		return ErrPaymentDeclined.Error()
+	}
+	return payment.StatusHistory[len(payment.StatusHistory)-1].Reason
+}
+
+// paymentResponse builds the response describing a stored payment
+func paymentResponse(payment *entity.Payment, message string) *PaymentResponse {
+	response := &PaymentResponse{
+		TransactionID:          payment.TransactionID,
+		UserID:                 payment.UserID,
+		Amount:                 payment.Amount.Decimal(),
+		Currency:               payment.Amount.Currency,
+		Status:                 payment.Status,
+		Message:                message,
+		PaymentMethod:          payment.PaymentMethod,
+		AuthorizationExpiresAt: payment.AuthorizationExpiresAt,
+		ProcessorReference:     payment.ProcessorReference,
+		Processor:              payment.Processor,
+		Queued:                 payment.Queued,
+	}
+	if payment.CapturedAmount.IsPositive() {
+		response.CapturedAmount = payment.CapturedAmount.Decimal()
+	}
+	if refunded := payment.RefundedAmount(); refunded.IsPositive() {
+		response.RefundedAmount = refunded.Decimal()
+	}
+	return response
+}
+
+// refundResponse builds the response describing a refund of a stored payment
+func refundResponse(payment *entity.Payment, refund entity.Refund, message string) *RefundResponse {
+	return &RefundResponse{
+		RefundID:       refund.RefundID,
+		TransactionID:  payment.TransactionID,
+		Amount:         refund.Amount.Decimal(),
+		Currency:       refund.Amount.Currency,
+		Reason:         refund.Reason,
+		IdempotencyKey: refund.IdempotencyKey,
+		CreatedAt:      refund.CreatedAt,
+		PaymentStatus:  payment.Status,
+		RefundedAmount: payment.RefundedAmount().Decimal(),
+		Message:        message,
+	}
+}
+
+// refundFailedResponse builds the response for a refund that could not be made.
+// It describes the payment's current state when it could be loaded.
+func refundFailedResponse(req RefundRequest, payment *entity.Payment, err error) *RefundResponse {
+	response := &RefundResponse{
+		TransactionID:  req.TransactionID,
+		Amount:         req.Amount,
+		Reason:         req.Reason,
+		IdempotencyKey: req.IdempotencyKey,
+		Message:        err.Error(),
+	}
+	if payment != nil {
+		response.Currency = payment.Amount.Currency
+		response.PaymentStatus = payment.Status
+		response.RefundedAmount = payment.RefundedAmount().Decimal()
+	}
+	return response
+}
+
+// actionFailedResponse builds the response for a failed action on an existing payment.
+// It describes the payment's current state when it could be loaded.
+func actionFailedResponse(transactionID string, payment *entity.Payment, err error) *PaymentResponse {
+	if payment != nil {
+		return paymentResponse(payment, err.Error())
+	}
+	return &PaymentResponse{
+		TransactionID: transactionID,
+		Status:        entity.StatusFailed,
+		Message:       err.Error(),
+	}
+}
+
+// failedResponse builds the response for a request that could not be processed
+func failedResponse(req PaymentRequest, message string) *PaymentResponse {
+	return &PaymentResponse{
+		TransactionID: req.TransactionID,
+		UserID:        req.UserID,
+		Amount:        req.Amount,
+		Currency:      req.Currency,
+		Status:        entity.StatusFailed,
+		Message:       message,
+	}
+}
+
+// fingerprint returns a SHA-256 hash of the canonical JSON encoding of the request.
+// Struct fields marshal in declaration order, so equal requests always hash equally.
+func fingerprint(req PaymentRequest) string {
+	// Automatic capture and card payment are the defaults, so spelling them out is the same request
+	if req.CaptureMethod == CaptureAutomatic {
+		req.CaptureMethod = ""
+	}
+	if req.PaymentMethod == entity.PaymentMethodCard {
+		req.PaymentMethod = ""
+	}
+	if req.ProcessingMode == ProcessingSync {
+		req.ProcessingMode = ""
+	}
+	req.Amount = canonicalAmount(req.Amount, req.Currency)
+	return hashJSON(req)
+}
+
+// transferFingerprint returns a SHA-256 hash of the canonical JSON encoding of a transfer request
+func transferFingerprint(req TransferRequest) string {
+	req.Amount = canonicalAmount(req.Amount, req.Currency)
+	return hashJSON(req)
+}
+
+// PaymentFingerprint identifies a payment request the way retries of its transaction ID are compared,
+// so callers keying on the transaction ID refuse exactly the retries the use case refuses
+func PaymentFingerprint(req PaymentRequest) string {
+	return fingerprint(req)
+}
+
+// TransferFingerprint identifies a transfer request the way retries of its transaction ID are compared
+func TransferFingerprint(req TransferRequest) string {
+	return transferFingerprint(req)
+}
+
+// canonicalAmount spells an amount the way its parsed Money does, so "10", "10.0" and "10.00"
+// are the same USD request. Amounts that do not parse are left as they are.
+func canonicalAmount(amount, currency string) string {
+	money, err := entity.ParseMoney(amount, currency)
+	if err != nil {
+		return amount
+	}
+	return money.Decimal()
+}
+
+// hashJSON returns the hex SHA-256 hash of the JSON encoding of v
+func hashJSON(v any) string {
+	canonical, _ := json.Marshal(v)
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:])
 }
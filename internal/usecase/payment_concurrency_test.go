@@ -0,0 +1,232 @@
+package usecase_test
+
+import (
+	"context"
+	"fmt"
+	"payment-service/internal/ledger"
+	"payment-service/internal/repository"
+	"payment-service/internal/usecase"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaymentUseCase_ProcessPayment_ConcurrentDuplicates(t *testing.T) {
+	// Arrange
+	const requests = 500
+	repo := repository.NewInMemoryPaymentRepository()
+	useCase := usecase.NewPaymentUseCase(repo)
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		processed int
+		replayed  int
+		start     = make(chan struct{})
+	)
+
+	// Act
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+
+			response, err := useCase.ProcessPayment(context.Background(), usecase.PaymentRequest{
+				UserID:        "user123",
+				Amount:        "100.50",
+				Currency:      "USD",
+				TransactionID: "txn-race",
+			})
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch response.Message {
+			case "Payment processed successfully":
+				processed++
+			case "Transaction already processed":
+				replayed++
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	// Assert
+	assert.Equal(t, 1, processed, "exactly one request must create the payment")
+	assert.Equal(t, requests-1, replayed)
+
+	stored, err := repo.GetByTransactionID(context.Background(), "txn-race")
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.Equal(t, "100.50 USD", stored.Amount.String())
+}
+
+func TestPaymentUseCase_RefundPayment_ConcurrentRefundsNeverExceedCapture(t *testing.T) {
+	// Arrange
+	const refunds = 50
+	repo := repository.NewInMemoryPaymentRepository()
+	l := ledger.NewJournal(repo)
+	useCase := usecase.NewPaymentUseCase(repo)
+	_, err := useCase.ProcessPayment(context.Background(), usecase.PaymentRequest{
+		UserID:        "user123",
+		Amount:        "10.00",
+		Currency:      "USD",
+		TransactionID: "txn-refund-race",
+	})
+	require.NoError(t, err)
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int64
+		start     = make(chan struct{})
+	)
+
+	// Act: 50 refunds of 1.00 race for a 10.00 capture
+	for i := 0; i < refunds; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			_, err := useCase.RefundPayment(context.Background(), usecase.RefundRequest{
+				TransactionID:  "txn-refund-race",
+				Amount:         "1.00",
+				Reason:         "requested_by_customer",
+				IdempotencyKey: fmt.Sprintf("refund-%d", i),
+			})
+			if err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	// Assert
+	payment, err := repo.GetByTransactionID(context.Background(), "txn-refund-race")
+	require.NoError(t, err)
+	assert.LessOrEqual(t, succeeded, int64(10))
+	assert.Len(t, payment.Refunds, int(succeeded))
+	assert.Equal(t, succeeded*100, payment.RefundedAmount().Amount)
+	assert.LessOrEqual(t, payment.RefundedAmount().Amount, payment.CapturedAmount.Amount)
+
+	cash, err := l.Balance(context.Background(), ledger.AccountCash, "USD")
+	require.NoError(t, err)
+	assert.Equal(t, payment.CapturedAmount.Amount-payment.RefundedAmount().Amount, cash.Amount)
+	assertBalanced(t, l)
+}
+
+func TestPaymentUseCase_ProcessPayment_ConcurrentWalletPaymentsNeverOverdraw(t *testing.T) {
+	// Arrange
+	const payments = 50
+	repo := repository.NewInMemoryPaymentRepository()
+	l := ledger.NewJournal(repo)
+	useCase := usecase.NewPaymentUseCase(repo, usecase.WithWallets(repo))
+	_, err := useCase.TopUpWallet(context.Background(), usecase.WalletRequest{UserID: "user123", Amount: "10.00", Currency: "USD", IdempotencyKey: "initial"})
+	require.NoError(t, err)
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int64
+		start     = make(chan struct{})
+	)
+
+	// Act: 50 payments of 1.00 race for a 10.00 balance
+	for i := 0; i < payments; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			_, err := useCase.ProcessPayment(context.Background(), usecase.PaymentRequest{
+				UserID:        "user123",
+				Amount:        "1.00",
+				Currency:      "USD",
+				TransactionID: fmt.Sprintf("txn-wallet-%d", i),
+				PaymentMethod: "wallet",
+			})
+			if err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	// Assert
+	wallet, err := useCase.GetWallet(context.Background(), "user123")
+	require.NoError(t, err)
+	assert.LessOrEqual(t, succeeded, int64(10))
+	assert.Equal(t, 1000-succeeded*100, wallet.Balance("USD").Amount)
+	assert.GreaterOrEqual(t, wallet.Balance("USD").Amount, int64(0))
+
+	funds, err := l.Balance(context.Background(), ledger.AccountWalletFunds, "USD")
+	require.NoError(t, err)
+	assert.Equal(t, wallet.Balance("USD"), funds)
+	assertBalanced(t, l)
+}
+
+func TestPaymentUseCase_TransferFunds_ConcurrentTransfersConserveFunds(t *testing.T) {
+	// Arrange
+	const transfers = 40
+	repo := repository.NewInMemoryPaymentRepository()
+	useCase := usecase.NewPaymentUseCase(repo, usecase.WithWallets(repo), usecase.WithTransfers(repo))
+	for _, userID := range []string{"user123", "user456"} {
+		_, err := useCase.TopUpWallet(context.Background(), usecase.WalletRequest{UserID: userID, Amount: "10.00", Currency: "USD", IdempotencyKey: "initial"})
+		require.NoError(t, err)
+	}
+
+	var (
+		wg    sync.WaitGroup
+		start = make(chan struct{})
+	)
+
+	// Act: both users send 1.00 to each other, and every transfer is retried concurrently
+	for i := 0; i < transfers; i++ {
+		from, to := "user123", "user456"
+		if i%2 == 1 {
+			from, to = to, from
+		}
+		req := usecase.TransferRequest{FromUserID: from, ToUserID: to, Amount: "1.00", Currency: "USD", TransactionID: fmt.Sprintf("transfer-%d", i)}
+		for retry := 0; retry < 2; retry++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				useCase.TransferFunds(context.Background(), req)
+			}()
+		}
+	}
+	close(start)
+	wg.Wait()
+
+	// Assert
+	sender, err := useCase.GetWallet(context.Background(), "user123")
+	require.NoError(t, err)
+	recipient, err := useCase.GetWallet(context.Background(), "user456")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2000), sender.Balance("USD").Amount+recipient.Balance("USD").Amount)
+	assert.GreaterOrEqual(t, sender.Balance("USD").Amount, int64(0))
+	assert.GreaterOrEqual(t, recipient.Balance("USD").Amount, int64(0))
+	for i := 0; i < transfers; i++ {
+		id := fmt.Sprintf("transfer:transfer-%d", i)
+		moved := 0
+		if sender.FindTransaction(id) != nil {
+			moved++
+		}
+		if recipient.FindTransaction(id) != nil {
+			moved++
+		}
+		assert.Contains(t, []int{0, 2}, moved, "funds of %s moved at most once, out of one wallet into the other", id)
+	}
+}
@@ -1,11 +1,15 @@
 package usecase
 
 import (
+	"context"
+	"fmt"
 	"payment-service/internal/entity"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // MockPaymentRepository is a mock implementation of PaymentRepository
@@ -13,18 +17,39 @@ type MockPaymentRepository struct {
 	mock.Mock
 }
 
-func (m *MockPaymentRepository) Store(payment *entity.Payment) error {
-	args := m.Called(payment)
+func (m *MockPaymentRepository) Store(ctx context.Context, payment *entity.Payment) error {
+	args := m.Called(ctx, payment)
 	return args.Error(0)
 }
 
-func (m *MockPaymentRepository) GetByTransactionID(transactionID string) (*entity.Payment, error) {
-	args := m.Called(transactionID)
+func (m *MockPaymentRepository) CreateIfAbsent(ctx context.Context, payment *entity.Payment) (*entity.Payment, bool, error) {
+	args := m.Called(ctx, payment)
+	if fn, ok := args.Get(0).(func(*entity.Payment) *entity.Payment); ok {
+		return fn(payment), args.Bool(1), args.Error(2)
+	}
+	return args.Get(0).(*entity.Payment), args.Bool(1), args.Error(2)
+}
+
+func (m *MockPaymentRepository) Update(ctx context.Context, payment *entity.Payment) error {
+	args := m.Called(ctx, payment)
+	return args.Error(0)
+}
+
+func (m *MockPaymentRepository) GetByTransactionID(ctx context.Context, transactionID string) (*entity.Payment, error) {
+	args := m.Called(ctx, transactionID)
+	if fn, ok := args.Get(0).(func(string) *entity.Payment); ok {
+		return fn(transactionID), args.Error(1)
+	}
 	return args.Get(0).(*entity.Payment), args.Error(1)
 }
 
-func (m *MockPaymentRepository) Exists(transactionID string) bool {
-	args := m.Called(transactionID)
+func (m *MockPaymentRepository) List(ctx context.Context, query PaymentQuery) ([]*entity.Payment, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).([]*entity.Payment), args.Error(1)
+}
+
+func (m *MockPaymentRepository) Exists(ctx context.Context, transactionID string) bool {
+	args := m.Called(ctx, transactionID)
 	return args.Bool(0)
 }
 
@@ -35,25 +60,42 @@ func TestPaymentUseCase_ProcessPayment_Success(t *testing.T) {
 
 	req := PaymentRequest{
 		UserID:        "user123",
-		Amount:        100.50,
+		Amount:        "100.50",
+		Currency:      "USD",
 		TransactionID: "txn123",
 	}
 
-	mockRepo.On("Exists", "txn123").Return(false)
-	mockRepo.On("Store", mock.AnythingOfType("*entity.Payment")).Return(nil)
+	var stored *entity.Payment
+	mockRepo.On("CreateIfAbsent", mock.Anything, mock.AnythingOfType("*entity.Payment")).Return(
+		func(payment *entity.Payment) *entity.Payment { return payment },
+		true,
+		nil,
+	).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(*entity.Payment)
+	})
 
 	// Act
-	response, err := useCase.ProcessPayment(req)
+	response, err := useCase.ProcessPayment(context.Background(), req)
 
 	// Assert
 	assert.NoError(t, err)
 	assert.NotNil(t, response)
 	assert.Equal(t, "txn123", response.TransactionID)
 	assert.Equal(t, "user123", response.UserID)
-	assert.Equal(t, 100.50, response.Amount)
-	assert.Equal(t, entity.StatusCompleted, response.Status)
+	assert.Equal(t, "100.50", response.Amount)
+	assert.Equal(t, "USD", response.Currency)
+	assert.Equal(t, entity.StatusCaptured, response.Status)
 	assert.Equal(t, "Payment processed successfully", response.Message)
 
+	// The stored payment went through the full lifecycle
+	assert.Equal(t, entity.StatusCaptured, stored.Status)
+	var statuses []string
+	for _, change := range stored.StatusHistory {
+		statuses = append(statuses, change.To)
+	}
+	assert.Equal(t, []string{entity.StatusPending, entity.StatusAuthorized, entity.StatusCaptured}, statuses)
+	assert.Equal(t, "user123", stored.StatusHistory[0].Actor)
+
 	mockRepo.AssertExpectations(t)
 }
 
@@ -64,35 +106,146 @@ func TestPaymentUseCase_ProcessPayment_IdempotentRequest(t *testing.T) {
 
 	req := PaymentRequest{
 		UserID:        "user123",
-		Amount:        100.50,
+		Amount:        "100.50",
+		Currency:      "USD",
 		TransactionID: "txn123",
 	}
 
 	existingPayment := &entity.Payment{
-		TransactionID: "txn123",
-		UserID:        "user123",
-		Amount:        100.50,
-		Status:        entity.StatusCompleted,
+		TransactionID:      "txn123",
+		UserID:             "user123",
+		Amount:             entity.Money{Amount: 10050, Currency: "USD"},
+		Status:             entity.StatusCaptured,
+		RequestFingerprint: fingerprint(req),
 	}
 
-	mockRepo.On("Exists", "txn123").Return(true)
-	mockRepo.On("GetByTransactionID", "txn123").Return(existingPayment, nil)
+	mockRepo.On("CreateIfAbsent", mock.Anything, mock.AnythingOfType("*entity.Payment")).Return(existingPayment, false, nil)
 
 	// Act
-	response, err := useCase.ProcessPayment(req)
+	response, err := useCase.ProcessPayment(context.Background(), req)
 
 	// Assert
 	assert.NoError(t, err)
 	assert.NotNil(t, response)
 	assert.Equal(t, "txn123", response.TransactionID)
 	assert.Equal(t, "user123", response.UserID)
-	assert.Equal(t, 100.50, response.Amount)
-	assert.Equal(t, entity.StatusCompleted, response.Status)
+	assert.Equal(t, "100.50", response.Amount)
+	assert.Equal(t, "USD", response.Currency)
+	assert.Equal(t, entity.StatusCaptured, response.Status)
 	assert.Equal(t, "Transaction already processed", response.Message)
 
 	mockRepo.AssertExpectations(t)
 }
 
+func TestPaymentUseCase_ProcessPayment_RetryOfFailedPaymentWithoutHistory(t *testing.T) {
+	// Arrange: a failed payment stored before status histories were kept
+	mockRepo := new(MockPaymentRepository)
+	useCase := NewPaymentUseCase(mockRepo)
+	req := PaymentRequest{UserID: "user123", Amount: "10.00", Currency: "USD", TransactionID: "txn123"}
+	mockRepo.On("CreateIfAbsent", mock.Anything, mock.AnythingOfType("*entity.Payment")).Return(&entity.Payment{
+		TransactionID: "txn123",
+		UserID:        "user123",
+		Amount:        entity.Money{Amount: 1000, Currency: "USD"},
+		Status:        entity.StatusFailed,
+	}, false, nil)
+
+	// Act
+	response, err := useCase.ProcessPayment(context.Background(), req)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrPaymentDeclined)
+	assert.Equal(t, entity.StatusFailed, response.Status)
+	assert.Equal(t, ErrPaymentDeclined.Error(), response.Message)
+}
+
+func TestPaymentUseCase_ProcessPayment_IdempotentRequestWithEquivalentAmount(t *testing.T) {
+	// Arrange: the payment was stored for "10.00"
+	mockRepo := new(MockPaymentRepository)
+	useCase := NewPaymentUseCase(mockRepo)
+	original := PaymentRequest{UserID: "user123", Amount: "10.00", Currency: "USD", TransactionID: "txn123"}
+	mockRepo.On("CreateIfAbsent", mock.Anything, mock.AnythingOfType("*entity.Payment")).Return(&entity.Payment{
+		TransactionID:      "txn123",
+		UserID:             "user123",
+		Amount:             entity.Money{Amount: 1000, Currency: "USD"},
+		Status:             entity.StatusCaptured,
+		RequestFingerprint: fingerprint(original),
+	}, false, nil)
+
+	for _, amount := range []string{"10", "10.0", "10.00"} {
+		t.Run(amount, func(t *testing.T) {
+			// Act
+			retry := original
+			retry.Amount = amount
+			response, err := useCase.ProcessPayment(context.Background(), retry)
+
+			// Assert
+			require.NoError(t, err, "the same amount spelled differently is the same request")
+			assert.Equal(t, "Transaction already processed", response.Message)
+		})
+	}
+}
+
+func TestPaymentUseCase_ProcessPayment_IdempotencyConflict(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockPaymentRepository)
+	useCase := NewPaymentUseCase(mockRepo)
+
+	original := PaymentRequest{
+		UserID:        "user123",
+		Amount:        "100.50",
+		Currency:      "USD",
+		TransactionID: "txn123",
+	}
+
+	existingPayment := &entity.Payment{
+		TransactionID:      "txn123",
+		UserID:             "user123",
+		Amount:             entity.Money{Amount: 10050, Currency: "USD"},
+		Status:             entity.StatusCaptured,
+		RequestFingerprint: fingerprint(original),
+	}
+
+	mockRepo.On("CreateIfAbsent", mock.Anything, mock.AnythingOfType("*entity.Payment")).Return(existingPayment, false, nil)
+
+	testCases := []struct {
+		name    string
+		request PaymentRequest
+	}{
+		{
+			name: "Different Amount",
+			request: PaymentRequest{
+				UserID:        "user123",
+				Amount:        "200.00",
+				Currency:      "USD",
+				TransactionID: "txn123",
+			},
+		},
+		{
+			name: "Different UserID",
+			request: PaymentRequest{
+				UserID:        "user456",
+				Amount:        "100.50",
+				Currency:      "USD",
+				TransactionID: "txn123",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			response, err := useCase.ProcessPayment(context.Background(), tc.request)
+
+			// Assert
+			assert.ErrorIs(t, err, ErrIdempotencyConflict)
+			assert.ErrorIs(t, err, ErrDuplicateTransaction)
+			assert.NotNil(t, response)
+			assert.Equal(t, entity.StatusFailed, response.Status)
+			assert.Equal(t, ErrIdempotencyConflict.Error(), response.Message)
+		})
+	}
+}
+
 func TestPaymentUseCase_ProcessPayment_ValidationErrors(t *testing.T) {
 	// Arrange
 	mockRepo := new(MockPaymentRepository)
@@ -107,7 +260,8 @@ func TestPaymentUseCase_ProcessPayment_ValidationErrors(t *testing.T) {
 			name: "Invalid UserID",
 			request: PaymentRequest{
 				UserID:        "",
-				Amount:        100.50,
+				Amount:        "100.50",
+				Currency:      "USD",
 				TransactionID: "txn123",
 			},
 			expectedErr: ErrInvalidUserID,
@@ -116,7 +270,8 @@ func TestPaymentUseCase_ProcessPayment_ValidationErrors(t *testing.T) {
 			name: "Invalid TransactionID",
 			request: PaymentRequest{
 				UserID:        "user123",
-				Amount:        100.50,
+				Amount:        "100.50",
+				Currency:      "USD",
 				TransactionID: "",
 			},
 			expectedErr: ErrInvalidTransaction,
@@ -125,7 +280,8 @@ func TestPaymentUseCase_ProcessPayment_ValidationErrors(t *testing.T) {
 			name: "Invalid Amount - Zero",
 			request: PaymentRequest{
 				UserID:        "user123",
-				Amount:        0,
+				Amount:        "0",
+				Currency:      "USD",
 				TransactionID: "txn123",
 			},
 			expectedErr: ErrInvalidAmount,
@@ -134,17 +290,58 @@ func TestPaymentUseCase_ProcessPayment_ValidationErrors(t *testing.T) {
 			name: "Invalid Amount - Negative",
 			request: PaymentRequest{
 				UserID:        "user123",
-				Amount:        -10.50,
+				Amount:        "-10.50",
+				Currency:      "USD",
 				TransactionID: "txn123",
 			},
 			expectedErr: ErrInvalidAmount,
 		},
+		{
+			name: "Invalid Amount - Too Many Decimals",
+			request: PaymentRequest{
+				UserID:        "user123",
+				Amount:        "10.505",
+				Currency:      "USD",
+				TransactionID: "txn123",
+			},
+			expectedErr: ErrInvalidAmountFormat,
+		},
+		{
+			name: "Invalid Amount - Not A Number",
+			request: PaymentRequest{
+				UserID:        "user123",
+				Amount:        "ten",
+				Currency:      "USD",
+				TransactionID: "txn123",
+			},
+			expectedErr: ErrInvalidAmountFormat,
+		},
+		{
+			name: "Invalid Currency - Missing",
+			request: PaymentRequest{
+				UserID:        "user123",
+				Amount:        "10.50",
+				Currency:      "",
+				TransactionID: "txn123",
+			},
+			expectedErr: ErrInvalidCurrency,
+		},
+		{
+			name: "Invalid Currency - Unknown",
+			request: PaymentRequest{
+				UserID:        "user123",
+				Amount:        "10.50",
+				Currency:      "ABC",
+				TransactionID: "txn123",
+			},
+			expectedErr: ErrInvalidCurrency,
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			// Act
-			response, err := useCase.ProcessPayment(tc.request)
+			response, err := useCase.ProcessPayment(context.Background(), tc.request)
 
 			// Assert
 			assert.Error(t, err)
@@ -155,3 +352,626 @@ func TestPaymentUseCase_ProcessPayment_ValidationErrors(t *testing.T) {
 		})
 	}
 }
+
+// authorizedPayment returns a payment authorized at the given time for 100.00 USD
+func authorizedPayment(authorizedAt time.Time, window time.Duration) *entity.Payment {
+	payment := entity.NewPayment("txn123", "user123", entity.Money{Amount: 10000, Currency: "USD"}, "user123", "payment requested", authorizedAt)
+	payment.TransitionTo(entity.StatusAuthorized, MerchantActor, "payment authorized", authorizedAt)
+	expiresAt := authorizedAt.Add(window)
+	payment.AuthorizationExpiresAt = &expiresAt
+	return payment
+}
+
+func TestPaymentUseCase_ProcessPayment_ManualCaptureStaysPending(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockPaymentRepository)
+	useCase := NewPaymentUseCase(mockRepo)
+
+	req := PaymentRequest{
+		UserID:        "user123",
+		Amount:        "100.00",
+		Currency:      "USD",
+		TransactionID: "txn123",
+		CaptureMethod: CaptureManual,
+	}
+
+	mockRepo.On("CreateIfAbsent", mock.Anything, mock.AnythingOfType("*entity.Payment")).Return(
+		func(payment *entity.Payment) *entity.Payment { return payment },
+		true,
+		nil,
+	)
+
+	// Act
+	response, err := useCase.ProcessPayment(context.Background(), req)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, entity.StatusPending, response.Status)
+	assert.Equal(t, "Payment created, awaiting authorization", response.Message)
+	assert.Empty(t, response.CapturedAmount)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPaymentUseCase_ProcessPayment_InvalidCaptureMethod(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockPaymentRepository)
+	useCase := NewPaymentUseCase(mockRepo)
+
+	req := PaymentRequest{
+		UserID:        "user123",
+		Amount:        "100.00",
+		Currency:      "USD",
+		TransactionID: "txn123",
+		CaptureMethod: "later",
+	}
+
+	// Act
+	_, err := useCase.ProcessPayment(context.Background(), req)
+
+	// Assert
+	assert.Equal(t, ErrInvalidCaptureMethod, err)
+	mockRepo.AssertNotCalled(t, "CreateIfAbsent")
+}
+
+func TestPaymentUseCase_AuthorizePayment_Success(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockPaymentRepository)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	useCase := NewPaymentUseCase(mockRepo, WithAuthorizationWindow(time.Hour))
+	useCase.now = func() time.Time { return now }
+
+	pending := entity.NewPayment("txn123", "user123", entity.Money{Amount: 10000, Currency: "USD"}, "user123", "payment requested", now)
+	mockRepo.On("GetByTransactionID", mock.Anything, "txn123").Return(pending, nil)
+	mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*entity.Payment")).Return(nil)
+
+	// Act
+	response, err := useCase.AuthorizePayment(context.Background(), "txn123")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, entity.StatusAuthorized, response.Status)
+	assert.Equal(t, "Payment authorized", response.Message)
+	if assert.NotNil(t, response.AuthorizationExpiresAt) {
+		assert.Equal(t, now.Add(time.Hour), *response.AuthorizationExpiresAt)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPaymentUseCase_AuthorizePayment_AlreadyAuthorized(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockPaymentRepository)
+	useCase := NewPaymentUseCase(mockRepo)
+
+	mockRepo.On("GetByTransactionID", mock.Anything, "txn123").Return(authorizedPayment(time.Now(), time.Hour), nil)
+
+	// Act
+	response, err := useCase.AuthorizePayment(context.Background(), "txn123")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, entity.StatusAuthorized, response.Status)
+	assert.Equal(t, "Payment already authorized", response.Message)
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestPaymentUseCase_AuthorizePayment_NotFound(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockPaymentRepository)
+	useCase := NewPaymentUseCase(mockRepo)
+
+	mockRepo.On("GetByTransactionID", mock.Anything, "missing").Return((*entity.Payment)(nil), nil)
+
+	// Act
+	response, err := useCase.AuthorizePayment(context.Background(), "missing")
+
+	// Assert
+	assert.Equal(t, ErrPaymentNotFound, err)
+	assert.Equal(t, "missing", response.TransactionID)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPaymentUseCase_CapturePayment_FullAndPartial(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   string
+		captured string
+		reason   string
+	}{
+		{"full", "", "100.00", "payment captured"},
+		{"partial", "40.00", "40.00", "payment partially captured for 40.00 USD"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockPaymentRepository)
+			useCase := NewPaymentUseCase(mockRepo)
+
+			payment := authorizedPayment(time.Now(), time.Hour)
+			mockRepo.On("GetByTransactionID", mock.Anything, "txn123").Return(payment, nil)
+			mockRepo.On("Update", mock.Anything, payment).Return(nil)
+
+			// Act
+			response, err := useCase.CapturePayment(context.Background(), CaptureRequest{TransactionID: "txn123", Amount: tt.amount})
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Equal(t, entity.StatusCaptured, response.Status)
+			assert.Equal(t, tt.captured, response.CapturedAmount)
+			assert.Nil(t, response.AuthorizationExpiresAt)
+			assert.Equal(t, tt.reason, payment.StatusHistory[len(payment.StatusHistory)-1].Reason)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPaymentUseCase_CapturePayment_RepeatIsIdempotent(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockPaymentRepository)
+	useCase := NewPaymentUseCase(mockRepo)
+
+	payment := authorizedPayment(time.Now(), time.Hour)
+	payment.TransitionTo(entity.StatusCaptured, MerchantActor, "payment captured", time.Now())
+	payment.CapturedAmount = payment.Amount
+	mockRepo.On("GetByTransactionID", mock.Anything, "txn123").Return(payment, nil)
+
+	// Act
+	response, err := useCase.CapturePayment(context.Background(), CaptureRequest{TransactionID: "txn123", Amount: "100.00"})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "Payment already captured", response.Message)
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestPaymentUseCase_CapturePayment_InvalidAmount(t *testing.T) {
+	tests := []string{"150.00", "0", "-1.00", "1.001", "abc"}
+
+	for _, amount := range tests {
+		t.Run(amount, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockPaymentRepository)
+			useCase := NewPaymentUseCase(mockRepo)
+
+			mockRepo.On("GetByTransactionID", mock.Anything, "txn123").Return(authorizedPayment(time.Now(), time.Hour), nil)
+
+			// Act
+			response, err := useCase.CapturePayment(context.Background(), CaptureRequest{TransactionID: "txn123", Amount: amount})
+
+			// Assert
+			assert.Equal(t, ErrInvalidCaptureAmount, err)
+			assert.Equal(t, entity.StatusAuthorized, response.Status)
+			mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestPaymentUseCase_CapturePayment_ExpiredAuthorizationIsVoided(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockPaymentRepository)
+	authorizedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	useCase := NewPaymentUseCase(mockRepo)
+	useCase.now = func() time.Time { return authorizedAt.Add(2 * time.Hour) }
+
+	payment := authorizedPayment(authorizedAt, time.Hour)
+	mockRepo.On("GetByTransactionID", mock.Anything, "txn123").Return(payment, nil)
+	mockRepo.On("Update", mock.Anything, payment).Return(nil)
+
+	// Act
+	response, err := useCase.CapturePayment(context.Background(), CaptureRequest{TransactionID: "txn123"})
+
+	// Assert
+	assert.Equal(t, ErrAuthorizationExpired, err)
+	assert.Equal(t, entity.StatusVoided, response.Status)
+	assert.Equal(t, SystemActor, payment.StatusHistory[len(payment.StatusHistory)-1].Actor)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPaymentUseCase_GetPayment_ExpiredAuthorizationIsVoided(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockPaymentRepository)
+	authorizedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	useCase := NewPaymentUseCase(mockRepo)
+	useCase.now = func() time.Time { return authorizedAt.Add(2 * time.Hour) }
+
+	payment := authorizedPayment(authorizedAt, time.Hour)
+	mockRepo.On("GetByTransactionID", mock.Anything, "txn123").Return(payment, nil)
+	mockRepo.On("Update", mock.Anything, payment).Return(nil).Once()
+
+	// Act
+	got, err := useCase.GetPayment(context.Background(), "txn123")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, entity.StatusVoided, got.Status)
+	assert.Nil(t, got.AuthorizationExpiresAt)
+	assert.Equal(t, "authorization expired", got.StatusHistory[len(got.StatusHistory)-1].Reason)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPaymentUseCase_AuthorizePayment_ExpiredAuthorizationIsVoided(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockPaymentRepository)
+	authorizedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	useCase := NewPaymentUseCase(mockRepo)
+	useCase.now = func() time.Time { return authorizedAt.Add(2 * time.Hour) }
+
+	payment := authorizedPayment(authorizedAt, time.Hour)
+	mockRepo.On("GetByTransactionID", mock.Anything, "txn123").Return(payment, nil)
+	mockRepo.On("Update", mock.Anything, payment).Return(nil).Once()
+
+	// Act
+	response, err := useCase.AuthorizePayment(context.Background(), "txn123")
+
+	// Assert
+	assert.Equal(t, ErrAuthorizationExpired, err)
+	assert.Equal(t, entity.StatusVoided, response.Status)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPaymentUseCase_ExpireAuthorizations(t *testing.T) {
+	// Arrange: one lapsed and one live authorization
+	mockRepo := new(MockPaymentRepository)
+	now := time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)
+	useCase := NewPaymentUseCase(mockRepo)
+	useCase.now = func() time.Time { return now }
+
+	lapsed := authorizedPayment(now.Add(-2*time.Hour), time.Hour)
+	live := authorizedPayment(now.Add(-time.Minute), time.Hour)
+	live.TransactionID = "txn456"
+	mockRepo.On("List", mock.Anything, PaymentQuery{Status: entity.StatusAuthorized, Limit: MaxPageSize}).Return([]*entity.Payment{live, lapsed}, nil)
+	mockRepo.On("GetByTransactionID", mock.Anything, "txn123").Return(lapsed, nil)
+	mockRepo.On("Update", mock.Anything, lapsed).Return(nil).Once()
+
+	// Act
+	expired, err := useCase.ExpireAuthorizations(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 1, expired)
+	assert.Equal(t, entity.StatusVoided, lapsed.Status)
+	assert.Equal(t, entity.StatusAuthorized, live.Status)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPaymentUseCase_CapturePayment_RetriesConcurrentUpdate(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockPaymentRepository)
+	useCase := NewPaymentUseCase(mockRepo)
+
+	mockRepo.On("GetByTransactionID", mock.Anything, "txn123").Return(
+		func(string) *entity.Payment { return authorizedPayment(time.Now(), time.Hour) }, nil)
+	mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*entity.Payment")).Return(ErrConcurrentUpdate).Once()
+	mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*entity.Payment")).Return(nil).Once()
+
+	// Act
+	response, err := useCase.CapturePayment(context.Background(), CaptureRequest{TransactionID: "txn123"})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, entity.StatusCaptured, response.Status)
+	mockRepo.AssertNumberOfCalls(t, "GetByTransactionID", 2)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPaymentUseCase_VoidPayment(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockPaymentRepository)
+	useCase := NewPaymentUseCase(mockRepo)
+
+	payment := authorizedPayment(time.Now(), time.Hour)
+	mockRepo.On("GetByTransactionID", mock.Anything, "txn123").Return(payment, nil)
+	mockRepo.On("Update", mock.Anything, payment).Return(nil).Once()
+
+	// Act
+	first, firstErr := useCase.VoidPayment(context.Background(), "txn123")
+	second, secondErr := useCase.VoidPayment(context.Background(), "txn123")
+
+	// Assert
+	assert.NoError(t, firstErr)
+	assert.Equal(t, entity.StatusVoided, first.Status)
+	assert.Equal(t, "Payment voided", first.Message)
+	assert.NoError(t, secondErr)
+	assert.Equal(t, "Payment already voided", second.Message)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPaymentUseCase_VoidPayment_CapturedPaymentRejected(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockPaymentRepository)
+	useCase := NewPaymentUseCase(mockRepo)
+
+	payment := authorizedPayment(time.Now(), time.Hour)
+	payment.TransitionTo(entity.StatusCaptured, MerchantActor, "payment captured", time.Now())
+	mockRepo.On("GetByTransactionID", mock.Anything, "txn123").Return(payment, nil)
+
+	// Act
+	response, err := useCase.VoidPayment(context.Background(), "txn123")
+
+	// Assert
+	assert.ErrorIs(t, err, entity.ErrInvalidTransition)
+	assert.Equal(t, entity.StatusCaptured, response.Status)
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+// capturedPayment returns a payment captured for 100.00 USD
+func capturedPayment() *entity.Payment {
+	payment := authorizedPayment(time.Now(), time.Hour)
+	payment.TransitionTo(entity.StatusCaptured, SystemActor, "payment captured", time.Now())
+	payment.CapturedAmount = payment.Amount
+	payment.AuthorizationExpiresAt = nil
+	return payment
+}
+
+func TestPaymentUseCase_RefundPayment_PartialThenFull(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockPaymentRepository)
+	useCase := NewPaymentUseCase(mockRepo)
+
+	payment := capturedPayment()
+	mockRepo.On("GetByTransactionID", mock.Anything, "txn123").Return(payment, nil)
+	mockRepo.On("Update", mock.Anything, payment).Return(nil).Twice()
+
+	// Act
+	partial, partialErr := useCase.RefundPayment(context.Background(), RefundRequest{
+		TransactionID:  "txn123",
+		Amount:         "30.00",
+		Reason:         entity.RefundReasonRequestedByCustomer,
+		IdempotencyKey: "refund-1",
+	})
+	rest, restErr := useCase.RefundPayment(context.Background(), RefundRequest{
+		TransactionID:  "txn123",
+		Reason:         entity.RefundReasonOther,
+		IdempotencyKey: "refund-2",
+	})
+
+	// Assert
+	assert.NoError(t, partialErr)
+	assert.Equal(t, "txn123-refund-1", partial.RefundID)
+	assert.Equal(t, "30.00", partial.Amount)
+	assert.Equal(t, entity.StatusPartiallyRefunded, partial.PaymentStatus)
+	assert.Equal(t, "30.00", partial.RefundedAmount)
+
+	assert.NoError(t, restErr)
+	assert.Equal(t, "txn123-refund-2", rest.RefundID)
+	assert.Equal(t, "70.00", rest.Amount)
+	assert.Equal(t, entity.StatusRefunded, rest.PaymentStatus)
+	assert.Equal(t, "100.00", rest.RefundedAmount)
+	assert.Len(t, payment.Refunds, 2)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPaymentUseCase_RefundPayment_RetryWithSameKey(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockPaymentRepository)
+	useCase := NewPaymentUseCase(mockRepo)
+
+	payment := capturedPayment()
+	mockRepo.On("GetByTransactionID", mock.Anything, "txn123").Return(payment, nil)
+	mockRepo.On("Update", mock.Anything, payment).Return(nil).Once()
+
+	req := RefundRequest{
+		TransactionID:  "txn123",
+		Amount:         "30.00",
+		Reason:         entity.RefundReasonDuplicate,
+		IdempotencyKey: "refund-1",
+	}
+
+	// Act
+	first, firstErr := useCase.RefundPayment(context.Background(), req)
+	second, secondErr := useCase.RefundPayment(context.Background(), req)
+	req.Amount = "40.00"
+	_, conflictErr := useCase.RefundPayment(context.Background(), req)
+
+	// Assert
+	assert.NoError(t, firstErr)
+	assert.NoError(t, secondErr)
+	assert.Equal(t, first.RefundID, second.RefundID)
+	assert.Equal(t, "Refund already processed", second.Message)
+	assert.Equal(t, ErrRefundConflict, conflictErr)
+	assert.Len(t, payment.Refunds, 1)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPaymentUseCase_RefundPayment_Rejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		payment func() *entity.Payment
+		req     RefundRequest
+		err     error
+	}{
+		{
+			name:    "exceeds captured amount",
+			payment: capturedPayment,
+			req:     RefundRequest{Amount: "100.01", Reason: entity.RefundReasonOther, IdempotencyKey: "refund-1"},
+			err:     ErrInvalidRefundAmount,
+		},
+		{
+			name:    "zero amount",
+			payment: capturedPayment,
+			req:     RefundRequest{Amount: "0", Reason: entity.RefundReasonOther, IdempotencyKey: "refund-1"},
+			err:     ErrInvalidRefundAmount,
+		},
+		{
+			name:    "not captured",
+			payment: func() *entity.Payment { return authorizedPayment(time.Now(), time.Hour) },
+			req:     RefundRequest{Reason: entity.RefundReasonOther, IdempotencyKey: "refund-1"},
+			err:     entity.ErrInvalidTransition,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockPaymentRepository)
+			useCase := NewPaymentUseCase(mockRepo)
+
+			mockRepo.On("GetByTransactionID", mock.Anything, "txn123").Return(tt.payment(), nil)
+			tt.req.TransactionID = "txn123"
+
+			// Act
+			_, err := useCase.RefundPayment(context.Background(), tt.req)
+
+			// Assert
+			assert.ErrorIs(t, err, tt.err)
+			mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestPaymentUseCase_RefundPayment_InvalidRequest(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockPaymentRepository)
+	useCase := NewPaymentUseCase(mockRepo)
+
+	// Act
+	_, missingKeyErr := useCase.RefundPayment(context.Background(), RefundRequest{TransactionID: "txn123", Reason: entity.RefundReasonOther})
+	_, badReasonErr := useCase.RefundPayment(context.Background(), RefundRequest{TransactionID: "txn123", Reason: "because", IdempotencyKey: "refund-1"})
+
+	// Assert
+	assert.Equal(t, ErrMissingRefundKey, missingKeyErr)
+	assert.Equal(t, ErrInvalidRefundReason, badReasonErr)
+	mockRepo.AssertNotCalled(t, "GetByTransactionID", mock.Anything, mock.Anything)
+}
+
+func TestPaymentUseCase_GetPayment(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockPaymentRepository)
+	useCase := NewPaymentUseCase(mockRepo)
+
+	payment := capturedPayment()
+	mockRepo.On("GetByTransactionID", mock.Anything, "txn123").Return(payment, nil)
+	mockRepo.On("GetByTransactionID", mock.Anything, "missing").Return((*entity.Payment)(nil), nil)
+
+	// Act
+	found, foundErr := useCase.GetPayment(context.Background(), "txn123")
+	missing, missingErr := useCase.GetPayment(context.Background(), "missing")
+
+	// Assert
+	assert.NoError(t, foundErr)
+	assert.Equal(t, payment, found)
+	assert.Nil(t, missing)
+	assert.Equal(t, ErrPaymentNotFound, missingErr)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPaymentUseCase_ListPayments_NextCursor(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockPaymentRepository)
+	useCase := NewPaymentUseCase(mockRepo)
+
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	payments := []*entity.Payment{
+		entity.NewPayment("txn3", "user123", entity.Money{Amount: 100, Currency: "USD"}, "user123", "payment requested", at.Add(2*time.Hour)),
+		entity.NewPayment("txn2", "user123", entity.Money{Amount: 100, Currency: "USD"}, "user123", "payment requested", at.Add(time.Hour)),
+		entity.NewPayment("txn1", "user123", entity.Money{Amount: 100, Currency: "USD"}, "user123", "payment requested", at),
+	}
+	minAmount := int64(50)
+	mockRepo.On("List", mock.Anything, PaymentQuery{UserID: "user123", Currency: "USD", MinAmount: &minAmount, Limit: 3}).Return(payments, nil)
+
+	// Act
+	response, err := useCase.ListPayments(context.Background(), ListPaymentsRequest{UserID: "user123", Currency: "USD", MinAmount: "0.50", Limit: 2})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, payments[:2], response.Payments)
+	assert.Equal(t, CursorOf(payments[1]).Encode(), response.NextCursor)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPaymentUseCase_ListPayments_LastPage(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockPaymentRepository)
+	useCase := NewPaymentUseCase(mockRepo)
+
+	cursor := PaymentCursor{CreatedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), TransactionID: "txn1"}
+	mockRepo.On("List", mock.Anything, PaymentQuery{After: &cursor, Limit: DefaultPageSize + 1}).Return([]*entity.Payment(nil), nil)
+
+	// Act
+	response, err := useCase.ListPayments(context.Background(), ListPaymentsRequest{Cursor: cursor.Encode()})
+
+	// Assert
+	require.NoError(t, err)
+	assert.NotNil(t, response.Payments)
+	assert.Empty(t, response.Payments)
+	assert.Empty(t, response.NextCursor)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPaymentUseCase_ListPayments_InvalidRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		req  ListPaymentsRequest
+		err  error
+	}{
+		{"limit too large", ListPaymentsRequest{Limit: MaxPageSize + 1}, ErrInvalidLimit},
+		{"negative limit", ListPaymentsRequest{Limit: -1}, ErrInvalidLimit},
+		{"unknown status", ListPaymentsRequest{Status: "completed"}, ErrInvalidStatus},
+		{"unknown currency", ListPaymentsRequest{Currency: "XXX"}, ErrInvalidCurrency},
+		{"amount without currency", ListPaymentsRequest{MinAmount: "10.00"}, ErrInvalidAmountRange},
+		{"inverted amount range", ListPaymentsRequest{Currency: "USD", MinAmount: "10.00", MaxAmount: "5.00"}, ErrInvalidAmountRange},
+		{"malformed time", ListPaymentsRequest{CreatedFrom: "yesterday"}, ErrInvalidDateRange},
+		{"inverted time range", ListPaymentsRequest{CreatedFrom: "2024-06-01T00:00:00Z", CreatedTo: "2024-05-01T00:00:00Z"}, ErrInvalidDateRange},
+		{"bad cursor", ListPaymentsRequest{Cursor: "???"}, ErrInvalidCursor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockPaymentRepository)
+			useCase := NewPaymentUseCase(mockRepo)
+
+			// Act
+			_, err := useCase.ListPayments(context.Background(), tt.req)
+
+			// Assert
+			assert.Equal(t, tt.err, err)
+			mockRepo.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestPaymentUseCase_GetStatement_ReadsEveryPage(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockPaymentRepository)
+	useCase := NewPaymentUseCase(mockRepo)
+
+	may := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	june := may.AddDate(0, 1, 0)
+	fullPage := make([]*entity.Payment, MaxPageSize)
+	for i := range fullPage {
+		payment := entity.NewPayment(fmt.Sprintf("txn%03d", i), "user123", entity.Money{Amount: 100, Currency: "USD"}, "user123", "payment requested", june.Add(-time.Duration(i+1)*time.Minute))
+		payment.CapturedAmount = payment.Amount
+		fullPage[i] = payment
+	}
+	last := entity.NewPayment("txn-last", "user123", entity.Money{Amount: 100, Currency: "USD"}, "user123", "payment requested", may.AddDate(0, -1, 0))
+	cursor := CursorOf(fullPage[len(fullPage)-1])
+
+	mockRepo.On("List", mock.Anything, PaymentQuery{UserID: "user123", CreatedTo: &june, Limit: MaxPageSize}).Return(fullPage, nil).Once()
+	mockRepo.On("List", mock.Anything, PaymentQuery{UserID: "user123", CreatedTo: &june, After: &cursor, Limit: MaxPageSize}).Return([]*entity.Payment{last}, nil).Once()
+
+	// Act
+	statement, err := useCase.GetStatement(context.Background(), "user123", "2024-05")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Len(t, statement.Entries, MaxPageSize)
+	require.Len(t, statement.Totals, 1)
+	assert.Equal(t, int64(MaxPageSize*100), statement.Totals[0].Net.Amount)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPaymentUseCase_GetStatement_InvalidRequest(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockPaymentRepository)
+	useCase := NewPaymentUseCase(mockRepo)
+
+	// Act
+	_, badMonthErr := useCase.GetStatement(context.Background(), "user123", "May 2024")
+	_, noUserErr := useCase.GetStatement(context.Background(), "", "2024-05")
+
+	// Assert
+	assert.Equal(t, ErrInvalidMonth, badMonthErr)
+	assert.Equal(t, ErrInvalidUserID, noUserErr)
+	mockRepo.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,99 @@
+package usecase
+
+import (
+	"context"
+	"payment-service/internal/entity"
+	"strings"
+	"time"
+)
+
+// providerTransitions maps each type of processor event to the status it moves a payment to
+var providerTransitions = map[string]string{
+	ProviderEventSettled:       entity.StatusSettled,
+	ProviderEventDeclined:      entity.StatusFailed,
+	ProviderEventDisputeOpened: entity.StatusDisputed,
+	ProviderEventDisputeWon:    entity.StatusSettled,
+	ProviderEventDisputeLost:   entity.StatusChargedBack,
+}
+
+// ApplyProviderEvent applies a processor's notification to the payment it concerns. An event is
+// applied once: a redelivered event is recognised by its ID in the status history and changes nothing.
+// Events the payment's lifecycle does not allow, such as a settlement arriving after a full refund,
+// are acknowledged without changing the payment. A payment that is not stored, or not handled by the
+// event's processor, fails with ErrPaymentNotFound, so the processor resends the event later.
+func (p *PaymentUseCase) ApplyProviderEvent(ctx context.Context, event ProviderEvent) (*ProviderEventResponse, error) {
+	response := &ProviderEventResponse{EventID: event.ID, TransactionID: event.TransactionID}
+	_, known := providerTransitions[event.Type]
+	if event.ID == "" || event.TransactionID == "" || !known {
+		response.Message = ErrInvalidProviderEvent.Error()
+		return response, ErrInvalidProviderEvent
+	}
+
+	response.Message = "Payment status updated"
+	payment, err := p.updatePayment(ctx, event.TransactionID, func(payment *entity.Payment, now time.Time) (bool, error) {
+		if !handledBy(payment, event) {
+			return false, ErrPaymentNotFound
+		}
+		if payment.AppliedEvent(event.ID) {
+			response.Message = "Event already processed"
+			return false, nil
+		}
+		status := providerStatus(payment, event.Type)
+		if !entity.CanTransition(payment.Status, status) {
+			response.Message = "Event does not apply to a " + payment.Status + " payment"
+			return false, nil
+		}
+
+		if err := payment.TransitionTo(status, ProcessorActor, providerReason(event), now); err != nil {
+			return false, err
+		}
+		payment.StatusHistory[len(payment.StatusHistory)-1].EventID = event.ID
+		if status == entity.StatusFailed {
+			payment.AuthorizationExpiresAt = nil
+		}
+		if status == entity.StatusChargedBack {
+			p.recordChargeback(payment, now)
+		}
+		response.Applied = true
+		return true, nil
+	})
+	if err != nil {
+		response.Applied = false
+		response.Message = err.Error()
+		return response, err
+	}
+	response.Status = payment.Status
+	return response, nil
+}
+
+// providerStatus returns the status an event moves a payment to. A won dispute settles the payment
+// again, unless it was partly refunded, in which case it returns to partially_refunded.
+func providerStatus(payment *entity.Payment, eventType string) string {
+	if eventType == ProviderEventDisputeWon && payment.RefundedAmount().IsPositive() {
+		return entity.StatusPartiallyRefunded
+	}
+	return providerTransitions[eventType]
+}
+
+// handledBy reports whether the event's processor charged the payment. Routed payments
+// record their processor and prefix its reference with the processor's name.
+func handledBy(payment *entity.Payment, event ProviderEvent) bool {
+	if payment.PaymentMethod != entity.PaymentMethodCard {
+		return false
+	}
+	if payment.Processor != "" && payment.Processor != event.Provider {
+		return false
+	}
+	return event.Reference == "" ||
+		payment.ProcessorReference == event.Reference ||
+		payment.ProcessorReference == event.Provider+":"+event.Reference
+}
+
+// providerReason describes a processor event for the status history, such as "dispute opened by primary: fraudulent"
+func providerReason(event ProviderEvent) string {
+	reason := strings.ReplaceAll(event.Type, "_", " ") + " by " + event.Provider
+	if event.Reason != "" {
+		reason += ": " + event.Reason
+	}
+	return reason
+}
@@ -0,0 +1,80 @@
+package usecase
+
+import (
+	"encoding/base64"
+	"payment-service/internal/entity"
+	"strings"
+	"time"
+)
+
+// PaymentQuery selects payments for PaymentRepository.List. Empty or nil fields do not filter.
+type PaymentQuery struct {
+	UserID      string
+	Status      string
+	Currency    string
+	MinAmount   *int64         // Smallest amount in minor units, inclusive
+	MaxAmount   *int64         // Largest amount in minor units, inclusive
+	CreatedFrom *time.Time     // Earliest creation time, inclusive
+	CreatedTo   *time.Time     // Latest creation time, exclusive
+	Queued      bool           // Only payments waiting for a worker
+	After       *PaymentCursor // Only payments listed after this position
+	Limit       int            // Maximum number of payments to return
+}
+
+// Matches reports whether the payment satisfies every filter of the query. The After position is not checked.
+func (q PaymentQuery) Matches(payment *entity.Payment) bool {
+	switch {
+	case q.UserID != "" && payment.UserID != q.UserID,
+		q.Status != "" && payment.Status != q.Status,
+		q.Currency != "" && payment.Amount.Currency != q.Currency,
+		q.MinAmount != nil && payment.Amount.Amount < *q.MinAmount,
+		q.MaxAmount != nil && payment.Amount.Amount > *q.MaxAmount,
+		q.CreatedFrom != nil && payment.CreatedAt.Before(*q.CreatedFrom),
+		q.CreatedTo != nil && !payment.CreatedAt.Before(*q.CreatedTo),
+		q.Queued && !payment.Queued:
+		return false
+	}
+	return true
+}
+
+// PaymentCursor is a position in the payment listing order: newest first, ties broken by
+// descending transaction ID. It stays valid while payments are added or changed.
+type PaymentCursor struct {
+	CreatedAt     time.Time
+	TransactionID string
+}
+
+// CursorOf returns the listing position of a payment
+func CursorOf(payment *entity.Payment) PaymentCursor {
+	return PaymentCursor{CreatedAt: payment.CreatedAt.Round(0), TransactionID: payment.TransactionID}
+}
+
+// Before reports whether c is listed before other
+func (c PaymentCursor) Before(other PaymentCursor) bool {
+	if !c.CreatedAt.Equal(other.CreatedAt) {
+		return c.CreatedAt.After(other.CreatedAt)
+	}
+	return c.TransactionID > other.TransactionID
+}
+
+// Encode returns the opaque string form of the cursor handed to API clients
+func (c PaymentCursor) Encode() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.TransactionID))
+}
+
+// DecodeCursor parses a cursor produced by PaymentCursor.Encode
+func DecodeCursor(s string) (PaymentCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return PaymentCursor{}, ErrInvalidCursor
+	}
+	createdAt, transactionID, ok := strings.Cut(string(raw), "|")
+	if !ok || transactionID == "" {
+		return PaymentCursor{}, ErrInvalidCursor
+	}
+	parsed, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return PaymentCursor{}, ErrInvalidCursor
+	}
+	return PaymentCursor{CreatedAt: parsed, TransactionID: transactionID}, nil
+}
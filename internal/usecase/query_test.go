@@ -0,0 +1,53 @@
+package usecase
+
+import (
+	"payment-service/internal/entity"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaymentCursor_EncodeDecode(t *testing.T) {
+	// Arrange
+	cursor := PaymentCursor{CreatedAt: time.Date(2024, 5, 1, 12, 0, 0, 123456789, time.UTC), TransactionID: "txn|123"}
+
+	// Act
+	decoded, err := DecodeCursor(cursor.Encode())
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, cursor.CreatedAt.Equal(decoded.CreatedAt))
+	assert.Equal(t, cursor.TransactionID, decoded.TransactionID)
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	for _, cursor := range []string{"not base64!", "bm8tc2VwYXJhdG9y", "bm90LWEtdGltZXx0eG4"} {
+		_, err := DecodeCursor(cursor)
+		assert.Equal(t, ErrInvalidCursor, err, cursor)
+	}
+}
+
+func TestPaymentCursor_Before(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.True(t, PaymentCursor{CreatedAt: at.Add(time.Second), TransactionID: "a"}.Before(PaymentCursor{CreatedAt: at, TransactionID: "b"}))
+	assert.True(t, PaymentCursor{CreatedAt: at, TransactionID: "b"}.Before(PaymentCursor{CreatedAt: at, TransactionID: "a"}))
+	assert.False(t, PaymentCursor{CreatedAt: at, TransactionID: "a"}.Before(PaymentCursor{CreatedAt: at, TransactionID: "a"}))
+}
+
+func TestPaymentQuery_Matches(t *testing.T) {
+	// Arrange
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	payment := entity.NewPayment("txn123", "user123", entity.Money{Amount: 1000, Currency: "USD"}, "user123", "payment requested", at)
+	low, high := int64(1000), int64(999)
+	later := at.Add(time.Second)
+
+	// Act & Assert
+	assert.True(t, PaymentQuery{}.Matches(payment))
+	assert.True(t, PaymentQuery{UserID: "user123", Status: entity.StatusPending, Currency: "USD", MinAmount: &low, CreatedFrom: &at, CreatedTo: &later}.Matches(payment))
+	assert.False(t, PaymentQuery{UserID: "other"}.Matches(payment))
+	assert.False(t, PaymentQuery{MaxAmount: &high}.Matches(payment))
+	assert.False(t, PaymentQuery{CreatedTo: &at}.Matches(payment))
+}
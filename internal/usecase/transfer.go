@@ -0,0 +1,244 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"payment-service/internal/entity"
+	"time"
+)
+
+// TransferLimits caps the amounts users can send in one currency, in minor units of that currency.
+// Zero leaves a limit off.
+type TransferLimits struct {
+	PerTransfer int64 // Largest single transfer
+	Daily       int64 // Most a user can send in any 24 hours
+}
+
+// WithTransfers enables transfers between user wallets. Transfers also need WithWallets.
+func WithTransfers(repo TransferRepository) Option {
+	return func(p *PaymentUseCase) {
+		p.transfers = repo
+	}
+}
+
+// WithTransferLimits caps the amounts users can send by transfer, with limits keyed by currency.
+// Once any limits are set, transfers in a currency without limits are rejected with
+// ErrTransferCurrencyNotAllowed, since minor units are worth too little in some currencies
+// and too much in others for one limit to suit them all.
+func WithTransferLimits(limits map[string]TransferLimits) Option {
+	return func(p *PaymentUseCase) {
+		p.transferLimits = limits
+	}
+}
+
+// TransferFunds moves funds from one user's wallet to another's with the same idempotency as
+// ProcessPayment: retrying a transaction ID returns the original transfer, and reusing it for a
+// different request fails with ErrIdempotencyConflict. Transfers declined for insufficient funds
+// or the daily limit can be retried with the same transaction ID once the sender can afford them.
+func (p *PaymentUseCase) TransferFunds(ctx context.Context, req TransferRequest) (*TransferResponse, error) {
+	amount, err := p.validateTransferRequest(req)
+	if err != nil {
+		return transferFailedResponse(req, err.Error()), err
+	}
+
+	// The transfer is claimed before any funds move, so concurrent retries agree on one request
+	transfer := &entity.Transfer{
+		TransactionID:      req.TransactionID,
+		FromUserID:         req.FromUserID,
+		ToUserID:           req.ToUserID,
+		Amount:             amount,
+		Status:             entity.TransferPending,
+		CreatedAt:          p.now(),
+		RequestFingerprint: transferFingerprint(req),
+	}
+	stored, created, err := p.transfers.CreateTransferIfAbsent(ctx, transfer)
+	if err != nil {
+		return transferFailedResponse(req, "Failed to process transfer"), err
+	}
+	if !created {
+		if stored.RequestFingerprint != transfer.RequestFingerprint {
+			return transferFailedResponse(req, ErrIdempotencyConflict.Error()), ErrIdempotencyConflict
+		}
+		if stored.Status == entity.TransferCompleted {
+			return transferResponse(stored, "Transfer already processed"), nil
+		}
+		// Pending transfers were interrupted and declined ones may succeed now; both try again
+	}
+
+	moveErr := p.moveTransferFunds(ctx, stored)
+	status := entity.TransferCompleted
+	switch {
+	case errors.Is(moveErr, ErrInsufficientFunds), errors.Is(moveErr, ErrTransferLimitExceeded):
+		status = entity.TransferDeclined
+	case moveErr != nil:
+		return transferResponse(stored, "Failed to process transfer"), moveErr
+	}
+
+	settled, err := p.settleTransfer(ctx, stored.TransactionID, status)
+	if err != nil {
+		return transferResponse(stored, "Failed to process transfer"), err
+	}
+	// A concurrent retry may have completed the transfer while this one was declined
+	if settled.Status == entity.TransferCompleted {
+		return transferResponse(settled, "Transfer completed"), nil
+	}
+	return transferResponse(settled, moveErr.Error()), moveErr
+}
+
+// GetTransferHistory returns the transfers a user sent and received, newest first.
+// A non-empty counterparty keeps only the transfers between the two users.
+func (p *PaymentUseCase) GetTransferHistory(ctx context.Context, userID, counterparty string) (*TransferHistoryResponse, error) {
+	if p.transfers == nil || p.wallets == nil {
+		return nil, ErrTransfersDisabled
+	}
+	wallet, err := p.GetWallet(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	history := &TransferHistoryResponse{UserID: userID, Counterparty: counterparty, Transfers: []TransferHistoryEntry{}}
+	for i := len(wallet.Transactions) - 1; i >= 0; i-- {
+		transaction := wallet.Transactions[i]
+		var direction string
+		switch transaction.Type {
+		case entity.WalletTransferOut:
+			direction = TransferSent
+		case entity.WalletTransferIn:
+			direction = TransferReceived
+		default:
+			continue
+		}
+		if counterparty != "" && transaction.Counterparty != counterparty {
+			continue
+		}
+		history.Transfers = append(history.Transfers, TransferHistoryEntry{
+			TransactionID: transaction.Reference,
+			Direction:     direction,
+			Counterparty:  transaction.Counterparty,
+			Amount:        transaction.Amount,
+			Balance:       transaction.Balance,
+			At:            transaction.CreatedAt,
+		})
+	}
+	return history, nil
+}
+
+// moveTransferFunds debits the sender and credits the recipient in a single save of both wallets.
+// The wallet transactions are keyed by the transaction ID, so funds never move twice.
+func (p *PaymentUseCase) moveTransferFunds(ctx context.Context, transfer *entity.Transfer) error {
+	id := "transfer:" + transfer.TransactionID
+	_, err := p.updateWallets(ctx, []string{transfer.FromUserID, transfer.ToUserID}, func(wallets []*entity.Wallet, now time.Time) (bool, error) {
+		sender, recipient := wallets[0], wallets[1]
+		if sender.FindTransaction(id) != nil {
+			return false, nil
+		}
+
+		if daily := p.transferLimits[transfer.Amount.Currency].Daily; daily > 0 {
+			sent := sender.SentSince(transfer.Amount.Currency, now.Add(-24*time.Hour))
+			if sent.Amount+transfer.Amount.Amount > daily {
+				return false, ErrTransferLimitExceeded
+			}
+		}
+		if _, ok := sender.TransferOut(id, transfer.TransactionID, transfer.ToUserID, transfer.Amount, now); !ok {
+			return false, ErrInsufficientFunds
+		}
+		recipient.TransferIn(id, transfer.TransactionID, transfer.FromUserID, transfer.Amount, now)
+		return true, nil
+	})
+	return err
+}
+
+// settleTransfer records the outcome of moving a transfer's funds. A completed transfer is final,
+// so a declined outcome never overwrites the success of a concurrent retry.
+func (p *PaymentUseCase) settleTransfer(ctx context.Context, transactionID, status string) (*entity.Transfer, error) {
+	for attempt := 0; attempt < maxUpdateAttempts; attempt++ {
+		transfer, err := p.transfers.GetTransfer(ctx, transactionID)
+		if err != nil {
+			return nil, err
+		}
+		if transfer == nil {
+			return nil, ErrTransferNotFound
+		}
+		if transfer.Status == entity.TransferCompleted || transfer.Status == status {
+			return transfer, nil
+		}
+
+		transfer.Status = status
+		if status == entity.TransferCompleted {
+			now := p.now()
+			transfer.CompletedAt = &now
+		}
+		if err := p.transfers.UpdateTransfer(ctx, transfer); err != nil {
+			if errors.Is(err, ErrConcurrentUpdate) {
+				continue
+			}
+			return transfer, err
+		}
+		return transfer, nil
+	}
+
+	return nil, ErrConcurrentUpdate
+}
+
+// validateTransferRequest validates a transfer request and returns the parsed amount
+func (p *PaymentUseCase) validateTransferRequest(req TransferRequest) (entity.Money, error) {
+	if p.transfers == nil || p.wallets == nil {
+		return entity.Money{}, ErrTransfersDisabled
+	}
+	if req.FromUserID == "" || req.ToUserID == "" {
+		return entity.Money{}, ErrInvalidUserID
+	}
+	if req.FromUserID == req.ToUserID {
+		return entity.Money{}, ErrSelfTransfer
+	}
+	if req.TransactionID == "" {
+		return entity.Money{}, ErrInvalidTransaction
+	}
+	if _, ok := entity.CurrencyExponent(req.Currency); !ok {
+		return entity.Money{}, ErrInvalidCurrency
+	}
+	amount, err := entity.ParseMoney(req.Amount, req.Currency)
+	if err != nil {
+		return entity.Money{}, ErrInvalidAmountFormat
+	}
+	if !amount.IsPositive() {
+		return entity.Money{}, ErrInvalidAmount
+	}
+	limits, ok := p.transferLimits[amount.Currency]
+	if !ok && len(p.transferLimits) > 0 {
+		return entity.Money{}, ErrTransferCurrencyNotAllowed
+	}
+	if limit := limits.PerTransfer; limit > 0 && amount.Amount > limit {
+		return entity.Money{}, ErrTransferLimitExceeded
+	}
+	return amount, nil
+}
+
+// transferResponse builds the response describing a stored transfer
+func transferResponse(transfer *entity.Transfer, message string) *TransferResponse {
+	createdAt := transfer.CreatedAt
+	return &TransferResponse{
+		TransactionID: transfer.TransactionID,
+		FromUserID:    transfer.FromUserID,
+		ToUserID:      transfer.ToUserID,
+		Amount:        transfer.Amount.Decimal(),
+		Currency:      transfer.Amount.Currency,
+		Status:        transfer.Status,
+		CreatedAt:     &createdAt,
+		CompletedAt:   transfer.CompletedAt,
+		Message:       message,
+	}
+}
+
+// transferFailedResponse builds the response for a transfer request that could not be processed
+func transferFailedResponse(req TransferRequest, message string) *TransferResponse {
+	return &TransferResponse{
+		TransactionID: req.TransactionID,
+		FromUserID:    req.FromUserID,
+		ToUserID:      req.ToUserID,
+		Amount:        req.Amount,
+		Currency:      req.Currency,
+		Status:        entity.StatusFailed,
+		Message:       message,
+	}
+}
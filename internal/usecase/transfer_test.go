@@ -0,0 +1,221 @@
+package usecase_test
+
+import (
+	"context"
+	"payment-service/internal/entity"
+	"payment-service/internal/ledger"
+	"payment-service/internal/repository"
+	"payment-service/internal/usecase"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTransferUseCase returns a use case with wallets, transfers and a ledger, and user123's wallet topped up with amount USD
+func newTransferUseCase(t *testing.T, amount string, limits map[string]usecase.TransferLimits) (*usecase.PaymentUseCase, *repository.InMemoryPaymentRepository, *ledger.Journal) {
+	repo := repository.NewInMemoryPaymentRepository()
+	l := ledger.NewJournal(repo)
+	useCase := usecase.NewPaymentUseCase(repo,
+		usecase.WithWallets(repo),
+		usecase.WithTransfers(repo),
+		usecase.WithTransferLimits(limits),
+	)
+	_, err := useCase.TopUpWallet(context.Background(), usecase.WalletRequest{UserID: "user123", Amount: amount, Currency: "USD", IdempotencyKey: "initial"})
+	require.NoError(t, err)
+	return useCase, repo, l
+}
+
+func transferRequest(transactionID, amount string) usecase.TransferRequest {
+	return usecase.TransferRequest{FromUserID: "user123", ToUserID: "user456", Amount: amount, Currency: "USD", TransactionID: transactionID}
+}
+
+func TestPaymentUseCase_TransferFunds_Success(t *testing.T) {
+	// Arrange
+	useCase, repo, l := newTransferUseCase(t, "50.00", nil)
+
+	// Act
+	response, err := useCase.TransferFunds(context.Background(), transferRequest("transfer-1", "20.00"))
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, entity.TransferCompleted, response.Status)
+	assert.Equal(t, "Transfer completed", response.Message)
+	assert.NotNil(t, response.CompletedAt)
+	assert.Equal(t, "30.00", walletBalance(t, useCase, "user123"))
+	assert.Equal(t, "20.00", walletBalance(t, useCase, "user456"))
+
+	stored, err := repo.GetTransfer(context.Background(), "transfer-1")
+	require.NoError(t, err)
+	assert.Equal(t, entity.TransferCompleted, stored.Status)
+
+	// Funds stay in the wallets, so the ledger is unchanged
+	assertBalance(t, l, ledger.AccountWalletFunds, usd(5000))
+}
+
+func TestPaymentUseCase_TransferFunds_RetryWithSameTransactionID(t *testing.T) {
+	// Arrange
+	useCase, _, _ := newTransferUseCase(t, "50.00", nil)
+	req := transferRequest("transfer-1", "20.00")
+
+	// Act
+	first, firstErr := useCase.TransferFunds(context.Background(), req)
+	retry, retryErr := useCase.TransferFunds(context.Background(), req)
+	req.Amount = "20"
+	_, equivalentErr := useCase.TransferFunds(context.Background(), req)
+	req.Amount = "25.00"
+	_, conflictErr := useCase.TransferFunds(context.Background(), req)
+
+	// Assert
+	require.NoError(t, firstErr)
+	require.NoError(t, retryErr)
+	assert.Equal(t, "Transfer already processed", retry.Message)
+	assert.NoError(t, equivalentErr, "the same amount spelled differently is the same request")
+	assert.Equal(t, first.CompletedAt, retry.CompletedAt)
+	assert.ErrorIs(t, conflictErr, usecase.ErrIdempotencyConflict)
+	assert.Equal(t, "30.00", walletBalance(t, useCase, "user123"))
+	assert.Equal(t, "20.00", walletBalance(t, useCase, "user456"))
+}
+
+func TestPaymentUseCase_TransferFunds_DeclinedThenRetriedAfterTopUp(t *testing.T) {
+	// Arrange
+	useCase, repo, _ := newTransferUseCase(t, "10.00", nil)
+	req := transferRequest("transfer-1", "20.00")
+
+	// Act
+	declined, declinedErr := useCase.TransferFunds(context.Background(), req)
+	stored, err := repo.GetTransfer(context.Background(), "transfer-1")
+	require.NoError(t, err)
+	_, err = useCase.TopUpWallet(context.Background(), usecase.WalletRequest{UserID: "user123", Amount: "15.00", Currency: "USD", IdempotencyKey: "top-up-2"})
+	require.NoError(t, err)
+	retry, retryErr := useCase.TransferFunds(context.Background(), req)
+
+	// Assert
+	assert.ErrorIs(t, declinedErr, usecase.ErrInsufficientFunds)
+	assert.Equal(t, entity.TransferDeclined, declined.Status)
+	assert.Equal(t, entity.TransferDeclined, stored.Status)
+	require.NoError(t, retryErr)
+	assert.Equal(t, entity.TransferCompleted, retry.Status)
+	assert.Equal(t, "5.00", walletBalance(t, useCase, "user123"))
+	assert.Equal(t, "20.00", walletBalance(t, useCase, "user456"))
+}
+
+func TestPaymentUseCase_TransferFunds_Rejections(t *testing.T) {
+	tests := []struct {
+		name string
+		req  usecase.TransferRequest
+		err  error
+	}{
+		{"self transfer", usecase.TransferRequest{FromUserID: "user123", ToUserID: "user123", Amount: "1.00", Currency: "USD", TransactionID: "t1"}, usecase.ErrSelfTransfer},
+		{"missing recipient", usecase.TransferRequest{FromUserID: "user123", Amount: "1.00", Currency: "USD", TransactionID: "t1"}, usecase.ErrInvalidUserID},
+		{"missing transaction ID", transferRequest("", "1.00"), usecase.ErrInvalidTransaction},
+		{"zero amount", transferRequest("t1", "0.00"), usecase.ErrInvalidAmount},
+		{"unknown currency", usecase.TransferRequest{FromUserID: "user123", ToUserID: "user456", Amount: "1.00", Currency: "XYZ", TransactionID: "t1"}, usecase.ErrInvalidCurrency},
+		{"over the per-transfer limit", transferRequest("t1", "10.01"), usecase.ErrTransferLimitExceeded},
+		{"currency without limits", usecase.TransferRequest{FromUserID: "user123", ToUserID: "user456", Amount: "1.00", Currency: "EUR", TransactionID: "t1"}, usecase.ErrTransferCurrencyNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			useCase, repo, _ := newTransferUseCase(t, "50.00", map[string]usecase.TransferLimits{"USD": {PerTransfer: 1000}})
+
+			// Act
+			response, err := useCase.TransferFunds(context.Background(), tt.req)
+
+			// Assert
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, entity.StatusFailed, response.Status)
+			assert.Equal(t, "50.00", walletBalance(t, useCase, "user123"))
+			stored, err := repo.GetTransfer(context.Background(), "t1")
+			require.NoError(t, err)
+			assert.Nil(t, stored, "rejected requests are not recorded")
+		})
+	}
+}
+
+func TestPaymentUseCase_TransferFunds_DailyLimit(t *testing.T) {
+	// Arrange
+	useCase, _, _ := newTransferUseCase(t, "100.00", map[string]usecase.TransferLimits{"USD": {Daily: 5000}})
+
+	// Act
+	_, firstErr := useCase.TransferFunds(context.Background(), transferRequest("transfer-1", "30.00"))
+	declined, declinedErr := useCase.TransferFunds(context.Background(), transferRequest("transfer-2", "20.01"))
+	_, lastErr := useCase.TransferFunds(context.Background(), transferRequest("transfer-3", "20.00"))
+
+	// Assert
+	require.NoError(t, firstErr)
+	assert.ErrorIs(t, declinedErr, usecase.ErrTransferLimitExceeded)
+	assert.Equal(t, entity.TransferDeclined, declined.Status)
+	require.NoError(t, lastErr)
+	assert.Equal(t, "50.00", walletBalance(t, useCase, "user123"))
+}
+
+func TestPaymentUseCase_TransferFunds_LimitsPerCurrency(t *testing.T) {
+	// Arrange: 1,000 minor units are 10.00 USD but only 1,000 JPY
+	useCase, _, _ := newTransferUseCase(t, "50.00", map[string]usecase.TransferLimits{
+		"USD": {PerTransfer: 1000},
+		"JPY": {PerTransfer: 100000},
+	})
+	_, err := useCase.TopUpWallet(context.Background(), usecase.WalletRequest{UserID: "user123", Amount: "50000", Currency: "JPY", IdempotencyKey: "initial-jpy"})
+	require.NoError(t, err)
+
+	// Act
+	_, usdErr := useCase.TransferFunds(context.Background(), transferRequest("transfer-1", "10.01"))
+	_, jpyErr := useCase.TransferFunds(context.Background(), usecase.TransferRequest{FromUserID: "user123", ToUserID: "user456", Amount: "20000", Currency: "JPY", TransactionID: "transfer-2"})
+
+	// Assert
+	assert.ErrorIs(t, usdErr, usecase.ErrTransferLimitExceeded)
+	assert.NoError(t, jpyErr, "the JPY limit applies to JPY transfers")
+}
+
+func TestPaymentUseCase_TransferFunds_Disabled(t *testing.T) {
+	// Arrange
+	repo := repository.NewInMemoryPaymentRepository()
+	useCase := usecase.NewPaymentUseCase(repo, usecase.WithWallets(repo))
+
+	// Act
+	_, transferErr := useCase.TransferFunds(context.Background(), transferRequest("transfer-1", "1.00"))
+	_, historyErr := useCase.GetTransferHistory(context.Background(), "user123", "")
+
+	// Assert
+	assert.ErrorIs(t, transferErr, usecase.ErrTransfersDisabled)
+	assert.ErrorIs(t, historyErr, usecase.ErrTransfersDisabled)
+}
+
+func TestPaymentUseCase_GetTransferHistory(t *testing.T) {
+	// Arrange
+	useCase, _, _ := newTransferUseCase(t, "100.00", nil)
+	_, err := useCase.TransferFunds(context.Background(), transferRequest("transfer-1", "30.00"))
+	require.NoError(t, err)
+	_, err = useCase.TransferFunds(context.Background(), usecase.TransferRequest{FromUserID: "user456", ToUserID: "user123", Amount: "10.00", Currency: "USD", TransactionID: "transfer-2"})
+	require.NoError(t, err)
+	_, err = useCase.TransferFunds(context.Background(), usecase.TransferRequest{FromUserID: "user123", ToUserID: "user789", Amount: "5.00", Currency: "USD", TransactionID: "transfer-3"})
+	require.NoError(t, err)
+
+	// Act
+	all, err := useCase.GetTransferHistory(context.Background(), "user123", "")
+	require.NoError(t, err)
+	between, err := useCase.GetTransferHistory(context.Background(), "user123", "user456")
+	require.NoError(t, err)
+	counterpart, err := useCase.GetTransferHistory(context.Background(), "user456", "user123")
+	require.NoError(t, err)
+
+	// Assert
+	require.Len(t, all.Transfers, 3)
+	assert.Equal(t, "transfer-3", all.Transfers[0].TransactionID, "newest first")
+	assert.Equal(t, "user789", all.Transfers[0].Counterparty)
+	assert.Equal(t, usecase.TransferSent, all.Transfers[0].Direction)
+
+	require.Len(t, between.Transfers, 2)
+	assert.Equal(t, "transfer-2", between.Transfers[0].TransactionID)
+	assert.Equal(t, usecase.TransferReceived, between.Transfers[0].Direction)
+	assert.Equal(t, usd(8000), between.Transfers[0].Balance)
+	assert.Equal(t, "transfer-1", between.Transfers[1].TransactionID)
+	assert.Equal(t, usecase.TransferSent, between.Transfers[1].Direction)
+
+	require.Len(t, counterpart.Transfers, 2)
+	assert.Equal(t, usecase.TransferSent, counterpart.Transfers[0].Direction)
+	assert.Equal(t, usecase.TransferReceived, counterpart.Transfers[1].Direction)
+	assert.Equal(t, usd(2000), counterpart.Transfers[0].Balance)
+}
@@ -0,0 +1,214 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"payment-service/internal/entity"
+	"time"
+)
+
+// WithWallets enables stored-value wallets and payments paid from them
+func WithWallets(repo WalletRepository) Option {
+	return func(p *PaymentUseCase) {
+		p.wallets = repo
+	}
+}
+
+// GetWallet returns a user's wallet. Users who never used their wallet have an empty one.
+func (p *PaymentUseCase) GetWallet(ctx context.Context, userID string) (*entity.Wallet, error) {
+	if p.wallets == nil {
+		return nil, ErrWalletsDisabled
+	}
+	if userID == "" {
+		return nil, ErrInvalidUserID
+	}
+	wallet, err := p.wallets.GetWallet(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if wallet == nil {
+		wallet = entity.NewWallet(userID)
+	}
+	return wallet, nil
+}
+
+// TopUpWallet adds funds to a user's wallet.
+// Retrying with the same idempotency key returns the original top-up without adding funds twice.
+func (p *PaymentUseCase) TopUpWallet(ctx context.Context, req WalletRequest) (*WalletResponse, error) {
+	return p.walletOperation(ctx, req, entity.WalletTopUp, "Wallet topped up")
+}
+
+// DebitWallet withdraws funds from a user's wallet, failing with ErrInsufficientFunds if the
+// balance is too low. Retrying with the same idempotency key returns the original debit.
+func (p *PaymentUseCase) DebitWallet(ctx context.Context, req WalletRequest) (*WalletResponse, error) {
+	return p.walletOperation(ctx, req, entity.WalletDebit, "Wallet debited")
+}
+
+// walletOperation applies a top-up or debit requested through the wallet endpoints
+func (p *PaymentUseCase) walletOperation(ctx context.Context, req WalletRequest, transactionType, message string) (*WalletResponse, error) {
+	amount, err := p.validateWalletRequest(req)
+	if err != nil {
+		return walletFailedResponse(req, err), err
+	}
+
+	id := transactionType + ":" + req.IdempotencyKey
+	var transaction entity.WalletTransaction
+	_, err = p.updateWallets(ctx, []string{req.UserID}, func(wallets []*entity.Wallet, now time.Time) (bool, error) {
+		wallet := wallets[0]
+		if existing := wallet.FindTransaction(id); existing != nil {
+			if existing.Amount != amount {
+				return false, ErrWalletConflict
+			}
+			transaction = *existing
+			message = "Wallet operation already processed"
+			return false, nil
+		}
+
+		if transactionType == entity.WalletTopUp {
+			transaction = wallet.Credit(id, transactionType, "", amount, now)
+		} else {
+			debit, ok := wallet.Debit(id, transactionType, "", amount, now)
+			if !ok {
+				return false, ErrInsufficientFunds
+			}
+			transaction = debit
+		}
+		p.recordWalletTransaction(wallet, transaction)
+		return true, nil
+	})
+	if err != nil {
+		return walletFailedResponse(req, err), err
+	}
+	return walletResponse(req.UserID, transaction, message), nil
+}
+
+// payFromWallet debits the payment amount from the payer's wallet. It reports whether this call
+// took the funds; a debit already made for the transaction ID is not repeated.
+func (p *PaymentUseCase) payFromWallet(ctx context.Context, payment *entity.Payment) (bool, error) {
+	id := "payment:" + payment.TransactionID
+	debited := false
+	_, err := p.updateWallets(ctx, []string{payment.UserID}, func(wallets []*entity.Wallet, now time.Time) (bool, error) {
+		if wallets[0].FindTransaction(id) != nil {
+			return false, nil
+		}
+		if _, ok := wallets[0].Debit(id, entity.WalletPayment, payment.TransactionID, payment.Amount, now); !ok {
+			return false, ErrInsufficientFunds
+		}
+		debited = true
+		return true, nil
+	})
+	return debited, err
+}
+
+// reverseWalletPayment returns the funds payFromWallet took for a payment that was never stored
+func (p *PaymentUseCase) reverseWalletPayment(ctx context.Context, payment *entity.Payment) error {
+	id := "payment:" + payment.TransactionID + ":reversal"
+	_, err := p.updateWallets(ctx, []string{payment.UserID}, func(wallets []*entity.Wallet, now time.Time) (bool, error) {
+		if wallets[0].FindTransaction(id) != nil {
+			return false, nil
+		}
+		wallets[0].Credit(id, entity.WalletRefund, payment.TransactionID, payment.Amount, now)
+		return true, nil
+	})
+	return err
+}
+
+// refundToWallet returns a refund of a wallet payment to the payer's wallet, once per refund
+func (p *PaymentUseCase) refundToWallet(ctx context.Context, payment *entity.Payment, refund entity.Refund) error {
+	id := "refund:" + refund.RefundID
+	_, err := p.updateWallets(ctx, []string{payment.UserID}, func(wallets []*entity.Wallet, now time.Time) (bool, error) {
+		if wallets[0].FindTransaction(id) != nil {
+			return false, nil
+		}
+		wallets[0].Credit(id, entity.WalletRefund, payment.TransactionID, refund.Amount, now)
+		return true, nil
+	})
+	return err
+}
+
+// updateWallets loads the wallets of userIDs, starting empty ones for new users, applies change
+// and saves them together when change reports a modification. Saves that lose an optimistic
+// concurrency race are retried on fresh data, so concurrent debits never overdraw a wallet.
+func (p *PaymentUseCase) updateWallets(ctx context.Context, userIDs []string, change func(wallets []*entity.Wallet, now time.Time) (bool, error)) ([]*entity.Wallet, error) {
+	if p.wallets == nil {
+		return nil, ErrWalletsDisabled
+	}
+
+	for attempt := 0; attempt < maxUpdateAttempts; attempt++ {
+		wallets := make([]*entity.Wallet, len(userIDs))
+		for i, userID := range userIDs {
+			wallet, err := p.wallets.GetWallet(ctx, userID)
+			if err != nil {
+				return nil, err
+			}
+			if wallet == nil {
+				wallet = entity.NewWallet(userID)
+			}
+			wallets[i] = wallet
+		}
+
+		changed, err := change(wallets, p.now())
+		if err != nil || !changed {
+			return wallets, err
+		}
+
+		if err := p.wallets.SaveWallets(ctx, wallets...); err != nil {
+			if errors.Is(err, ErrConcurrentUpdate) {
+				continue
+			}
+			return wallets, err
+		}
+		return wallets, nil
+	}
+
+	return nil, ErrConcurrentUpdate
+}
+
+// validateWalletRequest validates a top-up or debit request and returns the parsed amount
+func (p *PaymentUseCase) validateWalletRequest(req WalletRequest) (entity.Money, error) {
+	if p.wallets == nil {
+		return entity.Money{}, ErrWalletsDisabled
+	}
+	if req.UserID == "" {
+		return entity.Money{}, ErrInvalidUserID
+	}
+	if req.IdempotencyKey == "" {
+		return entity.Money{}, ErrMissingWalletKey
+	}
+	if _, ok := entity.CurrencyExponent(req.Currency); !ok {
+		return entity.Money{}, ErrInvalidCurrency
+	}
+	amount, err := entity.ParseMoney(req.Amount, req.Currency)
+	if err != nil {
+		return entity.Money{}, ErrInvalidAmountFormat
+	}
+	if !amount.IsPositive() {
+		return entity.Money{}, ErrInvalidAmount
+	}
+	return amount, nil
+}
+
+// walletResponse builds the response describing a wallet transaction
+func walletResponse(userID string, transaction entity.WalletTransaction, message string) *WalletResponse {
+	createdAt := transaction.CreatedAt
+	return &WalletResponse{
+		UserID:              userID,
+		WalletTransactionID: transaction.ID,
+		Type:                transaction.Type,
+		Amount:              transaction.Amount.Decimal(),
+		Currency:            transaction.Amount.Currency,
+		Balance:             transaction.Balance.Decimal(),
+		CreatedAt:           &createdAt,
+		Message:             message,
+	}
+}
+
+// walletFailedResponse builds the response for a wallet operation that was not applied
+func walletFailedResponse(req WalletRequest, err error) *WalletResponse {
+	return &WalletResponse{
+		UserID:   req.UserID,
+		Amount:   req.Amount,
+		Currency: req.Currency,
+		Message:  err.Error(),
+	}
+}
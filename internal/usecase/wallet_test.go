@@ -0,0 +1,205 @@
+package usecase_test
+
+import (
+	"context"
+	"payment-service/internal/entity"
+	"payment-service/internal/ledger"
+	"payment-service/internal/repository"
+	"payment-service/internal/usecase"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newWalletUseCase returns a use case with wallets and a ledger, and user123's wallet topped up with amount USD
+func newWalletUseCase(t *testing.T, amount string) (*usecase.PaymentUseCase, *repository.InMemoryPaymentRepository, *ledger.Journal) {
+	repo := repository.NewInMemoryPaymentRepository()
+	l := ledger.NewJournal(repo)
+	useCase := usecase.NewPaymentUseCase(repo, usecase.WithWallets(repo))
+	_, err := useCase.TopUpWallet(context.Background(), usecase.WalletRequest{UserID: "user123", Amount: amount, Currency: "USD", IdempotencyKey: "initial"})
+	require.NoError(t, err)
+	return useCase, repo, l
+}
+
+func walletBalance(t *testing.T, useCase *usecase.PaymentUseCase, userID string) string {
+	t.Helper()
+	wallet, err := useCase.GetWallet(context.Background(), userID)
+	require.NoError(t, err)
+	return wallet.Balance("USD").Decimal()
+}
+
+func TestPaymentUseCase_TopUpWallet_RetryWithSameKey(t *testing.T) {
+	// Arrange
+	useCase, _, l := newWalletUseCase(t, "50.00")
+	req := usecase.WalletRequest{UserID: "user123", Amount: "25.00", Currency: "USD", IdempotencyKey: "top-up-1"}
+
+	// Act
+	first, firstErr := useCase.TopUpWallet(context.Background(), req)
+	retry, retryErr := useCase.TopUpWallet(context.Background(), req)
+	req.Amount = "30.00"
+	_, conflictErr := useCase.TopUpWallet(context.Background(), req)
+
+	// Assert
+	require.NoError(t, firstErr)
+	require.NoError(t, retryErr)
+	assert.Equal(t, "75.00", first.Balance)
+	assert.Equal(t, first.WalletTransactionID, retry.WalletTransactionID)
+	assert.Equal(t, "Wallet operation already processed", retry.Message)
+	assert.ErrorIs(t, conflictErr, usecase.ErrWalletConflict)
+	assert.Equal(t, "75.00", walletBalance(t, useCase, "user123"))
+	assertBalance(t, l, ledger.AccountWalletFunds, usd(7500))
+	assertBalance(t, l, ledger.AccountCash, usd(7500))
+}
+
+func TestPaymentUseCase_DebitWallet(t *testing.T) {
+	// Arrange
+	useCase, _, l := newWalletUseCase(t, "50.00")
+
+	// Act
+	debit, err := useCase.DebitWallet(context.Background(), usecase.WalletRequest{UserID: "user123", Amount: "20.00", Currency: "USD", IdempotencyKey: "debit-1"})
+	_, overdraftErr := useCase.DebitWallet(context.Background(), usecase.WalletRequest{UserID: "user123", Amount: "30.01", Currency: "USD", IdempotencyKey: "debit-2"})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, entity.WalletDebit, debit.Type)
+	assert.Equal(t, "30.00", debit.Balance)
+	assert.ErrorIs(t, overdraftErr, usecase.ErrInsufficientFunds)
+	assert.Equal(t, "30.00", walletBalance(t, useCase, "user123"))
+	assertBalance(t, l, ledger.AccountWalletFunds, usd(3000))
+	assertBalanced(t, l)
+}
+
+func TestPaymentUseCase_WalletRequest_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      usecase.WalletRequest
+		expected error
+	}{
+		{"missing user", usecase.WalletRequest{Amount: "1.00", Currency: "USD", IdempotencyKey: "k"}, usecase.ErrInvalidUserID},
+		{"missing key", usecase.WalletRequest{UserID: "user123", Amount: "1.00", Currency: "USD"}, usecase.ErrMissingWalletKey},
+		{"unknown currency", usecase.WalletRequest{UserID: "user123", Amount: "1.00", Currency: "XXX", IdempotencyKey: "k"}, usecase.ErrInvalidCurrency},
+		{"zero amount", usecase.WalletRequest{UserID: "user123", Amount: "0", Currency: "USD", IdempotencyKey: "k"}, usecase.ErrInvalidAmount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			useCase, _, _ := newWalletUseCase(t, "1.00")
+
+			// Act
+			_, err := useCase.TopUpWallet(context.Background(), tt.req)
+
+			// Assert
+			assert.ErrorIs(t, err, tt.expected)
+		})
+	}
+}
+
+func TestPaymentUseCase_Wallet_Disabled(t *testing.T) {
+	// Arrange
+	useCase := usecase.NewPaymentUseCase(repository.NewInMemoryPaymentRepository())
+
+	// Act
+	_, walletErr := useCase.GetWallet(context.Background(), "user123")
+	_, paymentErr := useCase.ProcessPayment(context.Background(), usecase.PaymentRequest{UserID: "user123", Amount: "1.00", Currency: "USD", TransactionID: "txn1", PaymentMethod: "wallet"})
+
+	// Assert
+	assert.ErrorIs(t, walletErr, usecase.ErrWalletsDisabled)
+	assert.ErrorIs(t, paymentErr, usecase.ErrWalletsDisabled)
+}
+
+func TestPaymentUseCase_ProcessPayment_Wallet(t *testing.T) {
+	// Arrange
+	useCase, repo, l := newWalletUseCase(t, "100.00")
+	req := usecase.PaymentRequest{UserID: "user123", Amount: "60.00", Currency: "USD", TransactionID: "txn-wallet", PaymentMethod: "wallet"}
+
+	// Act
+	response, err := useCase.ProcessPayment(context.Background(), req)
+	_, retryErr := useCase.ProcessPayment(context.Background(), req)
+
+	// Assert: the retry neither debits nor posts again
+	require.NoError(t, err)
+	require.NoError(t, retryErr)
+	assert.Equal(t, entity.StatusCaptured, response.Status)
+	assert.Equal(t, entity.PaymentMethodWallet, response.PaymentMethod)
+	assert.Equal(t, "40.00", walletBalance(t, useCase, "user123"))
+	stored, err := repo.GetByTransactionID(context.Background(), "txn-wallet")
+	require.NoError(t, err)
+	assert.Equal(t, entity.PaymentMethodWallet, stored.PaymentMethod)
+	assertBalance(t, l, ledger.AccountWalletFunds, usd(4000))
+	assertBalance(t, l, ledger.AccountMerchantPayable, usd(6000))
+	assertBalance(t, l, ledger.AccountCash, usd(10000))
+	assertBalanced(t, l)
+}
+
+func TestPaymentUseCase_ProcessPayment_WalletInsufficientFunds(t *testing.T) {
+	// Arrange
+	useCase, repo, _ := newWalletUseCase(t, "10.00")
+	req := usecase.PaymentRequest{UserID: "user123", Amount: "10.01", Currency: "USD", TransactionID: "txn-short", PaymentMethod: "wallet"}
+
+	// Act
+	response, err := useCase.ProcessPayment(context.Background(), req)
+
+	// Assert
+	assert.ErrorIs(t, err, usecase.ErrInsufficientFunds)
+	assert.Equal(t, entity.StatusFailed, response.Status)
+	assert.False(t, repo.Exists(context.Background(), "txn-short"), "a declined payment is not stored, so it can be retried after a top-up")
+	assert.Equal(t, "10.00", walletBalance(t, useCase, "user123"))
+
+	// Act: retry after topping up
+	_, err = useCase.TopUpWallet(context.Background(), usecase.WalletRequest{UserID: "user123", Amount: "5.00", Currency: "USD", IdempotencyKey: "more"})
+	require.NoError(t, err)
+	_, err = useCase.ProcessPayment(context.Background(), req)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "4.99", walletBalance(t, useCase, "user123"))
+}
+
+func TestPaymentUseCase_ProcessPayment_WalletTransactionIDOfAnotherUser(t *testing.T) {
+	// Arrange
+	useCase, _, _ := newWalletUseCase(t, "10.00")
+	_, err := useCase.TopUpWallet(context.Background(), usecase.WalletRequest{UserID: "user456", Amount: "10.00", Currency: "USD", IdempotencyKey: "initial"})
+	require.NoError(t, err)
+	_, err = useCase.ProcessPayment(context.Background(), usecase.PaymentRequest{UserID: "user123", Amount: "5.00", Currency: "USD", TransactionID: "txn-shared", PaymentMethod: "wallet"})
+	require.NoError(t, err)
+
+	// Act
+	_, err = useCase.ProcessPayment(context.Background(), usecase.PaymentRequest{UserID: "user456", Amount: "5.00", Currency: "USD", TransactionID: "txn-shared", PaymentMethod: "wallet"})
+
+	// Assert
+	assert.ErrorIs(t, err, usecase.ErrIdempotencyConflict)
+	assert.Equal(t, "10.00", walletBalance(t, useCase, "user456"))
+}
+
+func TestPaymentUseCase_ProcessPayment_WalletManualCaptureRejected(t *testing.T) {
+	// Arrange
+	useCase, _, _ := newWalletUseCase(t, "10.00")
+
+	// Act
+	_, err := useCase.ProcessPayment(context.Background(), usecase.PaymentRequest{UserID: "user123", Amount: "5.00", Currency: "USD", TransactionID: "txn1", PaymentMethod: "wallet", CaptureMethod: "manual"})
+
+	// Assert
+	assert.ErrorIs(t, err, usecase.ErrInvalidPaymentMethod)
+}
+
+func TestPaymentUseCase_RefundPayment_ReturnsFundsToWallet(t *testing.T) {
+	// Arrange
+	useCase, _, l := newWalletUseCase(t, "100.00")
+	_, err := useCase.ProcessPayment(context.Background(), usecase.PaymentRequest{UserID: "user123", Amount: "60.00", Currency: "USD", TransactionID: "txn-wallet", PaymentMethod: "wallet"})
+	require.NoError(t, err)
+	refund := usecase.RefundRequest{TransactionID: "txn-wallet", Amount: "15.00", Reason: "requested_by_customer", IdempotencyKey: "refund-1"}
+
+	// Act
+	_, err = useCase.RefundPayment(context.Background(), refund)
+	require.NoError(t, err)
+	_, err = useCase.RefundPayment(context.Background(), refund)
+	require.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, "55.00", walletBalance(t, useCase, "user123"))
+	assertBalance(t, l, ledger.AccountWalletFunds, usd(5500))
+	assertBalance(t, l, ledger.AccountMerchantPayable, usd(4500))
+	assertBalanced(t, l)
+}
@@ -0,0 +1,445 @@
+// Package webhook delivers payment events to the HTTP endpoints merchants register.
+// Deliveries are signed, retried with exponential backoff and dead-lettered once their
+// attempts run out; a dead-lettered delivery can be replayed.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"payment-service/internal/entity"
+	"payment-service/internal/usecase"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Delivery statuses
+const (
+	DeliveryPending   = "pending"   // Waiting for its next attempt
+	DeliveryDelivered = "delivered" // The endpoint answered with a 2xx status
+	DeliveryDead      = "dead"      // Every attempt failed; only a replay sends it again
+)
+
+// Default settings
+const (
+	DefaultMaxAttempts  = 8
+	DefaultBaseDelay    = 30 * time.Second
+	DefaultMaxDelay     = time.Hour
+	DefaultPollInterval = time.Second
+	// DefaultRequestTimeout bounds every delivery request
+	DefaultRequestTimeout = 10 * time.Second
+	// DefaultLeaseTimeout is how long a delivery stays hidden from other dispatchers while it is attempted
+	DefaultLeaseTimeout = time.Minute
+	// DefaultConcurrency is how many deliveries a dispatcher attempts at once
+	DefaultConcurrency = 8
+)
+
+var (
+	ErrInvalidURL          = errors.New("url must be an absolute http or https URL")
+	ErrInvalidEventType    = errors.New("event_types must be payment status event types such as payment.captured")
+	ErrInvalidMerchant     = errors.New("merchant ID cannot be empty")
+	ErrDeliveryNotFound    = errors.New("webhook delivery not found")
+	ErrDeliveryInFlight    = errors.New("webhook delivery is being attempted; try again shortly")
+	ErrInvalidStatusFilter = errors.New("status must be pending, delivered or dead")
+)
+
+// Endpoint is an HTTP endpoint a merchant registered for the events of its payments
+type Endpoint struct {
+	ID         string    `json:"id" example:"we_3f9a2c71d04b8e56"`
+	MerchantID string    `json:"merchant_id" example:"merchant-1"`
+	URL        string    `json:"url" example:"https://merchant.example.com/webhooks"`
+	Secret     string    `json:"secret,omitempty" example:"whsec_5f2b..."`         // Signing secret; only returned when the endpoint is registered
+	EventTypes []string  `json:"event_types,omitempty" example:"payment.captured"` // Event types sent to the endpoint; all when empty
+	CreatedAt  time.Time `json:"created_at" example:"2025-01-01T10:00:00Z"`
+}
+
+// receives reports whether the endpoint subscribes to an event type
+func (e *Endpoint) receives(eventType string) bool {
+	if len(e.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range e.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivery is the delivery of one event to one endpoint
+type Delivery struct {
+	ID             string               `json:"id" example:"wd_8c41d07e2f9b3a65"`
+	EndpointID     string               `json:"endpoint_id" example:"we_3f9a2c71d04b8e56"`
+	MerchantID     string               `json:"merchant_id" example:"merchant-1"`
+	Event          usecase.PaymentEvent `json:"event"`
+	Status         string               `json:"status" example:"pending" enums:"pending,delivered,dead"`
+	Attempts       int                  `json:"attempts" example:"1"`                                     // Attempts since the delivery was created or last replayed
+	NextAttemptAt  *time.Time           `json:"next_attempt_at,omitempty" example:"2025-01-01T10:00:30Z"` // When a pending delivery is attempted next
+	LastAttemptAt  *time.Time           `json:"last_attempt_at,omitempty" example:"2025-01-01T10:00:00Z"`
+	LastStatusCode int                  `json:"last_status_code,omitempty" example:"503"` // HTTP status of the last answer
+	LastError      string               `json:"last_error,omitempty" example:"endpoint answered 503"`
+	DeliveredAt    *time.Time           `json:"delivered_at,omitempty" example:"2025-01-01T10:00:31Z"`
+	CreatedAt      time.Time            `json:"created_at" example:"2025-01-01T10:00:00Z"`
+	LeasedUntil    *time.Time           `json:"-"` // End of the lease of the dispatcher attempting it
+	Leases         int                  `json:"-"` // Leases and replays so far; identifies the current lease
+}
+
+// RetryPolicy decides how often and when failed deliveries are attempted again
+type RetryPolicy struct {
+	MaxAttempts int           // Attempts before a delivery is dead-lettered
+	BaseDelay   time.Duration // Wait after the first failed attempt; it doubles after every further failure
+	MaxDelay    time.Duration // Longest wait between two attempts
+}
+
+// backoff returns the wait after the given number of failed attempts
+func (p RetryPolicy) backoff(failures int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxDelay)
+}
+
+// Option configures a Dispatcher
+type Option func(*Dispatcher)
+
+// WithRetryPolicy sets how failed deliveries are retried
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(d *Dispatcher) {
+		d.policy = policy
+	}
+}
+
+// WithHTTPClient sets the client that sends deliveries
+func WithHTTPClient(client *http.Client) Option {
+	return func(d *Dispatcher) {
+		d.client = client
+	}
+}
+
+// WithConcurrency sets how many deliveries the dispatcher attempts at once
+func WithConcurrency(n int) Option {
+	return func(d *Dispatcher) {
+		d.concurrency = n
+	}
+}
+
+// Dispatcher keeps merchants' webhook endpoints and delivers payment events to them.
+// It implements usecase.EventPublisher; Run delivers the published events in the background.
+// Endpoints and deliveries are kept in a Store, so they survive restarts and every dispatcher
+// sharing the store delivers from the same queue. Deliveries of one payment's events may arrive
+// out of order, or more than once, so receivers should order events by created_at and drop
+// repeated event IDs.
+type Dispatcher struct {
+	store        Store
+	policy       RetryPolicy
+	client       *http.Client
+	leaseTimeout time.Duration
+	concurrency  int
+	now          func() time.Time
+	wake         chan struct{}
+}
+
+// NewDispatcher creates a dispatcher that keeps endpoints and deliveries in store
+func NewDispatcher(store Store, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		store:        store,
+		policy:       RetryPolicy{MaxAttempts: DefaultMaxAttempts, BaseDelay: DefaultBaseDelay, MaxDelay: DefaultMaxDelay},
+		client:       &http.Client{Timeout: DefaultRequestTimeout},
+		leaseTimeout: DefaultLeaseTimeout,
+		concurrency:  DefaultConcurrency,
+		now:          time.Now,
+		wake:         make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// RegisterEndpoint registers an endpoint for the events of a merchant's payments and
+// generates its signing secret. Without event types, the endpoint receives every event.
+func (d *Dispatcher) RegisterEndpoint(ctx context.Context, merchantID, endpointURL string, eventTypes []string) (*Endpoint, error) {
+	if merchantID == "" {
+		return nil, ErrInvalidMerchant
+	}
+	parsed, err := url.Parse(endpointURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, ErrInvalidURL
+	}
+	for _, eventType := range eventTypes {
+		if status, ok := strings.CutPrefix(eventType, usecase.EventTypePrefix); !ok || !entity.IsStatus(status) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidEventType, eventType)
+		}
+	}
+	secret, err := randomHex(24)
+	if err != nil {
+		return nil, err
+	}
+	id, err := randomHex(8)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := &Endpoint{
+		ID:         "we_" + id,
+		MerchantID: merchantID,
+		URL:        endpointURL,
+		Secret:     "whsec_" + secret,
+		EventTypes: eventTypes,
+		CreatedAt:  d.now(),
+	}
+	if err := d.store.SaveWebhookEndpoint(ctx, endpoint); err != nil {
+		return nil, err
+	}
+	return endpoint, nil
+}
+
+// Endpoints lists a merchant's endpoints, without their secrets
+func (d *Dispatcher) Endpoints(ctx context.Context, merchantID string) ([]Endpoint, error) {
+	stored, err := d.store.WebhookEndpoints(ctx, merchantID)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := []Endpoint{}
+	for _, endpoint := range stored {
+		listed := *endpoint
+		listed.Secret = ""
+		endpoints = append(endpoints, listed)
+	}
+	return endpoints, nil
+}
+
+// Publish creates a delivery of event to every endpoint of the payment's merchant that
+// subscribes to its type. Publishing an event again creates no further deliveries.
+func (d *Dispatcher) Publish(ctx context.Context, event usecase.PaymentEvent) error {
+	if event.Data.MerchantID == "" {
+		return nil
+	}
+	endpoints, err := d.store.WebhookEndpoints(ctx, event.Data.MerchantID)
+	if err != nil {
+		return fmt.Errorf("publishing event %s: %w", event.ID, err)
+	}
+
+	now := d.now()
+	var deliveries []*Delivery
+	for _, endpoint := range endpoints {
+		if !endpoint.receives(event.Type) {
+			continue
+		}
+		deliveries = append(deliveries, &Delivery{
+			ID:            deliveryID(event.ID, endpoint.ID),
+			EndpointID:    endpoint.ID,
+			MerchantID:    endpoint.MerchantID,
+			Event:         event,
+			Status:        DeliveryPending,
+			NextAttemptAt: &now,
+			CreatedAt:     now,
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	if err := d.store.AddWebhookDeliveries(ctx, deliveries); err != nil {
+		return fmt.Errorf("publishing event %s: %w", event.ID, err)
+	}
+	d.signal()
+	return nil
+}
+
+// Deliveries lists a merchant's deliveries, newest first, optionally only those with a status
+func (d *Dispatcher) Deliveries(ctx context.Context, merchantID, status string) ([]Delivery, error) {
+	if status != "" && status != DeliveryPending && status != DeliveryDelivered && status != DeliveryDead {
+		return nil, ErrInvalidStatusFilter
+	}
+	stored, err := d.store.WebhookDeliveries(ctx, merchantID, status)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := []Delivery{}
+	for _, delivery := range stored {
+		deliveries = append(deliveries, *delivery)
+	}
+	return deliveries, nil
+}
+
+// Replay sends a delivery again straight away, restarting its attempts. It is meant for
+// dead-lettered deliveries once the endpoint is fixed, but any delivery can be replayed.
+func (d *Dispatcher) Replay(ctx context.Context, merchantID, deliveryID string) (*Delivery, error) {
+	delivery, err := d.store.ReplayWebhookDelivery(ctx, merchantID, deliveryID, d.now())
+	if err != nil {
+		return nil, err
+	}
+	d.signal()
+	return delivery, nil
+}
+
+// Run delivers due deliveries until ctx is done, checking every pollInterval and whenever
+// an event is published or a delivery replayed
+func (d *Dispatcher) Run(ctx context.Context, pollInterval time.Duration) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		d.DeliverDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// DeliverDue attempts every pending delivery whose next attempt is due, and returns once none is
+// left. A fixed set of workers, as many as the dispatcher's concurrency, each lease one delivery
+// at a time just before attempting it, so a backlog never sends more requests at once, and no
+// delivery waits behind slow endpoints until its lease runs out.
+func (d *Dispatcher) DeliverDue(ctx context.Context) {
+	var wg sync.WaitGroup
+	for range d.concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for d.deliverNext(ctx) {
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// deliverNext leases the delivery due longest and attempts it. It reports false when
+// no delivery is due, or ctx is done.
+func (d *Dispatcher) deliverNext(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	now := d.now()
+	due, err := d.store.LeaseWebhookDeliveries(ctx, now, now.Add(d.leaseTimeout), 1)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("webhooks: leasing deliveries: %v", err)
+		}
+		return false
+	}
+	if len(due) == 0 {
+		return false
+	}
+	statusCode, err := d.send(ctx, due[0])
+	d.record(ctx, due[0], statusCode, err)
+	return true
+}
+
+// send posts the event of a delivery to its endpoint and returns the status of the answer
+func (d *Dispatcher) send(ctx context.Context, delivery *Delivery) (int, error) {
+	endpoint, err := d.endpoint(ctx, delivery)
+	if err != nil {
+		return 0, err
+	}
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventIDHeader, delivery.Event.ID)
+	req.Header.Set(SignatureHeader, Sign(endpoint.Secret, d.now(), body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint answered %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// record stores the outcome of an attempt, scheduling a retry or dead-lettering the delivery
+// when the attempt failed. Attempts cut short because ctx is done, such as on shutdown, do
+// not count: the delivery is handed back, due straight away.
+func (d *Dispatcher) record(ctx context.Context, delivery *Delivery, statusCode int, err error) {
+	now := d.now()
+	delivery.LeasedUntil = nil
+	if err != nil && ctx.Err() != nil {
+		delivery.NextAttemptAt = &now
+	} else {
+		delivery.Attempts++
+		delivery.LastAttemptAt = &now
+		delivery.LastStatusCode = statusCode
+		delivery.LastError = ""
+		delivery.NextAttemptAt = nil
+
+		switch {
+		case err == nil:
+			delivery.Status = DeliveryDelivered
+			delivery.DeliveredAt = &now
+		case delivery.Attempts >= d.policy.MaxAttempts:
+			delivery.Status = DeliveryDead
+			delivery.LastError = err.Error()
+		default:
+			delivery.LastError = err.Error()
+			next := now.Add(d.policy.backoff(delivery.Attempts))
+			delivery.NextAttemptAt = &next
+		}
+	}
+
+	// A lost lease means the delivery was replayed, or its lease ran out and another attempt
+	// started; the outcome of that one counts instead
+	err = d.store.SaveWebhookAttempt(context.WithoutCancel(ctx), delivery)
+	if err != nil && !errors.Is(err, ErrDeliveryLeaseLost) {
+		log.Printf("webhooks: recording an attempt of delivery %s: %v", delivery.ID, err)
+	}
+}
+
+// endpoint returns the endpoint a delivery is sent to
+func (d *Dispatcher) endpoint(ctx context.Context, delivery *Delivery) (*Endpoint, error) {
+	endpoints, err := d.store.WebhookEndpoints(ctx, delivery.MerchantID)
+	if err != nil {
+		return nil, err
+	}
+	for _, endpoint := range endpoints {
+		if endpoint.ID == delivery.EndpointID {
+			return endpoint, nil
+		}
+	}
+	return nil, fmt.Errorf("webhook endpoint %s not found", delivery.EndpointID)
+}
+
+// signal wakes Run without blocking
+func (d *Dispatcher) signal() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// randomHex returns n random bytes in hex
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// deliveryID derives the ID of the delivery of an event to an endpoint, so publishing
+// the event again yields the same delivery
+func deliveryID(eventID, endpointID string) string {
+	sum := sha256.Sum256([]byte(eventID + "\x00" + endpointID))
+	return "wd_" + hex.EncodeToString(sum[:8])
+}
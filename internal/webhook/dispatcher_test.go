@@ -0,0 +1,411 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"payment-service/internal/usecase"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receiver is a merchant's webhook endpoint that answers with the queued statuses, then 200
+type receiver struct {
+	*httptest.Server
+	mutex    sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	rcv := &receiver{statuses: statuses}
+	rcv.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rcv.mutex.Lock()
+		defer rcv.mutex.Unlock()
+		rcv.requests = append(rcv.requests, r)
+		rcv.bodies = append(rcv.bodies, body)
+		status := http.StatusOK
+		if len(rcv.statuses) > 0 {
+			status, rcv.statuses = rcv.statuses[0], rcv.statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(rcv.Close)
+	return rcv
+}
+
+func (r *receiver) received() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return len(r.requests)
+}
+
+// memoryStore keeps endpoints and deliveries in memory; the repositories run the full Store contract
+type memoryStore struct {
+	mutex      sync.Mutex
+	endpoints  []*Endpoint
+	deliveries []*Delivery
+}
+
+func (s *memoryStore) SaveWebhookEndpoint(_ context.Context, endpoint *Endpoint) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.endpoints = append(s.endpoints, endpoint.Clone())
+	return nil
+}
+
+func (s *memoryStore) WebhookEndpoints(_ context.Context, merchantID string) ([]*Endpoint, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	endpoints := []*Endpoint{}
+	for _, endpoint := range s.endpoints {
+		if endpoint.MerchantID == merchantID {
+			endpoints = append(endpoints, endpoint.Clone())
+		}
+	}
+	return endpoints, nil
+}
+
+func (s *memoryStore) AddWebhookDeliveries(_ context.Context, deliveries []*Delivery) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, delivery := range deliveries {
+		if s.find(delivery.ID) == nil {
+			s.deliveries = append(s.deliveries, delivery.Clone())
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) WebhookDeliveries(_ context.Context, merchantID, status string) ([]*Delivery, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	deliveries := []*Delivery{}
+	for i := len(s.deliveries) - 1; i >= 0; i-- {
+		if delivery := s.deliveries[i]; delivery.MerchantID == merchantID && (status == "" || delivery.Status == status) {
+			deliveries = append(deliveries, delivery.Clone())
+		}
+	}
+	return deliveries, nil
+}
+
+func (s *memoryStore) LeaseWebhookDeliveries(_ context.Context, now, leaseUntil time.Time, limit int) ([]*Delivery, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	leased := []*Delivery{}
+	for _, delivery := range s.deliveries {
+		if delivery.Due(now) && len(leased) < limit {
+			delivery.Lease(leaseUntil)
+			leased = append(leased, delivery.Clone())
+		}
+	}
+	return leased, nil
+}
+
+func (s *memoryStore) SaveWebhookAttempt(_ context.Context, delivery *Delivery) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	stored := s.find(delivery.ID)
+	if stored == nil || stored.LeasedUntil == nil || stored.Leases != delivery.Leases {
+		return ErrDeliveryLeaseLost
+	}
+	*stored = *delivery
+	stored.LeasedUntil = nil
+	return nil
+}
+
+func (s *memoryStore) ReplayWebhookDelivery(_ context.Context, merchantID, deliveryID string, now time.Time) (*Delivery, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delivery := s.find(deliveryID)
+	if delivery == nil || delivery.MerchantID != merchantID {
+		return nil, ErrDeliveryNotFound
+	}
+	if delivery.Leased(now) {
+		return nil, ErrDeliveryInFlight
+	}
+	delivery.Replay(now)
+	return delivery.Clone(), nil
+}
+
+// find returns a stored delivery by ID. The caller holds the mutex.
+func (s *memoryStore) find(deliveryID string) *Delivery {
+	for _, delivery := range s.deliveries {
+		if delivery.ID == deliveryID {
+			return delivery
+		}
+	}
+	return nil
+}
+
+// newTestDispatcher returns a dispatcher with a clock the test controls, retrying after 1s, 2s, 4s... up to 3 attempts
+func newTestDispatcher() (*Dispatcher, *time.Time) {
+	d := NewDispatcher(&memoryStore{}, WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Second, MaxDelay: time.Minute}))
+	now := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	d.now = func() time.Time { return now }
+	return d, &now
+}
+
+func capturedEvent(merchantID string) usecase.PaymentEvent {
+	return usecase.PaymentEvent{
+		ID:        "evt_txn123_2",
+		Type:      "payment.captured",
+		CreatedAt: time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC),
+		Data: usecase.PaymentEventData{
+			TransactionID:  "txn123",
+			UserID:         "user123",
+			MerchantID:     merchantID,
+			Amount:         "100.00",
+			Currency:       "USD",
+			Status:         "captured",
+			PreviousStatus: "authorized",
+			Actor:          "system",
+			Reason:         "payment captured",
+		},
+	}
+}
+
+func TestDispatcher_DeliversSignedEvents(t *testing.T) {
+	// Arrange
+	rcv := newReceiver(t)
+	d, now := newTestDispatcher()
+	endpoint, err := d.RegisterEndpoint(context.Background(), "merchant-1", rcv.URL, nil)
+	require.NoError(t, err)
+	_, err = d.RegisterEndpoint(context.Background(), "merchant-2", rcv.URL, nil)
+	require.NoError(t, err)
+
+	// Act
+	d.Publish(context.Background(), capturedEvent("merchant-1"))
+	d.Publish(context.Background(), capturedEvent("merchant-1"))
+	d.DeliverDue(context.Background())
+
+	// Assert
+	require.Equal(t, 1, rcv.received(), "only the merchant's endpoint gets the event, once")
+	req := rcv.requests[0]
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+	assert.Equal(t, "evt_txn123_2", req.Header.Get(EventIDHeader))
+	assert.NoError(t, Verify(endpoint.Secret, req.Header.Get(SignatureHeader), rcv.bodies[0], *now, DefaultTolerance))
+	var event usecase.PaymentEvent
+	require.NoError(t, json.Unmarshal(rcv.bodies[0], &event))
+	assert.Equal(t, capturedEvent("merchant-1"), event)
+
+	deliveries, err := d.Deliveries(context.Background(), "merchant-1", "")
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, DeliveryDelivered, deliveries[0].Status)
+	assert.Equal(t, 1, deliveries[0].Attempts)
+	assert.Equal(t, http.StatusOK, deliveries[0].LastStatusCode)
+	assert.NotNil(t, deliveries[0].DeliveredAt)
+}
+
+func TestDispatcher_RetriesWithBackoffThenDeadLetters(t *testing.T) {
+	// Arrange
+	rcv := newReceiver(t, http.StatusInternalServerError, http.StatusServiceUnavailable, http.StatusBadGateway)
+	d, now := newTestDispatcher()
+	_, err := d.RegisterEndpoint(context.Background(), "merchant-1", rcv.URL, nil)
+	require.NoError(t, err)
+	d.Publish(context.Background(), capturedEvent("merchant-1"))
+
+	// Act & Assert: the first retry waits 1s
+	d.DeliverDue(context.Background())
+	deliveries, _ := d.Deliveries(context.Background(), "merchant-1", DeliveryPending)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, now.Add(time.Second), *deliveries[0].NextAttemptAt)
+	assert.Equal(t, "endpoint answered 500", deliveries[0].LastError)
+
+	d.DeliverDue(context.Background())
+	assert.Equal(t, 1, rcv.received(), "no attempt before the backoff has passed")
+
+	// Act & Assert: the second retry waits 2s
+	*now = now.Add(time.Second)
+	d.DeliverDue(context.Background())
+	deliveries, _ = d.Deliveries(context.Background(), "merchant-1", DeliveryPending)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, now.Add(2*time.Second), *deliveries[0].NextAttemptAt)
+
+	// Act & Assert: the third failure dead-letters the delivery
+	*now = now.Add(2 * time.Second)
+	d.DeliverDue(context.Background())
+	dead, _ := d.Deliveries(context.Background(), "merchant-1", DeliveryDead)
+	require.Len(t, dead, 1)
+	assert.Equal(t, 3, dead[0].Attempts)
+	assert.Equal(t, http.StatusBadGateway, dead[0].LastStatusCode)
+	assert.Nil(t, dead[0].NextAttemptAt)
+
+	*now = now.Add(time.Hour)
+	d.DeliverDue(context.Background())
+	assert.Equal(t, 3, rcv.received(), "dead deliveries are not retried")
+}
+
+func TestDispatcher_ReplayDeadDelivery(t *testing.T) {
+	// Arrange
+	rcv := newReceiver(t, http.StatusGone)
+	d, _ := newTestDispatcher()
+	d.policy.MaxAttempts = 1
+	_, err := d.RegisterEndpoint(context.Background(), "merchant-1", rcv.URL, nil)
+	require.NoError(t, err)
+	d.Publish(context.Background(), capturedEvent("merchant-1"))
+	d.DeliverDue(context.Background())
+	dead, _ := d.Deliveries(context.Background(), "merchant-1", DeliveryDead)
+	require.Len(t, dead, 1)
+
+	// Act
+	_, otherMerchantErr := d.Replay(context.Background(), "merchant-2", dead[0].ID)
+	replayed, err := d.Replay(context.Background(), "merchant-1", dead[0].ID)
+	require.NoError(t, err)
+	d.DeliverDue(context.Background())
+
+	// Assert
+	assert.ErrorIs(t, otherMerchantErr, ErrDeliveryNotFound)
+	assert.Equal(t, DeliveryPending, replayed.Status)
+	assert.Zero(t, replayed.Attempts)
+	assert.Equal(t, 2, rcv.received())
+	delivered, _ := d.Deliveries(context.Background(), "merchant-1", DeliveryDelivered)
+	require.Len(t, delivered, 1)
+	assert.Equal(t, dead[0].ID, delivered[0].ID)
+}
+
+func TestDispatcher_RegisterEndpointValidatesAndFilters(t *testing.T) {
+	// Arrange
+	rcv := newReceiver(t)
+	d, _ := newTestDispatcher()
+
+	// Act
+	_, invalidURL := d.RegisterEndpoint(context.Background(), "merchant-1", "ftp://merchant.example.com", nil)
+	_, invalidType := d.RegisterEndpoint(context.Background(), "merchant-1", rcv.URL, []string{"payment.shipped"})
+	refunds, err := d.RegisterEndpoint(context.Background(), "merchant-1", rcv.URL, []string{"payment.refunded"})
+	require.NoError(t, err)
+	d.Publish(context.Background(), capturedEvent("merchant-1"))
+	d.DeliverDue(context.Background())
+
+	// Assert
+	assert.ErrorIs(t, invalidURL, ErrInvalidURL)
+	assert.ErrorIs(t, invalidType, ErrInvalidEventType)
+	assert.NotEmpty(t, refunds.Secret)
+	assert.Zero(t, rcv.received(), "the endpoint only subscribes to refunds")
+	endpoints, err := d.Endpoints(context.Background(), "merchant-1")
+	require.NoError(t, err)
+	require.Len(t, endpoints, 1)
+	assert.Empty(t, endpoints[0].Secret, "secrets are only shown on registration")
+}
+
+func TestDispatcher_RunDeliversPublishedEvents(t *testing.T) {
+	// Arrange
+	rcv := newReceiver(t)
+	d := NewDispatcher(&memoryStore{})
+	_, err := d.RegisterEndpoint(context.Background(), "merchant-1", rcv.URL, nil)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.Run(ctx, time.Hour)
+		close(done)
+	}()
+
+	// Act
+	d.Publish(context.Background(), capturedEvent("merchant-1"))
+
+	// Assert
+	assert.Eventually(t, func() bool { return rcv.received() == 1 }, time.Second, 5*time.Millisecond)
+	cancel()
+	<-done
+}
+
+func TestDispatcher_DispatchersShareTheStore(t *testing.T) {
+	// Arrange: one dispatcher only publishes, as in the worker; the other delivers, as in the server
+	rcv := newReceiver(t)
+	store := &memoryStore{}
+	publisher := NewDispatcher(store)
+	sender := NewDispatcher(store)
+	_, err := publisher.RegisterEndpoint(context.Background(), "merchant-1", rcv.URL, nil)
+	require.NoError(t, err)
+
+	// Act
+	publisher.Publish(context.Background(), capturedEvent("merchant-1"))
+	sender.DeliverDue(context.Background())
+
+	// Assert
+	assert.Equal(t, 1, rcv.received())
+	delivered, err := publisher.Deliveries(context.Background(), "merchant-1", DeliveryDelivered)
+	require.NoError(t, err)
+	assert.Len(t, delivered, 1)
+}
+
+func TestDispatcher_ShutdownHandsDeliveriesBack(t *testing.T) {
+	// Arrange: the dispatcher is stopped while the endpoint is answering
+	ctx, cancel := context.WithCancel(context.Background())
+	answered := make(chan struct{})
+	rcv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-answered:
+		default:
+			cancel()
+			<-answered
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(rcv.Close)
+	d, _ := newTestDispatcher()
+	_, err := d.RegisterEndpoint(context.Background(), "merchant-1", rcv.URL, nil)
+	require.NoError(t, err)
+	d.Publish(context.Background(), capturedEvent("merchant-1"))
+
+	// Act
+	d.DeliverDue(ctx)
+	pending, err := d.Deliveries(context.Background(), "merchant-1", DeliveryPending)
+	close(answered)
+	d.DeliverDue(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Zero(t, pending[0].Attempts, "an attempt cut short by shutdown does not count")
+	assert.False(t, pending[0].Leased(time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)), "the delivery is handed back straight away")
+	delivered, err := d.Deliveries(context.Background(), "merchant-1", DeliveryDelivered)
+	require.NoError(t, err)
+	assert.Len(t, delivered, 1)
+}
+
+func TestDispatcher_BacklogIsSentByAFixedSetOfWorkers(t *testing.T) {
+	// Arrange: a backlog of ten deliveries to an endpoint that answers slowly
+	var mutex sync.Mutex
+	var inFlight, mostInFlight, received int
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		inFlight++
+		mostInFlight = max(mostInFlight, inFlight)
+		mutex.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mutex.Lock()
+		inFlight--
+		received++
+		mutex.Unlock()
+	}))
+	t.Cleanup(endpoint.Close)
+	d := NewDispatcher(&memoryStore{}, WithConcurrency(3))
+	_, err := d.RegisterEndpoint(context.Background(), "merchant-1", endpoint.URL, nil)
+	require.NoError(t, err)
+	for i := range 10 {
+		event := capturedEvent("merchant-1")
+		event.ID = fmt.Sprintf("evt_txn%d_2", i)
+		d.Publish(context.Background(), event)
+	}
+
+	// Act
+	d.DeliverDue(context.Background())
+
+	// Assert
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.Equal(t, 10, received, "every due delivery is sent")
+	assert.LessOrEqual(t, mostInFlight, 3, "no more requests at once than the dispatcher's concurrency")
+}
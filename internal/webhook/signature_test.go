@@ -0,0 +1,45 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	// Arrange
+	body := []byte(`{"id":"evt_txn123_0"}`)
+	sentAt := time.Unix(1700000000, 0)
+	header := Sign("whsec_test", sentAt, body)
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		body   []byte
+		now    time.Time
+		err    error
+	}{
+		{"valid", "whsec_test", header, body, sentAt.Add(time.Minute), nil},
+		{"rotated secret", "whsec_test", Sign("whsec_old", sentAt, body) + ",v1=" + header[len("t=1700000000,v1="):], body, sentAt, nil},
+		{"wrong secret", "whsec_other", header, body, sentAt, ErrInvalidSignature},
+		{"tampered body", "whsec_test", header, []byte(`{"id":"evt_txn999_0"}`), sentAt, ErrInvalidSignature},
+		{"too old", "whsec_test", header, body, sentAt.Add(DefaultTolerance + time.Second), ErrSignatureExpired},
+		{"malformed", "whsec_test", "v1=abc", body, sentAt, ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			err := Verify(tt.secret, tt.header, tt.body, tt.now, DefaultTolerance)
+
+			// Assert
+			if tt.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.err)
+			}
+		})
+	}
+}
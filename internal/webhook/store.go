@@ -0,0 +1,74 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrDeliveryLeaseLost means a delivery was replayed or leased again while an attempt was made
+var ErrDeliveryLeaseLost = errors.New("webhook delivery lease lost")
+
+// Store keeps merchants' endpoints and the queue of deliveries, so they survive restarts and every
+// dispatcher sharing the store works from the same queue. Dispatchers lease the deliveries they attempt:
+// a leased delivery stays hidden until its lease runs out, and is attempted again if its dispatcher
+// has not recorded the outcome by then.
+type Store interface {
+	// SaveWebhookEndpoint stores a new endpoint
+	SaveWebhookEndpoint(ctx context.Context, endpoint *Endpoint) error
+	// WebhookEndpoints lists a merchant's endpoints with their secrets, oldest first
+	WebhookEndpoints(ctx context.Context, merchantID string) ([]*Endpoint, error)
+	// AddWebhookDeliveries stores new deliveries, skipping those whose ID is stored already
+	AddWebhookDeliveries(ctx context.Context, deliveries []*Delivery) error
+	// WebhookDeliveries lists a merchant's deliveries, newest first, only those with status unless it is empty
+	WebhookDeliveries(ctx context.Context, merchantID, status string) ([]*Delivery, error)
+	// LeaseWebhookDeliveries leases up to limit pending deliveries due at now, those due longest first,
+	// incrementing their Leases and hiding them until leaseUntil
+	LeaseWebhookDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*Delivery, error)
+	// SaveWebhookAttempt stores the outcome of an attempt and ends its lease. It fails with
+	// ErrDeliveryLeaseLost if the delivery was replayed or leased again since it was leased.
+	SaveWebhookAttempt(ctx context.Context, delivery *Delivery) error
+	// ReplayWebhookDelivery makes a merchant's delivery pending at now with no attempts. It fails with
+	// ErrDeliveryNotFound if the merchant has no such delivery, and ErrDeliveryInFlight while it is leased.
+	ReplayWebhookDelivery(ctx context.Context, merchantID, deliveryID string, now time.Time) (*Delivery, error)
+}
+
+// Clone returns a copy of the endpoint, so the copy can be modified independently
+func (e *Endpoint) Clone() *Endpoint {
+	clone := *e
+	clone.EventTypes = append([]string(nil), e.EventTypes...)
+	return &clone
+}
+
+// Clone returns a copy of the delivery, so the copy can be modified independently
+func (d *Delivery) Clone() *Delivery {
+	clone := *d
+	return &clone
+}
+
+// Leased reports whether the delivery is leased to a dispatcher at now
+func (d *Delivery) Leased(now time.Time) bool {
+	return d.LeasedUntil != nil && d.LeasedUntil.After(now)
+}
+
+// Due reports whether the delivery can be leased at now
+func (d *Delivery) Due(now time.Time) bool {
+	return d.Status == DeliveryPending && d.NextAttemptAt != nil && !d.NextAttemptAt.After(now)
+}
+
+// Replay makes the delivery pending at now with a fresh set of attempts, ending any lease
+func (d *Delivery) Replay(now time.Time) {
+	d.Status = DeliveryPending
+	d.Attempts = 0
+	d.NextAttemptAt = &now
+	d.DeliveredAt = nil
+	d.LeasedUntil = nil
+	d.Leases++
+}
+
+// Lease hides the delivery until leaseUntil
+func (d *Delivery) Lease(leaseUntil time.Time) {
+	d.NextAttemptAt = &leaseUntil
+	d.LeasedUntil = &leaseUntil
+	d.Leases++
+}
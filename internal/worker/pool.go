@@ -0,0 +1,312 @@
+// Package worker processes asynchronous payments in the background. A pool of workers leases
+// the jobs of the payments queued by POST /pay from a persistent job queue and executes them
+// through the payment use case, which writes their outcome back to the payment store.
+package worker
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand/v2"
+	"payment-service/internal/entity"
+	"payment-service/internal/usecase"
+	"sync"
+	"time"
+)
+
+// Defaults of a Pool
+const (
+	// DefaultPollInterval is how long an idle worker waits before looking for a job again
+	DefaultPollInterval = time.Second
+	// DefaultVisibilityTimeout is how long a leased job stays hidden from other workers;
+	// it must be longer than executing a payment takes
+	DefaultVisibilityTimeout = time.Minute
+	// DefaultSweepInterval is how often queued payments without a job get one
+	DefaultSweepInterval = time.Minute
+	// DefaultShutdownTimeout is how long a stopping pool waits for the tasks in progress;
+	// it must be shorter than the visibility timeout
+	DefaultShutdownTimeout = 30 * time.Second
+)
+
+// Task outcomes
+const (
+	OutcomeCompleted = "completed" // The payment was processed, captured or failed
+	OutcomeRetried   = "retried"   // The attempt failed and the job will be tried again
+	OutcomeDead      = "dead"      // The last attempt failed and the job was dead-lettered
+	OutcomeReleased  = "released"  // The pool stopped during the attempt and handed the job back to the queue
+)
+
+// Jobs finds and executes queued payments; usecase.PaymentUseCase implements it
+type Jobs interface {
+	QueuedPayments(ctx context.Context) ([]string, error)
+	ExecutePayment(ctx context.Context, transactionID string) (*usecase.PaymentResponse, error)
+}
+
+// Task is a leased job handed to a worker
+type Task struct {
+	TransactionID string `json:"transaction_id"`
+	Attempt       int    `json:"attempt"` // Attempts of the job so far, including this one
+}
+
+// Result is the outcome of a task
+type Result struct {
+	Worker        int
+	TransactionID string
+	Attempt       int
+	Status        string // Status of the payment after the task, empty if it could not be loaded
+	Outcome       string // OutcomeCompleted, OutcomeRetried, OutcomeDead or OutcomeReleased
+	Err           error
+	Duration      time.Duration
+}
+
+// Observer is told when a worker starts and finishes a task
+type Observer interface {
+	Started(worker int, task Task)
+	Finished(result Result)
+}
+
+// logObserver logs every result
+type logObserver struct{}
+
+func (logObserver) Started(int, Task) {}
+
+func (logObserver) Finished(result Result) {
+	if result.Err != nil {
+		log.Printf("worker %d: payment %s attempt %d %s after %s: %v", result.Worker, result.TransactionID, result.Attempt, result.Outcome, result.Duration, result.Err)
+		return
+	}
+	log.Printf("worker %d: payment %s %s after %s", result.Worker, result.TransactionID, result.Status, result.Duration)
+}
+
+// Pool runs a fixed number of workers over a job queue. Every worker leases its own jobs, so a job
+// is never held in memory only: one leased by a worker that crashed is delivered again once its
+// visibility timeout runs out, and payments are processed at least once.
+type Pool struct {
+	jobs              Jobs
+	queue             usecase.JobQueue
+	size              int
+	pollInterval      time.Duration
+	visibilityTimeout time.Duration
+	sweepInterval     time.Duration
+	shutdownTimeout   time.Duration
+	retryPolicy       RetryPolicy
+	observer          Observer
+	now               func() time.Time
+	random            func() float64
+
+	mutex     sync.Mutex
+	startedAt time.Time
+	states    []workerState // Indexed by worker ID - 1
+}
+
+// Option configures optional Pool behaviour
+type Option func(*Pool)
+
+// WithPollInterval sets how long an idle worker waits before looking for a job again
+func WithPollInterval(interval time.Duration) Option {
+	return func(p *Pool) {
+		p.pollInterval = interval
+	}
+}
+
+// WithVisibilityTimeout sets how long a leased job stays hidden before it is delivered again
+func WithVisibilityTimeout(timeout time.Duration) Option {
+	return func(p *Pool) {
+		p.visibilityTimeout = timeout
+	}
+}
+
+// WithSweepInterval sets how often queued payments without a job get one
+func WithSweepInterval(interval time.Duration) Option {
+	return func(p *Pool) {
+		p.sweepInterval = interval
+	}
+}
+
+// WithShutdownTimeout sets how long a stopping pool waits for the tasks in progress
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(p *Pool) {
+		p.shutdownTimeout = timeout
+	}
+}
+
+// WithRetryPolicy sets how failed jobs are retried
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(p *Pool) {
+		p.retryPolicy = policy
+	}
+}
+
+// WithObserver reports the tasks workers start and finish to observer instead of the log
+func WithObserver(observer Observer) Option {
+	return func(p *Pool) {
+		p.observer = observer
+	}
+}
+
+// NewPool creates a pool of size workers executing the jobs in queue; size must be positive
+func NewPool(jobs Jobs, queue usecase.JobQueue, size int, opts ...Option) *Pool {
+	p := &Pool{
+		jobs:              jobs,
+		queue:             queue,
+		size:              size,
+		pollInterval:      DefaultPollInterval,
+		visibilityTimeout: DefaultVisibilityTimeout,
+		sweepInterval:     DefaultSweepInterval,
+		shutdownTimeout:   DefaultShutdownTimeout,
+		retryPolicy:       DefaultRetryPolicy(),
+		observer:          logObserver{},
+		now:               time.Now,
+		random:            rand.Float64,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	p.states = make([]workerState, size)
+	return p
+}
+
+// Size returns the number of workers
+func (p *Pool) Size() int {
+	return p.size
+}
+
+// Run executes jobs until ctx is done. Every sweep interval, starting now, it also adds a job for
+// every queued payment that has none, such as one stored by a request that failed before queueing it.
+//
+// Once ctx is done the workers stop leasing jobs and Run waits up to the shutdown timeout for the tasks
+// in progress. Tasks still running after that are cancelled and their jobs handed back to the queue, to
+// be leased again straight away. Run returns once the outcome of every task is stored.
+func (p *Pool) Run(ctx context.Context) {
+	// Tasks outlive ctx until the shutdown timeout runs out
+	taskCtx, cancelTasks := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelTasks()
+
+	p.mutex.Lock()
+	p.startedAt = p.now()
+	p.mutex.Unlock()
+
+	var wg sync.WaitGroup
+	for id := 1; id <= p.size; id++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.work(ctx, taskCtx, id)
+		}()
+	}
+
+	ticker := time.NewTicker(p.sweepInterval)
+	defer ticker.Stop()
+	for {
+		p.sweep(ctx)
+		select {
+		case <-ctx.Done():
+			p.drain(&wg, cancelTasks)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// drain waits for the workers to finish their tasks, cancelling the tasks after the shutdown timeout
+func (p *Pool) drain(wg *sync.WaitGroup, cancelTasks context.CancelFunc) {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(p.shutdownTimeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		log.Printf("worker pool: tasks still in progress after %s; handing their jobs back", p.shutdownTimeout)
+		cancelTasks()
+		<-done
+	}
+}
+
+// sweep adds a job for every queued payment; payments that already have one keep it
+func (p *Pool) sweep(ctx context.Context) {
+	ids, err := p.jobs.QueuedPayments(ctx)
+	if err != nil {
+		log.Printf("worker pool: listing queued payments: %v", err)
+		return
+	}
+	for _, id := range ids {
+		if err := p.queue.EnqueueJob(ctx, id, p.now()); err != nil {
+			log.Printf("worker pool: queueing payment %s: %v", id, err)
+			return
+		}
+	}
+}
+
+// work leases jobs until ctx is done, waiting a poll interval whenever there is none, and executes
+// them with taskCtx so that a job leased before ctx is done is still executed
+func (p *Pool) work(ctx, taskCtx context.Context, id int) {
+	for ctx.Err() == nil {
+		now := p.now()
+		job, err := p.queue.LeaseJob(taskCtx, now, now.Add(p.visibilityTimeout))
+		if err != nil {
+			log.Printf("worker %d: leasing a job: %v", id, err)
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(p.pollInterval):
+			}
+			continue
+		}
+		p.execute(taskCtx, id, job)
+	}
+}
+
+// execute runs the payment of a leased job and completes, retries or buries the job
+func (p *Pool) execute(ctx context.Context, id int, job *entity.Job) {
+	task := Task{TransactionID: job.TransactionID, Attempt: job.Attempts}
+	started := p.now()
+	p.started(id, task, started)
+	p.observer.Started(id, task)
+	response, err := p.jobs.ExecutePayment(ctx, task.TransactionID)
+	result := Result{Worker: id, TransactionID: task.TransactionID, Attempt: task.Attempt, Err: err, Duration: p.now().Sub(started)}
+	if response != nil {
+		result.Status = response.Status
+	}
+
+	// The payment's outcome is stored, so the job is finished even if ctx is done meanwhile
+	cancelled := ctx.Err() != nil
+	ctx = context.WithoutCancel(ctx)
+	var queueErr error
+	switch now := p.now(); {
+	case err != nil && cancelled:
+		// The pool is stopping; the job is leased again straight away, and the attempt is not counted
+		result.Outcome = OutcomeReleased
+		queueErr = p.queue.ReleaseJob(ctx, job.TransactionID, job.Attempts, err.Error(), now)
+	case finished(err):
+		result.Outcome = OutcomeCompleted
+		queueErr = p.queue.CompleteJob(ctx, job.TransactionID, job.Attempts)
+	case job.Attempts >= p.retryPolicy.MaxAttempts:
+		result.Outcome = OutcomeDead
+		queueErr = p.queue.BuryJob(ctx, job.TransactionID, job.Attempts, err.Error(), now)
+	default:
+		result.Outcome = OutcomeRetried
+		retryAt := now.Add(p.retryPolicy.Backoff(job.Attempts, p.random()))
+		queueErr = p.queue.RetryJob(ctx, job.TransactionID, job.Attempts, err.Error(), retryAt, now)
+	}
+	if queueErr != nil {
+		// A lost lease means the job was delivered again; its new holder decides its fate
+		log.Printf("worker %d: recording the outcome of payment %s: %v", id, job.TransactionID, queueErr)
+	}
+	p.finishedTask(result)
+	p.observer.Finished(result)
+}
+
+// finished reports whether an attempt that ended with err settled the payment's outcome.
+// Declines are outcomes; outages, timeouts and storage failures are worth another attempt.
+func finished(err error) bool {
+	return err == nil ||
+		errors.Is(err, usecase.ErrPaymentDeclined) ||
+		errors.Is(err, usecase.ErrInsufficientFunds) ||
+		errors.Is(err, usecase.ErrPaymentNotFound)
+}
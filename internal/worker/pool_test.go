@@ -0,0 +1,312 @@
+package worker_test
+
+import (
+	"context"
+	"payment-service/internal/entity"
+	"payment-service/internal/gateway"
+	"payment-service/internal/repository"
+	"payment-service/internal/usecase"
+	"payment-service/internal/worker"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingObserver records the results of finished tasks
+type recordingObserver struct {
+	mutex   sync.Mutex
+	results []worker.Result
+}
+
+func (o *recordingObserver) Started(int, worker.Task) {}
+
+func (o *recordingObserver) Finished(result worker.Result) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.results = append(o.results, result)
+}
+
+func (o *recordingObserver) snapshot() []worker.Result {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return append([]worker.Result(nil), o.results...)
+}
+
+func (o *recordingObserver) outcomes() []string {
+	var outcomes []string
+	for _, result := range o.snapshot() {
+		outcomes = append(outcomes, result.Outcome)
+	}
+	return outcomes
+}
+
+// runPool runs the pool until the test ends
+func runPool(t *testing.T, pool *worker.Pool) {
+	shutdown, stopped := startPool(t, pool)
+	t.Cleanup(func() {
+		shutdown()
+		<-stopped
+	})
+}
+
+// newAsyncUseCase creates a use case charging through simulator that queues jobs in repo
+func newAsyncUseCase(repo *repository.InMemoryPaymentRepository, simulator *gateway.Simulator) *usecase.PaymentUseCase {
+	return usecase.NewPaymentUseCase(repo, usecase.WithGateway(simulator), usecase.WithJobQueue(repo))
+}
+
+// queuePayment makes an asynchronous payment request
+func queuePayment(t *testing.T, useCase *usecase.PaymentUseCase, transactionID, cardToken string) {
+	response, err := useCase.ProcessPayment(context.Background(), usecase.PaymentRequest{
+		UserID: "user123", Amount: "100.00", Currency: "USD", TransactionID: transactionID,
+		CardToken: cardToken, ProcessingMode: usecase.ProcessingAsync,
+	})
+	require.NoError(t, err)
+	require.True(t, response.Queued)
+}
+
+// eventuallyStatus waits for the stored payment to reach status
+func eventuallyStatus(t *testing.T, repo *repository.InMemoryPaymentRepository, transactionID, status string) {
+	require.Eventually(t, func() bool {
+		stored, err := repo.GetByTransactionID(context.Background(), transactionID)
+		return err == nil && stored.Status == status && !stored.Queued
+	}, 2*time.Second, 5*time.Millisecond, "payment %s should become %s", transactionID, status)
+}
+
+func TestPool_ExecutesQueuedPayments(t *testing.T) {
+	// Arrange
+	repo := repository.NewInMemoryPaymentRepository()
+	useCase := newAsyncUseCase(repo, gateway.NewSimulator())
+	queuePayment(t, useCase, "txn1", "tok_visa")
+	queuePayment(t, useCase, "txn2", gateway.CardDeclined)
+	queuePayment(t, useCase, "txn3", "tok_visa")
+	observer := &recordingObserver{}
+	pool := worker.NewPool(useCase, repo, 2, worker.WithPollInterval(5*time.Millisecond), worker.WithObserver(observer))
+
+	// Act
+	runPool(t, pool)
+
+	// Assert
+	eventuallyStatus(t, repo, "txn1", entity.StatusCaptured)
+	eventuallyStatus(t, repo, "txn2", entity.StatusFailed)
+	eventuallyStatus(t, repo, "txn3", entity.StatusCaptured)
+	require.Eventually(t, func() bool { return len(observer.snapshot()) == 3 }, time.Second, 5*time.Millisecond)
+	for _, result := range observer.snapshot() {
+		assert.Equal(t, worker.OutcomeCompleted, result.Outcome, "a decline is an outcome, not a failure to retry")
+		assert.Equal(t, 1, result.Attempt)
+		assert.True(t, result.Worker >= 1 && result.Worker <= 2)
+	}
+	leftover, err := repo.LeaseJob(context.Background(), time.Now().Add(time.Hour), time.Now().Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Nil(t, leftover, "completed jobs leave the queue")
+}
+
+func TestPool_RetriesWithBackoffThenDeadLetters(t *testing.T) {
+	// Arrange
+	repo := repository.NewInMemoryPaymentRepository()
+	simulator := gateway.NewSimulator()
+	simulator.SetDown(true)
+	useCase := newAsyncUseCase(repo, simulator)
+	queuePayment(t, useCase, "txn123", "tok_visa")
+	observer := &recordingObserver{}
+	pool := worker.NewPool(useCase, repo, 2,
+		worker.WithPollInterval(5*time.Millisecond),
+		worker.WithRetryPolicy(worker.RetryPolicy{MaxAttempts: 3, BaseDelay: 10 * time.Millisecond, MaxDelay: 20 * time.Millisecond}),
+		worker.WithObserver(observer),
+	)
+
+	// Act
+	runPool(t, pool)
+	var dead []*entity.Job
+	require.Eventually(t, func() bool {
+		var err error
+		dead, err = useCase.DeadJobs(context.Background())
+		return err == nil && len(dead) == 1
+	}, 2*time.Second, 5*time.Millisecond)
+
+	// Assert
+	assert.Equal(t, "txn123", dead[0].TransactionID)
+	assert.Equal(t, 3, dead[0].Attempts)
+	assert.Contains(t, dead[0].LastError, usecase.ErrGatewayUnavailable.Error())
+	assert.Equal(t, []string{worker.OutcomeRetried, worker.OutcomeRetried, worker.OutcomeDead}, observer.outcomes())
+	results := observer.snapshot()
+	assert.GreaterOrEqual(t, results[1].Attempt, 2)
+	stored, err := repo.GetByTransactionID(context.Background(), "txn123")
+	require.NoError(t, err)
+	assert.True(t, stored.Queued, "a dead-lettered payment stays queued until it is requeued")
+
+	// Requeued once the processor is back, the payment goes through
+	simulator.SetDown(false)
+	requeued, err := useCase.RequeueJob(context.Background(), "txn123")
+	require.NoError(t, err)
+	assert.Equal(t, 0, requeued.Attempts)
+	eventuallyStatus(t, repo, "txn123", entity.StatusCaptured)
+}
+
+func TestPool_RedeliversJobsOfCrashedWorkers(t *testing.T) {
+	// Arrange: a worker leased the job, then crashed before finishing it
+	repo := repository.NewInMemoryPaymentRepository()
+	useCase := newAsyncUseCase(repo, gateway.NewSimulator())
+	queuePayment(t, useCase, "txn123", "tok_visa")
+	now := time.Now()
+	leased, err := repo.LeaseJob(context.Background(), now, now.Add(50*time.Millisecond))
+	require.NoError(t, err)
+	require.NotNil(t, leased)
+	observer := &recordingObserver{}
+	pool := worker.NewPool(useCase, repo, 1, worker.WithPollInterval(5*time.Millisecond), worker.WithObserver(observer))
+
+	// Act
+	runPool(t, pool)
+
+	// Assert
+	eventuallyStatus(t, repo, "txn123", entity.StatusCaptured)
+	require.Eventually(t, func() bool { return len(observer.snapshot()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, 2, observer.snapshot()[0].Attempt, "the job is delivered again once its lease runs out")
+}
+
+func TestPool_SweepsQueuedPaymentsWithoutJobs(t *testing.T) {
+	// Arrange: the payment was queued without a job, as by a request that failed before queueing it
+	repo := repository.NewInMemoryPaymentRepository()
+	simulator := gateway.NewSimulator()
+	lost := repository.NewInMemoryPaymentRepository()
+	queuePayment(t, usecase.NewPaymentUseCase(repo, usecase.WithGateway(simulator), usecase.WithJobQueue(lost)), "txn123", "tok_visa")
+	pool := worker.NewPool(newAsyncUseCase(repo, simulator), repo, 1,
+		worker.WithPollInterval(5*time.Millisecond), worker.WithObserver(&recordingObserver{}))
+
+	// Act
+	runPool(t, pool)
+
+	// Assert
+	eventuallyStatus(t, repo, "txn123", entity.StatusCaptured)
+	assert.Equal(t, 1, pool.Size())
+}
+
+// blockingJobs executes payments once release is closed or their context is done, telling started
+type blockingJobs struct {
+	started chan string
+	release chan struct{}
+}
+
+func newBlockingJobs() *blockingJobs {
+	return &blockingJobs{started: make(chan string, 1), release: make(chan struct{})}
+}
+
+func (j *blockingJobs) QueuedPayments(context.Context) ([]string, error) {
+	return nil, nil
+}
+
+func (j *blockingJobs) ExecutePayment(ctx context.Context, transactionID string) (*usecase.PaymentResponse, error) {
+	j.started <- transactionID
+	select {
+	case <-j.release:
+		return &usecase.PaymentResponse{TransactionID: transactionID, Status: entity.StatusCaptured}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// startPool runs the pool in the background; cancelling the returned context stops it
+func startPool(t *testing.T, pool *worker.Pool) (context.CancelFunc, <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	stopped := make(chan struct{})
+	go func() {
+		pool.Run(ctx)
+		close(stopped)
+	}()
+	return cancel, stopped
+}
+
+func TestPool_ShutdownFinishesTasksInProgress(t *testing.T) {
+	// Arrange: a worker is executing a payment when the pool is stopped
+	repo := repository.NewInMemoryPaymentRepository()
+	require.NoError(t, repo.EnqueueJob(context.Background(), "txn1", time.Now()))
+	jobs := newBlockingJobs()
+	observer := &recordingObserver{}
+	pool := worker.NewPool(jobs, repo, 1, worker.WithPollInterval(5*time.Millisecond), worker.WithObserver(observer))
+	shutdown, stopped := startPool(t, pool)
+	assert.Equal(t, "txn1", <-jobs.started)
+	require.NoError(t, repo.EnqueueJob(context.Background(), "txn2", time.Now()))
+
+	// Act
+	shutdown()
+
+	// Assert: the pool waits for the payment, then stops without leasing another job
+	select {
+	case <-stopped:
+		t.Fatal("the pool stopped before its task finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(jobs.release)
+	<-stopped
+	assert.Equal(t, []string{worker.OutcomeCompleted}, observer.outcomes())
+	next, err := repo.LeaseJob(context.Background(), time.Now(), time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.NotNil(t, next)
+	assert.Equal(t, "txn2", next.TransactionID)
+	assert.Equal(t, 1, next.Attempts, "the job queued during shutdown was never leased")
+}
+
+func TestPool_ShutdownReleasesTasksOverTheDeadline(t *testing.T) {
+	// Arrange: the payment in progress takes longer than the shutdown timeout, on its last attempt
+	repo := repository.NewInMemoryPaymentRepository()
+	require.NoError(t, repo.EnqueueJob(context.Background(), "txn1", time.Now()))
+	jobs := newBlockingJobs()
+	observer := &recordingObserver{}
+	pool := worker.NewPool(jobs, repo, 1,
+		worker.WithPollInterval(5*time.Millisecond),
+		worker.WithShutdownTimeout(20*time.Millisecond),
+		worker.WithRetryPolicy(worker.RetryPolicy{MaxAttempts: 1, BaseDelay: time.Minute, MaxDelay: time.Minute}),
+		worker.WithObserver(observer),
+	)
+	shutdown, stopped := startPool(t, pool)
+	<-jobs.started
+
+	// Act
+	shutdown()
+	<-stopped
+
+	// Assert: the job is back in the queue, available straight away rather than dead-lettered
+	assert.Equal(t, []string{worker.OutcomeReleased}, observer.outcomes())
+	dead, err := repo.DeadJobs(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, dead)
+	next, err := repo.LeaseJob(context.Background(), time.Now(), time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.NotNil(t, next)
+	assert.Equal(t, "txn1", next.TransactionID)
+	assert.Contains(t, next.LastError, context.Canceled.Error())
+}
+
+func TestPool_ShutdownOnTheLastAttemptKeepsIt(t *testing.T) {
+	// Arrange: the job already failed once and its worker leases its last attempt when the pool stops
+	repo := repository.NewInMemoryPaymentRepository()
+	now := time.Now()
+	require.NoError(t, repo.EnqueueJob(context.Background(), "txn1", now))
+	_, err := repo.LeaseJob(context.Background(), now, now.Add(time.Minute))
+	require.NoError(t, err)
+	require.NoError(t, repo.RetryJob(context.Background(), "txn1", 1, "processor unavailable", now, now))
+	jobs := newBlockingJobs()
+	observer := &recordingObserver{}
+	pool := worker.NewPool(jobs, repo, 1,
+		worker.WithPollInterval(5*time.Millisecond),
+		worker.WithShutdownTimeout(20*time.Millisecond),
+		worker.WithRetryPolicy(worker.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Minute, MaxDelay: time.Minute}),
+		worker.WithObserver(observer),
+	)
+	shutdown, stopped := startPool(t, pool)
+	<-jobs.started
+
+	// Act
+	shutdown()
+	<-stopped
+
+	// Assert: the released attempt is not counted, so the job still has its last attempt
+	assert.Equal(t, []string{worker.OutcomeReleased}, observer.outcomes())
+	next, err := repo.LeaseJob(context.Background(), time.Now(), time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.NotNil(t, next)
+	assert.Equal(t, 2, next.Attempts)
+}
@@ -0,0 +1,35 @@
+package worker
+
+import "time"
+
+// Default retry policy of a Pool
+const (
+	DefaultMaxAttempts = 10
+	DefaultBaseDelay   = 5 * time.Second
+	DefaultMaxDelay    = 10 * time.Minute
+)
+
+// RetryPolicy decides how often and how long after a failed attempt a job is tried again
+type RetryPolicy struct {
+	MaxAttempts int           // Attempts before the job is dead-lettered
+	BaseDelay   time.Duration // Delay after the first failed attempt; it doubles with every further one
+	MaxDelay    time.Duration // Longest delay between attempts
+}
+
+// DefaultRetryPolicy returns the policy a Pool uses unless configured otherwise
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxAttempts: DefaultMaxAttempts, BaseDelay: DefaultBaseDelay, MaxDelay: DefaultMaxDelay}
+}
+
+// Backoff returns how long to wait after the given failed attempt. The exponential delay is
+// jittered by random, a number in [0, 1): the wait is between half the delay and the full delay,
+// so jobs that failed together are not all retried at the same moment.
+func (p RetryPolicy) Backoff(attempt int, random float64) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, p.MaxDelay)
+	half := delay / 2
+	return half + time.Duration(random*float64(delay-half))
+}
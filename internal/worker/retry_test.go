@@ -0,0 +1,36 @@
+package worker_test
+
+import (
+	"payment-service/internal/worker"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := worker.RetryPolicy{MaxAttempts: 10, BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+
+	tests := []struct {
+		name    string
+		attempt int
+		random  float64
+		want    time.Duration
+	}{
+		{"first attempt, least jitter", 1, 0, 500 * time.Millisecond},
+		{"first attempt, most jitter", 1, 0.99, 995 * time.Millisecond},
+		{"doubles every attempt", 3, 0, 2 * time.Second},
+		{"capped at the maximum", 8, 0, 5 * time.Second},
+		{"capped at the maximum, halfway", 20, 0.5, 7500 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			got := policy.Backoff(tt.attempt, tt.random)
+
+			// Assert
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
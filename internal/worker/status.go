@@ -0,0 +1,133 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"payment-service/internal/entity"
+	"text/tabwriter"
+	"time"
+)
+
+// PoolStatus is a snapshot of what a pool's workers are doing and how deep its job queue is
+type PoolStatus struct {
+	CheckedAt  time.Time         `json:"checked_at"`
+	StartedAt  time.Time         `json:"started_at"`            // When the pool started running; zero before
+	Processed  int               `json:"processed"`             // Tasks finished by all workers
+	Errors     int               `json:"errors"`                // Attempts of all workers that failed and were retried or dead-lettered
+	Throughput float64           `json:"throughput_per_minute"` // Tasks finished per minute since the pool started
+	Workers    []WorkerStatus    `json:"workers"`
+	Queue      *entity.JobCounts `json:"queue,omitempty"`
+	QueueError string            `json:"queue_error,omitempty"` // Why the queue could not be counted
+}
+
+// WorkerStatus is what one worker of a pool is doing and has done
+type WorkerStatus struct {
+	ID            int        `json:"id"`
+	Task          *Task      `json:"task,omitempty"`            // The task in progress; nil while the worker is idle
+	TaskStartedAt *time.Time `json:"task_started_at,omitempty"` // When the task in progress started
+	Processed     int        `json:"processed"`                 // Tasks finished
+	Errors        int        `json:"errors"`                    // Attempts that failed and were retried or dead-lettered
+	LastError     string     `json:"last_error,omitempty"`      // Why the latest failed attempt failed
+	Throughput    float64    `json:"throughput_per_minute"`     // Tasks finished per minute since the pool started
+}
+
+// workerState is the running record of a worker behind its WorkerStatus
+type workerState struct {
+	task          *Task
+	taskStartedAt time.Time
+	processed     int
+	errors        int
+	lastError     string
+}
+
+// started records the task a worker started at
+func (p *Pool) started(id int, task Task, at time.Time) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	state := &p.states[id-1]
+	state.task = &task
+	state.taskStartedAt = at
+}
+
+// finishedTask records the result of a worker's task
+func (p *Pool) finishedTask(result Result) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	state := &p.states[result.Worker-1]
+	state.task = nil
+	state.processed++
+	if result.Outcome == OutcomeRetried || result.Outcome == OutcomeDead {
+		state.errors++
+		state.lastError = result.Err.Error()
+	}
+}
+
+// Status reports what the workers are doing and how deep the job queue is. The workers are reported
+// even if the queue cannot be counted.
+func (p *Pool) Status(ctx context.Context) *PoolStatus {
+	now := p.now()
+	status := &PoolStatus{CheckedAt: now, Workers: make([]WorkerStatus, 0, p.size)}
+	if counts, err := p.queue.CountJobs(ctx, now); err != nil {
+		status.QueueError = err.Error()
+	} else {
+		status.Queue = counts
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	status.StartedAt = p.startedAt
+	for i, state := range p.states {
+		worker := WorkerStatus{
+			ID:         i + 1,
+			Processed:  state.processed,
+			Errors:     state.errors,
+			LastError:  state.lastError,
+			Throughput: perMinute(state.processed, p.startedAt, now),
+		}
+		if state.task != nil {
+			task, startedAt := *state.task, state.taskStartedAt
+			worker.Task, worker.TaskStartedAt = &task, &startedAt
+		}
+		status.Processed += state.processed
+		status.Errors += state.errors
+		status.Workers = append(status.Workers, worker)
+	}
+	status.Throughput = perMinute(status.Processed, p.startedAt, now)
+	return status
+}
+
+// perMinute returns how many of n happened per minute between start and now
+func perMinute(n int, start, now time.Time) float64 {
+	if start.IsZero() || !now.After(start) {
+		return 0
+	}
+	return float64(n) / now.Sub(start).Minutes()
+}
+
+// WriteText writes the status as a plain-text table, one line per worker
+func (s *PoolStatus) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "--------[%s]--------\n", s.CheckedAt.Format("2006-01-02 15:04:05"))
+	if !s.StartedAt.IsZero() {
+		fmt.Fprintf(w, "Up %s, %d processed, %d errors, %.1f/min\n", s.CheckedAt.Sub(s.StartedAt).Round(time.Second), s.Processed, s.Errors, s.Throughput)
+	}
+	if s.Queue != nil {
+		fmt.Fprintf(w, "Queue: %d available, %d delayed, %d dead\n", s.Queue.Available, s.Queue.Delayed, s.Queue.Dead)
+	} else {
+		fmt.Fprintf(w, "Queue: unknown (%s)\n", s.QueueError)
+	}
+	fmt.Fprintln(w)
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "WORKER\tPAYMENT\tATTEMPT\tRUNNING\tPROCESSED\tERRORS\tPER MIN")
+	for _, worker := range s.Workers {
+		payment, attempt, running := "idle", "-", "-"
+		if worker.Task != nil {
+			payment = worker.Task.TransactionID
+			attempt = fmt.Sprint(worker.Task.Attempt)
+			running = s.CheckedAt.Sub(*worker.TaskStartedAt).Round(time.Millisecond).String()
+		}
+		fmt.Fprintf(table, "%d\t%s\t%s\t%s\t%d\t%d\t%.1f\n", worker.ID, payment, attempt, running, worker.Processed, worker.Errors, worker.Throughput)
+	}
+	return table.Flush()
+}
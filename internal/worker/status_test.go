@@ -0,0 +1,150 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"payment-service/internal/entity"
+	"payment-service/internal/gateway"
+	"payment-service/internal/repository"
+	"payment-service/internal/usecase"
+	"payment-service/internal/worker"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// uncountableQueue is a job queue that cannot be counted
+type uncountableQueue struct {
+	*repository.InMemoryPaymentRepository
+}
+
+func (uncountableQueue) CountJobs(context.Context, time.Time) (*entity.JobCounts, error) {
+	return nil, errors.New("storage unavailable")
+}
+
+func TestPool_StatusReportsTasksInProgressAndQueueDepth(t *testing.T) {
+	// Arrange: a dead-lettered job, and a job a worker is executing
+	repo := repository.NewInMemoryPaymentRepository()
+	now := time.Now()
+	require.NoError(t, repo.EnqueueJob(context.Background(), "txn0", now))
+	_, err := repo.LeaseJob(context.Background(), now, now.Add(time.Minute))
+	require.NoError(t, err)
+	require.NoError(t, repo.BuryJob(context.Background(), "txn0", 1, "timeout", now))
+	require.NoError(t, repo.EnqueueJob(context.Background(), "txn1", now))
+	jobs := newBlockingJobs()
+	pool := worker.NewPool(jobs, repo, 2, worker.WithPollInterval(5*time.Millisecond), worker.WithObserver(&recordingObserver{}))
+	runPool(t, pool)
+	<-jobs.started
+
+	// Act
+	busy := pool.Status(context.Background())
+	close(jobs.release)
+	var idle *worker.PoolStatus
+	require.Eventually(t, func() bool {
+		idle = pool.Status(context.Background())
+		return idle.Processed == 1
+	}, time.Second, 5*time.Millisecond)
+
+	// Assert
+	require.Len(t, busy.Workers, 2)
+	assert.False(t, busy.StartedAt.IsZero())
+	assert.Equal(t, &entity.JobCounts{Available: 0, Delayed: 1, Dead: 1}, busy.Queue)
+	var working []worker.WorkerStatus
+	for _, status := range busy.Workers {
+		if status.Task != nil {
+			working = append(working, status)
+		}
+	}
+	require.Len(t, working, 1, "one worker holds the only available job")
+	assert.Equal(t, worker.Task{TransactionID: "txn1", Attempt: 1}, *working[0].Task)
+	require.NotNil(t, working[0].TaskStartedAt)
+	assert.False(t, working[0].TaskStartedAt.After(busy.CheckedAt))
+
+	assert.Equal(t, &entity.JobCounts{Available: 0, Delayed: 0, Dead: 1}, idle.Queue)
+	assert.Equal(t, 0, idle.Errors)
+	assert.Greater(t, idle.Throughput, 0.0)
+	worker1 := idle.Workers[working[0].ID-1]
+	assert.Nil(t, worker1.Task)
+	assert.Nil(t, worker1.TaskStartedAt)
+	assert.Equal(t, 1, worker1.Processed)
+	assert.Greater(t, worker1.Throughput, 0.0)
+}
+
+func TestPool_StatusCountsFailedAttempts(t *testing.T) {
+	// Arrange
+	repo := repository.NewInMemoryPaymentRepository()
+	simulator := gateway.NewSimulator()
+	simulator.SetDown(true)
+	useCase := newAsyncUseCase(repo, simulator)
+	queuePayment(t, useCase, "txn123", "tok_visa")
+	pool := worker.NewPool(useCase, repo, 1,
+		worker.WithPollInterval(5*time.Millisecond),
+		worker.WithRetryPolicy(worker.RetryPolicy{MaxAttempts: 2, BaseDelay: 10 * time.Millisecond, MaxDelay: 10 * time.Millisecond}),
+		worker.WithObserver(&recordingObserver{}),
+	)
+
+	// Act
+	runPool(t, pool)
+	var status *worker.PoolStatus
+	require.Eventually(t, func() bool {
+		status = pool.Status(context.Background())
+		return status.Queue != nil && status.Queue.Dead == 1
+	}, 2*time.Second, 5*time.Millisecond)
+
+	// Assert
+	assert.Equal(t, 2, status.Processed)
+	assert.Equal(t, 2, status.Errors, "a retried and a dead-lettered attempt")
+	assert.Equal(t, 2, status.Workers[0].Errors)
+	assert.Contains(t, status.Workers[0].LastError, usecase.ErrGatewayUnavailable.Error())
+}
+
+func TestPool_StatusWithoutQueueDepth(t *testing.T) {
+	// Arrange
+	pool := worker.NewPool(newBlockingJobs(), uncountableQueue{repository.NewInMemoryPaymentRepository()}, 3)
+
+	// Act
+	status := pool.Status(context.Background())
+
+	// Assert
+	assert.Nil(t, status.Queue)
+	assert.Equal(t, "storage unavailable", status.QueueError)
+	assert.Len(t, status.Workers, 3, "workers are reported without the queue")
+	assert.True(t, status.StartedAt.IsZero(), "the pool has not started")
+	assert.Zero(t, status.Throughput)
+}
+
+func TestPoolStatus_WriteText(t *testing.T) {
+	// Arrange
+	checkedAt := time.Date(2025, 1, 1, 10, 2, 0, 0, time.UTC)
+	taskStartedAt := checkedAt.Add(-1500 * time.Millisecond)
+	status := &worker.PoolStatus{
+		CheckedAt:  checkedAt,
+		StartedAt:  checkedAt.Add(-2 * time.Minute),
+		Processed:  9,
+		Errors:     1,
+		Throughput: 4.5,
+		Workers: []worker.WorkerStatus{
+			{ID: 1, Task: &worker.Task{TransactionID: "txn123", Attempt: 2}, TaskStartedAt: &taskStartedAt, Processed: 5, Errors: 1, Throughput: 2.5},
+			{ID: 2, Processed: 4, Throughput: 2},
+		},
+		Queue: &entity.JobCounts{Available: 3, Delayed: 2},
+	}
+	var text strings.Builder
+
+	// Act
+	err := status.WriteText(&text)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, `--------[2025-01-01 10:02:00]--------
+Up 2m0s, 9 processed, 1 errors, 4.5/min
+Queue: 3 available, 2 delayed, 0 dead
+
+WORKER  PAYMENT  ATTEMPT  RUNNING  PROCESSED  ERRORS  PER MIN
+1       txn123   2        1.5s     5          1       2.5
+2       idle     -        -        4          0       2.0
+`, text.String())
+}
@@ -22,10 +22,10 @@ echo.
 echo Basic commands:
 echo   scripts\build\build.bat help         - Show this help
 echo   scripts\build\build.bat run          - Run the payment service (default)
-echo   scripts\build\build.bat run-worker   - Run the worker pool demo
+echo   scripts\build\build.bat run-worker   - Run the payment worker pool
 echo   scripts\build\build.bat test         - Run tests
 echo   scripts\build\build.bat build        - Build the payment service (default)
-echo   scripts\build\build.bat build-worker - Build the worker pool demo
+echo   scripts\build\build.bat build-worker - Build the payment worker pool
 echo   scripts\build\build.bat docs         - Generate documentation
 echo.
 echo For more commands, use scripts\build\build.ps1 (PowerShell script)
@@ -39,8 +39,8 @@ go run cmd/server/main.go
 goto end
 
 :run-worker
-echo Running the worker pool demo...
-go run cmd/worker/main.go
+echo Running the payment worker pool...
+go run cmd/worker/main.go -store "%STORE%"
 goto end
 
 :test
@@ -55,7 +55,7 @@ go build -o bin/payment-service.exe cmd/server/main.go
 goto end
 
 :build-worker
-echo Building the worker pool demo...
+echo Building the payment worker pool...
 if not exist bin mkdir bin
 go build -o bin/worker-pool.exe cmd/worker/main.go
 goto end
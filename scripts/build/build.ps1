@@ -16,9 +16,9 @@ function Show-Help {
     Write-Host "Basic commands:" -ForegroundColor Blue
     Write-Host "  .\scripts\build\build.ps1 help          - Show this help message"
     Write-Host "  .\scripts\build\build.ps1 build         - Build the payment service (default)"
-    Write-Host "  .\scripts\build\build.ps1 build-worker  - Build the worker pool demo"
+    Write-Host "  .\scripts\build\build.ps1 build-worker  - Build the payment worker pool"
     Write-Host "  .\scripts\build\build.ps1 run           - Run the payment service (default)"
-    Write-Host "  .\scripts\build\build.ps1 run-worker    - Run the worker pool demo"
+    Write-Host "  .\scripts\build\build.ps1 run-worker    - Run the payment worker pool"
     Write-Host "  .\scripts\build\build.ps1 test          - Run tests"
     Write-Host "  .\scripts\build\build.ps1 test-coverage - Run tests with coverage report"
     Write-Host ""
@@ -56,13 +56,13 @@ function Build-App {
 }
 
 function Build-Worker {
-    Write-Host "🔨 Building the worker pool demo..." -ForegroundColor Blue
+    Write-Host "🔨 Building the payment worker pool..." -ForegroundColor Blue
     New-Item -ItemType Directory -Force -Path "bin" | Out-Null
     go build -o bin/worker-pool.exe cmd/worker/main.go
     if ($LASTEXITCODE -eq 0) {
-        Write-Host "✅ Worker pool demo build completed successfully!" -ForegroundColor Green
+        Write-Host "✅ Payment worker pool build completed successfully!" -ForegroundColor Green
     } else {
-        Write-Host "❌ Worker pool demo build failed!" -ForegroundColor Red
+        Write-Host "❌ Payment worker pool build failed!" -ForegroundColor Red
     }
 }
 
@@ -74,8 +74,8 @@ function Run-App {
 }
 
 function Run-Worker {
-    Write-Host "🚀 Running the worker pool demo..." -ForegroundColor Blue
-    go run cmd/worker/main.go
+    Write-Host "🚀 Running the payment worker pool..." -ForegroundColor Blue
+    go run cmd/worker/main.go -store "$env:STORE"
 }
 
 function Test-App {
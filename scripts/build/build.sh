@@ -9,9 +9,9 @@ show_help() {
     echo "Basic commands:"
     echo "  ./scripts/build/build.sh help          - Show this help message"
     echo "  ./scripts/build/build.sh build         - Build the payment service (default)"
-    echo "  ./scripts/build/build.sh build-worker  - Build the worker pool demo"
+    echo "  ./scripts/build/build.sh build-worker  - Build the payment worker pool"
     echo "  ./scripts/build/build.sh run           - Run the payment service (default)"
-    echo "  ./scripts/build/build.sh run-worker    - Run the worker pool demo"
+    echo "  ./scripts/build/build.sh run-worker    - Run the payment worker pool"
     echo "  ./scripts/build/build.sh test          - Run tests"
     echo "  ./scripts/build/build.sh test-coverage - Run tests with coverage report"
     echo ""
@@ -49,13 +49,13 @@ build_app() {
 }
 
 build_worker() {
-    echo "🔨 Building the worker pool demo..."
+    echo "🔨 Building the payment worker pool..."
     mkdir -p bin
     go build -o bin/worker-pool cmd/worker/main.go
     if [ $? -eq 0 ]; then
-        echo "✅ Worker pool demo build completed successfully!"
+        echo "✅ Payment worker pool build completed successfully!"
     else
-        echo "❌ Worker pool demo build failed!"
+        echo "❌ Payment worker pool build failed!"
     fi
 }
 
@@ -67,8 +67,8 @@ run_app() {
 }
 
 run_worker() {
-    echo "🚀 Running the worker pool demo..."
-    go run cmd/worker/main.go
+    echo "🚀 Running the payment worker pool..."
+    go run cmd/worker/main.go -store "$STORE"
 }
     go run cmd/server/main.go
 }